@@ -8,6 +8,37 @@ const GAME_CONFIG = {
   animationDuration: 300,
 }
 
+// Textos localizados (se completan desde /api/game/i18n)
+let I18N = {}
+
+// Traducir una clave reemplazando {placeholders}; si falta, usar el texto por defecto
+function t(clave, porDefecto, valores = {}) {
+  let texto = I18N[clave] || porDefecto
+  for (const [nombre, valor] of Object.entries(valores)) {
+    texto = texto.replace(`{${nombre}}`, valor)
+  }
+  return texto
+}
+
+// Huella del navegador (hash de características estables del dispositivo)
+// usada para detectar a una misma persona jugando con varios teléfonos
+async function calcularHuella() {
+  try {
+    const datos = [
+      navigator.userAgent,
+      navigator.language,
+      navigator.platform,
+      navigator.hardwareConcurrency,
+      screen.width + 'x' + screen.height + 'x' + screen.colorDepth,
+      Intl.DateTimeFormat().resolvedOptions().timeZone,
+    ].join('|')
+    const digest = await crypto.subtle.digest('SHA-256', new TextEncoder().encode(datos))
+    return Array.from(new Uint8Array(digest)).map((b) => b.toString(16).padStart(2, '0')).join('')
+  } catch (error) {
+    return ''
+  }
+}
+
 // Clase principal del juego
 class TimingGame {
   constructor() {
@@ -16,6 +47,8 @@ class TimingGame {
     this.targetTime = 0
     this.isGameRunning = false
     this.hasWon = false
+    this.targetToken = null
+    this.playedRound = null
 
     // Cache de elementos DOM
     this.elements = this.cacheElements()
@@ -38,11 +71,19 @@ class TimingGame {
       nombreInput: document.getElementById("nombre"),
       apellidoInput: document.getElementById("apellido"),
       telefonoInput: document.getElementById("telefono"),
+      emailInput: document.getElementById("email"),
+      idiomaInput: document.getElementById("idioma"),
+      muroInput: document.getElementById("mostrarEnMuro"),
+      sinWhatsAppOption: document.getElementById("opcionSinWhatsApp"),
+      sinWhatsAppInput: document.getElementById("sinWhatsApp"),
     }
   }
 
   // Inicializar el juego
   init() {
+    this.loadTexts()
+    this.loadBranding()
+    this.loadGameConfig()
     this.generateTargetTime()
     this.bindEvents()
     this.resetForm()
@@ -95,6 +136,71 @@ class TimingGame {
     }
   }
 
+  // Cargar textos en el idioma del navegador (o ?lang=) y aplicarlos a la página
+  async loadTexts() {
+    try {
+      const params = new URLSearchParams(window.location.search)
+      const locale = params.get("lang") || (navigator.language || "es").split("-")[0]
+      const response = await fetch(`/api/game/i18n/${encodeURIComponent(locale)}`);
+      const data = await response.json();
+      if (!data.success) return;
+
+      I18N = data.textos
+      document.documentElement.lang = data.locale
+      document.querySelectorAll("[data-i18n]").forEach((el) => {
+        el.textContent = t(el.dataset.i18n, el.textContent)
+      })
+      document.querySelectorAll("[data-i18n-placeholder]").forEach((el) => {
+        el.placeholder = t(el.dataset.i18nPlaceholder, el.placeholder)
+      })
+      // Preseleccionar el idioma de los mensajes de WhatsApp
+      if (this.elements.idiomaInput.querySelector(`option[value="${data.locale}"]`)) {
+        this.elements.idiomaInput.value = data.locale
+      }
+    } catch (error) {
+      console.error('Error cargando textos:', error);
+    }
+  }
+
+  // Aplicar logo, colores y texto legal configurados por el admin
+  // Mostrar la casilla "no uso WhatsApp" si el voucher en pantalla es opcional
+  async loadGameConfig() {
+    try {
+      const response = await fetch('/api/game/config');
+      const data = await response.json();
+      if (data.success && data.config.voucher_en_pantalla === "opcional" && this.elements.sinWhatsAppOption) {
+        this.elements.sinWhatsAppOption.classList.remove("hidden")
+      }
+    } catch (error) {
+      console.error("Error cargando configuración:", error)
+    }
+  }
+
+  async loadBranding() {
+    try {
+      const response = await fetch('/api/game/branding');
+      const data = await response.json();
+      if (!data.success) return;
+
+      const branding = data.branding;
+      if (branding.logo_url) {
+        const logo = document.getElementById("brandLogo");
+        logo.src = branding.logo_url;
+        logo.classList.remove("hidden");
+      }
+      document.getElementById("brandLegal").textContent = branding.texto_legal || "";
+
+      const style = document.createElement("style");
+      style.textContent = `
+        .game-title, .target-time { color: ${branding.color_primario}; }
+        .start-button, .submit-button { background: ${branding.color_primario}; color: ${branding.color_secundario}; }
+      `;
+      document.head.appendChild(style);
+    } catch (error) {
+      console.error('Error cargando branding:', error);
+    }
+  }
+
   // Generar tiempo objetivo desde el backend
   async generateTargetTime() {
     try {
@@ -102,6 +208,7 @@ class TimingGame {
       const data = await response.json();
       if (data.success) {
         this.targetTime = data.target_time;
+        this.targetToken = data.target_token;
         this.elements.targetTime.textContent = this.targetTime;
       } else {
         console.error('Error obteniendo tiempo objetivo:', data);
@@ -140,6 +247,9 @@ class TimingGame {
     this.isGameRunning = true
     this.startTime = performance.now()
 
+    // Abrir la sesión en el servidor, que mide la partida por su cuenta
+    this.session = this.startSession(this.targetToken)
+
     // Actualizar UI
     this.toggleButtons(false)
     this.hideElements([this.elements.resultMessage])
@@ -162,6 +272,9 @@ class TimingGame {
 
     const finalTime = (performance.now() - this.startTime) / 1000
     this.elements.timerDisplay.textContent = finalTime.toFixed(2)
+
+    // Guardar el objetivo jugado: se pide uno nuevo antes de que se envíe el formulario
+    this.playedRound = { targetTime: this.targetTime, session: this.stopSession(this.session), finalTime }
     this.elements.timerDisplay.classList.remove("pulsing")
 
     const difference = Math.abs(finalTime - Number.parseFloat(this.targetTime))
@@ -179,6 +292,31 @@ class TimingGame {
     setTimeout(() => this.generateTargetTime(), 4000)
   }
 
+  // Abrir la sesión de juego con el objetivo firmado; resuelve al token de sesión
+  async startSession(targetToken) {
+    const response = await fetch('/api/game/start', {
+      method: 'POST',
+      headers: { 'Content-Type': 'application/json' },
+      body: JSON.stringify({ token_objetivo: targetToken })
+    });
+    const data = await response.json();
+    if (!data.success) throw new Error(data.message);
+    return data.session_token;
+  }
+
+  // Avisar al servidor que se detuvo el cronómetro; resuelve al mismo token de sesión
+  async stopSession(session) {
+    const token = await session;
+    const response = await fetch('/api/game/stop', {
+      method: 'POST',
+      headers: { 'Content-Type': 'application/json' },
+      body: JSON.stringify({ token_sesion: token })
+    });
+    const data = await response.json();
+    if (!data.success) throw new Error(data.message);
+    return token;
+  }
+
   // Mostrar resultado del juego
   showResult(finalTime, difference) {
     const resultDiv = this.elements.resultMessage
@@ -186,14 +324,14 @@ class TimingGame {
     this.hasWon = isWin
 
     const discount = isWin ? GAME_CONFIG.winDiscount : GAME_CONFIG.loseDiscount
-    const message = isWin ? "¡GANASTE!" : "¡PERDISTE!"
+    const message = isWin ? t("ganaste", "¡GANASTE!") : t("perdiste", "¡PERDISTE!")
 
     resultDiv.className = `result-message ${isWin ? "win-message" : "lose-message"}`
     resultDiv.innerHTML = `
             ${emoji} <strong>${message}</strong> ${emoji}<br>
-            Tu tiempo: <strong>${finalTime.toFixed(2)}s</strong><br>
-            Objetivo: <strong>${this.targetTime}s</strong><br>
-            <strong>¡Descuento del ${discount}%!</strong>
+            ${t("tu_tiempo", "Tu tiempo")}: <strong>${finalTime.toFixed(2)}s</strong><br>
+            ${t("objetivo", "Objetivo")}: <strong>${this.targetTime}s</strong><br>
+            <strong>${t("descuento", "¡Descuento del {descuento}%!", { descuento: discount })}</strong>
         `
 
     this.showElement(resultDiv)
@@ -243,14 +381,28 @@ class TimingGame {
     const submitButton = e.target.querySelector('button[type="submit"]')
     const originalText = submitButton.innerHTML
     submitButton.disabled = true
-    submitButton.innerHTML = '<span class="button-icon">⏳</span> Enviando...'
+    submitButton.innerHTML = `<span class="button-icon">⏳</span> ${t("enviando", "Enviando...")}`
+
+    // Con tarjeta para compartir se deja más tiempo el mensaje en pantalla
+    let resetDelay = 3000
 
     try {
       // Simular envío al backend
-      await this.submitData(customerData, gameResult)
+      const result = await this.submitData(customerData, gameResult)
 
       // Mostrar mensaje de éxito
-      this.showSuccessMessage()
+      this.showSuccessMessage(result && result.compartir_url, result && result.celebracion, result && result.recomendacion, result && result.vence_en_segundos)
+      if (result && (result.compartir_url || result.vence_en_segundos)) resetDelay = 15000
+      if (result && result.mostrar_codigo && result.codigo) {
+        // WhatsApp no está entregando: el cliente tiene que ver (o fotografiar) el código
+        this.showCodeOnScreen(result.codigo, result.nota_codigo)
+        resetDelay = 30000
+      }
+      if (result && result.token_pantalla) {
+        // Voucher en pantalla: se revela confirmando los últimos dígitos del teléfono
+        this.showScreenVoucherPrompt(result.token_pantalla)
+        resetDelay = 60000
+      }
 
       // Feedback háptico
       this.vibrate([100, 50, 100, 50, 100])
@@ -263,7 +415,7 @@ class TimingGame {
       submitButton.innerHTML = originalText
 
       // Reset UI después de un delay
-      setTimeout(() => this.resetGameUI(), 3000)
+      setTimeout(() => this.resetGameUI(), resetDelay)
     }
   }
 
@@ -273,6 +425,10 @@ class TimingGame {
       nombre: this.elements.nombreInput.value.trim(),
       apellido: this.elements.apellidoInput.value.trim(),
       telefono: this.elements.telefonoInput.value.trim(),
+      email: this.elements.emailInput ? this.elements.emailInput.value.trim() : "",
+      idioma: this.elements.idiomaInput ? this.elements.idiomaInput.value : "es",
+      mostrarEnMuro: this.elements.muroInput ? this.elements.muroInput.checked : false,
+      sinWhatsApp: this.elements.sinWhatsAppInput ? this.elements.sinWhatsAppInput.checked : false,
     }
   }
 
@@ -296,6 +452,12 @@ class TimingGame {
       return false
     }
 
+    if (data.email && !/^[^\s@]+@[^\s@]+\.[^\s@]+$/.test(data.email)) {
+      this.showValidationError(t("email_invalido", "Por favor ingresa un email válido"))
+      this.elements.emailInput.focus()
+      return false
+    }
+
     return true
   }
 
@@ -317,18 +479,36 @@ class TimingGame {
         cliente: {
           nombre: customerData.nombre,
           apellido: customerData.apellido,
-          telefono: customerData.telefono
+          telefono: customerData.telefono,
+          email: customerData.email || undefined,
+          idioma: customerData.idioma,
+          mostrar_en_muro: customerData.mostrarEnMuro,
+          sin_whatsapp: customerData.sinWhatsApp
         },
         resultado: {
           gano: gameResult.gano,
-          tiempo_objetivo: parseFloat(this.targetTime),
-          tiempo_obtenido: gameResult.tiempoObtenido
-        }
+          tiempo_objetivo: parseFloat(this.playedRound.targetTime),
+          tiempo_obtenido: this.playedRound.finalTime,
+          token_sesion: await this.playedRound.session
+        },
+        huella: await calcularHuella(),
+        // Mesa del QR escaneado (?mesa=12), para llevar el premio a la mesa
+        mesa: new URLSearchParams(window.location.search).get('mesa') || undefined,
+        // Sucursal donde se juega (?sucursal=Palermo); define dónde se canjea el voucher
+        sucursal: new URLSearchParams(window.location.search).get('sucursal') || undefined
       };
 
+      // Una clave por partida: si el envío se repite (doble toque, reintento tras un corte)
+      // el servidor devuelve el mismo voucher en lugar de generar otro
+      if (!this.playedRound.claveEnvio) {
+        this.playedRound.claveEnvio = crypto.randomUUID
+          ? crypto.randomUUID()
+          : `${Date.now()}-${Math.random().toString(36).slice(2)}`
+      }
+
       const response = await fetch('/api/game/submit', {
         method: 'POST',
-        headers: { 'Content-Type': 'application/json' },
+        headers: { 'Content-Type': 'application/json', 'Idempotency-Key': this.playedRound.claveEnvio },
         body: JSON.stringify(payload)
       });
 
@@ -345,15 +525,227 @@ class TimingGame {
     }
   }
 
-  // Mostrar mensaje de éxito
-  showSuccessMessage() {
+  // Quitar del mensaje de éxito la promesa de recibir el descuento por WhatsApp
+  removeWhatsAppPromise() {
+    const detalle = this.elements.resultMessage.querySelector("br")
+    if (detalle && detalle.nextSibling) {
+      detalle.nextSibling.textContent = " "
+    }
+  }
+
+  // Pedir los últimos dígitos del teléfono para revelar el voucher en pantalla
+  showScreenVoucherPrompt(token) {
+    const resultDiv = this.elements.resultMessage
+    this.removeWhatsAppPromise()
+
+    const bloque = document.createElement("form")
+    bloque.className = "voucher-code-screen"
+
+    const pedido = document.createElement("div")
+    pedido.className = "voucher-code-title"
+    pedido.textContent = t("pantalla_pedido", "Para ver tu voucher, ingresá los últimos 4 dígitos de tu teléfono")
+    bloque.appendChild(pedido)
+
+    const digitos = document.createElement("input")
+    digitos.type = "text"
+    digitos.inputMode = "numeric"
+    digitos.maxLength = 4
+    digitos.pattern = "[0-9]{4}"
+    digitos.required = true
+    digitos.className = "voucher-code-digits"
+    bloque.appendChild(digitos)
+
+    const boton = document.createElement("button")
+    boton.type = "submit"
+    boton.className = "share-button"
+    boton.textContent = t("pantalla_ver", "Ver mi voucher")
+    bloque.appendChild(boton)
+
+    const error = document.createElement("small")
+    error.className = "voucher-code-note"
+    bloque.appendChild(error)
+
+    bloque.addEventListener("submit", async (e) => {
+      e.preventDefault()
+      boton.disabled = true
+      try {
+        const response = await fetch("/api/game/voucher/pantalla", {
+          method: "POST",
+          headers: { "Content-Type": "application/json" },
+          body: JSON.stringify({ token_pantalla: token, digitos: digitos.value.trim() }),
+        })
+        const data = await response.json()
+        if (!data.success) {
+          error.textContent = data.message
+          digitos.value = ""
+          digitos.focus()
+          return
+        }
+        bloque.remove()
+        const nota = t("pantalla_nota", "Sacale una foto o mostrá este código en caja. Vence {vencimiento}.", {
+          vencimiento: data.voucher.vencimiento_texto,
+        })
+        this.showCodeOnScreen(data.voucher.codigo, nota, data.voucher.qr_url)
+      } catch (err) {
+        console.error("Error revelando voucher:", err)
+        error.textContent = t("error_detalle", "Por favor intenta nuevamente.")
+      } finally {
+        boton.disabled = false
+      }
+    })
+
+    resultDiv.insertBefore(bloque, resultDiv.querySelector("small"))
+    digitos.focus()
+  }
+
+  // Mostrar el código del voucher en pantalla (WhatsApp caído o voucher en pantalla), con
+  // el QR para escanear en caja si está disponible
+  showCodeOnScreen(codigo, nota, qrUrl) {
+    const resultDiv = this.elements.resultMessage
+    this.removeWhatsAppPromise()
+
+    const bloque = document.createElement("div")
+    bloque.className = "voucher-code-screen"
+
+    const titulo = document.createElement("div")
+    titulo.className = "voucher-code-title"
+    titulo.textContent = t("codigo_titulo", "Tu código de descuento")
+    bloque.appendChild(titulo)
+
+    const valor = document.createElement("div")
+    valor.className = "voucher-code-value"
+    valor.textContent = codigo
+    bloque.appendChild(valor)
+
+    if (qrUrl) {
+      const qr = document.createElement("img")
+      qr.className = "voucher-code-qr"
+      qr.src = qrUrl
+      qr.alt = codigo
+      bloque.appendChild(qr)
+    }
+
+    const aviso = document.createElement("small")
+    aviso.className = "voucher-code-note"
+    aviso.textContent = nota || t("codigo_nota", "WhatsApp no está respondiendo: sacale una foto a este código o mostralo en caja.")
+    bloque.appendChild(aviso)
+
+    resultDiv.insertBefore(bloque, resultDiv.querySelector("small"))
+  }
+
+  // Mostrar mensaje de éxito (con botón para compartir si el servidor generó la tarjeta,
+  // la celebración configurada desde el panel si ganó, la recomendación del voucher y la
+  // cuenta regresiva si es un voucher flash)
+  showSuccessMessage(shareUrl, celebracion, recomendacion, venceEnSegundos) {
     const resultDiv = this.elements.resultMessage
     resultDiv.className = "result-message win-message"
     resultDiv.innerHTML = `
-            ✅ <strong>¡Datos enviados exitosamente!</strong><br>
-            Recibirás tu descuento por WhatsApp pronto.<br>
-            <small>¡Gracias por jugar!</small>
+            ✅ <strong>${t("exito_titulo", "¡Datos enviados exitosamente!")}</strong><br>
+            ${t("exito_detalle", "Recibirás tu descuento por WhatsApp pronto.")}<br>
+            <small>${t("gracias", "¡Gracias por jugar!")}</small>
         `
+
+    if (recomendacion) {
+      const sugerencia = document.createElement("div")
+      sugerencia.className = "voucher-recommendation"
+      sugerencia.textContent = `💡 ${recomendacion.mensaje}`
+      resultDiv.appendChild(sugerencia)
+    }
+
+    if (venceEnSegundos > 0) {
+      this.startFlashCountdown(venceEnSegundos)
+    }
+
+    if (celebracion) {
+      this.celebrate(celebracion)
+    }
+
+    if (shareUrl) {
+      const shareButton = document.createElement("button")
+      shareButton.type = "button"
+      shareButton.className = "share-button"
+      shareButton.textContent = `📸 ${t("compartir", "Compartir mi resultado")}`
+      shareButton.addEventListener("click", () => this.shareResult(shareUrl))
+      resultDiv.appendChild(shareButton)
+    }
+  }
+
+  // Cuenta regresiva del voucher flash; se detiene sola cuando el mensaje se limpia
+  startFlashCountdown(segundos) {
+    const cuenta = document.createElement("div")
+    cuenta.className = "voucher-flash-countdown"
+    this.elements.resultMessage.appendChild(cuenta)
+
+    const vence = Date.now() + segundos * 1000
+    const actualizar = () => {
+      const restante = Math.max(0, Math.round((vence - Date.now()) / 1000))
+      const horas = Math.floor(restante / 3600)
+      const minutos = String(Math.floor((restante % 3600) / 60)).padStart(2, "0")
+      const segs = String(restante % 60).padStart(2, "0")
+      cuenta.textContent = t("flash_cuenta", "⚡ Voucher flash: vence en {tiempo}", { tiempo: `${horas}:${minutos}:${segs}` })
+      if (restante === 0 || !cuenta.isConnected) clearInterval(intervalo)
+    }
+    const intervalo = setInterval(actualizar, 1000)
+    actualizar()
+  }
+
+  // Celebrar la victoria: titular y oferta arriba del mensaje, confeti y sonido
+  celebrate(celebracion) {
+    const resultDiv = this.elements.resultMessage
+
+    if (celebracion.upsell) {
+      const upsell = document.createElement("div")
+      upsell.className = "celebration-upsell"
+      upsell.textContent = celebracion.upsell
+      resultDiv.prepend(upsell)
+    }
+    if (celebracion.titular) {
+      const titular = document.createElement("div")
+      titular.className = "celebration-headline"
+      titular.textContent = celebracion.titular
+      resultDiv.prepend(titular)
+    }
+
+    if (celebracion.sonido_url) {
+      // Los navegadores pueden bloquear el audio; la celebración sigue sin sonido
+      new Audio(celebracion.sonido_url).play().catch(() => {})
+    }
+
+    const piezas = { clasico: ["■", "●", "▲"], queso: ["🧀"], estrellas: ["⭐", "✨"] }[celebracion.confeti]
+    if (!piezas || window.matchMedia("(prefers-reduced-motion: reduce)").matches) return
+
+    const colores = ["#F4B400", "#E53935", "#43A047", "#1E88E5", "#8E24AA"]
+    const capa = document.createElement("div")
+    capa.className = "confetti"
+    for (let i = 0; i < 60; i++) {
+      const pieza = document.createElement("span")
+      pieza.textContent = piezas[i % piezas.length]
+      pieza.style.left = `${Math.random() * 100}%`
+      pieza.style.color = colores[i % colores.length]
+      pieza.style.animationDelay = `${Math.random() * 0.8}s`
+      pieza.style.animationDuration = `${2 + Math.random() * 1.5}s`
+      capa.appendChild(pieza)
+    }
+    document.body.appendChild(capa)
+    setTimeout(() => capa.remove(), 4500)
+  }
+
+  // Compartir la tarjeta del resultado: en el celular como imagen (historias), si no
+  // se puede se abre la imagen para descargarla
+  async shareResult(shareUrl) {
+    const storyUrl = `${shareUrl}?formato=historia`
+    try {
+      const response = await fetch(storyUrl)
+      const file = new File([await response.blob()], "cheesehouse.png", { type: "image/png" })
+      if (navigator.canShare && navigator.canShare({ files: [file] })) {
+        await navigator.share({ files: [file], url: window.location.origin })
+        return
+      }
+    } catch (error) {
+      if (error.name === "AbortError") return // El usuario cerró el menú de compartir
+      console.error("Error compartiendo resultado:", error)
+    }
+    window.open(storyUrl, "_blank")
   }
 
   // Mostrar mensaje de error
@@ -361,9 +753,9 @@ class TimingGame {
     const resultDiv = this.elements.resultMessage
     resultDiv.className = "result-message lose-message"
     resultDiv.innerHTML = `
-            ❌ <strong>Error al enviar datos</strong><br>
-            Por favor intenta nuevamente.<br>
-            <small>Si el problema persiste, contacta al personal.</small>
+            ❌ <strong>${t("error_titulo", "Error al enviar datos")}</strong><br>
+            ${t("error_detalle", "Por favor intenta nuevamente.")}<br>
+            <small>${t("error_contacto", "Si el problema persiste, contacta al personal.")}</small>
         `
   }
 
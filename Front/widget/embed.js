@@ -0,0 +1,237 @@
+// Juego del Timing embebible. El servidor envuelve este archivo en una función que
+// recibe WIDGET = { api, restaurante, idioma } con la dirección de la API de la clave.
+// Uso: <div id="cheesehouse-juego"></div><script src=".../api/widget/CLAVE/embed.js" async></script>
+"use strict"
+
+const TEXTOS = {
+  es: {
+    titulo: "Juego del Timing",
+    detene_en: "Frená el cronómetro en",
+    segundos: "segundos",
+    iniciar: "Iniciar",
+    detener: "Detener",
+    ganaste: "¡GANASTE!",
+    perdiste: "¡Casi!",
+    form_titulo: "Dejanos tus datos y te mandamos el descuento por WhatsApp",
+    nombre: "Nombre",
+    apellido: "Apellido",
+    telefono: "Teléfono",
+    muro: "Mostrar mi nombre en el muro de ganadores",
+    enviar: "Enviar",
+    enviando: "Enviando...",
+    error: "No pudimos procesar tu juego, probá de nuevo.",
+    otra_vez: "Jugar de nuevo",
+    flash: "⚡ Voucher flash, vence:",
+  },
+  en: {
+    titulo: "Timing Game",
+    detene_en: "Stop the timer at",
+    segundos: "seconds",
+    iniciar: "Start",
+    detener: "Stop",
+    ganaste: "YOU WON!",
+    perdiste: "So close!",
+    form_titulo: "Leave your details and we'll send your discount on WhatsApp",
+    nombre: "First name",
+    apellido: "Last name",
+    telefono: "Phone",
+    muro: "Show my name on the winners wall",
+    enviar: "Send",
+    enviando: "Sending...",
+    error: "We couldn't process your game, please try again.",
+    otra_vez: "Play again",
+    flash: "⚡ Flash voucher, expires:",
+  },
+}
+
+const ESTILOS = `
+.chw { font-family: system-ui, sans-serif; max-width: 360px; margin: 0 auto; padding: 1.25rem;
+  border-radius: 16px; background: var(--chw-fondo, #F4B400); color: var(--chw-tinta, #3E2723); text-align: center; }
+.chw h3 { margin: 0 0 .5rem; }
+.chw .chw-objetivo { font-size: 2.5rem; font-weight: 700; margin: .25rem 0; }
+.chw .chw-reloj { font-size: 2rem; font-variant-numeric: tabular-nums; margin: .75rem 0; }
+.chw button { border: none; border-radius: 999px; padding: .6rem 1.4rem; font-size: 1rem; font-weight: 600;
+  cursor: pointer; background: var(--chw-tinta, #3E2723); color: var(--chw-fondo, #F4B400); }
+.chw button:disabled { opacity: .6; cursor: default; }
+.chw form { display: grid; gap: .5rem; margin-top: .75rem; text-align: left; }
+.chw input[type=text], .chw input[type=tel] { padding: .6rem; border-radius: 8px; border: 1px solid rgba(0,0,0,.2); font-size: 1rem; }
+.chw label { font-size: .85rem; display: flex; gap: .4rem; align-items: flex-start; }
+.chw .chw-mensaje { margin-top: .75rem; }
+`
+
+const script = document.currentScript
+const locale = (navigator.language || WIDGET.idioma || "es").slice(0, 2).toLowerCase()
+const t = TEXTOS[locale] || TEXTOS[WIDGET.idioma] || TEXTOS.es
+
+function api(ruta, opciones) {
+  return fetch(WIDGET.api + ruta, opciones).then((respuesta) => respuesta.json())
+}
+
+function crearContenedor() {
+  const existente = document.getElementById("cheesehouse-juego")
+  if (existente) return existente
+  const div = document.createElement("div")
+  div.id = "cheesehouse-juego"
+  script.parentNode.insertBefore(div, script)
+  return div
+}
+
+function montar() {
+  const contenedor = crearContenedor()
+  const raiz = contenedor.attachShadow ? contenedor.attachShadow({ mode: "open" }) : contenedor
+  raiz.innerHTML = `<style>${ESTILOS}</style><div class="chw"></div>`
+  const juego = raiz.querySelector(".chw")
+
+  let ronda = null
+  let tolerancia = 0
+  let inicio = 0
+  let intervalo = null
+
+  api("/config").then((data) => {
+    if (data.success && data.config) tolerancia = data.config.tolerancia || 0
+    if (data.success && data.branding) {
+      if (data.branding.color_primario) juego.style.setProperty("--chw-fondo", data.branding.color_primario)
+      if (data.branding.color_secundario) juego.style.setProperty("--chw-tinta", data.branding.color_secundario)
+    }
+  })
+
+  function nuevaRonda() {
+    juego.innerHTML = `<h3>${t.titulo} · ${WIDGET.restaurante}</h3><p>${t.detene_en}</p>
+      <div class="chw-objetivo">…</div><p>${t.segundos}</p>
+      <div class="chw-reloj">0.00</div><button type="button" disabled>${t.iniciar}</button>`
+    const boton = juego.querySelector("button")
+    const reloj = juego.querySelector(".chw-reloj")
+
+    api("/target")
+      .then((data) => {
+        if (!data.success) throw new Error(data.message)
+        ronda = { objetivo: data.target_time, token: data.target_token }
+        juego.querySelector(".chw-objetivo").textContent = Number(data.target_time).toFixed(1)
+        boton.disabled = false
+      })
+      .catch(() => mostrarError())
+
+    boton.addEventListener("click", () => {
+      if (!intervalo) {
+        inicio = performance.now()
+        ronda.sesion = api("/start", {
+          method: "POST",
+          headers: { "Content-Type": "application/json" },
+          body: JSON.stringify({ token_objetivo: ronda.token }),
+        }).then((data) => {
+          if (!data.success) throw new Error(data.message)
+          return data.session_token
+        })
+        intervalo = setInterval(() => {
+          reloj.textContent = ((performance.now() - inicio) / 1000).toFixed(2)
+        }, 10)
+        boton.textContent = t.detener
+        return
+      }
+      clearInterval(intervalo)
+      intervalo = null
+      ronda.obtenido = Number(((performance.now() - inicio) / 1000).toFixed(2))
+      // El servidor mide la partida entre /start y /stop y la compara con la del navegador
+      ronda.sesion = ronda.sesion.then((token) =>
+        api("/stop", {
+          method: "POST",
+          headers: { "Content-Type": "application/json" },
+          body: JSON.stringify({ token_sesion: token }),
+        }).then((data) => {
+          if (!data.success) throw new Error(data.message)
+          return token
+        }),
+      )
+      reloj.textContent = ronda.obtenido.toFixed(2)
+      mostrarFormulario()
+    })
+  }
+
+  function mostrarFormulario() {
+    const gano = Math.abs(ronda.obtenido - ronda.objetivo) <= tolerancia
+    juego.insertAdjacentHTML(
+      "beforeend",
+      `<h3>${gano ? t.ganaste : t.perdiste}</h3><p>${t.form_titulo}</p>
+      <form>
+        <input type="text" name="nombre" placeholder="${t.nombre}" required minlength="2" maxlength="50">
+        <input type="text" name="apellido" placeholder="${t.apellido}" required minlength="2" maxlength="50">
+        <input type="tel" name="telefono" placeholder="${t.telefono}" required>
+        <label><input type="checkbox" name="mostrar_en_muro"> ${t.muro}</label>
+        <button type="submit">${t.enviar}</button>
+      </form><div class="chw-mensaje"></div>`,
+    )
+    juego.querySelector(".chw > button").remove()
+
+    const form = juego.querySelector("form")
+    form.addEventListener("submit", (evento) => {
+      evento.preventDefault()
+      const enviar = form.querySelector("button")
+      enviar.disabled = true
+      enviar.textContent = t.enviando
+
+      ronda.sesion
+        .then((token) =>
+          api("/submit", {
+            method: "POST",
+            headers: { "Content-Type": "application/json" },
+            body: JSON.stringify({
+              cliente: {
+                nombre: form.nombre.value.trim(),
+                apellido: form.apellido.value.trim(),
+                telefono: form.telefono.value.trim(),
+                idioma: TEXTOS[locale] ? locale : undefined,
+                mostrar_en_muro: form.mostrar_en_muro.checked,
+              },
+              resultado: {
+                gano,
+                tiempo_objetivo: ronda.objetivo,
+                tiempo_obtenido: ronda.obtenido,
+                token_sesion: token,
+              },
+            }),
+          }),
+        )
+        .then((data) => {
+          form.remove()
+          const mensaje = juego.querySelector(".chw-mensaje")
+          mensaje.textContent = data.message || t.error
+          if (data.flash) {
+            mensaje.append(document.createElement("br"), `${t.flash} ${data.vencimiento_texto}`)
+          }
+          if (data.recomendacion) {
+            mensaje.append(document.createElement("br"), data.recomendacion.mensaje)
+          }
+          const celebracion = data.celebracion || {}
+          for (const texto of [celebracion.upsell, celebracion.titular]) {
+            if (!texto) continue
+            const linea = document.createElement("strong")
+            linea.textContent = texto
+            mensaje.prepend(linea, document.createElement("br"))
+          }
+          agregarBotonOtraVez()
+        })
+        .catch(() => mostrarError())
+    })
+  }
+
+  function agregarBotonOtraVez() {
+    const boton = document.createElement("button")
+    boton.type = "button"
+    boton.textContent = t.otra_vez
+    boton.addEventListener("click", nuevaRonda)
+    juego.appendChild(boton)
+  }
+
+  function mostrarError() {
+    juego.innerHTML = `<p class="chw-mensaje">${t.error}</p>`
+    agregarBotonOtraVez()
+  }
+
+  nuevaRonda()
+}
+
+if (document.readyState === "loading") {
+  document.addEventListener("DOMContentLoaded", montar)
+} else {
+  montar()
+}
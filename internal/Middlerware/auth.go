@@ -7,6 +7,7 @@ import (
 
 	"github.com/gin-gonic/gin"
 
+	"CheeseHouse/internal/models"
 	"CheeseHouse/internal/services"
 )
 
@@ -25,80 +26,86 @@ func NewAuthMiddleware(authService *services.AuthService) *AuthMiddleware {
 // RequireAuth middleware que requiere autenticación
 func (m *AuthMiddleware) RequireAuth() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		// Obtener token del header Authorization
-		authHeader := c.GetHeader("Authorization")
-		if authHeader == "" {
-			// Si no hay header, buscar en cookie
-			token, err := c.Cookie("auth_token")
-			if err != nil || token == "" {
-				log.Printf("🔒 Acceso denegado: No hay token - IP: %s, Path: %s", c.ClientIP(), c.Request.URL.Path)
-				c.JSON(http.StatusUnauthorized, gin.H{
-					"error":   "No autorizado",
-					"message": "Token de autenticación requerido",
-				})
-				c.Abort()
-				return
-			}
-			authHeader = "Bearer " + token
-		}
-
-		// Extraer token del header "Bearer <token>"
-		tokenString := strings.TrimPrefix(authHeader, "Bearer ")
-		if tokenString == authHeader {
-			log.Printf("🔒 Acceso denegado: Formato de token inválido - IP: %s", c.ClientIP())
-			c.JSON(http.StatusUnauthorized, gin.H{
-				"error":   "No autorizado",
-				"message": "Formato de token inválido",
-			})
-			c.Abort()
-			return
-		}
-
-		// Validar token
-		claims, err := m.authService.ValidateToken(tokenString)
-		if err != nil {
-			log.Printf("🔒 Acceso denegado: Token inválido - %v - IP: %s", err, c.ClientIP())
-			c.JSON(http.StatusUnauthorized, gin.H{
-				"error":   "No autorizado",
-				"message": "Token inválido o expirado",
-			})
-			c.Abort()
+		if !m.autenticar(c) {
 			return
 		}
+		c.Next()
+	}
+}
 
-		// Obtener usuario completo
-		usuario, err := m.authService.GetUsuarioFromToken(tokenString)
-		if err != nil {
-			log.Printf("🔒 Acceso denegado: Usuario no encontrado - %v - IP: %s", err, c.ClientIP())
+// autenticar valida el token (header Authorization o cookie auth_token) y guarda el
+// usuario en el contexto. Si no es válido responde 401 y retorna false
+func (m *AuthMiddleware) autenticar(c *gin.Context) bool {
+	// Obtener token del header Authorization
+	authHeader := c.GetHeader("Authorization")
+	if authHeader == "" {
+		// Si no hay header, buscar en cookie
+		token, err := c.Cookie("auth_token")
+		if err != nil || token == "" {
+			log.Printf("🔒 Acceso denegado: No hay token - IP: %s, Path: %s", c.ClientIP(), c.Request.URL.Path)
 			c.JSON(http.StatusUnauthorized, gin.H{
 				"error":   "No autorizado",
-				"message": "Usuario no válido",
+				"message": "Token de autenticación requerido",
 			})
 			c.Abort()
-			return
+			return false
 		}
+		authHeader = "Bearer " + token
+	}
 
-		// Guardar información del usuario en el contexto
-		c.Set("user_id", claims.UserID)
-		c.Set("user_email", claims.Email)
-		c.Set("user_name", claims.Nombre)
-		c.Set("rol_id", claims.RolID)
-		c.Set("rol_name", claims.RolName)
-		c.Set("usuario", usuario)
+	// Extraer token del header "Bearer <token>"
+	tokenString := strings.TrimPrefix(authHeader, "Bearer ")
+	if tokenString == authHeader {
+		log.Printf("🔒 Acceso denegado: Formato de token inválido - IP: %s", c.ClientIP())
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error":   "No autorizado",
+			"message": "Formato de token inválido",
+		})
+		c.Abort()
+		return false
+	}
 
-		log.Printf("✅ Usuario autenticado: %s (%s) - Path: %s", claims.Email, claims.RolName, c.Request.URL.Path)
+	// Validar token
+	claims, err := m.authService.ValidateToken(tokenString)
+	if err != nil {
+		log.Printf("🔒 Acceso denegado: Token inválido - %v - IP: %s", err, c.ClientIP())
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error":   "No autorizado",
+			"message": "Token inválido o expirado",
+		})
+		c.Abort()
+		return false
+	}
 
-		c.Next()
+	// Obtener usuario completo
+	usuario, err := m.authService.GetUsuarioFromToken(tokenString)
+	if err != nil {
+		log.Printf("🔒 Acceso denegado: Usuario no encontrado - %v - IP: %s", err, c.ClientIP())
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error":   "No autorizado",
+			"message": "Usuario no válido",
+		})
+		c.Abort()
+		return false
 	}
+
+	// Guardar información del usuario en el contexto
+	c.Set("user_id", claims.UserID)
+	c.Set("user_email", claims.Email)
+	c.Set("user_name", claims.Nombre)
+	c.Set("rol_id", claims.RolID)
+	c.Set("rol_name", claims.RolName)
+	c.Set("usuario", usuario)
+
+	log.Printf("✅ Usuario autenticado: %s (%s) - Path: %s", claims.Email, claims.RolName, c.Request.URL.Path)
+	return true
 }
 
 // RequireAdmin middleware que requiere rol de administrador
 func (m *AuthMiddleware) RequireAdmin() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		// Primero verificar autenticación
-		m.RequireAuth()(c)
-
-		if c.IsAborted() {
+		// Primero verificar autenticación (salvo que ya lo haya hecho RequireAuth en el grupo)
+		if _, autenticado := c.Get("user_id"); !autenticado && !m.autenticar(c) {
 			return
 		}
 
@@ -119,6 +126,36 @@ func (m *AuthMiddleware) RequireAdmin() gin.HandlerFunc {
 	}
 }
 
+// RequirePermission middleware que requiere que el rol del usuario tenga el permiso
+// (models.PermisoCanjear, models.PermisoUsuarios...); el admin los tiene todos
+func (m *AuthMiddleware) RequirePermission(permiso string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if _, autenticado := c.Get("user_id"); !autenticado && !m.autenticar(c) {
+			return
+		}
+
+		// El rol del usuario recién leído, no el del token: un cambio de rol vale al instante
+		rolID := c.GetUint("rol_id")
+		if usuario, ok := c.Get("usuario"); ok {
+			if u, ok := usuario.(*models.Usuario); ok {
+				rolID = u.RolID
+			}
+		}
+		if !m.authService.RolTienePermiso(rolID, permiso) {
+			log.Printf("🔒 Acceso denegado: Falta el permiso %s - Usuario: %v, Rol: %v",
+				permiso, c.GetString("user_email"), c.GetString("rol_name"))
+			c.JSON(http.StatusForbidden, gin.H{
+				"error":   "Acceso denegado",
+				"message": "No tenés permiso para esta acción (" + permiso + ")",
+			})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
+
 // OptionalAuth middleware que permite autenticación opcional
 func (m *AuthMiddleware) OptionalAuth() gin.HandlerFunc {
 	return func(c *gin.Context) {
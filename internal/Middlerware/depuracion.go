@@ -0,0 +1,160 @@
+package middleware
+
+import (
+	"bytes"
+	"io"
+	"regexp"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxCuerpoCapturado bytes de cada cuerpo que se guardan; el resto se descarta
+const maxCuerpoCapturado = 16 * 1024
+
+var (
+	// campoTelefono valores de campos JSON con teléfono ("telefono", "telefono_cliente", "phone"...)
+	campoTelefono = regexp.MustCompile(`("(?:telefono|phone|whatsapp)[A-Za-z_]*"\s*:\s*")([^"]*)(")`)
+	// numeroTelefono secuencias de dígitos con largo de teléfono en cualquier otro lugar
+	numeroTelefono = regexp.MustCompile(`\+?\d{8,15}`)
+)
+
+// IntercambioCapturado request y respuesta guardados para depurar el frontend
+type IntercambioCapturado struct {
+	Momento    time.Time `json:"momento"`
+	Metodo     string    `json:"metodo"`
+	Ruta       string    `json:"ruta"`
+	Query      string    `json:"query,omitempty"`
+	Status     int       `json:"status"`
+	DuracionMs float64   `json:"duracion_ms"`
+	IP         string    `json:"ip"`
+	Request    string    `json:"request,omitempty"`
+	Response   string    `json:"response,omitempty"`
+	Truncado   bool      `json:"truncado,omitempty"`
+}
+
+// CapturaRequests guarda los últimos intercambios en un buffer circular. Es solo para
+// desarrollo: los cuerpos quedan en memoria, con los teléfonos enmascarados
+type CapturaRequests struct {
+	mu           sync.Mutex
+	intercambios []IntercambioCapturado
+	siguiente    int
+	lleno        bool
+}
+
+// NewCapturaRequests crea la captura que conserva los últimos capacidad intercambios
+func NewCapturaRequests(capacidad int) *CapturaRequests {
+	return &CapturaRequests{
+		intercambios: make([]IntercambioCapturado, capacidad),
+	}
+}
+
+// escritorCaptura copia lo que el handler escribe en la respuesta, hasta el máximo
+type escritorCaptura struct {
+	gin.ResponseWriter
+	cuerpo   bytes.Buffer
+	truncado bool
+}
+
+func (e *escritorCaptura) Write(datos []byte) (int, error) {
+	e.copiar(datos)
+	return e.ResponseWriter.Write(datos)
+}
+
+func (e *escritorCaptura) WriteString(s string) (int, error) {
+	e.copiar([]byte(s))
+	return e.ResponseWriter.WriteString(s)
+}
+
+func (e *escritorCaptura) copiar(datos []byte) {
+	libre := maxCuerpoCapturado - e.cuerpo.Len()
+	if len(datos) > libre {
+		datos = datos[:max(libre, 0)]
+		e.truncado = true
+	}
+	e.cuerpo.Write(datos)
+}
+
+// Middleware captura el cuerpo del request y de la respuesta de cada llamada del grupo
+func (cr *CapturaRequests) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		inicio := time.Now()
+
+		var pedido []byte
+		truncado := false
+		if c.Request.Body != nil {
+			leido, _ := io.ReadAll(c.Request.Body)
+			c.Request.Body = io.NopCloser(bytes.NewReader(leido))
+			pedido = leido
+			if len(pedido) > maxCuerpoCapturado {
+				pedido = pedido[:maxCuerpoCapturado]
+				truncado = true
+			}
+		}
+
+		escritor := &escritorCaptura{ResponseWriter: c.Writer}
+		c.Writer = escritor
+
+		c.Next()
+
+		cr.agregar(IntercambioCapturado{
+			Momento:    inicio,
+			Metodo:     c.Request.Method,
+			Ruta:       c.Request.URL.Path,
+			Query:      redactarTelefonos(c.Request.URL.RawQuery),
+			Status:     escritor.Status(),
+			DuracionMs: float64(time.Since(inicio).Microseconds()) / 1000,
+			IP:         c.ClientIP(),
+			Request:    redactarTelefonos(string(pedido)),
+			Response:   redactarTelefonos(escritor.cuerpo.String()),
+			Truncado:   truncado || escritor.truncado,
+		})
+	}
+}
+
+// Ultimos retorna los intercambios guardados, el más reciente primero
+func (cr *CapturaRequests) Ultimos() []IntercambioCapturado {
+	cr.mu.Lock()
+	defer cr.mu.Unlock()
+
+	total := cr.siguiente
+	if cr.lleno {
+		total = len(cr.intercambios)
+	}
+	ultimos := make([]IntercambioCapturado, 0, total)
+	for i := 1; i <= total; i++ {
+		indice := (cr.siguiente - i + len(cr.intercambios)) % len(cr.intercambios)
+		ultimos = append(ultimos, cr.intercambios[indice])
+	}
+	return ultimos
+}
+
+// Limpiar descarta los intercambios guardados
+func (cr *CapturaRequests) Limpiar() {
+	cr.mu.Lock()
+	defer cr.mu.Unlock()
+	cr.siguiente = 0
+	cr.lleno = false
+}
+
+func (cr *CapturaRequests) agregar(intercambio IntercambioCapturado) {
+	cr.mu.Lock()
+	defer cr.mu.Unlock()
+
+	cr.intercambios[cr.siguiente] = intercambio
+	cr.siguiente = (cr.siguiente + 1) % len(cr.intercambios)
+	if cr.siguiente == 0 {
+		cr.lleno = true
+	}
+}
+
+// redactarTelefonos enmascara los teléfonos dejando los últimos 4 dígitos, como en los
+// logs de auditoría
+func redactarTelefonos(texto string) string {
+	texto = campoTelefono.ReplaceAllStringFunc(texto, func(campo string) string {
+		partes := campoTelefono.FindStringSubmatch(campo)
+		return partes[1] + enmascararTelefono(partes[2]) + partes[3]
+	})
+	return numeroTelefono.ReplaceAllStringFunc(texto, enmascararTelefono)
+}
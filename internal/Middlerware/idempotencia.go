@@ -0,0 +1,105 @@
+package middleware
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+
+	"CheeseHouse/internal/api"
+	"CheeseHouse/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxLargoClaveIdempotencia largo máximo aceptado del header Idempotency-Key
+const maxLargoClaveIdempotencia = 100
+
+// Idempotencia devuelve la respuesta original a los reintentos de un request con el
+// header Idempotency-Key; los requests sin el header se procesan como siempre
+type Idempotencia struct {
+	servicio *services.IdempotenciaService
+}
+
+// NewIdempotencia crea el middleware de idempotencia
+func NewIdempotencia(servicio *services.IdempotenciaService) *Idempotencia {
+	return &Idempotencia{servicio: servicio}
+}
+
+// Middleware aplica la idempotencia a la ruta. Las respuestas repetidas llevan el header
+// Idempotent-Replayed: true
+func (i *Idempotencia) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		clave := strings.TrimSpace(c.GetHeader("Idempotency-Key"))
+		if clave == "" {
+			c.Next()
+			return
+		}
+		if len(clave) > maxLargoClaveIdempotencia {
+			responderError(c, http.StatusBadRequest, api.ErrorDatosInvalidos, "Idempotency-Key demasiado larga")
+			return
+		}
+
+		var cuerpo []byte
+		if c.Request.Body != nil {
+			cuerpo, _ = io.ReadAll(c.Request.Body)
+			c.Request.Body = io.NopCloser(bytes.NewReader(cuerpo))
+		}
+
+		reserva, previa, err := i.servicio.Reservar(clave, c.Request.URL.Path, cuerpo)
+		switch {
+		case errors.Is(err, services.ErrIdempotenciaOtroCuerpo):
+			responderError(c, http.StatusUnprocessableEntity, api.ErrorDatosInvalidos, err.Error())
+			return
+		case errors.Is(err, services.ErrIdempotenciaEnCurso):
+			c.Header("Retry-After", "1")
+			responderError(c, http.StatusConflict, api.ErrorNoDisponible, err.Error())
+			return
+		case err != nil:
+			// Sin la base no se puede garantizar nada; mejor procesar que rechazar la partida
+			log.Printf("⚠️  Idempotency-Key %s sin verificar: %v", clave, err)
+			c.Next()
+			return
+		case previa != nil:
+			log.Printf("🔁 Reintento con Idempotency-Key %s, se devuelve la respuesta original", clave)
+			c.Header("Idempotent-Replayed", "true")
+			c.Data(previa.Status, "application/json; charset=utf-8", []byte(previa.Respuesta))
+			c.Abort()
+			return
+		}
+
+		escritor := &escritorCaptura{ResponseWriter: c.Writer}
+		c.Writer = escritor
+		defer func() {
+			// Si el handler entra en pánico la clave no puede quedar tomada para siempre
+			if recuperado := recover(); recuperado != nil {
+				i.servicio.Liberar(reserva)
+				panic(recuperado)
+			}
+		}()
+
+		c.Next()
+
+		if escritor.truncado {
+			i.servicio.Liberar(reserva)
+			return
+		}
+		i.servicio.Completar(reserva, escritor.Status(), escritor.cuerpo.Bytes())
+	}
+}
+
+// responderError responde un error con el formato de la ruta: el sobre de /api/v1 o
+// success/message en el resto
+func responderError(c *gin.Context, status int, codigo, mensaje string) {
+	if strings.HasPrefix(c.FullPath(), "/api/v1/") {
+		api.Fallo(c, status, codigo, mensaje)
+	} else {
+		c.JSON(status, gin.H{
+			"success": false,
+			"message": mensaje,
+		})
+	}
+	c.Abort()
+}
@@ -0,0 +1,184 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"net/http"
+	"strings"
+	"time"
+
+	"CheeseHouse/internal/api"
+	"CheeseHouse/internal/models"
+	"CheeseHouse/internal/repository"
+	"CheeseHouse/internal/telefono"
+
+	"github.com/gin-gonic/gin"
+)
+
+// cubeta token bucket de una IP o un teléfono, guardado en el estado compartido
+type cubeta struct {
+	Fichas      float64   `json:"fichas"`
+	Actualizada time.Time `json:"actualizada"`
+}
+
+// errSinFichas otro envío gastó la última ficha mientras se procesaba este
+var errSinFichas = errors.New("sin fichas")
+
+// LimiteEnvios limita los envíos de partidas con un token bucket por IP y otro por
+// teléfono normalizado, para que nadie junte vouchers repitiendo el envío. Los límites se
+// leen en cada pedido, así un admin puede ajustarlos sin reiniciar. Las cubetas viven en
+// el estado compartido para que el límite valga entre todas las instancias. La IP es la
+// de ClientIP, que solo toma X-Forwarded-For de TRUSTED_PROXIES: detrás de un balanceador
+// sin configurarlo todos los jugadores comparten la cubeta del balanceador
+type LimiteEnvios struct {
+	estado  repository.EstadoCompartidoRepository
+	limites func() (porIP, porTelefono int) // Envíos por hora
+	rafaga  int
+}
+
+// NewLimiteEnvios crea el limitador; limites retorna los envíos por hora vigentes por IP
+// y por teléfono, y rafaga cuántos se aceptan seguidos antes de que rija ese ritmo
+func NewLimiteEnvios(estado repository.EstadoCompartidoRepository, limites func() (porIP, porTelefono int), rafaga int) *LimiteEnvios {
+	return &LimiteEnvios{
+		estado:  estado,
+		limites: limites,
+		rafaga:  max(rafaga, 1),
+	}
+}
+
+// Limit middleware para las rutas de envío de partidas; el teléfono sale del cuerpo
+// (cliente.telefono), que queda intacto para el handler
+func (l *LimiteEnvios) Limit() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ip := c.ClientIP()
+		telefono := telefonoDelEnvio(c)
+		porIP, porTelefono := l.limites()
+		ahora := time.Now()
+
+		claves := map[string]int{"envios:ip:" + ip: porIP}
+		if telefono != "" {
+			claves["envios:tel:"+telefono] = porTelefono
+		}
+
+		// Se revisan todas antes de descontar, así un envío rechazado no gasta fichas
+		var espera time.Duration
+		for clave, limite := range claves {
+			guardada, err := l.estado.Obtener(clave)
+			if err != nil {
+				// Sin estado compartido no se frena a los jugadores: se sigue sin límite
+				log.Printf("⚠️  Error leyendo el límite de envíos, se deja pasar: %v", err)
+				c.Next()
+				return
+			}
+			_, falta := l.recargar(guardada, limite, ahora)
+			espera = max(espera, falta)
+		}
+		if espera > 0 {
+			l.rechazar(c, ip, telefono, espera)
+			return
+		}
+
+		for clave, limite := range claves {
+			err := repository.ActualizarCompartido(l.estado, clave, func(valor string, existe bool) (string, time.Time, error) {
+				var guardada *models.EstadoCompartido
+				if existe {
+					guardada = &models.EstadoCompartido{Valor: valor}
+				}
+				cb, falta := l.recargar(guardada, limite, ahora)
+				if falta > 0 {
+					espera = falta
+					return "", time.Time{}, errSinFichas
+				}
+				cb.Fichas--
+				return l.codificar(cb, limite)
+			})
+			if errors.Is(err, errSinFichas) {
+				l.rechazar(c, ip, telefono, espera)
+				return
+			}
+			if err != nil {
+				log.Printf("⚠️  Error descontando el límite de envíos: %v", err)
+			}
+		}
+
+		c.Next()
+	}
+}
+
+// recargar arma la cubeta guardada (llena si no hay) con las fichas ganadas desde la
+// última vez y retorna cuánto falta para tener una ficha (0 si ya la tiene)
+func (l *LimiteEnvios) recargar(guardada *models.EstadoCompartido, porHora int, ahora time.Time) (*cubeta, time.Duration) {
+	capacidad, ritmo := l.ritmo(porHora)
+
+	cb := &cubeta{Fichas: capacidad, Actualizada: ahora}
+	if guardada != nil && json.Unmarshal([]byte(guardada.Valor), cb) == nil {
+		cb.Fichas = math.Min(capacidad, cb.Fichas+ahora.Sub(cb.Actualizada).Seconds()*ritmo)
+		cb.Actualizada = ahora
+	}
+
+	if cb.Fichas >= 1 {
+		return cb, 0
+	}
+	return cb, time.Duration((1 - cb.Fichas) / ritmo * float64(time.Second))
+}
+
+// codificar serializa la cubeta; vence cuando se habría llenado de nuevo, que equivale a
+// no tener cubeta
+func (l *LimiteEnvios) codificar(cb *cubeta, porHora int) (string, time.Time, error) {
+	capacidad, ritmo := l.ritmo(porHora)
+	valor, err := json.Marshal(cb)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	llenado := time.Duration((capacidad - cb.Fichas) / ritmo * float64(time.Second))
+	return string(valor), cb.Actualizada.Add(llenado), nil
+}
+
+// ritmo capacidad de la cubeta y fichas por segundo para el límite por hora
+func (l *LimiteEnvios) ritmo(porHora int) (float64, float64) {
+	porHora = max(porHora, 1)
+	return float64(min(l.rafaga, porHora)), float64(porHora) / time.Hour.Seconds()
+}
+
+// rechazar responde 429 indicando cuánto esperar
+func (l *LimiteEnvios) rechazar(c *gin.Context, ip, telefono string, espera time.Duration) {
+	segundos := int(math.Ceil(espera.Seconds()))
+	log.Printf("⛔ Envío de partida limitado - IP: %s, Teléfono: %s, Reintentar en %ds",
+		ip, enmascararTelefono(telefono), segundos)
+
+	c.Header("Retry-After", fmt.Sprintf("%d", segundos))
+	responderError(c, http.StatusTooManyRequests, api.ErrorDemasiados, "Demasiadas partidas enviadas, intentá nuevamente más tarde")
+}
+
+// telefonoDelEnvio lee el teléfono del cuerpo JSON y lo normaliza para que "011 15..." y
+// "+54 9 11..." cuenten como el mismo; si no se puede normalizar se usa tal cual
+func telefonoDelEnvio(c *gin.Context) string {
+	if c.Request.Body == nil {
+		return ""
+	}
+	cuerpo, err := io.ReadAll(c.Request.Body)
+	c.Request.Body.Close()
+	c.Request.Body = io.NopCloser(bytes.NewReader(cuerpo))
+	if err != nil {
+		return ""
+	}
+
+	var envio struct {
+		Cliente struct {
+			Telefono string `json:"telefono"`
+		} `json:"cliente"`
+	}
+	if json.Unmarshal(cuerpo, &envio) != nil {
+		return ""
+	}
+	crudo := strings.TrimSpace(envio.Cliente.Telefono)
+	if normalizado, err := telefono.Normalizar(crudo); err == nil {
+		return normalizado
+	}
+	return crudo
+}
@@ -2,6 +2,7 @@ package middleware
 
 import (
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -50,7 +51,7 @@ func RequestLogger() gin.HandlerFunc {
 			statusEmoji = "❌"
 		}
 
-		return fmt.Sprintf("%s %s[%s]%s %s %3d %s| %13v | %15s | %s%-7s%s %s %#v %s\n%s",
+		return fmt.Sprintf("%s %s[%s]%s %s %3d %s| %13v %s| %15s | %s%-7s%s %#v\n%s",
 			statusEmoji,
 			statusColor,
 			param.TimeStamp.Format("2006/01/02 - 15:04:05"),
@@ -66,6 +67,35 @@ func RequestLogger() gin.HandlerFunc {
 	})
 }
 
+// StructuredRequestLogger logging de requests para LOG_FORMAT plain/json: una línea
+// "Clave: valor" por request que el escritor de internal/logging convierte en campos.
+// El emoji inicial solo marca el nivel (warn para 4xx, error para 5xx)
+func StructuredRequestLogger() gin.HandlerFunc {
+	return gin.LoggerWithFormatter(func(param gin.LogFormatterParams) string {
+		nivel := ""
+		switch {
+		case param.StatusCode >= 500:
+			nivel = "❌ "
+		case param.StatusCode >= 400:
+			nivel = "⚠️ "
+		}
+
+		linea := fmt.Sprintf("%srequest | Method: %s | Path: %s | Status: %d | Latency ms: %.3f | IP: %s | Proto: %s",
+			nivel,
+			param.Method,
+			param.Path,
+			param.StatusCode,
+			float64(param.Latency.Microseconds())/1000,
+			param.ClientIP,
+			param.Request.Proto,
+		)
+		if param.ErrorMessage != "" {
+			linea += " | Error: " + strings.TrimSpace(param.ErrorMessage)
+		}
+		return linea + "\n"
+	})
+}
+
 // APILogger middleware específico para APIs con más detalles
 func APILogger() gin.HandlerFunc {
 	return func(c *gin.Context) {
@@ -87,7 +117,7 @@ func APILogger() gin.HandlerFunc {
 		}
 
 		// Log detallado
-		fmt.Printf("🔍 API Request | "+
+		fmt.Fprintf(gin.DefaultWriter, "🔍 API Request | "+
 			"Time: %s | "+
 			"Status: %d | "+
 			"Latency: %v | "+
@@ -118,11 +148,11 @@ func ErrorLogger() gin.HandlerFunc {
 		// Si hay errores, logearlos
 		if len(c.Errors) > 0 {
 			for _, err := range c.Errors {
-				fmt.Printf("❌ ERROR | "+
+				fmt.Fprintf(gin.DefaultWriter, "❌ ERROR | "+
 					"Time: %s | "+
 					"Path: %s | "+
 					"IP: %s | "+
-					"Type: %s | "+
+					"Type: %v | "+
 					"Error: %v\n",
 					time.Now().Format("2006/01/02 15:04:05"),
 					c.Request.URL.Path,
@@ -142,7 +172,7 @@ func SecurityLogger() gin.HandlerFunc {
 
 		// Loguear intentos de acceso no autorizados
 		if c.Writer.Status() == 401 || c.Writer.Status() == 403 {
-			fmt.Printf("🔒 SECURITY | "+
+			fmt.Fprintf(gin.DefaultWriter, "🔒 SECURITY | "+
 				"Time: %s | "+
 				"Status: %d | "+
 				"IP: %s | "+
@@ -160,28 +190,35 @@ func SecurityLogger() gin.HandlerFunc {
 	}
 }
 
-// PerformanceLogger middleware para monitorear performance
-func PerformanceLogger(slowThreshold time.Duration) gin.HandlerFunc {
+// PerformanceLogger middleware para monitorear performance: registra la latencia
+// de cada ruta en las métricas y loguea los requests lentos junto con las
+// consultas lentas a la base que ocurrieron mientras corrían
+func PerformanceLogger(metricas *Metricas) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		startTime := time.Now()
+		ruta := c.FullPath()
+		id := metricas.iniciar(c.Request.Method, ruta)
 
 		c.Next()
 
 		latency := time.Since(startTime)
+		consultasLentas := metricas.finalizar(id, c.Request.Method, ruta, latency, c.Writer.Status())
 
 		// Loguear solo requests lentos
-		if latency > slowThreshold {
-			fmt.Printf("⚡ SLOW REQUEST | "+
+		if latency > metricas.UmbralLento() {
+			fmt.Fprintf(gin.DefaultWriter, "⚡ SLOW REQUEST | "+
 				"Time: %s | "+
 				"Latency: %v | "+
 				"Threshold: %v | "+
 				"Path: %s | "+
-				"Method: %s\n",
+				"Method: %s | "+
+				"Slow queries: %d\n",
 				startTime.Format("15:04:05"),
 				latency,
-				slowThreshold,
+				metricas.UmbralLento(),
 				c.Request.URL.Path,
 				c.Request.Method,
+				consultasLentas,
 			)
 		}
 	}
@@ -0,0 +1,245 @@
+package middleware
+
+import (
+	"math"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	// muestrasPorRuta latencias recientes que se guardan por ruta para calcular percentiles
+	muestrasPorRuta = 1000
+	// maxConsultasLentas consultas lentas recientes que se conservan para el endpoint interno
+	maxConsultasLentas = 200
+	// rutaSinRegistrar agrupa los requests que no coinciden con ninguna ruta (archivos estáticos, 404)
+	rutaSinRegistrar = "(sin ruta)"
+)
+
+// MetricaRuta resumen de latencias de una ruta
+type MetricaRuta struct {
+	Metodo          string  `json:"metodo"`
+	Ruta            string  `json:"ruta"`
+	Requests        int64   `json:"requests"`
+	P50Ms           float64 `json:"p50_ms"`
+	P95Ms           float64 `json:"p95_ms"`
+	MaxMs           float64 `json:"max_ms"`
+	Lentos          int64   `json:"lentos"`
+	Errores         int64   `json:"errores"`
+	ConsultasLentas int64   `json:"consultas_lentas"`
+}
+
+// ConsultaLenta consulta SQL que superó el umbral, con las rutas que estaban en curso
+type ConsultaLenta struct {
+	SQL        string    `json:"sql"`
+	DuracionMs float64   `json:"duracion_ms"`
+	Filas      int64     `json:"filas"`
+	Momento    time.Time `json:"momento"`
+	Rutas      []string  `json:"rutas"`
+}
+
+// ResumenMetricas lo que expone el endpoint interno de métricas
+type ResumenMetricas struct {
+	Desde           time.Time       `json:"desde"`
+	UmbralLentoMs   float64         `json:"umbral_lento_ms"`
+	RequestsEnCurso int             `json:"requests_en_curso"`
+	Rutas           []MetricaRuta   `json:"rutas"`
+	ConsultasLentas []ConsultaLenta `json:"consultas_lentas"`
+}
+
+// metricaRuta acumulados de una ruta; latencias es un buffer circular
+type metricaRuta struct {
+	metodo          string
+	ruta            string
+	latencias       []time.Duration
+	siguiente       int
+	requests        int64
+	lentos          int64
+	errores         int64
+	consultasLentas int64
+	maxima          time.Duration
+}
+
+// requestEnCurso request activo; acumula las consultas lentas que ocurren mientras corre
+type requestEnCurso struct {
+	clave           string
+	consultasLentas int
+}
+
+// Metricas registra la latencia de cada ruta y las consultas lentas de la base.
+// Como los repositorios no reciben el contexto del request, una consulta lenta
+// se asocia a todas las rutas que estaban en curso en ese momento
+type Metricas struct {
+	umbralLento time.Duration
+	desde       time.Time
+
+	mu              sync.Mutex
+	rutas           map[string]*metricaRuta
+	enCurso         map[uint64]*requestEnCurso
+	proximoID       uint64
+	consultasLentas []ConsultaLenta
+}
+
+// NewMetricas crea el registro de métricas; los requests que superan umbralLento se cuentan como lentos
+func NewMetricas(umbralLento time.Duration) *Metricas {
+	return &Metricas{
+		umbralLento: umbralLento,
+		desde:       time.Now(),
+		rutas:       make(map[string]*metricaRuta),
+		enCurso:     make(map[uint64]*requestEnCurso),
+	}
+}
+
+// UmbralLento retorna el tiempo a partir del cual un request se considera lento
+func (m *Metricas) UmbralLento() time.Duration {
+	return m.umbralLento
+}
+
+// iniciar marca un request como en curso y retorna su identificador
+func (m *Metricas) iniciar(metodo, ruta string) uint64 {
+	if ruta == "" {
+		ruta = rutaSinRegistrar
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.proximoID++
+	m.enCurso[m.proximoID] = &requestEnCurso{clave: metodo + " " + ruta}
+	return m.proximoID
+}
+
+// finalizar registra la latencia del request y retorna cuántas consultas lentas ocurrieron mientras corría
+func (m *Metricas) finalizar(id uint64, metodo, ruta string, latencia time.Duration, status int) int {
+	if ruta == "" {
+		ruta = rutaSinRegistrar
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	consultas := 0
+	if activo, ok := m.enCurso[id]; ok {
+		consultas = activo.consultasLentas
+		delete(m.enCurso, id)
+	}
+
+	clave := metodo + " " + ruta
+	metrica, ok := m.rutas[clave]
+	if !ok {
+		metrica = &metricaRuta{
+			metodo:    metodo,
+			ruta:      ruta,
+			latencias: make([]time.Duration, 0, 64),
+		}
+		m.rutas[clave] = metrica
+	}
+
+	if len(metrica.latencias) < muestrasPorRuta {
+		metrica.latencias = append(metrica.latencias, latencia)
+	} else {
+		metrica.latencias[metrica.siguiente] = latencia
+		metrica.siguiente = (metrica.siguiente + 1) % muestrasPorRuta
+	}
+
+	metrica.requests++
+	if latencia > metrica.maxima {
+		metrica.maxima = latencia
+	}
+	if latencia > m.umbralLento {
+		metrica.lentos++
+	}
+	if status >= 500 {
+		metrica.errores++
+	}
+	metrica.consultasLentas += int64(consultas)
+
+	return consultas
+}
+
+// RegistrarConsultaLenta guarda una consulta lenta y la asocia a los requests en curso.
+// Se registra en la base con db.ObservarConsultasLentas
+func (m *Metricas) RegistrarConsultaLenta(sql string, duracion time.Duration, filas int64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	vistas := make(map[string]bool)
+	rutas := []string{}
+	for _, activo := range m.enCurso {
+		activo.consultasLentas++
+		if !vistas[activo.clave] {
+			vistas[activo.clave] = true
+			rutas = append(rutas, activo.clave)
+		}
+	}
+	sort.Strings(rutas)
+
+	m.consultasLentas = append(m.consultasLentas, ConsultaLenta{
+		SQL:        sql,
+		DuracionMs: milisegundos(duracion),
+		Filas:      filas,
+		Momento:    time.Now(),
+		Rutas:      rutas,
+	})
+	if len(m.consultasLentas) > maxConsultasLentas {
+		m.consultasLentas = m.consultasLentas[len(m.consultasLentas)-maxConsultasLentas:]
+	}
+}
+
+// Resumen calcula p50/p95 por ruta (las más lentas primero) y lista las consultas lentas recientes
+func (m *Metricas) Resumen() ResumenMetricas {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	resumen := ResumenMetricas{
+		Desde:           m.desde,
+		UmbralLentoMs:   milisegundos(m.umbralLento),
+		RequestsEnCurso: len(m.enCurso),
+		Rutas:           make([]MetricaRuta, 0, len(m.rutas)),
+		ConsultasLentas: make([]ConsultaLenta, 0, len(m.consultasLentas)),
+	}
+
+	for _, metrica := range m.rutas {
+		ordenadas := append([]time.Duration(nil), metrica.latencias...)
+		sort.Slice(ordenadas, func(i, j int) bool { return ordenadas[i] < ordenadas[j] })
+
+		resumen.Rutas = append(resumen.Rutas, MetricaRuta{
+			Metodo:          metrica.metodo,
+			Ruta:            metrica.ruta,
+			Requests:        metrica.requests,
+			P50Ms:           milisegundos(percentil(ordenadas, 0.50)),
+			P95Ms:           milisegundos(percentil(ordenadas, 0.95)),
+			MaxMs:           milisegundos(metrica.maxima),
+			Lentos:          metrica.lentos,
+			Errores:         metrica.errores,
+			ConsultasLentas: metrica.consultasLentas,
+		})
+	}
+	sort.Slice(resumen.Rutas, func(i, j int) bool {
+		return resumen.Rutas[i].P95Ms > resumen.Rutas[j].P95Ms
+	})
+
+	// Las más recientes primero
+	for i := len(m.consultasLentas) - 1; i >= 0; i-- {
+		resumen.ConsultasLentas = append(resumen.ConsultasLentas, m.consultasLentas[i])
+	}
+
+	return resumen
+}
+
+// percentil toma el valor por rango más cercano sobre latencias ya ordenadas
+func percentil(ordenadas []time.Duration, p float64) time.Duration {
+	if len(ordenadas) == 0 {
+		return 0
+	}
+	indice := int(math.Ceil(p*float64(len(ordenadas)))) - 1
+	if indice < 0 {
+		indice = 0
+	}
+	return ordenadas[indice]
+}
+
+// milisegundos convierte una duración a milisegundos con un decimal
+func milisegundos(d time.Duration) float64 {
+	return math.Round(float64(d)/float64(time.Millisecond)*10) / 10
+}
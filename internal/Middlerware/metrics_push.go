@@ -0,0 +1,284 @@
+package middleware
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"CheeseHouse/internal/config"
+)
+
+const (
+	// intervaloEnvioMetricas se usa si METRICS_PUSH_INTERVAL_SECONDS no es válido
+	intervaloEnvioMetricas = 15 * time.Second
+	// maxPaqueteStatsD bytes por datagrama, para no fragmentar en una red con MTU de 1500
+	maxPaqueteStatsD = 1432
+)
+
+// EmisorMetricas envía periódicamente las mismas métricas de /api/admin/metricas a StatsD
+// y/o a un Prometheus Pushgateway, para despliegues sin un Prometheus que las lea. Cada
+// instancia envía las suyas: en StatsD los contadores van como diferencias desde el envío
+// anterior y en el Pushgateway como acumulados agrupados por INSTANCE_ID
+type EmisorMetricas struct {
+	config    config.MetricsPushConfig
+	instancia string
+	metricas  *Metricas
+	client    *http.Client
+
+	// Acumulados del último envío a StatsD, para mandar solo la diferencia
+	anteriores map[string]int64
+	// Destinos que están fallando, para avisar una vez y no en cada envío
+	fallando map[string]bool
+
+	detener  chan struct{}
+	listo    chan struct{}
+	detenido sync.Once
+}
+
+// NewEmisorMetricas crea el emisor de métricas; no envía nada hasta Iniciar
+func NewEmisorMetricas(cfg *config.Config, metricas *Metricas) *EmisorMetricas {
+	return &EmisorMetricas{
+		config:     cfg.MetricsPush,
+		instancia:  cfg.Scaling.InstanceID,
+		metricas:   metricas,
+		client:     &http.Client{Timeout: 10 * time.Second},
+		anteriores: make(map[string]int64),
+		fallando:   make(map[string]bool),
+		detener:    make(chan struct{}),
+		listo:      make(chan struct{}),
+	}
+}
+
+// Iniciar envía las métricas cada METRICS_PUSH_INTERVAL_SECONDS hasta Detener; sin
+// destinos configurados no hace nada
+func (e *EmisorMetricas) Iniciar() {
+	if !e.config.Habilitado() {
+		close(e.listo)
+		return
+	}
+	intervalo := e.config.Interval
+	if intervalo < time.Second {
+		intervalo = intervaloEnvioMetricas
+	}
+
+	go func() {
+		defer close(e.listo)
+		ticker := time.NewTicker(intervalo)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-e.detener:
+				// Último envío con lo que se acumuló desde el anterior
+				e.enviar()
+				return
+			case <-ticker.C:
+				e.enviar()
+			}
+		}
+	}()
+}
+
+// Detener hace un último envío y frena el emisor
+func (e *EmisorMetricas) Detener() {
+	e.detenido.Do(func() { close(e.detener) })
+	<-e.listo
+}
+
+// enviar manda el resumen actual a cada destino configurado
+func (e *EmisorMetricas) enviar() {
+	resumen := e.metricas.Resumen()
+	if e.config.StatsDAddr != "" {
+		e.registrarResultado("StatsD", e.enviarStatsD(resumen))
+	}
+	if e.config.PushgatewayURL != "" {
+		e.registrarResultado("Pushgateway", e.enviarPushgateway(resumen))
+	}
+}
+
+// registrarResultado avisa cuando un destino empieza a fallar y cuando se recupera
+func (e *EmisorMetricas) registrarResultado(destino string, err error) {
+	if err != nil {
+		if !e.fallando[destino] {
+			log.Printf("⚠️  No se pudieron enviar las métricas a %s: %v", destino, err)
+		}
+		e.fallando[destino] = true
+		return
+	}
+	if e.fallando[destino] {
+		log.Printf("📈 Métricas enviadas de nuevo a %s", destino)
+	}
+	e.fallando[destino] = false
+}
+
+// enviarStatsD manda por UDP un contador por ruta para requests, lentos, errores y
+// consultas lentas (la diferencia desde el envío anterior) y gauges con las latencias
+func (e *EmisorMetricas) enviarStatsD(resumen ResumenMetricas) error {
+	conexion, err := net.Dial("udp", e.config.StatsDAddr)
+	if err != nil {
+		return err
+	}
+	defer conexion.Close()
+
+	var paquete bytes.Buffer
+	agregar := func(linea string) error {
+		if paquete.Len() > 0 && paquete.Len()+1+len(linea) > maxPaqueteStatsD {
+			if _, err := conexion.Write(paquete.Bytes()); err != nil {
+				return err
+			}
+			paquete.Reset()
+		}
+		if paquete.Len() > 0 {
+			paquete.WriteByte('\n')
+		}
+		paquete.WriteString(linea)
+		return nil
+	}
+
+	prefijo := strings.TrimSuffix(e.config.Prefix, ".")
+	if prefijo != "" {
+		prefijo += "."
+	}
+	lineas := []string{fmt.Sprintf("%shttp.en_curso:%d|g", prefijo, resumen.RequestsEnCurso)}
+	for _, ruta := range resumen.Rutas {
+		nombre := prefijo + "http." + strings.ToLower(ruta.Metodo) + "." + nombreStatsD(ruta.Ruta)
+		contadores := []struct {
+			metrica string
+			valor   int64
+		}{
+			{"requests", ruta.Requests},
+			{"lentos", ruta.Lentos},
+			{"errores", ruta.Errores},
+			{"consultas_lentas", ruta.ConsultasLentas},
+		}
+		for _, contador := range contadores {
+			clave := nombre + "." + contador.metrica
+			diferencia := contador.valor - e.anteriores[clave]
+			if diferencia < 0 {
+				diferencia = contador.valor
+			}
+			e.anteriores[clave] = contador.valor
+			if diferencia > 0 {
+				lineas = append(lineas, fmt.Sprintf("%s:%d|c", clave, diferencia))
+			}
+		}
+		lineas = append(lineas,
+			fmt.Sprintf("%s.p50_ms:%s|g", nombre, numeroMetrica(ruta.P50Ms)),
+			fmt.Sprintf("%s.p95_ms:%s|g", nombre, numeroMetrica(ruta.P95Ms)),
+			fmt.Sprintf("%s.max_ms:%s|g", nombre, numeroMetrica(ruta.MaxMs)),
+		)
+	}
+
+	for _, linea := range lineas {
+		if err := agregar(linea); err != nil {
+			return err
+		}
+	}
+	if paquete.Len() > 0 {
+		if _, err := conexion.Write(paquete.Bytes()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// enviarPushgateway reemplaza el grupo de esta instancia en el Pushgateway con las
+// métricas en el formato de texto de Prometheus
+func (e *EmisorMetricas) enviarPushgateway(resumen ResumenMetricas) error {
+	prefijo := nombrePrometheus(e.config.Prefix)
+	if prefijo != "" {
+		prefijo += "_"
+	}
+
+	var cuerpo bytes.Buffer
+	fmt.Fprintf(&cuerpo, "# TYPE %shttp_requests_en_curso gauge\n%shttp_requests_en_curso %d\n",
+		prefijo, prefijo, resumen.RequestsEnCurso)
+
+	series := []struct {
+		nombre, tipo string
+		valor        func(MetricaRuta) string
+	}{
+		{"http_requests_total", "counter", func(r MetricaRuta) string { return strconv.FormatInt(r.Requests, 10) }},
+		{"http_requests_lentos_total", "counter", func(r MetricaRuta) string { return strconv.FormatInt(r.Lentos, 10) }},
+		{"http_requests_errores_total", "counter", func(r MetricaRuta) string { return strconv.FormatInt(r.Errores, 10) }},
+		{"http_consultas_lentas_total", "counter", func(r MetricaRuta) string { return strconv.FormatInt(r.ConsultasLentas, 10) }},
+		{"http_latencia_p50_ms", "gauge", func(r MetricaRuta) string { return numeroMetrica(r.P50Ms) }},
+		{"http_latencia_p95_ms", "gauge", func(r MetricaRuta) string { return numeroMetrica(r.P95Ms) }},
+		{"http_latencia_max_ms", "gauge", func(r MetricaRuta) string { return numeroMetrica(r.MaxMs) }},
+	}
+	for _, serie := range series {
+		fmt.Fprintf(&cuerpo, "# TYPE %s%s %s\n", prefijo, serie.nombre, serie.tipo)
+		for _, ruta := range resumen.Rutas {
+			fmt.Fprintf(&cuerpo, "%s%s{metodo=\"%s\",ruta=\"%s\"} %s\n", prefijo, serie.nombre,
+				etiquetaPrometheus(ruta.Metodo), etiquetaPrometheus(ruta.Ruta), serie.valor(ruta))
+		}
+	}
+
+	job := e.config.Job
+	if job == "" {
+		job = "cheesehouse"
+	}
+	destino := fmt.Sprintf("%s/metrics/job/%s/instance/%s", e.config.PushgatewayURL, url.PathEscape(job), url.PathEscape(e.instancia))
+	req, err := http.NewRequest(http.MethodPut, destino, &cuerpo)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "text/plain; version=0.0.4")
+
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusAccepted {
+		return fmt.Errorf("el Pushgateway respondió %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// nombreStatsD convierte la ruta en un segmento de nombre StatsD: /api/game/:id queda
+// api.game.id, "(sin ruta)" queda sin_ruta y "/" queda raiz
+func nombreStatsD(ruta string) string {
+	var nombre strings.Builder
+	for _, r := range strings.Trim(ruta, "/") {
+		switch {
+		case r == '/':
+			nombre.WriteByte('.')
+		case r == ':' || r == '*' || r == '(' || r == ')':
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '_', r == '-':
+			nombre.WriteRune(r)
+		default:
+			nombre.WriteByte('_')
+		}
+	}
+	if nombre := strings.Trim(nombre.String(), "_."); nombre != "" {
+		return nombre
+	}
+	return "raiz"
+}
+
+// nombrePrometheus deja solo los caracteres válidos en un nombre de métrica de Prometheus
+func nombrePrometheus(nombre string) string {
+	return strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '_' {
+			return r
+		}
+		return '_'
+	}, nombre)
+}
+
+// etiquetaPrometheus escapa el valor de una etiqueta
+func etiquetaPrometheus(valor string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(valor)
+}
+
+// numeroMetrica formatea un valor decimal sin notación exponencial
+func numeroMetrica(valor float64) string {
+	return strconv.FormatFloat(valor, 'f', -1, 64)
+}
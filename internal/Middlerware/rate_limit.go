@@ -0,0 +1,174 @@
+package middleware
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"slices"
+	"time"
+
+	"CheeseHouse/internal/repository"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// retrasoBaseFallos espera tras la primera consulta fallida; se duplica con cada fallo
+	retrasoBaseFallos = time.Second
+	// retrasoMaximoFallos tope de la espera exponencial por IP
+	retrasoMaximoFallos = 15 * time.Minute
+)
+
+// estadoConsultasIP actividad reciente de una IP sobre la consulta de clientes, guardada
+// en el estado compartido
+type estadoConsultasIP struct {
+	VentanaInicio  time.Time `json:"ventana_inicio"`
+	Consultas      int       `json:"consultas"`
+	Fallos         int       `json:"fallos"`
+	BloqueadoHasta time.Time `json:"bloqueado_hasta"`
+	Telefonos      []string  `json:"telefonos"`
+	Alertado       bool      `json:"alertado"`
+}
+
+// LookupLimiter limita y audita las consultas de clientes por teléfono.
+// Aplica un máximo de consultas por ventana, retrasos exponenciales por IP tras
+// consultas fallidas (que se acumulan hasta que vence la ventana) y alerta cuando una IP recorre muchos teléfonos distintos.
+// El estado de cada IP vive en el estado compartido, así vale entre instancias
+type LookupLimiter struct {
+	estado            repository.EstadoCompartidoRepository
+	maxPorVentana     int
+	ventana           time.Duration
+	umbralEnumeracion int
+}
+
+// NewLookupLimiter crea un limitador con el máximo de consultas por ventana y
+// la cantidad de teléfonos distintos a partir de la cual se alerta de enumeración
+func NewLookupLimiter(estado repository.EstadoCompartidoRepository, maxPorVentana int, ventana time.Duration, umbralEnumeracion int) *LookupLimiter {
+	return &LookupLimiter{
+		estado:            estado,
+		maxPorVentana:     maxPorVentana,
+		ventana:           ventana,
+		umbralEnumeracion: umbralEnumeracion,
+	}
+}
+
+// Limit middleware que aplica el límite a rutas con el parámetro :phone
+func (l *LookupLimiter) Limit() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ip := c.ClientIP()
+		telefono := c.Param("phone")
+		clave := "consultas:ip:" + ip
+
+		var espera time.Duration
+		err := l.actualizar(clave, func(estado *estadoConsultasIP, ahora time.Time) {
+			espera = 0
+			if ahora.Before(estado.BloqueadoHasta) {
+				espera = estado.BloqueadoHasta.Sub(ahora)
+				return
+			}
+
+			estado.Consultas++
+			if estado.Consultas > l.maxPorVentana {
+				estado.BloqueadoHasta = estado.VentanaInicio.Add(l.ventana)
+				espera = estado.BloqueadoHasta.Sub(ahora)
+				return
+			}
+
+			if !slices.Contains(estado.Telefonos, telefono) {
+				estado.Telefonos = append(estado.Telefonos, telefono)
+			}
+			if len(estado.Telefonos) >= l.umbralEnumeracion && !estado.Alertado {
+				estado.Alertado = true
+				log.Printf("🚨 ALERTA: posible enumeración de clientes desde IP %s - %d teléfonos distintos en %v",
+					ip, len(estado.Telefonos), l.ventana)
+			}
+		})
+		if err != nil {
+			// Sin estado compartido no se corta la consulta: queda igual el log de auditoría
+			log.Printf("⚠️  Error actualizando el límite de consultas, se deja pasar: %v", err)
+		}
+		if espera > 0 {
+			l.rechazar(c, ip, telefono, espera)
+			return
+		}
+
+		c.Next()
+
+		status := c.Writer.Status()
+		log.Printf("🔎 Consulta de cliente - IP: %s, Teléfono: %s, Método: %s, Status: %d",
+			ip, enmascararTelefono(telefono), c.Request.Method, status)
+
+		// Los fallos solo se olvidan al vencer la ventana: si una consulta exitosa los
+		// reiniciara, intercalar cualquier ruta que responda 200 anularía el retraso
+		if status != http.StatusNotFound {
+			return
+		}
+		err = l.actualizar(clave, func(estado *estadoConsultasIP, ahora time.Time) {
+			estado.Fallos++
+			retraso := time.Duration(float64(retrasoBaseFallos) * math.Pow(2, float64(estado.Fallos-1)))
+			if retraso > retrasoMaximoFallos {
+				retraso = retrasoMaximoFallos
+			}
+			estado.BloqueadoHasta = ahora.Add(retraso)
+		})
+		if err != nil {
+			log.Printf("⚠️  Error registrando el resultado de la consulta: %v", err)
+		}
+	}
+}
+
+// actualizar aplica el cambio al estado de la IP (uno nuevo si no hay o si la ventana
+// venció) y lo guarda hasta que venzan la ventana y el bloqueo
+func (l *LookupLimiter) actualizar(clave string, cambio func(estado *estadoConsultasIP, ahora time.Time)) error {
+	return repository.ActualizarCompartido(l.estado, clave, func(valor string, existe bool) (string, time.Time, error) {
+		ahora := time.Now()
+		estado := &estadoConsultasIP{VentanaInicio: ahora}
+		if existe && json.Unmarshal([]byte(valor), estado) != nil {
+			estado = &estadoConsultasIP{VentanaInicio: ahora}
+		}
+
+		if ahora.Sub(estado.VentanaInicio) >= l.ventana {
+			estado.VentanaInicio = ahora
+			estado.Consultas = 0
+			estado.Fallos = 0
+			estado.Telefonos = nil
+			estado.Alertado = false
+		}
+
+		cambio(estado, ahora)
+
+		codificado, err := json.Marshal(estado)
+		if err != nil {
+			return "", time.Time{}, err
+		}
+		vence := estado.VentanaInicio.Add(l.ventana)
+		if estado.BloqueadoHasta.After(vence) {
+			vence = estado.BloqueadoHasta
+		}
+		return string(codificado), vence, nil
+	})
+}
+
+// rechazar responde 429 indicando cuánto esperar
+func (l *LookupLimiter) rechazar(c *gin.Context, ip, telefono string, espera time.Duration) {
+	segundos := int(math.Ceil(espera.Seconds()))
+	log.Printf("⛔ Consulta de cliente limitada - IP: %s, Teléfono: %s, Reintentar en %ds",
+		ip, enmascararTelefono(telefono), segundos)
+
+	c.Header("Retry-After", fmt.Sprintf("%d", segundos))
+	c.JSON(http.StatusTooManyRequests, gin.H{
+		"success": false,
+		"message": "Demasiadas consultas, intentá nuevamente más tarde",
+	})
+	c.Abort()
+}
+
+// enmascararTelefono deja visibles solo los últimos 4 dígitos para los logs de auditoría
+func enmascararTelefono(telefono string) string {
+	if len(telefono) <= 4 {
+		return "****"
+	}
+	return "****" + telefono[len(telefono)-4:]
+}
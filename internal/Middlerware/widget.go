@@ -0,0 +1,59 @@
+package middleware
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"CheeseHouse/internal/services"
+)
+
+// WidgetMiddleware CORS restringido para la API del widget embebible: solo responde a
+// los orígenes registrados para la clave de la URL
+type WidgetMiddleware struct {
+	widgetService *services.WidgetService
+}
+
+// NewWidgetMiddleware crea una nueva instancia del middleware de widgets
+func NewWidgetMiddleware(widgetService *services.WidgetService) *WidgetMiddleware {
+	return &WidgetMiddleware{
+		widgetService: widgetService,
+	}
+}
+
+// RequireOrigen valida la clave (:clave) y el header Origin, agrega los headers CORS
+// para ese origen, responde los preflight y deja el ID del widget en el contexto
+func (m *WidgetMiddleware) RequireOrigen() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		origen := c.GetHeader("Origin")
+		widget, err := m.widgetService.Autorizar(c.Param("clave"), origen)
+		if err != nil {
+			if !errors.Is(err, services.ErrWidgetNoAutorizado) {
+				log.Printf("❌ Error validando widget: %v", err)
+			} else {
+				log.Printf("🔒 Widget rechazado - Origin: %q, IP: %s, Path: %s", origen, c.ClientIP(), c.Request.URL.Path)
+			}
+			// Sin headers CORS: el navegador del sitio no autorizado no puede leer la respuesta
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{
+				"success": false,
+				"message": "Sitio no autorizado para embeber el juego",
+			})
+			return
+		}
+
+		c.Header("Access-Control-Allow-Origin", origen)
+		c.Header("Vary", "Origin")
+		if c.Request.Method == http.MethodOptions {
+			c.Header("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
+			c.Header("Access-Control-Allow-Headers", "Content-Type, Idempotency-Key")
+			c.Header("Access-Control-Max-Age", "600")
+			c.AbortWithStatus(http.StatusNoContent)
+			return
+		}
+
+		c.Set("widget_id", widget.ID)
+		c.Next()
+	}
+}
@@ -0,0 +1,59 @@
+// Package almacenamiento guarda los archivos que genera el sistema (reportes CSV,
+// exportaciones, QR de mesas) en disco local o en un bucket S3 o compatible (MinIO) y
+// arma links temporales firmados para descargarlos
+package almacenamiento
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"CheeseHouse/internal/config"
+)
+
+var (
+	// ErrNoEncontrado la clave no corresponde a ningún archivo guardado
+	ErrNoEncontrado = errors.New("archivo no encontrado")
+	// ErrLinkInvalido el link de descarga está adulterado o vencido
+	ErrLinkInvalido = errors.New("link de descarga inválido o vencido")
+	// ErrClaveInvalida la clave tiene caracteres no permitidos o intenta salir de la carpeta
+	ErrClaveInvalida = errors.New("clave de archivo inválida")
+)
+
+// patronSegmento carpetas y nombres de archivo: letras, números, guiones y puntos
+var patronSegmento = regexp.MustCompile(`^[A-Za-z0-9_\-.]+$`)
+
+// Almacenamiento guarda archivos por clave (ej. "reportes/12/20240501-090000.csv") y
+// arma links de descarga que vencen
+type Almacenamiento interface {
+	Guardar(clave string, contenido []byte, tipoContenido string) error
+	URLFirmada(clave string, vigencia time.Duration) (string, error)
+	Eliminar(clave string) error
+	Descripcion() string
+}
+
+// Nuevo crea el almacenamiento configurado. baseURL y secreto arman y firman los links
+// de descarga del almacenamiento local, que sirve la propia API
+func Nuevo(cfg config.StorageConfig, baseURL, secreto string) (Almacenamiento, error) {
+	switch cfg.Driver {
+	case "local":
+		return NuevoLocal(cfg.LocalPath, baseURL, secreto)
+	case "s3":
+		return NuevoS3(cfg)
+	default:
+		return nil, fmt.Errorf("driver de almacenamiento desconocido: %q", cfg.Driver)
+	}
+}
+
+// validarClave rechaza claves vacías, absolutas o con "." y ".." para que nadie lea ni
+// escriba fuera de la carpeta del almacenamiento
+func validarClave(clave string) error {
+	for _, segmento := range strings.Split(clave, "/") {
+		if !patronSegmento.MatchString(segmento) || segmento == "." || segmento == ".." {
+			return fmt.Errorf("%w: %q", ErrClaveInvalida, clave)
+		}
+	}
+	return nil
+}
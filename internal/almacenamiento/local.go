@@ -0,0 +1,115 @@
+package almacenamiento
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RutaDescargas ruta pública de la API que sirve los archivos del almacenamiento local
+const RutaDescargas = "/api/descargas/"
+
+// Local guarda los archivos en una carpeta del servidor. Los links firmados apuntan a
+// RutaDescargas, que verifica la firma y el vencimiento antes de servir el archivo
+type Local struct {
+	carpeta string
+	baseURL string
+	secreto []byte
+}
+
+// NuevoLocal crea el almacenamiento en disco, creando la carpeta si no existe
+func NuevoLocal(carpeta, baseURL, secreto string) (*Local, error) {
+	if err := os.MkdirAll(carpeta, 0o750); err != nil {
+		return nil, fmt.Errorf("error creando carpeta de almacenamiento %s: %w", carpeta, err)
+	}
+	return &Local{
+		carpeta: carpeta,
+		baseURL: strings.TrimRight(baseURL, "/"),
+		secreto: []byte(secreto),
+	}, nil
+}
+
+// Guardar escribe el archivo; el tipo de contenido se deduce de la extensión al servirlo
+func (l *Local) Guardar(clave string, contenido []byte, _ string) error {
+	if err := validarClave(clave); err != nil {
+		return err
+	}
+	ruta := l.ruta(clave)
+	if err := os.MkdirAll(filepath.Dir(ruta), 0o750); err != nil {
+		return fmt.Errorf("error creando carpeta para %s: %w", clave, err)
+	}
+	if err := os.WriteFile(ruta, contenido, 0o640); err != nil {
+		return fmt.Errorf("error guardando %s: %w", clave, err)
+	}
+	return nil
+}
+
+// URLFirmada arma el link de descarga con su vencimiento y firma
+func (l *Local) URLFirmada(clave string, vigencia time.Duration) (string, error) {
+	if err := validarClave(clave); err != nil {
+		return "", err
+	}
+	expira := strconv.FormatInt(time.Now().Add(vigencia).Unix(), 10)
+	return fmt.Sprintf("%s%s%s?expira=%s&firma=%s", l.baseURL, RutaDescargas, clave,
+		expira, url.QueryEscape(l.firmar(clave, expira))), nil
+}
+
+// Eliminar borra el archivo; no falla si ya no existía
+func (l *Local) Eliminar(clave string) error {
+	if err := validarClave(clave); err != nil {
+		return err
+	}
+	if err := os.Remove(l.ruta(clave)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("error eliminando %s: %w", clave, err)
+	}
+	return nil
+}
+
+// Descripcion resume el almacenamiento para el log de arranque
+func (l *Local) Descripcion() string {
+	return "local (" + l.carpeta + ")"
+}
+
+// Leer verifica la firma y el vencimiento de un link y retorna el contenido del archivo
+func (l *Local) Leer(clave, expira, firma string) ([]byte, error) {
+	if err := validarClave(clave); err != nil {
+		return nil, ErrLinkInvalido
+	}
+	vence, err := strconv.ParseInt(expira, 10, 64)
+	if err != nil || time.Now().Unix() > vence {
+		return nil, ErrLinkInvalido
+	}
+	if !hmac.Equal([]byte(firma), []byte(l.firmar(clave, expira))) {
+		return nil, ErrLinkInvalido
+	}
+
+	contenido, err := os.ReadFile(l.ruta(clave))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, ErrNoEncontrado
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error leyendo %s: %w", clave, err)
+	}
+	return contenido, nil
+}
+
+// ruta ubica la clave dentro de la carpeta del almacenamiento
+func (l *Local) ruta(clave string) string {
+	return filepath.Join(l.carpeta, filepath.FromSlash(clave))
+}
+
+// firmar calcula el HMAC-SHA256 de la clave y su vencimiento
+func (l *Local) firmar(clave, expira string) string {
+	mac := hmac.New(sha256.New, l.secreto)
+	fmt.Fprintf(mac, "descarga:%s:%s", clave, expira)
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
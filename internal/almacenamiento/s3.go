@@ -0,0 +1,255 @@
+package almacenamiento
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"CheeseHouse/internal/config"
+)
+
+const (
+	// vigenciaMaxS3 máximo que S3 acepta para un link prefirmado (7 días)
+	vigenciaMaxS3   = 7 * 24 * time.Hour
+	formatoFechaS3  = "20060102T150405Z"
+	formatoDiaS3    = "20060102"
+	algoritmoFirma  = "AWS4-HMAC-SHA256"
+	payloadSinFirma = "UNSIGNED-PAYLOAD"
+)
+
+// S3 guarda los archivos en un bucket de S3 o compatible (MinIO). Firma los pedidos con
+// AWS Signature V4 y arma los links de descarga como URLs prefirmadas del bucket
+type S3 struct {
+	endpoint   *url.URL
+	region     string
+	bucket     string
+	accessKey  string
+	secretKey  string
+	pathStyle  bool
+	httpClient *http.Client
+}
+
+// NuevoS3 crea el almacenamiento en un bucket. Sin endpoint usa el de AWS para la región
+func NuevoS3(cfg config.StorageConfig) (*S3, error) {
+	if cfg.S3Bucket == "" || cfg.S3AccessKey == "" || cfg.S3SecretKey == "" {
+		return nil, fmt.Errorf("faltan bucket o credenciales de S3")
+	}
+
+	endpoint := cfg.S3Endpoint
+	if endpoint == "" {
+		endpoint = fmt.Sprintf("https://s3.%s.amazonaws.com", cfg.S3Region)
+	}
+	u, err := url.Parse(strings.TrimRight(endpoint, "/"))
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return nil, fmt.Errorf("endpoint de S3 inválido: %q", endpoint)
+	}
+
+	return &S3{
+		endpoint:   u,
+		region:     cfg.S3Region,
+		bucket:     cfg.S3Bucket,
+		accessKey:  cfg.S3AccessKey,
+		secretKey:  cfg.S3SecretKey,
+		pathStyle:  cfg.S3PathStyle,
+		httpClient: &http.Client{Timeout: 30 * time.Second},
+	}, nil
+}
+
+// Guardar sube el archivo al bucket
+func (s *S3) Guardar(clave string, contenido []byte, tipoContenido string) error {
+	if err := validarClave(clave); err != nil {
+		return err
+	}
+	headers := map[string]string{}
+	if tipoContenido != "" {
+		headers["Content-Type"] = tipoContenido
+	}
+	return s.ejecutar(http.MethodPut, clave, contenido, headers)
+}
+
+// URLFirmada arma una URL prefirmada de descarga directa desde el bucket
+func (s *S3) URLFirmada(clave string, vigencia time.Duration) (string, error) {
+	if err := validarClave(clave); err != nil {
+		return "", err
+	}
+	if vigencia > vigenciaMaxS3 {
+		vigencia = vigenciaMaxS3
+	}
+
+	ahora := time.Now().UTC()
+	host, ruta := s.destino(clave)
+	alcance := s.alcance(ahora)
+
+	query := url.Values{}
+	query.Set("X-Amz-Algorithm", algoritmoFirma)
+	query.Set("X-Amz-Credential", s.accessKey+"/"+alcance)
+	query.Set("X-Amz-Date", ahora.Format(formatoFechaS3))
+	query.Set("X-Amz-Expires", strconv.Itoa(int(vigencia.Seconds())))
+	query.Set("X-Amz-SignedHeaders", "host")
+
+	canonico := strings.Join([]string{
+		http.MethodGet,
+		ruta,
+		queryCanonica(query),
+		"host:" + host + "\n",
+		"host",
+		payloadSinFirma,
+	}, "\n")
+	query.Set("X-Amz-Signature", s.firmar(ahora, alcance, canonico))
+
+	return fmt.Sprintf("%s://%s%s?%s", s.endpoint.Scheme, host, ruta, queryCanonica(query)), nil
+}
+
+// Eliminar borra el archivo del bucket (S3 no falla si no existía)
+func (s *S3) Eliminar(clave string) error {
+	if err := validarClave(clave); err != nil {
+		return err
+	}
+	return s.ejecutar(http.MethodDelete, clave, nil, nil)
+}
+
+// Descripcion resume el almacenamiento para el log de arranque
+func (s *S3) Descripcion() string {
+	return fmt.Sprintf("s3 (%s en %s)", s.bucket, s.endpoint.Host)
+}
+
+// ejecutar envía un pedido firmado con los headers Authorization de Signature V4
+func (s *S3) ejecutar(metodo, clave string, cuerpo []byte, extra map[string]string) error {
+	ahora := time.Now().UTC()
+	host, ruta := s.destino(clave)
+	alcance := s.alcance(ahora)
+	hashCuerpo := hashHex(cuerpo)
+
+	headers := map[string]string{
+		"host":                 host,
+		"x-amz-content-sha256": hashCuerpo,
+		"x-amz-date":           ahora.Format(formatoFechaS3),
+	}
+	for nombre, valor := range extra {
+		headers[strings.ToLower(nombre)] = valor
+	}
+
+	nombres := make([]string, 0, len(headers))
+	for nombre := range headers {
+		nombres = append(nombres, nombre)
+	}
+	sort.Strings(nombres)
+	var canonicos strings.Builder
+	for _, nombre := range nombres {
+		canonicos.WriteString(nombre + ":" + strings.TrimSpace(headers[nombre]) + "\n")
+	}
+	firmados := strings.Join(nombres, ";")
+
+	canonico := strings.Join([]string{metodo, ruta, "", canonicos.String(), firmados, hashCuerpo}, "\n")
+	firma := s.firmar(ahora, alcance, canonico)
+
+	req, err := http.NewRequest(metodo, fmt.Sprintf("%s://%s%s", s.endpoint.Scheme, host, ruta), bytes.NewReader(cuerpo))
+	if err != nil {
+		return fmt.Errorf("error armando pedido a S3: %w", err)
+	}
+	for _, nombre := range nombres {
+		if nombre != "host" {
+			req.Header.Set(nombre, headers[nombre])
+		}
+	}
+	req.Header.Set("Authorization", fmt.Sprintf("%s Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		algoritmoFirma, s.accessKey, alcance, firmados, firma))
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("error conectando con S3: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return ErrNoEncontrado
+	}
+	if resp.StatusCode >= 300 {
+		detalle, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("S3 respondió %d en %s %s: %s", resp.StatusCode, metodo, clave, strings.TrimSpace(string(detalle)))
+	}
+	return nil
+}
+
+// destino retorna el host y la ruta del objeto según el estilo de URL del bucket
+func (s *S3) destino(clave string) (string, string) {
+	if s.pathStyle {
+		return s.endpoint.Host, s.endpoint.EscapedPath() + "/" + escaparRuta(s.bucket) + "/" + escaparRuta(clave)
+	}
+	return s.bucket + "." + s.endpoint.Host, s.endpoint.EscapedPath() + "/" + escaparRuta(clave)
+}
+
+// alcance credencial del día: fecha/región/s3/aws4_request
+func (s *S3) alcance(t time.Time) string {
+	return t.Format(formatoDiaS3) + "/" + s.region + "/s3/aws4_request"
+}
+
+// firmar calcula la firma V4 del pedido canónico con la clave derivada del día y la región
+func (s *S3) firmar(t time.Time, alcance, canonico string) string {
+	aFirmar := strings.Join([]string{algoritmoFirma, t.Format(formatoFechaS3), alcance, hashHex([]byte(canonico))}, "\n")
+
+	clave := hmacSHA256([]byte("AWS4"+s.secretKey), t.Format(formatoDiaS3))
+	clave = hmacSHA256(clave, s.region)
+	clave = hmacSHA256(clave, "s3")
+	clave = hmacSHA256(clave, "aws4_request")
+	return hex.EncodeToString(hmacSHA256(clave, aFirmar))
+}
+
+func hmacSHA256(clave []byte, dato string) []byte {
+	mac := hmac.New(sha256.New, clave)
+	mac.Write([]byte(dato))
+	return mac.Sum(nil)
+}
+
+func hashHex(dato []byte) string {
+	suma := sha256.Sum256(dato)
+	return hex.EncodeToString(suma[:])
+}
+
+// escaparRuta codifica cada segmento de la clave como pide S3 (la "/" se mantiene)
+func escaparRuta(clave string) string {
+	segmentos := strings.Split(clave, "/")
+	for i, segmento := range segmentos {
+		segmentos[i] = escaparURI(segmento)
+	}
+	return strings.Join(segmentos, "/")
+}
+
+// queryCanonica ordena los parámetros y los codifica con %20 en lugar de "+"
+func queryCanonica(query url.Values) string {
+	nombres := make([]string, 0, len(query))
+	for nombre := range query {
+		nombres = append(nombres, nombre)
+	}
+	sort.Strings(nombres)
+
+	partes := make([]string, 0, len(nombres))
+	for _, nombre := range nombres {
+		partes = append(partes, escaparURI(nombre)+"="+escaparURI(query.Get(nombre)))
+	}
+	return strings.Join(partes, "&")
+}
+
+// escaparURI codifica todo salvo los caracteres no reservados de RFC 3986
+func escaparURI(valor string) string {
+	var b strings.Builder
+	for i := 0; i < len(valor); i++ {
+		c := valor[i]
+		if (c >= 'A' && c <= 'Z') || (c >= 'a' && c <= 'z') || (c >= '0' && c <= '9') ||
+			c == '-' || c == '_' || c == '.' || c == '~' {
+			b.WriteByte(c)
+			continue
+		}
+		fmt.Fprintf(&b, "%%%02X", c)
+	}
+	return b.String()
+}
@@ -0,0 +1,248 @@
+package api
+
+import (
+	"time"
+
+	"CheeseHouse/internal/models"
+)
+
+// DTOs de las entidades principales. Se arman campo por campo a partir de los
+// modelos de GORM para que un cambio en el esquema (una columna nueva, una
+// relación precargada) no cambie la respuesta de la API sin que nadie lo note.
+
+// UsuarioResumen empleado referenciado desde otro recurso (quién canjeó, quién creó)
+type UsuarioResumen struct {
+	ID     uint   `json:"id"`
+	Nombre string `json:"nombre"`
+}
+
+// Usuario empleado del panel administrativo
+type Usuario struct {
+	ID           uint      `json:"id"`
+	Nombre       string    `json:"nombre"`
+	Email        string    `json:"email"`
+	Rol          string    `json:"rol,omitempty"`
+	RolID        uint      `json:"rol_id"`
+	Sucursal     string    `json:"sucursal,omitempty"`
+	LimiteCanjes *int      `json:"limite_canjes_diario"` // nil = el de la cadena, 0 = sin límite
+	Activo       bool      `json:"activo"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// Cliente cliente con sus contadores de juego
+type Cliente struct {
+	ID               uint       `json:"id"`
+	Nombre           string     `json:"nombre"`
+	Apellido         string     `json:"apellido"`
+	Telefono         string     `json:"telefono"`
+	Email            *string    `json:"email,omitempty"`
+	Idioma           string     `json:"idioma"`
+	Estado           string     `json:"estado"`
+	TipoCliente      string     `json:"tipo_cliente"`
+	TotalJuegos      int        `json:"total_juegos"`
+	JuegosGanados    int        `json:"juegos_ganados"`
+	JuegosPerdidos   int        `json:"juegos_perdidos"`
+	FechaRegistro    time.Time  `json:"fecha_registro"`
+	FechaUltimoJuego *time.Time `json:"fecha_ultimo_juego"`
+}
+
+// ClienteConEstadisticas cliente con el resumen de sus vouchers, para el panel
+type ClienteConEstadisticas struct {
+	Cliente
+	VouchersGenerados           int      `json:"vouchers_generados"`
+	VouchersUsados              int      `json:"vouchers_usados"`
+	VouchersPendientes          int      `json:"vouchers_pendientes"`
+	PorcentajeVictoriasPersonal float64  `json:"porcentaje_victorias_personal"`
+	UltimoVoucher               *Voucher `json:"ultimo_voucher,omitempty"`
+}
+
+// Voucher voucher sin los datos antifraude (IP y huella del dispositivo)
+type Voucher struct {
+	ID               uint            `json:"id"`
+	Codigo           string          `json:"codigo"`
+	Tipo             string          `json:"tipo"`
+	Descuento        int             `json:"descuento"`
+	Ganado           *bool           `json:"ganado"`
+	Usado            bool            `json:"usado"`
+	FechaEmision     time.Time       `json:"fecha_emision"`
+	FechaVencimiento time.Time       `json:"fecha_vencimiento"`
+	Flash            bool            `json:"flash"`
+	FechaUso         *time.Time      `json:"fecha_uso"`
+	Notas            string          `json:"notas,omitempty"`
+	Alcance          string          `json:"alcance,omitempty"`
+	Recomendacion    string          `json:"recomendacion,omitempty"`
+	Sucursal         string          `json:"sucursal,omitempty"`
+	SoloSucursal     bool            `json:"solo_sucursal"`
+	SucursalCanje    string          `json:"sucursal_canje,omitempty"`
+	ClienteID        uint            `json:"cliente_id"`
+	Cliente          *Cliente        `json:"cliente,omitempty"`
+	CanjeadoPor      *UsuarioResumen `json:"canjeado_por,omitempty"`
+	AprobadoPor      *UsuarioResumen `json:"aprobado_por,omitempty"` // Encargado que confirmó un descuento alto
+}
+
+// EnvioCampana envío de una campaña a un cliente
+type EnvioCampana struct {
+	ID            uint       `json:"id"`
+	ClienteID     uint       `json:"cliente_id"`
+	VoucherID     *uint      `json:"voucher_id,omitempty"`
+	CodigoVoucher string     `json:"codigo_voucher,omitempty"`
+	Estado        string     `json:"estado"`
+	ErrorMensaje  string     `json:"error_mensaje,omitempty"`
+	IntentosEnvio int        `json:"intentos_envio"`
+	EnviadoAt     time.Time  `json:"enviado_at"`
+	EntregadoAt   *time.Time `json:"entregado_at,omitempty"`
+	LeidoAt       *time.Time `json:"leido_at,omitempty"`
+}
+
+// Campana campaña promocional
+type Campana struct {
+	ID               uint            `json:"id"`
+	Nombre           string          `json:"nombre"`
+	Descripcion      string          `json:"descripcion,omitempty"`
+	Descuento        int             `json:"descuento"`
+	Mensaje          string          `json:"mensaje,omitempty"`
+	FechaVencimiento time.Time       `json:"fecha_vencimiento"`
+	Activa           bool            `json:"activa"`
+	CreadaPor        *UsuarioResumen `json:"creada_por,omitempty"`
+	CreatedAt        time.Time       `json:"created_at"`
+	Envios           []EnvioCampana  `json:"envios,omitempty"`
+}
+
+// NuevoUsuarioResumen serializa una referencia a un empleado; nil si no se precargó
+func NuevoUsuarioResumen(usuario *models.Usuario) *UsuarioResumen {
+	if usuario == nil {
+		return nil
+	}
+	return &UsuarioResumen{ID: usuario.ID, Nombre: usuario.Nombre}
+}
+
+// NuevoUsuario serializa un empleado
+func NuevoUsuario(usuario *models.Usuario) Usuario {
+	dto := Usuario{
+		ID:           usuario.ID,
+		Nombre:       usuario.Nombre,
+		Email:        usuario.Email,
+		RolID:        usuario.RolID,
+		Sucursal:     usuario.Sucursal,
+		LimiteCanjes: usuario.LimiteCanjes,
+		Activo:       usuario.Activo,
+		CreatedAt:    usuario.CreatedAt,
+	}
+	if usuario.Rol != nil {
+		dto.Rol = usuario.Rol.Nombre
+	}
+	return dto
+}
+
+// NuevoCliente serializa un cliente
+func NuevoCliente(cliente *models.Cliente) Cliente {
+	return Cliente{
+		ID:               cliente.ID,
+		Nombre:           cliente.Nombre,
+		Apellido:         cliente.Apellido,
+		Telefono:         cliente.Telefono,
+		Email:            cliente.Email,
+		Idioma:           cliente.Idioma,
+		Estado:           cliente.Estado,
+		TipoCliente:      cliente.TipoCliente,
+		TotalJuegos:      cliente.TotalJuegos,
+		JuegosGanados:    cliente.JuegosGanados,
+		JuegosPerdidos:   cliente.JuegosPerdidos,
+		FechaRegistro:    cliente.FechaRegistro,
+		FechaUltimoJuego: cliente.FechaUltimoJuego,
+	}
+}
+
+// NuevoClienteConEstadisticas serializa un cliente con sus estadísticas
+func NuevoClienteConEstadisticas(cliente *models.ClienteConEstadisticas) ClienteConEstadisticas {
+	dto := ClienteConEstadisticas{
+		Cliente:                     NuevoCliente(&cliente.Cliente),
+		VouchersGenerados:           cliente.VouchersGenerados,
+		VouchersUsados:              cliente.VouchersUsados,
+		VouchersPendientes:          cliente.VouchersPendientes,
+		PorcentajeVictoriasPersonal: cliente.PorcentajeVictoriasPersonal,
+	}
+	if cliente.UltimoVoucher != nil {
+		voucher := NuevoVoucher(cliente.UltimoVoucher)
+		dto.UltimoVoucher = &voucher
+	}
+	return dto
+}
+
+// NuevosClientesConEstadisticas serializa un listado de clientes con sus estadísticas
+func NuevosClientesConEstadisticas(clientes []*models.ClienteConEstadisticas) []ClienteConEstadisticas {
+	dtos := make([]ClienteConEstadisticas, 0, len(clientes))
+	for _, cliente := range clientes {
+		dtos = append(dtos, NuevoClienteConEstadisticas(cliente))
+	}
+	return dtos
+}
+
+// NuevoVoucher serializa un voucher con su cliente y quién lo canjeó si se precargaron
+func NuevoVoucher(voucher *models.Voucher) Voucher {
+	dto := Voucher{
+		ID:               voucher.ID,
+		Codigo:           voucher.Codigo,
+		Tipo:             voucher.Tipo,
+		Descuento:        voucher.Descuento,
+		Ganado:           voucher.Ganado,
+		Usado:            voucher.Usado,
+		FechaEmision:     voucher.FechaEmision,
+		FechaVencimiento: voucher.FechaVencimiento,
+		Flash:            voucher.Flash,
+		FechaUso:         voucher.FechaUso,
+		Notas:            voucher.Notas,
+		Alcance:          voucher.Alcance,
+		Recomendacion:    voucher.Recomendacion,
+		Sucursal:         voucher.Sucursal,
+		SoloSucursal:     voucher.SoloSucursal,
+		SucursalCanje:    voucher.SucursalCanje,
+		ClienteID:        voucher.ClienteID,
+		CanjeadoPor:      NuevoUsuarioResumen(voucher.UsuarioQueCanje),
+		AprobadoPor:      NuevoUsuarioResumen(voucher.Aprobador),
+	}
+	if voucher.Cliente != nil {
+		cliente := NuevoCliente(voucher.Cliente)
+		dto.Cliente = &cliente
+	}
+	return dto
+}
+
+// NuevosVouchers serializa un listado de vouchers
+func NuevosVouchers(vouchers []*models.Voucher) []Voucher {
+	dtos := make([]Voucher, 0, len(vouchers))
+	for _, voucher := range vouchers {
+		dtos = append(dtos, NuevoVoucher(voucher))
+	}
+	return dtos
+}
+
+// NuevaCampana serializa una campaña con sus envíos si se precargaron
+func NuevaCampana(campana *models.CampanaClientesVouchers) Campana {
+	dto := Campana{
+		ID:               campana.ID,
+		Nombre:           campana.Nombre,
+		Descripcion:      campana.Descripcion,
+		Descuento:        campana.Descuento,
+		Mensaje:          campana.Mensaje,
+		FechaVencimiento: campana.FechaVencimiento,
+		Activa:           campana.Activa,
+		CreadaPor:        NuevoUsuarioResumen(campana.CreadoPor),
+		CreatedAt:        campana.CreatedAt,
+	}
+	for _, envio := range campana.Envios {
+		dto.Envios = append(dto.Envios, EnvioCampana{
+			ID:            envio.ID,
+			ClienteID:     envio.ClienteID,
+			VoucherID:     envio.VoucherID,
+			CodigoVoucher: envio.CodigoVoucher,
+			Estado:        envio.Estado,
+			ErrorMensaje:  envio.ErrorMensaje,
+			IntentosEnvio: envio.IntentosEnvio,
+			EnviadoAt:     envio.EnviadoAt,
+			EntregadoAt:   envio.EntregadoAt,
+			LeidoAt:       envio.LeidoAt,
+		})
+	}
+	return dto
+}
@@ -0,0 +1,188 @@
+package api
+
+import (
+	"time"
+
+	"CheeseHouse/internal/models"
+)
+
+// ResultadoJuego resultado de una partida enviada
+type ResultadoJuego struct {
+	Codigo             string `json:"codigo,omitempty"`
+	Descuento          int    `json:"descuento,omitempty"`
+	FechaVencimiento   string `json:"fecha_vencimiento,omitempty"`
+	Flash              bool   `json:"flash,omitempty"`
+	MostrarCodigo      bool   `json:"mostrar_codigo,omitempty"` // WhatsApp caído: el código se muestra en pantalla
+	NotaCodigo         string `json:"nota_codigo,omitempty"`
+	TokenPantalla      string `json:"token_pantalla,omitempty"`    // Se canjea en /game/voucher/pantalla por el código
+	VenceEnSegundos    int    `json:"vence_en_segundos,omitempty"` // Solo en vouchers flash, para la cuenta regresiva
+	NecesitaAprobacion bool   `json:"necesita_aprobacion"`
+	ClienteID          uint   `json:"cliente_id,omitempty"`
+	EsClienteNuevo     bool   `json:"es_cliente_nuevo"`
+	EsperaSegundos     int    `json:"espera_segundos,omitempty"`   // Solo si la partida se rechazó por la espera
+	PuedeJugarDesde    string `json:"puede_jugar_desde,omitempty"` // Solo si llegó al tope de partidas del día o la semana
+	Mensaje            string `json:"mensaje"`
+	CompartirURL       string `json:"compartir_url,omitempty"` // Imagen para compartir; ?formato=historia para 9:16
+
+	Celebracion   *models.CelebracionGanador `json:"celebracion,omitempty"`   // Solo al ganar
+	Recomendacion *models.Recomendacion      `json:"recomendacion,omitempty"` // Alcance y sugerencia del voucher
+}
+
+// NuevoResultadoJuego serializa la respuesta del servicio de juego
+func NuevoResultadoJuego(respuesta *models.VoucherResponse) ResultadoJuego {
+	return ResultadoJuego{
+		Codigo:             respuesta.Codigo,
+		Descuento:          respuesta.Descuento,
+		FechaVencimiento:   respuesta.FechaVencimiento,
+		Flash:              respuesta.Flash,
+		MostrarCodigo:      respuesta.MostrarCodigo,
+		NotaCodigo:         respuesta.NotaCodigo,
+		TokenPantalla:      respuesta.TokenPantalla,
+		VenceEnSegundos:    respuesta.VenceEnSegundos,
+		NecesitaAprobacion: respuesta.NecesitaAprobacion,
+		ClienteID:          respuesta.ClienteID,
+		EsClienteNuevo:     respuesta.EsClienteNuevo,
+		EsperaSegundos:     respuesta.EsperaSegundos,
+		PuedeJugarDesde:    respuesta.PuedeJugarDesde,
+		Mensaje:            respuesta.Message,
+		CompartirURL:       respuesta.CompartirURL,
+		Celebracion:        respuesta.Celebracion,
+		Recomendacion:      respuesta.Recomendacion,
+	}
+}
+
+// EstadisticasJuego estadísticas públicas del juego
+type EstadisticasJuego struct {
+	Restaurante         string  `json:"restaurante"`
+	TotalClientes       int     `json:"total_clientes"`
+	TotalPartidas       int     `json:"total_partidas"`
+	PorcentajeVictorias float64 `json:"porcentaje_victorias"`
+	JugaronHoy          int     `json:"jugaron_hoy"`
+}
+
+// NuevasEstadisticasJuego serializa las estadísticas generales sin datos sensibles
+func NuevasEstadisticasJuego(restaurante string, stats *models.EstadisticasGenerales) EstadisticasJuego {
+	return EstadisticasJuego{
+		Restaurante:         restaurante,
+		TotalClientes:       stats.TotalClientes,
+		TotalPartidas:       stats.TotalPartidas,
+		PorcentajeVictorias: stats.PorcentajeVictorias,
+		JugaronHoy:          stats.JugaronHoy,
+	}
+}
+
+// ClientePublico datos de un cliente que se muestran al verificar su teléfono
+type ClientePublico struct {
+	Nombre        string     `json:"nombre"`
+	Apellido      string     `json:"apellido"`
+	TotalJuegos   int        `json:"total_juegos"`
+	JuegosGanados int        `json:"juegos_ganados"`
+	TipoCliente   string     `json:"tipo_cliente"`
+	UltimoJuego   *time.Time `json:"ultimo_juego"`
+}
+
+// NuevoClientePublico serializa el cliente verificado
+func NuevoClientePublico(cliente *models.ClienteConEstadisticas) ClientePublico {
+	return ClientePublico{
+		Nombre:        cliente.Nombre,
+		Apellido:      cliente.Apellido,
+		TotalJuegos:   cliente.TotalJuegos,
+		JuegosGanados: cliente.JuegosGanados,
+		TipoCliente:   cliente.TipoCliente,
+		UltimoJuego:   cliente.FechaUltimoJuego,
+	}
+}
+
+// Mensaje respuesta sin recurso, solo con un texto para mostrar
+type Mensaje struct {
+	Mensaje string `json:"mensaje"`
+}
+
+// Objetivo tiempo objetivo firmado para la próxima partida
+type Objetivo struct {
+	TiempoObjetivo float64 `json:"tiempo_objetivo"`
+	TokenObjetivo  string  `json:"token_objetivo"`
+}
+
+// Sesion partida iniciada: el token se detiene con /game/stop y se envía con el resultado
+type Sesion struct {
+	TokenSesion    string    `json:"token_sesion"`
+	TiempoObjetivo float64   `json:"tiempo_objetivo"`
+	IniciadaEl     time.Time `json:"iniciada_el"`
+}
+
+// Detencion segundos que midió el servidor entre el inicio y la detención de la partida
+type Detencion struct {
+	TiempoMedido float64 `json:"tiempo_medido"`
+}
+
+// Branding identidad visual vigente (sin datos de auditoría)
+type Branding struct {
+	LogoURL         string `json:"logo_url"`
+	ColorPrimario   string `json:"color_primario"`
+	ColorSecundario string `json:"color_secundario"`
+	TextoLegal      string `json:"texto_legal"`
+}
+
+// NuevoBranding serializa el branding vigente
+func NuevoBranding(branding models.Branding) Branding {
+	return Branding{
+		LogoURL:         branding.LogoURL,
+		ColorPrimario:   branding.ColorPrimario,
+		ColorSecundario: branding.ColorSecundario,
+		TextoLegal:      branding.TextoLegal,
+	}
+}
+
+// ConfiguracionJuego parámetros del juego junto con el branding para armar la página
+type ConfiguracionJuego struct {
+	Juego    models.ConfiguracionJuego `json:"juego"`
+	Branding Branding                  `json:"branding"`
+}
+
+// Textos textos localizados de la página del juego
+type Textos struct {
+	Locale      string            `json:"locale"`
+	Disponibles []string          `json:"disponibles"`
+	Textos      map[string]string `json:"textos"`
+}
+
+// EsperaPedido espera estimada que se publica en la web
+type EsperaPedido struct {
+	MinutosEstimados int       `json:"minutos_estimados"`
+	PedidosEnCola    int       `json:"pedidos_en_cola"`
+	CalculadoEn      time.Time `json:"calculado_en"`
+}
+
+// NuevaEsperaPedido serializa la estimación sin el detalle de promedios
+func NuevaEsperaPedido(estimacion *models.EstimacionEspera) EsperaPedido {
+	return EsperaPedido{
+		MinutosEstimados: estimacion.MinutosEstimados,
+		PedidosEnCola:    estimacion.PedidosEnCola,
+		CalculadoEn:      estimacion.CalculadoEn,
+	}
+}
+
+// VoucherPantalla voucher revelado para mostrar en la pantalla del juego
+type VoucherPantalla struct {
+	Codigo           string `json:"codigo"`
+	Descuento        int    `json:"descuento"`
+	FechaVencimiento string `json:"fecha_vencimiento"`
+	VencimientoTexto string `json:"vencimiento_texto"`
+	QRURL            string `json:"qr_url"`
+	CodigoBarrasURL  string `json:"codigo_barras_url"`
+	PDFURL           string `json:"pdf_url"`
+}
+
+// NuevoVoucherPantalla serializa el voucher revelado
+func NuevoVoucherPantalla(voucher *models.VoucherPantalla) VoucherPantalla {
+	return VoucherPantalla{
+		Codigo:           voucher.Codigo,
+		Descuento:        voucher.Descuento,
+		FechaVencimiento: voucher.FechaVencimiento,
+		VencimientoTexto: voucher.VencimientoTexto,
+		QRURL:            voucher.QRURL,
+		CodigoBarrasURL:  voucher.CodigoBarrasURL,
+		PDFURL:           voucher.PDFURL,
+	}
+}
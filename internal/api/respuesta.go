@@ -0,0 +1,76 @@
+// Package api define el formato de las respuestas de /api/v1: todas usan el mismo
+// sobre (data, meta, error) con claves snake_case en español, y cada endpoint
+// serializa structs propios en lugar de gin.H o modelos de GORM.
+package api
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Version versión de la API expuesta en meta
+const Version = "v1"
+
+// Códigos de error estables para que el frontend no dependa del texto del mensaje
+const (
+	ErrorDatosInvalidos = "datos_invalidos"
+	ErrorNoEncontrado   = "no_encontrado"
+	ErrorNoDisponible   = "no_disponible"
+	ErrorJuegoRechazado = "juego_rechazado"
+	ErrorInterno        = "error_interno"
+	ErrorDemasiados     = "demasiados_intentos"
+)
+
+// Respuesta sobre común de /api/v1. Data es null cuando hay error (salvo que el
+// error traiga datos útiles, como la espera para volver a jugar)
+type Respuesta struct {
+	Data  interface{} `json:"data"`
+	Meta  Meta        `json:"meta"`
+	Error *Error      `json:"error,omitempty"`
+}
+
+// Meta datos de la respuesta que no forman parte del recurso
+type Meta struct {
+	Version string `json:"version"`
+	Total   *int   `json:"total,omitempty"` // Solo en listados
+}
+
+// Error detalle de un error de la API
+type Error struct {
+	Codigo  string `json:"codigo"`
+	Mensaje string `json:"mensaje"`
+	Detalle string `json:"detalle,omitempty"` // Errores de validación del request
+}
+
+// OK responde con el recurso dentro del sobre
+func OK(c *gin.Context, status int, data interface{}) {
+	c.JSON(status, Respuesta{Data: data, Meta: Meta{Version: Version}})
+}
+
+// Lista responde un listado indicando el total en meta
+func Lista(c *gin.Context, status int, data interface{}, total int) {
+	c.JSON(status, Respuesta{Data: data, Meta: Meta{Version: Version, Total: &total}})
+}
+
+// Fallo responde un error sin datos
+func Fallo(c *gin.Context, status int, codigo, mensaje string) {
+	FalloConDatos(c, status, codigo, mensaje, nil)
+}
+
+// FalloValidacion responde 400 con el error de binding como detalle
+func FalloValidacion(c *gin.Context, mensaje string, err error) {
+	c.JSON(http.StatusBadRequest, Respuesta{
+		Meta:  Meta{Version: Version},
+		Error: &Error{Codigo: ErrorDatosInvalidos, Mensaje: mensaje, Detalle: err.Error()},
+	})
+}
+
+// FalloConDatos responde un error acompañado de datos para el cliente
+func FalloConDatos(c *gin.Context, status int, codigo, mensaje string, data interface{}) {
+	c.JSON(status, Respuesta{
+		Data:  data,
+		Meta:  Meta{Version: Version},
+		Error: &Error{Codigo: codigo, Mensaje: mensaje},
+	})
+}
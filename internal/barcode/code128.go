@@ -0,0 +1,155 @@
+// Package barcode genera los códigos para escanear: Code 128 en los vouchers y QR en las mesas
+package barcode
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+)
+
+const (
+	code128SwitchC = 99
+	code128SwitchB = 100
+	code128StartB  = 104
+	code128StartC  = 105
+	code128Stop    = 106
+
+	// zonaSilenciosa módulos en blanco a cada lado, requeridos por los lectores
+	zonaSilenciosa = 10
+)
+
+// code128Patrones anchos de barra/espacio (en módulos) de cada símbolo Code 128
+var code128Patrones = [...]string{
+	"212222", "222122", "222221", "121223", "121322", "131222", "122213", "122312",
+	"132212", "221213", "221312", "231212", "112232", "122132", "122231", "113222",
+	"123122", "123221", "223211", "221132", "221231", "213212", "223112", "312131",
+	"311222", "321122", "321221", "312212", "322112", "322211", "212123", "212321",
+	"232121", "111323", "131123", "131321", "112313", "132113", "132311", "211313",
+	"231113", "231311", "112133", "112331", "132131", "113123", "113321", "133121",
+	"313121", "211331", "231131", "213113", "213311", "213131", "311123", "311321",
+	"331121", "312113", "312311", "332111", "314111", "221411", "431111", "111224",
+	"111422", "121124", "121421", "141122", "141221", "112214", "112412", "122114",
+	"122411", "142112", "142211", "241211", "221114", "413111", "241112", "134111",
+	"111242", "121142", "121241", "114212", "124112", "124211", "411212", "421112",
+	"421211", "212141", "214121", "412121", "111143", "111341", "131141", "114113",
+	"114311", "411113", "411311", "113141", "114131", "311141", "411131", "211412",
+	"211214", "211232", "2331112",
+}
+
+// Code128 codifica el texto (ASCII imprimible) y retorna la secuencia de módulos,
+// true para barra y false para espacio. Usa el set B y cambia al set C en tramos
+// largos de dígitos para acortar el código
+func Code128(texto string) ([]bool, error) {
+	if texto == "" {
+		return nil, fmt.Errorf("texto vacío")
+	}
+	for _, r := range texto {
+		if r < 32 || r > 126 {
+			return nil, fmt.Errorf("carácter no soportado en Code 128: %q", r)
+		}
+	}
+
+	simbolos := codificar(texto)
+
+	checksum := simbolos[0]
+	for i := 1; i < len(simbolos); i++ {
+		checksum += i * simbolos[i]
+	}
+	simbolos = append(simbolos, checksum%103, code128Stop)
+
+	var modulos []bool
+	for _, simbolo := range simbolos {
+		for i, ancho := range code128Patrones[simbolo] {
+			for j := 0; j < int(ancho-'0'); j++ {
+				modulos = append(modulos, i%2 == 0)
+			}
+		}
+	}
+	return modulos, nil
+}
+
+// Code128PNG genera la imagen PNG del código con el ancho de módulo y alto en píxeles indicados
+func Code128PNG(texto string, anchoModulo, alto int) ([]byte, error) {
+	modulos, err := Code128(texto)
+	if err != nil {
+		return nil, err
+	}
+
+	ancho := (len(modulos) + 2*zonaSilenciosa) * anchoModulo
+	img := image.NewGray(image.Rect(0, 0, ancho, alto))
+	for i := range img.Pix {
+		img.Pix[i] = 0xff
+	}
+
+	for i, barra := range modulos {
+		if !barra {
+			continue
+		}
+		x0 := (i + zonaSilenciosa) * anchoModulo
+		for x := x0; x < x0+anchoModulo; x++ {
+			for y := 0; y < alto; y++ {
+				img.SetGray(x, y, color.Gray{Y: 0})
+			}
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, fmt.Errorf("error codificando PNG: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// codificar convierte el texto en valores de símbolo (sin checksum ni stop)
+func codificar(texto string) []int {
+	var simbolos []int
+	enC := false
+
+	if n := digitosDesde(texto, 0); n >= 4 && n%2 == 0 || n == len(texto) && n >= 2 && n%2 == 0 {
+		simbolos = append(simbolos, code128StartC)
+		enC = true
+	} else {
+		simbolos = append(simbolos, code128StartB)
+	}
+
+	for i := 0; i < len(texto); {
+		if enC {
+			if digitosDesde(texto, i) >= 2 {
+				simbolos = append(simbolos, int(texto[i]-'0')*10+int(texto[i+1]-'0'))
+				i += 2
+				continue
+			}
+			simbolos = append(simbolos, code128SwitchB)
+			enC = false
+			continue
+		}
+
+		// Pasar a C solo si el tramo de dígitos es largo; con cantidad impar,
+		// el primer dígito se deja en B
+		if n := digitosDesde(texto, i); n >= 6 {
+			if n%2 == 1 {
+				simbolos = append(simbolos, int(texto[i])-32)
+				i++
+			}
+			simbolos = append(simbolos, code128SwitchC)
+			enC = true
+			continue
+		}
+
+		simbolos = append(simbolos, int(texto[i])-32)
+		i++
+	}
+
+	return simbolos
+}
+
+// digitosDesde cuenta los dígitos consecutivos a partir de la posición indicada
+func digitosDesde(texto string, desde int) int {
+	n := 0
+	for i := desde; i < len(texto) && texto[i] >= '0' && texto[i] <= '9'; i++ {
+		n++
+	}
+	return n
+}
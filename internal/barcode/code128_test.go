@@ -0,0 +1,68 @@
+package barcode
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCodificarCode128(t *testing.T) {
+	casos := []struct {
+		nombre   string
+		texto    string
+		simbolos []int
+	}{
+		{"solo letras en B", "AB", []int{code128StartB, 33, 34}},
+		{"dígitos pares empiezan en C", "123456", []int{code128StartC, 12, 34, 56}},
+		{"dos dígitos solos van en C", "42", []int{code128StartC, 42}},
+		{"tres dígitos quedan en B", "123", []int{code128StartB, 17, 18, 19}},
+		{"de C a B al terminar los dígitos", "1234AB", []int{code128StartC, 12, 34, code128SwitchB, 33, 34}},
+		{"tramo largo de dígitos pasa a C", "CH-123456", []int{code128StartB, 35, 40, 13, code128SwitchC, 12, 34, 56}},
+		{"tramo impar deja el primer dígito en B", "A1234567", []int{code128StartB, 33, 17, code128SwitchC, 23, 45, 67}},
+		{"tramo corto sigue en B", "A1234", []int{code128StartB, 33, 17, 18, 19, 20}},
+	}
+
+	for _, caso := range casos {
+		t.Run(caso.nombre, func(t *testing.T) {
+			if obtenido := codificar(caso.texto); !reflect.DeepEqual(obtenido, caso.simbolos) {
+				t.Errorf("codificar(%q) = %v, se esperaba %v", caso.texto, obtenido, caso.simbolos)
+			}
+		})
+	}
+}
+
+func TestCode128(t *testing.T) {
+	casos := []struct {
+		nombre  string
+		texto   string
+		modulos int
+		err     bool
+	}{
+		// Cada símbolo ocupa 11 módulos y el stop 13: inicio + datos + checksum + stop
+		{"letras", "AB", 11*4 + 13, false},
+		{"dígitos en C", "123456", 11*5 + 13, false},
+		{"vacío", "", 0, true},
+		{"fuera de ASCII", "año", 0, true},
+		{"control", "A\nB", 0, true},
+	}
+
+	for _, caso := range casos {
+		t.Run(caso.nombre, func(t *testing.T) {
+			modulos, err := Code128(caso.texto)
+			if caso.err {
+				if err == nil {
+					t.Errorf("Code128(%q) no retornó error", caso.texto)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Code128(%q) error inesperado: %v", caso.texto, err)
+			}
+			if len(modulos) != caso.modulos {
+				t.Errorf("Code128(%q) tiene %d módulos, se esperaban %d", caso.texto, len(modulos), caso.modulos)
+			}
+			if !modulos[0] || !modulos[len(modulos)-1] {
+				t.Errorf("Code128(%q) debe empezar y terminar con barra", caso.texto)
+			}
+		})
+	}
+}
@@ -0,0 +1,466 @@
+package barcode
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+)
+
+// qrZonaSilenciosa módulos en blanco alrededor del código QR, requeridos por los lectores
+const qrZonaSilenciosa = 4
+
+// qrVersion bloques de corrección de error de una versión QR en nivel M (15%)
+type qrVersion struct {
+	ecPorBloque int
+	bloques     []int // Codewords de datos de cada bloque
+	alineacion  []int // Centros de los patrones de alineación
+}
+
+// qrVersiones versiones 1 a 10 en nivel M: alcanzan para URLs de hasta 213 bytes
+var qrVersiones = [...]qrVersion{
+	{10, []int{16}, nil},
+	{16, []int{28}, []int{6, 18}},
+	{26, []int{44}, []int{6, 22}},
+	{18, []int{32, 32}, []int{6, 26}},
+	{24, []int{43, 43}, []int{6, 30}},
+	{16, []int{27, 27, 27, 27}, []int{6, 34}},
+	{18, []int{31, 31, 31, 31}, []int{6, 22, 38}},
+	{22, []int{38, 38, 39, 39}, []int{6, 24, 42}},
+	{22, []int{36, 36, 36, 37, 37}, []int{6, 26, 46}},
+	{26, []int{43, 43, 43, 43, 44}, []int{6, 28, 50}},
+}
+
+// QR codifica el texto en modo byte con corrección de error nivel M y retorna la
+// matriz de módulos indexada [fila][columna], true para módulo oscuro
+func QR(texto string) ([][]bool, error) {
+	if texto == "" {
+		return nil, fmt.Errorf("texto vacío")
+	}
+
+	version := 0
+	for v := range qrVersiones {
+		bitsLargo := 8
+		if v+1 >= 10 {
+			bitsLargo = 16
+		}
+		if 4+bitsLargo+8*len(texto) <= 8*qrCapacidad(v) {
+			version = v + 1
+			break
+		}
+	}
+	if version == 0 {
+		return nil, fmt.Errorf("texto demasiado largo para un código QR: %d bytes", len(texto))
+	}
+
+	q := nuevoQR(version)
+	q.colocarDatos(q.codewords(texto))
+
+	// Elegir la máscara con menor penalización, como indica el estándar
+	mejor, mejorPenalizacion := 0, -1
+	for mascara := 0; mascara < 8; mascara++ {
+		q.aplicarMascara(mascara)
+		q.dibujarFormato(mascara)
+		if p := q.penalizacion(); mejorPenalizacion < 0 || p < mejorPenalizacion {
+			mejor, mejorPenalizacion = mascara, p
+		}
+		q.aplicarMascara(mascara) // La máscara es XOR: aplicarla de nuevo la quita
+	}
+	q.aplicarMascara(mejor)
+	q.dibujarFormato(mejor)
+
+	return q.modulos, nil
+}
+
+// QRPNG genera la imagen PNG del código QR con el tamaño de módulo indicado en píxeles
+func QRPNG(texto string, tamModulo int) ([]byte, error) {
+	modulos, err := QR(texto)
+	if err != nil {
+		return nil, err
+	}
+
+	lado := (len(modulos) + 2*qrZonaSilenciosa) * tamModulo
+	img := image.NewGray(image.Rect(0, 0, lado, lado))
+	for i := range img.Pix {
+		img.Pix[i] = 0xff
+	}
+
+	for fila, modulosFila := range modulos {
+		for columna, oscuro := range modulosFila {
+			if !oscuro {
+				continue
+			}
+			x0 := (columna + qrZonaSilenciosa) * tamModulo
+			y0 := (fila + qrZonaSilenciosa) * tamModulo
+			for y := y0; y < y0+tamModulo; y++ {
+				for x := x0; x < x0+tamModulo; x++ {
+					img.SetGray(x, y, color.Gray{Y: 0})
+				}
+			}
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, fmt.Errorf("error codificando PNG: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// qrCodigo matriz en construcción; funcion marca los módulos fijos (patrones y formato)
+// que no llevan datos ni se enmascaran
+type qrCodigo struct {
+	version int
+	lado    int
+	modulos [][]bool
+	funcion [][]bool
+}
+
+// qrCapacidad codewords de datos de la versión (índice desde 0)
+func qrCapacidad(v int) int {
+	total := 0
+	for _, bloque := range qrVersiones[v].bloques {
+		total += bloque
+	}
+	return total
+}
+
+// nuevoQR arma la matriz con los patrones fijos de la versión
+func nuevoQR(version int) *qrCodigo {
+	lado := 17 + 4*version
+	q := &qrCodigo{version: version, lado: lado}
+	q.modulos = make([][]bool, lado)
+	q.funcion = make([][]bool, lado)
+	for i := range q.modulos {
+		q.modulos[i] = make([]bool, lado)
+		q.funcion[i] = make([]bool, lado)
+	}
+
+	// Patrones de sincronización
+	for i := 0; i < lado; i++ {
+		q.fijar(6, i, i%2 == 0)
+		q.fijar(i, 6, i%2 == 0)
+	}
+
+	// Patrones de posición en tres esquinas, con su separador blanco
+	for _, esquina := range [][2]int{{3, 3}, {lado - 4, 3}, {3, lado - 4}} {
+		for dy := -4; dy <= 4; dy++ {
+			for dx := -4; dx <= 4; dx++ {
+				x, y := esquina[0]+dx, esquina[1]+dy
+				if x < 0 || x >= lado || y < 0 || y >= lado {
+					continue
+				}
+				distancia := max(abs(dx), abs(dy))
+				q.fijar(x, y, distancia != 2 && distancia != 4)
+			}
+		}
+	}
+
+	// Patrones de alineación, salvo los que se superponen con los de posición
+	alineacion := qrVersiones[version-1].alineacion
+	for i, cx := range alineacion {
+		for j, cy := range alineacion {
+			if i == 0 && j == 0 || i == 0 && j == len(alineacion)-1 || i == len(alineacion)-1 && j == 0 {
+				continue
+			}
+			for dy := -2; dy <= 2; dy++ {
+				for dx := -2; dx <= 2; dx++ {
+					q.fijar(cx+dx, cy+dy, max(abs(dx), abs(dy)) != 1)
+				}
+			}
+		}
+	}
+
+	// Reservar las zonas de formato (se dibujan con la máscara elegida) y el módulo oscuro
+	q.dibujarFormato(0)
+	q.fijar(8, lado-8, true)
+
+	// Información de versión, desde la 7
+	if version >= 7 {
+		resto := version
+		for i := 0; i < 12; i++ {
+			resto = resto<<1 ^ (resto>>11)*0x1F25
+		}
+		bits := version<<12 | resto
+		for i := 0; i < 18; i++ {
+			oscuro := bits>>i&1 == 1
+			a, b := lado-11+i%3, i/3
+			q.fijar(a, b, oscuro)
+			q.fijar(b, a, oscuro)
+		}
+	}
+
+	return q
+}
+
+// fijar pone un módulo de función en la columna x, fila y
+func (q *qrCodigo) fijar(x, y int, oscuro bool) {
+	q.modulos[y][x] = oscuro
+	q.funcion[y][x] = true
+}
+
+// codewords arma los datos en modo byte con relleno, agrega la corrección de error de
+// cada bloque y los intercala como pide el estándar
+func (q *qrCodigo) codewords(texto string) []byte {
+	v := qrVersiones[q.version-1]
+	capacidad := qrCapacidad(q.version - 1)
+
+	var bits []bool
+	agregar := func(valor, largo int) {
+		for i := largo - 1; i >= 0; i-- {
+			bits = append(bits, valor>>i&1 == 1)
+		}
+	}
+	agregar(0b0100, 4) // Modo byte
+	if q.version >= 10 {
+		agregar(len(texto), 16)
+	} else {
+		agregar(len(texto), 8)
+	}
+	for i := 0; i < len(texto); i++ {
+		agregar(int(texto[i]), 8)
+	}
+	agregar(0, min(4, 8*capacidad-len(bits))) // Terminador
+	agregar(0, (8-len(bits)%8)%8)
+
+	datos := make([]byte, 0, capacidad)
+	for i := 0; i < len(bits); i += 8 {
+		var b byte
+		for j := 0; j < 8; j++ {
+			if bits[i+j] {
+				b |= 1 << (7 - j)
+			}
+		}
+		datos = append(datos, b)
+	}
+	for relleno := byte(0xEC); len(datos) < capacidad; relleno ^= 0xEC ^ 0x11 {
+		datos = append(datos, relleno)
+	}
+
+	divisor := rsDivisor(v.ecPorBloque)
+	var bloquesDatos, bloquesEC [][]byte
+	for _, largo := range v.bloques {
+		bloque := datos[:largo]
+		datos = datos[largo:]
+		bloquesDatos = append(bloquesDatos, bloque)
+		bloquesEC = append(bloquesEC, rsResto(bloque, divisor))
+	}
+
+	var resultado []byte
+	for i := 0; i < v.bloques[len(v.bloques)-1]; i++ {
+		for _, bloque := range bloquesDatos {
+			if i < len(bloque) {
+				resultado = append(resultado, bloque[i])
+			}
+		}
+	}
+	for i := 0; i < v.ecPorBloque; i++ {
+		for _, bloque := range bloquesEC {
+			resultado = append(resultado, bloque[i])
+		}
+	}
+	return resultado
+}
+
+// colocarDatos recorre la matriz en zigzag de a dos columnas, de abajo a la derecha
+// hacia arriba, salteando los módulos de función
+func (q *qrCodigo) colocarDatos(datos []byte) {
+	i := 0
+	for derecha := q.lado - 1; derecha >= 1; derecha -= 2 {
+		if derecha == 6 {
+			derecha = 5 // La columna de sincronización no lleva datos
+		}
+		for vertical := 0; vertical < q.lado; vertical++ {
+			for j := 0; j < 2; j++ {
+				x := derecha - j
+				y := vertical
+				if (derecha+1)&2 == 0 {
+					y = q.lado - 1 - vertical
+				}
+				if q.funcion[y][x] || i >= len(datos)*8 {
+					continue
+				}
+				q.modulos[y][x] = datos[i>>3]>>(7-i&7)&1 == 1
+				i++
+			}
+		}
+	}
+}
+
+// aplicarMascara invierte los módulos de datos que cumplen el patrón de la máscara
+func (q *qrCodigo) aplicarMascara(mascara int) {
+	for y := 0; y < q.lado; y++ {
+		for x := 0; x < q.lado; x++ {
+			if q.funcion[y][x] {
+				continue
+			}
+			var invertir bool
+			switch mascara {
+			case 0:
+				invertir = (x+y)%2 == 0
+			case 1:
+				invertir = y%2 == 0
+			case 2:
+				invertir = x%3 == 0
+			case 3:
+				invertir = (x+y)%3 == 0
+			case 4:
+				invertir = (x/3+y/2)%2 == 0
+			case 5:
+				invertir = x*y%2+x*y%3 == 0
+			case 6:
+				invertir = (x*y%2+x*y%3)%2 == 0
+			case 7:
+				invertir = ((x+y)%2+x*y%3)%2 == 0
+			}
+			q.modulos[y][x] = q.modulos[y][x] != invertir
+		}
+	}
+}
+
+// dibujarFormato escribe el nivel de corrección (M) y la máscara en sus dos copias
+func (q *qrCodigo) dibujarFormato(mascara int) {
+	datos := 0b00<<3 | mascara // Nivel M = 00
+	resto := datos
+	for i := 0; i < 10; i++ {
+		resto = resto<<1 ^ (resto>>9)*0x537
+	}
+	bits := (datos<<10 | resto) ^ 0x5412
+	bit := func(i int) bool { return bits>>i&1 == 1 }
+
+	for i := 0; i <= 5; i++ {
+		q.fijar(8, i, bit(i))
+	}
+	q.fijar(8, 7, bit(6))
+	q.fijar(8, 8, bit(7))
+	q.fijar(7, 8, bit(8))
+	for i := 9; i < 15; i++ {
+		q.fijar(14-i, 8, bit(i))
+	}
+
+	for i := 0; i < 8; i++ {
+		q.fijar(q.lado-1-i, 8, bit(i))
+	}
+	for i := 8; i < 15; i++ {
+		q.fijar(8, q.lado-15+i, bit(i))
+	}
+}
+
+// penalizacion puntaje del estándar para comparar máscaras: tramos largos del mismo
+// color, bloques 2x2, patrones parecidos a los de posición y desbalance de oscuros
+func (q *qrCodigo) penalizacion() int {
+	puntaje := 0
+	oscuros := 0
+	en := func(fila bool, i, j int) bool {
+		if fila {
+			return q.modulos[i][j]
+		}
+		return q.modulos[j][i]
+	}
+
+	for _, fila := range []bool{true, false} {
+		for i := 0; i < q.lado; i++ {
+			tramo := 1
+			for j := 1; j <= q.lado; j++ {
+				if j < q.lado && en(fila, i, j) == en(fila, i, j-1) {
+					tramo++
+					continue
+				}
+				if tramo >= 5 {
+					puntaje += tramo - 2
+				}
+				tramo = 1
+			}
+
+			for j := 0; j+7 <= q.lado; j++ {
+				if !(en(fila, i, j) && !en(fila, i, j+1) && en(fila, i, j+2) && en(fila, i, j+3) &&
+					en(fila, i, j+4) && !en(fila, i, j+5) && en(fila, i, j+6)) {
+					continue
+				}
+				if blancos(q.lado, j-4, j, func(k int) bool { return en(fila, i, k) }) ||
+					blancos(q.lado, j+7, j+11, func(k int) bool { return en(fila, i, k) }) {
+					puntaje += 40
+				}
+			}
+		}
+	}
+
+	for y := 0; y < q.lado; y++ {
+		for x := 0; x < q.lado; x++ {
+			if q.modulos[y][x] {
+				oscuros++
+			}
+			if x+1 < q.lado && y+1 < q.lado {
+				c := q.modulos[y][x]
+				if q.modulos[y][x+1] == c && q.modulos[y+1][x] == c && q.modulos[y+1][x+1] == c {
+					puntaje += 3
+				}
+			}
+		}
+	}
+
+	total := q.lado * q.lado
+	desvio := abs(oscuros*20-total*10) / total // Pasos de 5% lejos del 50%
+	return puntaje + desvio*10
+}
+
+// blancos indica si el tramo [desde, hasta) es blanco; fuera de la matriz cuenta como
+// blanco por la zona silenciosa
+func blancos(lado, desde, hasta int, oscuro func(int) bool) bool {
+	for k := desde; k < hasta; k++ {
+		if k >= 0 && k < lado && oscuro(k) {
+			return false
+		}
+	}
+	return true
+}
+
+// rsDivisor polinomio generador de Reed-Solomon del grado indicado en GF(256)
+func rsDivisor(grado int) []byte {
+	resultado := make([]byte, grado)
+	resultado[grado-1] = 1
+	raiz := byte(1)
+	for i := 0; i < grado; i++ {
+		for j := range resultado {
+			resultado[j] = gfMultiplicar(resultado[j], raiz)
+			if j+1 < len(resultado) {
+				resultado[j] ^= resultado[j+1]
+			}
+		}
+		raiz = gfMultiplicar(raiz, 0x02)
+	}
+	return resultado
+}
+
+// rsResto codewords de corrección de error de un bloque de datos
+func rsResto(datos, divisor []byte) []byte {
+	resultado := make([]byte, len(divisor))
+	for _, b := range datos {
+		factor := b ^ resultado[0]
+		copy(resultado, resultado[1:])
+		resultado[len(resultado)-1] = 0
+		for i := range resultado {
+			resultado[i] ^= gfMultiplicar(divisor[i], factor)
+		}
+	}
+	return resultado
+}
+
+// gfMultiplicar multiplica en GF(256) con el polinomio del estándar QR (0x11D)
+func gfMultiplicar(x, y byte) byte {
+	z := 0
+	for i := 7; i >= 0; i-- {
+		z = z<<1 ^ (z>>7)*0x11D
+		z ^= int(y>>i&1) * int(x)
+	}
+	return byte(z)
+}
+
+// abs valor absoluto de un entero
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
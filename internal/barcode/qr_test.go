@@ -0,0 +1,53 @@
+package barcode
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestQR(t *testing.T) {
+	casos := []struct {
+		nombre string
+		texto  string
+		lado   int
+		err    bool
+	}{
+		{"versión 1", "CH-1234", 21, false},
+		{"límite de la versión 1", strings.Repeat("a", 14), 21, false},
+		{"pasa a la versión 2", strings.Repeat("a", 15), 25, false},
+		{"URL de mesa", "https://cheesehouse.example/mesa?codigo=AB12CD34", 33, false},
+		{"versión 10", strings.Repeat("a", 213), 57, false},
+		{"demasiado largo", strings.Repeat("a", 214), 0, true},
+		{"vacío", "", 0, true},
+	}
+
+	for _, caso := range casos {
+		t.Run(caso.nombre, func(t *testing.T) {
+			modulos, err := QR(caso.texto)
+			if caso.err {
+				if err == nil {
+					t.Errorf("QR(%q) no retornó error", caso.texto)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("QR(%q) error inesperado: %v", caso.texto, err)
+			}
+			if len(modulos) != caso.lado {
+				t.Fatalf("QR(%q) tiene %d filas, se esperaban %d", caso.texto, len(modulos), caso.lado)
+			}
+			for _, fila := range modulos {
+				if len(fila) != caso.lado {
+					t.Fatalf("QR(%q) no es cuadrado", caso.texto)
+				}
+			}
+			// Los patrones de posición tienen el borde oscuro y el anillo siguiente claro
+			for _, esquina := range [][2]int{{0, 0}, {0, caso.lado - 7}, {caso.lado - 7, 0}} {
+				fila, columna := esquina[0], esquina[1]
+				if !modulos[fila][columna] || modulos[fila+1][columna+1] || !modulos[fila+3][columna+3] {
+					t.Errorf("QR(%q) sin patrón de posición en %v", caso.texto, esquina)
+				}
+			}
+		})
+	}
+}
@@ -2,8 +2,15 @@ package config
 
 import (
 	"fmt"
+	"log"
+	"net/url"
 	"os"
 	"strconv"
+	"strings"
+	"time"
+
+	"CheeseHouse/internal/fechas"
+	"CheeseHouse/internal/i18n"
 )
 
 type Config struct {
@@ -12,22 +19,305 @@ type Config struct {
 	Location       string
 
 	// Database
+	DBDriver   string // mysql o postgres
 	DBHost     string
 	DBPort     string
 	DBUser     string
 	DBPassword string
 	DBName     string
+	DBSSLMode  string // sslmode de Postgres
+
+	// Filas por INSERT en las altas masivas (envíos de campañas, vouchers)
+	DBBatchSize int
+
+	// Crear o ampliar las tablas según los modelos al arrancar. Las migraciones SQL
+	// versionadas corren siempre
+	DBAutoMigrate bool
+
+	// Días tras los cuales los vouchers canjeados o vencidos pasan a vouchers_archivo (0 = no archivar)
+	ArchiveAfterDays int
+
+	// Formato de los logs: "pretty" (emojis y colores, desarrollo), "plain" (clave=valor) o "json"
+	LogFormat string
+
+	// Log de consultas SQL (las lentas se miden con Performance.SlowQueryThreshold)
+	DBLog DBLogConfig
 
 	// WhatsApp
 	WhatsAppToken         string
 	WhatsAppURL           string
 	WhatsAppPhoneNumberID string
+	WhatsAppVerifyToken   string // Token para validar la suscripción del webhook en Meta
+	WhatsAppAppSecret     string // App secret de Meta: firma X-Hub-Signature-256 de cada webhook
+
+	// Cuenta de WhatsApp Business dueña de los templates; sin ella no se monitorea su aprobación
+	WhatsAppBusinessAccountID string
+
+	// SMS por Twilio: respaldo para los vouchers cuyo WhatsApp no salió
+	SMS SMSConfig
+
+	// Límites de envío de WhatsApp (0 = sin límite)
+	WhatsAppDailyRecipientLimit    int // Destinatarios distintos en 24h (tier de Meta)
+	WhatsAppMonthlyConversationCap int // Conversaciones por mes según el presupuesto
+
+	// Parte del límite diario que pueden usar las campañas según la calidad del número en
+	// Meta (con GREEN, todo); el resto queda para los vouchers. 0 pausa el marketing
+	WhatsAppMarketingShareYellow float64
+	WhatsAppMarketingShareRed    float64
+
+	// Idioma por defecto para clientes y mensajes
+	DefaultLanguage string
+
+	// URL pública del sistema (para links en mensajes)
+	PublicBaseURL string
+
+	// Proxies (IPs o CIDR) cuyo X-Forwarded-For se cree para obtener la IP del cliente; sin
+	// ninguno se usa la IP de la conexión. TrustedPlatform toma la IP de un header del
+	// proveedor ("cloudflare", "google" o el nombre del header) y tiene prioridad
+	TrustedProxies  []string
+	TrustedPlatform string
 
 	// JWT
 	JWTSecret string
 
+	// Admin que se crea en el primer arranque, cuando todavía no hay usuarios
+	InitialAdminName     string
+	InitialAdminEmail    string
+	InitialAdminPassword string
+
+	// Secreto para firmar links enviados a clientes
+	LinkSigningSecret string
+
+	// Webhooks de eventos salientes (CRM)
+	EventWebhookURLs   []string
+	EventWebhookSecret string
+
 	// Game
 	Game GameConfig
+
+	// Notificaciones
+	Notifications NotificationConfig
+
+	// Costos para estimar campañas
+	Costs CostConfig
+
+	// Branding por defecto (se puede sobrescribir desde el panel admin)
+	Branding BrandingConfig
+
+	// Alertas operativas (centro de notificaciones, Slack, Telegram)
+	Alerts AlertConfig
+
+	// Horario de atención por defecto para la respuesta automática de WhatsApp
+	BusinessHours BusinessHoursConfig
+
+	// Estados de pedido que avisan al cliente por WhatsApp (template "pedido_<estado>");
+	// "confirmado" es el aviso con la espera estimada al cargar el pedido
+	OrderStatusNotifications []string
+
+	// Minutos de preparación que se prometen si todavía no hay historial
+	OrderPrepMinutes int
+
+	// Pedidos que la cocina prepara en paralelo, para estimar la espera según la cola
+	KitchenParallelOrders int
+
+	// Umbrales de requests y consultas lentas para las métricas internas
+	Performance PerformanceConfig
+
+	// Envío de las métricas internas a StatsD o a un Prometheus Pushgateway, para
+	// despliegues sin un Prometheus que las lea
+	MetricsPush MetricsPushConfig
+
+	// Clasificación de clientes (nuevo, ocasional, frecuente) según su actividad
+	ClientTiers ClientTierConfig
+
+	// Estado compartido entre instancias cuando corren varias detrás de un balanceador
+	Scaling ScalingConfig
+
+	// Tareas de mantenimiento programadas (vencimientos, archivado, reintentos, resumen diario)
+	Maintenance MaintenanceConfig
+
+	// Ranking semanal de las partidas más cercanas al objetivo y premio al ganador
+	Leaderboard LeaderboardConfig
+
+	// Topes de canje por empleado y umbrales del reporte de anomalías en caja
+	Redemption RedemptionConfig
+
+	// Dónde se guardan los archivos generados (reportes, exportaciones, QR) y por cuánto
+	// tiempo valen sus links de descarga
+	Storage StorageConfig
+
+	// Frontend del juego: assets con hash en el nombre y cache larga
+	Static StaticConfig
+
+	// Tiempo máximo para terminar requests y envíos de WhatsApp en curso al apagar
+	ShutdownTimeout time.Duration
+
+	// Tiempo durante el que un envío de partida con Idempotency-Key devuelve la respuesta
+	// original en lugar de procesarse de nuevo
+	IdempotencyTTL time.Duration
+
+	// Intercambios de /api/game que se guardan con sus cuerpos en /api/dev/requests para
+	// depurar el frontend (0 = desactivado; nunca en producción)
+	DebugCaptureRequests int
+
+	// Inyección de fallas para probar reintentos y outbox (solo desarrollo): habilita
+	// /api/dev/fallas y arranca con estas tasas y latencia
+	FaultInjection FaultInjectionConfig
+}
+
+type FaultInjectionConfig struct {
+	Enabled           bool
+	WhatsAppErrorRate float64 // 0-1: envíos que reciben un 500
+	WhatsApp429Rate   float64 // 0-1: envíos que reciben un 429
+	DBLatencyMs       int     // Demora agregada a cada consulta
+}
+
+type DBLogConfig struct {
+	Target string // "stdout", "stderr" o ruta de un archivo
+	Format string // "text" (clave=valor) o "json"
+	AllSQL bool   // Loguear todas las consultas; solo se respeta fuera de producción
+}
+
+type ClientTierConfig struct {
+	OccasionalScore     float64 // Puntaje a superar para ser "ocasional"
+	FrequentScore       float64 // Puntaje a superar para ser "frecuente"
+	RecencyHalfLifeDays int     // Días en que una partida pasa a valer la mitad (0 = todas valen 1)
+	RecalcTime          int     // Minutos desde medianoche del recálculo diario
+}
+
+type ScalingConfig struct {
+	SharedState string        // "memory" (una sola instancia) o "database" (varias instancias)
+	InstanceID  string        // Identifica a la instancia en el liderazgo y en /info
+	CacheTTL    time.Duration // Con estado en la base, cada cuánto se releen los datos cacheados
+}
+
+// Compartido indica si el estado efímero vive en la base para varias instancias
+func (s ScalingConfig) Compartido() bool {
+	return s.SharedState == "database"
+}
+
+// CacheVigente indica si un dato cacheado en cargadoEn todavía sirve. Con una sola
+// instancia el cache vale hasta que se invalida al editar; con varias, otra instancia
+// pudo haberlo editado, así que se relee después de SHARED_CACHE_TTL_SECONDS
+func (s ScalingConfig) CacheVigente(cargadoEn time.Time) bool {
+	return !s.Compartido() || time.Since(cargadoEn) < s.CacheTTL
+}
+
+type MaintenanceConfig struct {
+	Enabled              bool // Correr las tareas programadas; con false solo se ejecutan a mano
+	Time                 int  // Minutos desde medianoche de las tareas diarias
+	CampaignRetryMinutes int  // Cada cuánto se reintentan los envíos de campañas fallidos (0 = nunca)
+	CampaignMaxAttempts  int  // Intentos totales de un envío de campaña, contando el primero
+}
+
+type LeaderboardConfig struct {
+	Size           int // Posiciones que muestra el ranking público
+	BonusDiscount  int // Descuento del voucher para el ganador al cerrar la semana sola (0 = sin premio automático)
+	BonusValidDays int // Días de validez del voucher del ganador
+}
+
+type RedemptionConfig struct {
+	DailyCapPerEmployee int     // Canjes por empleado por día (0 = sin límite); cada usuario puede tener el suyo
+	HighValueDiscount   int     // Descuento desde el que un voucher se considera de alto valor
+	AnomalyFactor       float64 // Veces sobre el promedio de la cadena que marca a un empleado
+	AnomalyMinActivity  int     // Canjes + intentos mínimos en el período para evaluar a un empleado
+	ApprovalAbove       int     // Descuentos mayores a este piden el PIN de un encargado al canjear (0 = nunca)
+}
+
+type StaticConfig struct {
+	Dir         string        // Carpeta del frontend del juego
+	Fingerprint bool          // Publicar JS/CSS/imágenes con el hash del contenido en /static
+	AssetMaxAge time.Duration // Cache de los assets con hash (el HTML siempre se revalida)
+}
+
+type StorageConfig struct {
+	Driver       string // "local" (carpeta del servidor) o "s3" (S3 o compatible, ej. MinIO)
+	LocalPath    string
+	S3Endpoint   string // Vacío = endpoint de AWS para la región
+	S3Region     string
+	S3Bucket     string
+	S3AccessKey  string
+	S3SecretKey  string
+	S3PathStyle  bool          // URLs endpoint/bucket/clave en lugar de bucket.endpoint/clave (MinIO)
+	SignedURLTTL time.Duration // Vigencia de los links de descarga firmados
+}
+
+type SMSConfig struct {
+	TwilioURL        string
+	TwilioAccountSID string
+	TwilioAuthToken  string
+	From             string // Número remitente (+1...) o Messaging Service SID (MG...)
+	Fallback         bool   // Mandar por SMS el texto del voucher cuando el WhatsApp falla
+}
+
+// Configurado indica si hay credenciales de Twilio completas
+func (s SMSConfig) Configurado() bool {
+	return s.TwilioAccountSID != "" && s.TwilioAuthToken != "" && s.From != ""
+}
+
+type PerformanceConfig struct {
+	SlowRequestThreshold time.Duration // Requests que superan este tiempo se cuentan y loguean como lentos
+	SlowQueryThreshold   time.Duration // Consultas SQL que superan este tiempo se loguean y asocian a la ruta
+}
+
+type MetricsPushConfig struct {
+	StatsDAddr     string        // host:puerto UDP de StatsD; vacío = sin StatsD
+	PushgatewayURL string        // URL base del Pushgateway; vacío = sin Pushgateway
+	Prefix         string        // Prefijo de los nombres de las métricas
+	Job            string        // Job con que se agrupan en el Pushgateway (la instancia es INSTANCE_ID)
+	Interval       time.Duration // Cada cuánto se envían
+}
+
+// Habilitado indica si hay algún destino configurado para enviar las métricas
+func (m MetricsPushConfig) Habilitado() bool {
+	return m.StatsDAddr != "" || m.PushgatewayURL != ""
+}
+
+type BusinessHoursConfig struct {
+	Open                  int // Minutos desde medianoche; se usa si la sucursal no cargó horarios
+	Close                 int // Minutos desde medianoche; si es <= Open cierra al día siguiente
+	ResponseWindowMinutes int // Tiempo de respuesta que se promete con el local abierto
+}
+
+type AlertConfig struct {
+	SlackWebhookURL  string
+	TelegramBotToken string
+	TelegramChatID   string
+
+	// Picos de emisión de vouchers
+	IssuanceSpikeFactor          float64 // Veces sobre el promedio por hora que dispara la alerta
+	IssuanceSpikeMinPerHour      int     // Mínimo de vouchers en la hora para considerar un pico
+	IssuanceBaselineHours        int     // Horas previas usadas como línea base
+	IssuanceSpikeRequireApproval bool    // Exigir aprobación de empleado para premios durante un pico
+
+	// SLA de atención de pedidos/conversaciones de WhatsApp
+	SLAFirstResponseMinutes int
+	SLAResolutionMinutes    int
+}
+
+type BrandingConfig struct {
+	LogoURL        string
+	PrimaryColor   string
+	SecondaryColor string
+	LegalText      string
+}
+
+type CostConfig struct {
+	Currency                  string
+	MarketingConversationCost float64 // Precio por conversación de marketing de WhatsApp
+	AverageTicket             float64 // Ticket promedio sobre el que se aplica el descuento
+	DefaultRedemptionRate     float64 // Tasa de canje (0-1) si no hay historial suficiente
+}
+
+type NotificationConfig struct {
+	Timezone        string
+	QuietHoursStart int // Minutos desde medianoche
+	QuietHoursEnd   int // Minutos desde medianoche
+}
+
+type WhatsAppTemplateIdioma struct {
+	CodigoIdioma string
+	Templates    map[string]string
 }
 
 type PhoneValidation struct {
@@ -44,8 +334,48 @@ type GameConfig struct {
 	WinDiscount          int
 	LoseDiscount         int
 	Tolerance            float64
-	VoucherValidityDays  int
-	GamesRequireApproval int
+	VoucherValidityDays  int // Vigencia por defecto; las reglas de vigencia la ajustan por tipo y día
+	GamesRequireApproval int // Partidas en el mismo día a partir de las cuales hace falta aprobación
+	LossCooldownMinutes  int // Espera antes de volver a jugar tras perder (0 = sin espera)
+
+	// Tope de partidas con voucher por cliente, aunque un empleado apruebe seguir jugando
+	// (0 = sin tope). La semana va de lunes a domingo en la zona horaria del restaurante
+	MaxPlaysPerDay  int
+	MaxPlaysPerWeek int
+
+	// Corte automático si la tasa de victorias se dispara (posible exploit del frontend)
+	WinRateWindow  int     // Partidas consideradas (0 = desactivado)
+	WinRateCeiling float64 // Tasa de victorias máxima aceptada (0-1)
+	WinRateAction  string  // 'ajustar' (reduce la tolerancia a la mitad) o 'pausar'
+
+	// Vouchers flash: válidos solo unas horas desde la emisión ("canjealo hoy antes de las 23:00")
+	FlashVouchers      string // Partidas con voucher flash: '' (ninguna), 'ganado', 'perdido' o 'todos'
+	FlashVoucherHours  int    // Horas de validez desde la emisión
+	FlashVoucherCutoff int    // Minutos desde medianoche; si llega antes, vence a esa hora (-1 = sin corte)
+
+	// Dónde se canjean los vouchers de partida: 'cadena' (cualquier sucursal) o 'sucursal'
+	// (solo la que lo emitió, salvo que un admin lo transfiera)
+	VoucherBranchScope string
+
+	// Diferencia máxima (segundos) aceptada entre el tiempo que informa el cliente y el que
+	// midió el servidor entre /start y /stop; cubre la latencia de la red
+	SessionMaxDrift float64
+
+	// Envíos de partida por hora aceptados por IP y por teléfono (token bucket), para frenar
+	// la granja de vouchers; los admins los ajustan en caliente desde los parámetros del juego
+	SubmitLimitPerIP    int
+	SubmitLimitPerPhone int
+	SubmitBurst         int // Envíos seguidos permitidos antes de que rija el ritmo por hora
+
+	// Si la entrega por WhatsApp falla hace más de WhatsAppOutageMinutes, el juego muestra el
+	// código en pantalla en lugar de prometer el mensaje (solo con CodeOnScreenOnOutage)
+	CodeOnScreenOnOutage  bool
+	WhatsAppOutageMinutes int
+
+	// Voucher en la pantalla del juego (código y QR, tras confirmar los últimos dígitos del
+	// teléfono): 'no', 'opcional' (para quien marca que no usa WhatsApp, que no recibe el
+	// mensaje) o 'siempre' (además del WhatsApp)
+	ScreenVoucherMode string
 }
 
 func Load() *Config {
@@ -54,15 +384,24 @@ func Load() *Config {
 		RestaurantName: getEnv("RESTAURANT_NAME", "CheeseHouse"),
 		Location:       getEnv("LOCATION", "Centro"),
 
+		DBDriver:   strings.ToLower(getEnv("DB_DRIVER", "mysql")),
 		DBHost:     getEnv("DB_HOST", "127.0.0.1"),
-		DBPort:     getEnv("DB_PORT", "3306"),
 		DBUser:     getEnv("DB_USER", "root"),
 		DBPassword: getEnv("DB_PASSWORD", "12345"),
 		DBName:     getEnv("DB_NAME", "cheesehouse"),
+		DBSSLMode:  getEnv("DB_SSLMODE", "disable"),
 
 		WhatsAppToken:         getEnv("WHATSAPP_TOKEN", ""),
 		WhatsAppURL:           getEnv("WHATSAPP_URL", "https://api.twilio.com"),
 		WhatsAppPhoneNumberID: getEnv("WHATSAPP_PHONE_NUMBER_ID", ""),
+		WhatsAppVerifyToken:   getEnv("WHATSAPP_VERIFY_TOKEN", ""),
+		WhatsAppAppSecret:     getEnv("WHATSAPP_APP_SECRET", ""),
+
+		WhatsAppBusinessAccountID: getEnv("WHATSAPP_BUSINESS_ACCOUNT_ID", ""),
+
+		DefaultLanguage: getEnv("DEFAULT_LANGUAGE", "es"),
+
+		PublicBaseURL: getEnv("PUBLIC_BASE_URL", "http://localhost:8080"),
 
 		JWTSecret: getEnv("JWT_SECRET", "your-secret-key"),
 
@@ -74,9 +413,202 @@ func Load() *Config {
 			Tolerance:            0.1,
 			VoucherValidityDays:  30,
 			GamesRequireApproval: 3,
+			LossCooldownMinutes:  10,
+			MaxPlaysPerDay:       getEnvInt("MAX_PLAYS_PER_DAY", 0),
+			MaxPlaysPerWeek:      getEnvInt("MAX_PLAYS_PER_WEEK", 0),
+			WinRateWindow:        50,
+			WinRateCeiling:       0.6,
+			WinRateAction:        getEnv("WIN_RATE_ACTION", "ajustar"),
+			FlashVouchers:        strings.ToLower(getEnv("FLASH_VOUCHERS", "")),
+			FlashVoucherHours:    getEnvInt("FLASH_VOUCHER_HOURS", 4),
+			FlashVoucherCutoff:   parseHoraDelDia(getEnv("FLASH_VOUCHER_CUTOFF", "23:00"), -1), // "off" = sin corte
+			VoucherBranchScope:   strings.ToLower(getEnv("VOUCHER_BRANCH_SCOPE", "cadena")),
+			SessionMaxDrift:      getEnvFloat("GAME_SESSION_MAX_DRIFT", 0.5),
+			SubmitLimitPerIP:     getEnvInt("GAME_SUBMIT_LIMIT_IP", 30),
+			SubmitLimitPerPhone:  getEnvInt("GAME_SUBMIT_LIMIT_PHONE", 6),
+			SubmitBurst:          getEnvInt("GAME_SUBMIT_BURST", 3),
+
+			CodeOnScreenOnOutage:  getEnv("CODE_ON_SCREEN_ON_WHATSAPP_OUTAGE", "false") == "true",
+			WhatsAppOutageMinutes: getEnvInt("WHATSAPP_OUTAGE_MINUTES", 10),
+			ScreenVoucherMode:     strings.ToLower(getEnv("SCREEN_VOUCHER_MODE", "no")),
 		},
 	}
 
+	cfg.InitialAdminName = getEnv("ADMIN_NAME", "Administrador")
+	cfg.InitialAdminEmail = getEnv("ADMIN_EMAIL", "")
+	cfg.InitialAdminPassword = getEnv("ADMIN_PASSWORD", "")
+
+	cfg.LinkSigningSecret = getEnv("LINK_SIGNING_SECRET", cfg.JWTSecret)
+
+	if val := getEnv("EVENT_WEBHOOK_URLS", ""); val != "" {
+		for _, url := range strings.Split(val, ",") {
+			if url = strings.TrimSpace(url); url != "" {
+				cfg.EventWebhookURLs = append(cfg.EventWebhookURLs, url)
+			}
+		}
+	}
+	cfg.EventWebhookSecret = getEnv("EVENT_WEBHOOK_SECRET", "")
+
+	for _, proxy := range strings.Split(getEnv("TRUSTED_PROXIES", ""), ",") {
+		if proxy = strings.TrimSpace(proxy); proxy != "" {
+			cfg.TrustedProxies = append(cfg.TrustedProxies, proxy)
+		}
+	}
+	cfg.TrustedPlatform = strings.TrimSpace(getEnv("TRUSTED_PLATFORM", ""))
+
+	cfg.Notifications = NotificationConfig{
+		Timezone:        getEnv("TIMEZONE", "America/Argentina/Buenos_Aires"),
+		QuietHoursStart: parseHoraDelDia(getEnv("QUIET_HOURS_START", "00:00"), 0),
+		QuietHoursEnd:   parseHoraDelDia(getEnv("QUIET_HOURS_END", "09:00"), 9*60),
+	}
+
+	cfg.WhatsAppDailyRecipientLimit = getEnvInt("WHATSAPP_DAILY_RECIPIENT_LIMIT", 1000)
+	cfg.WhatsAppMonthlyConversationCap = getEnvInt("WHATSAPP_MONTHLY_CONVERSATION_CAP", 0)
+	cfg.WhatsAppMarketingShareYellow = getEnvFloat("WHATSAPP_MARKETING_SHARE_YELLOW", 0.5)
+	cfg.WhatsAppMarketingShareRed = getEnvFloat("WHATSAPP_MARKETING_SHARE_RED", 0)
+
+	cfg.Costs = CostConfig{
+		Currency:                  getEnv("COST_CURRENCY", "ARS"),
+		MarketingConversationCost: getEnvFloat("WHATSAPP_MARKETING_CONVERSATION_COST", 0),
+		AverageTicket:             getEnvFloat("AVERAGE_TICKET", 0),
+		DefaultRedemptionRate:     getEnvFloat("DEFAULT_REDEMPTION_RATE", 0.15),
+	}
+
+	cfg.Branding = BrandingConfig{
+		LogoURL:        getEnv("BRAND_LOGO_URL", ""),
+		PrimaryColor:   getEnv("BRAND_PRIMARY_COLOR", "#F4B400"),
+		SecondaryColor: getEnv("BRAND_SECONDARY_COLOR", "#3E2723"),
+		LegalText:      getEnv("BRAND_LEGAL_TEXT", "Voucher personal e intransferible. No acumulable con otras promociones."),
+	}
+
+	cfg.Alerts = AlertConfig{
+		SlackWebhookURL:              getEnv("SLACK_WEBHOOK_URL", ""),
+		TelegramBotToken:             getEnv("TELEGRAM_BOT_TOKEN", ""),
+		TelegramChatID:               getEnv("TELEGRAM_CHAT_ID", ""),
+		IssuanceSpikeFactor:          getEnvFloat("ISSUANCE_SPIKE_FACTOR", 3),
+		IssuanceSpikeMinPerHour:      getEnvInt("ISSUANCE_SPIKE_MIN_PER_HOUR", 20),
+		IssuanceBaselineHours:        getEnvInt("ISSUANCE_BASELINE_HOURS", 168),
+		IssuanceSpikeRequireApproval: getEnv("ISSUANCE_SPIKE_REQUIRE_APPROVAL", "false") == "true",
+		SLAFirstResponseMinutes:      getEnvInt("SLA_FIRST_RESPONSE_MINUTES", 10),
+		SLAResolutionMinutes:         getEnvInt("SLA_RESOLUTION_MINUTES", 60),
+	}
+
+	cfg.BusinessHours = BusinessHoursConfig{
+		Open:                  parseHoraDelDia(getEnv("BUSINESS_HOURS_OPEN", "12:00"), 12*60),
+		Close:                 parseHoraDelDia(getEnv("BUSINESS_HOURS_CLOSE", "00:00"), 0),
+		ResponseWindowMinutes: getEnvInt("AUTO_REPLY_RESPONSE_MINUTES", cfg.Alerts.SLAFirstResponseMinutes),
+	}
+
+	for _, estado := range strings.Split(getEnv("ORDER_STATUS_NOTIFICATIONS", "confirmado,procesando,completado"), ",") {
+		if estado = strings.TrimSpace(estado); estado != "" {
+			cfg.OrderStatusNotifications = append(cfg.OrderStatusNotifications, estado)
+		}
+	}
+
+	cfg.OrderPrepMinutes = getEnvInt("ORDER_PREP_MINUTES", 20)
+	cfg.KitchenParallelOrders = getEnvInt("KITCHEN_PARALLEL_ORDERS", 3)
+
+	cfg.Performance = PerformanceConfig{
+		SlowRequestThreshold: time.Duration(getEnvInt("SLOW_REQUEST_MS", 500)) * time.Millisecond,
+		SlowQueryThreshold:   time.Duration(getEnvInt("DB_SLOW_QUERY_MS", 200)) * time.Millisecond,
+	}
+
+	cfg.MetricsPush = MetricsPushConfig{
+		StatsDAddr:     getEnv("METRICS_STATSD_ADDR", ""),
+		PushgatewayURL: strings.TrimRight(getEnv("METRICS_PUSHGATEWAY_URL", ""), "/"),
+		Prefix:         getEnv("METRICS_PREFIX", "cheesehouse"),
+		Job:            getEnv("METRICS_PUSH_JOB", "cheesehouse"),
+		Interval:       time.Duration(getEnvInt("METRICS_PUSH_INTERVAL_SECONDS", 15)) * time.Second,
+	}
+
+	cfg.ClientTiers = ClientTierConfig{
+		OccasionalScore:     getEnvFloat("TIER_OCCASIONAL_SCORE", 3),
+		FrequentScore:       getEnvFloat("TIER_FREQUENT_SCORE", 10),
+		RecencyHalfLifeDays: getEnvInt("TIER_HALF_LIFE_DAYS", 90),
+		RecalcTime:          parseHoraDelDia(getEnv("TIER_RECALC_TIME", "04:00"), 4*60),
+	}
+
+	instancia, _ := os.Hostname()
+	cfg.Scaling = ScalingConfig{
+		SharedState: getEnv("SHARED_STATE", "memory"),
+		InstanceID:  getEnv("INSTANCE_ID", fmt.Sprintf("%s-%d", instancia, os.Getpid())),
+		CacheTTL:    time.Duration(getEnvInt("SHARED_CACHE_TTL_SECONDS", 30)) * time.Second,
+	}
+
+	cfg.Maintenance = MaintenanceConfig{
+		Enabled:              getEnv("MAINTENANCE_ENABLED", "true") == "true",
+		Time:                 parseHoraDelDia(getEnv("MAINTENANCE_TIME", "03:30"), 3*60+30),
+		CampaignRetryMinutes: getEnvInt("CAMPAIGN_RETRY_MINUTES", 60),
+		CampaignMaxAttempts:  getEnvInt("CAMPAIGN_MAX_ATTEMPTS", 3),
+	}
+
+	cfg.Leaderboard = LeaderboardConfig{
+		Size:           getEnvInt("LEADERBOARD_SIZE", 10),
+		BonusDiscount:  getEnvInt("LEADERBOARD_BONUS_DISCOUNT", 0),
+		BonusValidDays: getEnvInt("LEADERBOARD_BONUS_VALID_DAYS", 30),
+	}
+
+	// El puerto por defecto depende del driver
+	puertoDB := "3306"
+	if cfg.DBDriver == "postgres" {
+		puertoDB = "5432"
+	}
+	cfg.DBPort = getEnv("DB_PORT", puertoDB)
+	cfg.DBBatchSize = getEnvInt("DB_BATCH_SIZE", 500)
+	// En producción el esquema se actualiza a propósito (-migrar), no en cada arranque
+	cfg.DBAutoMigrate = getEnv("DB_AUTO_MIGRATE", strconv.FormatBool(!cfg.IsProduction())) == "true"
+
+	cfg.ShutdownTimeout = time.Duration(getEnvInt("SHUTDOWN_TIMEOUT_SECONDS", 30)) * time.Second
+	cfg.IdempotencyTTL = time.Duration(getEnvInt("IDEMPOTENCY_TTL_HOURS", 24)) * time.Hour
+	cfg.DebugCaptureRequests = getEnvInt("DEBUG_CAPTURE_REQUESTS", 0)
+	cfg.FaultInjection = FaultInjectionConfig{
+		Enabled:           getEnv("FAULT_INJECTION", "false") == "true",
+		WhatsAppErrorRate: getEnvFloat("FAULT_WHATSAPP_ERROR_RATE", 0),
+		WhatsApp429Rate:   getEnvFloat("FAULT_WHATSAPP_429_RATE", 0),
+		DBLatencyMs:       getEnvInt("FAULT_DB_LATENCY_MS", 0),
+	}
+
+	cfg.SMS = SMSConfig{
+		TwilioURL:        strings.TrimSuffix(getEnv("TWILIO_URL", "https://api.twilio.com"), "/"),
+		TwilioAccountSID: getEnv("TWILIO_ACCOUNT_SID", ""),
+		TwilioAuthToken:  getEnv("TWILIO_AUTH_TOKEN", ""),
+		From:             getEnv("TWILIO_SMS_FROM", ""),
+		Fallback:         getEnv("SMS_FALLBACK", "true") == "true",
+	}
+
+	cfg.Static = StaticConfig{
+		Dir:         getEnv("STATIC_DIR", "./Front/timing-game"),
+		Fingerprint: getEnv("STATIC_FINGERPRINT", "true") == "true",
+		AssetMaxAge: time.Duration(getEnvInt("STATIC_CACHE_DAYS", 365)) * 24 * time.Hour,
+	}
+
+	cfg.Storage = StorageConfig{
+		Driver:       strings.ToLower(getEnv("STORAGE_DRIVER", "local")),
+		LocalPath:    getEnv("STORAGE_LOCAL_PATH", "./data/archivos"),
+		S3Endpoint:   getEnv("S3_ENDPOINT", ""),
+		S3Region:     getEnv("S3_REGION", "us-east-1"),
+		S3Bucket:     getEnv("S3_BUCKET", ""),
+		S3AccessKey:  getEnv("S3_ACCESS_KEY", ""),
+		S3SecretKey:  getEnv("S3_SECRET_KEY", ""),
+		S3PathStyle:  getEnv("S3_PATH_STYLE", "false") == "true",
+		SignedURLTTL: time.Duration(getEnvInt("STORAGE_SIGNED_URL_MINUTES", 15)) * time.Minute,
+	}
+
+	cfg.ArchiveAfterDays = getEnvInt("ARCHIVE_AFTER_DAYS", 180)
+
+	cfg.LogFormat = strings.ToLower(getEnv("LOG_FORMAT", "pretty"))
+
+	// El log SQL sigue al log general si no se configura aparte
+	formatoSQL := "text"
+	if cfg.LogFormat == "json" {
+		formatoSQL = "json"
+	}
+	cfg.DBLog = DBLogConfig{
+		Target: getEnv("DB_LOG_TARGET", "stdout"),
+		Format: strings.ToLower(getEnv("DB_LOG_FORMAT", formatoSQL)),
+		AllSQL: getEnv("DB_LOG_ALL_SQL", "false") == "true",
+	}
+
 	// Override game config from env if present
 	if val := getEnv("MIN_TARGET_TIME", ""); val != "" {
 		if f, err := strconv.ParseFloat(val, 64); err == nil {
@@ -98,18 +630,45 @@ func Load() *Config {
 			cfg.Game.LoseDiscount = i
 		}
 	}
+	if val := getEnv("LOSS_COOLDOWN_MINUTES", ""); val != "" {
+		if i, err := strconv.Atoi(val); err == nil && i >= 0 {
+			cfg.Game.LossCooldownMinutes = i
+		}
+	}
+	if val := getEnv("WIN_RATE_WINDOW", ""); val != "" {
+		if i, err := strconv.Atoi(val); err == nil && i >= 0 {
+			cfg.Game.WinRateWindow = i
+		}
+	}
+	if val := getEnv("WIN_RATE_CEILING", ""); val != "" {
+		if f, err := strconv.ParseFloat(val, 64); err == nil && f > 0 && f <= 1 {
+			cfg.Game.WinRateCeiling = f
+		}
+	}
 	if val := getEnv("TOLERANCE", ""); val != "" {
 		if f, err := strconv.ParseFloat(val, 64); err == nil {
 			cfg.Game.Tolerance = f
 		}
 	}
 
+	// Después de los overrides del juego: por defecto alto valor = el premio de ganar
+	cfg.Redemption = RedemptionConfig{
+		DailyCapPerEmployee: getEnvInt("REDEMPTION_DAILY_CAP", 0),
+		HighValueDiscount:   getEnvInt("REDEMPTION_HIGH_VALUE_DISCOUNT", cfg.Game.WinDiscount),
+		AnomalyFactor:       getEnvFloat("REDEMPTION_ANOMALY_FACTOR", 2),
+		AnomalyMinActivity:  getEnvInt("REDEMPTION_ANOMALY_MIN_ACTIVITY", 10),
+		ApprovalAbove:       getEnvInt("REDEMPTION_APPROVAL_ABOVE", 0),
+	}
+
 	return cfg
 }
 
 func (c *Config) Validate() []string {
 	var errors []string
 
+	if c.DBDriver != "mysql" && c.DBDriver != "postgres" {
+		errors = append(errors, fmt.Sprintf("DB_DRIVER %q is not valid, use 'mysql' or 'postgres'", c.DBDriver))
+	}
 	if c.DBHost == "" {
 		errors = append(errors, "DB_HOST is required")
 	}
@@ -122,9 +681,134 @@ func (c *Config) Validate() []string {
 	if c.WhatsAppToken == "" {
 		errors = append(errors, "WHATSAPP_TOKEN is required for production")
 	}
+	if c.WhatsAppMarketingShareYellow < 0 || c.WhatsAppMarketingShareYellow > 1 || c.WhatsAppMarketingShareRed < 0 || c.WhatsAppMarketingShareRed > 1 {
+		errors = append(errors, "WHATSAPP_MARKETING_SHARE_YELLOW and WHATSAPP_MARKETING_SHARE_RED must be between 0 and 1")
+	}
+	if c.IsProduction() && len(c.TrustedProxies) == 0 && c.TrustedPlatform == "" {
+		errors = append(errors, "TRUSTED_PROXIES and TRUSTED_PLATFORM are not set; behind a load balancer every client shares its IP")
+	}
+	if c.WhatsAppVerifyToken != "" && c.WhatsAppAppSecret == "" {
+		errors = append(errors, "WHATSAPP_APP_SECRET is not set, incoming WhatsApp webhooks are rejected")
+	}
+	if c.WhatsAppToken != "" && c.WhatsAppBusinessAccountID == "" {
+		errors = append(errors, "WHATSAPP_BUSINESS_ACCOUNT_ID is not set, template approval status is not monitored")
+	}
+	if (c.SMS.TwilioAccountSID != "" || c.SMS.TwilioAuthToken != "" || c.SMS.From != "") && !c.SMS.Configurado() {
+		errors = append(errors, "TWILIO_ACCOUNT_SID, TWILIO_AUTH_TOKEN and TWILIO_SMS_FROM must all be set; SMS fallback disabled")
+	}
 	if c.JWTSecret == "" {
 		errors = append(errors, "JWT_SECRET is required")
 	}
+	if _, err := time.LoadLocation(c.Notifications.Timezone); err != nil {
+		errors = append(errors, fmt.Sprintf("TIMEZONE %q is not valid, using local time", c.Notifications.Timezone))
+	}
+	if c.LogFormat != "pretty" && c.LogFormat != "plain" && c.LogFormat != "json" {
+		errors = append(errors, fmt.Sprintf("LOG_FORMAT %q is not valid, use 'pretty', 'plain' or 'json'", c.LogFormat))
+	}
+	if c.DBLog.Format != "text" && c.DBLog.Format != "json" {
+		errors = append(errors, fmt.Sprintf("DB_LOG_FORMAT %q is not valid, use 'text' or 'json'", c.DBLog.Format))
+	}
+	if c.DBLog.AllSQL && c.IsProduction() {
+		errors = append(errors, "DB_LOG_ALL_SQL is ignored in production, only slow queries and errors are logged")
+	}
+	if c.ClientTiers.FrequentScore <= c.ClientTiers.OccasionalScore {
+		errors = append(errors, fmt.Sprintf("TIER_FREQUENT_SCORE (%.1f) must be greater than TIER_OCCASIONAL_SCORE (%.1f)",
+			c.ClientTiers.FrequentScore, c.ClientTiers.OccasionalScore))
+	}
+	if c.Scaling.SharedState != "memory" && c.Scaling.SharedState != "database" {
+		errors = append(errors, fmt.Sprintf("SHARED_STATE %q is not valid, use 'memory' or 'database'; using memory (single instance only)", c.Scaling.SharedState))
+	}
+	if c.Maintenance.CampaignMaxAttempts < 1 {
+		errors = append(errors, fmt.Sprintf("CAMPAIGN_MAX_ATTEMPTS (%d) must be at least 1, failed campaign sends are not retried", c.Maintenance.CampaignMaxAttempts))
+	}
+	if c.Game.MaxPlaysPerDay < 0 || c.Game.MaxPlaysPerWeek < 0 {
+		errors = append(errors, fmt.Sprintf("MAX_PLAYS_PER_DAY (%d) and MAX_PLAYS_PER_WEEK (%d) must be 0 (no cap) or positive, negative values disable the cap",
+			c.Game.MaxPlaysPerDay, c.Game.MaxPlaysPerWeek))
+	}
+	if c.Game.MaxPlaysPerDay > 0 && c.Game.MaxPlaysPerWeek > 0 && c.Game.MaxPlaysPerWeek < c.Game.MaxPlaysPerDay {
+		errors = append(errors, fmt.Sprintf("MAX_PLAYS_PER_WEEK (%d) is lower than MAX_PLAYS_PER_DAY (%d), the daily cap is never reached",
+			c.Game.MaxPlaysPerWeek, c.Game.MaxPlaysPerDay))
+	}
+	if c.Leaderboard.BonusDiscount < 0 || c.Leaderboard.BonusDiscount > 100 {
+		errors = append(errors, fmt.Sprintf("LEADERBOARD_BONUS_DISCOUNT (%d) must be between 0 and 100, the weekly winner gets no bonus voucher", c.Leaderboard.BonusDiscount))
+	}
+	if c.MetricsPush.Habilitado() && c.MetricsPush.Interval < time.Second {
+		errors = append(errors, fmt.Sprintf("METRICS_PUSH_INTERVAL_SECONDS (%v) must be at least 1, using 15", c.MetricsPush.Interval))
+	}
+	if c.MetricsPush.PushgatewayURL != "" && c.MetricsPush.Job == "" {
+		errors = append(errors, "METRICS_PUSH_JOB is required to push to the Pushgateway, using 'cheesehouse'")
+	}
+	if c.Leaderboard.BonusValidDays < 1 {
+		errors = append(errors, fmt.Sprintf("LEADERBOARD_BONUS_VALID_DAYS (%d) must be at least 1, using 30", c.Leaderboard.BonusValidDays))
+	}
+	if c.Game.WinRateAction != "ajustar" && c.Game.WinRateAction != "pausar" {
+		errors = append(errors, fmt.Sprintf("WIN_RATE_ACTION %q is not valid, use 'ajustar' or 'pausar'", c.Game.WinRateAction))
+	}
+	switch c.Game.FlashVouchers {
+	case "", "ganado", "perdido", "todos":
+	default:
+		errors = append(errors, fmt.Sprintf("FLASH_VOUCHERS %q is not valid, use 'ganado', 'perdido' or 'todos'; flash vouchers disabled", c.Game.FlashVouchers))
+	}
+	if c.Game.FlashVouchers != "" && c.Game.FlashVoucherHours <= 0 {
+		errors = append(errors, "FLASH_VOUCHER_HOURS must be greater than 0; flash vouchers disabled")
+	}
+	if c.Game.VoucherBranchScope != "cadena" && c.Game.VoucherBranchScope != "sucursal" {
+		errors = append(errors, fmt.Sprintf("VOUCHER_BRANCH_SCOPE %q is not valid, use 'cadena' or 'sucursal'; vouchers valid chain-wide", c.Game.VoucherBranchScope))
+	}
+	if c.Game.ScreenVoucherMode != "no" && c.Game.ScreenVoucherMode != "opcional" && c.Game.ScreenVoucherMode != "siempre" {
+		errors = append(errors, fmt.Sprintf("SCREEN_VOUCHER_MODE %q is not valid, use 'no', 'opcional' or 'siempre'; vouchers only sent by WhatsApp", c.Game.ScreenVoucherMode))
+	}
+	if c.Game.SessionMaxDrift <= 0 {
+		errors = append(errors, fmt.Sprintf("GAME_SESSION_MAX_DRIFT (%.2f) must be greater than 0", c.Game.SessionMaxDrift))
+	}
+	if c.Game.CodeOnScreenOnOutage && c.Game.WhatsAppOutageMinutes <= 0 {
+		errors = append(errors, "WHATSAPP_OUTAGE_MINUTES must be greater than 0; codes are shown on screen on any WhatsApp failure")
+	}
+	if c.Game.SubmitLimitPerIP <= 0 || c.Game.SubmitLimitPerPhone <= 0 || c.Game.SubmitBurst <= 0 {
+		errors = append(errors, "GAME_SUBMIT_LIMIT_IP, GAME_SUBMIT_LIMIT_PHONE and GAME_SUBMIT_BURST must be greater than 0")
+	}
+
+	if c.Redemption.DailyCapPerEmployee < 0 {
+		errors = append(errors, "REDEMPTION_DAILY_CAP must be 0 (no cap) or greater")
+	}
+	if c.Redemption.ApprovalAbove < 0 || c.Redemption.ApprovalAbove >= 100 {
+		errors = append(errors, fmt.Sprintf("REDEMPTION_APPROVAL_ABOVE (%d) must be between 0 (no approval) and 99", c.Redemption.ApprovalAbove))
+	}
+	if c.Redemption.AnomalyFactor <= 1 {
+		errors = append(errors, fmt.Sprintf("REDEMPTION_ANOMALY_FACTOR (%.1f) must be greater than 1", c.Redemption.AnomalyFactor))
+	}
+
+	if c.DebugCaptureRequests > 0 && c.IsProduction() {
+		errors = append(errors, "DEBUG_CAPTURE_REQUESTS is ignored in production")
+	}
+	if c.FaultInjection.Enabled && c.IsProduction() {
+		errors = append(errors, "FAULT_INJECTION is ignored in production")
+	}
+	if c.DebugCaptureRequests < 0 {
+		errors = append(errors, "DEBUG_CAPTURE_REQUESTS must be 0 (disabled) or greater")
+	}
+	if c.ShutdownTimeout <= 0 {
+		errors = append(errors, "SHUTDOWN_TIMEOUT_SECONDS must be greater than 0")
+	}
+	if c.Static.Fingerprint && c.Static.AssetMaxAge <= 0 {
+		errors = append(errors, "STATIC_CACHE_DAYS must be greater than 0 when STATIC_FINGERPRINT is enabled")
+	}
+
+	switch c.Storage.Driver {
+	case "local":
+	case "s3":
+		if c.Storage.S3Bucket == "" || c.Storage.S3AccessKey == "" || c.Storage.S3SecretKey == "" {
+			errors = append(errors, "S3_BUCKET, S3_ACCESS_KEY and S3_SECRET_KEY are required with STORAGE_DRIVER=s3; using local storage")
+		}
+	default:
+		errors = append(errors, fmt.Sprintf("STORAGE_DRIVER %q is not valid, use 'local' or 's3'; using local storage", c.Storage.Driver))
+	}
+	if c.IdempotencyTTL <= 0 {
+		errors = append(errors, "IDEMPOTENCY_TTL_HOURS must be greater than 0")
+	}
+	if c.Storage.SignedURLTTL <= 0 || c.Storage.SignedURLTTL > 7*24*time.Hour {
+		errors = append(errors, "STORAGE_SIGNED_URL_MINUTES must be between 1 and 10080 (7 days)")
+	}
 
 	return errors
 }
@@ -134,23 +818,113 @@ func (c *Config) IsProduction() bool {
 }
 
 func (c *Config) LogConfig() {
+	valores := [][2]string{
+		{"Environment", c.Environment},
+		{"Restaurant", fmt.Sprintf("%s (%s)", c.RestaurantName, c.Location)},
+		{"Database", fmt.Sprintf("%s %s@%s:%s/%s, auto-migrate: %t", c.DBDriver, c.DBUser, c.DBHost, c.DBPort, c.DBName, c.DBAutoMigrate)},
+		{"Game", fmt.Sprintf("%.1f-%.1fs, Win:%d%%, Lose:%d%%, Tol:%.1f, max drift %.2fs",
+			c.Game.MinTargetTime, c.Game.MaxTargetTime, c.Game.WinDiscount, c.Game.LoseDiscount, c.Game.Tolerance, c.Game.SessionMaxDrift)},
+		{"Submit limits", fmt.Sprintf("%d/h per IP, %d/h per phone, burst %d",
+			c.Game.SubmitLimitPerIP, c.Game.SubmitLimitPerPhone, c.Game.SubmitBurst)},
+		{"Campaign costs", fmt.Sprintf("%.4f %s/conversation, ticket %.2f",
+			c.Costs.MarketingConversationCost, c.Costs.Currency, c.Costs.AverageTicket)},
+		{"Quiet hours", fmt.Sprintf("%02d:%02d-%02d:%02d (%s)",
+			c.Notifications.QuietHoursStart/60, c.Notifications.QuietHoursStart%60,
+			c.Notifications.QuietHoursEnd/60, c.Notifications.QuietHoursEnd%60, c.Notifications.Timezone)},
+		{"Slow thresholds", fmt.Sprintf("requests %v, queries %v",
+			c.Performance.SlowRequestThreshold, c.Performance.SlowQueryThreshold)},
+		{"Client IP", c.descripcionIPCliente()},
+		{"Metrics push", c.descripcionMetricas()},
+		{"SQL log", fmt.Sprintf("%s (%s), all queries: %t",
+			c.DBLog.Target, c.DBLog.Format, c.DBLog.AllSQL && !c.IsProduction())},
+		{"Client tiers", fmt.Sprintf("occasional > %.1f, frequent > %.1f, half-life %d days",
+			c.ClientTiers.OccasionalScore, c.ClientTiers.FrequentScore, c.ClientTiers.RecencyHalfLifeDays)},
+		{"Log format", c.LogFormat},
+		{"Flash vouchers", c.descripcionFlash()},
+		{"Voucher branch scope", c.Game.VoucherBranchScope},
+		{"Screen vouchers", c.Game.ScreenVoucherMode},
+		{"Redemption", fmt.Sprintf("daily cap %d per employee (0 = none), high value >= %d%%, anomaly x%.1f, manager PIN above %d%% (0 = never)",
+			c.Redemption.DailyCapPerEmployee, c.Redemption.HighValueDiscount, c.Redemption.AnomalyFactor, c.Redemption.ApprovalAbove)},
+		{"Storage", c.descripcionStorage()},
+		{"SMS fallback", c.descripcionSMS()},
+		{"Maintenance", c.descripcionMantenimiento()},
+		{"Shared state", c.descripcionEstadoCompartido()},
+		{"Leaderboard", c.descripcionRanking()},
+		{"Fault injection", c.descripcionFallas()},
+		{"Static assets", fmt.Sprintf("%s, fingerprint: %t, cache %v", c.Static.Dir, c.Static.Fingerprint, c.Static.AssetMaxAge)},
+	}
+
+	// Fuera de pretty va en una sola línea "Clave: valor | ..." para que quede como campos
+	if c.LogFormat == "plain" || c.LogFormat == "json" {
+		partes := []string{"Configuration loaded"}
+		for _, valor := range valores {
+			partes = append(partes, valor[0]+": "+valor[1])
+		}
+		log.Print(strings.Join(partes, " | "))
+		return
+	}
+
 	fmt.Println("🧀 Configuration loaded:")
-	fmt.Printf("   Environment: %s\n", c.Environment)
-	fmt.Printf("   Restaurant: %s (%s)\n", c.RestaurantName, c.Location)
-	fmt.Printf("   Database: %s@%s:%s/%s\n", c.DBUser, c.DBHost, c.DBPort, c.DBName)
-	fmt.Printf("   Game: %.1f-%.1fs, Win:%d%%, Lose:%d%%, Tol:%.1f\n",
-		c.Game.MinTargetTime, c.Game.MaxTargetTime, c.Game.WinDiscount, c.Game.LoseDiscount, c.Game.Tolerance)
+	for _, valor := range valores {
+		fmt.Printf("   %s: %s\n", valor[0], valor[1])
+	}
 }
 
 func (c *Config) GetWhatsAppTemplates() map[string]string {
 	return map[string]string{
-		"voucher_ganador":  "voucher_ganador",
-		"voucher_perdedor": "voucher_perdedor",
-		"bienvenida":       "bienvenida",
-		"recordatorio":     "recordatorio",
+		"voucher_ganador":   "voucher_ganador",
+		"voucher_perdedor":  "voucher_perdedor",
+		"bienvenida":        "bienvenida",
+		"recordatorio":      "recordatorio",
+		"pedido_confirmado": "pedido_confirmado",
+		"pedido_procesando": "pedido_procesando",
+		"pedido_completado": "pedido_completado",
+		"pedido_cancelado":  "pedido_cancelado",
 	}
 }
 
+// GetWhatsAppTemplatesPorIdioma retorna las variantes de cada template por idioma
+// junto con el código de idioma que espera la API de WhatsApp
+func (c *Config) GetWhatsAppTemplatesPorIdioma() map[string]WhatsAppTemplateIdioma {
+	return map[string]WhatsAppTemplateIdioma{
+		"es": {
+			CodigoIdioma: "es",
+			Templates:    c.GetWhatsAppTemplates(),
+		},
+		"en": {
+			CodigoIdioma: "en_US",
+			Templates: map[string]string{
+				"voucher_ganador":   "voucher_ganador_en",
+				"voucher_perdedor":  "voucher_perdedor_en",
+				"bienvenida":        "bienvenida_en",
+				"recordatorio":      "recordatorio_en",
+				"pedido_confirmado": "pedido_confirmado_en",
+				"pedido_procesando": "pedido_procesando_en",
+				"pedido_completado": "pedido_completado_en",
+				"pedido_cancelado":  "pedido_cancelado_en",
+			},
+		},
+	}
+}
+
+// GetWhatsAppTemplate resuelve el nombre y código de idioma de un template,
+// usando español cuando el idioma o la variante no existen
+func (c *Config) GetWhatsAppTemplate(nombre, idioma string) (string, string) {
+	variantes := c.GetWhatsAppTemplatesPorIdioma()
+
+	if variante, ok := variantes[idioma]; ok {
+		if template, ok := variante.Templates[nombre]; ok {
+			return template, variante.CodigoIdioma
+		}
+	}
+
+	porDefecto, ok := variantes[c.DefaultLanguage]
+	if !ok {
+		porDefecto = variantes["es"]
+	}
+	return porDefecto.Templates[nombre], porDefecto.CodigoIdioma
+}
+
 func (c *Config) GetPhoneValidation() *PhoneValidation {
 	return &PhoneValidation{
 		CountryCode: "54",
@@ -165,9 +939,260 @@ func (c *Config) GenerateVoucherCode() string {
 	return "CH" // CheeseHouse prefix
 }
 
+// NotificaEstadoPedido indica si pasar un pedido a ese estado avisa al cliente
+func (c *Config) NotificaEstadoPedido(estado string) bool {
+	for _, e := range c.OrderStatusNotifications {
+		if e == estado {
+			return true
+		}
+	}
+	return false
+}
+
+// Sucursal normaliza el nombre de una sucursal; vacío es la configurada en LOCATION
+func (c *Config) Sucursal(nombre string) string {
+	if nombre = strings.TrimSpace(nombre); nombre != "" {
+		return nombre
+	}
+	return c.Location
+}
+
+// RequiereAprobacionCanje indica si canjear un voucher con ese descuento necesita el PIN
+// de un encargado
+func (c *Config) RequiereAprobacionCanje(descuento int) bool {
+	return c.Redemption.ApprovalAbove > 0 && descuento > c.Redemption.ApprovalAbove
+}
+
+// VoucherSoloSucursal indica si los vouchers de partida nuevos quedan restringidos a la
+// sucursal que los emitió
+func (c *Config) VoucherSoloSucursal() bool {
+	return c.Game.VoucherBranchScope == "sucursal"
+}
+
+// VoucherEnPantalla indica si el voucher de la partida se muestra en la pantalla del juego.
+// sinWhatsApp es la casilla "no uso WhatsApp" del formulario
+func (c *Config) VoucherEnPantalla(sinWhatsApp bool) bool {
+	switch c.Game.ScreenVoucherMode {
+	case "siempre":
+		return true
+	case "opcional":
+		return sinWhatsApp
+	}
+	return false
+}
+
+// GetLocation retorna la zona horaria configurada del restaurante
+func (c *Config) GetLocation() *time.Location {
+	loc, err := time.LoadLocation(c.Notifications.Timezone)
+	if err != nil {
+		return time.Local
+	}
+	return loc
+}
+
+// Fechas formato de fechas en la zona horaria del restaurante y el idioma indicado
+// (ej. el del cliente); si no está soportado usa DEFAULT_LANGUAGE
+func (c *Config) Fechas(idioma string) fechas.Formato {
+	return fechas.Nuevo(c.GetLocation(), i18n.Detectar(idioma, "", c.DefaultLanguage))
+}
+
+// InicioDelDia retorna la medianoche del día de t en la zona horaria del restaurante
+func (c *Config) InicioDelDia(t time.Time) time.Time {
+	local := t.In(c.GetLocation())
+	return time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, local.Location())
+}
+
+// InicioDeSemana lunes a medianoche de la semana de t, en la zona horaria del restaurante
+func (c *Config) InicioDeSemana(t time.Time) time.Time {
+	dia := c.InicioDelDia(t)
+	return dia.AddDate(0, 0, -((int(dia.Weekday()) + 6) % 7))
+}
+
+// EsVoucherFlash indica si el voucher de una partida con ese resultado es flash
+func (c *Config) EsVoucherFlash(gano bool) bool {
+	if c.Game.FlashVoucherHours <= 0 {
+		return false
+	}
+	switch c.Game.FlashVouchers {
+	case "todos":
+		return true
+	case "ganado":
+		return gano
+	case "perdido":
+		return !gano
+	}
+	return false
+}
+
+// VencimientoFlash calcula el vencimiento de un voucher flash: las horas configuradas
+// desde la emisión, o antes si en ese lapso llega la hora de corte del día
+func (c *Config) VencimientoFlash(emision time.Time) time.Time {
+	vence := emision.Add(time.Duration(c.Game.FlashVoucherHours) * time.Hour)
+	if c.Game.FlashVoucherCutoff < 0 {
+		return vence
+	}
+
+	corte := c.InicioDelDia(emision).Add(time.Duration(c.Game.FlashVoucherCutoff) * time.Minute)
+	if corte.After(emision) && corte.Before(vence) {
+		return corte
+	}
+	return vence
+}
+
+// descripcionFlash resume la configuración de vouchers flash para el log de arranque
+func (c *Config) descripcionFlash() string {
+	if !c.EsVoucherFlash(true) && !c.EsVoucherFlash(false) {
+		return "disabled"
+	}
+	corte := "no cutoff"
+	if c.Game.FlashVoucherCutoff >= 0 {
+		corte = fmt.Sprintf("cutoff %02d:%02d", c.Game.FlashVoucherCutoff/60, c.Game.FlashVoucherCutoff%60)
+	}
+	return fmt.Sprintf("%s, %dh, %s", c.Game.FlashVouchers, c.Game.FlashVoucherHours, corte)
+}
+
+// parseHoraDelDia convierte "HH:MM" a minutos desde medianoche
+func parseHoraDelDia(value string, defaultValue int) int {
+	t, err := time.Parse("15:04", value)
+	if err != nil {
+		return defaultValue
+	}
+	return t.Hour()*60 + t.Minute()
+}
+
+// descripcionFallas resume la inyección de fallas para el log de arranque
+func (c *Config) descripcionFallas() string {
+	if !c.InyeccionFallasActiva() {
+		return "disabled"
+	}
+	return fmt.Sprintf("WhatsApp 500 %.0f%%, 429 %.0f%%, DB +%dms",
+		c.FaultInjection.WhatsAppErrorRate*100, c.FaultInjection.WhatsApp429Rate*100, c.FaultInjection.DBLatencyMs)
+}
+
+// InyeccionFallasActiva indica si corre la inyección de fallas (nunca en producción)
+func (c *Config) InyeccionFallasActiva() bool {
+	return c.FaultInjection.Enabled && !c.IsProduction()
+}
+
+// descripcionStorage resume el almacenamiento de archivos para el log de arranque
+func (c *Config) descripcionStorage() string {
+	destino := c.Storage.LocalPath
+	if c.Storage.Driver == "s3" {
+		destino = fmt.Sprintf("bucket %s (%s)", c.Storage.S3Bucket, c.Storage.S3Region)
+		if c.Storage.S3Endpoint != "" {
+			destino += " at " + c.Storage.S3Endpoint
+		}
+	}
+	return fmt.Sprintf("%s, %s, links valid %v", c.Storage.Driver, destino, c.Storage.SignedURLTTL)
+}
+
+// descripcionSMS resume el respaldo por SMS para el log de arranque
+func (c *Config) descripcionSMS() string {
+	switch {
+	case !c.SMS.Configurado():
+		return "disabled (Twilio not configured)"
+	case !c.SMS.Fallback:
+		return fmt.Sprintf("disabled (Twilio from %s)", c.SMS.From)
+	}
+	return fmt.Sprintf("Twilio from %s", c.SMS.From)
+}
+
 func getEnv(key, defaultValue string) string {
 	if value := os.Getenv(key); value != "" {
 		return value
 	}
 	return defaultValue
 }
+
+func getEnvFloat(key string, defaultValue float64) float64 {
+	if value := os.Getenv(key); value != "" {
+		if f, err := strconv.ParseFloat(value, 64); err == nil {
+			return f
+		}
+	}
+	return defaultValue
+}
+
+func getEnvInt(key string, defaultValue int) int {
+	if value := os.Getenv(key); value != "" {
+		if i, err := strconv.Atoi(value); err == nil {
+			return i
+		}
+	}
+	return defaultValue
+}
+
+// descripcionMantenimiento resume las tareas programadas para el log de arranque
+func (c *Config) descripcionMantenimiento() string {
+	if !c.Maintenance.Enabled {
+		return "disabled"
+	}
+	reintentos := "never"
+	if c.Maintenance.CampaignRetryMinutes > 0 && c.Maintenance.CampaignMaxAttempts > 1 {
+		reintentos = fmt.Sprintf("every %dm, up to %d attempts", c.Maintenance.CampaignRetryMinutes, c.Maintenance.CampaignMaxAttempts)
+	}
+	return fmt.Sprintf("daily at %02d:%02d, campaign retries %s",
+		c.Maintenance.Time/60, c.Maintenance.Time%60, reintentos)
+}
+
+// HeaderIPCliente header del que se toma la IP del cliente según TRUSTED_PLATFORM ("" = ninguno)
+func (c *Config) HeaderIPCliente() string {
+	switch strings.ToLower(c.TrustedPlatform) {
+	case "":
+		return ""
+	case "cloudflare":
+		return "CF-Connecting-IP"
+	case "google":
+		return "X-Appengine-Remote-Addr"
+	default:
+		return c.TrustedPlatform
+	}
+}
+
+// descripcionIPCliente resume de dónde sale la IP del cliente para el log de arranque
+func (c *Config) descripcionIPCliente() string {
+	if header := c.HeaderIPCliente(); header != "" {
+		return "header " + header
+	}
+	if len(c.TrustedProxies) == 0 {
+		return "connection address (no trusted proxies)"
+	}
+	return "X-Forwarded-For from " + strings.Join(c.TrustedProxies, ", ")
+}
+
+// descripcionMetricas resume a dónde se envían las métricas internas para el log de arranque
+func (c *Config) descripcionMetricas() string {
+	if !c.MetricsPush.Habilitado() {
+		return "disabled"
+	}
+	var destinos []string
+	if c.MetricsPush.StatsDAddr != "" {
+		destinos = append(destinos, "StatsD "+c.MetricsPush.StatsDAddr)
+	}
+	if c.MetricsPush.PushgatewayURL != "" {
+		// Sin la contraseña si la URL trae usuario
+		destino := c.MetricsPush.PushgatewayURL
+		if u, err := url.Parse(destino); err == nil {
+			destino = u.Redacted()
+		}
+		destinos = append(destinos, fmt.Sprintf("Pushgateway %s (job %s)", destino, c.MetricsPush.Job))
+	}
+	return fmt.Sprintf("%s every %v, prefix %q", strings.Join(destinos, ", "), c.MetricsPush.Interval, c.MetricsPush.Prefix)
+}
+
+// descripcionRanking resume el ranking semanal para el log de arranque
+func (c *Config) descripcionRanking() string {
+	premio := "no automatic bonus"
+	if c.Leaderboard.BonusDiscount > 0 && c.Leaderboard.BonusDiscount <= 100 {
+		premio = fmt.Sprintf("weekly winner gets %d%% for %d days", c.Leaderboard.BonusDiscount, c.Leaderboard.BonusValidDays)
+	}
+	return fmt.Sprintf("top %d, %s", c.Leaderboard.Size, premio)
+}
+
+// descripcionEstadoCompartido resume dónde vive el estado entre instancias para el log de arranque
+func (c *Config) descripcionEstadoCompartido() string {
+	if !c.Scaling.Compartido() {
+		return fmt.Sprintf("memory (single instance), instance %s", c.Scaling.InstanceID)
+	}
+	return fmt.Sprintf("database, instance %s, caches refreshed every %v", c.Scaling.InstanceID, c.Scaling.CacheTTL)
+}
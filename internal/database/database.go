@@ -4,31 +4,42 @@ import (
 	"database/sql"
 	"fmt"
 	"log"
+	"sync"
+	"time"
 
 	"CheeseHouse/internal/config"
 
 	"gorm.io/driver/mysql"
+	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 )
 
 type Database struct {
 	*gorm.DB
-	sqlDB *sql.DB
+	sqlDB     *sql.DB
+	consultas *consultaLogger
+
+	// Último estado del esquema revisado, para no consultar el catálogo en cada /readyz
+	esquemaMu sync.Mutex
+	esquema   *EstadoEsquema
+	esquemaEn time.Time
 }
 
 func Connect(cfg *config.Config) (*Database, error) {
-	// DSN para conectarse al servidor (sin seleccionar una base)
-	serverDSN := fmt.Sprintf("%s:%sssssssssssssssssssssssssss@tcp(%s:%s)/?charset=utf8mb4&parseTime=True&loc=Local",
-		cfg.DBUser, cfg.DBPassword, cfg.DBHost, cfg.DBPort)
-
 	// Nombre de la base que queremos usar/crear
 	dbName := cfg.DBName
 	if dbName == "" {
 		dbName = "cheesehouse"
 	}
 
+	// Dialectos para el servidor (sin seleccionar una base) y para la base
+	servidor, base, err := dialectos(cfg, dbName)
+	if err != nil {
+		return nil, err
+	}
+
 	// Abrir conexión al servidor para comprobar si la base existe
-	serverDB, err := gorm.Open(mysql.Open(serverDSN), &gorm.Config{})
+	serverDB, err := gorm.Open(servidor, &gorm.Config{})
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect to database server: %w", err)
 	}
@@ -41,6 +52,10 @@ func Connect(cfg *config.Config) (*Database, error) {
 	if !exists {
 		// Crear la base de datos si no existe
 		createStmt := fmt.Sprintf("CREATE DATABASE IF NOT EXISTS %s CHARACTER SET utf8mb4 COLLATE utf8mb4_general_ci;", dbName)
+		if cfg.DBDriver == "postgres" {
+			// Postgres no admite IF NOT EXISTS en CREATE DATABASE
+			createStmt = fmt.Sprintf("CREATE DATABASE %s ENCODING 'UTF8'", dbName)
+		}
 		if err := serverDB.Exec(createStmt).Error; err != nil {
 			return nil, fmt.Errorf("failed to create database: %w", err)
 		}
@@ -52,10 +67,14 @@ func Connect(cfg *config.Config) (*Database, error) {
 	sqlDB.Close()
 
 	// Ahora conectamos a la base de datos específica
-	dsn := fmt.Sprintf("%s:%s@tcp(%s:%s)/%s?charset=utf8mb4&parseTime=True&loc=Local",
-		cfg.DBUser, cfg.DBPassword, cfg.DBHost, cfg.DBPort, dbName)
-
-	db, err := gorm.Open(mysql.Open(dsn), &gorm.Config{})
+	consultas := newConsultaLogger(cfg)
+
+	db, err := gorm.Open(base, &gorm.Config{
+		// Traducir errores del driver (ej. clave duplicada) a errores de GORM
+		TranslateError: true,
+		// Loguear consultas lentas y avisar a las métricas de requests
+		Logger: consultas,
+	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect to database: %w", err)
 	}
@@ -69,19 +88,52 @@ func Connect(cfg *config.Config) (*Database, error) {
 	sqlDB.SetMaxIdleConns(10)
 	sqlDB.SetMaxOpenConns(100)
 
-	log.Println("✅ Connected to database successfully")
+	log.Printf("✅ Connected to %s database successfully", db.Dialector.Name())
 
-	return &Database{DB: db, sqlDB: sqlDB}, nil
+	return &Database{DB: db, sqlDB: sqlDB, consultas: consultas}, nil
+}
+
+// ObservarConsultasLentas registra una función que recibe cada consulta que
+// superó DB_SLOW_QUERY_MS (las métricas la usan para asociarla a la ruta)
+func (d *Database) ObservarConsultasLentas(observador ObservadorConsultas) {
+	d.consultas.agregar(observador)
+}
+
+// dialectos arma las conexiones del driver configurado: al servidor (para crear la base si
+// falta) y a la base
+func dialectos(cfg *config.Config, dbName string) (servidor, base gorm.Dialector, err error) {
+	switch cfg.DBDriver {
+	case "", "mysql":
+		dsn := "%s:%s@tcp(%s:%s)/%s?charset=utf8mb4&parseTime=True&loc=Local"
+		servidor = mysql.Open(fmt.Sprintf(dsn, cfg.DBUser, cfg.DBPassword, cfg.DBHost, cfg.DBPort, ""))
+		base = mysql.Open(fmt.Sprintf(dsn, cfg.DBUser, cfg.DBPassword, cfg.DBHost, cfg.DBPort, dbName))
+	case "postgres":
+		// Postgres siempre se conecta a una base: la de mantenimiento "postgres" sirve para crear la nuestra
+		dsn := "host=%s port=%s user=%s password=%s dbname=%s sslmode=%s"
+		servidor = postgres.Open(fmt.Sprintf(dsn, cfg.DBHost, cfg.DBPort, cfg.DBUser, cfg.DBPassword, "postgres", cfg.DBSSLMode))
+		base = postgres.Open(fmt.Sprintf(dsn, cfg.DBHost, cfg.DBPort, cfg.DBUser, cfg.DBPassword, dbName, cfg.DBSSLMode))
+	default:
+		return nil, nil, fmt.Errorf("unsupported DB_DRIVER %q, use 'mysql' or 'postgres'", cfg.DBDriver)
+	}
+	return servidor, base, nil
 }
 
 // isDatabasePresent verifica si una base de datos existe
 func isDatabasePresent(db *gorm.DB, dbName string) (bool, error) {
+	query := "SELECT EXISTS(SELECT 1 FROM information_schema.schemata WHERE schema_name = ?)"
+	if db.Dialector.Name() == "postgres" {
+		query = "SELECT EXISTS(SELECT 1 FROM pg_database WHERE datname = ?)"
+	}
 	var exists bool
-	query := fmt.Sprintf("SELECT EXISTS(SELECT 1 FROM information_schema.schemata WHERE schema_name = '%s')", dbName)
-	err := db.Raw(query).Scan(&exists).Error
+	err := db.Raw(query, dbName).Scan(&exists).Error
 	return exists, err
 }
 
+// Close cierra el pool de conexiones; las consultas en curso terminan antes
+func (d *Database) Close() error {
+	return d.sqlDB.Close()
+}
+
 func (d *Database) Health() error {
 	return d.sqlDB.Ping()
 }
@@ -0,0 +1,147 @@
+package database
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+
+	"CheeseHouse/internal/models"
+)
+
+// cacheEstadoEsquema cuánto se reutiliza la última revisión del esquema
+const cacheEstadoEsquema = time.Minute
+
+// EstadoEsquema versión de las migraciones aplicadas en la base y lo que le falta para
+// este binario. Con DB_AUTO_MIGRATE apagado, un deploy que no corrió -migrar aparece acá
+// en lugar de fallar en el primer INSERT
+type EstadoEsquema struct {
+	Version      string    `json:"version"`                // Última migración SQL aplicada
+	Esperada     string    `json:"version_esperada"`       // Última migración SQL incluida en el binario
+	Pendientes   []string  `json:"pendientes,omitempty"`   // Migraciones, tablas y columnas que faltan
+	Desconocidas []string  `json:"desconocidas,omitempty"` // Migraciones aplicadas que el binario no trae (binario viejo)
+	Advertencia  string    `json:"advertencia,omitempty"`
+	RevisadoEn   time.Time `json:"revisado_en"`
+}
+
+// AlDia indica si la base tiene todo lo que necesita el binario
+func (e *EstadoEsquema) AlDia() bool {
+	return len(e.Pendientes) == 0
+}
+
+// EstadoEsquema compara las migraciones SQL y las tablas de los modelos con la base. El
+// resultado se reutiliza durante cacheEstadoEsquema
+func (d *Database) EstadoEsquema() (*EstadoEsquema, error) {
+	d.esquemaMu.Lock()
+	defer d.esquemaMu.Unlock()
+
+	if d.esquema != nil && time.Since(d.esquemaEn) < cacheEstadoEsquema {
+		return d.esquema, nil
+	}
+	estado, err := d.revisarEsquema()
+	if err != nil {
+		return nil, err
+	}
+	d.esquema, d.esquemaEn = estado, time.Now()
+	return estado, nil
+}
+
+// olvidarEstadoEsquema descarta la revisión guardada (después de migrar)
+func (d *Database) olvidarEstadoEsquema() {
+	d.esquemaMu.Lock()
+	d.esquema = nil
+	d.esquemaMu.Unlock()
+}
+
+// revisarEsquema arma el estado del esquema consultando la base
+func (d *Database) revisarEsquema() (*EstadoEsquema, error) {
+	estado := &EstadoEsquema{RevisadoEn: time.Now()}
+
+	archivos, err := d.archivosMigracion()
+	if err != nil {
+		return nil, err
+	}
+	conocidas := make(map[string]bool, len(archivos))
+	for _, archivo := range archivos {
+		version := versionMigracion(archivo)
+		conocidas[version] = true
+		estado.Esperada = version // archivosMigracion los devuelve en orden
+	}
+
+	var aplicadas []string
+	if d.DB.Migrator().HasTable(&migracionAplicada{}) {
+		if err := d.DB.Model(&migracionAplicada{}).Order("version").Pluck("version", &aplicadas).Error; err != nil {
+			return nil, fmt.Errorf("error leyendo migraciones aplicadas: %w", err)
+		}
+	}
+	yaAplicada := make(map[string]bool, len(aplicadas))
+	for _, version := range aplicadas {
+		yaAplicada[version] = true
+		estado.Version = version
+		if !conocidas[version] {
+			estado.Desconocidas = append(estado.Desconocidas, version)
+		}
+	}
+	for _, archivo := range archivos {
+		if version := versionMigracion(archivo); !yaAplicada[version] {
+			estado.Pendientes = append(estado.Pendientes, "migración "+version)
+		}
+	}
+
+	faltantes, err := d.faltantesModelos()
+	if err != nil {
+		return nil, err
+	}
+	estado.Pendientes = append(estado.Pendientes, faltantes...)
+
+	switch {
+	case !estado.AlDia():
+		estado.Advertencia = fmt.Sprintf("Esquema desactualizado: faltan %d cambios (%s). Correr el binario con -migrar", len(estado.Pendientes), resumirPendientes(estado.Pendientes))
+	case len(estado.Desconocidas) > 0:
+		estado.Advertencia = fmt.Sprintf("La base tiene migraciones que este binario no conoce (%s): ¿versión anterior desplegada?", strings.Join(estado.Desconocidas, ", "))
+	}
+	return estado, nil
+}
+
+// faltantesModelos lista las tablas y columnas de los modelos que no existen en la base.
+// No compara tipos: solo lo que haría fallar un INSERT o un SELECT
+func (d *Database) faltantesModelos() ([]string, error) {
+	migrador := d.DB.Migrator()
+	var faltantes []string
+	for _, modelo := range models.Modelos() {
+		sentencia := &gorm.Statement{DB: d.DB}
+		if err := sentencia.Parse(modelo); err != nil {
+			return nil, fmt.Errorf("error leyendo modelo %T: %w", modelo, err)
+		}
+		tabla := sentencia.Schema.Table
+		if !migrador.HasTable(tabla) {
+			faltantes = append(faltantes, "tabla "+tabla)
+			continue
+		}
+
+		columnas, err := migrador.ColumnTypes(tabla)
+		if err != nil {
+			return nil, fmt.Errorf("error leyendo columnas de %s: %w", tabla, err)
+		}
+		existentes := make(map[string]bool, len(columnas))
+		for _, columna := range columnas {
+			existentes[strings.ToLower(columna.Name())] = true
+		}
+		for _, nombre := range sentencia.Schema.DBNames {
+			if !existentes[strings.ToLower(nombre)] {
+				faltantes = append(faltantes, "columna "+tabla+"."+nombre)
+			}
+		}
+	}
+	return faltantes, nil
+}
+
+// resumirPendientes los primeros pendientes para la advertencia; el resto se cuenta
+func resumirPendientes(pendientes []string) string {
+	const mostrar = 3
+	if len(pendientes) <= mostrar {
+		return strings.Join(pendientes, ", ")
+	}
+	return fmt.Sprintf("%s y %d más", strings.Join(pendientes[:mostrar], ", "), len(pendientes)-mostrar)
+}
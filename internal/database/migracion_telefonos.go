@@ -0,0 +1,171 @@
+package database
+
+import (
+	"fmt"
+	"log"
+
+	"gorm.io/gorm"
+
+	"CheeseHouse/internal/models"
+	"CheeseHouse/internal/telefono"
+)
+
+// ResultadoMigracionTelefonos resumen de la normalización de teléfonos
+type ResultadoMigracionTelefonos struct {
+	Revisados    int `json:"revisados"`
+	Actualizados int `json:"actualizados"`
+	Fusionados   int `json:"fusionados"`
+	Invalidos    int `json:"invalidos"`
+
+	// Fusiones realizadas (el duplicado eliminado y el cliente que lo absorbió)
+	Fusiones []FusionCliente `json:"fusiones,omitempty"`
+}
+
+// FusionCliente registro de un cliente duplicado fusionado en otro
+type FusionCliente struct {
+	Duplicado       models.Cliente `json:"duplicado"`
+	SobrevivienteID uint           `json:"sobreviviente_id"`
+}
+
+// MigrarTelefonosE164 reescribe los teléfonos de clientes, pedidos y conversaciones en
+// formato E.164 canónico. Los clientes que quedan con el mismo teléfono normalizado se
+// fusionan en el más antiguo (todo lo que apunta al duplicado pasa al sobreviviente).
+func (d *Database) MigrarTelefonosE164() (*ResultadoMigracionTelefonos, error) {
+	resultado := &ResultadoMigracionTelefonos{}
+
+	var clientes []models.Cliente
+	if err := d.DB.Order("id ASC").Find(&clientes).Error; err != nil {
+		return nil, fmt.Errorf("error leyendo clientes: %w", err)
+	}
+
+	grupos := make(map[string][]models.Cliente)
+	var orden []string
+	for _, cliente := range clientes {
+		resultado.Revisados++
+
+		normalizado, err := telefono.Normalizar(cliente.Telefono)
+		if err != nil {
+			resultado.Invalidos++
+			log.Printf("⚠️  Cliente %d con teléfono inválido %q: %v", cliente.ID, cliente.Telefono, err)
+			continue
+		}
+
+		if _, ok := grupos[normalizado]; !ok {
+			orden = append(orden, normalizado)
+		}
+		grupos[normalizado] = append(grupos[normalizado], cliente)
+	}
+
+	err := d.DB.Transaction(func(tx *gorm.DB) error {
+		for _, normalizado := range orden {
+			grupo := grupos[normalizado]
+			sobreviviente := grupo[0]
+
+			for _, duplicado := range grupo[1:] {
+				if err := fusionarClientes(tx, &sobreviviente, duplicado); err != nil {
+					return err
+				}
+				resultado.Fusionados++
+				resultado.Fusiones = append(resultado.Fusiones, FusionCliente{
+					Duplicado:       duplicado,
+					SobrevivienteID: sobreviviente.ID,
+				})
+			}
+
+			if sobreviviente.Telefono == normalizado && len(grupo) == 1 {
+				continue
+			}
+
+			sobreviviente.Telefono = normalizado
+			if err := tx.Save(&sobreviviente).Error; err != nil {
+				return fmt.Errorf("error actualizando cliente %d: %w", sobreviviente.ID, err)
+			}
+			resultado.Actualizados++
+		}
+
+		return migrarTelefonosSinCliente(tx)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf("✅ Teléfonos normalizados: %d revisados, %d actualizados, %d fusionados, %d inválidos",
+		resultado.Revisados, resultado.Actualizados, resultado.Fusionados, resultado.Invalidos)
+
+	return resultado, nil
+}
+
+// fusionarClientes mueve los datos del duplicado al sobreviviente y elimina el duplicado.
+// Toda tabla con cliente_id tiene que estar acá: si falta una, la clave foránea impide
+// borrar el duplicado y la migración entera se revierte
+func fusionarClientes(tx *gorm.DB, sobreviviente *models.Cliente, duplicado models.Cliente) error {
+	tablas := []interface{}{
+		&models.Voucher{}, &models.Pedido{}, &models.ClientesVouchersEnvios{}, &models.Juego{},
+		&models.Conversacion{}, &models.BajaMarketing{}, &models.CierreRanking{},
+	}
+	for _, tabla := range tablas {
+		if err := tx.Model(tabla).
+			Where("cliente_id = ?", duplicado.ID).
+			Update("cliente_id", sobreviviente.ID).Error; err != nil {
+			return fmt.Errorf("error reasignando datos del cliente %d: %w", duplicado.ID, err)
+		}
+	}
+
+	for _, archivo := range []string{models.TablaVouchersArchivo, models.TablaJuegosArchivo} {
+		if !tx.Migrator().HasTable(archivo) {
+			continue
+		}
+		if err := tx.Table(archivo).
+			Where("cliente_id = ?", duplicado.ID).
+			Update("cliente_id", sobreviviente.ID).Error; err != nil {
+			return fmt.Errorf("error reasignando %s del cliente %d: %w", archivo, duplicado.ID, err)
+		}
+	}
+
+	if err := tx.Where("cliente_id = ?", duplicado.ID).
+		Delete(&models.PreferenciasComunicacion{}).Error; err != nil {
+		return fmt.Errorf("error eliminando preferencias del cliente %d: %w", duplicado.ID, err)
+	}
+
+	sobreviviente.TotalJuegos += duplicado.TotalJuegos
+	sobreviviente.JuegosGanados += duplicado.JuegosGanados
+	sobreviviente.JuegosPerdidos += duplicado.JuegosPerdidos
+	if duplicado.FechaUltimoJuego != nil &&
+		(sobreviviente.FechaUltimoJuego == nil || duplicado.FechaUltimoJuego.After(*sobreviviente.FechaUltimoJuego)) {
+		sobreviviente.FechaUltimoJuego = duplicado.FechaUltimoJuego
+	}
+
+	if err := tx.Delete(&models.Cliente{}, duplicado.ID).Error; err != nil {
+		return fmt.Errorf("error eliminando cliente duplicado %d: %w", duplicado.ID, err)
+	}
+
+	log.Printf("🔗 Cliente %d (%s) fusionado en cliente %d",
+		duplicado.ID, duplicado.Telefono, sobreviviente.ID)
+	return nil
+}
+
+// migrarTelefonosSinCliente normaliza los teléfonos guardados fuera de clientes: pedidos y
+// la bandeja de WhatsApp, donde las atenciones se buscan solo por teléfono
+func migrarTelefonosSinCliente(tx *gorm.DB) error {
+	tablas := []interface{}{&models.Pedido{}, &models.Conversacion{}, &models.Atencion{}}
+	for _, tabla := range tablas {
+		var telefonos []string
+		if err := tx.Model(tabla).Distinct("telefono").Pluck("telefono", &telefonos).Error; err != nil {
+			return fmt.Errorf("error leyendo teléfonos: %w", err)
+		}
+
+		for _, original := range telefonos {
+			normalizado, err := telefono.Normalizar(original)
+			if err != nil || normalizado == original {
+				continue
+			}
+			if err := tx.Model(tabla).
+				Where("telefono = ?", original).
+				Update("telefono", normalizado).Error; err != nil {
+				return fmt.Errorf("error actualizando teléfono: %w", err)
+			}
+		}
+	}
+
+	return nil
+}
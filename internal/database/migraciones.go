@@ -0,0 +1,190 @@
+package database
+
+import (
+	"embed"
+	"fmt"
+	"io/fs"
+	"log"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+
+	"CheeseHouse/internal/models"
+)
+
+// migracionesSQL cambios de esquema y datos versionados (NNNN_descripcion.sql) que
+// AutoMigrate no cubre: datos base, tablas sin modelo, índices, renombres. Si la sintaxis
+// cambia entre motores, NNNN_descripcion.postgres.sql reemplaza al archivo en Postgres
+//
+//go:embed migrations/*.sql
+var migracionesSQL embed.FS
+
+// migracionAplicada registro de una migración SQL ya ejecutada en esta base
+type migracionAplicada struct {
+	Version    string    `gorm:"primaryKey;size:100"`
+	AplicadaEn time.Time `gorm:"not null"`
+}
+
+func (migracionAplicada) TableName() string { return "schema_migraciones" }
+
+// Migrar prepara el esquema: con autoMigrate crea o amplía las tablas de todos los modelos
+// (AutoMigrate no borra columnas) y después aplica en orden las migraciones SQL que
+// todavía no corrieron en esta base
+func (d *Database) Migrar(autoMigrate bool) error {
+	defer d.olvidarEstadoEsquema()
+
+	if autoMigrate {
+		inicio := time.Now()
+		if err := d.adaptarTipos(); err != nil {
+			return err
+		}
+		if err := d.DB.AutoMigrate(models.Modelos()...); err != nil {
+			return fmt.Errorf("error en AutoMigrate: %w", err)
+		}
+		log.Printf("✅ Esquema sincronizado con los modelos (%d tablas, %v)", len(models.Modelos()), time.Since(inicio).Round(time.Millisecond))
+	}
+
+	if err := d.DB.AutoMigrate(&migracionAplicada{}); err != nil {
+		return fmt.Errorf("error creando tabla de migraciones: %w", err)
+	}
+
+	var aplicadas []string
+	if err := d.DB.Model(&migracionAplicada{}).Pluck("version", &aplicadas).Error; err != nil {
+		return fmt.Errorf("error leyendo migraciones aplicadas: %w", err)
+	}
+	yaAplicada := make(map[string]bool, len(aplicadas))
+	for _, version := range aplicadas {
+		yaAplicada[version] = true
+	}
+
+	archivos, err := d.archivosMigracion()
+	if err != nil {
+		return err
+	}
+
+	nuevas := 0
+	for _, archivo := range archivos {
+		version := versionMigracion(archivo)
+		if yaAplicada[version] {
+			continue
+		}
+		if err := d.aplicarMigracion(archivo); err != nil {
+			return fmt.Errorf("migración %s: %w", version, err)
+		}
+		if err := d.DB.Create(&migracionAplicada{Version: version, AplicadaEn: time.Now()}).Error; err != nil {
+			return fmt.Errorf("error registrando migración %s: %w", version, err)
+		}
+		log.Printf("🗃️ Migración aplicada: %s", version)
+		nuevas++
+	}
+
+	if nuevas == 0 {
+		log.Printf("✅ Esquema al día (%d migraciones SQL)", len(archivos))
+	}
+	return nil
+}
+
+// archivosMigracion lista en orden las migraciones a aplicar con este motor: la variante
+// del driver si existe, si no el archivo común
+func (d *Database) archivosMigracion() ([]string, error) {
+	todos, err := fs.Glob(migracionesSQL, "migrations/*.sql")
+	if err != nil {
+		return nil, fmt.Errorf("error listando migraciones: %w", err)
+	}
+
+	sufijo := "." + d.DB.Dialector.Name() + ".sql"
+	porVersion := make(map[string]string)
+	for _, archivo := range todos {
+		version := versionMigracion(archivo)
+		switch {
+		case strings.HasSuffix(archivo, sufijo):
+			porVersion[version] = archivo
+		case strings.Count(path.Base(archivo), ".") == 1 && porVersion[version] == "":
+			porVersion[version] = archivo
+		}
+	}
+
+	archivos := make([]string, 0, len(porVersion))
+	for _, archivo := range porVersion {
+		archivos = append(archivos, archivo)
+	}
+	sort.Slice(archivos, func(i, j int) bool { return versionMigracion(archivos[i]) < versionMigracion(archivos[j]) })
+	return archivos, nil
+}
+
+// versionMigracion nombre del archivo sin extensión ni driver: "0002_vouchers_archivo"
+func versionMigracion(archivo string) string {
+	version, _, _ := strings.Cut(path.Base(archivo), ".")
+	return version
+}
+
+// adaptarTipos cambia las columnas enum('a','b') de los modelos por varchar en Postgres,
+// que no tiene enums en línea; los valores válidos ya los controla la aplicación
+func (d *Database) adaptarTipos() error {
+	if d.DB.Dialector.Name() != "postgres" {
+		return nil
+	}
+	for _, modelo := range models.Modelos() {
+		sentencia := &gorm.Statement{DB: d.DB}
+		if err := sentencia.Parse(modelo); err != nil {
+			return fmt.Errorf("error leyendo modelo %T: %w", modelo, err)
+		}
+		// El esquema queda en la cache de GORM, así AutoMigrate usa el tipo cambiado
+		for _, campo := range sentencia.Schema.Fields {
+			valores, ok := strings.CutPrefix(strings.ToLower(string(campo.DataType)), "enum(")
+			if !ok {
+				continue
+			}
+			largo := 1
+			for _, valor := range strings.Split(strings.TrimSuffix(valores, ")"), ",") {
+				largo = max(largo, len(strings.Trim(strings.TrimSpace(valor), "'")))
+			}
+			campo.DataType = schema.DataType(fmt.Sprintf("varchar(%d)", largo))
+		}
+	}
+	return nil
+}
+
+// aplicarMigracion ejecuta las sentencias del archivo una por una. No van en una
+// transacción: en MySQL el DDL hace commit implícito, así que cada migración debe poder
+// re-ejecutarse si falla a mitad (IF NOT EXISTS, INSERT IGNORE)
+func (d *Database) aplicarMigracion(archivo string) error {
+	contenido, err := migracionesSQL.ReadFile(archivo)
+	if err != nil {
+		return err
+	}
+	for _, sentencia := range sentenciasSQL(string(contenido)) {
+		if err := d.DB.Exec(sentencia).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// sentenciasSQL separa el script en sentencias: cada una termina en una línea que acaba
+// con ";". Se descartan las líneas de comentario "--"
+func sentenciasSQL(script string) []string {
+	var (
+		sentencias []string
+		actual     strings.Builder
+	)
+	for _, linea := range strings.Split(script, "\n") {
+		recortada := strings.TrimSpace(linea)
+		if recortada == "" || strings.HasPrefix(recortada, "--") {
+			continue
+		}
+		actual.WriteString(linea + "\n")
+		if strings.HasSuffix(recortada, ";") {
+			sentencias = append(sentencias, strings.TrimSpace(actual.String()))
+			actual.Reset()
+		}
+	}
+	if resto := strings.TrimSpace(actual.String()); resto != "" {
+		sentencias = append(sentencias, resto)
+	}
+	return sentencias
+}
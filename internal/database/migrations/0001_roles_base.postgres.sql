@@ -0,0 +1,5 @@
+-- Roles con los que trabaja el sistema (RequireAdmin compara por nombre)
+INSERT INTO roles (nombre, permisos, created_at) VALUES
+    ('admin', '{"todo": true}', NOW()),
+    ('empleado', '{"canjear": true, "pedidos": true}', NOW())
+ON CONFLICT (nombre) DO NOTHING;
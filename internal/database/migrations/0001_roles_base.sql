@@ -0,0 +1,4 @@
+-- Roles con los que trabaja el sistema (RequireAdmin compara por nombre)
+INSERT IGNORE INTO roles (nombre, permisos, created_at) VALUES
+    ('admin', '{"todo": true}', NOW()),
+    ('empleado', '{"canjear": true, "pedidos": true}', NOW());
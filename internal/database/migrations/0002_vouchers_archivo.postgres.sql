@@ -0,0 +1,3 @@
+-- Tabla de archivo de vouchers viejos; el archivado también la crea si falta, pero así
+-- las búsquedas en el archivo funcionan desde la primera instalación
+CREATE TABLE IF NOT EXISTS vouchers_archivo (LIKE vouchers INCLUDING ALL);
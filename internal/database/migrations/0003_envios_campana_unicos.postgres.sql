@@ -0,0 +1,6 @@
+-- Un envío por cliente y campaña: se conservan los envíos más viejos de los duplicados
+-- que dejaron dos envíos simultáneos y el índice único impide que se repitan
+DELETE FROM clientes_vouchers_envios e
+USING clientes_vouchers_envios d
+WHERE d.campana_id = e.campana_id AND d.cliente_id = e.cliente_id AND d.id < e.id;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_envio_campana_cliente ON clientes_vouchers_envios (campana_id, cliente_id);
@@ -0,0 +1,5 @@
+-- Un envío por cliente y campaña: se conservan los envíos más viejos de los duplicados
+-- que dejaron dos envíos simultáneos y el índice único impide que se repitan
+DELETE e FROM clientes_vouchers_envios e
+JOIN clientes_vouchers_envios d ON d.campana_id = e.campana_id AND d.cliente_id = e.cliente_id AND d.id < e.id;
+CREATE UNIQUE INDEX idx_envio_campana_cliente ON clientes_vouchers_envios (campana_id, cliente_id);
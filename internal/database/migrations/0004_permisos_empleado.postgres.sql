@@ -0,0 +1,4 @@
+-- Las rutas del panel piden el permiso de su sección: el rol base de empleado suma clientes
+-- (aprobaciones y premios retenidos en caja) y conversaciones (bandeja de WhatsApp), que
+-- usaba antes de que se controlaran los permisos
+UPDATE roles SET permisos = (permisos::jsonb || '{"clientes": true, "conversaciones": true}'::jsonb)::json WHERE nombre = 'empleado';
@@ -0,0 +1,158 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"log/slog"
+	"os"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+	"gorm.io/gorm/utils"
+
+	"CheeseHouse/internal/config"
+)
+
+// ObservadorConsultas recibe cada consulta que superó el umbral de lentitud
+type ObservadorConsultas func(sql string, duracion time.Duration, filas int64)
+
+// observadoresConsultas lista compartida entre las copias del logger que crea GORM con LogMode
+type observadoresConsultas struct {
+	mu    sync.RWMutex
+	lista []ObservadorConsultas
+}
+
+// consultaLogger logger de GORM que escribe en formato estructurado (texto clave=valor
+// o JSON) y avisa de las consultas lentas a los observadores registrados
+type consultaLogger struct {
+	nivel        logger.LogLevel
+	umbral       time.Duration
+	salida       *slog.Logger
+	observadores *observadoresConsultas
+}
+
+// newConsultaLogger crea el logger de GORM según DB_LOG_*: errores y consultas lentas
+// siempre; todas las consultas solo con DB_LOG_ALL_SQL fuera de producción
+func newConsultaLogger(cfg *config.Config) *consultaLogger {
+	nivel := logger.Warn
+	if cfg.DBLog.AllSQL && !cfg.IsProduction() {
+		nivel = logger.Info
+	}
+
+	return &consultaLogger{
+		nivel:        nivel,
+		umbral:       cfg.Performance.SlowQueryThreshold,
+		salida:       slog.New(handlerLogSQL(cfg.DBLog)).With("componente", "gorm"),
+		observadores: &observadoresConsultas{},
+	}
+}
+
+// handlerLogSQL abre el destino del log SQL; si el archivo no se puede abrir usa stdout
+func handlerLogSQL(cfg config.DBLogConfig) slog.Handler {
+	var destino io.Writer = os.Stdout
+	switch cfg.Target {
+	case "", "stdout":
+	case "stderr":
+		destino = os.Stderr
+	default:
+		archivo, err := os.OpenFile(cfg.Target, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+		if err != nil {
+			log.Printf("⚠️ No se pudo abrir %s para el log SQL, usando stdout: %v", cfg.Target, err)
+		} else {
+			destino = archivo
+		}
+	}
+
+	opciones := &slog.HandlerOptions{Level: slog.LevelDebug}
+	if cfg.Format == "json" {
+		return slog.NewJSONHandler(destino, opciones)
+	}
+	return slog.NewTextHandler(destino, opciones)
+}
+
+// LogMode retorna una copia con otro nivel (db.Debug()) que conserva umbral, salida y observadores
+func (l *consultaLogger) LogMode(nivel logger.LogLevel) logger.Interface {
+	copia := *l
+	copia.nivel = nivel
+	return &copia
+}
+
+// Info mensajes informativos de GORM
+func (l *consultaLogger) Info(ctx context.Context, msg string, data ...interface{}) {
+	if l.nivel >= logger.Info {
+		l.salida.InfoContext(ctx, fmt.Sprintf(msg, data...), "origen", utils.FileWithLineNum())
+	}
+}
+
+// Warn advertencias de GORM
+func (l *consultaLogger) Warn(ctx context.Context, msg string, data ...interface{}) {
+	if l.nivel >= logger.Warn {
+		l.salida.WarnContext(ctx, fmt.Sprintf(msg, data...), "origen", utils.FileWithLineNum())
+	}
+}
+
+// Error errores de GORM
+func (l *consultaLogger) Error(ctx context.Context, msg string, data ...interface{}) {
+	if l.nivel >= logger.Error {
+		l.salida.ErrorContext(ctx, fmt.Sprintf(msg, data...), "origen", utils.FileWithLineNum())
+	}
+}
+
+// Trace loguea la consulta según el nivel (los "registro no encontrado" no se consideran
+// errores) y avisa a los observadores si fue lenta
+func (l *consultaLogger) Trace(ctx context.Context, begin time.Time, fc func() (string, int64), err error) {
+	if l.nivel <= logger.Silent {
+		return
+	}
+
+	duracion := time.Since(begin)
+	lenta := l.umbral > 0 && duracion > l.umbral
+	fallo := err != nil && !errors.Is(err, gorm.ErrRecordNotFound)
+
+	// Solo se arma el SQL si hay algo que loguear o a quién avisar
+	if !lenta && !(fallo && l.nivel >= logger.Error) && l.nivel < logger.Info {
+		return
+	}
+
+	sql, filas := fc()
+	atributos := []any{
+		"sql", sql,
+		"filas", filas,
+		"duracion_ms", float64(duracion.Microseconds()) / 1000,
+		"origen", utils.FileWithLineNum(),
+	}
+
+	switch {
+	case fallo && l.nivel >= logger.Error:
+		l.salida.ErrorContext(ctx, "error en consulta", append(atributos, "error", err.Error())...)
+	case lenta && l.nivel >= logger.Warn:
+		l.salida.WarnContext(ctx, "consulta lenta", append(atributos, "umbral_ms", l.umbral.Milliseconds())...)
+	case l.nivel >= logger.Info:
+		l.salida.DebugContext(ctx, "consulta", atributos...)
+	}
+
+	if lenta {
+		l.avisar(sql, duracion, filas)
+	}
+}
+
+// avisar pasa una consulta lenta a los observadores registrados
+func (l *consultaLogger) avisar(sql string, duracion time.Duration, filas int64) {
+	l.observadores.mu.RLock()
+	defer l.observadores.mu.RUnlock()
+	for _, observador := range l.observadores.lista {
+		observador(sql, duracion, filas)
+	}
+}
+
+// agregar registra un observador de consultas lentas
+func (l *consultaLogger) agregar(observador ObservadorConsultas) {
+	l.observadores.mu.Lock()
+	defer l.observadores.mu.Unlock()
+	l.observadores.lista = append(l.observadores.lista, observador)
+}
@@ -0,0 +1,191 @@
+// Package estaticos sirve el frontend del juego. Al arrancar calcula el hash del contenido
+// de JS, CSS e imágenes, los publica en /static con el hash en el nombre y cache de un año,
+// y reescribe las referencias de las páginas HTML; así la tablet no vuelve a bajar el
+// juego en cada carga y cada deploy invalida la cache por sí solo
+package estaticos
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"path"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Prefijo ruta donde se publican los assets con hash
+const Prefijo = "/static/"
+
+// extensionesConHash archivos que se publican con hash; el HTML siempre se revalida
+var extensionesConHash = map[string]bool{
+	".js": true, ".css": true, ".png": true, ".jpg": true, ".jpeg": true, ".gif": true,
+	".svg": true, ".webp": true, ".ico": true, ".woff": true, ".woff2": true,
+}
+
+// asset archivo cargado en memoria con su ETag
+type asset struct {
+	contenido []byte
+	etag      string
+}
+
+// Sitio sirve el frontend desde memoria con los assets ya fingerprinteados
+type Sitio struct {
+	archivos    fs.FS
+	conHash     map[string]asset  // "script.3f2a9c1b04.js" -> contenido
+	nombres     map[string]string // "script.js" -> "script.3f2a9c1b04.js"
+	paginas     map[string]asset  // "index.html" -> HTML con referencias reescritas
+	cacheAssets time.Duration
+}
+
+// Nuevo lee el frontend de archivos y arma el manifiesto de nombres con hash.
+// cacheAssets es el max-age de los assets con hash
+func Nuevo(archivos fs.FS, cacheAssets time.Duration) (*Sitio, error) {
+	s := &Sitio{
+		archivos:    archivos,
+		conHash:     make(map[string]asset),
+		nombres:     make(map[string]string),
+		paginas:     make(map[string]asset),
+		cacheAssets: cacheAssets,
+	}
+
+	var htmls []string
+	err := fs.WalkDir(archivos, ".", func(ruta string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		extension := strings.ToLower(path.Ext(ruta))
+		if extension == ".html" {
+			htmls = append(htmls, ruta)
+			return nil
+		}
+		if !extensionesConHash[extension] {
+			return nil
+		}
+
+		contenido, err := fs.ReadFile(archivos, ruta)
+		if err != nil {
+			return err
+		}
+		hash := hashContenido(contenido)
+		nombre := strings.TrimSuffix(ruta, path.Ext(ruta)) + "." + hash[:10] + path.Ext(ruta)
+		s.nombres[ruta] = nombre
+		s.conHash[nombre] = asset{contenido: contenido, etag: `"` + hash + `"`}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error leyendo archivos estáticos: %w", err)
+	}
+
+	for _, ruta := range htmls {
+		contenido, err := fs.ReadFile(archivos, ruta)
+		if err != nil {
+			return nil, fmt.Errorf("error leyendo %s: %w", ruta, err)
+		}
+		contenido = s.reescribir(ruta, contenido)
+		s.paginas[ruta] = asset{contenido: contenido, etag: `"` + hashContenido(contenido) + `"`}
+	}
+	return s, nil
+}
+
+// Manifiesto nombre original -> URL publicada de cada asset con hash
+func (s *Sitio) Manifiesto() map[string]string {
+	manifiesto := make(map[string]string, len(s.nombres))
+	for original, nombre := range s.nombres {
+		manifiesto[original] = Prefijo + nombre
+	}
+	return manifiesto
+}
+
+// ServeHTTP sirve los assets con hash (cache larga e inmutable), las páginas HTML
+// reescritas (se revalidan con ETag) y el resto de los archivos tal cual
+func (s *Sitio) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	ruta := strings.TrimPrefix(path.Clean("/"+r.URL.Path), "/")
+
+	if nombre, ok := strings.CutPrefix("/"+ruta, Prefijo); ok {
+		archivo, existe := s.conHash[nombre]
+		if !existe {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Cache-Control", fmt.Sprintf("public, max-age=%d, immutable", int(s.cacheAssets.Seconds())))
+		servir(w, r, nombre, archivo)
+		return
+	}
+
+	if ruta == "" {
+		ruta = "index.html"
+	}
+	if pagina, ok := s.paginas[ruta]; ok {
+		w.Header().Set("Cache-Control", "no-cache")
+		servir(w, r, ruta, pagina)
+		return
+	}
+
+	// Nombres originales y otros archivos: se revalidan siempre por si algo quedó sin hash
+	w.Header().Set("Cache-Control", "no-cache")
+	http.FileServer(http.FS(s.archivos)).ServeHTTP(w, r)
+}
+
+// Existe indica si la ruta de la URL corresponde a un archivo del frontend ("/" y las
+// carpetas cuentan si tienen index.html)
+func Existe(archivos fs.FS, rutaURL string) bool {
+	ruta := strings.TrimPrefix(path.Clean("/"+rutaURL), "/")
+	if ruta == "" {
+		ruta = "."
+	}
+	info, err := fs.Stat(archivos, ruta)
+	if err != nil {
+		return false
+	}
+	if info.IsDir() {
+		_, err = fs.Stat(archivos, path.Join(ruta, "index.html"))
+		return err == nil
+	}
+	return true
+}
+
+// reescribir cambia en el HTML las referencias a assets ("script.js", "/script.js") por
+// su URL con hash. Las rutas se resuelven relativas a la carpeta de la página
+func (s *Sitio) reescribir(pagina string, html []byte) []byte {
+	originales := make([]string, 0, len(s.nombres))
+	for original := range s.nombres {
+		originales = append(originales, original)
+	}
+	// Primero los más largos para que "img/logo.png" no lo pise "logo.png"
+	sort.Slice(originales, func(i, j int) bool { return len(originales[i]) > len(originales[j]) })
+
+	carpeta := path.Dir(pagina)
+	for _, original := range originales {
+		destino := []byte(Prefijo + s.nombres[original])
+		referencias := []string{"/" + original}
+		if carpeta == "." {
+			referencias = append(referencias, original, "./"+original)
+		} else if relativa, ok := strings.CutPrefix(original, carpeta+"/"); ok {
+			referencias = append(referencias, relativa, "./"+relativa)
+		}
+		for _, referencia := range referencias {
+			for _, comilla := range []string{`"`, `'`} {
+				html = bytes.ReplaceAll(html,
+					[]byte(comilla+referencia+comilla),
+					append(append([]byte(comilla), destino...), comilla...))
+			}
+		}
+	}
+	return html
+}
+
+// servir responde el contenido con su ETag; ServeContent contesta 304 si el navegador
+// ya lo tiene
+func servir(w http.ResponseWriter, r *http.Request, nombre string, archivo asset) {
+	w.Header().Set("ETag", archivo.etag)
+	http.ServeContent(w, r, nombre, time.Time{}, bytes.NewReader(archivo.contenido))
+}
+
+func hashContenido(contenido []byte) string {
+	suma := sha256.Sum256(contenido)
+	return hex.EncodeToString(suma[:])
+}
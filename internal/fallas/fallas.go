@@ -0,0 +1,155 @@
+// Package fallas inyecta fallas a propósito en desarrollo: errores y 429 de la API de
+// WhatsApp y latencia en la base, para probar de punta a punta los reintentos, el outbox
+// y el corte del circuito de premios antes de que pasen en producción
+package fallas
+
+import (
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Estado fallas activas; todo en cero = sin fallas
+type Estado struct {
+	WhatsAppErrorRate float64 `json:"whatsapp_error_rate"` // 0-1: envíos que reciben un 500 del proveedor
+	WhatsApp429Rate   float64 `json:"whatsapp_429_rate"`   // 0-1: envíos que reciben un 429 (rate limit)
+	DBLatenciaMs      int     `json:"db_latencia_ms"`      // Demora agregada a cada consulta
+}
+
+// Contadores fallas inyectadas desde el arranque
+type Contadores struct {
+	WhatsAppErrores    int64 `json:"whatsapp_errores"`
+	WhatsApp429        int64 `json:"whatsapp_429"`
+	ConsultasDemoradas int64 `json:"consultas_demoradas"`
+}
+
+// Inyector guarda las fallas activas; se cambian en caliente desde /api/dev/fallas
+type Inyector struct {
+	mu         sync.RWMutex
+	estado     Estado
+	contadores Contadores
+}
+
+// Nuevo crea el inyector con las fallas iniciales de la configuración
+func Nuevo(inicial Estado) (*Inyector, error) {
+	i := &Inyector{}
+	if err := i.Configurar(inicial); err != nil {
+		return nil, err
+	}
+	return i, nil
+}
+
+// Estado retorna las fallas activas y cuántas se inyectaron
+func (i *Inyector) Estado() (Estado, Contadores) {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+	return i.estado, i.contadores
+}
+
+// Configurar reemplaza las fallas activas
+func (i *Inyector) Configurar(estado Estado) error {
+	if estado.WhatsAppErrorRate < 0 || estado.WhatsApp429Rate < 0 || estado.WhatsAppErrorRate+estado.WhatsApp429Rate > 1 {
+		return fmt.Errorf("las tasas de falla de WhatsApp van de 0 a 1 y juntas no pueden superar 1")
+	}
+	if estado.DBLatenciaMs < 0 || estado.DBLatenciaMs > 30000 {
+		return fmt.Errorf("la latencia de la base debe estar entre 0 y 30000 ms")
+	}
+
+	i.mu.Lock()
+	i.estado = estado
+	i.mu.Unlock()
+	return nil
+}
+
+// Transporte envuelve el transporte HTTP del cliente de WhatsApp: según las tasas
+// configuradas responde un 429 o un 500 con el formato de error de Meta sin llegar al
+// proveedor; el resto de los pedidos pasa al transporte original
+func (i *Inyector) Transporte(base http.RoundTripper) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return transporteConFallas{inyector: i, base: base}
+}
+
+type transporteConFallas struct {
+	inyector *Inyector
+	base     http.RoundTripper
+}
+
+func (t transporteConFallas) RoundTrip(req *http.Request) (*http.Response, error) {
+	// Solo se simulan fallas de envío; consultas de estado y cuota pasan siempre
+	if req.Method != http.MethodPost {
+		return t.base.RoundTrip(req)
+	}
+
+	t.inyector.mu.Lock()
+	sorteo := rand.Float64()
+	status, cuerpo := 0, ""
+	switch {
+	case sorteo < t.inyector.estado.WhatsApp429Rate:
+		status = http.StatusTooManyRequests
+		cuerpo = `{"error":{"message":"(#130429) Rate limit hit (falla inyectada)","type":"OAuthException","code":130429}}`
+		t.inyector.contadores.WhatsApp429++
+	case sorteo < t.inyector.estado.WhatsApp429Rate+t.inyector.estado.WhatsAppErrorRate:
+		status = http.StatusInternalServerError
+		cuerpo = `{"error":{"message":"Service temporarily unavailable (falla inyectada)","type":"OAuthException","code":2}}`
+		t.inyector.contadores.WhatsAppErrores++
+	}
+	t.inyector.mu.Unlock()
+
+	if status == 0 {
+		return t.base.RoundTrip(req)
+	}
+	if req.Body != nil {
+		req.Body.Close()
+	}
+	return &http.Response{
+		StatusCode: status,
+		Status:     fmt.Sprintf("%d %s", status, http.StatusText(status)),
+		Proto:      "HTTP/1.1",
+		ProtoMajor: 1,
+		ProtoMinor: 1,
+		Header:     http.Header{"Content-Type": []string{"application/json"}},
+		Body:       io.NopCloser(strings.NewReader(cuerpo)),
+		Request:    req,
+	}, nil
+}
+
+// RegistrarEnBase agrega la latencia configurada antes de cada consulta de GORM
+func (i *Inyector) RegistrarEnBase(db *gorm.DB) error {
+	demorar := func(*gorm.DB) {
+		i.mu.RLock()
+		latencia := time.Duration(i.estado.DBLatenciaMs) * time.Millisecond
+		i.mu.RUnlock()
+		if latencia <= 0 {
+			return
+		}
+
+		i.mu.Lock()
+		i.contadores.ConsultasDemoradas++
+		i.mu.Unlock()
+		time.Sleep(latencia)
+	}
+
+	callbacks := db.Callback()
+	registros := []error{
+		callbacks.Create().Before("gorm:create").Register("fallas:latencia", demorar),
+		callbacks.Query().Before("gorm:query").Register("fallas:latencia", demorar),
+		callbacks.Update().Before("gorm:update").Register("fallas:latencia", demorar),
+		callbacks.Delete().Before("gorm:delete").Register("fallas:latencia", demorar),
+		callbacks.Row().Before("gorm:row").Register("fallas:latencia", demorar),
+		callbacks.Raw().Before("gorm:raw").Register("fallas:latencia", demorar),
+	}
+	for _, err := range registros {
+		if err != nil {
+			return fmt.Errorf("error registrando latencia inyectada: %w", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,67 @@
+// Package fechas centraliza cómo se muestran las fechas: siempre en la zona horaria del
+// restaurante y con el orden y la hora que usa cada idioma. La API devuelve además la
+// fecha en ISO 8601 para que el frontend no tenga que interpretar el texto
+package fechas
+
+import "time"
+
+// disenos layouts de time.Format de un idioma
+type disenos struct {
+	fecha  string
+	diaMes string
+	hora   string
+}
+
+// porLocale formatos de cada idioma soportado; "es" es el de respaldo
+var porLocale = map[string]disenos{
+	"es": {fecha: "02/01/2006", diaMes: "02/01", hora: "15:04"},
+	"en": {fecha: "01/02/2006", diaMes: "01/02", hora: "3:04 PM"},
+}
+
+// Formato formatea fechas en una zona horaria y un idioma
+type Formato struct {
+	zona    *time.Location
+	disenos disenos
+}
+
+// Nuevo crea el formato para la zona horaria y el locale ya normalizado ("es", "en")
+func Nuevo(zona *time.Location, locale string) Formato {
+	d, ok := porLocale[locale]
+	if !ok {
+		d = porLocale["es"]
+	}
+	if zona == nil {
+		zona = time.Local
+	}
+	return Formato{zona: zona, disenos: d}
+}
+
+// Fecha día para mostrar: "31/12/2024" (es), "12/31/2024" (en)
+func (f Formato) Fecha(t time.Time) string {
+	return t.In(f.zona).Format(f.disenos.fecha)
+}
+
+// FechaHora día y hora para mostrar: "31/12/2024 23:00" (es), "12/31/2024 11:00 PM" (en)
+func (f Formato) FechaHora(t time.Time) string {
+	return t.In(f.zona).Format(f.disenos.fecha + " " + f.disenos.hora)
+}
+
+// DiaMes día sin año para textos cortos: "31/12" (es), "12/31" (en)
+func (f Formato) DiaMes(t time.Time) string {
+	return t.In(f.zona).Format(f.disenos.diaMes)
+}
+
+// Hora hora del día: "23:00" (es), "11:00 PM" (en)
+func (f Formato) Hora(t time.Time) string {
+	return t.In(f.zona).Format(f.disenos.hora)
+}
+
+// ISO fecha y hora en ISO 8601 con el offset del restaurante, para la API
+func (f Formato) ISO(t time.Time) string {
+	return t.In(f.zona).Format(time.RFC3339)
+}
+
+// Dia día en ISO 8601 (AAAA-MM-DD) según el calendario del restaurante
+func (f Formato) Dia(t time.Time) string {
+	return t.In(f.zona).Format(time.DateOnly)
+}
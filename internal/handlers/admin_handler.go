@@ -0,0 +1,1981 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"CheeseHouse/internal/api"
+	"CheeseHouse/internal/models"
+	"CheeseHouse/internal/planilla"
+	"CheeseHouse/internal/repository"
+	"CheeseHouse/internal/services"
+)
+
+// AdminHandler maneja los endpoints del panel administrativo
+type AdminHandler struct {
+	adminService   *services.AdminService
+	reporteService *services.ReporteService
+	costoCampana   *services.CostoCampanaService
+	branding       *services.BrandingService
+	notificaciones *services.NotificacionService
+	bloqueos       *services.BloqueoService
+	archivo        *services.ArchivoService
+	celebracion    *services.CelebracionService
+	diasSinCanje   *services.DiaSinCanjeService
+	canjesEmpleado *services.CanjeEmpleadoService
+	calendario     *services.CalendarioService
+	metas          *services.MetaService
+	artefactos     *services.ArtefactoService
+	trabajos       *services.TrabajoService
+	mantenimiento  *services.MantenimientoService
+	ranking        *services.RankingService
+}
+
+// NewAdminHandler crea una nueva instancia del handler administrativo
+func NewAdminHandler(
+	adminService *services.AdminService,
+	reporteService *services.ReporteService,
+	costoCampana *services.CostoCampanaService,
+	branding *services.BrandingService,
+	notificaciones *services.NotificacionService,
+	bloqueos *services.BloqueoService,
+	archivo *services.ArchivoService,
+	celebracion *services.CelebracionService,
+	diasSinCanje *services.DiaSinCanjeService,
+	canjesEmpleado *services.CanjeEmpleadoService,
+	calendario *services.CalendarioService,
+	metas *services.MetaService,
+	artefactos *services.ArtefactoService,
+	trabajos *services.TrabajoService,
+	mantenimiento *services.MantenimientoService,
+	ranking *services.RankingService,
+) *AdminHandler {
+	return &AdminHandler{
+		adminService:   adminService,
+		reporteService: reporteService,
+		costoCampana:   costoCampana,
+		branding:       branding,
+		notificaciones: notificaciones,
+		bloqueos:       bloqueos,
+		archivo:        archivo,
+		celebracion:    celebracion,
+		diasSinCanje:   diasSinCanje,
+		canjesEmpleado: canjesEmpleado,
+		calendario:     calendario,
+		metas:          metas,
+		artefactos:     artefactos,
+		trabajos:       trabajos,
+		mantenimiento:  mantenimiento,
+		ranking:        ranking,
+	}
+}
+
+// GetDashboard obtiene los datos del dashboard junto con las alertas operativas
+func (h *AdminHandler) GetDashboard(c *gin.Context) {
+	datos, err := h.adminService.GetDashboardData()
+	if err != nil {
+		log.Printf("❌ Error obteniendo dashboard: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "Error obteniendo datos del dashboard",
+		})
+		return
+	}
+
+	// Sin metas el dashboard sigue funcionando
+	metas, err := h.metas.Progreso("")
+	if err != nil {
+		log.Printf("⚠️  Error obteniendo avance de metas: %v", err)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":   true,
+		"dashboard": datos,
+		"alertas":   h.adminService.GetAlertasOperativas(),
+		"metas":     metas,
+	})
+}
+
+// GetAlertas lista las alertas operativas (vouchers por vencer, WhatsApp, circuito de premios)
+func (h *AdminHandler) GetAlertas(c *gin.Context) {
+	alertas := h.adminService.GetAlertasOperativas()
+	if alertas == nil {
+		alertas = []map[string]interface{}{}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"alertas": alertas,
+	})
+}
+
+// CanjearVoucher canjea un voucher en caja a nombre del empleado autenticado. Los
+// rechazos (usado, vencido, día sin canje, tope diario, falta la aprobación de un encargado)
+// responden 422 con el motivo
+func (h *AdminHandler) CanjearVoucher(c *gin.Context) {
+	var req models.CanjearVoucherRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Código de voucher inválido",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	sucursal := ""
+	if usuario, ok := c.Value("usuario").(*models.Usuario); ok {
+		sucursal = usuario.Sucursal
+	}
+	req.AprobadorEmail = strings.ToLower(strings.TrimSpace(req.AprobadorEmail))
+
+	respuesta, err := h.adminService.CanjearVoucher(strings.ToUpper(strings.TrimSpace(req.Codigo)), c.GetUint("user_id"), sucursal, req.AprobacionCanje)
+	if err != nil {
+		log.Printf("❌ Error canjeando voucher %s: %v", req.Codigo, err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "Error procesando canje",
+		})
+		return
+	}
+
+	estado := http.StatusOK
+	if !respuesta.Success {
+		estado = http.StatusUnprocessableEntity
+	}
+	c.JSON(estado, respuesta)
+}
+
+// EscanearVoucher canjea un voucher desde el QR leído con la cámara de la caja
+func (h *AdminHandler) EscanearVoucher(c *gin.Context) {
+	var req models.EscanearVoucherRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "QR de voucher inválido",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	sucursal := ""
+	if usuario, ok := c.Value("usuario").(*models.Usuario); ok {
+		sucursal = usuario.Sucursal
+	}
+	req.AprobadorEmail = strings.ToLower(strings.TrimSpace(req.AprobadorEmail))
+
+	respuesta, err := h.adminService.CanjearVoucherQR(req.Contenido, c.GetUint("user_id"), sucursal, req.AprobacionCanje)
+	if err != nil {
+		log.Printf("❌ Error canjeando voucher escaneado: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "Error procesando canje",
+		})
+		return
+	}
+
+	estado := http.StatusOK
+	if !respuesta.Success {
+		estado = http.StatusUnprocessableEntity
+	}
+	c.JSON(estado, respuesta)
+}
+
+// TransferirVoucher mueve un voucher sin usar a otra sucursal (solo administradores)
+func (h *AdminHandler) TransferirVoucher(c *gin.Context) {
+	var req models.TransferirVoucherRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Datos de la transferencia inválidos",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	voucher, err := h.adminService.TransferirVoucher(c.Param("codigo"), req, c.GetUint("user_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Voucher transferido",
+		"voucher": api.NuevoVoucher(voucher),
+	})
+}
+
+// ListarVouchers lista una página de vouchers (?tipo=&usado=&ganado=&cliente_id=&fecha_desde=
+// &fecha_hasta=&vencido=true&por_vencer_dias=&page=&page_size=&sort=)
+func (h *AdminHandler) ListarVouchers(c *gin.Context) {
+	paginacion, ok := parsePaginacion(c, 100, 500)
+	if !ok {
+		return
+	}
+
+	filtros, ok := filtrosVouchers(c)
+	if !ok {
+		return
+	}
+
+	vouchers, total, err := h.adminService.GetVouchers(filtros, paginacion)
+	if err != nil {
+		responderErrorListado(c, err, "Error obteniendo vouchers")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":    true,
+		"vouchers":   api.NuevosVouchers(vouchers),
+		"total":      total,
+		"paginacion": paginacion.Meta(total),
+	})
+}
+
+// filtrosVouchers lee los filtros del listado de vouchers (?tipo=&usado=&ganado=&cliente_id=
+// &fecha_desde=&fecha_hasta=&vencido=&por_vencer_dias=); si una fecha es inválida responde 400
+func filtrosVouchers(c *gin.Context) (map[string]interface{}, bool) {
+	filtros := map[string]interface{}{}
+	if tipo := c.Query("tipo"); tipo != "" {
+		filtros["tipo"] = tipo
+	}
+	for _, campo := range []string{"usado", "ganado"} {
+		if valor, err := strconv.ParseBool(c.Query(campo)); err == nil {
+			filtros[campo] = valor
+		}
+	}
+	if clienteID, err := strconv.ParseUint(c.Query("cliente_id"), 10, 32); err == nil {
+		filtros["cliente_id"] = uint(clienteID)
+	}
+	for _, campo := range []string{"fecha_desde", "fecha_hasta"} {
+		if valor := c.Query(campo); valor != "" {
+			fecha, err := time.Parse("2006-01-02", valor)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{
+					"success": false,
+					"message": campo + " inválida, usar el formato AAAA-MM-DD",
+				})
+				return nil, false
+			}
+			if campo == "fecha_hasta" {
+				fecha = fecha.AddDate(0, 0, 1).Add(-time.Second)
+			}
+			filtros[campo] = fecha
+		}
+	}
+	if c.Query("vencido") == "true" {
+		filtros["vencido"] = true
+	}
+	if dias, err := strconv.Atoi(c.Query("por_vencer_dias")); err == nil && dias > 0 {
+		filtros["por_vencer_dias"] = dias
+	}
+	return filtros, true
+}
+
+// ListarVouchersVencidos lista los vouchers vencidos en los últimos días (?dias=30)
+func (h *AdminHandler) ListarVouchersVencidos(c *gin.Context) {
+	dias, err := strconv.Atoi(c.DefaultQuery("dias", "30"))
+	if err != nil || dias < 1 || dias > 365 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "El parámetro dias debe estar entre 1 y 365",
+		})
+		return
+	}
+
+	vouchers, err := h.adminService.GetVouchersVencidos(dias)
+	if err != nil {
+		log.Printf("❌ Error listando vouchers vencidos: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "Error obteniendo vouchers vencidos",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":  true,
+		"dias":     dias,
+		"vouchers": api.NuevosVouchers(vouchers),
+		"total":    len(vouchers),
+	})
+}
+
+// ListarClientes lista una página de clientes con sus estadísticas (?telefono=&nombre=&estado=
+// &tipo_cliente=&min_juegos=&page=&page_size=&sort=)
+func (h *AdminHandler) ListarClientes(c *gin.Context) {
+	paginacion, ok := parsePaginacion(c, 50, 200)
+	if !ok {
+		return
+	}
+
+	clientes, total, err := h.adminService.GetClientes(filtrosClientes(c), paginacion)
+	if err != nil {
+		responderErrorListado(c, err, "Error obteniendo clientes")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":    true,
+		"clientes":   api.NuevosClientesConEstadisticas(clientes),
+		"total":      total,
+		"paginacion": paginacion.Meta(total),
+	})
+}
+
+// filtrosClientes lee los filtros del listado de clientes (?telefono=&nombre=&email=&estado=
+// &tipo_cliente=&min_juegos=)
+func filtrosClientes(c *gin.Context) map[string]interface{} {
+	filtros := map[string]interface{}{}
+	for _, campo := range []string{"telefono", "nombre", "email", "estado", "tipo_cliente"} {
+		if valor := c.Query(campo); valor != "" {
+			filtros[campo] = valor
+		}
+	}
+	if minJuegos, err := strconv.Atoi(c.Query("min_juegos")); err == nil && minJuegos > 0 {
+		filtros["min_juegos"] = minJuegos
+	}
+	return filtros
+}
+
+// ListarClientesPendientes lista los clientes que hoy necesitan aprobación para seguir jugando
+func (h *AdminHandler) ListarClientesPendientes(c *gin.Context) {
+	clientes, err := h.adminService.GetClientesPendientesAprobacion()
+	if err != nil {
+		log.Printf("❌ Error listando clientes pendientes de aprobación: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "Error obteniendo clientes pendientes",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":  true,
+		"clientes": api.NuevosClientesConEstadisticas(clientes),
+		"total":    len(clientes),
+	})
+}
+
+// GetCliente obtiene el detalle de un cliente con sus estadísticas
+func (h *AdminHandler) GetCliente(c *gin.Context) {
+	clienteID, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	cliente, err := h.adminService.GetClienteDetalle(clienteID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"message": "Cliente no encontrado",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"cliente": api.NuevoClienteConEstadisticas(cliente),
+	})
+}
+
+// AprobarCliente habilita a un cliente a seguir jugando hoy
+func (h *AdminHandler) AprobarCliente(c *gin.Context) {
+	clienteID, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	if err := h.adminService.AprobarJuegoFrecuente(clienteID, c.GetUint("user_id")); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Cliente aprobado",
+	})
+}
+
+// CambiarEstadoCliente bloquea o reactiva a un cliente
+func (h *AdminHandler) CambiarEstadoCliente(c *gin.Context) {
+	clienteID, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	var req models.CambiarEstadoClienteRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Datos de estado inválidos",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	cliente, err := h.adminService.CambiarEstadoCliente(clienteID, req.Estado, req.Motivo, c.GetUint("user_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Estado del cliente actualizado",
+		"cliente": api.NuevoCliente(cliente),
+	})
+}
+
+// AnonimizarCliente borra los datos personales de un cliente conservando sus estadísticas
+func (h *AdminHandler) AnonimizarCliente(c *gin.Context) {
+	clienteID, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	cliente, err := h.adminService.AnonimizarCliente(clienteID, c.GetUint("user_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Cliente anonimizado",
+		"cliente": api.NuevoCliente(cliente),
+	})
+}
+
+// GetReporteVentas reporte de vouchers canjeados (?fecha_inicio=&fecha_fin=&tipo=&descuento_minimo=).
+// Sin fechas toma los últimos 30 días
+func (h *AdminHandler) GetReporteVentas(c *gin.Context) {
+	fin := time.Now()
+	inicio := fin.AddDate(0, 0, -30)
+	for campo, destino := range map[string]*time.Time{"fecha_inicio": &inicio, "fecha_fin": &fin} {
+		valor := c.Query(campo)
+		if valor == "" {
+			continue
+		}
+		fecha, err := time.Parse("2006-01-02", valor)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"message": campo + " inválida, usar el formato AAAA-MM-DD",
+			})
+			return
+		}
+		if campo == "fecha_fin" {
+			fecha = fecha.AddDate(0, 0, 1).Add(-time.Second)
+		}
+		*destino = fecha
+	}
+	if inicio.After(fin) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "fecha_inicio debe ser anterior a fecha_fin",
+		})
+		return
+	}
+
+	filtros := map[string]string{
+		"tipo":             c.Query("tipo"),
+		"descuento_minimo": c.Query("descuento_minimo"),
+	}
+	reporte, err := h.adminService.GetReporteVentasConFiltros(inicio, fin, filtros)
+	if err != nil {
+		log.Printf("❌ Error generando reporte de ventas: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "Error generando reporte",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"reporte": reporte,
+	})
+}
+
+// GetEstadisticasDetalladas estadísticas de clientes y vouchers para los reportes, con la
+// comparación de la semana y el mes en curso contra el período anterior
+func (h *AdminHandler) GetEstadisticasDetalladas(c *gin.Context) {
+	estadisticas, err := h.adminService.GetEstadisticasDetalladas()
+	if err != nil {
+		log.Printf("❌ Error obteniendo estadísticas detalladas: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "Error obteniendo estadísticas",
+		})
+		return
+	}
+
+	// Sin la comparación de períodos las estadísticas siguen funcionando
+	comparaciones, err := h.metas.CompararTodos()
+	if err != nil {
+		log.Printf("⚠️  Error comparando períodos: %v", err)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":       true,
+		"estadisticas":  estadisticas,
+		"comparaciones": comparaciones,
+	})
+}
+
+// GetComparacion compara los indicadores de la semana en curso con la pasada y los del
+// mes en curso con el mismo mes del año anterior (?periodo=semana|mes, vacío = ambos)
+func (h *AdminHandler) GetComparacion(c *gin.Context) {
+	periodo := c.Query("periodo")
+	if periodo == "" {
+		comparaciones, err := h.metas.CompararTodos()
+		if err != nil {
+			log.Printf("❌ Error comparando períodos: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"success": false,
+				"message": "Error comparando períodos",
+			})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"success":       true,
+			"comparaciones": comparaciones,
+		})
+		return
+	}
+
+	comparacion, err := h.metas.Comparar(periodo)
+	if errors.Is(err, services.ErrPeriodoComparacion) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+	if err != nil {
+		log.Printf("❌ Error comparando períodos: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "Error comparando períodos",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":     true,
+		"comparacion": comparacion,
+	})
+}
+
+// ExportarDatos exporta clientes, contactos de marketing, vouchers o todo (/exportar/:tipo).
+// Con ?guardar=true la página queda guardada como archivo y se responde el link de descarga
+func (h *AdminHandler) ExportarDatos(c *gin.Context) {
+	paginacion, ok := parsePaginacion(c, 1000, 5000)
+	if !ok {
+		return
+	}
+
+	datos, err := h.adminService.ExportarDatos(c.Param("tipo"), paginacion)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	log.Printf("📦 Usuario %d exportó %s", c.GetUint("user_id"), c.Param("tipo"))
+
+	if c.Query("guardar") == "true" {
+		archivo, err := h.guardarExportacion(c.Param("tipo"), paginacion.Pagina, datos)
+		if err != nil {
+			log.Printf("❌ Error guardando exportación %s: %v", c.Param("tipo"), err)
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"success": false,
+				"message": "Error guardando la exportación",
+			})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"success": true,
+			"archivo": archivo,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"datos":   datos,
+	})
+}
+
+// ExportarDatosEnSegundoPlano guarda todas las páginas de la exportación como archivos en
+// un trabajo en segundo plano (/exportar/:tipo/trabajo?page_size=). Responde con el
+// trabajo para seguir el avance en /jobs/:id
+func (h *AdminHandler) ExportarDatosEnSegundoPlano(c *gin.Context) {
+	paginacion, ok := parsePaginacion(c, 1000, 5000)
+	if !ok {
+		return
+	}
+	tipo := c.Param("tipo")
+
+	// La primera página valida el tipo y dice cuántas hay
+	primera, err := h.adminService.ExportarDatos(tipo, models.Paginacion{Pagina: 1, TamanoPagina: paginacion.TamanoPagina, Orden: paginacion.Orden})
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+	paginas := paginasExportacion(primera)
+
+	trabajo := h.trabajos.Iniciar("exportacion", fmt.Sprintf("Exportación de %s", tipo), paginas, true, func(avance *services.AvanceTrabajo) error {
+		archivos := make([]*models.ArchivoGenerado, 0, paginas)
+		defer func() { avance.Resultado(gin.H{"archivos": archivos}) }()
+
+		datos := primera
+		for pagina := 1; pagina <= paginas; pagina++ {
+			if avance.Cancelado() {
+				return nil
+			}
+			if pagina > 1 {
+				siguiente, err := h.adminService.ExportarDatos(tipo, models.Paginacion{Pagina: pagina, TamanoPagina: paginacion.TamanoPagina, Orden: paginacion.Orden})
+				if err != nil {
+					return err
+				}
+				datos = siguiente
+			}
+			archivo, err := h.guardarExportacion(tipo, pagina, datos)
+			if err != nil {
+				avance.Fallo(fmt.Sprintf("página %d", pagina), err)
+				continue
+			}
+			archivos = append(archivos, archivo)
+			avance.Avanzar(1)
+		}
+		return nil
+	})
+
+	log.Printf("📦 Usuario %d inició la exportación de %s (%d páginas)", c.GetUint("user_id"), tipo, paginas)
+	c.JSON(http.StatusAccepted, gin.H{
+		"success": true,
+		"trabajo": trabajo,
+	})
+}
+
+// paginasExportacion cantidad de páginas de una exportación: la mayor de sus paginaciones
+// (la completa pagina clientes y vouchers por separado)
+func paginasExportacion(datos map[string]interface{}) int {
+	paginas := 1
+	for _, valor := range datos {
+		if meta, ok := valor.(models.MetaPaginacion); ok {
+			paginas = max(paginas, meta.TotalPaginas)
+		}
+	}
+	return paginas
+}
+
+// ExportarPlanilla descarga todos los clientes o vouchers que cumplen los filtros de su
+// listado como planilla para Excel (/export/clientes.csv, /export/vouchers.xlsx). El archivo
+// se escribe en la respuesta a medida que se lee la base, sin paginar
+func (h *AdminHandler) ExportarPlanilla(c *gin.Context) {
+	tipo, formato, _ := strings.Cut(c.Param("archivo"), ".")
+	hoja := map[string]string{"clientes": "Clientes", "vouchers": "Vouchers"}[tipo]
+	if hoja == "" || (formato != planilla.FormatoCSV && formato != planilla.FormatoXLSX) {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"message": "Exportación no encontrada: usar clientes o vouchers con extensión .csv o .xlsx",
+		})
+		return
+	}
+
+	filtros := filtrosClientes(c)
+	if tipo == "vouchers" {
+		var ok bool
+		if filtros, ok = filtrosVouchers(c); !ok {
+			return
+		}
+	}
+
+	nombre := fmt.Sprintf("%s-%s.%s", tipo, time.Now().Format("20060102-150405"), formato)
+	c.Header("Content-Type", planilla.TipoContenido(formato))
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s\"", nombre))
+	c.Status(http.StatusOK)
+
+	escritor, err := planilla.Nuevo(formato, c.Writer, hoja)
+	if err != nil {
+		log.Printf("❌ Error iniciando la exportación %s: %v", nombre, err)
+		return
+	}
+	filas, err := h.adminService.ExportarPlanilla(tipo, filtros, escritor)
+	if err == nil {
+		err = escritor.Cerrar()
+	}
+	if err != nil {
+		// La descarga ya empezó: el archivo queda incompleto (el XLSX no abre)
+		log.Printf("❌ Exportación %s interrumpida tras %d filas: %v", nombre, filas, err)
+		return
+	}
+
+	log.Printf("📦 Usuario %d exportó %d %s en %s", c.GetUint("user_id"), filas, tipo, formato)
+}
+
+// guardarExportacion publica una página de la exportación como exportaciones/<tipo>-<fecha>-p<página>.json
+func (h *AdminHandler) guardarExportacion(tipo string, pagina int, datos interface{}) (*models.ArchivoGenerado, error) {
+	contenido, err := json.Marshal(datos)
+	if err != nil {
+		return nil, err
+	}
+	nombre := fmt.Sprintf("%s-%s-p%d.json", tipo, time.Now().Format("20060102-150405"), pagina)
+	return h.artefactos.Publicar("exportaciones", nombre, contenido, "application/json")
+}
+
+// ListarReportes lista los reportes guardados del usuario autenticado
+func (h *AdminHandler) ListarReportes(c *gin.Context) {
+	reportes, err := h.reporteService.Listar(c.GetUint("user_id"))
+	if err != nil {
+		log.Printf("❌ Error listando reportes: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "Error obteniendo reportes",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":  true,
+		"reportes": reportes,
+	})
+}
+
+// GuardarReporte crea una nueva definición de reporte
+func (h *AdminHandler) GuardarReporte(c *gin.Context) {
+	var req models.GuardarReporteRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Datos de reporte inválidos",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	reporte, err := h.reporteService.Guardar(c.GetUint("user_id"), &req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"success": true,
+		"message": "Reporte guardado",
+		"reporte": reporte,
+	})
+}
+
+// ActualizarReporte modifica una definición de reporte existente
+func (h *AdminHandler) ActualizarReporte(c *gin.Context) {
+	reporteID, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	var req models.GuardarReporteRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Datos de reporte inválidos",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	reporte, err := h.reporteService.Actualizar(c.GetUint("user_id"), reporteID, &req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Reporte actualizado",
+		"reporte": reporte,
+	})
+}
+
+// EliminarReporte borra una definición de reporte
+func (h *AdminHandler) EliminarReporte(c *gin.Context) {
+	reporteID, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	if err := h.reporteService.Eliminar(c.GetUint("user_id"), reporteID); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Reporte eliminado",
+	})
+}
+
+// MarcarReporteFavorito agrega o quita un reporte de favoritos
+func (h *AdminHandler) MarcarReporteFavorito(c *gin.Context) {
+	reporteID, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	var req struct {
+		Favorito bool `json:"favorito"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Datos inválidos",
+		})
+		return
+	}
+
+	reporte, err := h.reporteService.MarcarFavorito(c.GetUint("user_id"), reporteID, req.Favorito)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"reporte": reporte,
+	})
+}
+
+// EjecutarReporte re-ejecuta un reporte guardado en su formato configurado
+// (se puede forzar con ?formato=json|csv)
+func (h *AdminHandler) EjecutarReporte(c *gin.Context) {
+	reporteID, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	reporte, resultado, err := h.reporteService.Ejecutar(c.GetUint("user_id"), reporteID)
+	if err != nil {
+		log.Printf("❌ Error ejecutando reporte %d: %v", reporteID, err)
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	if c.DefaultQuery("formato", reporte.Formato) == "csv" {
+		contenido, err := h.reporteService.ExportarCSV(resultado)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"message": err.Error(),
+			})
+			return
+		}
+		c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=\"reporte_%d.csv\"", reporte.ID))
+		c.Data(http.StatusOK, "text/csv; charset=utf-8", contenido)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":   true,
+		"reporte":   reporte,
+		"resultado": resultado,
+	})
+}
+
+// DescargarReporte entrega un link temporal al último resultado guardado de un reporte programado
+func (h *AdminHandler) DescargarReporte(c *gin.Context) {
+	reporteID, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	archivo, err := h.reporteService.DescargaUltimo(c.GetUint("user_id"), reporteID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"archivo": archivo,
+	})
+}
+
+// GetReporteHuellas muestra teléfonos distintos que jugaron desde el mismo
+// dispositivo o IP (?dias=30&min_clientes=2)
+func (h *AdminHandler) GetReporteHuellas(c *gin.Context) {
+	dias, err := strconv.Atoi(c.DefaultQuery("dias", "30"))
+	if err != nil || dias < 1 || dias > 365 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "El parámetro dias debe estar entre 1 y 365",
+		})
+		return
+	}
+	minClientes, err := strconv.Atoi(c.DefaultQuery("min_clientes", "2"))
+	if err != nil || minClientes < 2 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "El parámetro min_clientes debe ser al menos 2",
+		})
+		return
+	}
+
+	reporte, err := h.adminService.GetReporteHuellas(dias, minClientes)
+	if err != nil {
+		log.Printf("❌ Error generando reporte de huellas: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "Error generando reporte",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"reporte": reporte,
+	})
+}
+
+// GetRendimientoFlash compara los vouchers flash con los estándar (?dias=30 por defecto)
+func (h *AdminHandler) GetRendimientoFlash(c *gin.Context) {
+	dias, err := strconv.Atoi(c.DefaultQuery("dias", "30"))
+	if err != nil || dias < 1 || dias > 365 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "El parámetro dias debe estar entre 1 y 365",
+		})
+		return
+	}
+
+	rendimiento, err := h.adminService.GetRendimientoFlash(dias)
+	if err != nil {
+		log.Printf("❌ Error obteniendo rendimiento de vouchers flash: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "Error generando reporte",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":     true,
+		"dias":        dias,
+		"rendimiento": rendimiento,
+	})
+}
+
+// GetReporteCanjesEmpleados canjes e intentos rechazados por empleado, marcando a los que
+// se apartan del promedio de la cadena (?dias=30 por defecto)
+func (h *AdminHandler) GetReporteCanjesEmpleados(c *gin.Context) {
+	dias, err := strconv.Atoi(c.DefaultQuery("dias", "30"))
+	if err != nil || dias < 1 || dias > 365 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "El parámetro dias debe estar entre 1 y 365",
+		})
+		return
+	}
+
+	reporte, err := h.canjesEmpleado.Reporte(dias)
+	if err != nil {
+		log.Printf("❌ Error obteniendo reporte de canjes por empleado: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "Error generando reporte",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"dias":    dias,
+		"reporte": reporte,
+	})
+}
+
+// ActualizarLimiteCanjes fija el tope diario de canjes de un empleado (solo administradores)
+func (h *AdminHandler) ActualizarLimiteCanjes(c *gin.Context) {
+	usuarioID, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	var req models.ActualizarLimiteCanjesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Tope de canjes inválido",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	usuario, err := h.canjesEmpleado.ActualizarLimite(usuarioID, req.LimiteCanjes, c.GetUint("user_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":              true,
+		"message":              "Tope de canjes actualizado",
+		"limite_canjes_diario": h.canjesEmpleado.Limite(usuario),
+	})
+}
+
+// ListarCampanas lista una página de campañas con el resumen de sus envíos (?page=&page_size=&sort=)
+func (h *AdminHandler) ListarCampanas(c *gin.Context) {
+	paginacion, ok := parsePaginacion(c, 50, 200)
+	if !ok {
+		return
+	}
+
+	campanas, total, err := h.adminService.GetCampanas(paginacion)
+	if err != nil {
+		responderErrorListado(c, err, "Error obteniendo campañas")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":    true,
+		"campanas":   campanas,
+		"total":      total,
+		"paginacion": paginacion.Meta(total),
+	})
+}
+
+// GetCampana obtiene una campaña con el estado de cada envío
+func (h *AdminHandler) GetCampana(c *gin.Context) {
+	campanaID, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	campana, estadisticas, err := h.adminService.GetCampanaDetalle(campanaID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":      true,
+		"campana":      api.NuevaCampana(campana),
+		"estadisticas": estadisticas,
+	})
+}
+
+// CrearCampana crea una campaña promocional; los vouchers vencen al final de fecha_vencimiento
+func (h *AdminHandler) CrearCampana(c *gin.Context) {
+	var req models.CrearCampanaRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Datos de campaña inválidos",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	vencimiento, err := time.Parse("2006-01-02", req.FechaVencimiento)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "fecha_vencimiento inválida, usar el formato AAAA-MM-DD",
+		})
+		return
+	}
+
+	campana := &models.CampanaClientesVouchers{
+		Nombre:           req.Nombre,
+		Descripcion:      req.Descripcion,
+		Descuento:        req.Descuento,
+		FechaVencimiento: vencimiento.AddDate(0, 0, 1).Add(-time.Second),
+		Mensaje:          req.Mensaje,
+		CreatedBy:        c.GetUint("user_id"),
+	}
+	if err := h.adminService.CrearCampana(campana); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"success": true,
+		"message": "Campaña creada",
+		"campana": api.NuevaCampana(campana),
+	})
+}
+
+// EnviarCampana genera los vouchers de la campaña y encola los mensajes de WhatsApp.
+// Responde apenas quedan registrados los envíos; el despacho sigue en segundo plano
+func (h *AdminHandler) EnviarCampana(c *gin.Context) {
+	campanaID, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	var req models.EnviarCampanaRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Seleccioná al menos un cliente",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	resultado, err := h.adminService.EnviarCampana(campanaID, req.ClientesIDs)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"success":   true,
+		"message":   fmt.Sprintf("Campaña en envío a %d clientes", resultado.Destinatarios),
+		"resultado": resultado,
+	})
+}
+
+// EstimarCostoCampana previsualiza el costo de una campaña antes de enviarla
+func (h *AdminHandler) EstimarCostoCampana(c *gin.Context) {
+	var req models.EstimarCampanaRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Datos de campaña inválidos",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	estimacion := h.costoCampana.Estimar(req.Descuento, req.ClientesIDs)
+
+	respuesta := gin.H{
+		"success":    true,
+		"estimacion": estimacion,
+	}
+	if err := h.costoCampana.VerificarCuota(estimacion.ConversacionesMarketing); err != nil {
+		respuesta["excede_cuota"] = true
+		respuesta["motivo_cuota"] = err.Error()
+	}
+
+	c.JSON(http.StatusOK, respuesta)
+}
+
+// GetCostosCampana compara el costo estimado de una campaña con el real
+func (h *AdminHandler) GetCostosCampana(c *gin.Context) {
+	campanaID, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	estimacion, err := h.costoCampana.Conciliar(campanaID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":    true,
+		"estimacion": estimacion,
+		"desvio":     estimacion.CostoTotalReal - estimacion.CostoTotalEstimado,
+	})
+}
+
+// GetCuotaWhatsApp muestra el límite de envíos de WhatsApp y cuánto queda disponible
+func (h *AdminHandler) GetCuotaWhatsApp(c *gin.Context) {
+	cuota, err := h.adminService.GetCuotaWhatsApp()
+	if err != nil {
+		log.Printf("❌ Error obteniendo cuota de WhatsApp: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "Error obteniendo cuota de WhatsApp",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"cuota":   cuota,
+	})
+}
+
+// GetPlantillasWhatsApp muestra el estado de aprobación de los templates de WhatsApp
+func (h *AdminHandler) GetPlantillasWhatsApp(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"success":    true,
+		"plantillas": h.adminService.GetEstadoPlantillasWhatsApp(),
+	})
+}
+
+// GetEstadisticasMensajes resume envíos, entregas, fallos y latencia de WhatsApp por día y
+// por plantilla (?dias=30)
+func (h *AdminHandler) GetEstadisticasMensajes(c *gin.Context) {
+	dias, err := strconv.Atoi(c.DefaultQuery("dias", "30"))
+	if err != nil || dias < 1 || dias > 365 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "El parámetro dias debe estar entre 1 y 365",
+		})
+		return
+	}
+
+	estadisticas, err := h.adminService.GetEstadisticasMensajes(dias)
+	if err != nil {
+		log.Printf("❌ Error obteniendo estadísticas de mensajes: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "Error obteniendo estadísticas de mensajes",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":  true,
+		"dias":     dias,
+		"mensajes": estadisticas,
+	})
+}
+
+// GetCircuitoPremios muestra el estado del corte automático por tasa de victorias
+func (h *AdminHandler) GetCircuitoPremios(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"success":  true,
+		"circuito": h.adminService.GetCircuitoPremios(),
+	})
+}
+
+// RestablecerCircuitoPremios vuelve la tolerancia y la emisión de vouchers a la normalidad
+func (h *AdminHandler) RestablecerCircuitoPremios(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"success":  true,
+		"message":  "Circuito de premios restablecido",
+		"circuito": h.adminService.RestablecerCircuitoPremios(c.GetUint("user_id")),
+	})
+}
+
+// ListarNotificaciones obtiene el centro de notificaciones
+// (?no_leidas=true para ver solo las pendientes, ?limite=N)
+func (h *AdminHandler) ListarNotificaciones(c *gin.Context) {
+	limite, _ := strconv.Atoi(c.DefaultQuery("limite", "50"))
+
+	notificaciones, noLeidas, err := h.notificaciones.Listar(c.Query("no_leidas") == "true", limite)
+	if err != nil {
+		log.Printf("❌ Error listando notificaciones: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "Error obteniendo notificaciones",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":        true,
+		"notificaciones": notificaciones,
+		"no_leidas":      noLeidas,
+	})
+}
+
+// MarcarNotificacionLeida marca una notificación como revisada
+func (h *AdminHandler) MarcarNotificacionLeida(c *gin.Context) {
+	notificacionID, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	if err := h.notificaciones.MarcarLeida(notificacionID, c.GetUint("user_id")); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Notificación marcada como leída",
+	})
+}
+
+// ListarBloqueos lista los bloqueos vigentes (?historial=true incluye levantados y vencidos)
+func (h *AdminHandler) ListarBloqueos(c *gin.Context) {
+	bloqueos, err := h.bloqueos.Listar(c.Query("historial") == "true")
+	if err != nil {
+		log.Printf("❌ Error listando bloqueos: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "Error obteniendo lista de bloqueo",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":  true,
+		"bloqueos": bloqueos,
+	})
+}
+
+// CrearBloqueo agrega un teléfono, IP o huella a la lista de bloqueo
+func (h *AdminHandler) CrearBloqueo(c *gin.Context) {
+	var req models.CrearBloqueoRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Datos de bloqueo inválidos",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	bloqueo, err := h.bloqueos.Bloquear(req, c.GetUint("user_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"success": true,
+		"message": "Bloqueo creado",
+		"bloqueo": bloqueo,
+	})
+}
+
+// LevantarBloqueo desactiva un bloqueo manteniéndolo en el historial
+func (h *AdminHandler) LevantarBloqueo(c *gin.Context) {
+	bloqueoID, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	bloqueo, err := h.bloqueos.Levantar(bloqueoID, c.GetUint("user_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Bloqueo levantado",
+		"bloqueo": bloqueo,
+	})
+}
+
+// GetVoucher muestra un voucher con las fechas sin canje que caen dentro de su vigencia
+func (h *AdminHandler) GetVoucher(c *gin.Context) {
+	voucher, diasSinCanje, err := h.adminService.GetVoucherDetalle(c.Param("codigo"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"message": "Voucher no encontrado",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":        true,
+		"voucher":        api.NuevoVoucher(voucher),
+		"dias_sin_canje": diasSinCanje,
+	})
+}
+
+// ListarDiasSinCanje lista las próximas fechas en que no se aceptan vouchers
+func (h *AdminHandler) ListarDiasSinCanje(c *gin.Context) {
+	dias, err := h.diasSinCanje.Listar()
+	if err != nil {
+		log.Printf("❌ Error listando días sin canje: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "Error obteniendo días sin canje",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":        true,
+		"dias_sin_canje": dias,
+	})
+}
+
+// CrearDiaSinCanje bloquea el canje de vouchers en una fecha (feriado, evento especial)
+func (h *AdminHandler) CrearDiaSinCanje(c *gin.Context) {
+	var req models.CrearDiaSinCanjeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Datos del día sin canje inválidos",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	dia, err := h.diasSinCanje.Crear(req, c.GetUint("user_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"success":       true,
+		"message":       "Día sin canje creado",
+		"dia_sin_canje": dia,
+	})
+}
+
+// EliminarDiaSinCanje vuelve a habilitar el canje en una fecha
+func (h *AdminHandler) EliminarDiaSinCanje(c *gin.Context) {
+	diaID, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	if err := h.diasSinCanje.Eliminar(diaID, c.GetUint("user_id")); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Día sin canje eliminado",
+	})
+}
+
+// GetBranding obtiene el branding vigente
+func (h *AdminHandler) GetBranding(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"success":  true,
+		"branding": h.branding.Obtener(),
+	})
+}
+
+// ActualizarBranding modifica logo, colores y texto legal sin necesidad de redeploy
+func (h *AdminHandler) ActualizarBranding(c *gin.Context) {
+	var req models.ActualizarBrandingRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Datos de branding inválidos",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	branding, err := h.branding.Actualizar(req, c.GetUint("user_id"))
+	if err != nil {
+		log.Printf("❌ Error actualizando branding: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "Error actualizando branding",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":  true,
+		"message":  "Branding actualizado",
+		"branding": branding,
+	})
+}
+
+// GetCelebracion obtiene la celebración vigente de las victorias
+func (h *AdminHandler) GetCelebracion(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"success":     true,
+		"celebracion": h.celebracion.Obtener(),
+	})
+}
+
+// ActualizarCelebracion modifica confeti, sonido, titular y oferta de las victorias
+// sin necesidad de redeploy del frontend
+func (h *AdminHandler) ActualizarCelebracion(c *gin.Context) {
+	var req models.ActualizarCelebracionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Datos de celebración inválidos",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	celebracion, err := h.celebracion.Actualizar(req, c.GetUint("user_id"))
+	if err != nil {
+		log.Printf("❌ Error actualizando celebración: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "Error actualizando celebración",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":     true,
+		"message":     "Celebración actualizada",
+		"celebracion": celebracion,
+	})
+}
+
+// ArchivarVouchers corre el archivado de vouchers viejos sin esperar al programador
+func (h *AdminHandler) ArchivarVouchers(c *gin.Context) {
+	resultado, err := h.archivo.Archivar()
+	if err != nil {
+		// Sin resultado no llegó a correr (desactivado o ya en curso)
+		status := http.StatusConflict
+		if resultado != nil {
+			log.Printf("❌ Error archivando vouchers: %v", err)
+			status = http.StatusInternalServerError
+		}
+		c.JSON(status, gin.H{
+			"success":   false,
+			"message":   err.Error(),
+			"resultado": resultado,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":   true,
+		"message":   "Archivado completado",
+		"resultado": resultado,
+	})
+}
+
+// ListarTrabajos lista las operaciones en segundo plano recientes
+func (h *AdminHandler) ListarTrabajos(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"success":  true,
+		"trabajos": h.trabajos.Listar(),
+	})
+}
+
+// GetTrabajo consulta el avance de una operación en segundo plano
+func (h *AdminHandler) GetTrabajo(c *gin.Context) {
+	trabajo, err := h.trabajos.Obtener(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"trabajo": trabajo,
+	})
+}
+
+// CancelarTrabajo pide detener una operación en segundo plano. Termina el ítem en curso,
+// así que el estado pasa a cancelado unos instantes después
+func (h *AdminHandler) CancelarTrabajo(c *gin.Context) {
+	if err := h.trabajos.Cancelar(c.Param("id")); err != nil {
+		status := http.StatusConflict
+		if errors.Is(err, services.ErrTrabajoNoEncontrado) {
+			status = http.StatusNotFound
+		}
+		c.JSON(status, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	log.Printf("🛑 Usuario %d canceló el trabajo %s", c.GetUint("user_id"), c.Param("id"))
+	c.JSON(http.StatusAccepted, gin.H{
+		"success": true,
+		"message": "Cancelación pedida",
+	})
+}
+
+// ListarTareasProgramadas lista las tareas de mantenimiento con su programación, la
+// próxima ejecución y el trabajo de la última
+func (h *AdminHandler) ListarTareasProgramadas(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"tareas":  h.mantenimiento.Tareas(),
+	})
+}
+
+// EjecutarTareaProgramada corre una tarea de mantenimiento ahora, fuera de su horario
+func (h *AdminHandler) EjecutarTareaProgramada(c *gin.Context) {
+	trabajo, err := h.mantenimiento.Ejecutar(c.Param("nombre"))
+	if err != nil {
+		status := http.StatusConflict
+		if errors.Is(err, services.ErrTareaNoEncontrada) {
+			status = http.StatusNotFound
+		}
+		c.JSON(status, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	log.Printf("🧹 Usuario %d ejecutó la tarea %s (trabajo %s)", c.GetUint("user_id"), c.Param("nombre"), trabajo.ID)
+	c.JSON(http.StatusAccepted, gin.H{
+		"success": true,
+		"message": "Tarea iniciada",
+		"trabajo": trabajo,
+	})
+}
+
+// GetEstadisticasDiarias devuelve los resúmenes diarios guardados por el mantenimiento (?dias=30)
+func (h *AdminHandler) GetEstadisticasDiarias(c *gin.Context) {
+	dias, err := strconv.Atoi(c.DefaultQuery("dias", "30"))
+	if err != nil || dias < 1 || dias > 365 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "El parámetro dias debe estar entre 1 y 365",
+		})
+		return
+	}
+
+	estadisticas, err := h.mantenimiento.GetEstadisticasDiarias(dias)
+	if err != nil {
+		log.Printf("❌ Error obteniendo estadísticas diarias: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "Error obteniendo estadísticas diarias",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"dias":    estadisticas,
+	})
+}
+
+// BuscarVouchersArchivados consulta el archivo (?codigo=&cliente_id=&tipo=&fecha_desde=&fecha_hasta=&limit=)
+func (h *AdminHandler) BuscarVouchersArchivados(c *gin.Context) {
+	filtros := map[string]interface{}{}
+	if codigo := c.Query("codigo"); codigo != "" {
+		filtros["codigo"] = codigo
+	}
+	if clienteID, err := strconv.ParseUint(c.Query("cliente_id"), 10, 32); err == nil {
+		filtros["cliente_id"] = uint(clienteID)
+	}
+	if tipo := c.Query("tipo"); tipo != "" {
+		filtros["tipo"] = tipo
+	}
+	for _, campo := range []string{"fecha_desde", "fecha_hasta"} {
+		if valor := c.Query(campo); valor != "" {
+			fecha, err := time.Parse("2006-01-02", valor)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{
+					"success": false,
+					"message": campo + " inválida, usar el formato AAAA-MM-DD",
+				})
+				return
+			}
+			if campo == "fecha_hasta" {
+				fecha = fecha.AddDate(0, 0, 1).Add(-time.Second)
+			}
+			filtros[campo] = fecha
+		}
+	}
+	limite, _ := strconv.Atoi(c.DefaultQuery("limit", "100"))
+	if limite <= 0 || limite > 500 {
+		limite = 100
+	}
+	filtros["limit"] = limite
+
+	vouchers, err := h.archivo.BuscarVouchers(filtros)
+	if err != nil {
+		log.Printf("❌ Error consultando archivo: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "Error consultando vouchers archivados",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":  true,
+		"vouchers": api.NuevosVouchers(vouchers),
+		"total":    len(vouchers),
+	})
+}
+
+// parseIDParam lee un parámetro numérico de la ruta respondiendo 400 si es inválido
+func parseIDParam(c *gin.Context, nombre string) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param(nombre), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "ID inválido",
+		})
+		return 0, false
+	}
+	return uint(id), true
+}
+
+// parsePaginacion lee page, page_size (o el viejo limit) y sort de la consulta,
+// respondiendo 400 si la página es inválida. page_size se acota a maximo
+func parsePaginacion(c *gin.Context, porDefecto, maximo int) (models.Paginacion, bool) {
+	paginacion := models.Paginacion{Pagina: 1, TamanoPagina: porDefecto, Orden: c.Query("sort")}
+
+	if valor := c.Query("page"); valor != "" {
+		pagina, err := strconv.Atoi(valor)
+		if err != nil || pagina < 1 {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"message": "page debe ser un número mayor o igual a 1",
+			})
+			return paginacion, false
+		}
+		paginacion.Pagina = pagina
+	}
+
+	valor := c.Query("page_size")
+	if valor == "" {
+		valor = c.Query("limit")
+	}
+	if tamano, err := strconv.Atoi(valor); err == nil && tamano > 0 {
+		paginacion.TamanoPagina = min(tamano, maximo)
+	}
+	return paginacion, true
+}
+
+// responderErrorListado responde 400 si el orden pedido no está permitido y 500 si falló la consulta
+func responderErrorListado(c *gin.Context, err error, mensaje string) {
+	if errors.Is(err, repository.ErrOrdenInvalido) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	log.Printf("❌ %s: %v", mensaje, err)
+	c.JSON(http.StatusInternalServerError, gin.H{
+		"success": false,
+		"message": mensaje,
+	})
+}
+
+// GetCalendario obtiene la actividad programada del mes (?month=AAAA-MM, por defecto el actual)
+func (h *AdminHandler) GetCalendario(c *gin.Context) {
+	mes := c.Query("month")
+	if mes != "" {
+		if _, err := time.Parse("2006-01", mes); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"message": "Mes inválido, usar el formato AAAA-MM",
+			})
+			return
+		}
+	}
+
+	calendario, err := h.calendario.GetMes(mes)
+	if err != nil {
+		log.Printf("❌ Error obteniendo calendario: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "Error obteniendo calendario",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":    true,
+		"calendario": calendario,
+	})
+}
+
+// GetMetas obtiene las metas del mes y el avance logrado (?mes=AAAA-MM, por defecto el actual)
+func (h *AdminHandler) GetMetas(c *gin.Context) {
+	mes := c.Query("mes")
+	if mes != "" {
+		if _, err := time.Parse("2006-01", mes); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"message": "Mes inválido, usar el formato AAAA-MM",
+			})
+			return
+		}
+	}
+
+	progreso, err := h.metas.Progreso(mes)
+	if err != nil {
+		log.Printf("❌ Error obteniendo metas: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "Error obteniendo metas",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":  true,
+		"progreso": progreso,
+	})
+}
+
+// GuardarMetas fija las metas de un mes (AAAA-MM); 0 deja el indicador sin meta
+func (h *AdminHandler) GuardarMetas(c *gin.Context) {
+	var req models.GuardarMetaMensualRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Metas inválidas",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	meta, err := h.metas.Guardar(c.Param("mes"), req, c.GetUint("user_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Metas guardadas",
+		"meta":    meta,
+	})
+}
+
+// GetRanking devuelve el ranking en curso con todos los clientes y los cierres anteriores
+func (h *AdminHandler) GetRanking(c *gin.Context) {
+	ranking, err := h.ranking.GetRanking(false)
+	if err != nil {
+		log.Printf("❌ Error obteniendo ranking: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "Error obteniendo ranking",
+		})
+		return
+	}
+	cierres, err := h.ranking.GetCierres()
+	if err != nil {
+		log.Printf("❌ Error obteniendo cierres del ranking: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "Error obteniendo ranking",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"ranking": ranking,
+		"cierres": cierres,
+	})
+}
+
+// CerrarRanking cierra la semana del ranking ahora y opcionalmente premia al ganador
+func (h *AdminHandler) CerrarRanking(c *gin.Context) {
+	var req models.CerrarRankingRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"message": "Datos inválidos",
+				"error":   err.Error(),
+			})
+			return
+		}
+	}
+
+	cierre, err := h.ranking.Cerrar(req, c.GetUint("user_id"))
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrDescuentoPremioRanking):
+			c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": err.Error()})
+		case errors.Is(err, services.ErrRankingYaCerrado):
+			c.JSON(http.StatusConflict, gin.H{"success": false, "message": err.Error()})
+		default:
+			log.Printf("❌ Error cerrando ranking: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"success": false,
+				"message": "Error cerrando ranking",
+			})
+		}
+		return
+	}
+
+	log.Printf("🏆 Usuario %d cerró el ranking (cierre #%d)", c.GetUint("user_id"), cierre.ID)
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Ranking cerrado",
+		"cierre":  cierre,
+	})
+}
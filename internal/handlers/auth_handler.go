@@ -0,0 +1,266 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"CheeseHouse/internal/api"
+	"CheeseHouse/internal/config"
+	"CheeseHouse/internal/models"
+	"CheeseHouse/internal/services"
+)
+
+// cookieAuth cookie de sesión del panel; AuthMiddleware la lee si no hay header Authorization
+const cookieAuth = "auth_token"
+
+// AuthHandler maneja el login de los empleados al panel administrativo
+type AuthHandler struct {
+	config      *config.Config
+	authService *services.AuthService
+}
+
+// NewAuthHandler crea una nueva instancia del handler de autenticación
+func NewAuthHandler(cfg *config.Config, authService *services.AuthService) *AuthHandler {
+	return &AuthHandler{
+		config:      cfg,
+		authService: authService,
+	}
+}
+
+// Login valida email y contraseña. Retorna el token en el body y además lo guarda en una
+// cookie HttpOnly, para usar el panel desde el navegador o la API con el header
+func (h *AuthHandler) Login(c *gin.Context) {
+	var req models.LoginRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Email y contraseña requeridos",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	respuesta, err := h.authService.Login(strings.ToLower(strings.TrimSpace(req.Email)), req.Password)
+	if err != nil {
+		log.Printf("❌ Error en login: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "Error interno del servidor",
+		})
+		return
+	}
+	if !respuesta.Success {
+		c.JSON(http.StatusUnauthorized, respuesta)
+		return
+	}
+
+	h.guardarCookie(c, respuesta.Token)
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": respuesta.Message,
+		"token":   respuesta.Token,
+		"usuario": api.NuevoUsuario(respuesta.Usuario),
+	})
+}
+
+// Refresh cambia un token vigente por uno nuevo; el anterior queda revocado
+func (h *AuthHandler) Refresh(c *gin.Context) {
+	token := tokenDeRequest(c)
+	if token == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"success": false,
+			"message": "Token de autenticación requerido",
+		})
+		return
+	}
+
+	nuevo, err := h.authService.RefreshToken(token)
+	if err != nil {
+		log.Printf("🔒 Refresh rechazado: %v - IP: %s", err, c.ClientIP())
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"success": false,
+			"message": "Token inválido o expirado",
+		})
+		return
+	}
+
+	h.guardarCookie(c, nuevo)
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"token":   nuevo,
+	})
+}
+
+// Logout revoca el token y borra la cookie. Responde OK aunque el token ya no sea válido
+func (h *AuthHandler) Logout(c *gin.Context) {
+	if token := tokenDeRequest(c); token != "" {
+		h.authService.RevocarToken(token)
+	}
+
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(cookieAuth, "", -1, "/", "", h.config.IsProduction(), true)
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Sesión cerrada",
+	})
+}
+
+// Me retorna el usuario autenticado con los permisos de su rol
+func (h *AuthHandler) Me(c *gin.Context) {
+	usuario, _ := c.Value("usuario").(*models.Usuario)
+	permisos, err := h.authService.PermisosDeRol(c.GetUint("rol_id"))
+	if err != nil {
+		log.Printf("⚠️  Error obteniendo permisos del usuario: %v", err)
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success":  true,
+		"usuario":  api.NuevoUsuario(usuario),
+		"permisos": permisos,
+	})
+}
+
+// ListarRoles lista los roles con sus permisos y el catálogo de permisos disponibles
+func (h *AuthHandler) ListarRoles(c *gin.Context) {
+	roles, err := h.authService.ListarRoles()
+	if err != nil {
+		log.Printf("❌ Error listando roles: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "Error obteniendo roles",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":     true,
+		"roles":       roles,
+		"disponibles": models.CatalogoPermisos,
+	})
+}
+
+// CrearRol crea un rol con los permisos indicados
+func (h *AuthHandler) CrearRol(c *gin.Context) {
+	var req models.RolRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Datos inválidos",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	rol, err := h.authService.CrearRol(req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"success": true,
+		"rol":     rol,
+	})
+}
+
+// ActualizarRol cambia el nombre y los permisos de un rol
+func (h *AuthHandler) ActualizarRol(c *gin.Context) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	var req models.RolRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Datos inválidos",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	rol, err := h.authService.ActualizarRol(id, req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"rol":     rol,
+	})
+}
+
+// EliminarRol elimina un rol sin usuarios que no sea de los base
+func (h *AuthHandler) EliminarRol(c *gin.Context) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	if err := h.authService.EliminarRol(id); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Rol eliminado",
+	})
+}
+
+// EstablecerPIN fija el PIN del administrador autenticado para aprobar canjes de descuento alto
+func (h *AuthHandler) EstablecerPIN(c *gin.Context) {
+	var req models.EstablecerPINRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "El PIN debe tener entre 4 y 8 dígitos",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	if err := h.authService.EstablecerPIN(c.GetUint("user_id"), req.Password, req.PIN); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "PIN de aprobación actualizado",
+	})
+}
+
+// guardarCookie deja el token en la cookie de sesión con la misma duración que el token
+func (h *AuthHandler) guardarCookie(c *gin.Context, token string) {
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(cookieAuth, token, int(h.authService.Expiracion().Seconds()), "/", "", h.config.IsProduction(), true)
+}
+
+// tokenDeRequest lee el token del header "Authorization: Bearer" o de la cookie de sesión
+func tokenDeRequest(c *gin.Context) string {
+	if header := c.GetHeader("Authorization"); header != "" {
+		if token := strings.TrimPrefix(header, "Bearer "); token != header {
+			return token
+		}
+		return ""
+	}
+	token, _ := c.Cookie(cookieAuth)
+	return token
+}
@@ -0,0 +1,116 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"CheeseHouse/internal/models"
+	"CheeseHouse/internal/services"
+)
+
+// ConfiguracionHandler maneja los parámetros del juego editables en caliente y el
+// historial de cambios de configuración
+type ConfiguracionHandler struct {
+	configuracion *services.ConfiguracionService
+}
+
+// NewConfiguracionHandler crea una nueva instancia del handler de configuración
+func NewConfiguracionHandler(configuracion *services.ConfiguracionService) *ConfiguracionHandler {
+	return &ConfiguracionHandler{
+		configuracion: configuracion,
+	}
+}
+
+// GetParametrosJuego obtiene los parámetros vigentes del juego y los de la configuración
+func (h *ConfiguracionHandler) GetParametrosJuego(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"success":     true,
+		"parametros":  h.configuracion.ParametrosJuego(),
+		"por_defecto": h.configuracion.ParametrosPorDefecto(),
+	})
+}
+
+// ActualizarParametrosJuego modifica tiempos, descuentos o tolerancia sin redeploy
+func (h *ConfiguracionHandler) ActualizarParametrosJuego(c *gin.Context) {
+	var req models.ActualizarParametrosJuegoRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Parámetros del juego inválidos",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	parametros, cambios, err := h.configuracion.ActualizarParametrosJuego(req, c.GetUint("user_id"))
+	if err != nil {
+		status := http.StatusBadRequest
+		if errors.Is(err, services.ErrCambioBrusco) {
+			status = http.StatusConflict
+		}
+		c.JSON(status, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":    true,
+		"message":    "Parámetros del juego actualizados",
+		"parametros": parametros,
+		"cambios":    cambios,
+	})
+}
+
+// ListarCambiosConfiguracion lista el historial de cambios (filtros: ambito, clave, limit)
+func (h *ConfiguracionHandler) ListarCambiosConfiguracion(c *gin.Context) {
+	limite, _ := strconv.Atoi(c.DefaultQuery("limit", "100"))
+
+	cambios, err := h.configuracion.Historial(c.Query("ambito"), c.Query("clave"), limite)
+	if err != nil {
+		log.Printf("❌ Error listando cambios de configuración: %v", err)
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"cambios": cambios,
+		"total":   len(cambios),
+	})
+}
+
+// RevertirCambioConfiguracion vuelve un valor de configuración al que tenía antes de un cambio
+func (h *ConfiguracionHandler) RevertirCambioConfiguracion(c *gin.Context) {
+	cambioID, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	reversion, err := h.configuracion.Revertir(cambioID, c.GetUint("user_id"))
+	if err != nil {
+		status := http.StatusBadRequest
+		if errors.Is(err, services.ErrCambioSuperado) {
+			status = http.StatusConflict
+		}
+		c.JSON(status, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Cambio revertido",
+		"cambio":  reversion,
+	})
+}
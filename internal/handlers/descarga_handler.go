@@ -0,0 +1,60 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"mime"
+	"net/http"
+	"path"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"CheeseHouse/internal/almacenamiento"
+	"CheeseHouse/internal/services"
+)
+
+// DescargaHandler sirve los archivos generados del almacenamiento local a quien tenga
+// un link firmado vigente (no requiere sesión)
+type DescargaHandler struct {
+	artefactos *services.ArtefactoService
+}
+
+// NewDescargaHandler crea una nueva instancia del handler de descargas
+func NewDescargaHandler(artefactos *services.ArtefactoService) *DescargaHandler {
+	return &DescargaHandler{
+		artefactos: artefactos,
+	}
+}
+
+// Descargar entrega el archivo si la firma y el vencimiento del link son válidos
+func (h *DescargaHandler) Descargar(c *gin.Context) {
+	clave := strings.TrimPrefix(c.Param("clave"), "/")
+
+	contenido, err := h.artefactos.Leer(clave, c.Query("expira"), c.Query("firma"))
+	if err != nil {
+		status := http.StatusInternalServerError
+		mensaje := "Error leyendo el archivo"
+		switch {
+		case errors.Is(err, almacenamiento.ErrLinkInvalido):
+			status, mensaje = http.StatusForbidden, err.Error()
+		case errors.Is(err, almacenamiento.ErrNoEncontrado):
+			status, mensaje = http.StatusNotFound, err.Error()
+		default:
+			log.Printf("❌ Error sirviendo descarga %s: %v", clave, err)
+		}
+		c.JSON(status, gin.H{
+			"success": false,
+			"message": mensaje,
+		})
+		return
+	}
+
+	tipo := mime.TypeByExtension(path.Ext(clave))
+	if tipo == "" {
+		tipo = "application/octet-stream"
+	}
+	c.Header("Content-Disposition", "attachment; filename=\""+path.Base(clave)+"\"")
+	c.Header("Cache-Control", "private, no-store")
+	c.Data(http.StatusOK, tipo, contenido)
+}
@@ -0,0 +1,106 @@
+package handlers
+
+import (
+	"bytes"
+	"embed"
+	"html/template"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"CheeseHouse/internal/services"
+	"CheeseHouse/internal/version"
+)
+
+//go:embed plantillas/error.html
+var plantillasError embed.FS
+
+// ErrorHandler responde las rutas inexistentes y los errores inesperados: página con la
+// marca del local para el navegador, JSON para la API y el resto de los clientes
+type ErrorHandler struct {
+	branding    *services.BrandingService
+	restaurante string
+	plantilla   *template.Template
+}
+
+// NewErrorHandler crea una nueva instancia del handler de páginas de error
+func NewErrorHandler(restaurante string, branding *services.BrandingService) *ErrorHandler {
+	return &ErrorHandler{
+		branding:    branding,
+		restaurante: restaurante,
+		plantilla:   template.Must(template.ParseFS(plantillasError, "plantillas/error.html")),
+	}
+}
+
+// NoEncontrado sirve los archivos del frontend que existen y responde 404 al resto
+func (h *ErrorHandler) NoEncontrado(archivos http.Handler, existe func(ruta string) bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ruta := c.Request.URL.Path
+		lectura := c.Request.Method == http.MethodGet || c.Request.Method == http.MethodHead
+		if lectura && !strings.HasPrefix(ruta, "/api/") && existe(ruta) {
+			archivos.ServeHTTP(c.Writer, c.Request)
+			return
+		}
+		h.responder(c, http.StatusNotFound, "Página no encontrada",
+			"La dirección que buscás no existe o cambió de lugar.")
+	}
+}
+
+// Recuperar responde 500 tras un panic; gin ya logueó el panic con su stack y acá se
+// agrega la versión del binario para el reporte
+func (h *ErrorHandler) Recuperar(c *gin.Context, recuperado interface{}) {
+	log.Printf("❌ Panic en %s %s con la versión %s: %v", c.Request.Method, c.Request.URL.Path, version.Obtener(), recuperado)
+	if c.Writer.Written() {
+		c.Abort()
+		return
+	}
+	h.responder(c, http.StatusInternalServerError, "Algo salió mal",
+		"Tuvimos un problema procesando tu pedido. Probá de nuevo en unos minutos.")
+}
+
+// responder arma la página de error si el cliente es un navegador (pide text/html y no es
+// la API) y el JSON de siempre en cualquier otro caso
+func (h *ErrorHandler) responder(c *gin.Context, status int, titulo, mensaje string) {
+	if strings.HasPrefix(c.Request.URL.Path, "/api/") || !strings.Contains(c.GetHeader("Accept"), "text/html") {
+		respuesta := gin.H{
+			"success": false,
+			"message": titulo,
+		}
+		if status >= http.StatusInternalServerError {
+			respuesta["version"] = version.Obtener().Version
+		}
+		c.AbortWithStatusJSON(status, respuesta)
+		return
+	}
+
+	branding := h.branding.Obtener()
+	versionPagina := ""
+	if status >= http.StatusInternalServerError {
+		versionPagina = version.Obtener().Version
+	}
+	var pagina bytes.Buffer
+	err := h.plantilla.Execute(&pagina, gin.H{
+		"Estado":          status,
+		"Titulo":          titulo,
+		"Mensaje":         mensaje,
+		"Restaurante":     h.restaurante,
+		"LogoURL":         branding.LogoURL,
+		"ColorPrimario":   branding.ColorPrimario,
+		"ColorSecundario": branding.ColorSecundario,
+		"Version":         versionPagina,
+	})
+	if err != nil {
+		log.Printf("❌ Error armando página de error %d: %v", status, err)
+		c.AbortWithStatusJSON(status, gin.H{
+			"success": false,
+			"message": titulo,
+		})
+		return
+	}
+
+	c.Header("Cache-Control", "no-store")
+	c.Data(status, "text/html; charset=utf-8", pagina.Bytes())
+	c.Abort()
+}
@@ -1,25 +1,40 @@
 package handlers
 
 import (
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
 
+	"CheeseHouse/internal/api"
+	"CheeseHouse/internal/barcode"
+	"CheeseHouse/internal/i18n"
 	"CheeseHouse/internal/models"
 	"CheeseHouse/internal/services"
+	"CheeseHouse/internal/tarjeta"
 )
 
 // GameHandler maneja todas las rutas relacionadas con el juego
 type GameHandler struct {
-	gameService *services.GameService
+	gameService     *services.GameService
+	brandingService *services.BrandingService
+	tarjetaService  *services.TarjetaService
+	voucherQR       *services.VoucherQRService
+	ranking         *services.RankingService
 }
 
 // NewGameHandler crea una nueva instancia del handler del juego
-func NewGameHandler(gameService *services.GameService) *GameHandler {
+func NewGameHandler(gameService *services.GameService, brandingService *services.BrandingService, tarjetaService *services.TarjetaService, voucherQR *services.VoucherQRService, ranking *services.RankingService) *GameHandler {
 	return &GameHandler{
-		gameService: gameService,
+		gameService:     gameService,
+		brandingService: brandingService,
+		tarjetaService:  tarjetaService,
+		voucherQR:       voucherQR,
+		ranking:         ranking,
 	}
 }
 
@@ -28,15 +43,22 @@ func (h *GameHandler) ShowGame(c *gin.Context) {
 	// Obtener configuración del juego para el template
 	gameConfig := h.gameService.GetConfiguracionJuego()
 
+	// Idioma: ?lang= explícito, luego Accept-Language, luego el configurado
+	locale := i18n.Detectar(c.Query("lang"), c.GetHeader("Accept-Language"), gameConfig.IdiomaPorDefecto)
+	textos := i18n.Textos(locale)
+
 	// Datos para el template
 	data := gin.H{
-		"titulo":         "CheeseHouse - Juego de Timing",
-		"restaurante":    gameConfig["restaurante"],
-		"tolerancia":     gameConfig["tolerancia"],
-		"tiempo_min":     gameConfig["tiempo_min"],
-		"tiempo_max":     gameConfig["tiempo_max"],
-		"descuento_win":  gameConfig["descuento_ganador"],
-		"descuento_lose": gameConfig["descuento_perdedor"],
+		"titulo":         fmt.Sprintf("%s - %s", gameConfig.Restaurante, textos["titulo"]),
+		"locale":         locale,
+		"textos":         textos,
+		"restaurante":    gameConfig.Restaurante,
+		"tolerancia":     gameConfig.Tolerancia,
+		"tiempo_min":     gameConfig.TiempoMin,
+		"tiempo_max":     gameConfig.TiempoMax,
+		"descuento_win":  gameConfig.DescuentoGanador,
+		"descuento_lose": gameConfig.DescuentoPerdedor,
+		"branding":       h.brandingService.Obtener(),
 	}
 
 	c.HTML(http.StatusOK, "game.html", data)
@@ -57,6 +79,11 @@ func (h *GameHandler) SubmitGameResult(c *gin.Context) {
 		return
 	}
 
+	gameResult.IP = c.ClientIP()
+	if widgetID := c.GetUint("widget_id"); widgetID != 0 {
+		gameResult.WidgetID = &widgetID
+	}
+
 	// Log del intento de juego
 	log.Printf("🎮 Juego recibido: %s %s (%s) - Objetivo: %.1fs, Obtenido: %.2fs",
 		gameResult.ClienteData.Nombre,
@@ -116,9 +143,12 @@ func (h *GameHandler) GetGameStats(c *gin.Context) {
 	})
 }
 
-// GetClientByPhone obtiene información básica de un cliente por teléfono
+// GetClientByPhone obtiene información básica de un cliente por teléfono.
+// Requiere el apellido o un código de verificación para evitar la enumeración de clientes
 func (h *GameHandler) GetClientByPhone(c *gin.Context) {
 	telefono := c.Param("phone")
+	apellido := c.Query("apellido")
+	codigo := c.Query("codigo")
 
 	if telefono == "" {
 		c.JSON(http.StatusBadRequest, gin.H{
@@ -128,12 +158,20 @@ func (h *GameHandler) GetClientByPhone(c *gin.Context) {
 		return
 	}
 
-	cliente, err := h.gameService.GetClientePorTelefono(telefono)
+	if apellido == "" && codigo == "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Se requiere el apellido o un código de verificación",
+		})
+		return
+	}
+
+	cliente, err := h.gameService.GetClienteVerificado(telefono, apellido, codigo)
 	if err != nil {
-		// Cliente no encontrado no es error crítico
+		// Misma respuesta para cliente inexistente y datos incorrectos
 		c.JSON(http.StatusNotFound, gin.H{
 			"success": false,
-			"message": "Cliente no encontrado",
+			"message": "Cliente no encontrado o los datos no coinciden",
 		})
 		return
 	}
@@ -154,13 +192,297 @@ func (h *GameHandler) GetClientByPhone(c *gin.Context) {
 	})
 }
 
+// SolicitarCodigoCliente envía por WhatsApp un código para consultar los datos del cliente
+func (h *GameHandler) SolicitarCodigoCliente(c *gin.Context) {
+	telefono := c.Param("phone")
+
+	if telefono == "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Teléfono requerido",
+		})
+		return
+	}
+
+	h.gameService.SolicitarCodigoCliente(telefono)
+
+	// Respuesta genérica: no revela si el teléfono está registrado
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Si el teléfono está registrado, te enviamos un código por WhatsApp",
+	})
+}
+
+// GetPublicWidgetStats expone estadísticas agregadas para widgets embebibles
+func (h *GameHandler) GetPublicWidgetStats(c *gin.Context) {
+	stats, err := h.gameService.GetEstadisticasPublicas()
+	if err != nil {
+		log.Printf("❌ Error obteniendo estadísticas públicas: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "Error obteniendo estadísticas",
+		})
+		return
+	}
+
+	c.Header("Cache-Control", "public, max-age=60")
+	c.JSON(http.StatusOK, gin.H{
+		"success":      true,
+		"estadisticas": stats,
+	})
+}
+
+// GetWinners lista los últimos ganadores que aceptaron aparecer en el muro (landing y
+// pantalla del local). Solo nombre e inicial del apellido, nunca teléfono ni hora exacta
+func (h *GameHandler) GetWinners(c *gin.Context) {
+	limite, _ := strconv.Atoi(c.DefaultQuery("limite", "10"))
+	locale := i18n.Detectar(c.Query("lang"), c.GetHeader("Accept-Language"), h.gameService.GetConfiguracionJuego().IdiomaPorDefecto)
+
+	ganadores, err := h.gameService.GetMuroGanadores(limite, locale)
+	if err != nil {
+		log.Printf("❌ Error obteniendo muro de ganadores: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "Error obteniendo ganadores",
+		})
+		return
+	}
+
+	c.Header("Cache-Control", "public, max-age=60")
+	c.Header("Vary", "Accept-Language")
+	c.JSON(http.StatusOK, gin.H{
+		"success":   true,
+		"locale":    locale,
+		"ganadores": ganadores,
+	})
+}
+
+// GetLeaderboard devuelve el ranking de la semana: la mejor partida de cada cliente que
+// aceptó aparecer en el muro, de la más cercana al objetivo a la más lejana
+func (h *GameHandler) GetLeaderboard(c *gin.Context) {
+	ranking, err := h.ranking.GetRanking(true)
+	if err != nil {
+		log.Printf("❌ Error obteniendo ranking: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "Error obteniendo ranking",
+		})
+		return
+	}
+
+	c.Header("Cache-Control", "public, max-age=60")
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"ranking": ranking,
+	})
+}
+
+// GetShareCard genera la imagen para compartir el resultado de una partida. El link
+// lo firma el servidor al procesar la partida; formato=historia devuelve 1080x1920
+func (h *GameHandler) GetShareCard(c *gin.Context) {
+	formato := tarjeta.FormatoOpenGraph
+	switch c.DefaultQuery("formato", "og") {
+	case "og":
+	case "historia":
+		formato = tarjeta.FormatoHistoria
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Formato no soportado, usar formato=og o formato=historia",
+		})
+		return
+	}
+
+	imagen, err := h.tarjetaService.Generar(c.Param("token"), formato)
+	if err != nil {
+		if errors.Is(err, services.ErrTarjetaInvalida) {
+			c.JSON(http.StatusNotFound, gin.H{
+				"success": false,
+				"message": "Tarjeta no encontrada",
+			})
+			return
+		}
+		log.Printf("❌ Error generando tarjeta para compartir: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "Error generando imagen",
+		})
+		return
+	}
+
+	// El contenido de un link nunca cambia: las redes y los CDN pueden guardarlo
+	c.Header("Cache-Control", "public, max-age=604800, immutable")
+	c.Data(http.StatusOK, "image/png", imagen)
+}
+
+// GetVoucherMedia genera la imagen del código del voucher para escanear en caja: Code 128
+// (formato=code128), que leen los lectores 1D del POS, QR firmado (formato=qr) para el
+// voucher en pantalla, que la app de caja canjea con /api/admin/vouchers/scan, o el PDF
+// imprimible con ambos (formato=pdf). Solo responde a links firmados con URLMedia: sin
+// la firma la ruta no confirma si un código existe
+func (h *GameHandler) GetVoucherMedia(c *gin.Context) {
+	formato := c.DefaultQuery("formato", "code128")
+	if formato != "code128" && formato != "qr" && formato != "pdf" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Formato no soportado, usar formato=code128, formato=qr o formato=pdf",
+		})
+		return
+	}
+
+	noEncontrado := func() {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"message": "Voucher no encontrado",
+		})
+	}
+	if !h.voucherQR.ValidarMedia(c.Param("codigo"), c.Query("vence"), c.Query("firma"), time.Now()) {
+		noEncontrado()
+		return
+	}
+	voucher, err := h.gameService.GetVoucherPorCodigo(c.Param("codigo"))
+	if err != nil {
+		noEncontrado()
+		return
+	}
+
+	var contenido []byte
+	tipo := "image/png"
+	switch formato {
+	case "qr":
+		contenido, err = barcode.QRPNG(h.voucherQR.Contenido(voucher), 8)
+	case "pdf":
+		tipo = "application/pdf"
+		contenido, err = h.voucherQR.PDF(voucher, h.brandingService.Obtener().TextoLegal)
+		c.Header("Content-Disposition", fmt.Sprintf("inline; filename=\"voucher-%s.pdf\"", voucher.Codigo))
+	default:
+		contenido, err = barcode.Code128PNG(voucher.Codigo, 2, 80)
+	}
+	if err != nil {
+		log.Printf("❌ Error generando %s del voucher %s: %v", formato, voucher.Codigo, err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "Error generando imagen del voucher",
+		})
+		return
+	}
+
+	c.Header("Cache-Control", "private, max-age=86400")
+	c.Data(http.StatusOK, tipo, contenido)
+}
+
+// RevelarVoucherPantalla muestra el voucher de la partida a quien no usa WhatsApp, tras
+// confirmar los últimos dígitos del teléfono
+func (h *GameHandler) RevelarVoucherPantalla(c *gin.Context) {
+	var req models.RevelarVoucherRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Ingresá los últimos 4 dígitos de tu teléfono",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	voucher, err := h.gameService.RevelarVoucherPantalla(req.Token, req.Digitos)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"voucher": voucher,
+	})
+}
+
 // GenerateTargetTime genera un nuevo tiempo objetivo (para el frontend)
 func (h *GameHandler) GenerateTargetTime(c *gin.Context) {
-	targetTime := h.gameService.GenerarTiempoObjetivo()
+	targetTime, token, err := h.gameService.GenerarObjetivoFirmado()
+	if err != nil {
+		log.Printf("❌ Error generando tiempo objetivo: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "Error generando tiempo objetivo",
+		})
+		return
+	}
+
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusOK, gin.H{
+		"success":      true,
+		"target_time":  targetTime,
+		"target_token": token,
+	})
+}
+
+// StartGame abre la sesión de juego cuando el jugador arranca el cronómetro. El token de
+// sesión lleva el objetivo y el inicio registrado por el servidor
+func (h *GameHandler) StartGame(c *gin.Context) {
+	var req models.IniciarPartidaRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Datos inválidos",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	token, objetivo, inicio, err := h.gameService.IniciarPartida(req.TokenObjetivo)
+	if err != nil {
+		if errors.Is(err, services.ErrTokenObjetivoInvalido) {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"message": err.Error(),
+			})
+			return
+		}
+		log.Printf("❌ Error iniciando partida: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "Error iniciando partida",
+		})
+		return
+	}
+
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusOK, gin.H{
+		"success":       true,
+		"session_token": token,
+		"target_time":   objetivo,
+		"started_at":    inicio.UnixMilli(),
+	})
+}
+
+// StopGame registra el momento en que el jugador detuvo el cronómetro
+func (h *GameHandler) StopGame(c *gin.Context) {
+	var req models.DetenerPartidaRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Datos inválidos",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	medido, err := h.gameService.DetenerPartida(req.TokenSesion)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
 
 	c.JSON(http.StatusOK, gin.H{
 		"success":     true,
-		"target_time": targetTime,
+		"server_time": medido,
 	})
 }
 
@@ -169,8 +491,34 @@ func (h *GameHandler) GetGameConfig(c *gin.Context) {
 	config := h.gameService.GetConfiguracionJuego()
 
 	c.JSON(http.StatusOK, gin.H{
-		"success": true,
-		"config":  config,
+		"success":  true,
+		"config":   config,
+		"branding": h.brandingService.Obtener(),
+	})
+}
+
+// GetTextos retorna los textos localizados de la página del juego para el frontend JS.
+// Si el locale pedido no está soportado se usa el detectado por Accept-Language
+func (h *GameHandler) GetTextos(c *gin.Context) {
+	gameConfig := h.gameService.GetConfiguracionJuego()
+	locale := i18n.Detectar(c.Param("locale"), c.GetHeader("Accept-Language"), gameConfig.IdiomaPorDefecto)
+
+	c.Header("Cache-Control", "public, max-age=3600")
+	c.Header("Vary", "Accept-Language")
+	c.JSON(http.StatusOK, gin.H{
+		"success":     true,
+		"locale":      locale,
+		"disponibles": i18n.Locales(),
+		"textos":      i18n.Textos(locale),
+	})
+}
+
+// GetBranding expone el logo, colores y texto legal vigentes para el frontend
+func (h *GameHandler) GetBranding(c *gin.Context) {
+	c.Header("Cache-Control", "public, max-age=300")
+	c.JSON(http.StatusOK, gin.H{
+		"success":  true,
+		"branding": h.brandingService.Obtener(),
 	})
 }
 
@@ -205,7 +553,33 @@ func (h *GameHandler) TestGame(c *gin.Context) {
 		return
 	}
 
-	// Simular un juego de prueba
+	// Simular un juego de prueba con una sesión real: se detiene enseguida, así que el
+	// tiempo obtenido es el que midió el servidor (la partida de prueba pierde)
+	_, tokenObjetivo, err := h.gameService.GenerarObjetivoFirmado()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"error":   err.Error(),
+		})
+		return
+	}
+	token, objetivo, _, err := h.gameService.IniciarPartida(tokenObjetivo)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"error":   err.Error(),
+		})
+		return
+	}
+	medido, err := h.gameService.DetenerPartida(token)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"error":   err.Error(),
+		})
+		return
+	}
+
 	testResult := models.GameResult{
 		ClienteData: models.ClienteData{
 			Nombre:   "Test",
@@ -213,9 +587,9 @@ func (h *GameHandler) TestGame(c *gin.Context) {
 			Telefono: "+5491123456789",
 		},
 		Resultado: models.Resultado{
-			Gano:           true,
-			TiempoObjetivo: 7.5,
-			TiempoObtenido: 7.3,
+			TiempoObjetivo: objetivo,
+			TiempoObtenido: medido,
+			TokenSesion:    token,
 		},
 	}
 
@@ -235,6 +609,70 @@ func (h *GameHandler) TestGame(c *gin.Context) {
 	})
 }
 
+// ListarPremiosRetenidos lista los premios ganados durante un pico de emisión que esperan
+// a un empleado
+func (h *GameHandler) ListarPremiosRetenidos(c *gin.Context) {
+	retenidos, err := h.gameService.ListarPremiosRetenidos()
+	if err != nil {
+		log.Printf("❌ Error listando premios retenidos: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "Error obteniendo premios retenidos",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":   true,
+		"retenidos": retenidos,
+		"total":     len(retenidos),
+	})
+}
+
+// LiberarPremioRetenido emite y envía el voucher de un premio retenido
+func (h *GameHandler) LiberarPremioRetenido(c *gin.Context) {
+	juegoID, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	voucher, err := h.gameService.LiberarPremioRetenido(juegoID, c.GetUint("user_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Premio liberado",
+		"voucher": api.NuevoVoucher(voucher),
+	})
+}
+
+// DescartarPremioRetenido descarta un premio retenido sin emitir el voucher
+func (h *GameHandler) DescartarPremioRetenido(c *gin.Context) {
+	juegoID, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	if err := h.gameService.DescartarPremioRetenido(juegoID, c.GetUint("user_id")); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Premio descartado",
+	})
+}
+
 // Middleware para logging de requests de juego
 func (h *GameHandler) GameLoggingMiddleware() gin.HandlerFunc {
 	return gin.LoggerWithFormatter(func(param gin.LogFormatterParams) string {
@@ -0,0 +1,164 @@
+package handlers
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"CheeseHouse/internal/models"
+	"CheeseHouse/internal/services"
+)
+
+// MesaHandler maneja las mesas del local y sus códigos QR
+type MesaHandler struct {
+	mesaService *services.MesaService
+	artefactos  *services.ArtefactoService
+}
+
+// NewMesaHandler crea una nueva instancia del handler de mesas
+func NewMesaHandler(mesaService *services.MesaService, artefactos *services.ArtefactoService) *MesaHandler {
+	return &MesaHandler{
+		mesaService: mesaService,
+		artefactos:  artefactos,
+	}
+}
+
+// ListarMesas lista las mesas con sus partidas y el resumen por área (?dias=30 por defecto)
+func (h *MesaHandler) ListarMesas(c *gin.Context) {
+	dias, err := strconv.Atoi(c.DefaultQuery("dias", "30"))
+	if err != nil || dias < 1 || dias > 365 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "dias debe estar entre 1 y 365",
+		})
+		return
+	}
+
+	mesas, areas, err := h.mesaService.Listar(dias)
+	if err != nil {
+		log.Printf("❌ Error listando mesas: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "Error obteniendo mesas",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"dias":    dias,
+		"mesas":   mesas,
+		"areas":   areas,
+	})
+}
+
+// CrearMesa da de alta una mesa
+func (h *MesaHandler) CrearMesa(c *gin.Context) {
+	var req models.CrearMesaRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Datos de la mesa inválidos",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	mesa, err := h.mesaService.Crear(req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"success": true,
+		"message": "Mesa creada",
+		"mesa":    mesa,
+	})
+}
+
+// ActualizarMesa cambia el número, el área o activa/desactiva una mesa
+func (h *MesaHandler) ActualizarMesa(c *gin.Context) {
+	mesaID, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	var req models.ActualizarMesaRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Datos de la mesa inválidos",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	mesa, err := h.mesaService.Actualizar(mesaID, req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Mesa actualizada",
+		"mesa":    mesa,
+	})
+}
+
+// GetQRMesa genera el PNG del QR de la mesa para imprimir (?modulo=10 píxeles por módulo).
+// Con ?link=true lo guarda y responde un link de descarga para mandarlo a la imprenta
+func (h *MesaHandler) GetQRMesa(c *gin.Context) {
+	mesaID, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+	modulo, err := strconv.Atoi(c.DefaultQuery("modulo", "10"))
+	if err != nil || modulo < 2 || modulo > 40 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "modulo debe estar entre 2 y 40",
+		})
+		return
+	}
+
+	mesa, imagen, err := h.mesaService.QR(mesaID, modulo)
+	if err != nil {
+		log.Printf("❌ Error generando QR de la mesa %d: %v", mesaID, err)
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	if c.Query("link") == "true" {
+		archivo, err := h.artefactos.Publicar("mesas", fmt.Sprintf("mesa-%d-qr-%d.png", mesa.ID, modulo), imagen, "image/png")
+		if err != nil {
+			log.Printf("❌ Error guardando QR de la mesa %d: %v", mesaID, err)
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"success": false,
+				"message": "Error guardando el QR",
+			})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"success": true,
+			"archivo": archivo,
+		})
+		return
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf("inline; filename=\"mesa-%s.png\"", mesa.Numero))
+	c.Data(http.StatusOK, "image/png", imagen)
+}
@@ -0,0 +1,531 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/net/websocket"
+
+	"CheeseHouse/internal/models"
+	"CheeseHouse/internal/services"
+)
+
+// PedidoHandler maneja el menú y la carga de pedidos recibidos por WhatsApp
+type PedidoHandler struct {
+	pedidoService *services.PedidoService
+}
+
+// NewPedidoHandler crea una nueva instancia del handler de pedidos
+func NewPedidoHandler(pedidoService *services.PedidoService) *PedidoHandler {
+	return &PedidoHandler{
+		pedidoService: pedidoService,
+	}
+}
+
+// ListarMenu lista los items del menú (?activos=true para ocultar los desactivados)
+func (h *PedidoHandler) ListarMenu(c *gin.Context) {
+	items, err := h.pedidoService.ListarMenu(c.Query("activos") == "true")
+	if err != nil {
+		log.Printf("❌ Error listando menú: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "Error obteniendo el menú",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"items":   items,
+	})
+}
+
+// CrearItemMenu agrega un item al menú
+func (h *PedidoHandler) CrearItemMenu(c *gin.Context) {
+	var req models.GuardarItemMenuRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Datos del item inválidos",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	item, err := h.pedidoService.CrearItemMenu(req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"success": true,
+		"message": "Item agregado al menú",
+		"item":    item,
+	})
+}
+
+// ActualizarItemMenu modifica un item del menú
+func (h *PedidoHandler) ActualizarItemMenu(c *gin.Context) {
+	itemID, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	var req models.GuardarItemMenuRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Datos del item inválidos",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	item, err := h.pedidoService.ActualizarItemMenu(itemID, req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Item actualizado",
+		"item":    item,
+	})
+}
+
+// EliminarItemMenu borra un item del menú
+func (h *PedidoHandler) EliminarItemMenu(c *gin.Context) {
+	itemID, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	if err := h.pedidoService.EliminarItemMenu(itemID); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Item eliminado del menú",
+	})
+}
+
+// InterpretarPedido propone items y cantidades a partir del texto del cliente para que el empleado los confirme
+func (h *PedidoHandler) InterpretarPedido(c *gin.Context) {
+	var req models.InterpretarPedidoRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Indicá el mensaje o el teléfono de la conversación",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	propuesta, err := h.pedidoService.Interpretar(req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":   true,
+		"propuesta": propuesta,
+	})
+}
+
+// ConfirmarPedido guarda el pedido revisado por el empleado
+func (h *PedidoHandler) ConfirmarPedido(c *gin.Context) {
+	var req models.ConfirmarPedidoRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Datos del pedido inválidos",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	pedido, err := h.pedidoService.Confirmar(req, c.GetUint("user_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"success": true,
+		"message": "Pedido confirmado",
+		"pedido":  pedido,
+	})
+}
+
+// ListarPedidos lista los pedidos (?estado=&atendido_por=&sin_asignar=true&telefono=
+// &fecha_desde=&fecha_hasta=&limit=)
+func (h *PedidoHandler) ListarPedidos(c *gin.Context) {
+	filtros := map[string]interface{}{}
+	if estado := c.Query("estado"); estado != "" {
+		switch estado {
+		case "pendiente", "procesando", "completado", "cancelado":
+			filtros["estado"] = estado
+		default:
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"message": "Estado inválido, usar pendiente, procesando, completado o cancelado",
+			})
+			return
+		}
+	}
+	if atendidoPor, err := strconv.ParseUint(c.Query("atendido_por"), 10, 32); err == nil {
+		filtros["atendido_por"] = uint(atendidoPor)
+	}
+	if c.Query("sin_asignar") == "true" {
+		filtros["sin_asignar"] = true
+	}
+	if telefono := c.Query("telefono"); telefono != "" {
+		filtros["telefono"] = telefono
+	}
+	for _, campo := range []string{"fecha_desde", "fecha_hasta"} {
+		if valor := c.Query(campo); valor != "" {
+			fecha, err := time.Parse("2006-01-02", valor)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{
+					"success": false,
+					"message": campo + " inválida, usar el formato AAAA-MM-DD",
+				})
+				return
+			}
+			if campo == "fecha_hasta" {
+				fecha = fecha.AddDate(0, 0, 1).Add(-time.Second)
+			}
+			filtros[campo] = fecha
+		}
+	}
+	limite, _ := strconv.Atoi(c.DefaultQuery("limit", "100"))
+	if limite <= 0 || limite > 500 {
+		limite = 100
+	}
+	filtros["limit"] = limite
+
+	pedidos, err := h.pedidoService.Listar(filtros)
+	if err != nil {
+		log.Printf("❌ Error listando pedidos: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "Error obteniendo pedidos",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"pedidos": pedidos,
+		"total":   len(pedidos),
+	})
+}
+
+// GetPedido obtiene un pedido con sus items y el empleado que lo atiende
+func (h *PedidoHandler) GetPedido(c *gin.Context) {
+	pedidoID, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	pedido, err := h.pedidoService.Obtener(pedidoID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"pedido":  pedido,
+	})
+}
+
+// AsignarPedido deja un pedido abierto a cargo de un empleado (por defecto, quien lo pide)
+func (h *PedidoHandler) AsignarPedido(c *gin.Context) {
+	pedidoID, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	var req models.AsignarPedidoRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"message": "Datos inválidos",
+				"error":   err.Error(),
+			})
+			return
+		}
+	}
+	usuarioID := c.GetUint("user_id")
+	if req.UsuarioID == 0 {
+		req.UsuarioID = usuarioID
+	}
+
+	pedido, err := h.pedidoService.Asignar(pedidoID, req.UsuarioID, usuarioID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Pedido asignado",
+		"pedido":  pedido,
+	})
+}
+
+// CambiarEstadoPedido mueve un pedido de estado y avisa al cliente si corresponde
+func (h *PedidoHandler) CambiarEstadoPedido(c *gin.Context) {
+	pedidoID, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	var req models.CambiarEstadoPedidoRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Estado de pedido inválido",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	pedido, notificado, err := h.pedidoService.CambiarEstado(pedidoID, req, c.GetUint("user_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":    true,
+		"message":    "Estado del pedido actualizado",
+		"pedido":     pedido,
+		"notificado": notificado,
+	})
+}
+
+// ListarPedidosCliente lista el historial de pedidos de un cliente (?limite=N)
+func (h *PedidoHandler) ListarPedidosCliente(c *gin.Context) {
+	clienteID, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+	limite, _ := strconv.Atoi(c.DefaultQuery("limite", "50"))
+
+	pedidos, err := h.pedidoService.ListarPorCliente(clienteID, limite)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"pedidos": pedidos,
+	})
+}
+
+// RepetirPedidoCliente crea un pedido pendiente igual al último (o al indicado) del cliente
+func (h *PedidoHandler) RepetirPedidoCliente(c *gin.Context) {
+	clienteID, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	var req models.RepetirPedidoRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"message": "Datos inválidos",
+				"error":   err.Error(),
+			})
+			return
+		}
+	}
+
+	pedido, omitidos, err := h.pedidoService.Repetir(clienteID, req, c.GetUint("user_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success":  false,
+			"message":  err.Error(),
+			"omitidos": omitidos,
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"success":  true,
+		"message":  "Pedido repetido",
+		"pedido":   pedido,
+		"omitidos": omitidos,
+	})
+}
+
+// GetColaCocina lista los pedidos a preparar ordenados por hora prometida, para la tablet de la cocina
+func (h *PedidoHandler) GetColaCocina(c *gin.Context) {
+	pedidos, err := h.pedidoService.ColaCocina()
+	if err != nil {
+		log.Printf("❌ Error obteniendo cola de cocina: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "Error obteniendo la cola de cocina",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"pedidos": pedidos,
+		"total":   len(pedidos),
+	})
+}
+
+// MarcarItemPreparado marca o desmarca un item como listo desde la cocina
+func (h *PedidoHandler) MarcarItemPreparado(c *gin.Context) {
+	pedidoID, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+	itemID, ok := parseIDParam(c, "item_id")
+	if !ok {
+		return
+	}
+
+	var req models.MarcarPreparadoRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Indicá si el item está preparado",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	pedido, err := h.pedidoService.MarcarItemPreparado(pedidoID, itemID, *req.Preparado)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"pedido":  pedido,
+	})
+}
+
+// ColaCocinaWS empuja por WebSocket los pedidos confirmados y sus cambios a la
+// pantalla de cocina. Cada 30 segundos envía un ping para mantener viva la conexión
+func (h *PedidoHandler) ColaCocinaWS(c *gin.Context) {
+	websocket.Handler(func(ws *websocket.Conn) {
+		defer ws.Close()
+
+		eventos, cancelar := h.pedidoService.SuscribirCocina()
+		defer cancelar()
+
+		// La pantalla no envía nada; leer solo sirve para detectar que se desconectó
+		desconectado := make(chan struct{})
+		go func() {
+			var ignorado string
+			for websocket.Message.Receive(ws, &ignorado) == nil {
+			}
+			close(desconectado)
+		}()
+
+		ping := time.NewTicker(30 * time.Second)
+		defer ping.Stop()
+
+		for {
+			var evento models.EventoCocina
+			select {
+			case <-desconectado:
+				return
+			case evento = <-eventos:
+			case <-ping.C:
+				evento = models.EventoCocina{Tipo: "ping"}
+			}
+			if err := websocket.JSON.Send(ws, evento); err != nil {
+				return
+			}
+		}
+	}).ServeHTTP(c.Writer, c.Request)
+}
+
+// GetEsperaEstimada muestra la espera estimada con el detalle de los promedios (?unidades=N)
+func (h *PedidoHandler) GetEsperaEstimada(c *gin.Context) {
+	unidades, _ := strconv.Atoi(c.DefaultQuery("unidades", "0"))
+
+	estimacion, err := h.pedidoService.EstimarEspera(unidades)
+	if err != nil {
+		log.Printf("❌ Error estimando espera: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "Error estimando la espera",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"espera":  estimacion,
+	})
+}
+
+// GetEsperaPublica expone la espera estimada para mostrar en la web
+func (h *PedidoHandler) GetEsperaPublica(c *gin.Context) {
+	estimacion, err := h.pedidoService.EsperaPublica()
+	if err != nil {
+		log.Printf("❌ Error obteniendo espera pública: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "Error obteniendo la espera",
+		})
+		return
+	}
+
+	c.Header("Cache-Control", "public, max-age=60")
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"espera":  estimacion,
+	})
+}
@@ -0,0 +1,124 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"CheeseHouse/internal/models"
+	"CheeseHouse/internal/services"
+)
+
+// PreferenciasHandler maneja el centro de preferencias de comunicación de los clientes
+type PreferenciasHandler struct {
+	preferenciasService *services.PreferenciasService
+}
+
+// NewPreferenciasHandler crea una nueva instancia del handler de preferencias
+func NewPreferenciasHandler(preferenciasService *services.PreferenciasService) *PreferenciasHandler {
+	return &PreferenciasHandler{
+		preferenciasService: preferenciasService,
+	}
+}
+
+// GetPreferencias obtiene las preferencias del cliente a partir del link firmado
+func (h *PreferenciasHandler) GetPreferencias(c *gin.Context) {
+	clienteID, err := strconv.ParseUint(c.Param("cliente_id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Cliente inválido",
+		})
+		return
+	}
+
+	preferencias, err := h.preferenciasService.ObtenerPreferencias(uint(clienteID), c.Query("token"))
+	if err != nil {
+		c.JSON(http.StatusForbidden, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":      true,
+		"preferencias": preferencias,
+	})
+}
+
+// DarDeBaja aplica la baja de marketing desde el link de una campaña
+func (h *PreferenciasHandler) DarDeBaja(c *gin.Context) {
+	clienteID, err := strconv.ParseUint(c.Param("cliente_id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Cliente inválido",
+		})
+		return
+	}
+
+	var req models.BajaMarketingRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Link de baja inválido",
+		})
+		return
+	}
+
+	if err := h.preferenciasService.DarDeBaja(uint(clienteID), req); err != nil {
+		log.Printf("❌ Error aplicando baja del cliente %d: %v", clienteID, err)
+		c.JSON(http.StatusForbidden, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":           true,
+		"message":           "Te diste de baja de las promociones",
+		"link_preferencias": h.preferenciasService.GenerarLinkPreferencias(uint(clienteID)),
+	})
+}
+
+// ActualizarPreferencias guarda el canal y las categorías elegidas por el cliente
+func (h *PreferenciasHandler) ActualizarPreferencias(c *gin.Context) {
+	clienteID, err := strconv.ParseUint(c.Param("cliente_id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Cliente inválido",
+		})
+		return
+	}
+
+	var req models.ActualizarPreferenciasRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Datos de preferencias inválidos",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	preferencias, err := h.preferenciasService.ActualizarPreferencias(uint(clienteID), req)
+	if err != nil {
+		log.Printf("❌ Error actualizando preferencias del cliente %d: %v", clienteID, err)
+		c.JSON(http.StatusForbidden, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":      true,
+		"message":      "Preferencias actualizadas",
+		"preferencias": preferencias,
+	})
+}
@@ -0,0 +1,135 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"CheeseHouse/internal/models"
+	"CheeseHouse/internal/services"
+)
+
+// RecomendacionHandler maneja las reglas de recomendación de los vouchers
+type RecomendacionHandler struct {
+	recomendacionService *services.RecomendacionService
+}
+
+// NewRecomendacionHandler crea una nueva instancia del handler de recomendaciones
+func NewRecomendacionHandler(recomendacionService *services.RecomendacionService) *RecomendacionHandler {
+	return &RecomendacionHandler{
+		recomendacionService: recomendacionService,
+	}
+}
+
+// ListarReglas lista las reglas en orden de evaluación con sus canjes (?dias=30 por defecto)
+func (h *RecomendacionHandler) ListarReglas(c *gin.Context) {
+	dias, err := strconv.Atoi(c.DefaultQuery("dias", "30"))
+	if err != nil || dias < 1 || dias > 365 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "dias debe estar entre 1 y 365",
+		})
+		return
+	}
+
+	reglas, err := h.recomendacionService.Listar(dias)
+	if err != nil {
+		log.Printf("❌ Error listando reglas de recomendación: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "Error obteniendo reglas de recomendación",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"dias":    dias,
+		"reglas":  reglas,
+	})
+}
+
+// CrearRegla agrega una regla de recomendación
+func (h *RecomendacionHandler) CrearRegla(c *gin.Context) {
+	var req models.GuardarReglaRecomendacionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Datos de la regla inválidos",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	regla, err := h.recomendacionService.Crear(req, c.GetUint("user_id"))
+	if err != nil {
+		log.Printf("❌ Error creando regla de recomendación: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "Error creando regla de recomendación",
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"success": true,
+		"message": "Regla creada",
+		"regla":   regla,
+	})
+}
+
+// ActualizarRegla reemplaza las condiciones, el alcance o el mensaje de una regla
+func (h *RecomendacionHandler) ActualizarRegla(c *gin.Context) {
+	reglaID, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	var req models.GuardarReglaRecomendacionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Datos de la regla inválidos",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	regla, err := h.recomendacionService.Actualizar(reglaID, req, c.GetUint("user_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Regla actualizada",
+		"regla":   regla,
+	})
+}
+
+// EliminarRegla borra una regla de recomendación
+func (h *RecomendacionHandler) EliminarRegla(c *gin.Context) {
+	reglaID, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	if err := h.recomendacionService.Eliminar(reglaID, c.GetUint("user_id")); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Regla eliminada",
+	})
+}
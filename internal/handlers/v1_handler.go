@@ -0,0 +1,237 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"CheeseHouse/internal/api"
+	"CheeseHouse/internal/i18n"
+	"CheeseHouse/internal/models"
+	"CheeseHouse/internal/services"
+)
+
+// Endpoints públicos de /api/v1. Usan el sobre de internal/api; las rutas sin
+// versión siguen respondiendo el formato anterior que consume el frontend actual
+
+// SubmitGameResultV1 procesa el resultado de una partida
+func (h *GameHandler) SubmitGameResultV1(c *gin.Context) {
+	var gameResult models.GameResult
+	if err := c.ShouldBindJSON(&gameResult); err != nil {
+		api.FalloValidacion(c, "Datos del juego inválidos", err)
+		return
+	}
+	gameResult.IP = c.ClientIP()
+
+	response, err := h.gameService.ProcesarResultadoJuego(gameResult)
+	if err != nil {
+		log.Printf("❌ Error procesando juego: %v", err)
+		api.Fallo(c, http.StatusInternalServerError, api.ErrorInterno, "Error interno del servidor")
+		return
+	}
+
+	resultado := api.NuevoResultadoJuego(response)
+	if !response.Success {
+		api.FalloConDatos(c, http.StatusUnprocessableEntity, api.ErrorJuegoRechazado, response.Message, resultado)
+		return
+	}
+	api.OK(c, http.StatusOK, resultado)
+}
+
+// RevelarVoucherPantallaV1 muestra el voucher de la partida tras confirmar los últimos
+// dígitos del teléfono
+func (h *GameHandler) RevelarVoucherPantallaV1(c *gin.Context) {
+	var req models.RevelarVoucherRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		api.FalloValidacion(c, "Ingresá los últimos 4 dígitos de tu teléfono", err)
+		return
+	}
+
+	voucher, err := h.gameService.RevelarVoucherPantalla(req.Token, req.Digitos)
+	if err != nil {
+		api.Fallo(c, http.StatusUnprocessableEntity, api.ErrorDatosInvalidos, err.Error())
+		return
+	}
+	c.Header("Cache-Control", "no-store")
+	api.OK(c, http.StatusOK, api.NuevoVoucherPantalla(voucher))
+}
+
+// GetGameStatsV1 obtiene las estadísticas públicas del juego
+func (h *GameHandler) GetGameStatsV1(c *gin.Context) {
+	stats, err := h.gameService.GetEstadisticasGenerales()
+	if err != nil {
+		log.Printf("❌ Error obteniendo estadísticas: %v", err)
+		api.Fallo(c, http.StatusInternalServerError, api.ErrorInterno, "Error obteniendo estadísticas")
+		return
+	}
+
+	api.OK(c, http.StatusOK, api.NuevasEstadisticasJuego(h.gameService.GetConfiguracionJuego().Restaurante, stats))
+}
+
+// GetGameConfigV1 obtiene la configuración del juego y el branding
+func (h *GameHandler) GetGameConfigV1(c *gin.Context) {
+	api.OK(c, http.StatusOK, api.ConfiguracionJuego{
+		Juego:    h.gameService.GetConfiguracionJuego(),
+		Branding: api.NuevoBranding(h.brandingService.Obtener()),
+	})
+}
+
+// GenerateTargetTimeV1 genera un tiempo objetivo firmado
+func (h *GameHandler) GenerateTargetTimeV1(c *gin.Context) {
+	tiempo, token, err := h.gameService.GenerarObjetivoFirmado()
+	if err != nil {
+		log.Printf("❌ Error generando tiempo objetivo: %v", err)
+		api.Fallo(c, http.StatusInternalServerError, api.ErrorInterno, "Error generando tiempo objetivo")
+		return
+	}
+
+	c.Header("Cache-Control", "no-store")
+	api.OK(c, http.StatusOK, api.Objetivo{TiempoObjetivo: tiempo, TokenObjetivo: token})
+}
+
+// StartGameV1 abre la sesión de juego con un objetivo firmado
+func (h *GameHandler) StartGameV1(c *gin.Context) {
+	var req models.IniciarPartidaRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		api.FalloValidacion(c, "Datos inválidos", err)
+		return
+	}
+
+	token, objetivo, inicio, err := h.gameService.IniciarPartida(req.TokenObjetivo)
+	if err != nil {
+		if errors.Is(err, services.ErrTokenObjetivoInvalido) {
+			api.Fallo(c, http.StatusUnprocessableEntity, api.ErrorJuegoRechazado, err.Error())
+			return
+		}
+		log.Printf("❌ Error iniciando partida: %v", err)
+		api.Fallo(c, http.StatusInternalServerError, api.ErrorInterno, "Error iniciando partida")
+		return
+	}
+
+	c.Header("Cache-Control", "no-store")
+	api.OK(c, http.StatusOK, api.Sesion{TokenSesion: token, TiempoObjetivo: objetivo, IniciadaEl: inicio})
+}
+
+// StopGameV1 registra la detención del cronómetro
+func (h *GameHandler) StopGameV1(c *gin.Context) {
+	var req models.DetenerPartidaRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		api.FalloValidacion(c, "Datos inválidos", err)
+		return
+	}
+
+	medido, err := h.gameService.DetenerPartida(req.TokenSesion)
+	if err != nil {
+		api.Fallo(c, http.StatusUnprocessableEntity, api.ErrorJuegoRechazado, err.Error())
+		return
+	}
+	api.OK(c, http.StatusOK, api.Detencion{TiempoMedido: medido})
+}
+
+// GetBrandingV1 expone el branding vigente
+func (h *GameHandler) GetBrandingV1(c *gin.Context) {
+	c.Header("Cache-Control", "public, max-age=300")
+	api.OK(c, http.StatusOK, api.NuevoBranding(h.brandingService.Obtener()))
+}
+
+// GetTextosV1 retorna los textos localizados de la página del juego
+func (h *GameHandler) GetTextosV1(c *gin.Context) {
+	locale := i18n.Detectar(c.Param("locale"), c.GetHeader("Accept-Language"), h.gameService.GetConfiguracionJuego().IdiomaPorDefecto)
+
+	c.Header("Cache-Control", "public, max-age=3600")
+	c.Header("Vary", "Accept-Language")
+	api.OK(c, http.StatusOK, api.Textos{
+		Locale:      locale,
+		Disponibles: i18n.Locales(),
+		Textos:      i18n.Textos(locale),
+	})
+}
+
+// GetWinnersV1 lista los últimos ganadores que aceptaron aparecer en el muro
+func (h *GameHandler) GetWinnersV1(c *gin.Context) {
+	limite, _ := strconv.Atoi(c.DefaultQuery("limite", "10"))
+	locale := i18n.Detectar(c.Query("lang"), c.GetHeader("Accept-Language"), h.gameService.GetConfiguracionJuego().IdiomaPorDefecto)
+
+	ganadores, err := h.gameService.GetMuroGanadores(limite, locale)
+	if err != nil {
+		log.Printf("❌ Error obteniendo muro de ganadores: %v", err)
+		api.Fallo(c, http.StatusInternalServerError, api.ErrorInterno, "Error obteniendo ganadores")
+		return
+	}
+
+	c.Header("Cache-Control", "public, max-age=60")
+	c.Header("Vary", "Accept-Language")
+	api.Lista(c, http.StatusOK, ganadores, len(ganadores))
+}
+
+// GetLeaderboardV1 devuelve el ranking de la semana de los clientes que aceptaron
+// aparecer en el muro
+func (h *GameHandler) GetLeaderboardV1(c *gin.Context) {
+	ranking, err := h.ranking.GetRanking(true)
+	if err != nil {
+		log.Printf("❌ Error obteniendo ranking: %v", err)
+		api.Fallo(c, http.StatusInternalServerError, api.ErrorInterno, "Error obteniendo ranking")
+		return
+	}
+
+	c.Header("Cache-Control", "public, max-age=60")
+	api.OK(c, http.StatusOK, ranking)
+}
+
+// GetClientByPhoneV1 obtiene los datos básicos de un cliente verificado con apellido o código
+func (h *GameHandler) GetClientByPhoneV1(c *gin.Context) {
+	apellido := c.Query("apellido")
+	codigo := c.Query("codigo")
+	if apellido == "" && codigo == "" {
+		api.Fallo(c, http.StatusBadRequest, api.ErrorDatosInvalidos, "Se requiere el apellido o un código de verificación")
+		return
+	}
+
+	cliente, err := h.gameService.GetClienteVerificado(c.Param("phone"), apellido, codigo)
+	if err != nil {
+		// Misma respuesta para cliente inexistente y datos incorrectos
+		api.Fallo(c, http.StatusNotFound, api.ErrorNoEncontrado, "Cliente no encontrado o los datos no coinciden")
+		return
+	}
+
+	api.OK(c, http.StatusOK, api.NuevoClientePublico(cliente))
+}
+
+// SolicitarCodigoClienteV1 envía por WhatsApp un código para consultar los datos del cliente
+func (h *GameHandler) SolicitarCodigoClienteV1(c *gin.Context) {
+	h.gameService.SolicitarCodigoCliente(c.Param("phone"))
+
+	// Respuesta genérica: no revela si el teléfono está registrado
+	api.OK(c, http.StatusOK, api.Mensaje{
+		Mensaje: "Si el teléfono está registrado, te enviamos un código por WhatsApp",
+	})
+}
+
+// GetPublicWidgetStatsV1 expone estadísticas agregadas para widgets embebibles
+func (h *GameHandler) GetPublicWidgetStatsV1(c *gin.Context) {
+	stats, err := h.gameService.GetEstadisticasPublicas()
+	if err != nil {
+		log.Printf("❌ Error obteniendo estadísticas públicas: %v", err)
+		api.Fallo(c, http.StatusInternalServerError, api.ErrorInterno, "Error obteniendo estadísticas")
+		return
+	}
+
+	c.Header("Cache-Control", "public, max-age=60")
+	api.OK(c, http.StatusOK, stats)
+}
+
+// GetEsperaPublicaV1 expone la espera estimada de pedidos para la web
+func (h *PedidoHandler) GetEsperaPublicaV1(c *gin.Context) {
+	estimacion, err := h.pedidoService.EsperaPublica()
+	if err != nil {
+		log.Printf("❌ Error obteniendo espera pública: %v", err)
+		api.Fallo(c, http.StatusInternalServerError, api.ErrorInterno, "Error obteniendo la espera")
+		return
+	}
+
+	c.Header("Cache-Control", "public, max-age=60")
+	api.OK(c, http.StatusOK, api.NuevaEsperaPedido(estimacion))
+}
@@ -0,0 +1,124 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"CheeseHouse/internal/models"
+	"CheeseHouse/internal/services"
+)
+
+// VigenciaHandler maneja las reglas de vigencia de los vouchers de partida
+type VigenciaHandler struct {
+	vigenciaService *services.VigenciaService
+}
+
+// NewVigenciaHandler crea una nueva instancia del handler de vigencias
+func NewVigenciaHandler(vigenciaService *services.VigenciaService) *VigenciaHandler {
+	return &VigenciaHandler{
+		vigenciaService: vigenciaService,
+	}
+}
+
+// ListarVigencias lista las reglas de vigencia y la vigencia por defecto
+func (h *VigenciaHandler) ListarVigencias(c *gin.Context) {
+	reglas, err := h.vigenciaService.Listar()
+	if err != nil {
+		log.Printf("❌ Error listando reglas de vigencia: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "Error obteniendo reglas de vigencia",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":     true,
+		"por_defecto": h.vigenciaService.PorDefecto(),
+		"reglas":      reglas,
+	})
+}
+
+// CrearVigencia agrega una regla de vigencia
+func (h *VigenciaHandler) CrearVigencia(c *gin.Context) {
+	var req models.GuardarReglaVigenciaRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Datos de la regla inválidos",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	regla, err := h.vigenciaService.Crear(req, c.GetUint("user_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"success": true,
+		"message": "Regla creada",
+		"regla":   regla,
+	})
+}
+
+// ActualizarVigencia cambia el tipo, el día o los días de validez de una regla
+func (h *VigenciaHandler) ActualizarVigencia(c *gin.Context) {
+	reglaID, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	var req models.GuardarReglaVigenciaRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Datos de la regla inválidos",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	regla, err := h.vigenciaService.Actualizar(reglaID, req, c.GetUint("user_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Regla actualizada",
+		"regla":   regla,
+	})
+}
+
+// EliminarVigencia borra una regla; esos vouchers vuelven a la vigencia por defecto
+func (h *VigenciaHandler) EliminarVigencia(c *gin.Context) {
+	reglaID, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	if err := h.vigenciaService.Eliminar(reglaID, c.GetUint("user_id")); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Regla eliminada",
+	})
+}
@@ -0,0 +1,603 @@
+package handlers
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"CheeseHouse/internal/config"
+	"CheeseHouse/internal/models"
+	"CheeseHouse/internal/services"
+)
+
+// WhatsAppHandler maneja el webhook de WhatsApp y la bandeja de conversaciones del staff
+type WhatsAppHandler struct {
+	config         *config.Config
+	whatsapp       *services.WhatsAppService
+	adminService   *services.AdminService
+	conversaciones *services.ConversacionService
+	respuestas     *services.RespuestaRapidaService
+	horarios       *services.HorarioService
+	mensajes       *services.MensajeConfigService
+}
+
+// NewWhatsAppHandler crea una nueva instancia del handler de WhatsApp
+func NewWhatsAppHandler(
+	cfg *config.Config,
+	whatsapp *services.WhatsAppService,
+	adminService *services.AdminService,
+	conversaciones *services.ConversacionService,
+	respuestas *services.RespuestaRapidaService,
+	horarios *services.HorarioService,
+	mensajes *services.MensajeConfigService,
+) *WhatsAppHandler {
+	return &WhatsAppHandler{
+		config:         cfg,
+		whatsapp:       whatsapp,
+		adminService:   adminService,
+		conversaciones: conversaciones,
+		respuestas:     respuestas,
+		horarios:       horarios,
+		mensajes:       mensajes,
+	}
+}
+
+// VerificarWebhook responde el desafío de suscripción del webhook de Meta
+func (h *WhatsAppHandler) VerificarWebhook(c *gin.Context) {
+	if h.config.WhatsAppVerifyToken == "" ||
+		c.Query("hub.mode") != "subscribe" ||
+		c.Query("hub.verify_token") != h.config.WhatsAppVerifyToken {
+		c.Status(http.StatusForbidden)
+		return
+	}
+
+	c.String(http.StatusOK, c.Query("hub.challenge"))
+}
+
+// RecibirWebhook registra los estados de entrega de los mensajes enviados (también en los
+// envíos de campañas), guarda los entrantes en la bandeja y procesa los pedidos.
+// Sin una firma X-Hub-Signature-256 válida responde 401 sin procesar nada; firmado,
+// siempre responde 200 para que Meta no reintente mensajes ya recibidos
+func (h *WhatsAppHandler) RecibirWebhook(c *gin.Context) {
+	cuerpo, err := c.GetRawData()
+	if err != nil || !h.firmaValida(cuerpo, c.GetHeader("X-Hub-Signature-256")) {
+		log.Printf("⚠️  Webhook de WhatsApp rechazado: firma inválida (IP %s)", c.ClientIP())
+		c.Status(http.StatusUnauthorized)
+		return
+	}
+
+	var webhook models.WhatsAppWebhookMessage
+	if err := json.Unmarshal(cuerpo, &webhook); err != nil {
+		log.Printf("⚠️  Webhook de WhatsApp inválido: %v", err)
+		c.Status(http.StatusOK)
+		return
+	}
+
+	h.whatsapp.ProcesarEstados(webhook)
+	h.adminService.ActualizarEnviosCampanas(webhook)
+
+	if registrados := h.conversaciones.RegistrarEntrantes(webhook); registrados > 0 {
+		log.Printf("📥 %d mensajes nuevos en la bandeja de WhatsApp", registrados)
+	}
+
+	for _, pedido := range h.whatsapp.ProcesarMensajeEntrante(webhook) {
+		pedido := pedido
+		if err := h.adminService.ProcesarPedidoWhatsApp(&pedido); err != nil {
+			log.Printf("❌ Error procesando pedido de %s: %v", pedido.Telefono, err)
+		}
+	}
+
+	c.Status(http.StatusOK)
+}
+
+// firmaValida compara la firma de Meta ("sha256=<hex>") con el HMAC-SHA256 del cuerpo
+// crudo usando el app secret; sin secret configurado no se acepta ningún webhook
+func (h *WhatsAppHandler) firmaValida(cuerpo []byte, firma string) bool {
+	if h.config.WhatsAppAppSecret == "" {
+		return false
+	}
+	recibida, err := hex.DecodeString(strings.TrimPrefix(firma, "sha256="))
+	if err != nil || !strings.HasPrefix(firma, "sha256=") {
+		return false
+	}
+	mac := hmac.New(sha256.New, []byte(h.config.WhatsAppAppSecret))
+	mac.Write(cuerpo)
+	return hmac.Equal(recibida, mac.Sum(nil))
+}
+
+// ListarConversaciones lista los hilos de la bandeja (?no_leidos=true para ver solo pendientes)
+func (h *WhatsAppHandler) ListarConversaciones(c *gin.Context) {
+	hilos, noLeidos, err := h.conversaciones.ListarHilos(c.Query("no_leidos") == "true")
+	if err != nil {
+		log.Printf("❌ Error listando conversaciones: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "Error obteniendo conversaciones",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":        true,
+		"conversaciones": hilos,
+		"no_leidos":      noLeidos,
+	})
+}
+
+// GetConversacion muestra los mensajes con un teléfono y los marca como leídos
+func (h *WhatsAppHandler) GetConversacion(c *gin.Context) {
+	mensajes, err := h.conversaciones.GetConversacion(c.Param("telefono"))
+	if err != nil {
+		log.Printf("❌ Error obteniendo conversación: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "Error obteniendo conversación",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":  true,
+		"mensajes": mensajes,
+	})
+}
+
+// AsignarConversacion toma la conversación para el usuario autenticado
+func (h *WhatsAppHandler) AsignarConversacion(c *gin.Context) {
+	atencion, err := h.conversaciones.Asignar(c.Param("telefono"), c.GetUint("user_id"))
+	if err != nil {
+		c.JSON(http.StatusConflict, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":  true,
+		"message":  "Conversación asignada",
+		"atencion": atencion,
+	})
+}
+
+// ResolverConversacion marca la conversación como resuelta
+func (h *WhatsAppHandler) ResolverConversacion(c *gin.Context) {
+	atencion, err := h.conversaciones.Resolver(c.Param("telefono"), c.GetUint("user_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":  true,
+		"message":  "Conversación resuelta",
+		"atencion": atencion,
+	})
+}
+
+// GetResumenSLA muestra las atenciones fuera de SLA y los tiempos promedio
+func (h *WhatsAppHandler) GetResumenSLA(c *gin.Context) {
+	resumen, err := h.conversaciones.GetResumenSLA()
+	if err != nil {
+		log.Printf("❌ Error obteniendo SLA de atención: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "Error obteniendo SLA de atención",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"sla":     resumen,
+	})
+}
+
+// ResponderConversacion envía una respuesta manual al cliente
+func (h *WhatsAppHandler) ResponderConversacion(c *gin.Context) {
+	var req models.ResponderConversacionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Mensaje inválido",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	mensaje, err := h.conversaciones.Responder(c.Param("telefono"), req, c.GetUint("user_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Respuesta enviada",
+		"mensaje": mensaje,
+	})
+}
+
+// ListarRespuestasRapidas lista las respuestas rápidas con sus usos (?activas=true para la bandeja)
+func (h *WhatsAppHandler) ListarRespuestasRapidas(c *gin.Context) {
+	respuestas, err := h.respuestas.Listar(c.Query("activas") == "true")
+	if err != nil {
+		log.Printf("❌ Error listando respuestas rápidas: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "Error obteniendo respuestas rápidas",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":    true,
+		"respuestas": respuestas,
+	})
+}
+
+// CrearRespuestaRapida agrega una respuesta rápida
+func (h *WhatsAppHandler) CrearRespuestaRapida(c *gin.Context) {
+	var req models.GuardarRespuestaRapidaRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Datos de respuesta rápida inválidos",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	respuesta, err := h.respuestas.Crear(req, c.GetUint("user_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"success":   true,
+		"message":   "Respuesta rápida creada",
+		"respuesta": respuesta,
+	})
+}
+
+// ActualizarRespuestaRapida modifica una respuesta rápida
+func (h *WhatsAppHandler) ActualizarRespuestaRapida(c *gin.Context) {
+	respuestaID, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	var req models.GuardarRespuestaRapidaRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Datos de respuesta rápida inválidos",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	respuesta, err := h.respuestas.Actualizar(respuestaID, req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":   true,
+		"message":   "Respuesta rápida actualizada",
+		"respuesta": respuesta,
+	})
+}
+
+// EliminarRespuestaRapida borra una respuesta rápida
+func (h *WhatsAppHandler) EliminarRespuestaRapida(c *gin.Context) {
+	respuestaID, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	if err := h.respuestas.Eliminar(respuestaID); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Respuesta rápida eliminada",
+	})
+}
+
+// ListarMensajes lista los textos de WhatsApp editables con el original y sus variables
+func (h *WhatsAppHandler) ListarMensajes(c *gin.Context) {
+	mensajes, err := h.mensajes.Listar()
+	if err != nil {
+		log.Printf("❌ Error listando textos de mensajes: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "Error obteniendo textos de mensajes",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":  true,
+		"mensajes": mensajes,
+	})
+}
+
+// GuardarMensaje reemplaza el texto de un mensaje de WhatsApp
+func (h *WhatsAppHandler) GuardarMensaje(c *gin.Context) {
+	var req models.GuardarMensajeConfigRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Texto de mensaje inválido",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	mensaje, err := h.mensajes.Guardar(c.Param("clave"), req, c.GetUint("user_id"))
+	if err != nil {
+		c.JSON(statusErrorMensaje(err), gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Texto del mensaje actualizado",
+		"mensaje": mensaje,
+	})
+}
+
+// RestablecerMensaje vuelve un mensaje de WhatsApp a su texto original
+func (h *WhatsAppHandler) RestablecerMensaje(c *gin.Context) {
+	mensaje, err := h.mensajes.Restablecer(c.Param("clave"), c.GetUint("user_id"))
+	if err != nil {
+		c.JSON(statusErrorMensaje(err), gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Texto del mensaje restablecido",
+		"mensaje": mensaje,
+	})
+}
+
+// PrevisualizarMensaje muestra cómo llega un mensaje al cliente, con el texto guardado o
+// con uno en edición, sin guardarlo
+func (h *WhatsAppHandler) PrevisualizarMensaje(c *gin.Context) {
+	var req models.PrevisualizarMensajeRequest
+	if err := c.ShouldBindJSON(&req); err != nil && c.Request.ContentLength > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Datos de previsualización inválidos",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	texto, err := h.mensajes.Previsualizar(c.Param("clave"), req)
+	if err != nil {
+		c.JSON(statusErrorMensaje(err), gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"texto":   texto,
+	})
+}
+
+// ListarOutboxWhatsApp lista los mensajes de WhatsApp retenidos o esperando reintento
+func (h *WhatsAppHandler) ListarOutboxWhatsApp(c *gin.Context) {
+	limite, _ := strconv.Atoi(c.DefaultQuery("limit", "100"))
+
+	mensajes, err := h.whatsapp.ListarOutbox(c.Query("estado"), limite)
+	if err != nil {
+		log.Printf("❌ Error listando outbox de WhatsApp: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "Error obteniendo mensajes pendientes",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":  true,
+		"mensajes": mensajes,
+		"total":    len(mensajes),
+	})
+}
+
+// ReintentarOutboxWhatsApp fuerza el envío inmediato de un mensaje pendiente o fallido
+func (h *WhatsAppHandler) ReintentarOutboxWhatsApp(c *gin.Context) {
+	mensajeID, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	mensaje, err := h.whatsapp.ReintentarOutbox(mensajeID, c.GetUint("user_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	message := "Mensaje enviado"
+	if mensaje.Estado != "enviado" {
+		message = "El reintento falló"
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success": mensaje.Estado == "enviado",
+		"message": message,
+		"mensaje": mensaje,
+	})
+}
+
+// statusErrorMensaje distingue una clave inexistente de un texto inválido
+func statusErrorMensaje(err error) int {
+	if errors.Is(err, services.ErrMensajeDesconocido) {
+		return http.StatusNotFound
+	}
+	return http.StatusBadRequest
+}
+
+// GetHorarios muestra el horario semanal de una sucursal (?sucursal=, por defecto la configurada)
+// y si en este momento está abierta
+func (h *WhatsAppHandler) GetHorarios(c *gin.Context) {
+	sucursal := c.Query("sucursal")
+	horarios, err := h.horarios.GetHorarios(sucursal)
+	if err != nil {
+		log.Printf("❌ Error obteniendo horarios: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "Error obteniendo horarios",
+		})
+		return
+	}
+
+	estado, err := h.horarios.Estado(sucursal, time.Now())
+	if err != nil {
+		log.Printf("⚠️  Error calculando estado de atención: %v", err)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":  true,
+		"horarios": horarios,
+		"estado":   estado,
+	})
+}
+
+// GuardarHorarios reemplaza el horario semanal de una sucursal
+func (h *WhatsAppHandler) GuardarHorarios(c *gin.Context) {
+	var req models.GuardarHorariosRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Datos de horarios inválidos",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	horarios, err := h.horarios.GuardarHorarios(c.Query("sucursal"), req, c.GetUint("user_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":  true,
+		"message":  "Horarios actualizados",
+		"horarios": horarios,
+	})
+}
+
+// ListarFeriados lista los próximos feriados de una sucursal
+func (h *WhatsAppHandler) ListarFeriados(c *gin.Context) {
+	feriados, err := h.horarios.ListarFeriados(c.Query("sucursal"))
+	if err != nil {
+		log.Printf("❌ Error listando feriados: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "Error obteniendo feriados",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":  true,
+		"feriados": feriados,
+	})
+}
+
+// CrearFeriado carga un feriado para una sucursal o para todas
+func (h *WhatsAppHandler) CrearFeriado(c *gin.Context) {
+	var req models.CrearFeriadoRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Datos de feriado inválidos",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	feriado, err := h.horarios.CrearFeriado(req, c.GetUint("user_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"success": true,
+		"message": "Feriado creado",
+		"feriado": feriado,
+	})
+}
+
+// EliminarFeriado borra un feriado
+func (h *WhatsAppHandler) EliminarFeriado(c *gin.Context) {
+	feriadoID, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	if err := h.horarios.EliminarFeriado(feriadoID); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Feriado eliminado",
+	})
+}
@@ -0,0 +1,154 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"CheeseHouse/internal/models"
+	"CheeseHouse/internal/services"
+)
+
+// archivoScriptWidget código del juego embebible; se sirve envuelto con la configuración
+// de cada widget
+const archivoScriptWidget = "./Front/widget/embed.js"
+
+// WidgetHandler maneja el snippet del juego embebible y su administración
+type WidgetHandler struct {
+	widgetService *services.WidgetService
+	gameService   *services.GameService
+}
+
+// NewWidgetHandler crea una nueva instancia del handler de widgets
+func NewWidgetHandler(widgetService *services.WidgetService, gameService *services.GameService) *WidgetHandler {
+	return &WidgetHandler{
+		widgetService: widgetService,
+		gameService:   gameService,
+	}
+}
+
+// GetScript sirve el JS que dibuja el juego dentro del sitio externo. Los <script> no
+// envían Origin, así que acá solo se valida la clave; la API del widget valida el origen
+func (h *WidgetHandler) GetScript(c *gin.Context) {
+	widget, err := h.widgetService.BuscarActivo(c.Param("clave"))
+	if err != nil {
+		if !errors.Is(err, services.ErrWidgetNoAutorizado) {
+			log.Printf("❌ Error buscando widget: %v", err)
+		}
+		c.Data(http.StatusNotFound, "application/javascript; charset=utf-8",
+			[]byte("console.warn(\"CheeseHouse: widget no encontrado o desactivado\");\n"))
+		return
+	}
+
+	codigo, err := os.ReadFile(archivoScriptWidget)
+	if err != nil {
+		log.Printf("❌ Error leyendo script del widget: %v", err)
+		c.Status(http.StatusInternalServerError)
+		return
+	}
+
+	juego := h.gameService.GetConfiguracionJuego()
+	configuracion, _ := json.Marshal(gin.H{
+		"api":         h.widgetService.URLBase(widget),
+		"restaurante": juego.Restaurante,
+		"idioma":      juego.IdiomaPorDefecto,
+	})
+
+	script := "(function (WIDGET) {\n" + string(codigo) + "\n})(" + string(configuracion) + ");\n"
+	c.Header("Cache-Control", "public, max-age=300")
+	c.Data(http.StatusOK, "application/javascript; charset=utf-8", []byte(script))
+}
+
+// ListarWidgets lista los sitios autorizados con sus partidas (?dias=30 por defecto)
+func (h *WidgetHandler) ListarWidgets(c *gin.Context) {
+	dias, err := strconv.Atoi(c.DefaultQuery("dias", "30"))
+	if err != nil || dias < 1 || dias > 365 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "dias debe estar entre 1 y 365",
+		})
+		return
+	}
+
+	widgets, err := h.widgetService.Listar(dias)
+	if err != nil {
+		log.Printf("❌ Error listando widgets: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "Error obteniendo widgets",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"dias":    dias,
+		"widgets": widgets,
+	})
+}
+
+// CrearWidget autoriza un sitio externo a embeber el juego
+func (h *WidgetHandler) CrearWidget(c *gin.Context) {
+	var req models.CrearWidgetRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Datos del widget inválidos",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	widget, err := h.widgetService.Crear(req, c.GetUint("user_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"success": true,
+		"message": "Widget creado",
+		"widget":  widget,
+	})
+}
+
+// ActualizarWidget cambia el nombre, los orígenes o activa/desactiva un widget
+func (h *WidgetHandler) ActualizarWidget(c *gin.Context) {
+	widgetID, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	var req models.ActualizarWidgetRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Datos del widget inválidos",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	widget, err := h.widgetService.Actualizar(widgetID, req, c.GetUint("user_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Widget actualizado",
+		"widget":  widget,
+	})
+}
@@ -0,0 +1,185 @@
+// Package i18n contiene los textos localizados de la página pública del juego
+package i18n
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// LocalePorDefecto se usa cuando no se puede detectar un idioma soportado
+const LocalePorDefecto = "es"
+
+// textos traducciones de la página del juego por locale
+var textos = map[string]map[string]string{
+	"es": {
+		"titulo":              "Juego del Timing",
+		"detene_en":           "Detén el cronómetro en",
+		"segundos":            "segundos",
+		"iniciar":             "Iniciar",
+		"detener":             "Detener",
+		"ganaste":             "¡GANASTE!",
+		"perdiste":            "¡PERDISTE!",
+		"tu_tiempo":           "Tu tiempo",
+		"objetivo":            "Objetivo",
+		"descuento":           "¡Descuento del {descuento}%!",
+		"form_titulo":         "¡Ingresa tus datos para recibir tu descuento!",
+		"nombre":              "Nombre",
+		"apellido":            "Apellido",
+		"telefono":            "Teléfono",
+		"enviar":              "Enviar Datos",
+		"enviando":            "Enviando...",
+		"exito_titulo":        "¡Datos enviados exitosamente!",
+		"exito_detalle":       "Recibirás tu descuento por WhatsApp pronto.",
+		"gracias":             "¡Gracias por jugar!",
+		"error_titulo":        "Error al enviar datos",
+		"error_detalle":       "Por favor intenta nuevamente.",
+		"error_contacto":      "Si el problema persiste, contacta al personal.",
+		"cerrar":              "Cerrar",
+		"idioma_espanol":      "Español",
+		"idioma_ingles":       "English",
+		"muro_consentimiento": "Mostrar mi nombre en el muro de ganadores (solo nombre e inicial del apellido)",
+		"muro_titulo":         "Últimos ganadores",
+		"muro_ganador":        "{nombre} — a {diferencia}s del objetivo",
+		"tarjeta_gane":        "¡Clavé {tiempo}\u00a0s en {restaurante}!",
+		"tarjeta_casi":        "¡Frené en {tiempo}\u00a0s en {restaurante}!",
+		"tarjeta_objetivo":    "Objetivo: {objetivo}\u00a0s",
+		"tarjeta_invitacion":  "¿Te animás a superarme?",
+		"compartir":           "Compartir mi resultado",
+		"flash_vence_hoy":     "hoy hasta las {hora}",
+		"flash_cuenta":        "⚡ Voucher flash: vence en {tiempo}",
+		"codigo_titulo":       "Tu código de descuento",
+		"codigo_nota":         "WhatsApp no está respondiendo: sacale una foto a este código o mostralo en caja. Si el mensaje llega más tarde, es el mismo código.",
+		"sin_whatsapp":        "No uso WhatsApp: mostrame el voucher en pantalla",
+		"email":               "Email (opcional)",
+		"email_invalido":      "Por favor ingresa un email válido",
+		"pantalla_pedido":     "Para ver tu voucher, ingresá los últimos 4 dígitos de tu teléfono",
+		"pantalla_ver":        "Ver mi voucher",
+		"pantalla_nota":       "Sacale una foto o mostrá este código en caja. Vence {vencimiento}.",
+	},
+	"en": {
+		"titulo":              "Timing Game",
+		"detene_en":           "Stop the timer at",
+		"segundos":            "seconds",
+		"iniciar":             "Start",
+		"detener":             "Stop",
+		"ganaste":             "YOU WON!",
+		"perdiste":            "YOU LOST!",
+		"tu_tiempo":           "Your time",
+		"objetivo":            "Target",
+		"descuento":           "{descuento}% off!",
+		"form_titulo":         "Enter your details to get your discount!",
+		"nombre":              "First name",
+		"apellido":            "Last name",
+		"telefono":            "Phone",
+		"enviar":              "Send",
+		"enviando":            "Sending...",
+		"exito_titulo":        "Details sent successfully!",
+		"exito_detalle":       "You'll get your discount on WhatsApp shortly.",
+		"gracias":             "Thanks for playing!",
+		"error_titulo":        "Couldn't send your details",
+		"error_detalle":       "Please try again.",
+		"error_contacto":      "If the problem persists, ask our staff.",
+		"cerrar":              "Close",
+		"idioma_espanol":      "Español",
+		"idioma_ingles":       "English",
+		"muro_consentimiento": "Show my name on the winners wall (first name and last initial only)",
+		"muro_titulo":         "Recent winners",
+		"muro_ganador":        "{nombre} — {diferencia}s off",
+		"tarjeta_gane":        "I nailed {tiempo}\u00a0s at {restaurante}!",
+		"tarjeta_casi":        "I stopped at {tiempo}\u00a0s at {restaurante}!",
+		"tarjeta_objetivo":    "Target: {objetivo}\u00a0s",
+		"tarjeta_invitacion":  "Think you can beat me?",
+		"compartir":           "Share my result",
+		"flash_vence_hoy":     "today until {hora}",
+		"flash_cuenta":        "⚡ Flash voucher: expires in {tiempo}",
+		"codigo_titulo":       "Your discount code",
+		"codigo_nota":         "WhatsApp isn't responding: take a photo of this code or show it at the counter. If the message arrives later, it's the same code.",
+		"sin_whatsapp":        "I don't use WhatsApp: show my voucher on screen",
+		"email":               "Email (optional)",
+		"email_invalido":      "Please enter a valid email",
+		"pantalla_pedido":     "To see your voucher, enter the last 4 digits of your phone number",
+		"pantalla_ver":        "Show my voucher",
+		"pantalla_nota":       "Take a photo or show this code at the counter. Expires {vencimiento}.",
+	},
+}
+
+// Soportado indica si hay traducciones para el locale
+func Soportado(locale string) bool {
+	_, ok := textos[normalizar(locale)]
+	return ok
+}
+
+// Locales retorna los locales disponibles ordenados
+func Locales() []string {
+	locales := make([]string, 0, len(textos))
+	for locale := range textos {
+		locales = append(locales, locale)
+	}
+	sort.Strings(locales)
+	return locales
+}
+
+// Textos retorna una copia de las traducciones del locale (o del locale por defecto)
+func Textos(locale string) map[string]string {
+	origen, ok := textos[normalizar(locale)]
+	if !ok {
+		origen = textos[LocalePorDefecto]
+	}
+
+	copia := make(map[string]string, len(origen))
+	for clave, valor := range origen {
+		copia[clave] = valor
+	}
+	return copia
+}
+
+// Detectar elige el locale a partir del parámetro explícito (ej. ?lang=en) o,
+// si no es válido, del header Accept-Language respetando sus pesos q
+func Detectar(parametro, acceptLanguage, defecto string) string {
+	if Soportado(parametro) {
+		return normalizar(parametro)
+	}
+
+	type candidato struct {
+		locale string
+		peso   float64
+	}
+	var candidatos []candidato
+	for _, parte := range strings.Split(acceptLanguage, ",") {
+		campos := strings.Split(strings.TrimSpace(parte), ";")
+		locale := normalizar(campos[0])
+		if !Soportado(locale) {
+			continue
+		}
+
+		peso := 1.0
+		for _, campo := range campos[1:] {
+			if q, ok := strings.CutPrefix(strings.TrimSpace(campo), "q="); ok {
+				if valor, err := strconv.ParseFloat(q, 64); err == nil {
+					peso = valor
+				}
+			}
+		}
+		candidatos = append(candidatos, candidato{locale, peso})
+	}
+
+	if len(candidatos) > 0 {
+		sort.SliceStable(candidatos, func(i, j int) bool { return candidatos[i].peso > candidatos[j].peso })
+		return candidatos[0].locale
+	}
+
+	if Soportado(defecto) {
+		return normalizar(defecto)
+	}
+	return LocalePorDefecto
+}
+
+// normalizar reduce "en-US" o "EN_us" al idioma base "en"
+func normalizar(locale string) string {
+	locale = strings.ToLower(strings.TrimSpace(locale))
+	if i := strings.IndexAny(locale, "-_"); i >= 0 {
+		locale = locale[:i]
+	}
+	return locale
+}
@@ -0,0 +1,149 @@
+// Package logging adapta la salida del paquete log (y de gin) al formato elegido con
+// LOG_FORMAT. En "pretty" no se toca nada: emojis y colores para desarrollo local. En
+// "plain" (clave=valor) y "json" cada mensaje se limpia de emojis y códigos de color,
+// el emoji inicial se traduce a nivel y los segmentos "Clave: valor" separados por " | "
+// pasan a ser campos, para que los agregadores de logs y grep los procesen sin sorpresas.
+package logging
+
+import (
+	"context"
+	"io"
+	"log"
+	"log/slog"
+	"os"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// Formatos de LOG_FORMAT
+const (
+	FormatoPretty = "pretty"
+	FormatoPlain  = "plain"
+	FormatoJSON   = "json"
+)
+
+// nivelesPorEmoji nivel de los mensajes según el emoji con el que empiezan; el resto es info
+var nivelesPorEmoji = map[rune]slog.Level{
+	'❌': slog.LevelError,
+	'⚠': slog.LevelWarn,
+	'🚨': slog.LevelWarn,
+	'⛔': slog.LevelWarn,
+	'🔒': slog.LevelWarn,
+	'⚡': slog.LevelWarn,
+}
+
+var (
+	patronColor    = regexp.MustCompile(`\x1b\[[0-9;]*m`)
+	patronEspacios = regexp.MustCompile(`[ \t]{2,}`)
+)
+
+// Escritor recibe cada mensaje ya formateado (una llamada a Write por mensaje, como
+// hacen log y gin) y lo vuelve a escribir como registro estructurado
+type Escritor struct {
+	salida *slog.Logger
+}
+
+// NuevoEscritor crea un escritor que emite registros en texto clave=valor o JSON
+func NuevoEscritor(formato string, destino io.Writer) *Escritor {
+	opciones := &slog.HandlerOptions{Level: slog.LevelDebug}
+	var handler slog.Handler = slog.NewTextHandler(destino, opciones)
+	if formato == FormatoJSON {
+		handler = slog.NewJSONHandler(destino, opciones)
+	}
+	return &Escritor{salida: slog.New(handler)}
+}
+
+// Configurar redirige el paquete log según el formato. Retorna el escritor para usar
+// también en gin, o nil en modo pretty (se mantiene la salida de siempre)
+func Configurar(formato string) io.Writer {
+	if formato != FormatoPlain && formato != FormatoJSON {
+		return nil
+	}
+
+	escritor := NuevoEscritor(formato, os.Stdout)
+	log.SetFlags(0) // La fecha la agrega el registro estructurado
+	log.SetOutput(escritor)
+	return escritor
+}
+
+// Write convierte un mensaje en un registro con nivel, texto limpio y campos
+func (e *Escritor) Write(p []byte) (int, error) {
+	mensaje := strings.TrimRight(string(p), "\n")
+	if strings.TrimSpace(mensaje) == "" {
+		return len(p), nil
+	}
+
+	nivel := nivelDe(mensaje)
+	texto, campos := separarCampos(Limpiar(mensaje))
+	e.salida.Log(context.Background(), nivel, texto, campos...)
+	return len(p), nil
+}
+
+// nivelDe toma el nivel del emoji con el que empieza el mensaje
+func nivelDe(mensaje string) slog.Level {
+	primera, _ := utf8.DecodeRuneInString(strings.TrimSpace(patronColor.ReplaceAllString(mensaje, "")))
+	if nivel, ok := nivelesPorEmoji[primera]; ok {
+		return nivel
+	}
+	return slog.LevelInfo
+}
+
+// Limpiar quita emojis, símbolos decorativos y códigos de color, y junta los espacios
+// que quedan sueltos
+func Limpiar(mensaje string) string {
+	mensaje = patronColor.ReplaceAllString(mensaje, "")
+	mensaje = strings.Map(func(r rune) rune {
+		if decorativo(r) {
+			return -1
+		}
+		return r
+	}, mensaje)
+
+	lineas := strings.Split(mensaje, "\n")
+	for i, linea := range lineas {
+		lineas[i] = strings.TrimSpace(patronEspacios.ReplaceAllString(linea, " "))
+	}
+	return strings.TrimSpace(strings.Join(lineas, "\n"))
+}
+
+// decorativo indica si la runa es un emoji o símbolo gráfico (flechas, dingbats,
+// pictogramas) o uno de los modificadores que los acompañan
+func decorativo(r rune) bool {
+	switch {
+	case r == 0xFE0F || r == 0xFE0E || r == 0x200D || r == 0x20E3:
+		return true
+	case r >= 0x2190 && r <= 0x21FF: // Flechas
+		return true
+	case r >= 0x2300 && r <= 0x2BFF: // Técnicos, símbolos varios, dingbats
+		return true
+	case r >= 0x1F000 && r <= 0x1FAFF: // Emojis
+		return true
+	}
+	return false
+}
+
+// separarCampos toma "TEXTO | Clave: valor | Otra clave: valor" y devuelve el texto y
+// los campos (clave_en_snake_case=valor). Los segmentos sin "Clave:" quedan en el texto
+func separarCampos(mensaje string) (string, []any) {
+	segmentos := strings.Split(mensaje, " | ")
+	if len(segmentos) == 1 {
+		return mensaje, nil
+	}
+
+	texto := []string{segmentos[0]}
+	var campos []any
+	for _, segmento := range segmentos[1:] {
+		clave, valor, ok := strings.Cut(segmento, ": ")
+		if !ok || clave == "" || strings.ContainsAny(clave, ",.") {
+			texto = append(texto, segmento)
+			continue
+		}
+		clave = strings.ReplaceAll(strings.ToLower(strings.TrimSpace(clave)), " ", "_")
+		if clave == slog.TimeKey || clave == slog.LevelKey || clave == slog.MessageKey {
+			clave = "detalle_" + clave // No pisar las claves del registro ("Time:" de los middlewares)
+		}
+		campos = append(campos, slog.String(clave, strings.TrimSpace(valor)))
+	}
+	return strings.Join(texto, " | "), campos
+}
@@ -1,6 +1,7 @@
 package models
 
 import (
+	"fmt"
 	"time"
 )
 
@@ -12,6 +13,86 @@ type Rol struct {
 	CreatedAt time.Time `json:"created_at"`
 }
 
+// Nombres de los permisos que se pueden dar a un rol
+const (
+	PermisoTodo           = "todo" // Todos los permisos
+	PermisoCanjear        = "canjear"
+	PermisoPedidos        = "pedidos"
+	PermisoClientes       = "clientes"
+	PermisoCampanas       = "campanas"
+	PermisoReportes       = "reportes"
+	PermisoConversaciones = "conversaciones"
+	PermisoConfiguracion  = "configuracion"
+	PermisoUsuarios       = "usuarios" // Usuarios y roles
+)
+
+// CatalogoPermisos permisos disponibles con su descripción para el panel
+var CatalogoPermisos = map[string]string{
+	PermisoTodo:           "Acceso completo, incluidos los permisos que se agreguen después",
+	PermisoCanjear:        "Canjear vouchers en caja",
+	PermisoPedidos:        "Ver y avanzar pedidos",
+	PermisoClientes:       "Ver y gestionar clientes",
+	PermisoCampanas:       "Crear y enviar campañas",
+	PermisoReportes:       "Ver reportes y estadísticas",
+	PermisoConversaciones: "Atender la bandeja de WhatsApp",
+	PermisoConfiguracion:  "Cambiar la configuración del local",
+	PermisoUsuarios:       "Gestionar usuarios y roles",
+}
+
+// Permisos contenido de Rol.Permisos ya interpretado
+type Permisos struct {
+	Todo           bool `json:"todo,omitempty"`
+	Canjear        bool `json:"canjear,omitempty"`
+	Pedidos        bool `json:"pedidos,omitempty"`
+	Clientes       bool `json:"clientes,omitempty"`
+	Campanas       bool `json:"campanas,omitempty"`
+	Reportes       bool `json:"reportes,omitempty"`
+	Conversaciones bool `json:"conversaciones,omitempty"`
+	Configuracion  bool `json:"configuracion,omitempty"`
+	Usuarios       bool `json:"usuarios,omitempty"`
+}
+
+// Tiene indica si los permisos incluyen el indicado ("todo" incluye cualquiera)
+func (p Permisos) Tiene(permiso string) bool {
+	if p.Todo {
+		return true
+	}
+	switch permiso {
+	case PermisoCanjear:
+		return p.Canjear
+	case PermisoPedidos:
+		return p.Pedidos
+	case PermisoClientes:
+		return p.Clientes
+	case PermisoCampanas:
+		return p.Campanas
+	case PermisoReportes:
+		return p.Reportes
+	case PermisoConversaciones:
+		return p.Conversaciones
+	case PermisoConfiguracion:
+		return p.Configuracion
+	case PermisoUsuarios:
+		return p.Usuarios
+	}
+	return false
+}
+
+// RolRequest alta o edición de un rol desde el panel
+type RolRequest struct {
+	Nombre   string   `json:"nombre" binding:"required,max=50"`
+	Permisos Permisos `json:"permisos"`
+}
+
+// RolConPermisos rol con los permisos interpretados y cuántos usuarios lo tienen
+type RolConPermisos struct {
+	ID        uint      `json:"id"`
+	Nombre    string    `json:"nombre"`
+	Permisos  Permisos  `json:"permisos"`
+	Usuarios  int       `json:"usuarios"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
 // Usuario representa empleados y administradores de CheeseHouse
 type Usuario struct {
 	ID           uint      `gorm:"primaryKey" json:"id"`
@@ -19,6 +100,9 @@ type Usuario struct {
 	Email        string    `gorm:"unique;size:255;not null" json:"email"`
 	PasswordHash string    `gorm:"size:255;not null" json:"-"` // No incluir en JSON
 	RolID        uint      `gorm:"not null" json:"rol_id"`
+	Sucursal     string    `gorm:"size:100" json:"sucursal,omitempty"` // Donde trabaja; vacío = la de LOCATION
+	LimiteCanjes *int      `json:"limite_canjes_diario"`               // Canjes por día; nil = REDEMPTION_DAILY_CAP, 0 = sin límite
+	PINHash      string    `gorm:"size:255" json:"-"`                  // PIN de encargado para aprobar canjes de descuento alto
 	Activo       bool      `gorm:"default:true" json:"activo"`
 	CreatedAt    time.Time `json:"created_at"`
 	UpdatedAt    time.Time `json:"updated_at"`
@@ -32,7 +116,9 @@ type Cliente struct {
 	ID               uint       `gorm:"primaryKey" json:"id"`
 	Nombre           string     `gorm:"size:100;not null" json:"nombre"`
 	Apellido         string     `gorm:"size:100;not null" json:"apellido"`
-	Telefono         string     `gorm:"unique;size:20;not null" json:"telefono"` // +5491112345678
+	Telefono         string     `gorm:"unique;size:20;not null" json:"telefono"`     // +5491112345678
+	Idioma           string     `gorm:"size:5;default:'es'" json:"idioma"`           // 'es', 'en'
+	Email            *string    `gorm:"uniqueIndex;size:255" json:"email,omitempty"` // Opcional, en minúsculas; segunda clave para deduplicar
 	FechaRegistro    time.Time  `gorm:"default:CURRENT_TIMESTAMP" json:"fecha_registro"`
 	FechaUltimoJuego *time.Time `json:"fecha_ultimo_juego,omitempty"` // NULL si nunca jugó
 	TotalJuegos      int        `gorm:"default:0" json:"total_juegos"`
@@ -42,29 +128,144 @@ type Cliente struct {
 	CreatedAt        time.Time  `json:"created_at"`
 	UpdatedAt        time.Time  `json:"updated_at"`
 
+	// Clasificación por actividad: partidas ponderadas por antigüedad (ver TipoClienteService)
+	TipoCliente        string     `gorm:"type:enum('nuevo','ocasional','frecuente');default:'nuevo';index" json:"tipo_cliente"`
+	PuntajeActividad   float64    `gorm:"type:decimal(10,3);default:0" json:"puntaje_actividad"`
+	PuntajeCalculadoEn *time.Time `json:"puntaje_calculado_en,omitempty"`
+
+	// Consentimiento para aparecer en el muro de ganadores (solo nombre e inicial del apellido)
+	ConsentimientoMuro   bool       `gorm:"default:false" json:"consentimiento_muro"`
+	ConsentimientoMuroEn *time.Time `json:"consentimiento_muro_en,omitempty"` // Última vez que eligió
+
+	// Aprobación de un empleado para seguir jugando pasado GAMES_REQUIRE_APPROVAL; vale
+	// por el día en que se dio
+	AprobadoEl    *time.Time `json:"aprobado_el,omitempty"`
+	AprobadoPorID *uint      `json:"aprobado_por_id,omitempty"`
+
 	// Relaciones
 	Vouchers []Voucher `gorm:"foreignKey:ClienteID" json:"vouchers,omitempty"`
+	Juegos   []Juego   `gorm:"foreignKey:ClienteID" json:"juegos,omitempty"`
+}
+
+// Tipos de cliente según su puntaje de actividad
+const (
+	TipoClienteNuevo     = "nuevo"
+	TipoClienteOcasional = "ocasional"
+	TipoClienteFrecuente = "frecuente"
+)
+
+// ResultadoRecalculoTipos resumen de una recalculación de tipos de cliente
+type ResultadoRecalculoTipos struct {
+	Clientes int            `json:"clientes"`
+	Cambios  int            `json:"cambios"`
+	PorTipo  map[string]int `json:"por_tipo"`
+	Duracion string         `json:"duracion"`
+}
+
+// PreferenciasComunicacion canal y categorías de mensajes que acepta un cliente
+type PreferenciasComunicacion struct {
+	ID            uint      `gorm:"primaryKey" json:"id"`
+	ClienteID     uint      `gorm:"uniqueIndex;not null" json:"cliente_id"`
+	Canal         string    `gorm:"type:enum('whatsapp','sms','email','ninguno');default:'whatsapp'" json:"canal"`
+	Transaccional bool      `gorm:"not null" json:"transaccional"` // Vouchers del juego, avisos de pedidos; siempre true
+	Marketing     bool      `gorm:"not null" json:"marketing"`     // Campañas, recordatorios, win-back
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
+
+// ActualizarPreferenciasRequest request del centro de preferencias
+type ActualizarPreferenciasRequest struct {
+	Token         string `json:"token" binding:"required"`
+	Canal         string `json:"canal" binding:"required,oneof=whatsapp sms email ninguno"`
+	Transaccional *bool  `json:"transaccional"` // Ignorado: los transaccionales se envían siempre
+	Marketing     *bool  `json:"marketing" binding:"required"`
+}
+
+// ContactoMarketing cliente que acepta marketing, con sus datos de contacto para exportar
+type ContactoMarketing struct {
+	ClienteID uint    `json:"cliente_id"`
+	Nombre    string  `json:"nombre"`
+	Apellido  string  `json:"apellido"`
+	Telefono  string  `json:"telefono"`
+	Email     *string `json:"email,omitempty"`
+	Idioma    string  `json:"idioma"`
+	Canal     string  `json:"canal"` // Canal preferido (whatsapp si no eligió)
+}
+
+// BajaMarketing registro de una baja de marketing hecha desde el link de una campaña
+type BajaMarketing struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	ClienteID uint      `gorm:"not null;index" json:"cliente_id"`
+	CampanaID *uint     `gorm:"index" json:"campana_id,omitempty"` // NULL si no vino de una campaña
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// BajaMarketingRequest request de la página de baja en un click
+type BajaMarketingRequest struct {
+	Token     string `json:"token" binding:"required"`
+	CampanaID uint   `json:"campana_id"`
 }
 
 // Voucher representa cupones de descuento de CheeseHouse
 type Voucher struct {
-	ID               uint       `gorm:"primaryKey" json:"id"`
-	Codigo           string     `gorm:"unique;size:20;not null" json:"codigo"` // CH12345678
-	ClienteID        uint       `gorm:"not null" json:"cliente_id"`
-	Tipo             string     `gorm:"type:enum('juego_ganado','juego_perdido','cliente_promocion');not null" json:"tipo"`
-	Descuento        int        `gorm:"not null" json:"descuento"` // Porcentaje 1-100
-	Ganado           *bool      `json:"ganado,omitempty"`          // NULL para promociones, true/false para juegos
-	FechaEmision     time.Time  `gorm:"default:CURRENT_TIMESTAMP" json:"fecha_emision"`
-	FechaVencimiento time.Time  `gorm:"not null" json:"fecha_vencimiento"`
-	FechaUso         *time.Time `json:"fecha_uso,omitempty"`
-	Usado            bool       `gorm:"default:false" json:"usado"`
-	UsuarioCanje     *uint      `json:"usuario_canje,omitempty"` // ID del empleado que procesó el canje
-	Notas            string     `gorm:"type:text" json:"notas,omitempty"`
-	CreatedAt        time.Time  `json:"created_at"`
+	ID                 uint       `gorm:"primaryKey" json:"id"`
+	Codigo             string     `gorm:"unique;size:20;not null" json:"codigo"` // CH12345678
+	ClienteID          uint       `gorm:"not null" json:"cliente_id"`
+	Tipo               string     `gorm:"type:enum('juego_ganado','juego_perdido','cliente_promocion');not null" json:"tipo"`
+	Descuento          int        `gorm:"not null" json:"descuento"` // Porcentaje 1-100
+	Ganado             *bool      `json:"ganado,omitempty"`          // NULL para promociones, true/false para juegos
+	FechaEmision       time.Time  `gorm:"default:CURRENT_TIMESTAMP" json:"fecha_emision"`
+	FechaVencimiento   time.Time  `gorm:"not null" json:"fecha_vencimiento"`
+	Flash              bool       `gorm:"not null;default:false;index" json:"flash"` // Válido solo unas horas desde la emisión
+	FechaUso           *time.Time `json:"fecha_uso,omitempty"`
+	Usado              bool       `gorm:"default:false" json:"usado"`
+	UsuarioCanje       *uint      `json:"usuario_canje,omitempty"` // ID del empleado que procesó el canje
+	Notas              string     `gorm:"type:text" json:"notas,omitempty"`
+	IPOrigen           string     `gorm:"size:45;index" json:"ip_origen,omitempty"`               // IP desde la que se jugó
+	HuellaDispositivo  string     `gorm:"size:64;index" json:"huella_dispositivo,omitempty"`      // SHA-256 de la huella del navegador
+	DiferenciaSegundos *float64   `gorm:"type:decimal(6,3)" json:"diferencia_segundos,omitempty"` // Distancia al objetivo, solo en partidas
+	WidgetID           *uint      `gorm:"index" json:"widget_id,omitempty"`                       // Sitio externo donde se jugó; NULL = página propia
+	MesaID             *uint      `gorm:"index" json:"mesa_id,omitempty"`                         // Mesa del QR escaneado, si se jugó en el local
+	ReglaID            *uint      `gorm:"index" json:"regla_id,omitempty"`                        // Regla de recomendación que eligió el alcance
+	Alcance            string     `gorm:"size:100" json:"alcance,omitempty"`                      // Productos a los que aplica; vacío = todo el menú
+	Recomendacion      string     `gorm:"size:300" json:"recomendacion,omitempty"`                // Sugerencia para el cliente al canjear
+	Sucursal           string     `gorm:"size:100;index" json:"sucursal,omitempty"`               // Emisora, o la de destino si se transfirió
+	SoloSucursal       bool       `gorm:"not null;default:false" json:"solo_sucursal"`            // false = se canjea en toda la cadena
+	SucursalCanje      string     `gorm:"size:100" json:"sucursal_canje,omitempty"`               // Dónde se canjeó
+	AprobadoPor        *uint      `json:"aprobado_por,omitempty"`                                 // Encargado que aprobó con su PIN un canje de descuento alto
+	CreatedAt          time.Time  `json:"created_at"`
 
 	// Relaciones
 	Cliente         *Cliente `gorm:"foreignKey:ClienteID" json:"cliente,omitempty"`
 	UsuarioQueCanje *Usuario `gorm:"foreignKey:UsuarioCanje" json:"usuario_que_canje,omitempty"`
+	Aprobador       *Usuario `gorm:"foreignKey:AprobadoPor" json:"aprobador,omitempty"`
+	Mesa            *Mesa    `gorm:"foreignKey:MesaID" json:"mesa,omitempty"`
+}
+
+// Juego registra cada intento del juego de timing de un cliente identificado, haya o no
+// recibido voucher (espera tras perder, aprobación pendiente, emisión pausada)
+type Juego struct {
+	ID             uint      `gorm:"primaryKey" json:"id"`
+	ClienteID      uint      `gorm:"not null;index" json:"cliente_id"`
+	TiempoObjetivo float64   `gorm:"type:decimal(5,2);not null" json:"tiempo_objetivo"`
+	TiempoObtenido float64   `gorm:"type:decimal(6,3);not null" json:"tiempo_obtenido"`
+	Gano           bool      `gorm:"not null" json:"gano"`
+	VoucherID      *uint     `gorm:"index" json:"voucher_id,omitempty"` // NULL = el intento no generó voucher
+	IP             string    `gorm:"size:45;index" json:"ip,omitempty"`
+	PremioRetenido bool      `gorm:"default:false;index" json:"premio_retenido,omitempty"` // Ganó en un pico de emisión: el voucher sale cuando un empleado lo libera
+	Rechazado      bool      `gorm:"default:false;index" json:"rechazado,omitempty"`       // Intento frenado (espera, tope, aprobación, emisión pausada) o premio descartado: no cuenta como partida
+	CreatedAt      time.Time `gorm:"index" json:"created_at"`
+}
+
+// PremioRetenido partida ganada durante un pico de emisión que espera a un empleado
+type PremioRetenido struct {
+	JuegoID        uint      `json:"juego_id"`
+	ClienteID      uint      `json:"cliente_id"`
+	Nombre         string    `json:"nombre"`
+	Telefono       string    `json:"telefono"`
+	TiempoObjetivo float64   `json:"tiempo_objetivo"`
+	TiempoObtenido float64   `json:"tiempo_obtenido"`
+	JugadoEn       time.Time `json:"jugado_en"`
 }
 
 // CampanaClientesVouchers representa campañas promocionales
@@ -85,17 +286,81 @@ type CampanaClientesVouchers struct {
 	Envios    []ClientesVouchersEnvios `gorm:"foreignKey:CampanaID" json:"envios,omitempty"`
 }
 
+// EstimacionCostoCampana costo proyectado de una campaña y, una vez enviada, el costo real
+type EstimacionCostoCampana struct {
+	ID        uint   `gorm:"primaryKey" json:"id"`
+	CampanaID uint   `gorm:"uniqueIndex;not null" json:"campana_id"`
+	Moneda    string `gorm:"size:3;not null" json:"moneda"`
+
+	// Proyección al momento del envío
+	Destinatarios           int     `gorm:"not null" json:"destinatarios"`
+	ConversacionesMarketing int     `gorm:"not null" json:"conversaciones_marketing"` // Destinatarios que aceptan marketing
+	PrecioConversacion      float64 `gorm:"type:decimal(10,4);not null" json:"precio_conversacion"`
+	CostoMensajesEstimado   float64 `gorm:"type:decimal(12,2);not null" json:"costo_mensajes_estimado"`
+	TasaCanjeProyectada     float64 `gorm:"type:decimal(5,4);not null" json:"tasa_canje_proyectada"`
+	CanjesProyectados       float64 `gorm:"type:decimal(10,2);not null" json:"canjes_proyectados"`
+	CostoDescuentosEstimado float64 `gorm:"type:decimal(12,2);not null" json:"costo_descuentos_estimado"`
+	CostoTotalEstimado      float64 `gorm:"type:decimal(12,2);not null" json:"costo_total_estimado"`
+
+	// Resultado real (se recalcula al consultar la campaña)
+	ConversacionesReales int        `gorm:"default:0" json:"conversaciones_reales"`
+	CanjesReales         int        `gorm:"default:0" json:"canjes_reales"`
+	CostoMensajesReal    float64    `gorm:"type:decimal(12,2);default:0" json:"costo_mensajes_real"`
+	CostoDescuentosReal  float64    `gorm:"type:decimal(12,2);default:0" json:"costo_descuentos_real"`
+	CostoTotalReal       float64    `gorm:"type:decimal(12,2);default:0" json:"costo_total_real"`
+	ConciliadoAt         *time.Time `json:"conciliado_at,omitempty"`
+
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// EstimarCampanaRequest request para previsualizar el costo de una campaña
+type EstimarCampanaRequest struct {
+	Descuento   int    `json:"descuento" binding:"required,min=1,max=100"`
+	ClientesIDs []uint `json:"clientes_ids" binding:"required,min=1"`
+}
+
+// CrearCampanaRequest request para crear una campaña promocional
+type CrearCampanaRequest struct {
+	Nombre           string `json:"nombre" binding:"required,max=200"`
+	Descripcion      string `json:"descripcion"`
+	Descuento        int    `json:"descuento" binding:"required,min=1,max=100"`
+	FechaVencimiento string `json:"fecha_vencimiento" binding:"required"` // YYYY-MM-DD, vencimiento de los vouchers
+	Mensaje          string `json:"mensaje" binding:"max=1000"`
+}
+
+// EnviarCampanaRequest request para enviar una campaña a clientes seleccionados
+type EnviarCampanaRequest struct {
+	ClientesIDs []uint `json:"clientes_ids" binding:"required,min=1"`
+}
+
+// ResultadoEnvioCampana resumen del lanzamiento de una campaña. Los mensajes salen en
+// segundo plano; el avance se consulta en /api/admin/jobs/:id y el estado de cada envío
+// en el detalle de la campaña
+type ResultadoEnvioCampana struct {
+	CampanaID     uint                    `json:"campana_id"`
+	TrabajoID     string                  `json:"trabajo_id,omitempty"` // Vacío si no hubo destinatarios
+	Seleccionados int                     `json:"seleccionados"`
+	Destinatarios int                     `json:"destinatarios"`      // Con voucher generado y mensaje en cola
+	Omitidos      map[string]int          `json:"omitidos,omitempty"` // Por motivo: no_encontrado, bloqueado, sin_marketing, ya_enviado
+	Estimacion    *EstimacionCostoCampana `json:"estimacion,omitempty"`
+}
+
 // ClientesVouchersEnvios representa envíos de campañas promocionales
 type ClientesVouchersEnvios struct {
-	ID            uint      `gorm:"primaryKey" json:"id"`
-	CampanaID     uint      `gorm:"not null" json:"campana_id"`
-	ClienteID     uint      `gorm:"not null" json:"cliente_id"`
-	VoucherID     *uint     `json:"voucher_id,omitempty"` // NULL hasta que se genere el voucher
-	CodigoVoucher string    `gorm:"size:20" json:"codigo_voucher,omitempty"`
-	EnviadoAt     time.Time `gorm:"default:CURRENT_TIMESTAMP" json:"enviado_at"`
-	Estado        string    `gorm:"type:enum('enviado','entregado','fallido');default:'enviado'" json:"estado"`
-	ErrorMensaje  string    `gorm:"type:text" json:"error_mensaje,omitempty"`
-	IntentosEnvio int       `gorm:"default:1" json:"intentos_envio"`
+	ID            uint       `gorm:"primaryKey" json:"id"`
+	CampanaID     uint       `gorm:"not null" json:"campana_id"` // Único con ClienteID (migración 0003)
+	ClienteID     uint       `gorm:"not null" json:"cliente_id"`
+	VoucherID     *uint      `json:"voucher_id,omitempty"` // NULL hasta que se genere el voucher
+	CodigoVoucher string     `gorm:"size:20" json:"codigo_voucher,omitempty"`
+	EnviadoAt     time.Time  `gorm:"default:CURRENT_TIMESTAMP" json:"enviado_at"`
+	Estado        string     `gorm:"type:enum('pendiente','enviado','entregado','leido','fallido');default:'pendiente'" json:"estado"`
+	ErrorMensaje  string     `gorm:"type:text" json:"error_mensaje,omitempty"`
+	IntentosEnvio int        `gorm:"default:1" json:"intentos_envio"`
+	MessageID     string     `gorm:"size:100;index" json:"message_id,omitempty"` // wamid devuelto por la API
+	OutboxID      *uint      `gorm:"index" json:"outbox_id,omitempty"`           // Mensaje retenido en el outbox por horario silencioso
+	EntregadoAt   *time.Time `json:"entregado_at,omitempty"`                     // Confirmado por el webhook de estados
+	LeidoAt       *time.Time `json:"leido_at,omitempty"`
 
 	// Relaciones
 	Campana *CampanaClientesVouchers `gorm:"foreignKey:CampanaID" json:"campana,omitempty"`
@@ -105,26 +370,159 @@ type ClientesVouchersEnvios struct {
 
 // Pedido representa pedidos recibidos por WhatsApp (futuro)
 type Pedido struct {
-	ID          uint      `gorm:"primaryKey" json:"id"`
-	ClienteID   uint      `gorm:"not null" json:"cliente_id"`
-	Telefono    string    `gorm:"size:20;not null" json:"telefono"` // Por si el cliente no está registrado
-	Mensaje     string    `gorm:"type:text;not null" json:"mensaje"`
-	Estado      string    `gorm:"type:enum('pendiente','procesando','completado','cancelado');default:'pendiente'" json:"estado"`
-	Total       *float64  `json:"total,omitempty"`        // Monto del pedido si se calcula
-	Notas       string    `gorm:"type:text" json:"notas"` // Notas del empleado
-	AtendidoPor *uint     `json:"atendido_por,omitempty"` // ID del empleado que atendió
-	CreatedAt   time.Time `json:"created_at"`
-	UpdatedAt   time.Time `json:"updated_at"`
+	ID            uint       `gorm:"primaryKey" json:"id"`
+	ClienteID     *uint      `gorm:"index" json:"cliente_id,omitempty"` // NULL si el número no es de un cliente registrado
+	Telefono      string     `gorm:"size:20;not null" json:"telefono"`  // Por si el cliente no está registrado
+	Mensaje       string     `gorm:"type:text;not null" json:"mensaje"`
+	Estado        string     `gorm:"type:enum('pendiente','procesando','completado','cancelado');default:'pendiente'" json:"estado"`
+	Total         *float64   `json:"total,omitempty"`                       // Monto del pedido si se calcula
+	Notas         string     `gorm:"type:text" json:"notas"`                // Notas del empleado
+	AtendidoPor   *uint      `json:"atendido_por,omitempty"`                // ID del empleado que atendió
+	PrometidoPara *time.Time `gorm:"index" json:"prometido_para,omitempty"` // Hora comprometida con el cliente
+	CompletadoAt  *time.Time `json:"completado_at,omitempty"`               // Para medir el tiempo de preparación
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+
+	// Calculado para la pantalla de cocina
+	Atrasado bool `gorm:"-" json:"atrasado,omitempty"`
 
 	// Relaciones
-	Cliente         *Cliente `gorm:"foreignKey:ClienteID" json:"cliente,omitempty"`
-	EmpleadoAtiende *Usuario `gorm:"foreignKey:AtendidoPor" json:"empleado_atiende,omitempty"`
+	Cliente         *Cliente     `gorm:"foreignKey:ClienteID" json:"cliente,omitempty"`
+	EmpleadoAtiende *Usuario     `gorm:"foreignKey:AtendidoPor" json:"empleado_atiende,omitempty"`
+	Items           []PedidoItem `gorm:"foreignKey:PedidoID" json:"items,omitempty"`
+}
+
+// PedidoItem línea de un pedido confirmado por el staff
+type PedidoItem struct {
+	ID             uint    `gorm:"primaryKey" json:"id"`
+	PedidoID       uint    `gorm:"not null;index" json:"pedido_id"`
+	ItemMenuID     *uint   `gorm:"index" json:"item_menu_id,omitempty"` // NULL si el item ya no está en el menú
+	Nombre         string  `gorm:"size:100;not null" json:"nombre"`     // Copia del nombre al momento del pedido
+	Cantidad       int     `gorm:"not null" json:"cantidad"`
+	PrecioUnitario float64 `gorm:"type:decimal(10,2);not null" json:"precio_unitario"`
+	Notas          string  `gorm:"size:255" json:"notas,omitempty"`         // ej. "sin cebolla"
+	Preparado      bool    `gorm:"not null;default:false" json:"preparado"` // Marcado desde la pantalla de cocina
+}
+
+// ItemMenu producto del menú que se puede reconocer en los mensajes de pedidos.
+// Alias son otras formas de escribirlo separadas por coma ("cheese, cheeseburger")
+type ItemMenu struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	Nombre    string    `gorm:"unique;size:100;not null" json:"nombre"`
+	Alias     string    `gorm:"type:text" json:"alias,omitempty"`
+	Precio    float64   `gorm:"type:decimal(10,2);not null" json:"precio"`
+	Activo    bool      `gorm:"not null" json:"activo"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// GuardarItemMenuRequest request para crear o editar un item del menú
+type GuardarItemMenuRequest struct {
+	Nombre string   `json:"nombre" binding:"required,min=2,max=100"`
+	Alias  []string `json:"alias" binding:"dive,max=100"`
+	Precio float64  `json:"precio" binding:"min=0"`
+	Activo *bool    `json:"activo"`
+}
+
+// InterpretarPedidoRequest texto a interpretar; con telefono se usan los últimos
+// mensajes sin responder de esa conversación
+type InterpretarPedidoRequest struct {
+	Mensaje  string `json:"mensaje" binding:"required_without=Telefono,max=4096"`
+	Telefono string `json:"telefono"`
+}
+
+// ItemPropuesto item del menú reconocido en el texto de un pedido
+type ItemPropuesto struct {
+	ItemMenuID     uint    `json:"item_menu_id"`
+	Nombre         string  `json:"nombre"`
+	Cantidad       int     `json:"cantidad"`
+	PrecioUnitario float64 `json:"precio_unitario"`
+	Subtotal       float64 `json:"subtotal"`
+	TextoOriginal  string  `json:"texto_original"` // Fragmento del mensaje que coincidió
+}
+
+// PropuestaPedido pedido estructurado que el empleado revisa antes de confirmar
+type PropuestaPedido struct {
+	Mensaje        string           `json:"mensaje"`
+	Items          []*ItemPropuesto `json:"items"`
+	Total          float64          `json:"total"`
+	SinInterpretar []string         `json:"sin_interpretar"` // Partes del mensaje que no coinciden con el menú
+}
+
+// ItemPedidoRequest línea de un pedido al confirmarlo
+type ItemPedidoRequest struct {
+	ItemMenuID uint   `json:"item_menu_id" binding:"required"`
+	Cantidad   int    `json:"cantidad" binding:"required,min=1,max=100"`
+	Notas      string `json:"notas" binding:"max=255"`
+}
+
+// CambiarEstadoPedidoRequest request para mover un pedido de estado.
+// Notificar en false evita el aviso al cliente aunque el estado lo tenga configurado
+type CambiarEstadoPedidoRequest struct {
+	Estado    string `json:"estado" binding:"required,oneof=pendiente procesando completado cancelado"`
+	Notificar *bool  `json:"notificar"`
+}
+
+// AsignarPedidoRequest request para dejar un pedido a cargo de un empleado.
+// Sin usuario_id el pedido queda a cargo de quien hace el pedido
+type AsignarPedidoRequest struct {
+	UsuarioID uint `json:"usuario_id"`
+}
+
+// RepetirPedidoRequest request para repetir un pedido anterior del cliente.
+// Sin pedido_id se repite el último que no fue cancelado
+type RepetirPedidoRequest struct {
+	PedidoID uint   `json:"pedido_id"`
+	Notas    string `json:"notas" binding:"max=1000"`
+}
+
+// ConfirmarPedidoRequest pedido revisado por el empleado
+type ConfirmarPedidoRequest struct {
+	Telefono string              `json:"telefono" binding:"required"`
+	Mensaje  string              `json:"mensaje" binding:"max=4096"` // Texto original del cliente
+	Items    []ItemPedidoRequest `json:"items" binding:"required,min=1,dive"`
+	Notas    string              `json:"notas" binding:"max=1000"`
+
+	// Hora comprometida; si no viene se calcula con ORDER_PREP_MINUTES
+	PrometidoPara *time.Time `json:"prometido_para"`
+}
+
+// MarcarPreparadoRequest toggle de preparación de un item desde la cocina
+type MarcarPreparadoRequest struct {
+	Preparado *bool `json:"preparado" binding:"required"`
+}
+
+// PromedioPreparacion tiempo promedio desde la confirmación hasta que el pedido se
+// completa, por tamaño ('chico' hasta 2 unidades, 'mediano' hasta 5, 'grande')
+type PromedioPreparacion struct {
+	Tamano  string  `json:"tamano"`
+	Pedidos int     `json:"pedidos"`
+	Minutos float64 `json:"minutos"`
+}
+
+// EstimacionEspera espera estimada para un pedido nuevo según el historial y la cola actual
+type EstimacionEspera struct {
+	MinutosEstimados int                    `json:"minutos_estimados"`
+	PedidosEnCola    int                    `json:"pedidos_en_cola"`
+	Promedios        []*PromedioPreparacion `json:"promedios,omitempty"`
+	CalculadoEn      time.Time              `json:"calculado_en"`
+}
+
+// EventoCocina aviso que se empuja a las pantallas de cocina conectadas
+type EventoCocina struct {
+	Tipo   string  `json:"tipo"` // 'confirmado', 'actualizado', 'ping'
+	Pedido *Pedido `json:"pedido,omitempty"`
 }
 
 // GameResult representa el resultado de un juego (para DTOs)
 type GameResult struct {
 	ClienteData ClienteData `json:"cliente"`
 	Resultado   Resultado   `json:"resultado"`
+	Huella      string      `json:"huella,omitempty" binding:"omitempty,max=128"` // Huella del navegador calculada en el frontend
+	IP          string      `json:"-"`                                            // Completada por el handler
+	WidgetID    *uint       `json:"-"`                                            // Completado si se jugó desde un widget embebido
+	Mesa        string      `json:"mesa,omitempty" binding:"omitempty,max=20"`    // Número de mesa del QR (?mesa= en la URL)
+	Sucursal    string      `json:"sucursal,omitempty" binding:"max=100"`         // Sucursal donde se jugó (?sucursal=); vacío = la de LOCATION
 }
 
 // ClienteData datos del cliente para el juego
@@ -132,6 +530,17 @@ type ClienteData struct {
 	Nombre   string `json:"nombre" binding:"required,min=2,max=50"`
 	Apellido string `json:"apellido" binding:"required,min=2,max=50"`
 	Telefono string `json:"telefono" binding:"required"`
+	Idioma   string `json:"idioma,omitempty" binding:"omitempty,oneof=es en"`
+
+	// Casilla "mostrar mi nombre en el muro de ganadores"; nil conserva la elección anterior
+	MostrarEnMuro *bool `json:"mostrar_en_muro,omitempty"`
+
+	// Email opcional: identifica al cliente si cambió de teléfono y habilita el canal email
+	Email string `json:"email,omitempty" binding:"omitempty,email,max=255"`
+
+	// Casilla "no uso WhatsApp": con SCREEN_VOUCHER_MODE=opcional el voucher solo se muestra
+	// en pantalla
+	SinWhatsApp bool `json:"sin_whatsapp,omitempty"`
 }
 
 // Resultado datos del resultado del juego
@@ -139,7 +548,35 @@ type Resultado struct {
 	Gano           bool    `json:"gano"`
 	TiempoObjetivo float64 `json:"tiempo_objetivo" binding:"required,min=5,max=20"`
 	TiempoObtenido float64 `json:"tiempo_obtenido" binding:"required,min=0"`
-	Tolerancia     float64 `json:"tolerancia,omitempty"` // Calculado por el servidor
+	Tolerancia     float64 `json:"tolerancia,omitempty"`            // Calculado por el servidor
+	TokenSesion    string  `json:"token_sesion" binding:"required"` // Emitido por POST /api/game/start
+}
+
+// IniciarPartidaRequest abre la sesión de juego al arrancar el cronómetro
+type IniciarPartidaRequest struct {
+	TokenObjetivo string `json:"token_objetivo" binding:"required"` // Emitido por GET /api/game/target
+}
+
+// DetenerPartidaRequest registra el momento en que el jugador detuvo el cronómetro
+type DetenerPartidaRequest struct {
+	TokenSesion string `json:"token_sesion" binding:"required"`
+}
+
+// RevelarVoucherRequest confirma los últimos dígitos del teléfono para ver el voucher en pantalla
+type RevelarVoucherRequest struct {
+	Token   string `json:"token_pantalla" binding:"required"`
+	Digitos string `json:"digitos" binding:"required,len=4,numeric"`
+}
+
+// VoucherPantalla voucher revelado en la pantalla del juego
+type VoucherPantalla struct {
+	Codigo           string `json:"codigo"`
+	Descuento        int    `json:"descuento"`
+	FechaVencimiento string `json:"fecha_vencimiento"` // ISO 8601
+	VencimientoTexto string `json:"vencimiento_texto"`
+	QRURL            string `json:"qr_url"`            // PNG con el código para escanear en caja
+	CodigoBarrasURL  string `json:"codigo_barras_url"` // PNG Code 128 para los lectores del POS
+	PDFURL           string `json:"pdf_url"`           // Voucher imprimible
 }
 
 // VoucherResponse respuesta al generar un voucher
@@ -148,10 +585,65 @@ type VoucherResponse struct {
 	Message            string `json:"message"`
 	Codigo             string `json:"codigo,omitempty"`
 	Descuento          int    `json:"descuento,omitempty"`
-	FechaVencimiento   string `json:"fecha_vencimiento,omitempty"`
+	FechaVencimiento   string `json:"fecha_vencimiento,omitempty"` // ISO 8601
+	VencimientoTexto   string `json:"vencimiento_texto,omitempty"` // Para mostrar, en el idioma del cliente
 	NecesitaAprobacion bool   `json:"necesita_aprobacion,omitempty"`
 	ClienteID          uint   `json:"cliente_id,omitempty"`
+	JuegoID            uint   `json:"juego_id,omitempty"` // Premio retenido por un pico de emisión: lo libera un empleado
 	EsClienteNuevo     bool   `json:"es_cliente_nuevo,omitempty"`
+	EsperaSegundos     int    `json:"espera_segundos,omitempty"`   // Tiempo restante para volver a jugar
+	PuedeJugarDesde    string `json:"puede_jugar_desde,omitempty"` // ISO 8601; llegó al tope de partidas del día o la semana
+	CompartirURL       string `json:"compartir_url,omitempty"`     // Imagen firmada para compartir en redes
+	Flash              bool   `json:"flash,omitempty"`             // Voucher válido solo por unas horas
+	VenceEnSegundos    int    `json:"vence_en_segundos,omitempty"` // Cuenta regresiva de los vouchers flash
+	MostrarCodigo      bool   `json:"mostrar_codigo,omitempty"`    // WhatsApp caído: el frontend muestra el código en grande
+	NotaCodigo         string `json:"nota_codigo,omitempty"`       // Aclaración que acompaña al código en pantalla
+	TokenPantalla      string `json:"token_pantalla,omitempty"`    // Voucher en pantalla: se revela confirmando los dígitos del teléfono
+
+	Celebracion   *CelebracionGanador `json:"celebracion,omitempty"`   // Solo al ganar
+	Recomendacion *Recomendacion      `json:"recomendacion,omitempty"` // Si alguna regla aplicó al voucher
+}
+
+// Recomendacion alcance y sugerencia de un voucher elegidos por una regla
+type Recomendacion struct {
+	Alcance string `json:"alcance,omitempty"`
+	Mensaje string `json:"mensaje"`
+}
+
+// ConfiguracionJuego parámetros vigentes del juego que necesita el frontend
+type ConfiguracionJuego struct {
+	Tolerancia        float64 `json:"tolerancia"`
+	DescuentoGanador  int     `json:"descuento_ganador"`
+	DescuentoPerdedor int     `json:"descuento_perdedor"`
+	TiempoMin         float64 `json:"tiempo_min"`
+	TiempoMax         float64 `json:"tiempo_max"`
+	ValidezVoucher    int     `json:"validez_voucher"`               // Días
+	JuegosAprobacion  int     `json:"juegos_aprobacion"`             // Partidas a partir de las que se requiere aprobación
+	PartidasPorDia    int     `json:"partidas_por_dia,omitempty"`    // Tope de partidas con voucher por día (0 = sin tope)
+	PartidasPorSemana int     `json:"partidas_por_semana,omitempty"` // Tope por semana, de lunes a domingo
+	Restaurante       string  `json:"restaurante"`
+	IdiomaPorDefecto  string  `json:"idioma_por_defecto"`
+	VoucherEnPantalla string  `json:"voucher_en_pantalla"` // no, opcional o siempre (SCREEN_VOUCHER_MODE)
+}
+
+// EstadisticasPublicas agregados aptos para widgets públicos (conteos redondeados)
+type EstadisticasPublicas struct {
+	Restaurante         string   `json:"restaurante"`
+	TotalPartidas       int      `json:"total_partidas"`
+	TotalJugadores      int      `json:"total_jugadores"`
+	JugaronHoy          int      `json:"jugaron_hoy"`
+	VouchersActivos     int      `json:"vouchers_activos"`
+	PorcentajeVictorias *float64 `json:"porcentaje_victorias,omitempty"` // Se omite con pocas partidas
+}
+
+// GanadorMuro ganador reciente para el muro público: nunca lleva el apellido completo,
+// el teléfono ni la hora exacta de la partida
+type GanadorMuro struct {
+	Nombre             string  `json:"nombre"` // "Juan P."
+	DiferenciaSegundos float64 `json:"diferencia_segundos"`
+	Texto              string  `json:"texto"`       // "Juan P. — 0.04s off" en el idioma pedido
+	Fecha              string  `json:"fecha"`       // Día de la partida (YYYY-MM-DD)
+	FechaTexto         string  `json:"fecha_texto"` // El mismo día para mostrar
 }
 
 // EstadisticasGenerales estadísticas del dashboard
@@ -168,6 +660,20 @@ type EstadisticasGenerales struct {
 	VouchersVencidos    int     `json:"vouchers_vencidos"`
 }
 
+// ClusterHuella grupo de teléfonos distintos que jugaron desde el mismo dispositivo o IP
+type ClusterHuella struct {
+	Criterio       string    `json:"criterio"` // 'huella' o 'ip'
+	Valor          string    `json:"valor"`
+	Clientes       int       `json:"clientes"`
+	Telefonos      string    `json:"telefonos"` // Separados por coma
+	Partidas       int       `json:"partidas"`
+	Ganadas        int       `json:"ganadas"`
+	Canjeados      int       `json:"canjeados"`
+	PrimeraPartida time.Time `json:"primera_partida"`
+	UltimaPartida  time.Time `json:"ultima_partida"`
+	Riesgo         string    `json:"riesgo" gorm:"-"` // 'alto' o 'medio'
+}
+
 // EstadisticasPorPeriodo estadísticas diarias/mensuales
 type EstadisticasPorPeriodo struct {
 	Fecha               string  `json:"fecha"`
@@ -184,10 +690,24 @@ type ClienteConEstadisticas struct {
 	VouchersUsados              int      `json:"vouchers_usados"`
 	VouchersPendientes          int      `json:"vouchers_pendientes"`
 	PorcentajeVictoriasPersonal float64  `json:"porcentaje_victorias_personal"`
-	TipoCliente                 string   `json:"tipo_cliente"` // 'nuevo', 'ocasional', 'frecuente'
 	UltimoVoucher               *Voucher `json:"ultimo_voucher,omitempty"`
 }
 
+// Evento evento de dominio enviado a los webhooks externos (CRM, integraciones)
+type Evento struct {
+	ID         string      `json:"id"`
+	Tipo       string      `json:"tipo"` // 'cliente.creado', 'cliente.actualizado', ...
+	OcurridoEn time.Time   `json:"ocurrido_en"`
+	Datos      interface{} `json:"datos"`
+}
+
+// EventoCliente payload de los eventos del ciclo de vida de un cliente
+type EventoCliente struct {
+	Cliente       *Cliente `json:"cliente"`
+	Motivo        string   `json:"motivo,omitempty"`
+	FusionadoEnID uint     `json:"fusionado_en_id,omitempty"` // Solo en cliente.fusionado
+}
+
 // WhatsAppMessage estructura para enviar mensajes por WhatsApp
 type WhatsAppMessage struct {
 	MessagingProduct string    `json:"messaging_product"`
@@ -226,6 +746,291 @@ type Parameter struct {
 	Text string `json:"text"`
 }
 
+// WhatsAppAPIError error estructurado devuelto por la API de WhatsApp (Meta Graph API)
+type WhatsAppAPIError struct {
+	HTTPStatus int    `json:"-"`
+	Message    string `json:"message"`
+	Type       string `json:"type"`
+	Code       int    `json:"code"`
+	Subcode    int    `json:"error_subcode,omitempty"`
+	FbTraceID  string `json:"fbtrace_id,omitempty"`
+	ErrorData  struct {
+		Details string `json:"details,omitempty"`
+	} `json:"error_data,omitempty"`
+}
+
+// Error implementa la interfaz error
+func (e *WhatsAppAPIError) Error() string {
+	msg := fmt.Sprintf("WhatsApp API error %d (code %d", e.HTTPStatus, e.Code)
+	if e.Subcode != 0 {
+		msg += fmt.Sprintf(", subcode %d", e.Subcode)
+	}
+	msg += "): " + e.Message
+	if e.ErrorData.Details != "" {
+		msg += " - " + e.ErrorData.Details
+	}
+	if e.FbTraceID != "" {
+		msg += " [fbtrace_id " + e.FbTraceID + "]"
+	}
+	return msg
+}
+
+// WhatsAppErrorResponse envoltorio de error de la API de WhatsApp
+type WhatsAppErrorResponse struct {
+	Error *WhatsAppAPIError `json:"error"`
+}
+
+// MensajeLog registro de cada mensaje enviado (o intentado) por WhatsApp
+type MensajeLog struct {
+	ID             uint       `gorm:"primaryKey" json:"id"`
+	Telefono       string     `gorm:"size:20;not null;index" json:"telefono"`
+	Tipo           string     `gorm:"size:20;not null" json:"tipo"` // 'template', 'text'
+	Plantilla      string     `gorm:"size:100" json:"plantilla,omitempty"`
+	Estado         string     `gorm:"type:enum('enviado','fallido');not null" json:"estado"`
+	MessageID      string     `gorm:"size:100;index" json:"message_id,omitempty"` // wamid devuelto por la API
+	HTTPStatus     int        `json:"http_status,omitempty"`
+	LatenciaMs     int        `json:"latencia_ms,omitempty"`  // Duración de la llamada a la API
+	EntregadoAt    *time.Time `json:"entregado_at,omitempty"` // Confirmado por el webhook de estados
+	ErrorCodigo    *int       `gorm:"index" json:"error_codigo,omitempty"`
+	ErrorSubcodigo *int       `json:"error_subcodigo,omitempty"`
+	ErrorMensaje   string     `gorm:"type:text" json:"error_mensaje,omitempty"`
+	FbTraceID      string     `gorm:"size:100" json:"fbtrace_id,omitempty"`
+	CreatedAt      time.Time  `gorm:"index" json:"created_at"`
+}
+
+// EstadisticaEnvios resumen de los envíos de WhatsApp de un día o de una plantilla
+type EstadisticaEnvios struct {
+	Dia             string  `json:"dia,omitempty"`       // AAAA-MM-DD, en el resumen por día
+	Plantilla       string  `json:"plantilla,omitempty"` // En el resumen por plantilla ("texto" si no usó plantilla)
+	Intentos        int     `json:"intentos"`
+	Enviados        int     `json:"enviados"`   // Aceptados por la API
+	Entregados      int     `json:"entregados"` // Con entrega confirmada por el webhook
+	Fallidos        int     `json:"fallidos"`   // Rechazados por la API o informados como fallidos después
+	TasaFallo       float64 `json:"tasa_fallo"` // % de intentos fallidos
+	LatenciaMs      float64 `json:"latencia_ms"`
+	EntregaSegundos float64 `json:"entrega_segundos"` // Promedio desde el envío hasta la entrega
+}
+
+// EstadisticasEnvios envíos de WhatsApp desde una fecha, por día y por plantilla
+type EstadisticasEnvios struct {
+	Desde        time.Time           `json:"desde"`
+	Total        EstadisticaEnvios   `json:"total"`
+	PorDia       []EstadisticaEnvios `json:"por_dia"`
+	PorPlantilla []EstadisticaEnvios `json:"por_plantilla"`
+}
+
+// CuotaWhatsApp límites de envío del número de WhatsApp y su consumo actual.
+// Los campos Disponibles valen -1 cuando no hay límite
+type CuotaWhatsApp struct {
+	Fuente                 string     `json:"fuente"` // 'proveedor' o 'configuracion'
+	TierProveedor          string     `json:"tier_proveedor,omitempty"`
+	LimiteDiario           int        `json:"limite_diario"`
+	DestinatariosUltimas24 int        `json:"destinatarios_ultimas_24h"`
+	DisponiblesHoy         int        `json:"disponibles_hoy"`
+	LimiteMensual          int        `json:"limite_mensual"`
+	ConversacionesMes      int        `json:"conversaciones_mes"`
+	DisponiblesMes         int        `json:"disponibles_mes"`
+	ConsultadoProveedorEn  *time.Time `json:"consultado_proveedor_en,omitempty"`
+
+	// Calidad del número en Meta (GREEN, YELLOW, RED o UNKNOWN) y la parte del límite
+	// diario que pueden usar las campañas con esa calidad (0 = marketing pausado)
+	Calidad                 string  `json:"calidad,omitempty"`
+	ParteMarketing          float64 `json:"parte_marketing"`
+	DisponiblesMarketingHoy int     `json:"disponibles_marketing_hoy"`
+}
+
+// EstadoPlantilla estado de aprobación en Meta de un template de WhatsApp que usamos
+type EstadoPlantilla struct {
+	Clave        string    `json:"clave"`            // Uso en el sistema: voucher_ganador, pedido_confirmado, ...
+	Nombre       string    `json:"nombre"`           // Nombre del template en Meta
+	Idioma       string    `json:"idioma"`           // Código de idioma de la API (es, en_US)
+	Estado       string    `json:"estado"`           // APPROVED, PENDING, REJECTED, PAUSED, DISABLED... o NO_ENCONTRADO
+	Motivo       string    `json:"motivo,omitempty"` // Motivo de rechazo informado por Meta
+	Utilizable   bool      `json:"utilizable"`
+	Juego        bool      `json:"juego"` // Lo usa el juego para mandar el voucher
+	ConsultadoEn time.Time `json:"consultado_en"`
+}
+
+// EstadoCircuitoPremios estado del corte automático por tasa de victorias anómala
+type EstadoCircuitoPremios struct {
+	Estado          string     `json:"estado"` // 'normal', 'ajustado' o 'pausado'
+	Ventana         int        `json:"ventana"`
+	PartidasVentana int        `json:"partidas_ventana"`
+	TasaVictorias   float64    `json:"tasa_victorias"`
+	TasaMaxima      float64    `json:"tasa_maxima"`
+	Tolerancia      float64    `json:"tolerancia"`
+	ActivadoEn      *time.Time `json:"activado_en,omitempty"`
+	TasaActivacion  float64    `json:"tasa_activacion,omitempty"`
+	RestablecidoPor uint       `json:"restablecido_por,omitempty"`
+	RestablecidoEn  *time.Time `json:"restablecido_en,omitempty"`
+}
+
+// MensajeOutbox mensaje pendiente de envío (programado, retenido por horario silencioso,
+// en reintento o aviso del voucher de una partida)
+type MensajeOutbox struct {
+	ID             uint       `gorm:"primaryKey" json:"id"`
+	Telefono       string     `gorm:"size:20;not null;index" json:"telefono"`
+	Categoria      string     `gorm:"type:enum('transaccional','marketing');not null" json:"categoria"`
+	Payload        string     `gorm:"type:json;not null" json:"payload"` // WhatsAppMessage serializado
+	Estado         string     `gorm:"type:enum('pendiente','enviado','fallido');default:'pendiente';index" json:"estado"`
+	ProgramadoPara time.Time  `gorm:"not null;index" json:"programado_para"`
+	Intentos       int        `gorm:"default:0" json:"intentos"`
+	UltimoError    string     `gorm:"type:text" json:"ultimo_error,omitempty"`
+	EnviadoAt      *time.Time `json:"enviado_at,omitempty"`
+	MessageID      string     `gorm:"size:100;index" json:"message_id,omitempty"` // wamid devuelto por la API al enviarlo
+	// Aviso del voucher de una partida: se encola en la transacción que crea el voucher y el
+	// mensaje se arma al enviarlo (el payload queda vacío)
+	VoucherID *uint     `gorm:"index" json:"voucher_id,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	Voucher *Voucher `gorm:"foreignKey:VoucherID" json:"-"`
+}
+
+// Branding identidad visual aplicada a vouchers y a la página pública del juego.
+// Hay una sola fila; los campos vacíos toman el valor de la configuración
+type Branding struct {
+	ID              uint      `gorm:"primaryKey" json:"id"`
+	LogoURL         string    `gorm:"size:500" json:"logo_url"`
+	ColorPrimario   string    `gorm:"size:7" json:"color_primario"`   // #RRGGBB
+	ColorSecundario string    `gorm:"size:7" json:"color_secundario"` // #RRGGBB
+	TextoLegal      string    `gorm:"type:text" json:"texto_legal"`   // Pie legal de vouchers
+	UpdatedBy       uint      `json:"updated_by,omitempty"`
+	UpdatedAt       time.Time `json:"updated_at"`
+}
+
+// Celebracion cómo festeja el frontend una victoria (confeti, sonido, titular y oferta).
+// Hay una sola fila, editable por marketing sin redeploy del frontend
+type Celebracion struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	Confeti   string    `gorm:"size:20;not null;default:'clasico'" json:"confeti"` // clasico, queso, estrellas o ninguno
+	SonidoURL string    `gorm:"size:500" json:"sonido_url"`                        // Vacío = sin sonido
+	Titular   string    `gorm:"size:200" json:"titular"`                           // Admite {nombre} y {descuento}
+	Upsell    string    `gorm:"size:300" json:"upsell"`                            // Ej. "Sumá papas con 30% off"
+	UpdatedBy uint      `json:"updated_by,omitempty"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// CelebracionGanador celebración lista para mostrar, con los textos ya completados
+type CelebracionGanador struct {
+	Confeti   string `json:"confeti"`
+	SonidoURL string `json:"sonido_url,omitempty"`
+	Titular   string `json:"titular,omitempty"`
+	Upsell    string `json:"upsell,omitempty"`
+}
+
+// Notificacion alerta operativa del centro de notificaciones del panel admin
+type Notificacion struct {
+	ID          uint       `gorm:"primaryKey" json:"id"`
+	Tipo        string     `gorm:"type:enum('info','warning','error');not null" json:"tipo"`
+	Titulo      string     `gorm:"size:200;not null" json:"titulo"`
+	Descripcion string     `gorm:"type:text" json:"descripcion"`
+	Accion      string     `gorm:"size:100" json:"accion,omitempty"`
+	Leida       bool       `gorm:"not null;index" json:"leida"`
+	LeidaPor    *uint      `json:"leida_por,omitempty"`
+	LeidaAt     *time.Time `json:"leida_at,omitempty"`
+	CreatedAt   time.Time  `gorm:"index" json:"created_at"`
+}
+
+// Bloqueo entrada de la lista de bloqueo de teléfonos, IPs y huellas de dispositivo.
+// Se conserva al levantarlo para auditoría
+type Bloqueo struct {
+	ID           uint       `gorm:"primaryKey" json:"id"`
+	Tipo         string     `gorm:"type:enum('telefono','ip','huella');not null;index:idx_bloqueo_tipo_valor" json:"tipo"`
+	Valor        string     `gorm:"size:128;not null;index:idx_bloqueo_tipo_valor" json:"valor"`
+	Motivo       string     `gorm:"type:text;not null" json:"motivo"`
+	CreadoPor    uint       `gorm:"not null" json:"creado_por"`
+	ExpiraEn     *time.Time `json:"expira_en,omitempty"` // NULL = permanente
+	Activo       bool       `gorm:"not null;index" json:"activo"`
+	LevantadoPor *uint      `json:"levantado_por,omitempty"`
+	LevantadoAt  *time.Time `json:"levantado_at,omitempty"`
+	CreatedAt    time.Time  `json:"created_at"`
+
+	// Relaciones
+	UsuarioCreador *Usuario `gorm:"foreignKey:CreadoPor" json:"usuario_creador,omitempty"`
+}
+
+// CrearBloqueoRequest request para agregar una entrada a la lista de bloqueo
+type CrearBloqueoRequest struct {
+	Tipo          string `json:"tipo" binding:"required,oneof=telefono ip huella"`
+	Valor         string `json:"valor" binding:"required,max=128"`
+	Motivo        string `json:"motivo" binding:"required,min=3,max=500"`
+	DuracionHoras int    `json:"duracion_horas,omitempty" binding:"omitempty,min=1,max=87600"` // Sin valor = permanente
+}
+
+// PicoEmision estado de la detección de picos de emisión de vouchers
+type PicoEmision struct {
+	Activo             bool       `json:"activo"`
+	EmitidosUltimaHora int        `json:"emitidos_ultima_hora"`
+	PromedioPorHora    float64    `json:"promedio_por_hora"`
+	Factor             float64    `json:"factor"`
+	RequiereAprobacion bool       `json:"requiere_aprobacion"`
+	DetectadoEn        *time.Time `json:"detectado_en,omitempty"`
+	UltimaVerificacion *time.Time `json:"ultima_verificacion,omitempty"`
+}
+
+// ActualizarBrandingRequest request para modificar el branding desde el panel admin
+type ActualizarBrandingRequest struct {
+	LogoURL         string `json:"logo_url" binding:"omitempty,url,max=500"`
+	ColorPrimario   string `json:"color_primario" binding:"omitempty,hexcolor,len=7"`
+	ColorSecundario string `json:"color_secundario" binding:"omitempty,hexcolor,len=7"`
+	TextoLegal      string `json:"texto_legal" binding:"max=2000"`
+}
+
+// ActualizarCelebracionRequest request para editar la celebración de las victorias
+type ActualizarCelebracionRequest struct {
+	Confeti   string `json:"confeti" binding:"required,oneof=clasico queso estrellas ninguno"`
+	SonidoURL string `json:"sonido_url" binding:"omitempty,url,max=500"`
+	Titular   string `json:"titular" binding:"max=200"`
+	Upsell    string `json:"upsell" binding:"max=300"`
+}
+
+// ReporteGuardado configuración de reporte con nombre que un admin puede re-ejecutar o programar
+type ReporteGuardado struct {
+	ID               uint       `gorm:"primaryKey" json:"id"`
+	UsuarioID        uint       `gorm:"not null;index" json:"usuario_id"`
+	Nombre           string     `gorm:"size:100;not null" json:"nombre"`
+	Tipo             string     `gorm:"type:enum('ventas','estadisticas');not null" json:"tipo"`
+	Parametros       string     `gorm:"type:json;not null" json:"parametros"` // ParametrosReporte serializado
+	Formato          string     `gorm:"type:enum('json','csv');default:'json'" json:"formato"`
+	Favorito         bool       `gorm:"not null" json:"favorito"`
+	Programacion     string     `gorm:"type:enum('ninguna','diaria','semanal','mensual');default:'ninguna'" json:"programacion"`
+	ProximaEjecucion *time.Time `gorm:"index" json:"proxima_ejecucion,omitempty"`
+	UltimaEjecucion  *time.Time `json:"ultima_ejecucion,omitempty"`
+	UltimoResultado  string     `gorm:"type:json" json:"ultimo_resultado,omitempty"`
+	UltimoArchivo    string     `gorm:"size:255" json:"ultimo_archivo,omitempty"` // Clave en el almacenamiento del último resultado completo
+	CreatedAt        time.Time  `json:"created_at"`
+	UpdatedAt        time.Time  `json:"updated_at"`
+}
+
+// ArchivoGenerado archivo guardado en el almacenamiento (reporte, exportación, QR) con
+// su link de descarga temporal
+type ArchivoGenerado struct {
+	Clave string    `json:"clave"`
+	URL   string    `json:"url"`
+	Vence time.Time `json:"vence"`
+}
+
+// ParametrosReporte filtros de un reporte guardado. Si se indica RangoRelativo
+// (ultimos_7_dias, ultimos_30_dias, semana_anterior, mes_actual, mes_anterior)
+// las fechas se recalculan en cada ejecución
+type ParametrosReporte struct {
+	RangoRelativo string            `json:"rango_relativo,omitempty"`
+	FechaInicio   string            `json:"fecha_inicio,omitempty"` // 2006-01-02
+	FechaFin      string            `json:"fecha_fin,omitempty"`    // 2006-01-02
+	Filtros       map[string]string `json:"filtros,omitempty"`      // ej. tipo=juego_ganado
+}
+
+// GuardarReporteRequest request para crear o actualizar un reporte guardado
+type GuardarReporteRequest struct {
+	Nombre       string            `json:"nombre" binding:"required,max=100"`
+	Tipo         string            `json:"tipo" binding:"required,oneof=ventas estadisticas"`
+	Parametros   ParametrosReporte `json:"parametros"`
+	Formato      string            `json:"formato" binding:"omitempty,oneof=json csv"`
+	Favorito     bool              `json:"favorito"`
+	Programacion string            `json:"programacion" binding:"omitempty,oneof=ninguna diaria semanal mensual"`
+}
+
 // WhatsAppWebhookMessage mensaje recibido por webhook
 type WhatsAppWebhookMessage struct {
 	Object string `json:"object"`
@@ -253,12 +1058,234 @@ type WhatsAppWebhookMessage struct {
 					} `json:"text"`
 					Type string `json:"type"`
 				} `json:"messages"`
+				// Statuses cambios de estado de los mensajes enviados (sent, delivered, read, failed)
+				Statuses []struct {
+					ID          string `json:"id"`
+					Status      string `json:"status"`
+					Timestamp   string `json:"timestamp"`
+					RecipientID string `json:"recipient_id"`
+					Errors      []struct {
+						Code  int    `json:"code"`
+						Title string `json:"title"`
+					} `json:"errors"`
+				} `json:"statuses"`
 			} `json:"value"`
 			Field string `json:"field"`
 		} `json:"changes"`
 	} `json:"entry"`
 }
 
+// Conversacion mensaje de la bandeja de WhatsApp compartida por el staff
+type Conversacion struct {
+	ID             uint      `gorm:"primaryKey" json:"id"`
+	Telefono       string    `gorm:"size:20;not null;index" json:"telefono"`
+	ClienteID      *uint     `gorm:"index" json:"cliente_id,omitempty"`
+	Direccion      string    `gorm:"type:enum('entrante','saliente');not null" json:"direccion"`
+	Tipo           string    `gorm:"size:20;not null" json:"tipo"` // text, image, audio...
+	Mensaje        string    `gorm:"type:text;not null" json:"mensaje"`
+	MessageID      string    `gorm:"size:100;index" json:"message_id,omitempty"` // ID de WhatsApp, evita duplicados por reintentos
+	NombreContacto string    `gorm:"size:100" json:"nombre_contacto,omitempty"`
+	Leido          bool      `gorm:"not null;index" json:"leido"`
+	EnviadoPor     *uint     `json:"enviado_por,omitempty"`               // Empleado que respondió
+	RespuestaID    *uint     `gorm:"index" json:"respuesta_id,omitempty"` // Respuesta rápida usada, si hubo
+	CreatedAt      time.Time `gorm:"index" json:"created_at"`
+
+	// Relaciones
+	Empleado *Usuario `gorm:"foreignKey:EnviadoPor" json:"empleado,omitempty"`
+}
+
+// HiloConversacion resumen de la conversación con un teléfono
+type HiloConversacion struct {
+	Telefono       string    `json:"telefono"`
+	ClienteID      *uint     `json:"cliente_id,omitempty"`
+	NombreContacto string    `json:"nombre_contacto,omitempty"`
+	UltimoMensaje  string    `json:"ultimo_mensaje"`
+	UltimaFecha    time.Time `json:"ultima_fecha"`
+	NoLeidos       int       `json:"no_leidos"`
+	AtencionID     *uint     `json:"atencion_id,omitempty"` // Atención abierta, si hay
+	AsignadoA      *uint     `json:"asignado_a,omitempty"`
+}
+
+// Atencion seguimiento de un pedido/conversación entrante desde el primer mensaje
+// del cliente hasta que el staff la resuelve, para medir los tiempos de SLA
+type Atencion struct {
+	ID                 uint       `gorm:"primaryKey" json:"id"`
+	Telefono           string     `gorm:"size:20;not null;index" json:"telefono"`
+	Estado             string     `gorm:"type:enum('abierta','resuelta');default:'abierta';index" json:"estado"`
+	AsignadoA          *uint      `gorm:"index" json:"asignado_a,omitempty"`
+	AsignadoAt         *time.Time `json:"asignado_at,omitempty"`
+	PrimerMensajeAt    time.Time  `gorm:"not null" json:"primer_mensaje_at"`
+	PrimeraRespuestaAt *time.Time `json:"primera_respuesta_at,omitempty"`
+	ResueltaAt         *time.Time `json:"resuelta_at,omitempty"`
+	ResueltaPor        *uint      `json:"resuelta_por,omitempty"`
+	CreatedAt          time.Time  `json:"created_at"`
+	UpdatedAt          time.Time  `json:"updated_at"`
+
+	// Calculados según el SLA configurado
+	IncumpleRespuesta  bool `gorm:"-" json:"incumple_respuesta"`
+	IncumpleResolucion bool `gorm:"-" json:"incumple_resolucion"`
+
+	// Relaciones
+	Empleado *Usuario `gorm:"foreignKey:AsignadoA" json:"empleado,omitempty"`
+}
+
+// ResumenSLA estado de las atenciones abiertas y tiempos promedio del período
+type ResumenSLA struct {
+	Abiertas                  int         `json:"abiertas"`
+	SinAsignar                int         `json:"sin_asignar"`
+	IncumplenRespuesta        int         `json:"incumplen_respuesta"`
+	IncumplenResolucion       int         `json:"incumplen_resolucion"`
+	MinutosObjetivoRespuesta  int         `json:"minutos_objetivo_respuesta"`
+	MinutosObjetivoResolucion int         `json:"minutos_objetivo_resolucion"`
+	PromedioRespuestaMinutos  float64     `json:"promedio_respuesta_minutos"`
+	PromedioResolucionMinutos float64     `json:"promedio_resolucion_minutos"`
+	ResueltasUltimas24        int         `json:"resueltas_ultimas_24h"`
+	Incumplimientos           []*Atencion `json:"incumplimientos"`
+}
+
+// ResponderConversacionRequest respuesta manual del staff desde la bandeja.
+// Con respuesta_id se usa esa respuesta rápida; si además viene mensaje, es el texto editado
+type ResponderConversacionRequest struct {
+	Mensaje     string `json:"mensaje" binding:"required_without=RespuestaID,max=4096"`
+	RespuestaID uint   `json:"respuesta_id"`
+}
+
+// RespuestaRapida texto predefinido que el staff inserta al responder desde la bandeja.
+// El contenido admite {nombre}, que se reemplaza por el nombre del cliente
+type RespuestaRapida struct {
+	ID        uint       `gorm:"primaryKey" json:"id"`
+	Atajo     string     `gorm:"unique;size:50;not null" json:"atajo"` // ej. "saludo", "horarios"
+	Titulo    string     `gorm:"size:100;not null" json:"titulo"`
+	Contenido string     `gorm:"type:text;not null" json:"contenido"`
+	Activa    bool       `gorm:"not null" json:"activa"`
+	Usos      int        `gorm:"not null;default:0" json:"usos"`
+	UltimoUso *time.Time `json:"ultimo_uso,omitempty"`
+	CreatedBy uint       `json:"created_by,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+}
+
+// GuardarRespuestaRapidaRequest request para crear o editar una respuesta rápida
+type GuardarRespuestaRapidaRequest struct {
+	Atajo     string `json:"atajo" binding:"required,min=2,max=50"`
+	Titulo    string `json:"titulo" binding:"required,min=2,max=100"`
+	Contenido string `json:"contenido" binding:"required,min=1,max=4096"`
+	Activa    *bool  `json:"activa"`
+}
+
+// Claves de los textos de WhatsApp editables desde el panel
+const (
+	MensajeMarketing           = "marketing"
+	MensajeRespuestaAutomatica = "respuesta_automatica"
+	MensajeCodigoVerificacion  = "codigo_verificacion"
+	MensajeVoucherGanador      = "voucher_ganador"  // Respaldo si el template no está aprobado
+	MensajeVoucherPerdedor     = "voucher_perdedor" // Respaldo si el template no está aprobado
+)
+
+// MensajeConfig texto de un mensaje de WhatsApp editado por un admin. El contenido
+// admite variables entre llaves ({nombre}, {codigo}, {vencimiento}, ...) según la clave
+type MensajeConfig struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	Clave     string    `gorm:"unique;size:50;not null" json:"clave"`
+	Contenido string    `gorm:"type:text;not null" json:"contenido"`
+	UpdatedBy uint      `json:"updated_by,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// MensajeConfigDetalle texto vigente de una clave junto con su versión original
+// y las variables que acepta, para mostrar en el editor del panel
+type MensajeConfigDetalle struct {
+	Clave         string     `json:"clave"`
+	Descripcion   string     `json:"descripcion"`
+	Contenido     string     `json:"contenido"`
+	PorDefecto    string     `json:"por_defecto"`
+	Personalizado bool       `json:"personalizado"`
+	Variables     []string   `json:"variables"`
+	UpdatedBy     uint       `json:"updated_by,omitempty"`
+	UltimoCambio  *time.Time `json:"ultimo_cambio,omitempty"`
+}
+
+// GuardarMensajeConfigRequest request para editar el texto de un mensaje
+type GuardarMensajeConfigRequest struct {
+	Contenido string `json:"contenido" binding:"required,min=1,max=4096"`
+}
+
+// PrevisualizarMensajeRequest request para ver cómo queda un mensaje. Sin contenido
+// usa el texto guardado; las variables que no se envían toman valores de ejemplo
+type PrevisualizarMensajeRequest struct {
+	Contenido string            `json:"contenido" binding:"max=4096"`
+	Variables map[string]string `json:"variables"`
+}
+
+// HorarioSucursal turno de apertura de una sucursal en un día de la semana.
+// Puede haber más de un turno por día; si Cierre <= Apertura el turno cruza la medianoche
+type HorarioSucursal struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	Sucursal  string    `gorm:"size:100;not null;index" json:"sucursal"`
+	DiaSemana int       `gorm:"not null" json:"dia_semana"`      // 0 = domingo
+	Apertura  string    `gorm:"size:5;not null" json:"apertura"` // HH:MM
+	Cierre    string    `gorm:"size:5;not null" json:"cierre"`   // HH:MM
+	UpdatedBy uint      `json:"updated_by,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Feriado día en que la sucursal no abre aunque tenga horario ese día de la semana.
+// Sin sucursal aplica a todas
+type Feriado struct {
+	ID          uint      `gorm:"primaryKey" json:"id"`
+	Sucursal    string    `gorm:"size:100;index" json:"sucursal,omitempty"`
+	Fecha       time.Time `gorm:"type:date;not null;index" json:"fecha"`
+	Descripcion string    `gorm:"size:100" json:"descripcion,omitempty"`
+	CreatedBy   uint      `json:"created_by,omitempty"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// TurnoRequest turno de un día al guardar el horario de una sucursal
+type TurnoRequest struct {
+	DiaSemana int    `json:"dia_semana" binding:"min=0,max=6"`
+	Apertura  string `json:"apertura" binding:"required"`
+	Cierre    string `json:"cierre" binding:"required"`
+}
+
+// GuardarHorariosRequest reemplaza el horario semanal completo de una sucursal
+type GuardarHorariosRequest struct {
+	Turnos []TurnoRequest `json:"turnos" binding:"dive"`
+}
+
+// DiaSinCanje fecha en la que la caja no acepta vouchers (feriados, eventos especiales).
+// Los vouchers no se extienden: siguen venciendo en su fecha
+type DiaSinCanje struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	Fecha     time.Time `gorm:"type:date;not null;uniqueIndex" json:"fecha"`
+	Motivo    string    `gorm:"size:100" json:"motivo,omitempty"`
+	CreatedBy uint      `json:"created_by,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// CrearDiaSinCanjeRequest request para bloquear el canje en una fecha
+type CrearDiaSinCanjeRequest struct {
+	Fecha  string `json:"fecha" binding:"required"` // YYYY-MM-DD
+	Motivo string `json:"motivo" binding:"max=100"`
+}
+
+// CrearFeriadoRequest request para cargar un feriado
+type CrearFeriadoRequest struct {
+	Sucursal    string `json:"sucursal" binding:"max=100"`
+	Fecha       string `json:"fecha" binding:"required"` // YYYY-MM-DD
+	Descripcion string `json:"descripcion" binding:"max=100"`
+}
+
+// EstadoAtencion indica si la sucursal está abierta en un momento dado
+type EstadoAtencion struct {
+	Sucursal        string     `json:"sucursal"`
+	Abierta         bool       `json:"abierta"`
+	Feriado         string     `json:"feriado,omitempty"` // Descripción si hoy es feriado
+	ProximaApertura *time.Time `json:"proxima_apertura,omitempty"`
+	CierraA         *time.Time `json:"cierra_a,omitempty"`
+}
+
 // LoginRequest request para login de empleados
 type LoginRequest struct {
 	Email    string `json:"email" binding:"required,email"`
@@ -273,24 +1300,650 @@ type LoginResponse struct {
 	Usuario *Usuario `json:"usuario,omitempty"`
 }
 
+// AprobacionCanje encargado que confirma un canje de descuento alto. El PIN se valida
+// contra el administrador de ese email, así que dos encargados pueden tener el mismo
+type AprobacionCanje struct {
+	AprobadorEmail string `json:"aprobador_email" binding:"omitempty,email"`
+	PINAprobacion  string `json:"pin_aprobacion" binding:"omitempty,numeric,min=4,max=8"`
+}
+
 // CanjearVoucherRequest request para canjear voucher
 type CanjearVoucherRequest struct {
 	Codigo string `json:"codigo" binding:"required,min=6,max=20"`
+	AprobacionCanje
+}
+
+// EscanearVoucherRequest contenido del QR de un voucher leído por la app de caja
+type EscanearVoucherRequest struct {
+	Contenido string `json:"contenido" binding:"required,max=200"`
+	AprobacionCanje
+}
+
+// EstablecerPINRequest request para que un administrador fije su PIN de aprobación de canjes
+type EstablecerPINRequest struct {
+	Password string `json:"password" binding:"required"`
+	PIN      string `json:"pin" binding:"required,numeric,min=4,max=8"`
+}
+
+// TransferirVoucherRequest request para mover un voucher a otra sucursal
+type TransferirVoucherRequest struct {
+	Sucursal string `json:"sucursal" binding:"required,max=100"`
+	Motivo   string `json:"motivo" binding:"max=255"`
+}
+
+// CambiarEstadoClienteRequest request para bloquear o reactivar a un cliente
+type CambiarEstadoClienteRequest struct {
+	Estado string `json:"estado" binding:"required,oneof=activo bloqueado"`
+	Motivo string `json:"motivo" binding:"max=255"`
 }
 
 // CanjearVoucherResponse respuesta del canje
 type CanjearVoucherResponse struct {
-	Success   bool   `json:"success"`
-	Message   string `json:"message"`
-	Descuento int    `json:"descuento,omitempty"`
-	Cliente   string `json:"cliente,omitempty"`
+	Success           bool   `json:"success"`
+	Message           string `json:"message"`
+	Descuento         int    `json:"descuento,omitempty"`
+	Cliente           string `json:"cliente,omitempty"`
+	Alcance           string `json:"alcance,omitempty"`       // Productos a los que aplica el descuento
+	Recomendacion     string `json:"recomendacion,omitempty"` // Para ofrecer en caja
+	ProximoCanje      string `json:"proximo_canje,omitempty"` // Si hoy no se aceptan vouchers: primer día en que sí (AAAA-MM-DD)
+	ProximoCanjeTexto string `json:"proximo_canje_texto,omitempty"`
+
+	RequiereAprobacion bool `json:"requiere_aprobacion,omitempty"` // Falta (o es incorrecta) la aprobación de un encargado
 }
 
+// ResultadoArchivo resumen de una corrida del archivado de vouchers y partidas
+type ResultadoArchivo struct {
+	AntesDe             time.Time `json:"antes_de"`
+	Vouchers            int       `json:"vouchers"`
+	Juegos              int       `json:"juegos"`
+	EnviosDesvinculados int       `json:"envios_desvinculados"` // Envíos que conservan solo el código del voucher archivado
+	Duracion            string    `json:"duracion"`
+}
+
+// TablaVouchersArchivo tabla con la misma estructura que vouchers donde se mueven
+// los vouchers (y partidas) viejos ya canjeados o vencidos
+const TablaVouchersArchivo = "vouchers_archivo"
+
+// TablaJuegosArchivo tabla con la misma estructura que juegos donde se mueven las
+// partidas viejas
+const TablaJuegosArchivo = "juegos_archivo"
+
 // TableName especifica nombres de tabla personalizados para GORM
-func (Rol) TableName() string                     { return "roles" }
-func (Usuario) TableName() string                 { return "usuarios" }
-func (Cliente) TableName() string                 { return "clientes" }
-func (Voucher) TableName() string                 { return "vouchers" }
-func (CampanaClientesVouchers) TableName() string { return "campañas_clientes_vouchers" }
-func (ClientesVouchersEnvios) TableName() string  { return "clientes_vouchers_envios" }
-func (Pedido) TableName() string                  { return "pedidos" }
+func (Rol) TableName() string                      { return "roles" }
+func (Usuario) TableName() string                  { return "usuarios" }
+func (Cliente) TableName() string                  { return "clientes" }
+func (Voucher) TableName() string                  { return "vouchers" }
+func (Juego) TableName() string                    { return "juegos" }
+func (CampanaClientesVouchers) TableName() string  { return "campañas_clientes_vouchers" }
+func (ClientesVouchersEnvios) TableName() string   { return "clientes_vouchers_envios" }
+func (Pedido) TableName() string                   { return "pedidos" }
+func (PedidoItem) TableName() string               { return "pedidos_items" }
+func (ItemMenu) TableName() string                 { return "menu_items" }
+func (MensajeLog) TableName() string               { return "mensajes_log" }
+func (MensajeOutbox) TableName() string            { return "mensajes_outbox" }
+func (PreferenciasComunicacion) TableName() string { return "preferencias_comunicacion" }
+func (BajaMarketing) TableName() string            { return "bajas_marketing" }
+func (Conversacion) TableName() string             { return "conversaciones" }
+func (Atencion) TableName() string                 { return "atenciones" }
+func (RespuestaRapida) TableName() string          { return "respuestas_rapidas" }
+func (MensajeConfig) TableName() string            { return "mensajes_config" }
+func (HorarioSucursal) TableName() string          { return "horarios_sucursal" }
+func (Feriado) TableName() string                  { return "feriados" }
+func (ReporteGuardado) TableName() string          { return "reportes_guardados" }
+func (EstimacionCostoCampana) TableName() string   { return "campanas_estimaciones_costo" }
+func (Branding) TableName() string                 { return "branding" }
+func (Celebracion) TableName() string              { return "celebracion" }
+func (ReglaRecomendacion) TableName() string       { return "reglas_recomendacion" }
+func (ReglaVigencia) TableName() string            { return "reglas_vigencia" }
+func (DiaSinCanje) TableName() string              { return "dias_sin_canje" }
+func (IntentoCanje) TableName() string             { return "intentos_canje" }
+func (Notificacion) TableName() string             { return "notificaciones" }
+func (Bloqueo) TableName() string                  { return "bloqueos" }
+func (MetaMensual) TableName() string              { return "metas_mensuales" }
+func (ParametrosJuego) TableName() string          { return "parametros_juego" }
+func (CambioConfiguracion) TableName() string      { return "cambios_configuracion" }
+func (ClaveIdempotencia) TableName() string        { return "claves_idempotencia" }
+
+// Modelos lista todos los modelos con tabla propia, padres antes que hijos, para que
+// AutoMigrate cree el esquema completo. Cada modelo nuevo con tabla se agrega acá
+func Modelos() []interface{} {
+	return []interface{}{
+		&Rol{}, &Usuario{}, &Cliente{}, &PreferenciasComunicacion{}, &BajaMarketing{},
+		&Widget{}, &Mesa{}, &Voucher{}, &Juego{},
+		&CampanaClientesVouchers{}, &EstimacionCostoCampana{}, &ClientesVouchersEnvios{},
+		&ItemMenu{}, &Pedido{}, &PedidoItem{},
+		&MensajeLog{}, &MensajeOutbox{}, &MensajeConfig{}, &Conversacion{}, &Atencion{}, &RespuestaRapida{},
+		&HorarioSucursal{}, &Feriado{}, &DiaSinCanje{}, &IntentoCanje{},
+		&Branding{}, &Celebracion{}, &ReglaRecomendacion{}, &ReglaVigencia{},
+		&ReporteGuardado{}, &Notificacion{}, &Bloqueo{}, &MetaMensual{},
+		&ParametrosJuego{}, &CambioConfiguracion{}, &ClaveIdempotencia{}, &EstadisticaDiaria{},
+		&EstadoCompartido{}, &CierreRanking{},
+	}
+}
+
+// ClaveIdempotencia respuesta guardada de un request con Idempotency-Key, para devolver la
+// misma ante un reintento (ej. doble toque en el kiosco) sin volver a procesarlo. Status 0
+// indica que el primer request todavía se está procesando
+type ClaveIdempotencia struct {
+	ID        uint      `gorm:"primaryKey"`
+	Clave     string    `gorm:"size:100;not null;uniqueIndex:idx_idempotencia_clave_ruta"`
+	Ruta      string    `gorm:"size:100;not null;uniqueIndex:idx_idempotencia_clave_ruta"`
+	Huella    string    `gorm:"size:64;not null"` // SHA-256 del cuerpo: la misma clave con otro cuerpo es un error
+	Status    int       `gorm:"not null;default:0"`
+	Respuesta string    `gorm:"type:text"`
+	ExpiraEn  time.Time `gorm:"not null;index"`
+	CreatedAt time.Time
+}
+
+// Widget sitio externo autorizado a embeber el juego. La clave es pública (viaja en el
+// snippet), lo que restringe el uso es la lista de orígenes permitidos
+type Widget struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	Nombre    string    `gorm:"size:100;not null" json:"nombre"`
+	Clave     string    `gorm:"uniqueIndex;size:40;not null" json:"clave"`
+	Origenes  string    `gorm:"type:text;not null" json:"-"` // Separados por coma, ej. https://cheesehouse.com.ar
+	Activo    bool      `gorm:"not null;default:true" json:"activo"`
+	CreadoPor uint      `gorm:"not null" json:"creado_por"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// EstadisticasWidget partidas atribuidas a un widget en un período
+type EstadisticasWidget struct {
+	WidgetID  uint `json:"-"`
+	Partidas  int  `json:"partidas"`
+	Ganadas   int  `json:"ganadas"`
+	Jugadores int  `json:"jugadores"`
+	Canjeados int  `json:"canjeados"`
+}
+
+// WidgetDetalle widget con sus orígenes, el snippet para pegar y sus estadísticas
+type WidgetDetalle struct {
+	*Widget
+	Origenes     []string           `json:"origenes"`
+	Snippet      string             `json:"snippet"`
+	Estadisticas EstadisticasWidget `json:"estadisticas"`
+}
+
+// CrearWidgetRequest request para autorizar un sitio externo
+type CrearWidgetRequest struct {
+	Nombre   string   `json:"nombre" binding:"required,min=2,max=100"`
+	Origenes []string `json:"origenes" binding:"required,min=1,max=10,dive,url"`
+}
+
+// ActualizarWidgetRequest request para editar un widget; los campos vacíos no cambian
+type ActualizarWidgetRequest struct {
+	Nombre   string   `json:"nombre" binding:"omitempty,min=2,max=100"`
+	Origenes []string `json:"origenes" binding:"omitempty,min=1,max=10,dive,url"`
+	Activo   *bool    `json:"activo"`
+}
+
+// Mesa mesa del local con su código QR. El número viaja en la URL del juego (?mesa=12)
+// para atribuir las partidas y avisar al personal dónde entregar los premios
+type Mesa struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	Numero    string    `gorm:"uniqueIndex;size:20;not null" json:"numero"` // Lo que se imprime en la mesa, ej. 12 o T3
+	Area      string    `gorm:"size:50;index" json:"area,omitempty"`        // Salón, Terraza, Barra...
+	Activa    bool      `gorm:"not null;default:true" json:"activa"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// EstadisticasMesa partidas jugadas desde el QR de una mesa en un período
+type EstadisticasMesa struct {
+	MesaID    uint `json:"-"`
+	Partidas  int  `json:"partidas"`
+	Ganadas   int  `json:"ganadas"`
+	Jugadores int  `json:"jugadores"`
+	Canjeados int  `json:"canjeados"`
+}
+
+// EstadisticasArea partidas de todas las mesas de un área
+type EstadisticasArea struct {
+	Area      string `json:"area"`
+	Mesas     int    `json:"mesas"`
+	Partidas  int    `json:"partidas"`
+	Ganadas   int    `json:"ganadas"`
+	Jugadores int    `json:"jugadores"`
+	Canjeados int    `json:"canjeados"`
+}
+
+// MesaDetalle mesa con la URL de su QR y sus estadísticas
+type MesaDetalle struct {
+	*Mesa
+	URL          string           `json:"url"`
+	Estadisticas EstadisticasMesa `json:"estadisticas"`
+}
+
+// CrearMesaRequest request para dar de alta una mesa
+type CrearMesaRequest struct {
+	Numero string `json:"numero" binding:"required,max=20"`
+	Area   string `json:"area" binding:"omitempty,max=50"`
+}
+
+// ActualizarMesaRequest request para editar una mesa; los campos vacíos no cambian
+type ActualizarMesaRequest struct {
+	Numero string `json:"numero" binding:"omitempty,max=20"`
+	Area   string `json:"area" binding:"omitempty,max=50"`
+	Activa *bool  `json:"activa"`
+}
+
+// ReglaRecomendacion regla del motor de recomendaciones: según el resultado de la
+// partida y el tipo de cliente elige a qué productos aplica el voucher y qué sugerirle.
+// Se evalúan por prioridad (menor primero) y aplica la primera que coincide
+type ReglaRecomendacion struct {
+	ID          uint      `gorm:"primaryKey" json:"id"`
+	Nombre      string    `gorm:"size:100;not null" json:"nombre"`
+	Resultado   string    `gorm:"type:enum('ganado','perdido','cualquiera');not null;default:'cualquiera'" json:"resultado"`
+	TipoCliente string    `gorm:"size:20" json:"tipo_cliente,omitempty"` // Vacío = todos
+	Alcance     string    `gorm:"size:100" json:"alcance,omitempty"`     // Ej. Postres, Upgrade a combo
+	Mensaje     string    `gorm:"size:300;not null" json:"mensaje"`      // Admite {nombre} y {descuento}
+	Prioridad   int       `gorm:"not null;default:0" json:"prioridad"`
+	Activa      bool      `gorm:"not null;default:true" json:"activa"`
+	CreadoPor   uint      `gorm:"not null" json:"creado_por"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// EstadisticasRegla vouchers emitidos y canjeados con una regla
+type EstadisticasRegla struct {
+	ReglaID   uint    `json:"-"`
+	Emitidos  int     `json:"emitidos"`
+	Canjeados int     `json:"canjeados"`
+	TasaCanje float64 `json:"tasa_canje" gorm:"-"` // Porcentaje
+}
+
+// ReglaRecomendacionDetalle regla con sus resultados en el período
+type ReglaRecomendacionDetalle struct {
+	*ReglaRecomendacion
+	Estadisticas EstadisticasRegla `json:"estadisticas"`
+}
+
+// GuardarReglaRecomendacionRequest request para crear o editar una regla
+type GuardarReglaRecomendacionRequest struct {
+	Nombre      string `json:"nombre" binding:"required,min=2,max=100"`
+	Resultado   string `json:"resultado" binding:"required,oneof=ganado perdido cualquiera"`
+	TipoCliente string `json:"tipo_cliente" binding:"omitempty,oneof=nuevo ocasional frecuente"`
+	Alcance     string `json:"alcance" binding:"max=100"`
+	Mensaje     string `json:"mensaje" binding:"required,max=300"`
+	Prioridad   int    `json:"prioridad" binding:"min=0,max=1000"`
+	Activa      *bool  `json:"activa"`
+}
+
+// ReglaVigencia días de validez de los vouchers de partida según el tipo y el día de la
+// semana en que se emiten. Gana la regla más específica; sin regla se usa VOUCHER_VALIDITY_DAYS
+type ReglaVigencia struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	Tipo      string    `gorm:"type:enum('juego_ganado','juego_perdido','cualquiera');not null;default:'cualquiera'" json:"tipo"`
+	DiaSemana *int      `json:"dia_semana"` // 0 = domingo; nil = todos los días
+	Dias      int       `gorm:"not null" json:"dias"`
+	CreadoPor uint      `gorm:"not null" json:"creado_por"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// GuardarReglaVigenciaRequest request para crear o editar una regla de vigencia
+type GuardarReglaVigenciaRequest struct {
+	Tipo      string `json:"tipo" binding:"required,oneof=juego_ganado juego_perdido cualquiera"`
+	DiaSemana *int   `json:"dia_semana" binding:"omitempty,min=0,max=6"`
+	Dias      int    `json:"dias" binding:"required,min=1,max=365"`
+}
+
+// RendimientoVouchers resultados de los vouchers de partida flash o estándar en un período
+type RendimientoVouchers struct {
+	Flash             bool    `json:"flash"`
+	Emitidos          int     `json:"emitidos"`
+	Canjeados         int     `json:"canjeados"`
+	Vencidos          int     `json:"vencidos"`            // Sin canjear y ya vencidos
+	TasaCanje         float64 `json:"tasa_canje" gorm:"-"` // Porcentaje
+	MinutosHastaCanje float64 `json:"minutos_hasta_canje"` // Promedio entre emisión y canje
+}
+
+// IntentoCanje canje rechazado en caja. Se guarda para detectar empleados que prueban
+// vouchers vencidos, ajenos o ya usados
+type IntentoCanje struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	UsuarioID uint      `gorm:"not null;index" json:"usuario_id"`
+	Codigo    string    `gorm:"size:20;not null" json:"codigo"`
+	Motivo    string    `gorm:"type:enum('invalido','usado','vencido','sucursal','dia_sin_canje','limite','aprobacion');not null" json:"motivo"`
+	Descuento int       `json:"descuento"`
+	CreatedAt time.Time `gorm:"index" json:"created_at"`
+}
+
+// ActualizarLimiteCanjesRequest request para fijar el tope diario de canjes de un empleado
+type ActualizarLimiteCanjesRequest struct {
+	LimiteCanjes *int `json:"limite_canjes_diario" binding:"omitempty,min=0,max=10000"` // null = volver al de la cadena
+}
+
+// ActividadCanjeEmpleado canjes e intentos rechazados de un empleado en el período
+type ActividadCanjeEmpleado struct {
+	UsuarioID        uint     `json:"usuario_id"`
+	Nombre           string   `json:"nombre"`
+	Sucursal         string   `json:"sucursal,omitempty"`
+	Canjes           int      `json:"canjes"`
+	CanjesAltoValor  int      `json:"canjes_alto_valor"`
+	IntentosVencidos int      `json:"intentos_vencidos"`
+	IntentosOtros    int      `json:"intentos_otros"`               // Usados, inválidos, de otra sucursal, etc.
+	TasaAltoValor    float64  `json:"tasa_alto_valor" gorm:"-"`     // Porcentaje de sus canjes
+	TasaVencidos     float64  `json:"tasa_vencidos" gorm:"-"`       // Porcentaje de sus canjes + intentos vencidos
+	Anomalias        []string `json:"anomalias,omitempty" gorm:"-"` // Vacío = sin nada raro
+}
+
+// ReporteCanjesEmpleados actividad de canje por empleado comparada con el promedio de la cadena
+type ReporteCanjesEmpleados struct {
+	Desde                 time.Time                `json:"desde"`
+	DescuentoAltoValor    int                      `json:"descuento_alto_valor"`
+	TasaAltoValorPromedio float64                  `json:"tasa_alto_valor_promedio"`
+	TasaVencidosPromedio  float64                  `json:"tasa_vencidos_promedio"`
+	Empleados             []ActividadCanjeEmpleado `json:"empleados"`
+}
+
+// EjecucionProgramada corrida prevista de un reporte programado
+type EjecucionProgramada struct {
+	ReporteID    uint      `json:"reporte_id"`
+	Nombre       string    `json:"nombre"`
+	Programacion string    `json:"programacion"`
+	Fecha        time.Time `json:"fecha"`
+}
+
+// CalendarioMes actividad programada de un mes día por día, para planificar promociones
+// sin superponerlas
+type CalendarioMes struct {
+	Mes                  string          `json:"mes"`                   // AAAA-MM
+	PromedioVencimientos float64         `json:"promedio_vencimientos"` // Vouchers sin usar que vencen por día en el mes
+	Dias                 []DiaCalendario `json:"dias"`
+}
+
+// DiaCalendario eventos de un día del calendario
+type DiaCalendario struct {
+	Fecha             string             `json:"fecha"` // AAAA-MM-DD
+	SinCanje          bool               `json:"sin_canje"`
+	VouchersQueVencen int                `json:"vouchers_que_vencen"`
+	PicoVencimientos  bool               `json:"pico_vencimientos"`
+	Eventos           []EventoCalendario `json:"eventos"`
+	Avisos            []string           `json:"avisos,omitempty"` // Promociones que se pisan ese día
+}
+
+// EventoCalendario actividad programada en un día
+type EventoCalendario struct {
+	Tipo    string `json:"tipo"` // campana_inicio, campana_vencimiento, reporte_programado, mensajes_programados, dia_sin_canje
+	ID      uint   `json:"id,omitempty"`
+	Titulo  string `json:"titulo"`
+	Detalle string `json:"detalle,omitempty"`
+}
+
+// MetaMensual objetivos promocionales del mes fijados por el dueño. 0 = sin meta
+type MetaMensual struct {
+	ID             uint      `gorm:"primaryKey" json:"id"`
+	Mes            string    `gorm:"size:7;not null;uniqueIndex" json:"mes"` // AAAA-MM
+	Partidas       int       `gorm:"not null;default:0" json:"partidas"`
+	ClientesNuevos int       `gorm:"not null;default:0" json:"clientes_nuevos"`
+	Canjes         int       `gorm:"not null;default:0" json:"canjes"`
+	TasaCanje      float64   `gorm:"type:decimal(5,2);not null;default:0" json:"tasa_canje"` // Porcentaje de vouchers emitidos en el mes que se canjean
+	UpdatedBy      uint      `json:"updated_by,omitempty"`
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
+
+// GuardarMetaMensualRequest request para fijar las metas de un mes
+type GuardarMetaMensualRequest struct {
+	Partidas       int     `json:"partidas" binding:"min=0"`
+	ClientesNuevos int     `json:"clientes_nuevos" binding:"min=0"`
+	Canjes         int     `json:"canjes" binding:"min=0"`
+	TasaCanje      float64 `json:"tasa_canje" binding:"min=0,max=100"`
+}
+
+// IndicadoresPromocion valores de los indicadores promocionales en un período
+type IndicadoresPromocion struct {
+	Partidas       int     `json:"partidas"`
+	ClientesNuevos int     `json:"clientes_nuevos"`
+	Canjes         int     `json:"canjes"`
+	TasaCanje      float64 `json:"tasa_canje"`
+}
+
+// ProgresoIndicador avance de un indicador respecto de su meta del mes
+type ProgresoIndicador struct {
+	Indicador  string  `json:"indicador"`
+	Meta       float64 `json:"meta"`
+	Actual     float64 `json:"actual"`
+	Porcentaje float64 `json:"porcentaje"` // Actual sobre meta
+	Proyectado float64 `json:"proyectado"` // Al ritmo actual, a fin de mes
+	EnCamino   bool    `json:"en_camino"`
+}
+
+// VariacionIndicador valor de un indicador en el período actual y en el de comparación.
+// Porcentaje es nil si el período anterior fue 0 (no hay base para compararlo); en la
+// tasa de canje la diferencia son puntos porcentuales
+type VariacionIndicador struct {
+	Actual     float64  `json:"actual"`
+	Anterior   float64  `json:"anterior"`
+	Diferencia float64  `json:"diferencia"`
+	Porcentaje *float64 `json:"porcentaje"`
+	Tendencia  string   `json:"tendencia"` // sube, baja, igual
+}
+
+// ComparacionPeriodos indicadores del período en curso (hasta ahora) contra el mismo tramo
+// del período de comparación: la semana pasada o el mismo mes del año anterior
+type ComparacionPeriodos struct {
+	Periodo       string                        `json:"periodo"` // semana, mes
+	Desde         time.Time                     `json:"desde"`
+	Hasta         time.Time                     `json:"hasta"`
+	AnteriorDesde time.Time                     `json:"anterior_desde"`
+	AnteriorHasta time.Time                     `json:"anterior_hasta"`
+	Indicadores   map[string]VariacionIndicador `json:"indicadores"`
+}
+
+// ProgresoMetas avance del mes contra las metas fijadas
+type ProgresoMetas struct {
+	Mes          string               `json:"mes"`
+	DiasTotales  int                  `json:"dias_totales"`
+	DiasCorridos int                  `json:"dias_corridos"`
+	Meta         *MetaMensual         `json:"meta"` // nil = sin metas cargadas
+	Actual       IndicadoresPromocion `json:"actual"`
+	Indicadores  []ProgresoIndicador  `json:"indicadores"` // Solo los que tienen meta
+}
+
+// Ámbitos del historial de cambios de configuración
+const (
+	AmbitoJuego   = "juego"
+	AmbitoMensaje = "mensaje"
+)
+
+// ParametrosJuego parámetros del juego editables en caliente desde el panel. Hay una
+// sola fila; si no existe rigen los valores de la configuración
+type ParametrosJuego struct {
+	ID                uint    `gorm:"primaryKey" json:"-"`
+	TiempoMin         float64 `gorm:"type:decimal(5,2);not null" json:"tiempo_min"`
+	TiempoMax         float64 `gorm:"type:decimal(5,2);not null" json:"tiempo_max"`
+	DescuentoGanador  int     `gorm:"not null" json:"descuento_ganador"`
+	DescuentoPerdedor int     `gorm:"not null" json:"descuento_perdedor"`
+	Tolerancia        float64 `gorm:"type:decimal(5,3);not null" json:"tolerancia"`
+	// Envíos de partida por hora aceptados por IP y por teléfono; 0 en la base = el de la configuración
+	LimiteIP       int       `gorm:"not null;default:0" json:"limite_ip"`
+	LimiteTelefono int       `gorm:"not null;default:0" json:"limite_telefono"`
+	UpdatedBy      uint      `json:"updated_by,omitempty"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
+
+// ActualizarParametrosJuegoRequest request para editar los parámetros del juego. Los
+// campos omitidos no cambian
+type ActualizarParametrosJuegoRequest struct {
+	TiempoMin         *float64 `json:"tiempo_min" binding:"omitempty,gt=0,lte=30"`
+	TiempoMax         *float64 `json:"tiempo_max" binding:"omitempty,gt=0,lte=30"`
+	DescuentoGanador  *int     `json:"descuento_ganador" binding:"omitempty,min=1,max=100"`
+	DescuentoPerdedor *int     `json:"descuento_perdedor" binding:"omitempty,min=0,max=100"`
+	Tolerancia        *float64 `json:"tolerancia" binding:"omitempty,gt=0"`
+	LimiteIP          *int     `json:"limite_ip" binding:"omitempty,min=1,max=10000"`
+	LimiteTelefono    *int     `json:"limite_telefono" binding:"omitempty,min=1,max=10000"`
+	Motivo            string   `json:"motivo" binding:"max=300"`
+	Confirmar         bool     `json:"confirmar"` // Necesario para cambios bruscos (más del triple o menos de un tercio)
+}
+
+// CambioConfiguracion versión de un valor de configuración editable: quién lo cambió,
+// cuándo y de qué valor a cuál. Las reversiones quedan como un cambio más
+type CambioConfiguracion struct {
+	ID            uint      `gorm:"primaryKey" json:"id"`
+	Ambito        string    `gorm:"type:enum('juego','mensaje');not null;index:idx_cambio_clave" json:"ambito"`
+	Clave         string    `gorm:"size:50;not null;index:idx_cambio_clave" json:"clave"` // Parámetro del juego o clave del mensaje
+	ValorAnterior string    `gorm:"type:text" json:"valor_anterior"`
+	ValorNuevo    string    `gorm:"type:text" json:"valor_nuevo"`
+	Motivo        string    `gorm:"size:300" json:"motivo,omitempty"`
+	UsuarioID     uint      `gorm:"not null" json:"usuario_id"`
+	RevierteA     *uint     `json:"revierte_a,omitempty"` // Cambio que esta reversión deshace
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+// Paginacion página, tamaño y orden pedidos a un listado. Orden es un campo de la lista
+// blanca del listado, con "-" adelante para orden descendente (ej. "-created_at")
+type Paginacion struct {
+	Pagina       int    // Desde 1
+	TamanoPagina int    // 0 = sin límite (solo para usos internos)
+	Orden        string // Vacío = orden por defecto del listado
+}
+
+// Offset cantidad de filas a saltear para llegar a la página
+func (p Paginacion) Offset() int {
+	if p.Pagina <= 1 || p.TamanoPagina <= 0 {
+		return 0
+	}
+	return (p.Pagina - 1) * p.TamanoPagina
+}
+
+// Meta arma los metadatos de la página para la respuesta, a partir del total de filas
+func (p Paginacion) Meta(total int64) MetaPaginacion {
+	meta := MetaPaginacion{
+		Pagina:       max(p.Pagina, 1),
+		TamanoPagina: p.TamanoPagina,
+		Orden:        p.Orden,
+		Total:        total,
+		TotalPaginas: 1,
+	}
+	if p.TamanoPagina > 0 {
+		meta.TotalPaginas = int((total + int64(p.TamanoPagina) - 1) / int64(p.TamanoPagina))
+	}
+	if meta.Pagina > 1 {
+		anterior := min(meta.Pagina-1, max(meta.TotalPaginas, 1))
+		meta.Anterior = &anterior
+	}
+	if meta.Pagina < meta.TotalPaginas {
+		siguiente := meta.Pagina + 1
+		meta.Siguiente = &siguiente
+	}
+	return meta
+}
+
+// MetaPaginacion metadatos de una página de resultados
+type MetaPaginacion struct {
+	Pagina       int    `json:"pagina"`
+	TamanoPagina int    `json:"tamano_pagina"`
+	Orden        string `json:"orden,omitempty"`
+	Total        int64  `json:"total"`
+	TotalPaginas int    `json:"total_paginas"`
+	Siguiente    *int   `json:"siguiente"` // nil en la última página
+	Anterior     *int   `json:"anterior"`  // nil en la primera página
+}
+
+// Trabajo estado de una operación larga que corre en segundo plano (envío de campaña,
+// exportación). Vive en memoria: se consulta mientras corre y un rato después de terminar
+type Trabajo struct {
+	ID             string         `json:"id"`
+	Tipo           string         `json:"tipo"` // campana, exportacion, mantenimiento
+	Descripcion    string         `json:"descripcion"`
+	Estado         string         `json:"estado"` // en_curso, completado, fallido, cancelado
+	Total          int            `json:"total"`
+	Procesados     int            `json:"procesados"`
+	Errores        int            `json:"errores"`
+	Porcentaje     int            `json:"porcentaje"`
+	MuestraErrores []ErrorTrabajo `json:"muestra_errores"` // Los primeros errores por ítem
+	Resultado      interface{}    `json:"resultado,omitempty"`
+	Error          string         `json:"error,omitempty"`
+	Cancelable     bool           `json:"cancelable"`
+	IniciadoEn     time.Time      `json:"iniciado_en"`
+	FinalizadoEn   *time.Time     `json:"finalizado_en,omitempty"`
+}
+
+// ErrorTrabajo error de un ítem dentro de un trabajo
+type ErrorTrabajo struct {
+	Item  string `json:"item"`
+	Error string `json:"error"`
+}
+
+// TareaProgramada tarea de mantenimiento que corre sola según su programación; cada
+// ejecución queda registrada como un trabajo de tipo "mantenimiento"
+type TareaProgramada struct {
+	Nombre           string     `json:"nombre"`
+	Descripcion      string     `json:"descripcion"`
+	Programacion     string     `json:"programacion"`                // ej. "diaria 03:30", "cada 1h0m0s"
+	ProximaEjecucion *time.Time `json:"proxima_ejecucion,omitempty"` // nil si el programador está apagado
+	UltimaEjecucion  *time.Time `json:"ultima_ejecucion,omitempty"`
+	UltimoTrabajo    *Trabajo   `json:"ultimo_trabajo,omitempty"` // nil si ya se descartó de memoria
+}
+
+// EstadisticaDiaria actividad de un día cerrado, calculada por el mantenimiento nocturno
+// para ver la evolución sin recorrer partidas y vouchers cada vez
+type EstadisticaDiaria struct {
+	ID                uint      `gorm:"primaryKey" json:"id"`
+	Fecha             time.Time `gorm:"type:date;not null;uniqueIndex" json:"fecha"`
+	Partidas          int       `gorm:"not null;default:0" json:"partidas"`
+	VouchersEmitidos  int       `gorm:"not null;default:0" json:"vouchers_emitidos"` // De partidas
+	VouchersCanjeados int       `gorm:"not null;default:0" json:"vouchers_canjeados"`
+	ClientesNuevos    int       `gorm:"not null;default:0" json:"clientes_nuevos"`
+	CreatedAt         time.Time `json:"created_at"`
+	UpdatedAt         time.Time `json:"updated_at"`
+}
+
+// EstadoCompartido valor efímero que comparten las instancias del servidor detrás del
+// balanceador (partidas en curso, límites de envío, tokens revocados, liderazgo). Version
+// cambia en cada escritura para detectar si otra instancia lo modificó en el medio
+type EstadoCompartido struct {
+	Clave   string    `gorm:"primaryKey;size:191"`
+	Valor   string    `gorm:"type:text"`
+	Version int64     `gorm:"not null;default:1"`
+	VenceEn time.Time `gorm:"not null;index"`
+}
+
+func (EstadoCompartido) TableName() string { return "estado_compartido" }
+
+// PosicionRanking mejor partida de un cliente en el período del ranking. En el ranking
+// público solo van el nombre reducido ("Juan P.") y la diferencia
+type PosicionRanking struct {
+	Posicion           int      `json:"posicion"`
+	Nombre             string   `json:"nombre"`
+	DiferenciaSegundos float64  `json:"diferencia_segundos"`
+	ClienteID          uint     `json:"cliente_id,omitempty"` // Solo en el panel
+	Partidas           int      `json:"partidas,omitempty"`   // Solo en el panel
+	Cliente            *Cliente `json:"cliente,omitempty"`    // Solo en el panel
+}
+
+// Ranking posiciones del período en curso: desde el lunes o desde el último cierre a mano
+type Ranking struct {
+	Desde      time.Time         `json:"desde"`
+	Hasta      time.Time         `json:"hasta"` // Cuando cierra la semana
+	Posiciones []PosicionRanking `json:"posiciones"`
+}
+
+// CierreRanking período cerrado del ranking con su ganador. La semana se cierra sola el
+// lunes; un admin puede cerrarla antes y el ranking vuelve a empezar desde ese momento
+type CierreRanking struct {
+	ID                 uint      `gorm:"primaryKey" json:"id"`
+	Desde              time.Time `gorm:"not null;uniqueIndex" json:"desde"` // Único: dos cierres del mismo período no pasan
+	Hasta              time.Time `gorm:"not null;index" json:"hasta"`
+	ClienteID          *uint     `gorm:"index" json:"cliente_id,omitempty"` // NULL = nadie jugó en el período
+	DiferenciaSegundos *float64  `gorm:"type:decimal(6,3)" json:"diferencia_segundos,omitempty"`
+	Participantes      int       `gorm:"not null;default:0" json:"participantes"`
+	VoucherID          *uint     `json:"voucher_id,omitempty"`  // Premio del ganador, si se emitió
+	CerradoPor         *uint     `json:"cerrado_por,omitempty"` // NULL = cierre automático del lunes
+	CreatedAt          time.Time `json:"created_at"`
+
+	// Relaciones
+	Cliente *Cliente `gorm:"foreignKey:ClienteID" json:"cliente,omitempty"`
+	Voucher *Voucher `gorm:"foreignKey:VoucherID" json:"voucher,omitempty"`
+}
+
+func (CierreRanking) TableName() string { return "cierres_ranking" }
+
+// CerrarRankingRequest request para cerrar el período del ranking antes del lunes
+type CerrarRankingRequest struct {
+	EmitirVoucher bool `json:"emitir_voucher"`
+	Descuento     int  `json:"descuento" binding:"omitempty,min=1,max=100"` // 0 = el de LEADERBOARD_BONUS_DISCOUNT
+}
@@ -0,0 +1,159 @@
+// Package pdf arma documentos PDF de una página con texto en Helvetica e imágenes de
+// módulos blanco y negro (códigos de barras y QR), sin dependencias externas
+package pdf
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"strings"
+)
+
+// Medidas en puntos (1/72 de pulgada)
+const (
+	A6Ancho = 297.64
+	A6Alto  = 419.53
+)
+
+// imagen bitmap de 1 bit a dibujar en la página
+type imagen struct {
+	ancho, alto int
+	datos       []byte // Filas empaquetadas de a 8 píxeles, 0 = negro
+}
+
+// Pagina documento de una página; el origen de coordenadas es la esquina inferior izquierda
+type Pagina struct {
+	ancho, alto float64
+	contenido   bytes.Buffer
+	imagenes    []imagen
+}
+
+// NuevaPagina crea una página en blanco del tamaño indicado
+func NuevaPagina(ancho, alto float64) *Pagina {
+	return &Pagina{ancho: ancho, alto: alto}
+}
+
+// Texto escribe una línea en Helvetica (o Helvetica-Bold con negrita). Los caracteres
+// fuera de Latin-1 se reemplazan por "?"
+func (p *Pagina) Texto(x, y, tamano float64, negrita bool, texto string) {
+	fuente := "F1"
+	if negrita {
+		fuente = "F2"
+	}
+	fmt.Fprintf(&p.contenido, "BT /%s %.2f Tf %.2f %.2f Td (%s) Tj ET\n", fuente, tamano, x, y, escaparTexto(texto))
+}
+
+// TextoCentrado escribe una línea centrada horizontalmente en la página. El ancho se
+// estima con el promedio de Helvetica, que alcanza para textos cortos
+func (p *Pagina) TextoCentrado(y, tamano float64, negrita bool, texto string) {
+	ancho := float64(len([]rune(texto))) * tamano * 0.52
+	p.Texto((p.ancho-ancho)/2, y, tamano, negrita, texto)
+}
+
+// Modulos dibuja una matriz de módulos (true = oscuro) escalada al rectángulo indicado.
+// Un código de barras 1D se pasa como una sola fila
+func (p *Pagina) Modulos(x, y, ancho, alto float64, modulos [][]bool) {
+	if len(modulos) == 0 || len(modulos[0]) == 0 {
+		return
+	}
+	columnas := len(modulos[0])
+	porFila := (columnas + 7) / 8
+	datos := make([]byte, porFila*len(modulos))
+	for i := range datos {
+		datos[i] = 0xff
+	}
+	for fila, modulosFila := range modulos {
+		for columna, oscuro := range modulosFila {
+			if oscuro {
+				datos[fila*porFila+columna/8] &^= 0x80 >> (columna % 8)
+			}
+		}
+	}
+
+	nombre := len(p.imagenes) + 1
+	p.imagenes = append(p.imagenes, imagen{ancho: columnas, alto: len(modulos), datos: datos})
+	fmt.Fprintf(&p.contenido, "q %.2f 0 0 %.2f %.2f %.2f cm /Im%d Do Q\n", ancho, alto, x, y, nombre)
+}
+
+// Bytes serializa el documento
+func (p *Pagina) Bytes() ([]byte, error) {
+	contenido, err := comprimir(p.contenido.Bytes())
+	if err != nil {
+		return nil, err
+	}
+
+	var objetos [][]byte
+	agregar := func(formato string, args ...interface{}) {
+		objetos = append(objetos, []byte(fmt.Sprintf(formato, args...)))
+	}
+
+	var recursos strings.Builder
+	for i := range p.imagenes {
+		fmt.Fprintf(&recursos, " /Im%d %d 0 R", i+1, 7+i)
+	}
+	agregar("<< /Type /Catalog /Pages 2 0 R >>")
+	agregar("<< /Type /Pages /Kids [3 0 R] /Count 1 >>")
+	agregar("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %.2f %.2f] /Contents 6 0 R "+
+		"/Resources << /Font << /F1 4 0 R /F2 5 0 R >> /XObject <<%s >> >> >>", p.ancho, p.alto, recursos.String())
+	agregar("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>")
+	agregar("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica-Bold /Encoding /WinAnsiEncoding >>")
+	agregar("<< /Length %d /Filter /FlateDecode >>\nstream\n%s\nendstream", len(contenido), contenido)
+	for _, img := range p.imagenes {
+		datos, err := comprimir(img.datos)
+		if err != nil {
+			return nil, err
+		}
+		agregar("<< /Type /XObject /Subtype /Image /Width %d /Height %d /ColorSpace /DeviceGray "+
+			"/BitsPerComponent 1 /Length %d /Filter /FlateDecode >>\nstream\n%s\nendstream",
+			img.ancho, img.alto, len(datos), datos)
+	}
+
+	var doc bytes.Buffer
+	doc.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+	desplazamientos := make([]int, len(objetos))
+	for i, objeto := range objetos {
+		desplazamientos[i] = doc.Len()
+		fmt.Fprintf(&doc, "%d 0 obj\n", i+1)
+		doc.Write(objeto)
+		doc.WriteString("\nendobj\n")
+	}
+
+	inicioXref := doc.Len()
+	fmt.Fprintf(&doc, "xref\n0 %d\n0000000000 65535 f \n", len(objetos)+1)
+	for _, desplazamiento := range desplazamientos {
+		fmt.Fprintf(&doc, "%010d 00000 n \n", desplazamiento)
+	}
+	fmt.Fprintf(&doc, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(objetos)+1, inicioXref)
+	return doc.Bytes(), nil
+}
+
+// comprimir aplica FlateDecode a un stream
+func comprimir(datos []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	w := zlib.NewWriter(&buf)
+	if _, err := w.Write(datos); err != nil {
+		return nil, fmt.Errorf("error comprimiendo PDF: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return nil, fmt.Errorf("error comprimiendo PDF: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// escaparTexto pasa el texto a WinAnsi (igual a Latin-1 en los acentos del español) y
+// escapa los caracteres especiales de los strings de PDF
+func escaparTexto(texto string) string {
+	var buf strings.Builder
+	for _, r := range texto {
+		switch {
+		case r == '(' || r == ')' || r == '\\':
+			buf.WriteByte('\\')
+			buf.WriteByte(byte(r))
+		case r >= 32 && r < 127 || r >= 0xa0 && r <= 0xff:
+			buf.WriteByte(byte(r))
+		default:
+			buf.WriteByte('?')
+		}
+	}
+	return buf.String()
+}
@@ -0,0 +1,114 @@
+// Package planilla escribe exportaciones fila por fila en CSV o en XLSX, sin armar el
+// archivo completo en memoria, para abrirlas directamente en Excel
+package planilla
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Formatos soportados
+const (
+	FormatoCSV  = "csv"
+	FormatoXLSX = "xlsx"
+)
+
+// formatoFechaCSV formato de las fechas en el CSV, que Excel reconoce como fecha y hora
+const formatoFechaCSV = "2006-01-02 15:04:05"
+
+// Escritor recibe las filas de una exportación. Las celdas pueden ser texto, números,
+// time.Time o *time.Time (nil = celda vacía); las fechas se escriben en la zona horaria
+// que traen. Cerrar completa el archivo y es obligatorio
+type Escritor interface {
+	Fila(celdas ...interface{}) error
+	Cerrar() error
+}
+
+// Nuevo crea el escritor del formato indicado; hoja es el nombre de la hoja del XLSX
+func Nuevo(formato string, w io.Writer, hoja string) (Escritor, error) {
+	switch formato {
+	case FormatoCSV:
+		return NuevoCSV(w)
+	case FormatoXLSX:
+		return NuevoXLSX(w, hoja)
+	default:
+		return nil, fmt.Errorf("formato no soportado: %s", formato)
+	}
+}
+
+// TipoContenido Content-Type del formato para la descarga
+func TipoContenido(formato string) string {
+	if formato == FormatoXLSX {
+		return "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+	}
+	return "text/csv; charset=utf-8"
+}
+
+// escritorCSV escribe CSV en UTF-8 con BOM, así Excel respeta los acentos
+type escritorCSV struct {
+	w *csv.Writer
+}
+
+// NuevoCSV crea un escritor CSV sobre w
+func NuevoCSV(w io.Writer) (Escritor, error) {
+	if _, err := io.WriteString(w, "\ufeff"); err != nil {
+		return nil, err
+	}
+	return &escritorCSV{w: csv.NewWriter(w)}, nil
+}
+
+func (e *escritorCSV) Fila(celdas ...interface{}) error {
+	registro := make([]string, len(celdas))
+	for i, celda := range celdas {
+		registro[i] = textoCSV(celda)
+	}
+	return e.w.Write(registro)
+}
+
+func (e *escritorCSV) Cerrar() error {
+	e.w.Flush()
+	return e.w.Error()
+}
+
+// textoCSV convierte la celda a texto. Los textos que Excel tomaría como fórmula (datos
+// cargados por los clientes, como el nombre) se anteponen con un apóstrofo
+func textoCSV(celda interface{}) string {
+	switch valor := celda.(type) {
+	case nil:
+		return ""
+	case string:
+		return neutralizarFormula(valor)
+	case time.Time:
+		return valor.Format(formatoFechaCSV)
+	case *time.Time:
+		if valor == nil {
+			return ""
+		}
+		return valor.Format(formatoFechaCSV)
+	case float64:
+		return strconv.FormatFloat(valor, 'f', -1, 64)
+	default:
+		return fmt.Sprint(valor)
+	}
+}
+
+// neutralizarFormula evita que un texto se ejecute como fórmula al abrir el CSV. Los
+// números con signo (teléfonos, montos) se dejan como están
+func neutralizarFormula(texto string) string {
+	if texto == "" {
+		return texto
+	}
+	switch texto[0] {
+	case '=', '@', '\t', '\r':
+		return "'" + texto
+	case '+', '-':
+		if _, err := strconv.ParseFloat(strings.TrimPrefix(texto, "+"), 64); err != nil {
+			return "'" + texto
+		}
+	}
+	return texto
+}
@@ -0,0 +1,212 @@
+package planilla
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Estilos de celda definidos en estilosXLSX (índices de cellXfs)
+const (
+	estiloFecha      = 1
+	estiloEncabezado = 2
+)
+
+// origenFechasExcel día 0 de las fechas de Excel (serie 1900, con el 29/2/1900 ficticio)
+var origenFechasExcel = time.Date(1899, 12, 30, 0, 0, 0, 0, time.UTC)
+
+// escritorXLSX arma un libro con una sola hoja. Las partes fijas del zip se escriben al
+// crearlo y las filas van directo a la hoja a medida que llegan; la primera fila queda
+// en negrita e inmovilizada como encabezado
+type escritorXLSX struct {
+	zip  *zip.Writer
+	hoja io.Writer
+	fila int
+	buf  bytes.Buffer
+}
+
+// NuevoXLSX crea un escritor XLSX sobre w con una hoja del nombre indicado
+func NuevoXLSX(w io.Writer, hoja string) (Escritor, error) {
+	e := &escritorXLSX{zip: zip.NewWriter(w)}
+	partes := []struct{ nombre, contenido string }{
+		{"[Content_Types].xml", tiposContenidoXLSX},
+		{"_rels/.rels", relacionesXLSX},
+		{"xl/workbook.xml", fmt.Sprintf(libroXLSX, escaparXML(nombreHoja(hoja)))},
+		{"xl/_rels/workbook.xml.rels", relacionesLibroXLSX},
+		{"xl/styles.xml", estilosXLSX},
+	}
+	for _, parte := range partes {
+		archivo, err := e.zip.Create(parte.nombre)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := io.WriteString(archivo, parte.contenido); err != nil {
+			return nil, err
+		}
+	}
+
+	var err error
+	if e.hoja, err = e.zip.Create("xl/worksheets/sheet1.xml"); err != nil {
+		return nil, err
+	}
+	if _, err := io.WriteString(e.hoja, inicioHojaXLSX); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+func (e *escritorXLSX) Fila(celdas ...interface{}) error {
+	e.fila++
+	e.buf.Reset()
+	fmt.Fprintf(&e.buf, `<row r="%d">`, e.fila)
+	for i, celda := range celdas {
+		referencia := columnaExcel(i) + strconv.Itoa(e.fila)
+		if e.fila == 1 {
+			fmt.Fprintf(&e.buf, `<c r="%s" s="%d" t="inlineStr"><is><t xml:space="preserve">%s</t></is></c>`,
+				referencia, estiloEncabezado, escaparXML(fmt.Sprint(celda)))
+			continue
+		}
+		e.celda(referencia, celda)
+	}
+	e.buf.WriteString(`</row>`)
+	_, err := e.hoja.Write(e.buf.Bytes())
+	return err
+}
+
+func (e *escritorXLSX) Cerrar() error {
+	if _, err := io.WriteString(e.hoja, finHojaXLSX); err != nil {
+		return err
+	}
+	return e.zip.Close()
+}
+
+// celda agrega una celda a la fila en armado: números y fechas como valores (las fechas con
+// formato de fecha y hora), el resto como texto
+func (e *escritorXLSX) celda(referencia string, celda interface{}) {
+	var numero string
+	estilo := 0
+	switch valor := celda.(type) {
+	case nil:
+		return
+	case int:
+		numero = strconv.Itoa(valor)
+	case int64:
+		numero = strconv.FormatInt(valor, 10)
+	case uint:
+		numero = strconv.FormatUint(uint64(valor), 10)
+	case float64:
+		numero = strconv.FormatFloat(valor, 'f', -1, 64)
+	case *time.Time:
+		if valor == nil {
+			return
+		}
+		numero, estilo = serieExcel(*valor), estiloFecha
+	case time.Time:
+		numero, estilo = serieExcel(valor), estiloFecha
+	default:
+		texto := fmt.Sprint(valor)
+		if texto == "" {
+			return
+		}
+		fmt.Fprintf(&e.buf, `<c r="%s" t="inlineStr"><is><t xml:space="preserve">%s</t></is></c>`, referencia, escaparXML(texto))
+		return
+	}
+	if estilo != 0 {
+		fmt.Fprintf(&e.buf, `<c r="%s" s="%d"><v>%s</v></c>`, referencia, estilo, numero)
+		return
+	}
+	fmt.Fprintf(&e.buf, `<c r="%s"><v>%s</v></c>`, referencia, numero)
+}
+
+// serieExcel convierte la hora local de t (la que se ve en pantalla) en el número de
+// serie con que Excel guarda las fechas: días desde el origen con la hora como fracción
+func serieExcel(t time.Time) string {
+	local := time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), 0, time.UTC)
+	dias := local.Sub(origenFechasExcel).Seconds() / 86400
+	return strconv.FormatFloat(dias, 'f', 6, 64)
+}
+
+// columnaExcel letra de la columna i (0 = A, 26 = AA)
+func columnaExcel(i int) string {
+	columna := ""
+	for i++; i > 0; i = (i - 1) / 26 {
+		columna = string(rune('A'+(i-1)%26)) + columna
+	}
+	return columna
+}
+
+// nombreHoja adapta el nombre a lo que Excel admite: sin []:*?/\ y hasta 31 caracteres
+func nombreHoja(nombre string) string {
+	nombre = strings.Map(func(r rune) rune {
+		if strings.ContainsRune(`[]:*?/\`, r) {
+			return -1
+		}
+		return r
+	}, strings.TrimSpace(nombre))
+	if runas := []rune(nombre); len(runas) > 31 {
+		nombre = string(runas[:31])
+	}
+	if nombre == "" {
+		return "Hoja1"
+	}
+	return nombre
+}
+
+// escaparXML escapa el texto para el XML; los caracteres inválidos en XML se reemplazan
+func escaparXML(texto string) string {
+	var buf bytes.Buffer
+	xml.EscapeText(&buf, []byte(texto))
+	return buf.String()
+}
+
+const tiposContenidoXLSX = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">` +
+	`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>` +
+	`<Default Extension="xml" ContentType="application/xml"/>` +
+	`<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>` +
+	`<Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>` +
+	`<Override PartName="/xl/styles.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.styles+xml"/>` +
+	`</Types>`
+
+const relacionesXLSX = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+	`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>` +
+	`</Relationships>`
+
+const libroXLSX = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">` +
+	`<sheets><sheet name="%s" sheetId="1" r:id="rId1"/></sheets>` +
+	`</workbook>`
+
+const relacionesLibroXLSX = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+	`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/>` +
+	`<Relationship Id="rId2" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/styles" Target="styles.xml"/>` +
+	`</Relationships>`
+
+// estilosXLSX cellXfs: 0 normal, 1 fecha y hora (formato 22 de Excel), 2 encabezado en negrita
+const estilosXLSX = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<styleSheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">` +
+	`<fonts count="2"><font><sz val="11"/><name val="Calibri"/></font><font><b/><sz val="11"/><name val="Calibri"/></font></fonts>` +
+	`<fills count="2"><fill><patternFill patternType="none"/></fill><fill><patternFill patternType="gray125"/></fill></fills>` +
+	`<borders count="1"><border><left/><right/><top/><bottom/><diagonal/></border></borders>` +
+	`<cellStyleXfs count="1"><xf numFmtId="0" fontId="0" fillId="0" borderId="0"/></cellStyleXfs>` +
+	`<cellXfs count="3">` +
+	`<xf numFmtId="0" fontId="0" fillId="0" borderId="0" xfId="0"/>` +
+	`<xf numFmtId="22" fontId="0" fillId="0" borderId="0" xfId="0" applyNumberFormat="1"/>` +
+	`<xf numFmtId="0" fontId="1" fillId="0" borderId="0" xfId="0" applyFont="1"/>` +
+	`</cellXfs>` +
+	`<cellStyles count="1"><cellStyle name="Normal" xfId="0" builtinId="0"/></cellStyles>` +
+	`</styleSheet>`
+
+const inicioHojaXLSX = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">` +
+	`<sheetViews><sheetView workbookViewId="0"><pane ySplit="1" topLeftCell="A2" activePane="bottomLeft" state="frozen"/></sheetView></sheetViews>` +
+	`<sheetData>`
+
+const finHojaXLSX = `</sheetData></worksheet>`
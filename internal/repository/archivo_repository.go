@@ -0,0 +1,192 @@
+package repository
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+
+	"CheeseHouse/internal/models"
+)
+
+// ArchivoRepository define la interfaz para mover registros viejos a las tablas de archivo
+type ArchivoRepository interface {
+	ArchivarVouchers(antesDe time.Time, tamanoLote int) (vouchers int, envios int, err error)
+	ArchivarJuegos(antesDe time.Time, tamanoLote int) (int, error)
+	BuscarVouchers(filtros map[string]interface{}) ([]*models.Voucher, error)
+}
+
+// archivoRepository implementación de ArchivoRepository
+type archivoRepository struct {
+	db *gorm.DB
+}
+
+// NewArchivoRepository crea una nueva instancia del repositorio de archivo
+func NewArchivoRepository(db *gorm.DB) ArchivoRepository {
+	return &archivoRepository{db: db}
+}
+
+// ArchivarVouchers mueve a vouchers_archivo los vouchers emitidos antes de antesDe que ya
+// se canjearon o vencieron, salvo los premios del ranking. Cada lote se copia y borra en
+// una transacción; los envíos de campaña que apuntaban a esos vouchers quedan sin
+// voucher_id pero conservan el código y sus avisos del outbox se eliminan
+func (r *archivoRepository) ArchivarVouchers(antesDe time.Time, tamano int) (int, int, error) {
+	// El DDL va fuera de la transacción: en MySQL provoca un commit implícito
+	if err := r.db.Exec(copiarEstructura(r.db, models.TablaVouchersArchivo, "vouchers")).Error; err != nil {
+		return 0, 0, fmt.Errorf("error creando tabla de archivo: %w", err)
+	}
+
+	columnas, err := r.columnasArchivo(models.TablaVouchersArchivo)
+	if err != nil {
+		return 0, 0, err
+	}
+	insertar := fmt.Sprintf("INSERT INTO %s (%s) SELECT %s FROM vouchers WHERE id IN ?",
+		models.TablaVouchersArchivo, columnas, columnas)
+
+	archivados, desvinculados := 0, 0
+	for {
+		// Los premios de cierres del ranking quedan en vouchers: el cierre los sigue mostrando
+		premiosRanking := r.db.Model(&models.CierreRanking{}).
+			Select("voucher_id").
+			Where("voucher_id IS NOT NULL")
+
+		var ids []uint
+		if err := r.db.Model(&models.Voucher{}).
+			Where("created_at < ? AND (usado = TRUE OR fecha_vencimiento < ?)", antesDe, time.Now()).
+			Where("id NOT IN (?)", premiosRanking).
+			Order("id ASC").
+			Limit(tamanoLote(tamano)).
+			Pluck("id", &ids).Error; err != nil {
+			return archivados, desvinculados, fmt.Errorf("error buscando vouchers a archivar: %w", err)
+		}
+		if len(ids) == 0 {
+			break
+		}
+
+		err := r.db.Transaction(func(tx *gorm.DB) error {
+			envios := tx.Model(&models.ClientesVouchersEnvios{}).
+				Where("voucher_id IN ?", ids).
+				Update("voucher_id", nil)
+			if envios.Error != nil {
+				return fmt.Errorf("error desvinculando envíos: %w", envios.Error)
+			}
+			// Los avisos del outbox de vouchers ya canjeados o vencidos no tienen nada que
+			// avisar, enviados o no: se borran para que no bloqueen la clave foránea
+			if err := tx.Where("voucher_id IN ?", ids).Delete(&models.MensajeOutbox{}).Error; err != nil {
+				return fmt.Errorf("error eliminando avisos de vouchers archivados: %w", err)
+			}
+			if err := tx.Exec(insertar, ids).Error; err != nil {
+				return fmt.Errorf("error copiando vouchers al archivo: %w", err)
+			}
+			if err := tx.Delete(&models.Voucher{}, ids).Error; err != nil {
+				return fmt.Errorf("error eliminando vouchers archivados: %w", err)
+			}
+			desvinculados += int(envios.RowsAffected)
+			return nil
+		})
+		if err != nil {
+			return archivados, desvinculados, err
+		}
+		archivados += len(ids)
+	}
+
+	return archivados, desvinculados, nil
+}
+
+// ArchivarJuegos mueve a juegos_archivo las partidas jugadas antes de antesDe, en lotes
+// que se copian y borran en una transacción. Nada apunta a juegos, así que no hay que
+// desvincular nada; el voucher_id de las partidas archivadas puede quedar apuntando a
+// vouchers_archivo
+func (r *archivoRepository) ArchivarJuegos(antesDe time.Time, tamano int) (int, error) {
+	if err := r.db.Exec(copiarEstructura(r.db, models.TablaJuegosArchivo, "juegos")).Error; err != nil {
+		return 0, fmt.Errorf("error creando tabla de archivo de partidas: %w", err)
+	}
+
+	columnas, err := r.columnasArchivo(models.TablaJuegosArchivo)
+	if err != nil {
+		return 0, err
+	}
+	insertar := fmt.Sprintf("INSERT INTO %s (%s) SELECT %s FROM juegos WHERE id IN ?",
+		models.TablaJuegosArchivo, columnas, columnas)
+
+	archivados := 0
+	for {
+		var ids []uint
+		if err := r.db.Model(&models.Juego{}).
+			Where("created_at < ?", antesDe).
+			Order("id ASC").
+			Limit(tamanoLote(tamano)).
+			Pluck("id", &ids).Error; err != nil {
+			return archivados, fmt.Errorf("error buscando partidas a archivar: %w", err)
+		}
+		if len(ids) == 0 {
+			break
+		}
+
+		err := r.db.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Exec(insertar, ids).Error; err != nil {
+				return fmt.Errorf("error copiando partidas al archivo: %w", err)
+			}
+			if err := tx.Delete(&models.Juego{}, ids).Error; err != nil {
+				return fmt.Errorf("error eliminando partidas archivadas: %w", err)
+			}
+			return nil
+		})
+		if err != nil {
+			return archivados, err
+		}
+		archivados += len(ids)
+	}
+
+	return archivados, nil
+}
+
+// columnasArchivo lista las columnas de la tabla de archivo para copiar por nombre y no
+// por posición (la tabla original puede ganar columnas después de crear el archivo)
+func (r *archivoRepository) columnasArchivo(tabla string) (string, error) {
+	tipos, err := r.db.Migrator().ColumnTypes(tabla)
+	if err != nil {
+		return "", fmt.Errorf("error leyendo columnas del archivo: %w", err)
+	}
+
+	columnas := make([]string, 0, len(tipos))
+	for _, tipo := range tipos {
+		columnas = append(columnas, r.db.Statement.Quote(tipo.Name()))
+	}
+	return strings.Join(columnas, ", "), nil
+}
+
+// BuscarVouchers consulta vouchers archivados (codigo, cliente_id, tipo, fecha_desde, fecha_hasta, limit)
+func (r *archivoRepository) BuscarVouchers(filtros map[string]interface{}) ([]*models.Voucher, error) {
+	var vouchers []*models.Voucher
+	if !r.db.Migrator().HasTable(models.TablaVouchersArchivo) {
+		return vouchers, nil
+	}
+
+	query := r.db.Table(models.TablaVouchersArchivo).Preload("Cliente").Preload("UsuarioQueCanje")
+
+	if codigo, ok := filtros["codigo"]; ok {
+		query = query.Where("codigo = ?", codigo)
+	}
+	if clienteID, ok := filtros["cliente_id"]; ok {
+		query = query.Where("cliente_id = ?", clienteID)
+	}
+	if tipo, ok := filtros["tipo"]; ok {
+		query = query.Where("tipo = ?", tipo)
+	}
+	if fechaDesde, ok := filtros["fecha_desde"]; ok {
+		query = query.Where("fecha_emision >= ?", fechaDesde)
+	}
+	if fechaHasta, ok := filtros["fecha_hasta"]; ok {
+		query = query.Where("fecha_emision <= ?", fechaHasta)
+	}
+	if limit, ok := filtros["limit"]; ok {
+		query = query.Limit(limit.(int))
+	}
+
+	if err := query.Order("fecha_emision DESC").Find(&vouchers).Error; err != nil {
+		return nil, fmt.Errorf("error consultando vouchers archivados: %w", err)
+	}
+	return vouchers, nil
+}
@@ -0,0 +1,98 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+
+	"CheeseHouse/internal/models"
+)
+
+// AtencionRepository define la interfaz para el seguimiento de atenciones
+type AtencionRepository interface {
+	Crear(atencion *models.Atencion) error
+	Actualizar(atencion *models.Atencion) error
+	BuscarAbierta(telefono string) (*models.Atencion, error)
+	ListarAbiertas() ([]*models.Atencion, error)
+	GetPromedios(desde time.Time) (*PromediosAtencion, error)
+}
+
+// PromediosAtencion tiempos promedio de las atenciones de un período
+type PromediosAtencion struct {
+	Resueltas          int
+	RespuestaSegundos  float64
+	ResolucionSegundos float64
+}
+
+// atencionRepository implementación de AtencionRepository
+type atencionRepository struct {
+	db *gorm.DB
+}
+
+// NewAtencionRepository crea una nueva instancia del repositorio de atenciones
+func NewAtencionRepository(db *gorm.DB) AtencionRepository {
+	return &atencionRepository{db: db}
+}
+
+// Crear abre una nueva atención
+func (r *atencionRepository) Crear(atencion *models.Atencion) error {
+	if err := r.db.Create(atencion).Error; err != nil {
+		return fmt.Errorf("error creando atención: %w", err)
+	}
+	return nil
+}
+
+// Actualizar guarda los cambios de una atención
+func (r *atencionRepository) Actualizar(atencion *models.Atencion) error {
+	if err := r.db.Omit("Empleado").Save(atencion).Error; err != nil {
+		return fmt.Errorf("error actualizando atención: %w", err)
+	}
+	return nil
+}
+
+// BuscarAbierta retorna la atención abierta del teléfono, o nil si no hay
+func (r *atencionRepository) BuscarAbierta(telefono string) (*models.Atencion, error) {
+	var atencion models.Atencion
+	err := r.db.Preload("Empleado").
+		Where("telefono = ? AND estado = 'abierta'", telefono).
+		Order("id DESC").
+		First(&atencion).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("error buscando atención abierta: %w", err)
+	}
+	return &atencion, nil
+}
+
+// ListarAbiertas obtiene las atenciones abiertas, las más antiguas primero
+func (r *atencionRepository) ListarAbiertas() ([]*models.Atencion, error) {
+	var atenciones []*models.Atencion
+	if err := r.db.Preload("Empleado").
+		Where("estado = 'abierta'").
+		Order("primer_mensaje_at ASC").
+		Find(&atenciones).Error; err != nil {
+		return nil, fmt.Errorf("error listando atenciones abiertas: %w", err)
+	}
+	return atenciones, nil
+}
+
+// GetPromedios calcula los tiempos promedio de primera respuesta y resolución
+// de las atenciones resueltas desde la fecha indicada
+func (r *atencionRepository) GetPromedios(desde time.Time) (*PromediosAtencion, error) {
+	var promedios PromediosAtencion
+	if err := r.db.Model(&models.Atencion{}).
+		Select(fmt.Sprintf(`COUNT(*) AS resueltas,
+			COALESCE(AVG(%s), 0) AS respuesta_segundos,
+			COALESCE(AVG(%s), 0) AS resolucion_segundos`,
+			segundosEntre(r.db, "primer_mensaje_at", "primera_respuesta_at"),
+			segundosEntre(r.db, "primer_mensaje_at", "resuelta_at"))).
+		Where("estado = 'resuelta' AND resuelta_at >= ?", desde).
+		Scan(&promedios).Error; err != nil {
+		return nil, fmt.Errorf("error calculando tiempos de atención: %w", err)
+	}
+	return &promedios, nil
+}
@@ -0,0 +1,86 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+
+	"CheeseHouse/internal/models"
+)
+
+// BloqueoRepository define la interfaz para la lista de bloqueo
+type BloqueoRepository interface {
+	Crear(bloqueo *models.Bloqueo) error
+	BuscarPorID(id uint) (*models.Bloqueo, error)
+	Actualizar(bloqueo *models.Bloqueo) error
+	Listar(incluirInactivos bool) ([]*models.Bloqueo, error)
+	BuscarVigente(tipo, valor string) (*models.Bloqueo, error)
+}
+
+// bloqueoRepository implementación de BloqueoRepository
+type bloqueoRepository struct {
+	db *gorm.DB
+}
+
+// NewBloqueoRepository crea una nueva instancia del repositorio de bloqueos
+func NewBloqueoRepository(db *gorm.DB) BloqueoRepository {
+	return &bloqueoRepository{db: db}
+}
+
+// Crear registra un nuevo bloqueo
+func (r *bloqueoRepository) Crear(bloqueo *models.Bloqueo) error {
+	if err := r.db.Create(bloqueo).Error; err != nil {
+		return fmt.Errorf("error creando bloqueo: %w", err)
+	}
+	return nil
+}
+
+// BuscarPorID busca un bloqueo por ID
+func (r *bloqueoRepository) BuscarPorID(id uint) (*models.Bloqueo, error) {
+	var bloqueo models.Bloqueo
+	if err := r.db.First(&bloqueo, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("bloqueo con ID %d no encontrado", id)
+		}
+		return nil, fmt.Errorf("error buscando bloqueo: %w", err)
+	}
+	return &bloqueo, nil
+}
+
+// Actualizar guarda los cambios de un bloqueo
+func (r *bloqueoRepository) Actualizar(bloqueo *models.Bloqueo) error {
+	if err := r.db.Save(bloqueo).Error; err != nil {
+		return fmt.Errorf("error actualizando bloqueo: %w", err)
+	}
+	return nil
+}
+
+// Listar obtiene los bloqueos vigentes, o todo el historial si se pide
+func (r *bloqueoRepository) Listar(incluirInactivos bool) ([]*models.Bloqueo, error) {
+	var bloqueos []*models.Bloqueo
+	query := r.db.Preload("UsuarioCreador").Order("created_at DESC")
+	if !incluirInactivos {
+		query = query.Where("activo = TRUE AND (expira_en IS NULL OR expira_en > ?)", time.Now())
+	}
+	if err := query.Find(&bloqueos).Error; err != nil {
+		return nil, fmt.Errorf("error listando bloqueos: %w", err)
+	}
+	return bloqueos, nil
+}
+
+// BuscarVigente retorna el bloqueo activo y no vencido para el valor, o nil si no hay
+func (r *bloqueoRepository) BuscarVigente(tipo, valor string) (*models.Bloqueo, error) {
+	var bloqueo models.Bloqueo
+	err := r.db.Where("tipo = ? AND valor = ? AND activo = TRUE AND (expira_en IS NULL OR expira_en > ?)",
+		tipo, valor, time.Now()).
+		First(&bloqueo).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("error consultando lista de bloqueo: %w", err)
+	}
+	return &bloqueo, nil
+}
@@ -0,0 +1,46 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+
+	"gorm.io/gorm"
+
+	"CheeseHouse/internal/models"
+)
+
+// BrandingRepository define la interfaz para el branding configurable
+type BrandingRepository interface {
+	Obtener() (*models.Branding, error)
+	Guardar(branding *models.Branding) error
+}
+
+// brandingRepository implementación de BrandingRepository
+type brandingRepository struct {
+	db *gorm.DB
+}
+
+// NewBrandingRepository crea una nueva instancia del repositorio de branding
+func NewBrandingRepository(db *gorm.DB) BrandingRepository {
+	return &brandingRepository{db: db}
+}
+
+// Obtener retorna el branding guardado, o nil si nunca se configuró
+func (r *brandingRepository) Obtener() (*models.Branding, error) {
+	var branding models.Branding
+	if err := r.db.Order("id ASC").First(&branding).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("error obteniendo branding: %w", err)
+	}
+	return &branding, nil
+}
+
+// Guardar crea o actualiza el branding
+func (r *brandingRepository) Guardar(branding *models.Branding) error {
+	if err := r.db.Save(branding).Error; err != nil {
+		return fmt.Errorf("error guardando branding: %w", err)
+	}
+	return nil
+}
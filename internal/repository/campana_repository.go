@@ -1,32 +1,57 @@
 package repository
 
 import (
+	"errors"
 	"fmt"
 	"time"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 
 	"CheeseHouse/internal/models"
 )
 
+// ErrEnvioDuplicado se retorna cuando alguno de los envíos ya existía (misma campaña y
+// cliente): otro envío de la campaña se registró a la vez
+var ErrEnvioDuplicado = errors.New("la campaña ya tiene envío para alguno de esos clientes")
+
 // CampanaRepository define la interfaz para operaciones con campañas
 type CampanaRepository interface {
 	// CRUD básico
 	Crear(campana *models.CampanaClientesVouchers) error
 	BuscarPorID(id uint) (*models.CampanaClientesVouchers, error)
+	BloquearParaEnvio(id uint) (*models.CampanaClientesVouchers, error)
 	Actualizar(campana *models.CampanaClientesVouchers) error
 	Eliminar(id uint) error
 	ListarTodas() ([]*models.CampanaClientesVouchers, error)
 	ListarActivas() ([]*models.CampanaClientesVouchers, error)
+	ListarEntre(desde, hasta time.Time) ([]*models.CampanaClientesVouchers, error)
 
 	// Gestión de envíos
 	CrearEnvio(envio *models.ClientesVouchersEnvios) error
+	CrearEnviosEnLote(envios []*models.ClientesVouchersEnvios, tamanoLote int) error
 	GetEnviosPorCampana(campanaID uint) ([]*models.ClientesVouchersEnvios, error)
+	GetClientesConEnvio(campanaID uint) (map[uint]bool, error)
 	ActualizarEstadoEnvio(envioID uint, estado string, errorMsg string) error
+	AsociarMensajeEnvio(envioID uint, messageID string, outboxID *uint) error
+	ActualizarEstadoPorMensaje(messageID, estado string, momento time.Time, errorMsg string) (int64, error)
+	GetEnviosPendientesReintento(maxIntentos int) ([]*models.ClientesVouchersEnvios, error)
 
 	// Estadísticas de campañas
 	GetEstadisticasCampana(campanaID uint) (map[string]interface{}, error)
-	GetCampanasConEstadisticas() ([]map[string]interface{}, error)
+	GetCampanasConEstadisticas(paginacion models.Paginacion) ([]map[string]interface{}, int64, error)
+
+	// Costos de campañas
+	GuardarEstimacion(estimacion *models.EstimacionCostoCampana) error
+	BuscarEstimacion(campanaID uint) (*models.EstimacionCostoCampana, error)
+	GetResultadosCosto(campanaID uint) (*ResultadosCostoCampana, error)
+}
+
+// ResultadosCostoCampana resultados reales de una campaña usados para conciliar su costo
+type ResultadosCostoCampana struct {
+	Conversaciones  int `json:"conversaciones"`   // Envíos no fallidos
+	Canjes          int `json:"canjes"`           // Vouchers de la campaña ya canjeados
+	DescuentoCanjes int `json:"descuento_canjes"` // Suma de porcentajes de los vouchers canjeados
 }
 
 // campanaRepository implementación de CampanaRepository
@@ -59,6 +84,21 @@ func (r *campanaRepository) BuscarPorID(id uint) (*models.CampanaClientesVoucher
 	return &campana, nil
 }
 
+// BloquearParaEnvio busca la campaña bloqueando su fila (SELECT ... FOR UPDATE) hasta el
+// fin de la transacción: dos envíos simultáneos de la misma campaña, aunque sean de otra
+// instancia, se hacen de a uno y el segundo ve los envíos del primero. Solo tiene sentido
+// con el repositorio de una UnidadDeTrabajo
+func (r *campanaRepository) BloquearParaEnvio(id uint) (*models.CampanaClientesVouchers, error) {
+	var campana models.CampanaClientesVouchers
+	if err := r.db.Clauses(clause.Locking{Strength: "UPDATE"}).First(&campana, id).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, fmt.Errorf("campaña con ID %d no encontrada", id)
+		}
+		return nil, fmt.Errorf("error buscando campaña: %w", err)
+	}
+	return &campana, nil
+}
+
 // Actualizar actualiza una campaña
 func (r *campanaRepository) Actualizar(campana *models.CampanaClientesVouchers) error {
 	if err := r.db.Save(campana).Error; err != nil {
@@ -88,7 +128,7 @@ func (r *campanaRepository) ListarTodas() ([]*models.CampanaClientesVouchers, er
 func (r *campanaRepository) ListarActivas() ([]*models.CampanaClientesVouchers, error) {
 	var campanas []*models.CampanaClientesVouchers
 	if err := r.db.Preload("CreadoPor").
-		Where("activa = TRUE AND fecha_vencimiento >= CURDATE()").
+		Where("activa = TRUE AND fecha_vencimiento >= ?", hoy()).
 		Order("created_at DESC").
 		Find(&campanas).Error; err != nil {
 		return nil, fmt.Errorf("error listando campañas activas: %w", err)
@@ -96,6 +136,18 @@ func (r *campanaRepository) ListarActivas() ([]*models.CampanaClientesVouchers,
 	return campanas, nil
 }
 
+// ListarEntre obtiene las campañas activas creadas o que vencen en el rango [desde, hasta)
+func (r *campanaRepository) ListarEntre(desde, hasta time.Time) ([]*models.CampanaClientesVouchers, error) {
+	var campanas []*models.CampanaClientesVouchers
+	if err := r.db.Where("activa = TRUE AND ((created_at >= ? AND created_at < ?) OR (fecha_vencimiento >= ? AND fecha_vencimiento < ?))",
+		desde, hasta, desde, hasta).
+		Order("created_at ASC").
+		Find(&campanas).Error; err != nil {
+		return nil, fmt.Errorf("error listando campañas del período: %w", err)
+	}
+	return campanas, nil
+}
+
 // CrearEnvio registra un envío de campaña
 func (r *campanaRepository) CrearEnvio(envio *models.ClientesVouchersEnvios) error {
 	if err := r.db.Create(envio).Error; err != nil {
@@ -104,11 +156,29 @@ func (r *campanaRepository) CrearEnvio(envio *models.ClientesVouchersEnvios) err
 	return nil
 }
 
+// CrearEnviosEnLote registra los envíos de una campaña con INSERTs de tamanoLote filas.
+// Todo el alta corre en una transacción: si un lote falla no queda ningún envío. Los que
+// ya existían (índice único de campaña y cliente) no se insertan y se retorna
+// ErrEnvioDuplicado, para que quien llama revierta la transacción
+func (r *campanaRepository) CrearEnviosEnLote(envios []*models.ClientesVouchersEnvios, tamano int) error {
+	if len(envios) == 0 {
+		return nil
+	}
+	resultado := r.db.Clauses(clause.OnConflict{DoNothing: true}).CreateInBatches(envios, tamanoLote(tamano))
+	if resultado.Error != nil {
+		return fmt.Errorf("error creando %d envíos: %w", len(envios), resultado.Error)
+	}
+	if int(resultado.RowsAffected) < len(envios) {
+		return ErrEnvioDuplicado
+	}
+	return nil
+}
+
 // GetEnviosPorCampana obtiene todos los envíos de una campaña
 func (r *campanaRepository) GetEnviosPorCampana(campanaID uint) ([]*models.ClientesVouchersEnvios, error) {
 	var envios []*models.ClientesVouchersEnvios
 	if err := r.db.Preload("Cliente").Preload("Voucher").
-		Where("campaña_id = ?", campanaID).
+		Where("campana_id = ?", campanaID).
 		Order("enviado_at DESC").
 		Find(&envios).Error; err != nil {
 		return nil, fmt.Errorf("error obteniendo envíos de campaña: %w", err)
@@ -116,11 +186,30 @@ func (r *campanaRepository) GetEnviosPorCampana(campanaID uint) ([]*models.Clien
 	return envios, nil
 }
 
+// GetClientesConEnvio obtiene los clientes que ya tienen un envío de la campaña
+func (r *campanaRepository) GetClientesConEnvio(campanaID uint) (map[uint]bool, error) {
+	var clientesIDs []uint
+	if err := r.db.Model(&models.ClientesVouchersEnvios{}).
+		Where("campana_id = ?", campanaID).
+		Pluck("cliente_id", &clientesIDs).Error; err != nil {
+		return nil, fmt.Errorf("error obteniendo clientes de la campaña: %w", err)
+	}
+
+	conEnvio := make(map[uint]bool, len(clientesIDs))
+	for _, clienteID := range clientesIDs {
+		conEnvio[clienteID] = true
+	}
+	return conEnvio, nil
+}
+
 // ActualizarEstadoEnvio actualiza el estado de un envío
 func (r *campanaRepository) ActualizarEstadoEnvio(envioID uint, estado string, errorMsg string) error {
 	updates := map[string]interface{}{
 		"estado": estado,
 	}
+	if estado == "enviado" {
+		updates["enviado_at"] = time.Now()
+	}
 
 	if errorMsg != "" {
 		updates["error_mensaje"] = errorMsg
@@ -142,24 +231,85 @@ func (r *campanaRepository) ActualizarEstadoEnvio(envioID uint, estado string, e
 	return nil
 }
 
+// AsociarMensajeEnvio guarda con qué mensaje salió el envío: el wamid si la API lo aceptó
+// o el mensaje del outbox si quedó retenido, para después seguir su entrega
+func (r *campanaRepository) AsociarMensajeEnvio(envioID uint, messageID string, outboxID *uint) error {
+	if err := r.db.Model(&models.ClientesVouchersEnvios{}).
+		Where("id = ?", envioID).
+		Updates(map[string]interface{}{
+			"message_id": messageID,
+			"outbox_id":  outboxID,
+		}).Error; err != nil {
+		return fmt.Errorf("error asociando mensaje al envío: %w", err)
+	}
+	return nil
+}
+
+// estadosPreviosEnvio estados desde los que se puede pasar a cada estado del webhook.
+// Los estados llegan desordenados (read antes que delivered), así que nunca se retrocede
+var estadosPreviosEnvio = map[string][]string{
+	"enviado":   {"pendiente"},
+	"entregado": {"pendiente", "enviado"},
+	"leido":     {"pendiente", "enviado", "entregado"},
+	"fallido":   {"pendiente", "enviado"},
+}
+
+// ActualizarEstadoPorMensaje aplica el estado informado por el webhook a los envíos del
+// mensaje, ya sea que salió directo o desde el outbox. Retorna cuántos envíos cambiaron
+func (r *campanaRepository) ActualizarEstadoPorMensaje(messageID, estado string, momento time.Time, errorMsg string) (int64, error) {
+	previos, ok := estadosPreviosEnvio[estado]
+	if !ok {
+		return 0, fmt.Errorf("estado de envío no válido: %s", estado)
+	}
+
+	updates := map[string]interface{}{"estado": estado}
+	switch estado {
+	case "enviado":
+		updates["enviado_at"] = momento
+	case "entregado":
+		updates["entregado_at"] = momento
+	case "leido":
+		updates["leido_at"] = momento
+		updates["entregado_at"] = gorm.Expr("COALESCE(entregado_at, ?)", momento)
+	case "fallido":
+		// Cuenta como un intento fallido más, igual que un error al enviar
+		updates["error_mensaje"] = errorMsg
+		updates["intentos_envio"] = gorm.Expr("intentos_envio + 1")
+	}
+
+	outbox := r.db.Model(&models.MensajeOutbox{}).Select("id").Where("message_id = ?", messageID)
+	resultado := r.db.Model(&models.ClientesVouchersEnvios{}).
+		Where("message_id = ? OR outbox_id IN (?)", messageID, outbox).
+		Where("estado IN ?", previos).
+		Updates(updates)
+	if resultado.Error != nil {
+		return 0, fmt.Errorf("error actualizando estado de envío por mensaje: %w", resultado.Error)
+	}
+	return resultado.RowsAffected, nil
+}
+
 // GetEstadisticasCampana obtiene estadísticas detalladas de una campaña
 func (r *campanaRepository) GetEstadisticasCampana(campanaID uint) (map[string]interface{}, error) {
 	query := `
 		SELECT 
 			COUNT(*) as total_envios,
+			COUNT(CASE WHEN estado = 'pendiente' THEN 1 END) as pendientes,
 			COUNT(CASE WHEN estado = 'enviado' THEN 1 END) as enviados,
-			COUNT(CASE WHEN estado = 'entregado' THEN 1 END) as entregados,
+			COUNT(CASE WHEN estado IN ('entregado', 'leido') THEN 1 END) as entregados,
+			COUNT(CASE WHEN estado = 'leido' THEN 1 END) as leidos,
 			COUNT(CASE WHEN estado = 'fallido' THEN 1 END) as fallidos,
 			COUNT(CASE WHEN voucher_id IS NOT NULL THEN 1 END) as vouchers_generados,
 			AVG(intentos_envio) as promedio_intentos
 		FROM clientes_vouchers_envios
-		WHERE campaña_id = ?
+		WHERE campana_id = ?
 	`
 
 	var stats struct {
 		TotalEnvios       int     `json:"total_envios"`
+		Pendientes        int     `json:"pendientes"`
 		Enviados          int     `json:"enviados"`
-		Entregados        int     `json:"entregados"`
+		Entregados        int     `json:"entregados"` // Incluye los leídos
+		Leidos            int     `json:"leidos"`
 		Fallidos          int     `json:"fallidos"`
 		VouchersGenerados int     `json:"vouchers_generados"`
 		PromedioIntentos  float64 `json:"promedio_intentos"`
@@ -169,11 +319,21 @@ func (r *campanaRepository) GetEstadisticasCampana(campanaID uint) (map[string]i
 		return nil, fmt.Errorf("error obteniendo estadísticas de campaña: %w", err)
 	}
 
+	var bajas int64
+	if err := r.db.Model(&models.BajaMarketing{}).
+		Where("campana_id = ?", campanaID).
+		Count(&bajas).Error; err != nil {
+		return nil, fmt.Errorf("error contando bajas de campaña: %w", err)
+	}
+
 	// Calcular porcentajes
 	resultado := map[string]interface{}{
+		"bajas":              int(bajas),
 		"total_envios":       stats.TotalEnvios,
+		"pendientes":         stats.Pendientes,
 		"enviados":           stats.Enviados,
 		"entregados":         stats.Entregados,
+		"leidos":             stats.Leidos,
 		"fallidos":           stats.Fallidos,
 		"vouchers_generados": stats.VouchersGenerados,
 		"promedio_intentos":  stats.PromedioIntentos,
@@ -181,14 +341,27 @@ func (r *campanaRepository) GetEstadisticasCampana(campanaID uint) (map[string]i
 
 	if stats.TotalEnvios > 0 {
 		resultado["porcentaje_entrega"] = float64(stats.Entregados) / float64(stats.TotalEnvios) * 100
+		resultado["porcentaje_lectura"] = float64(stats.Leidos) / float64(stats.TotalEnvios) * 100
 		resultado["porcentaje_fallo"] = float64(stats.Fallidos) / float64(stats.TotalEnvios) * 100
+		resultado["porcentaje_bajas"] = float64(bajas) / float64(stats.TotalEnvios) * 100
 	}
 
 	return resultado, nil
 }
 
-// GetCampanasConEstadisticas obtiene todas las campañas con sus estadísticas básicas
-func (r *campanaRepository) GetCampanasConEstadisticas() ([]map[string]interface{}, error) {
+// GetCampanasConEstadisticas obtiene una página de campañas con sus estadísticas básicas
+// junto con el total de campañas
+func (r *campanaRepository) GetCampanasConEstadisticas(paginacion models.Paginacion) ([]map[string]interface{}, int64, error) {
+	orden, err := ordenarPor(paginacion.Orden, ordenCampanas, "c.created_at DESC, c.id DESC")
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var total int64
+	if err := r.db.Model(&models.CampanaClientesVouchers{}).Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("error contando campañas: %w", err)
+	}
+
 	query := `
 		SELECT 
 			c.id,
@@ -200,28 +373,32 @@ func (r *campanaRepository) GetCampanasConEstadisticas() ([]map[string]interface
 			c.created_at,
 			u.nombre as creado_por,
 			COUNT(e.id) as total_envios,
-			COUNT(CASE WHEN e.estado = 'entregado' THEN 1 END) as entregados,
-			COUNT(CASE WHEN e.estado = 'fallido' THEN 1 END) as fallidos
+			COUNT(CASE WHEN e.estado IN ('entregado', 'leido') THEN 1 END) as entregados,
+			COUNT(CASE WHEN e.estado = 'leido' THEN 1 END) as leidos,
+			COUNT(CASE WHEN e.estado = 'fallido' THEN 1 END) as fallidos,
+			(SELECT COUNT(*) FROM bajas_marketing b WHERE b.campana_id = c.id) as bajas
 		FROM campañas_clientes_vouchers c
 		LEFT JOIN usuarios u ON c.created_by = u.id
-		LEFT JOIN clientes_vouchers_envios e ON c.id = e.campaña_id
+		LEFT JOIN clientes_vouchers_envios e ON c.id = e.campana_id
 		GROUP BY c.id, c.nombre, c.descripcion, c.descuento, c.fecha_vencimiento, 
 				 c.activa, c.created_at, u.nombre
-		ORDER BY c.created_at DESC
-	`
+		ORDER BY ` + orden
+	if paginacion.TamanoPagina > 0 {
+		query += fmt.Sprintf(" LIMIT %d OFFSET %d", paginacion.TamanoPagina, paginacion.Offset())
+	}
 
 	var campanas []map[string]interface{}
 	if err := r.db.Raw(query).Scan(&campanas).Error; err != nil {
-		return nil, fmt.Errorf("error obteniendo campañas con estadísticas: %w", err)
+		return nil, 0, fmt.Errorf("error obteniendo campañas con estadísticas: %w", err)
 	}
 
-	return campanas, nil
+	return campanas, total, nil
 }
 
 // GetEnviosPendientesReintento obtiene envíos que fallaron y pueden ser reintentados
 func (r *campanaRepository) GetEnviosPendientesReintento(maxIntentos int) ([]*models.ClientesVouchersEnvios, error) {
 	var envios []*models.ClientesVouchersEnvios
-	if err := r.db.Preload("Campana").Preload("Cliente").
+	if err := r.db.Preload("Campana").Preload("Cliente").Preload("Voucher").
 		Where("estado = 'fallido' AND intentos_envio < ?", maxIntentos).
 		Find(&envios).Error; err != nil {
 		return nil, fmt.Errorf("error obteniendo envíos pendientes de reintento: %w", err)
@@ -232,7 +409,7 @@ func (r *campanaRepository) GetEnviosPendientesReintento(maxIntentos int) ([]*mo
 // LimpiarCampanasAntiguas elimina campañas muy antiguas (mantenimiento)
 func (r *campanaRepository) LimpiarCampanasAntiguas(diasAntiguedad int) (int, error) {
 	// Eliminar campañas vencidas hace más de X días
-	result := r.db.Where("fecha_vencimiento < DATE_SUB(CURDATE(), INTERVAL ? DAY)", diasAntiguedad).
+	result := r.db.Where("fecha_vencimiento < ?", hoy().AddDate(0, 0, -diasAntiguedad)).
 		Delete(&models.CampanaClientesVouchers{})
 
 	if result.Error != nil {
@@ -253,3 +430,46 @@ func (r *campanaRepository) GetCampanasPorPeriodo(inicio, fin time.Time) ([]*mod
 	}
 	return campanas, nil
 }
+
+// GuardarEstimacion crea o actualiza la estimación de costo de una campaña
+func (r *campanaRepository) GuardarEstimacion(estimacion *models.EstimacionCostoCampana) error {
+	if err := r.db.Save(estimacion).Error; err != nil {
+		return fmt.Errorf("error guardando estimación de costo: %w", err)
+	}
+	return nil
+}
+
+// BuscarEstimacion obtiene la estimación de costo registrada para una campaña
+func (r *campanaRepository) BuscarEstimacion(campanaID uint) (*models.EstimacionCostoCampana, error) {
+	var estimacion models.EstimacionCostoCampana
+	if err := r.db.Where("campana_id = ?", campanaID).First(&estimacion).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("la campaña no tiene estimación de costo")
+		}
+		return nil, fmt.Errorf("error buscando estimación de costo: %w", err)
+	}
+	return &estimacion, nil
+}
+
+// GetResultadosCosto cuenta conversaciones y canjes reales de una campaña
+func (r *campanaRepository) GetResultadosCosto(campanaID uint) (*ResultadosCostoCampana, error) {
+	var resultados ResultadosCostoCampana
+
+	var conversaciones int64
+	if err := r.db.Model(&models.ClientesVouchersEnvios{}).
+		Where("campana_id = ? AND estado <> 'fallido'", campanaID).
+		Count(&conversaciones).Error; err != nil {
+		return nil, fmt.Errorf("error contando envíos de campaña: %w", err)
+	}
+	resultados.Conversaciones = int(conversaciones)
+
+	if err := r.db.Table("vouchers").
+		Select("COUNT(*) AS canjes, COALESCE(SUM(vouchers.descuento), 0) AS descuento_canjes").
+		Joins("JOIN clientes_vouchers_envios e ON e.voucher_id = vouchers.id").
+		Where("e.campana_id = ? AND vouchers.usado = TRUE", campanaID).
+		Scan(&resultados).Error; err != nil {
+		return nil, fmt.Errorf("error contando canjes de campaña: %w", err)
+	}
+
+	return &resultados, nil
+}
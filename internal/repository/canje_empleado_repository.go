@@ -0,0 +1,84 @@
+package repository
+
+import (
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+
+	"CheeseHouse/internal/models"
+)
+
+// CanjeEmpleadoRepository define la interfaz para el control de canjes por empleado
+type CanjeEmpleadoRepository interface {
+	RegistrarIntento(intento *models.IntentoCanje) error
+	ContarCanjes(usuarioID uint, desde time.Time) (int, error)
+	GetActividadPorEmpleado(desde time.Time, descuentoAltoValor int) ([]models.ActividadCanjeEmpleado, error)
+}
+
+// canjeEmpleadoRepository implementación de CanjeEmpleadoRepository
+type canjeEmpleadoRepository struct {
+	db *gorm.DB
+}
+
+// NewCanjeEmpleadoRepository crea una nueva instancia del repositorio de canjes por empleado
+func NewCanjeEmpleadoRepository(db *gorm.DB) CanjeEmpleadoRepository {
+	return &canjeEmpleadoRepository{db: db}
+}
+
+// RegistrarIntento guarda un canje rechazado
+func (r *canjeEmpleadoRepository) RegistrarIntento(intento *models.IntentoCanje) error {
+	if err := r.db.Create(intento).Error; err != nil {
+		return fmt.Errorf("error registrando intento de canje: %w", err)
+	}
+	return nil
+}
+
+// ContarCanjes cuenta los vouchers que canjeó un empleado desde una fecha
+func (r *canjeEmpleadoRepository) ContarCanjes(usuarioID uint, desde time.Time) (int, error) {
+	var count int64
+	if err := r.db.Model(&models.Voucher{}).
+		Where("usuario_canje = ? AND usado = TRUE AND fecha_uso >= ?", usuarioID, desde).
+		Count(&count).Error; err != nil {
+		return 0, fmt.Errorf("error contando canjes del empleado: %w", err)
+	}
+	return int(count), nil
+}
+
+// GetActividadPorEmpleado obtiene, por empleado con actividad desde una fecha, sus canjes
+// (y cuántos de alto valor) y sus intentos rechazados separando los de vouchers vencidos
+func (r *canjeEmpleadoRepository) GetActividadPorEmpleado(desde time.Time, descuentoAltoValor int) ([]models.ActividadCanjeEmpleado, error) {
+	query := `
+		SELECT
+			u.id AS usuario_id,
+			u.nombre,
+			u.sucursal,
+			COALESCE(c.canjes, 0) AS canjes,
+			COALESCE(c.alto_valor, 0) AS canjes_alto_valor,
+			COALESCE(i.vencidos, 0) AS intentos_vencidos,
+			COALESCE(i.otros, 0) AS intentos_otros
+		FROM usuarios u
+		LEFT JOIN (
+			SELECT usuario_canje, COUNT(*) AS canjes, COUNT(CASE WHEN descuento >= ? THEN 1 END) AS alto_valor
+			FROM vouchers
+			WHERE usado = TRUE AND fecha_uso >= ?
+			GROUP BY usuario_canje
+		) c ON c.usuario_canje = u.id
+		LEFT JOIN (
+			SELECT usuario_id,
+				COUNT(CASE WHEN motivo = 'vencido' THEN 1 END) AS vencidos,
+				COUNT(CASE WHEN motivo <> 'vencido' THEN 1 END) AS otros
+			FROM intentos_canje
+			WHERE created_at >= ?
+			GROUP BY usuario_id
+		) i ON i.usuario_id = u.id
+		WHERE c.usuario_canje IS NOT NULL OR i.usuario_id IS NOT NULL
+		ORDER BY canjes DESC
+	`
+
+	var actividad []models.ActividadCanjeEmpleado
+	if err := r.db.Raw(query, descuentoAltoValor, desde, desde).Scan(&actividad).Error; err != nil {
+		return nil, fmt.Errorf("error obteniendo actividad de canje por empleado: %w", err)
+	}
+	return actividad, nil
+}
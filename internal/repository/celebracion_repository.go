@@ -0,0 +1,46 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+
+	"gorm.io/gorm"
+
+	"CheeseHouse/internal/models"
+)
+
+// CelebracionRepository define la interfaz para la celebración configurable de las victorias
+type CelebracionRepository interface {
+	Obtener() (*models.Celebracion, error)
+	Guardar(celebracion *models.Celebracion) error
+}
+
+// celebracionRepository implementación de CelebracionRepository
+type celebracionRepository struct {
+	db *gorm.DB
+}
+
+// NewCelebracionRepository crea una nueva instancia del repositorio de celebración
+func NewCelebracionRepository(db *gorm.DB) CelebracionRepository {
+	return &celebracionRepository{db: db}
+}
+
+// Obtener retorna la celebración guardada, o nil si nunca se configuró
+func (r *celebracionRepository) Obtener() (*models.Celebracion, error) {
+	var celebracion models.Celebracion
+	if err := r.db.Order("id ASC").First(&celebracion).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("error obteniendo celebración: %w", err)
+	}
+	return &celebracion, nil
+}
+
+// Guardar crea o actualiza la celebración
+func (r *celebracionRepository) Guardar(celebracion *models.Celebracion) error {
+	if err := r.db.Save(celebracion).Error; err != nil {
+		return fmt.Errorf("error guardando celebración: %w", err)
+	}
+	return nil
+}
@@ -2,11 +2,21 @@ package repository
 
 import (
 	"CheeseHouse/internal/models"
+	"errors"
 	"fmt"
+	"strings"
+	"time"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
+// tiposJuego tipos de voucher que registran una partida (el historial de juegos)
+var tiposJuego = []string{"juego_ganado", "juego_perdido"}
+
+// ErrClienteDuplicado se retorna al crear un cliente con un teléfono o email ya registrado
+var ErrClienteDuplicado = errors.New("ya existe un cliente con ese teléfono o email")
+
 type ClienteRepository struct {
 	db *gorm.DB
 }
@@ -16,7 +26,13 @@ func NewClienteRepository(db *gorm.DB) *ClienteRepository {
 }
 
 func (r *ClienteRepository) Create(cliente *models.Cliente) error {
-	return r.db.Create(cliente).Error
+	if err := r.db.Create(cliente).Error; err != nil {
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			return ErrClienteDuplicado
+		}
+		return err
+	}
+	return nil
 }
 
 func (r *ClienteRepository) GetByTelefono(telefono string) (*models.Cliente, error) {
@@ -64,7 +80,9 @@ func (r *ClienteRepository) GetClientesWithMultipleGames(minGames int) ([]models
 	return clientes, err
 }
 
-func (r *ClienteRepository) GetEstadisticasGenerales() (*models.EstadisticasGenerales, error) {
+// GetEstadisticasGenerales calcula los totales del juego; inicioDia es la medianoche de hoy
+// en la zona horaria del restaurante (ver Config.InicioDelDia)
+func (r *ClienteRepository) GetEstadisticasGenerales(inicioDia time.Time) (*models.EstadisticasGenerales, error) {
 	var stats models.EstadisticasGenerales
 
 	// Total de clientes
@@ -86,14 +104,18 @@ func (r *ClienteRepository) GetEstadisticasGenerales() (*models.EstadisticasGene
 		stats.PorcentajeVictorias = float64(totalVictorias) / float64(totalPartidas) * 100
 	}
 
-	// Clientes que jugaron hoy (simplificado)
+	// Clientes que jugaron hoy, según el historial de partidas y no fecha_ultimo_juego
+	// (que queda desactualizada si falla la actualización de estadísticas)
 	var jugaronHoy int64
-	r.db.Model(&models.Cliente{}).Where("fecha_ultimo_juego >= CURDATE()").Count(&jugaronHoy)
+	r.db.Model(&models.Voucher{}).
+		Where("tipo IN ? AND created_at >= ?", tiposJuego, inicioDia).
+		Distinct("cliente_id").
+		Count(&jugaronHoy)
 	stats.JugaronHoy = int(jugaronHoy)
 
-	// Clientes frecuentes (más de 3 juegos)
+	// Clientes frecuentes (tipo calculado por TipoClienteService)
 	var clientesFrecuentes int64
-	r.db.Model(&models.Cliente{}).Where("total_juegos > 3").Count(&clientesFrecuentes)
+	r.db.Model(&models.Cliente{}).Where("tipo_cliente = ?", models.TipoClienteFrecuente).Count(&clientesFrecuentes)
 	stats.ClientesFrecuentes = int(clientesFrecuentes)
 
 	return &stats, nil
@@ -116,14 +138,6 @@ func (r *ClienteRepository) GetClienteConEstadisticas(clienteID uint) (*models.C
 		porcentajeVictorias = float64(victorias) / float64(totalJuegos) * 100
 	}
 
-	// Determinar tipo de cliente
-	tipoCliente := "nuevo"
-	if totalJuegos > 10 {
-		tipoCliente = "frecuente"
-	} else if totalJuegos > 3 {
-		tipoCliente = "ocasional"
-	}
-
 	// Contar vouchers por estado
 	vouchersGenerados := len(cliente.Vouchers)
 	vouchersUsados := 0
@@ -147,7 +161,6 @@ func (r *ClienteRepository) GetClienteConEstadisticas(clienteID uint) (*models.C
 		VouchersUsados:              vouchersUsados,
 		VouchersPendientes:          vouchersPendientes,
 		PorcentajeVictoriasPersonal: porcentajeVictorias,
-		TipoCliente:                 tipoCliente,
 		UltimoVoucher:               ultimoVoucher,
 	}, nil
 }
@@ -157,6 +170,39 @@ func (r *ClienteRepository) BuscarPorTelefono(telefono string) (*models.Cliente,
 	return r.GetByTelefono(telefono)
 }
 
+// BloquearPorTelefono busca el cliente bloqueando su fila (SELECT ... FOR UPDATE) hasta el
+// fin de la transacción: otra partida del mismo teléfono espera a que esta termine y
+// después cuenta sus intentos. Solo tiene sentido con el repositorio de una UnidadDeTrabajo
+func (r *ClienteRepository) BloquearPorTelefono(telefono string) (*models.Cliente, error) {
+	var cliente models.Cliente
+	err := r.db.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Preload("Juegos").Preload("Vouchers").
+		Where("telefono = ?", telefono).First(&cliente).Error
+	if err != nil {
+		return nil, err
+	}
+	return &cliente, nil
+}
+
+// BloquearPorID busca el cliente por ID bloqueando su fila, como BloquearPorTelefono
+func (r *ClienteRepository) BloquearPorID(id uint) (*models.Cliente, error) {
+	var cliente models.Cliente
+	if err := r.db.Clauses(clause.Locking{Strength: "UPDATE"}).First(&cliente, id).Error; err != nil {
+		return nil, err
+	}
+	return &cliente, nil
+}
+
+// BuscarPorEmail busca un cliente por su email (ya normalizado en minúsculas)
+func (r *ClienteRepository) BuscarPorEmail(email string) (*models.Cliente, error) {
+	var cliente models.Cliente
+	err := r.db.Preload("Juegos").Preload("Vouchers").Where("email = ?", email).First(&cliente).Error
+	if err != nil {
+		return nil, err
+	}
+	return &cliente, nil
+}
+
 func (r *ClienteRepository) BuscarPorID(id uint) (*models.Cliente, error) {
 	return r.GetByID(id)
 }
@@ -189,14 +235,6 @@ func (r *ClienteRepository) GetTopClientes(limit int) ([]*models.ClienteConEstad
 			porcentajeVictorias = float64(victorias) / float64(totalJuegos) * 100
 		}
 
-		// Determinar tipo de cliente
-		tipoCliente := "nuevo"
-		if totalJuegos > 10 {
-			tipoCliente = "frecuente"
-		} else if totalJuegos > 3 {
-			tipoCliente = "ocasional"
-		}
-
 		// Contar vouchers por estado
 		vouchersGenerados := len(cliente.Vouchers)
 		vouchersUsados := 0
@@ -220,7 +258,6 @@ func (r *ClienteRepository) GetTopClientes(limit int) ([]*models.ClienteConEstad
 			VouchersUsados:              vouchersUsados,
 			VouchersPendientes:          vouchersPendientes,
 			PorcentajeVictoriasPersonal: porcentajeVictorias,
-			TipoCliente:                 tipoCliente,
 			UltimoVoucher:               ultimoVoucher,
 		})
 	}
@@ -228,38 +265,20 @@ func (r *ClienteRepository) GetTopClientes(limit int) ([]*models.ClienteConEstad
 	return result, nil
 }
 
-// ListarConEstadisticas lista clientes con estadísticas aplicando filtros
-func (r *ClienteRepository) ListarConEstadisticas(filtros map[string]interface{}) ([]*models.ClienteConEstadisticas, error) {
-	query := r.db.Preload("Vouchers")
-
-	// Aplicar filtros
-	if telefono, ok := filtros["telefono"].(string); ok && telefono != "" {
-		query = query.Where("telefono LIKE ?", "%"+telefono+"%")
-	}
-	if nombre, ok := filtros["nombre"].(string); ok && nombre != "" {
-		query = query.Where("nombre LIKE ? OR apellido LIKE ?", "%"+nombre+"%", "%"+nombre+"%")
-	}
-	if estado, ok := filtros["estado"].(string); ok && estado != "" {
-		query = query.Where("estado = ?", estado)
-	}
-	if tipoCliente, ok := filtros["tipo_cliente"].(string); ok && tipoCliente != "" {
-		switch tipoCliente {
-		case "nuevo":
-			query = query.Where("total_juegos <= 3")
-		case "ocasional":
-			query = query.Where("total_juegos > 3 AND total_juegos <= 10")
-		case "frecuente":
-			query = query.Where("total_juegos > 10")
-		}
+// ListarConEstadisticas lista una página de clientes con estadísticas aplicando filtros,
+// junto con el total de clientes que cumplen los filtros
+func (r *ClienteRepository) ListarConEstadisticas(filtros map[string]interface{}, paginacion models.Paginacion) ([]*models.ClienteConEstadisticas, int64, error) {
+	query, total, err := paginar(r.filtrar(filtros), &models.Cliente{}, paginacion, ordenClientes, "id DESC")
+	if err != nil {
+		return nil, 0, err
 	}
 
 	var clientes []models.Cliente
-	err := query.Find(&clientes).Error
-	if err != nil {
-		return nil, err
+	if err := query.Preload("Vouchers").Find(&clientes).Error; err != nil {
+		return nil, 0, err
 	}
 
-	var result []*models.ClienteConEstadisticas
+	result := []*models.ClienteConEstadisticas{}
 	for _, cliente := range clientes {
 		// Calcular estadísticas adicionales
 		totalJuegos := cliente.TotalJuegos
@@ -271,14 +290,6 @@ func (r *ClienteRepository) ListarConEstadisticas(filtros map[string]interface{}
 			porcentajeVictorias = float64(victorias) / float64(totalJuegos) * 100
 		}
 
-		// Determinar tipo de cliente
-		tipoCliente := "nuevo"
-		if totalJuegos > 10 {
-			tipoCliente = "frecuente"
-		} else if totalJuegos > 3 {
-			tipoCliente = "ocasional"
-		}
-
 		// Contar vouchers por estado
 		vouchersGenerados := len(cliente.Vouchers)
 		vouchersUsados := 0
@@ -302,12 +313,75 @@ func (r *ClienteRepository) ListarConEstadisticas(filtros map[string]interface{}
 			VouchersUsados:              vouchersUsados,
 			VouchersPendientes:          vouchersPendientes,
 			PorcentajeVictoriasPersonal: porcentajeVictorias,
-			TipoCliente:                 tipoCliente,
 			UltimoVoucher:               ultimoVoucher,
 		})
 	}
 
-	return result, nil
+	return result, total, nil
+}
+
+// RecorrerConFiltros recorre en lotes (por id) todos los clientes que cumplen los filtros
+// del listado, sin cargarlos todos en memoria. Si fn retorna error se corta el recorrido
+func (r *ClienteRepository) RecorrerConFiltros(filtros map[string]interface{}, lote int, fn func([]*models.Cliente) error) error {
+	var clientes []*models.Cliente
+	resultado := r.filtrar(filtros).FindInBatches(&clientes, lote, func(*gorm.DB, int) error {
+		return fn(clientes)
+	})
+	if resultado.Error != nil {
+		return fmt.Errorf("error recorriendo clientes: %w", resultado.Error)
+	}
+	return nil
+}
+
+// filtrar aplica los filtros del listado de clientes
+func (r *ClienteRepository) filtrar(filtros map[string]interface{}) *gorm.DB {
+	query := r.db
+	if telefono, ok := filtros["telefono"].(string); ok && telefono != "" {
+		query = query.Where("telefono LIKE ?", "%"+telefono+"%")
+	}
+	if nombre, ok := filtros["nombre"].(string); ok && nombre != "" {
+		query = query.Where("nombre LIKE ? OR apellido LIKE ?", "%"+nombre+"%", "%"+nombre+"%")
+	}
+	if email, ok := filtros["email"].(string); ok && email != "" {
+		query = query.Where("email LIKE ?", "%"+strings.ToLower(email)+"%")
+	}
+	if estado, ok := filtros["estado"].(string); ok && estado != "" {
+		query = query.Where("estado = ?", estado)
+	}
+	if tipoCliente, ok := filtros["tipo_cliente"].(string); ok && tipoCliente != "" {
+		query = query.Where("tipo_cliente = ?", tipoCliente)
+	}
+	// jugaron_desde filtra por el historial de partidas (sin los intentos rechazados, como
+	// los topes del juego); con min_juegos cuenta solo las partidas desde esa fecha, sin
+	// jugaron_desde compara contra el total del cliente
+	minJuegos, conMinimo := filtros["min_juegos"].(int)
+	if desde, ok := filtros["jugaron_desde"].(time.Time); ok {
+		if !conMinimo || minJuegos < 1 {
+			minJuegos = 1
+		}
+		query = query.Where("id IN (?)", r.db.Model(&models.Juego{}).
+			Select("cliente_id").
+			Where("created_at >= ? AND rechazado = ?", desde, false).
+			Group("cliente_id").
+			Having("COUNT(*) >= ?", minJuegos))
+	} else if conMinimo {
+		query = query.Where("total_juegos >= ?", minJuegos)
+	}
+	if desde, ok := filtros["sin_aprobar_desde"].(time.Time); ok {
+		query = query.Where("aprobado_el IS NULL OR aprobado_el < ?", desde)
+	}
+	return query
+}
+
+// ContarNuevosEntre cuenta los clientes registrados en el rango [desde, hasta)
+func (r *ClienteRepository) ContarNuevosEntre(desde, hasta time.Time) (int, error) {
+	var count int64
+	if err := r.db.Model(&models.Cliente{}).
+		Where("created_at >= ? AND created_at < ?", desde, hasta).
+		Count(&count).Error; err != nil {
+		return 0, fmt.Errorf("error contando clientes nuevos: %w", err)
+	}
+	return int(count), nil
 }
 
 // ContarClientesPorTipo cuenta clientes por tipo
@@ -316,12 +390,8 @@ func (r *ClienteRepository) ContarClientesPorTipo(tipo string) (int, error) {
 	query := r.db.Model(&models.Cliente{})
 
 	switch tipo {
-	case "nuevo":
-		query = query.Where("total_juegos <= 3")
-	case "ocasional":
-		query = query.Where("total_juegos > 3 AND total_juegos <= 10")
-	case "frecuente":
-		query = query.Where("total_juegos > 10")
+	case models.TipoClienteNuevo, models.TipoClienteOcasional, models.TipoClienteFrecuente:
+		query = query.Where("tipo_cliente = ?", tipo)
 	default:
 		return 0, fmt.Errorf("tipo de cliente no válido: %s", tipo)
 	}
@@ -330,9 +400,114 @@ func (r *ClienteRepository) ContarClientesPorTipo(tipo string) (int, error) {
 	return int(count), err
 }
 
-// ListarTodos lista todos los clientes
-func (r *ClienteRepository) ListarTodos() ([]*models.Cliente, error) {
+// BuscarPorIDs obtiene varios clientes por ID, sin historial (altas masivas de campañas)
+func (r *ClienteRepository) BuscarPorIDs(ids []uint) ([]*models.Cliente, error) {
 	var clientes []*models.Cliente
-	err := r.db.Find(&clientes).Error
+	err := r.db.Where("id IN ?", ids).Find(&clientes).Error
 	return clientes, err
 }
+
+// ListarTodos lista una página de todos los clientes junto con el total
+func (r *ClienteRepository) ListarTodos(paginacion models.Paginacion) ([]*models.Cliente, int64, error) {
+	query, total, err := paginar(r.db, &models.Cliente{}, paginacion, ordenClientes, "id ASC")
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var clientes []*models.Cliente
+	err = query.Find(&clientes).Error
+	return clientes, total, err
+}
+
+// ListarContactosMarketing obtiene una página de los clientes activos que aceptan
+// marketing (los que nunca configuraron preferencias lo aceptan por defecto)
+func (r *ClienteRepository) ListarContactosMarketing(paginacion models.Paginacion) ([]*models.ContactoMarketing, int64, error) {
+	orden, err := ordenarPor(paginacion.Orden, ordenContactos, "c.id ASC")
+	if err != nil {
+		return nil, 0, err
+	}
+
+	query := r.db.Table("clientes c").
+		Joins("LEFT JOIN preferencias_comunicacion p ON p.cliente_id = c.id").
+		Where("c.estado = ?", "activo").
+		Where("p.id IS NULL OR p.marketing = ?", true)
+
+	var total int64
+	if err := query.Session(&gorm.Session{}).Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("error contando contactos: %w", err)
+	}
+
+	query = query.Select("c.id AS cliente_id, c.nombre, c.apellido, c.telefono, c.email, c.idioma, COALESCE(p.canal, 'whatsapp') AS canal").
+		Order(orden)
+	if paginacion.TamanoPagina > 0 {
+		query = query.Offset(paginacion.Offset()).Limit(paginacion.TamanoPagina)
+	}
+
+	var contactos []*models.ContactoMarketing
+	if err := query.Scan(&contactos).Error; err != nil {
+		return nil, 0, fmt.Errorf("error listando contactos: %w", err)
+	}
+	return contactos, total, nil
+}
+
+// puntajeActividad fila del cálculo de puntajes por cliente
+type puntajeActividad struct {
+	ClienteID uint
+	Puntaje   float64
+}
+
+// PuntajesActividad suma las partidas de cada cliente (vouchers de juego) ponderando cada
+// una por 0.5^(días de antigüedad / mediaVidaDias); con mediaVidaDias 0 todas valen 1.
+// Las partidas ya movidas a vouchers_archivo no cuentan
+func (r *ClienteRepository) PuntajesActividad(mediaVidaDias int, ahora time.Time) (map[uint]float64, error) {
+	seleccion := "cliente_id, COUNT(*) AS puntaje"
+	var args []interface{}
+	if mediaVidaDias > 0 {
+		seleccion = fmt.Sprintf("cliente_id, SUM(POWER(0.5, %s / ?)) AS puntaje", segundosEntre(r.db, "fecha_emision", "?"))
+		args = append(args, ahora, float64(mediaVidaDias)*86400)
+	}
+
+	var filas []puntajeActividad
+	if err := r.db.Model(&models.Voucher{}).
+		Select(seleccion, args...).
+		Where("tipo IN ?", tiposJuego).
+		Group("cliente_id").
+		Scan(&filas).Error; err != nil {
+		return nil, fmt.Errorf("error calculando puntajes de actividad: %w", err)
+	}
+
+	puntajes := make(map[uint]float64, len(filas))
+	for _, fila := range filas {
+		puntajes[fila.ClienteID] = fila.Puntaje
+	}
+	return puntajes, nil
+}
+
+// ListarTipos obtiene el tipo y puntaje guardados de todos los clientes
+func (r *ClienteRepository) ListarTipos() ([]*models.Cliente, error) {
+	var clientes []*models.Cliente
+	err := r.db.Select("id", "tipo_cliente", "puntaje_actividad").Find(&clientes).Error
+	return clientes, err
+}
+
+// RegistrarAprobacion guarda que un empleado habilitó al cliente a seguir jugando
+func (r *ClienteRepository) RegistrarAprobacion(clienteID, empleadoID uint, el time.Time) error {
+	if err := r.db.Model(&models.Cliente{}).
+		Where("id = ?", clienteID).
+		Updates(map[string]interface{}{"aprobado_el": el, "aprobado_por_id": empleadoID}).Error; err != nil {
+		return fmt.Errorf("error registrando aprobación del cliente: %w", err)
+	}
+	return nil
+}
+
+// ActualizarTipo guarda el tipo y puntaje calculados sin tocar updated_at (no es un
+// cambio de datos del cliente)
+func (r *ClienteRepository) ActualizarTipo(clienteID uint, tipo string, puntaje float64, calculadoEn time.Time) error {
+	return r.db.Model(&models.Cliente{}).
+		Where("id = ?", clienteID).
+		UpdateColumns(map[string]interface{}{
+			"tipo_cliente":         tipo,
+			"puntaje_actividad":    puntaje,
+			"puntaje_calculado_en": calculadoEn,
+		}).Error
+}
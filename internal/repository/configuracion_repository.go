@@ -0,0 +1,87 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+
+	"gorm.io/gorm"
+
+	"CheeseHouse/internal/models"
+)
+
+// ConfiguracionRepository define la interfaz para los parámetros editables en caliente
+// y el historial de cambios de configuración
+type ConfiguracionRepository interface {
+	ObtenerParametrosJuego() (*models.ParametrosJuego, error)
+	GuardarConCambios(registro interface{}, cambios []*models.CambioConfiguracion) error
+	ListarCambios(ambito, clave string, limite int) ([]*models.CambioConfiguracion, error)
+	BuscarCambio(id uint) (*models.CambioConfiguracion, error)
+}
+
+// configuracionRepository implementación de ConfiguracionRepository
+type configuracionRepository struct {
+	db *gorm.DB
+}
+
+// NewConfiguracionRepository crea una nueva instancia del repositorio de configuración
+func NewConfiguracionRepository(db *gorm.DB) ConfiguracionRepository {
+	return &configuracionRepository{db: db}
+}
+
+// ObtenerParametrosJuego retorna los parámetros guardados, o nil si nunca se editaron
+func (r *configuracionRepository) ObtenerParametrosJuego() (*models.ParametrosJuego, error) {
+	var parametros models.ParametrosJuego
+	if err := r.db.Order("id ASC").First(&parametros).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("error obteniendo parámetros del juego: %w", err)
+	}
+	return &parametros, nil
+}
+
+// GuardarConCambios guarda el registro editado junto con sus cambios en una transacción,
+// para que no quede un valor vigente sin su versión en el historial
+func (r *configuracionRepository) GuardarConCambios(registro interface{}, cambios []*models.CambioConfiguracion) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(registro).Error; err != nil {
+			return fmt.Errorf("error guardando configuración: %w", err)
+		}
+		if len(cambios) == 0 {
+			return nil
+		}
+		if err := tx.Create(&cambios).Error; err != nil {
+			return fmt.Errorf("error registrando cambios de configuración: %w", err)
+		}
+		return nil
+	})
+}
+
+// ListarCambios obtiene el historial de cambios, del más reciente al más viejo. Ámbito
+// y clave vacíos no filtran
+func (r *configuracionRepository) ListarCambios(ambito, clave string, limite int) ([]*models.CambioConfiguracion, error) {
+	var cambios []*models.CambioConfiguracion
+	query := r.db.Order("id DESC").Limit(limite)
+	if ambito != "" {
+		query = query.Where("ambito = ?", ambito)
+	}
+	if clave != "" {
+		query = query.Where("clave = ?", clave)
+	}
+	if err := query.Find(&cambios).Error; err != nil {
+		return nil, fmt.Errorf("error listando cambios de configuración: %w", err)
+	}
+	return cambios, nil
+}
+
+// BuscarCambio obtiene un cambio del historial
+func (r *configuracionRepository) BuscarCambio(id uint) (*models.CambioConfiguracion, error) {
+	var cambio models.CambioConfiguracion
+	if err := r.db.First(&cambio, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("cambio de configuración #%d no encontrado", id)
+		}
+		return nil, fmt.Errorf("error buscando cambio de configuración: %w", err)
+	}
+	return &cambio, nil
+}
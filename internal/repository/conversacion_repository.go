@@ -0,0 +1,135 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+
+	"CheeseHouse/internal/models"
+)
+
+// ConversacionRepository define la interfaz para la bandeja de WhatsApp
+type ConversacionRepository interface {
+	Crear(mensaje *models.Conversacion) error
+	ExisteMessageID(messageID string) (bool, error)
+	ListarHilos(soloNoLeidos bool) ([]*models.HiloConversacion, error)
+	GetMensajes(telefono string, limite int) ([]*models.Conversacion, error)
+	MarcarLeidos(telefono string) error
+	ContarNoLeidos() (int, error)
+	UltimoEntrante(telefono string) (*time.Time, error)
+}
+
+// conversacionRepository implementación de ConversacionRepository
+type conversacionRepository struct {
+	db *gorm.DB
+}
+
+// NewConversacionRepository crea una nueva instancia del repositorio de conversaciones
+func NewConversacionRepository(db *gorm.DB) ConversacionRepository {
+	return &conversacionRepository{db: db}
+}
+
+// Crear guarda un mensaje de la bandeja
+func (r *conversacionRepository) Crear(mensaje *models.Conversacion) error {
+	if err := r.db.Create(mensaje).Error; err != nil {
+		return fmt.Errorf("error guardando mensaje de conversación: %w", err)
+	}
+	return nil
+}
+
+// ExisteMessageID indica si un mensaje de WhatsApp ya fue registrado
+func (r *conversacionRepository) ExisteMessageID(messageID string) (bool, error) {
+	var count int64
+	if err := r.db.Model(&models.Conversacion{}).
+		Where("message_id = ?", messageID).
+		Count(&count).Error; err != nil {
+		return false, fmt.Errorf("error verificando mensaje: %w", err)
+	}
+	return count > 0, nil
+}
+
+// ListarHilos obtiene una fila por teléfono con su último mensaje y cantidad sin leer
+func (r *conversacionRepository) ListarHilos(soloNoLeidos bool) ([]*models.HiloConversacion, error) {
+	query := `
+		SELECT
+			c.telefono,
+			MAX(c.cliente_id) as cliente_id,
+			MAX(c.nombre_contacto) as nombre_contacto,
+			u.mensaje as ultimo_mensaje,
+			MAX(c.created_at) as ultima_fecha,
+			COUNT(CASE WHEN c.direccion = 'entrante' AND c.leido = FALSE THEN 1 END) as no_leidos,
+			a.id as atencion_id,
+			a.asignado_a
+		FROM conversaciones c
+		JOIN conversaciones u ON u.id = (
+			SELECT MAX(id) FROM conversaciones WHERE telefono = c.telefono
+		)
+		LEFT JOIN atenciones a ON a.telefono = c.telefono AND a.estado = 'abierta'
+		GROUP BY c.telefono, u.mensaje, a.id, a.asignado_a
+	`
+	if soloNoLeidos {
+		query += " HAVING COUNT(CASE WHEN c.direccion = 'entrante' AND c.leido = FALSE THEN 1 END) > 0"
+	}
+	query += " ORDER BY ultima_fecha DESC"
+
+	var hilos []*models.HiloConversacion
+	if err := r.db.Raw(query).Scan(&hilos).Error; err != nil {
+		return nil, fmt.Errorf("error listando conversaciones: %w", err)
+	}
+	return hilos, nil
+}
+
+// GetMensajes obtiene los últimos mensajes con un teléfono en orden cronológico
+func (r *conversacionRepository) GetMensajes(telefono string, limite int) ([]*models.Conversacion, error) {
+	var mensajes []*models.Conversacion
+	if err := r.db.Preload("Empleado").
+		Where("telefono = ?", telefono).
+		Order("id DESC").
+		Limit(limite).
+		Find(&mensajes).Error; err != nil {
+		return nil, fmt.Errorf("error obteniendo mensajes: %w", err)
+	}
+
+	for i, j := 0, len(mensajes)-1; i < j; i, j = i+1, j-1 {
+		mensajes[i], mensajes[j] = mensajes[j], mensajes[i]
+	}
+	return mensajes, nil
+}
+
+// MarcarLeidos marca como leídos los mensajes entrantes de un teléfono
+func (r *conversacionRepository) MarcarLeidos(telefono string) error {
+	if err := r.db.Model(&models.Conversacion{}).
+		Where("telefono = ? AND direccion = 'entrante' AND leido = FALSE", telefono).
+		Update("leido", true).Error; err != nil {
+		return fmt.Errorf("error marcando mensajes como leídos: %w", err)
+	}
+	return nil
+}
+
+// ContarNoLeidos cuenta los mensajes entrantes sin leer de todas las conversaciones
+func (r *conversacionRepository) ContarNoLeidos() (int, error) {
+	var count int64
+	if err := r.db.Model(&models.Conversacion{}).
+		Where("direccion = 'entrante' AND leido = FALSE").
+		Count(&count).Error; err != nil {
+		return 0, fmt.Errorf("error contando mensajes sin leer: %w", err)
+	}
+	return int(count), nil
+}
+
+// UltimoEntrante retorna la fecha del último mensaje recibido del teléfono, o nil
+func (r *conversacionRepository) UltimoEntrante(telefono string) (*time.Time, error) {
+	var mensaje models.Conversacion
+	err := r.db.Where("telefono = ? AND direccion = 'entrante'", telefono).
+		Order("id DESC").
+		First(&mensaje).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("error buscando último mensaje recibido: %w", err)
+	}
+	return &mensaje.CreatedAt, nil
+}
@@ -0,0 +1,58 @@
+package repository
+
+import (
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+
+	"CheeseHouse/internal/models"
+)
+
+// DiaSinCanjeRepository define la interfaz para las fechas sin canje de vouchers
+type DiaSinCanjeRepository interface {
+	Crear(dia *models.DiaSinCanje) error
+	Eliminar(id uint) error
+	ListarEntre(desde, hasta time.Time) ([]*models.DiaSinCanje, error)
+}
+
+// diaSinCanjeRepository implementación de DiaSinCanjeRepository
+type diaSinCanjeRepository struct {
+	db *gorm.DB
+}
+
+// NewDiaSinCanjeRepository crea una nueva instancia del repositorio de días sin canje
+func NewDiaSinCanjeRepository(db *gorm.DB) DiaSinCanjeRepository {
+	return &diaSinCanjeRepository{db: db}
+}
+
+// Crear registra una fecha sin canje
+func (r *diaSinCanjeRepository) Crear(dia *models.DiaSinCanje) error {
+	if err := r.db.Create(dia).Error; err != nil {
+		return fmt.Errorf("error creando día sin canje: %w", err)
+	}
+	return nil
+}
+
+// Eliminar vuelve a habilitar el canje en una fecha
+func (r *diaSinCanjeRepository) Eliminar(id uint) error {
+	resultado := r.db.Delete(&models.DiaSinCanje{}, id)
+	if resultado.Error != nil {
+		return fmt.Errorf("error eliminando día sin canje: %w", resultado.Error)
+	}
+	if resultado.RowsAffected == 0 {
+		return fmt.Errorf("día sin canje con ID %d no encontrado", id)
+	}
+	return nil
+}
+
+// ListarEntre obtiene las fechas sin canje entre dos fechas inclusive
+func (r *diaSinCanjeRepository) ListarEntre(desde, hasta time.Time) ([]*models.DiaSinCanje, error) {
+	var dias []*models.DiaSinCanje
+	if err := r.db.Where("fecha BETWEEN ? AND ?", desde.Format("2006-01-02"), hasta.Format("2006-01-02")).
+		Order("fecha ASC").
+		Find(&dias).Error; err != nil {
+		return nil, fmt.Errorf("error listando días sin canje: %w", err)
+	}
+	return dias, nil
+}
@@ -0,0 +1,58 @@
+package repository
+
+import (
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Las consultas apuntan a SQL común a MySQL y Postgres (CAST, POWER, COALESCE, fechas
+// calculadas en Go como parámetros). Lo que no tiene forma común pasa por estas funciones,
+// que eligen la sintaxis según el driver con el que se abrió la conexión
+
+// esPostgres indica si la conexión es a Postgres
+func esPostgres(db *gorm.DB) bool {
+	return db.Dialector.Name() == "postgres"
+}
+
+// hoy medianoche de hoy en hora local; reemplaza a CURDATE() en los filtros por fecha
+func hoy() time.Time {
+	ahora := time.Now()
+	return time.Date(ahora.Year(), ahora.Month(), ahora.Day(), 0, 0, 0, 0, time.Local)
+}
+
+// segundosEntre expresión con los segundos enteros transcurridos entre dos columnas
+// (o parámetros) de fecha y hora
+func segundosEntre(db *gorm.DB, desde, hasta string) string {
+	if esPostgres(db) {
+		return fmt.Sprintf("FLOOR(EXTRACT(EPOCH FROM (%s - %s)))", hasta, desde)
+	}
+	return fmt.Sprintf("TIMESTAMPDIFF(SECOND, %s, %s)", desde, hasta)
+}
+
+// minutosEntre igual que segundosEntre pero en minutos enteros
+func minutosEntre(db *gorm.DB, desde, hasta string) string {
+	if esPostgres(db) {
+		return fmt.Sprintf("FLOOR(EXTRACT(EPOCH FROM (%s - %s)) / 60)", hasta, desde)
+	}
+	return fmt.Sprintf("TIMESTAMPDIFF(MINUTE, %s, %s)", desde, hasta)
+}
+
+// unirDistintos agregación con los valores distintos de la columna, ordenados y separados
+// por coma
+func unirDistintos(db *gorm.DB, columna string) string {
+	if esPostgres(db) {
+		return fmt.Sprintf("STRING_AGG(DISTINCT %[1]s, ',' ORDER BY %[1]s)", columna)
+	}
+	return fmt.Sprintf("GROUP_CONCAT(DISTINCT %[1]s ORDER BY %[1]s SEPARATOR ',')", columna)
+}
+
+// copiarEstructura sentencia que crea tabla (si no existe) con las columnas e índices de
+// origen
+func copiarEstructura(db *gorm.DB, tabla, origen string) string {
+	if esPostgres(db) {
+		return fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (LIKE %s INCLUDING ALL)", tabla, origen)
+	}
+	return fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s LIKE %s", tabla, origen)
+}
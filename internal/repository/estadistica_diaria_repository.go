@@ -0,0 +1,49 @@
+package repository
+
+import (
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"CheeseHouse/internal/models"
+)
+
+// EstadisticaDiariaRepository define la interfaz para los resúmenes diarios de actividad
+type EstadisticaDiariaRepository interface {
+	Guardar(estadistica *models.EstadisticaDiaria) error
+	ListarEntre(desde, hasta time.Time) ([]*models.EstadisticaDiaria, error)
+}
+
+// estadisticaDiariaRepository implementación de EstadisticaDiariaRepository
+type estadisticaDiariaRepository struct {
+	db *gorm.DB
+}
+
+// NewEstadisticaDiariaRepository crea una nueva instancia del repositorio de estadísticas diarias
+func NewEstadisticaDiariaRepository(db *gorm.DB) EstadisticaDiariaRepository {
+	return &estadisticaDiariaRepository{db: db}
+}
+
+// Guardar crea o reemplaza el resumen de la fecha, así recalcular un día no lo duplica
+func (r *estadisticaDiariaRepository) Guardar(estadistica *models.EstadisticaDiaria) error {
+	if err := r.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "fecha"}},
+		DoUpdates: clause.AssignmentColumns([]string{"partidas", "vouchers_emitidos", "vouchers_canjeados", "clientes_nuevos", "updated_at"}),
+	}).Create(estadistica).Error; err != nil {
+		return fmt.Errorf("error guardando estadística diaria: %w", err)
+	}
+	return nil
+}
+
+// ListarEntre obtiene los resúmenes entre dos fechas inclusive
+func (r *estadisticaDiariaRepository) ListarEntre(desde, hasta time.Time) ([]*models.EstadisticaDiaria, error) {
+	var estadisticas []*models.EstadisticaDiaria
+	if err := r.db.Where("fecha BETWEEN ? AND ?", desde.Format("2006-01-02"), hasta.Format("2006-01-02")).
+		Order("fecha ASC").
+		Find(&estadisticas).Error; err != nil {
+		return nil, fmt.Errorf("error listando estadísticas diarias: %w", err)
+	}
+	return estadisticas, nil
+}
@@ -0,0 +1,221 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+
+	"CheeseHouse/internal/models"
+)
+
+// reintentosEstadoCompartido veces que ActualizarCompartido vuelve a leer y aplicar el
+// cambio cuando otra instancia escribió la misma clave en el medio
+const reintentosEstadoCompartido = 5
+
+// ErrConflictoEstadoCompartido la clave cambió en todos los reintentos
+var ErrConflictoEstadoCompartido = errors.New("la clave compartida cambió durante la actualización")
+
+// EstadoCompartidoRepository define la interfaz para el estado efímero que comparten las
+// instancias. Los valores vencidos se comportan como si no existieran
+type EstadoCompartidoRepository interface {
+	// Obtener retorna el valor vigente; nil si no existe o venció
+	Obtener(clave string) (*models.EstadoCompartido, error)
+	// Crear guarda la clave solo si no existe; false si otra instancia ya la tenía
+	Crear(clave, valor string, vence time.Time) (bool, error)
+	// Reemplazar escribe el valor solo si la clave sigue en version; false si cambió
+	Reemplazar(clave string, version int64, valor string, vence time.Time) (bool, error)
+	Eliminar(clave string) error
+	EliminarVencidos(antes time.Time) (int64, error)
+}
+
+// ActualizarCompartido aplica cambio al valor de la clave con control optimista: si otra
+// instancia la modificó entre la lectura y la escritura, vuelve a leer y aplicar. cambio
+// recibe el valor vigente (existe false si no hay) y retorna el nuevo y su vencimiento
+func ActualizarCompartido(repo EstadoCompartidoRepository, clave string, cambio func(valor string, existe bool) (string, time.Time, error)) error {
+	for i := 0; i < reintentosEstadoCompartido; i++ {
+		actual, err := repo.Obtener(clave)
+		if err != nil {
+			return err
+		}
+
+		var escrito bool
+		if actual == nil {
+			valor, vence, err := cambio("", false)
+			if err != nil {
+				return err
+			}
+			escrito, err = repo.Crear(clave, valor, vence)
+			if err != nil {
+				return err
+			}
+		} else {
+			valor, vence, err := cambio(actual.Valor, true)
+			if err != nil {
+				return err
+			}
+			escrito, err = repo.Reemplazar(clave, actual.Version, valor, vence)
+			if err != nil {
+				return err
+			}
+		}
+		if escrito {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: %s", ErrConflictoEstadoCompartido, clave)
+}
+
+// estadoCompartidoRepository implementación en la base, para varias instancias
+type estadoCompartidoRepository struct {
+	db *gorm.DB
+}
+
+// NewEstadoCompartidoRepository crea el repositorio de estado compartido en la base
+func NewEstadoCompartidoRepository(db *gorm.DB) EstadoCompartidoRepository {
+	return &estadoCompartidoRepository{db: db}
+}
+
+// Obtener retorna el valor vigente de la clave
+func (r *estadoCompartidoRepository) Obtener(clave string) (*models.EstadoCompartido, error) {
+	var estado models.EstadoCompartido
+	if err := r.db.Where("clave = ? AND vence_en >= ?", clave, time.Now()).First(&estado).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("error obteniendo estado compartido: %w", err)
+	}
+	return &estado, nil
+}
+
+// Crear inserta la clave; si quedó una vencida con el mismo nombre se borra antes
+func (r *estadoCompartidoRepository) Crear(clave, valor string, vence time.Time) (bool, error) {
+	if err := r.db.Where("clave = ? AND vence_en < ?", clave, time.Now()).
+		Delete(&models.EstadoCompartido{}).Error; err != nil {
+		return false, fmt.Errorf("error liberando estado compartido vencido: %w", err)
+	}
+
+	estado := &models.EstadoCompartido{Clave: clave, Valor: valor, Version: 1, VenceEn: vence}
+	if err := r.db.Create(estado).Error; err != nil {
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			return false, nil
+		}
+		return false, fmt.Errorf("error creando estado compartido: %w", err)
+	}
+	return true, nil
+}
+
+// Reemplazar actualiza la clave si nadie la escribió desde que se leyó version
+func (r *estadoCompartidoRepository) Reemplazar(clave string, version int64, valor string, vence time.Time) (bool, error) {
+	resultado := r.db.Model(&models.EstadoCompartido{}).
+		Where("clave = ? AND version = ? AND vence_en >= ?", clave, version, time.Now()).
+		Updates(map[string]interface{}{
+			"valor":    valor,
+			"version":  version + 1,
+			"vence_en": vence,
+		})
+	if resultado.Error != nil {
+		return false, fmt.Errorf("error actualizando estado compartido: %w", resultado.Error)
+	}
+	return resultado.RowsAffected == 1, nil
+}
+
+// Eliminar borra la clave
+func (r *estadoCompartidoRepository) Eliminar(clave string) error {
+	if err := r.db.Where("clave = ?", clave).Delete(&models.EstadoCompartido{}).Error; err != nil {
+		return fmt.Errorf("error eliminando estado compartido: %w", err)
+	}
+	return nil
+}
+
+// EliminarVencidos borra las claves vencidas antes de la fecha indicada
+func (r *estadoCompartidoRepository) EliminarVencidos(antes time.Time) (int64, error) {
+	resultado := r.db.Where("vence_en < ?", antes).Delete(&models.EstadoCompartido{})
+	if resultado.Error != nil {
+		return 0, fmt.Errorf("error eliminando estado compartido vencido: %w", resultado.Error)
+	}
+	return resultado.RowsAffected, nil
+}
+
+// purgaEnMemoria cada cuánto la implementación en memoria borra sola lo vencido, para no
+// depender de que el mantenimiento esté habilitado
+const purgaEnMemoria = time.Minute
+
+// estadoCompartidoEnMemoria implementación para una sola instancia, sin ir a la base
+type estadoCompartidoEnMemoria struct {
+	mu        sync.Mutex
+	valores   map[string]models.EstadoCompartido
+	purgadoEn time.Time
+}
+
+// NewEstadoCompartidoEnMemoria crea el estado compartido en memoria; alcanza cuando corre
+// una sola instancia
+func NewEstadoCompartidoEnMemoria() EstadoCompartidoRepository {
+	return &estadoCompartidoEnMemoria{valores: make(map[string]models.EstadoCompartido)}
+}
+
+func (r *estadoCompartidoEnMemoria) Obtener(clave string) (*models.EstadoCompartido, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	estado, ok := r.valores[clave]
+	if !ok || estado.VenceEn.Before(time.Now()) {
+		return nil, nil
+	}
+	return &estado, nil
+}
+
+func (r *estadoCompartidoEnMemoria) Crear(clave, valor string, vence time.Time) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	ahora := time.Now()
+	if ahora.Sub(r.purgadoEn) > purgaEnMemoria {
+		r.purgar(ahora)
+		r.purgadoEn = ahora
+	}
+	if estado, ok := r.valores[clave]; ok && !estado.VenceEn.Before(ahora) {
+		return false, nil
+	}
+	r.valores[clave] = models.EstadoCompartido{Clave: clave, Valor: valor, Version: 1, VenceEn: vence}
+	return true, nil
+}
+
+func (r *estadoCompartidoEnMemoria) Reemplazar(clave string, version int64, valor string, vence time.Time) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	estado, ok := r.valores[clave]
+	if !ok || estado.Version != version || estado.VenceEn.Before(time.Now()) {
+		return false, nil
+	}
+	r.valores[clave] = models.EstadoCompartido{Clave: clave, Valor: valor, Version: version + 1, VenceEn: vence}
+	return true, nil
+}
+
+func (r *estadoCompartidoEnMemoria) Eliminar(clave string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.valores, clave)
+	return nil
+}
+
+func (r *estadoCompartidoEnMemoria) EliminarVencidos(antes time.Time) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.purgar(antes), nil
+}
+
+// purgar borra las claves vencidas antes de la fecha; el llamador tiene el lock
+func (r *estadoCompartidoEnMemoria) purgar(antes time.Time) int64 {
+	var eliminados int64
+	for clave, estado := range r.valores {
+		if estado.VenceEn.Before(antes) {
+			delete(r.valores, clave)
+			eliminados++
+		}
+	}
+	return eliminados
+}
@@ -0,0 +1,88 @@
+package repository
+
+import (
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+
+	"CheeseHouse/internal/models"
+)
+
+// HorarioRepository define la interfaz para los horarios de atención y feriados de las sucursales
+type HorarioRepository interface {
+	ListarHorarios(sucursal string) ([]*models.HorarioSucursal, error)
+	ReemplazarHorarios(sucursal string, horarios []*models.HorarioSucursal) error
+	CrearFeriado(feriado *models.Feriado) error
+	EliminarFeriado(id uint) error
+	ListarFeriados(sucursal string, desde, hasta time.Time) ([]*models.Feriado, error)
+}
+
+// horarioRepository implementación de HorarioRepository
+type horarioRepository struct {
+	db *gorm.DB
+}
+
+// NewHorarioRepository crea una nueva instancia del repositorio de horarios
+func NewHorarioRepository(db *gorm.DB) HorarioRepository {
+	return &horarioRepository{db: db}
+}
+
+// ListarHorarios obtiene los turnos de una sucursal ordenados por día y hora de apertura
+func (r *horarioRepository) ListarHorarios(sucursal string) ([]*models.HorarioSucursal, error) {
+	var horarios []*models.HorarioSucursal
+	if err := r.db.Where("sucursal = ?", sucursal).
+		Order("dia_semana ASC, apertura ASC").
+		Find(&horarios).Error; err != nil {
+		return nil, fmt.Errorf("error listando horarios: %w", err)
+	}
+	return horarios, nil
+}
+
+// ReemplazarHorarios borra los turnos de la sucursal y guarda los nuevos en una transacción
+func (r *horarioRepository) ReemplazarHorarios(sucursal string, horarios []*models.HorarioSucursal) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("sucursal = ?", sucursal).Delete(&models.HorarioSucursal{}).Error; err != nil {
+			return fmt.Errorf("error borrando horarios: %w", err)
+		}
+		if len(horarios) == 0 {
+			return nil
+		}
+		if err := tx.Create(&horarios).Error; err != nil {
+			return fmt.Errorf("error guardando horarios: %w", err)
+		}
+		return nil
+	})
+}
+
+// CrearFeriado registra un feriado
+func (r *horarioRepository) CrearFeriado(feriado *models.Feriado) error {
+	if err := r.db.Create(feriado).Error; err != nil {
+		return fmt.Errorf("error creando feriado: %w", err)
+	}
+	return nil
+}
+
+// EliminarFeriado borra un feriado
+func (r *horarioRepository) EliminarFeriado(id uint) error {
+	resultado := r.db.Delete(&models.Feriado{}, id)
+	if resultado.Error != nil {
+		return fmt.Errorf("error eliminando feriado: %w", resultado.Error)
+	}
+	if resultado.RowsAffected == 0 {
+		return fmt.Errorf("feriado con ID %d no encontrado", id)
+	}
+	return nil
+}
+
+// ListarFeriados obtiene los feriados de la sucursal (y los generales) entre dos fechas inclusive
+func (r *horarioRepository) ListarFeriados(sucursal string, desde, hasta time.Time) ([]*models.Feriado, error) {
+	var feriados []*models.Feriado
+	if err := r.db.Where("(sucursal = ? OR sucursal = '' OR sucursal IS NULL) AND fecha BETWEEN ? AND ?",
+		sucursal, desde.Format("2006-01-02"), hasta.Format("2006-01-02")).
+		Order("fecha ASC").
+		Find(&feriados).Error; err != nil {
+		return nil, fmt.Errorf("error listando feriados: %w", err)
+	}
+	return feriados, nil
+}
@@ -0,0 +1,80 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+
+	"CheeseHouse/internal/models"
+)
+
+// IdempotenciaRepository define la interfaz para las respuestas guardadas por Idempotency-Key
+type IdempotenciaRepository interface {
+	Reservar(clave *models.ClaveIdempotencia) (bool, error)
+	Buscar(clave, ruta string) (*models.ClaveIdempotencia, error)
+	Completar(id uint, status int, respuesta string) error
+	Eliminar(id uint) error
+	EliminarVencidas(antes time.Time) (int64, error)
+}
+
+// idempotenciaRepository implementación de IdempotenciaRepository
+type idempotenciaRepository struct {
+	db *gorm.DB
+}
+
+// NewIdempotenciaRepository crea una nueva instancia del repositorio de idempotencia
+func NewIdempotenciaRepository(db *gorm.DB) IdempotenciaRepository {
+	return &idempotenciaRepository{db: db}
+}
+
+// Reservar registra la clave como en proceso; false si otro request ya la tiene
+func (r *idempotenciaRepository) Reservar(clave *models.ClaveIdempotencia) (bool, error) {
+	if err := r.db.Create(clave).Error; err != nil {
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			return false, nil
+		}
+		return false, fmt.Errorf("error reservando clave de idempotencia: %w", err)
+	}
+	return true, nil
+}
+
+// Buscar obtiene la clave guardada para la ruta; nil si no existe
+func (r *idempotenciaRepository) Buscar(clave, ruta string) (*models.ClaveIdempotencia, error) {
+	var registro models.ClaveIdempotencia
+	if err := r.db.Where("clave = ? AND ruta = ?", clave, ruta).First(&registro).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("error buscando clave de idempotencia: %w", err)
+	}
+	return &registro, nil
+}
+
+// Completar guarda la respuesta del request que tenía reservada la clave
+func (r *idempotenciaRepository) Completar(id uint, status int, respuesta string) error {
+	if err := r.db.Model(&models.ClaveIdempotencia{}).
+		Where("id = ?", id).
+		Updates(map[string]interface{}{"status": status, "respuesta": respuesta}).Error; err != nil {
+		return fmt.Errorf("error guardando respuesta idempotente: %w", err)
+	}
+	return nil
+}
+
+// Eliminar libera una clave para que un reintento se procese de nuevo
+func (r *idempotenciaRepository) Eliminar(id uint) error {
+	if err := r.db.Delete(&models.ClaveIdempotencia{}, id).Error; err != nil {
+		return fmt.Errorf("error liberando clave de idempotencia: %w", err)
+	}
+	return nil
+}
+
+// EliminarVencidas borra las claves que vencieron antes de la fecha indicada
+func (r *idempotenciaRepository) EliminarVencidas(antes time.Time) (int64, error) {
+	resultado := r.db.Where("expira_en < ?", antes).Delete(&models.ClaveIdempotencia{})
+	if resultado.Error != nil {
+		return 0, fmt.Errorf("error eliminando claves de idempotencia vencidas: %w", resultado.Error)
+	}
+	return resultado.RowsAffected, nil
+}
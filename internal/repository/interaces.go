@@ -21,3 +21,14 @@ func NewRepositories(
 		Campana: campana,
 	}
 }
+
+// tamanoLotePorDefecto filas por INSERT en las altas masivas si no se indica otro tamaño
+const tamanoLotePorDefecto = 500
+
+// tamanoLote normaliza el tamaño de lote recibido (DB_BATCH_SIZE)
+func tamanoLote(tamano int) int {
+	if tamano <= 0 {
+		return tamanoLotePorDefecto
+	}
+	return tamano
+}
@@ -0,0 +1,131 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"CheeseHouse/internal/models"
+)
+
+// JuegoRepository define la interfaz para el historial de partidas
+type JuegoRepository interface {
+	Crear(juego *models.Juego) error
+	ListarPorCliente(clienteID uint, limite int) ([]*models.Juego, error)
+	ContarEntre(desde, hasta time.Time) (int, error)
+	ContarPorClienteDesde(clienteID uint, desde time.Time) (int, error)
+	UltimoResueltoPorCliente(clienteID uint) (*models.Juego, error)
+	ListarRetenidos() ([]*models.Juego, error)
+	BloquearRetenido(id uint) (*models.Juego, error)
+	ResolverRetenido(id uint, voucherID *uint) error
+}
+
+// juegoRepository implementación de JuegoRepository
+type juegoRepository struct {
+	db *gorm.DB
+}
+
+// NewJuegoRepository crea una nueva instancia del repositorio de partidas
+func NewJuegoRepository(db *gorm.DB) JuegoRepository {
+	return &juegoRepository{db: db}
+}
+
+// Crear registra una partida
+func (r *juegoRepository) Crear(juego *models.Juego) error {
+	if err := r.db.Create(juego).Error; err != nil {
+		return fmt.Errorf("error registrando partida: %w", err)
+	}
+	return nil
+}
+
+// ListarPorCliente obtiene las últimas partidas de un cliente, de la más reciente a la más vieja
+func (r *juegoRepository) ListarPorCliente(clienteID uint, limite int) ([]*models.Juego, error) {
+	var juegos []*models.Juego
+	if err := r.db.Where("cliente_id = ?", clienteID).
+		Order("created_at DESC").
+		Limit(limite).
+		Find(&juegos).Error; err != nil {
+		return nil, fmt.Errorf("error obteniendo partidas del cliente: %w", err)
+	}
+	return juegos, nil
+}
+
+// ContarEntre cuenta las partidas jugadas en el rango [desde, hasta)
+func (r *juegoRepository) ContarEntre(desde, hasta time.Time) (int, error) {
+	var count int64
+	if err := r.db.Model(&models.Juego{}).
+		Where("created_at >= ? AND created_at < ?", desde, hasta).
+		Count(&count).Error; err != nil {
+		return 0, fmt.Errorf("error contando partidas: %w", err)
+	}
+	return int(count), nil
+}
+
+// ContarPorClienteDesde cuenta las partidas jugadas por el cliente desde una fecha, con o sin
+// voucher: los topes y la aprobación limitan partidas, no premios. Los intentos rechazados
+// no cuentan, así un reintento frenado por la espera no gasta el tope
+func (r *juegoRepository) ContarPorClienteDesde(clienteID uint, desde time.Time) (int, error) {
+	var count int64
+	if err := r.db.Model(&models.Juego{}).
+		Where("cliente_id = ? AND created_at >= ? AND rechazado = ?", clienteID, desde, false).
+		Count(&count).Error; err != nil {
+		return 0, fmt.Errorf("error contando partidas del cliente: %w", err)
+	}
+	return int(count), nil
+}
+
+// UltimoResueltoPorCliente obtiene la última partida del cliente que generó voucher (nil si
+// no hay): los intentos rechazados no cuentan como derrota
+func (r *juegoRepository) UltimoResueltoPorCliente(clienteID uint) (*models.Juego, error) {
+	var juego models.Juego
+	err := r.db.Where("cliente_id = ? AND voucher_id IS NOT NULL", clienteID).
+		Order("created_at DESC").
+		First(&juego).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("error obteniendo última partida del cliente: %w", err)
+	}
+	return &juego, nil
+}
+
+// ListarRetenidos obtiene las partidas con el premio retenido, de la más vieja a la más nueva
+func (r *juegoRepository) ListarRetenidos() ([]*models.Juego, error) {
+	var juegos []*models.Juego
+	if err := r.db.Where("premio_retenido = ?", true).
+		Order("created_at ASC").
+		Find(&juegos).Error; err != nil {
+		return nil, fmt.Errorf("error obteniendo premios retenidos: %w", err)
+	}
+	return juegos, nil
+}
+
+// BloquearRetenido busca una partida con el premio retenido bloqueando su fila hasta el fin
+// de la transacción, para que dos empleados no lo liberen a la vez
+func (r *juegoRepository) BloquearRetenido(id uint) (*models.Juego, error) {
+	var juego models.Juego
+	err := r.db.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("id = ? AND premio_retenido = ?", id, true).
+		First(&juego).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("la partida #%d no tiene un premio retenido", id)
+		}
+		return nil, fmt.Errorf("error buscando premio retenido: %w", err)
+	}
+	return &juego, nil
+}
+
+// ResolverRetenido saca la partida de los retenidos con el voucher liberado (nil si se
+// descartó: la partida queda como rechazada)
+func (r *juegoRepository) ResolverRetenido(id uint, voucherID *uint) error {
+	if err := r.db.Model(&models.Juego{}).Where("id = ?", id).
+		Updates(map[string]interface{}{"premio_retenido": false, "voucher_id": voucherID, "rechazado": voucherID == nil}).Error; err != nil {
+		return fmt.Errorf("error resolviendo premio retenido: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,29 @@
+package repository
+
+import (
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestContarPorClienteDesdeSinRechazados(t *testing.T) {
+	db, mock := baseDePrueba(t)
+	desde := time.Date(2026, 10, 12, 0, 0, 0, 0, time.Local)
+
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT count(*) FROM `juegos` WHERE cliente_id = ? AND created_at >= ? AND rechazado = ?")).
+		WithArgs(7, desde, false).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(3))
+
+	jugadas, err := NewJuegoRepository(db).ContarPorClienteDesde(7, desde)
+	if err != nil {
+		t.Fatalf("error contando partidas: %v", err)
+	}
+	if jugadas != 3 {
+		t.Errorf("partidas = %d, se esperaba 3", jugadas)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,59 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+
+	"gorm.io/gorm"
+
+	"CheeseHouse/internal/models"
+)
+
+// MensajeConfigRepository define la interfaz para los textos de WhatsApp editables
+type MensajeConfigRepository interface {
+	Crear(mensaje *models.MensajeConfig) error
+	BuscarPorClave(clave string) (*models.MensajeConfig, error)
+	Listar() ([]*models.MensajeConfig, error)
+}
+
+// mensajeConfigRepository implementación de MensajeConfigRepository
+type mensajeConfigRepository struct {
+	db *gorm.DB
+}
+
+// NewMensajeConfigRepository crea una nueva instancia del repositorio de textos de mensajes
+func NewMensajeConfigRepository(db *gorm.DB) MensajeConfigRepository {
+	return &mensajeConfigRepository{db: db}
+}
+
+// Crear registra el texto de una clave
+func (r *mensajeConfigRepository) Crear(mensaje *models.MensajeConfig) error {
+	if err := r.db.Create(mensaje).Error; err != nil {
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			return fmt.Errorf("ya existe un texto para el mensaje %q", mensaje.Clave)
+		}
+		return fmt.Errorf("error creando texto de mensaje: %w", err)
+	}
+	return nil
+}
+
+// BuscarPorClave busca el texto guardado de una clave; nil si nunca se guardó
+func (r *mensajeConfigRepository) BuscarPorClave(clave string) (*models.MensajeConfig, error) {
+	var mensaje models.MensajeConfig
+	if err := r.db.Where("clave = ?", clave).First(&mensaje).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("error buscando texto de mensaje: %w", err)
+	}
+	return &mensaje, nil
+}
+
+// Listar obtiene todos los textos guardados
+func (r *mensajeConfigRepository) Listar() ([]*models.MensajeConfig, error) {
+	var mensajes []*models.MensajeConfig
+	if err := r.db.Order("clave ASC").Find(&mensajes).Error; err != nil {
+		return nil, fmt.Errorf("error listando textos de mensajes: %w", err)
+	}
+	return mensajes, nil
+}
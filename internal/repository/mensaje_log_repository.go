@@ -0,0 +1,189 @@
+package repository
+
+import (
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+
+	"CheeseHouse/internal/models"
+)
+
+// MensajeLogRepository define la interfaz para el registro de mensajes enviados
+type MensajeLogRepository interface {
+	Crear(mensaje *models.MensajeLog) error
+	ListarPorTelefono(telefono string, limit int) ([]*models.MensajeLog, error)
+	ListarFallidosDesde(desde time.Time) ([]*models.MensajeLog, error)
+	ContarErroresPorCodigo(desde time.Time) (map[int]int, error)
+	ContarDestinatariosDesde(desde time.Time) (int, error)
+	ContarConversacionesDesde(desde time.Time) (int, error)
+	MarcarEntregado(messageID string, momento time.Time) error
+	MarcarFallido(messageID string, codigo int, mensaje string) error
+	EstadisticasPorDia(desde time.Time) ([]models.EstadisticaEnvios, error)
+	EstadisticasPorPlantilla(desde time.Time) ([]models.EstadisticaEnvios, error)
+}
+
+// mensajeLogRepository implementación de MensajeLogRepository
+type mensajeLogRepository struct {
+	db *gorm.DB
+}
+
+// NewMensajeLogRepository crea una nueva instancia del repositorio de mensajes
+func NewMensajeLogRepository(db *gorm.DB) MensajeLogRepository {
+	return &mensajeLogRepository{db: db}
+}
+
+// Crear registra un mensaje enviado o fallido
+func (r *mensajeLogRepository) Crear(mensaje *models.MensajeLog) error {
+	if err := r.db.Create(mensaje).Error; err != nil {
+		return fmt.Errorf("error registrando mensaje: %w", err)
+	}
+	return nil
+}
+
+// ListarPorTelefono obtiene los últimos mensajes enviados a un teléfono
+func (r *mensajeLogRepository) ListarPorTelefono(telefono string, limit int) ([]*models.MensajeLog, error) {
+	var mensajes []*models.MensajeLog
+	query := r.db.Where("telefono = ?", telefono).Order("created_at DESC")
+	if limit > 0 {
+		query = query.Limit(limit)
+	}
+	if err := query.Find(&mensajes).Error; err != nil {
+		return nil, fmt.Errorf("error listando mensajes del teléfono: %w", err)
+	}
+	return mensajes, nil
+}
+
+// ListarFallidosDesde obtiene los mensajes fallidos desde una fecha
+func (r *mensajeLogRepository) ListarFallidosDesde(desde time.Time) ([]*models.MensajeLog, error) {
+	var mensajes []*models.MensajeLog
+	if err := r.db.Where("estado = 'fallido' AND created_at >= ?", desde).
+		Order("created_at DESC").
+		Find(&mensajes).Error; err != nil {
+		return nil, fmt.Errorf("error listando mensajes fallidos: %w", err)
+	}
+	return mensajes, nil
+}
+
+// ContarErroresPorCodigo agrupa los mensajes fallidos por código de error de la API
+func (r *mensajeLogRepository) ContarErroresPorCodigo(desde time.Time) (map[int]int, error) {
+	var filas []struct {
+		ErrorCodigo int
+		Total       int
+	}
+	if err := r.db.Model(&models.MensajeLog{}).
+		Select("error_codigo, COUNT(*) as total").
+		Where("estado = 'fallido' AND error_codigo IS NOT NULL AND created_at >= ?", desde).
+		Group("error_codigo").
+		Scan(&filas).Error; err != nil {
+		return nil, fmt.Errorf("error contando errores por código: %w", err)
+	}
+
+	resultado := make(map[int]int, len(filas))
+	for _, fila := range filas {
+		resultado[fila.ErrorCodigo] = fila.Total
+	}
+	return resultado, nil
+}
+
+// ContarDestinatariosDesde cuenta los teléfonos distintos a los que se envió algún mensaje
+func (r *mensajeLogRepository) ContarDestinatariosDesde(desde time.Time) (int, error) {
+	var total int64
+	if err := r.db.Model(&models.MensajeLog{}).
+		Where("estado = 'enviado' AND created_at >= ?", desde).
+		Distinct("telefono").
+		Count(&total).Error; err != nil {
+		return 0, fmt.Errorf("error contando destinatarios: %w", err)
+	}
+	return int(total), nil
+}
+
+// ContarConversacionesDesde aproxima las conversaciones facturables como
+// pares distintos de teléfono y día con al menos un mensaje enviado
+func (r *mensajeLogRepository) ContarConversacionesDesde(desde time.Time) (int, error) {
+	var total int
+	if err := r.db.Model(&models.MensajeLog{}).
+		Select("COUNT(DISTINCT CONCAT(telefono, '|', CAST(created_at AS DATE)))").
+		Where("estado = 'enviado' AND created_at >= ?", desde).
+		Scan(&total).Error; err != nil {
+		return 0, fmt.Errorf("error contando conversaciones: %w", err)
+	}
+	return total, nil
+}
+
+// MarcarEntregado registra la entrega informada por el webhook; si llegan varios estados
+// (delivered y read) queda el primero
+func (r *mensajeLogRepository) MarcarEntregado(messageID string, momento time.Time) error {
+	if err := r.db.Model(&models.MensajeLog{}).
+		Where("message_id = ? AND entregado_at IS NULL", messageID).
+		Update("entregado_at", momento).Error; err != nil {
+		return fmt.Errorf("error marcando mensaje entregado: %w", err)
+	}
+	return nil
+}
+
+// MarcarFallido pasa a fallido un mensaje que la API aceptó pero después no se pudo entregar
+func (r *mensajeLogRepository) MarcarFallido(messageID string, codigo int, mensaje string) error {
+	cambios := map[string]interface{}{
+		"estado":        "fallido",
+		"error_mensaje": mensaje,
+	}
+	if codigo != 0 {
+		cambios["error_codigo"] = codigo
+	}
+	if err := r.db.Model(&models.MensajeLog{}).
+		Where("message_id = ?", messageID).
+		Updates(cambios).Error; err != nil {
+		return fmt.Errorf("error marcando mensaje fallido: %w", err)
+	}
+	return nil
+}
+
+// seleccionEnvios columnas comunes de los resúmenes de envíos
+func (r *mensajeLogRepository) seleccionEnvios() string {
+	return fmt.Sprintf(`COUNT(*) AS intentos,
+		COUNT(CASE WHEN estado = 'enviado' THEN 1 END) AS enviados,
+		COUNT(CASE WHEN entregado_at IS NOT NULL THEN 1 END) AS entregados,
+		COUNT(CASE WHEN estado = 'fallido' THEN 1 END) AS fallidos,
+		COALESCE(AVG(CASE WHEN latencia_ms > 0 THEN latencia_ms END), 0) AS latencia_ms,
+		COALESCE(AVG(CASE WHEN entregado_at IS NOT NULL THEN %s END), 0) AS entrega_segundos`,
+		segundosEntre(r.db, "created_at", "entregado_at"))
+}
+
+// EstadisticasPorDia resume los envíos desde la fecha indicada agrupados por día
+func (r *mensajeLogRepository) EstadisticasPorDia(desde time.Time) ([]models.EstadisticaEnvios, error) {
+	var filas []struct {
+		models.EstadisticaEnvios
+		Fecha time.Time
+	}
+	if err := r.db.Model(&models.MensajeLog{}).
+		Select("CAST(created_at AS DATE) AS fecha, "+r.seleccionEnvios()).
+		Where("created_at >= ?", desde).
+		Group("CAST(created_at AS DATE)").
+		Order("fecha ASC").
+		Scan(&filas).Error; err != nil {
+		return nil, fmt.Errorf("error resumiendo envíos por día: %w", err)
+	}
+
+	estadisticas := make([]models.EstadisticaEnvios, len(filas))
+	for i, fila := range filas {
+		estadisticas[i] = fila.EstadisticaEnvios
+		estadisticas[i].Dia = fila.Fecha.Format(time.DateOnly)
+	}
+	return estadisticas, nil
+}
+
+// EstadisticasPorPlantilla resume los envíos desde la fecha indicada agrupados por
+// plantilla; los mensajes de texto libre quedan juntos como "texto"
+func (r *mensajeLogRepository) EstadisticasPorPlantilla(desde time.Time) ([]models.EstadisticaEnvios, error) {
+	var estadisticas []models.EstadisticaEnvios
+	if err := r.db.Model(&models.MensajeLog{}).
+		Select("COALESCE(NULLIF(plantilla, ''), 'texto') AS plantilla, "+r.seleccionEnvios()).
+		Where("created_at >= ?", desde).
+		Group("COALESCE(NULLIF(plantilla, ''), 'texto')").
+		Order("intentos DESC").
+		Scan(&estadisticas).Error; err != nil {
+		return nil, fmt.Errorf("error resumiendo envíos por plantilla: %w", err)
+	}
+	return estadisticas, nil
+}
@@ -0,0 +1,88 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+
+	"gorm.io/gorm"
+
+	"CheeseHouse/internal/models"
+)
+
+// MenuRepository define la interfaz para los items del menú
+type MenuRepository interface {
+	Crear(item *models.ItemMenu) error
+	BuscarPorID(id uint) (*models.ItemMenu, error)
+	Actualizar(item *models.ItemMenu) error
+	Eliminar(id uint) error
+	Listar(soloActivos bool) ([]*models.ItemMenu, error)
+}
+
+// menuRepository implementación de MenuRepository
+type menuRepository struct {
+	db *gorm.DB
+}
+
+// NewMenuRepository crea una nueva instancia del repositorio del menú
+func NewMenuRepository(db *gorm.DB) MenuRepository {
+	return &menuRepository{db: db}
+}
+
+// Crear registra un nuevo item del menú
+func (r *menuRepository) Crear(item *models.ItemMenu) error {
+	if err := r.db.Create(item).Error; err != nil {
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			return fmt.Errorf("ya existe un item del menú llamado %q", item.Nombre)
+		}
+		return fmt.Errorf("error creando item del menú: %w", err)
+	}
+	return nil
+}
+
+// BuscarPorID busca un item del menú por ID
+func (r *menuRepository) BuscarPorID(id uint) (*models.ItemMenu, error) {
+	var item models.ItemMenu
+	if err := r.db.First(&item, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("item del menú con ID %d no encontrado", id)
+		}
+		return nil, fmt.Errorf("error buscando item del menú: %w", err)
+	}
+	return &item, nil
+}
+
+// Actualizar guarda los cambios de un item del menú
+func (r *menuRepository) Actualizar(item *models.ItemMenu) error {
+	if err := r.db.Save(item).Error; err != nil {
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			return fmt.Errorf("ya existe un item del menú llamado %q", item.Nombre)
+		}
+		return fmt.Errorf("error actualizando item del menú: %w", err)
+	}
+	return nil
+}
+
+// Eliminar borra un item del menú; los pedidos conservan el nombre y precio copiados
+func (r *menuRepository) Eliminar(id uint) error {
+	resultado := r.db.Delete(&models.ItemMenu{}, id)
+	if resultado.Error != nil {
+		return fmt.Errorf("error eliminando item del menú: %w", resultado.Error)
+	}
+	if resultado.RowsAffected == 0 {
+		return fmt.Errorf("item del menú con ID %d no encontrado", id)
+	}
+	return nil
+}
+
+// Listar obtiene los items del menú por nombre
+func (r *menuRepository) Listar(soloActivos bool) ([]*models.ItemMenu, error) {
+	var items []*models.ItemMenu
+	query := r.db.Order("nombre ASC")
+	if soloActivos {
+		query = query.Where("activo = TRUE")
+	}
+	if err := query.Find(&items).Error; err != nil {
+		return nil, fmt.Errorf("error listando menú: %w", err)
+	}
+	return items, nil
+}
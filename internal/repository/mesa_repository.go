@@ -0,0 +1,78 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+
+	"gorm.io/gorm"
+
+	"CheeseHouse/internal/models"
+)
+
+// MesaRepository define la interfaz para las mesas del local con código QR
+type MesaRepository interface {
+	Crear(mesa *models.Mesa) error
+	BuscarPorID(id uint) (*models.Mesa, error)
+	BuscarPorNumero(numero string) (*models.Mesa, error)
+	Actualizar(mesa *models.Mesa) error
+	Listar() ([]*models.Mesa, error)
+}
+
+// mesaRepository implementación de MesaRepository
+type mesaRepository struct {
+	db *gorm.DB
+}
+
+// NewMesaRepository crea una nueva instancia del repositorio de mesas
+func NewMesaRepository(db *gorm.DB) MesaRepository {
+	return &mesaRepository{db: db}
+}
+
+// Crear registra una nueva mesa
+func (r *mesaRepository) Crear(mesa *models.Mesa) error {
+	if err := r.db.Create(mesa).Error; err != nil {
+		return fmt.Errorf("error creando mesa: %w", err)
+	}
+	return nil
+}
+
+// BuscarPorID busca una mesa por ID
+func (r *mesaRepository) BuscarPorID(id uint) (*models.Mesa, error) {
+	var mesa models.Mesa
+	if err := r.db.First(&mesa, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("mesa con ID %d no encontrada", id)
+		}
+		return nil, fmt.Errorf("error buscando mesa: %w", err)
+	}
+	return &mesa, nil
+}
+
+// BuscarPorNumero busca una mesa por el número impreso; nil si no existe
+func (r *mesaRepository) BuscarPorNumero(numero string) (*models.Mesa, error) {
+	var mesa models.Mesa
+	if err := r.db.Where("numero = ?", numero).First(&mesa).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("error buscando mesa: %w", err)
+	}
+	return &mesa, nil
+}
+
+// Actualizar guarda los cambios de una mesa
+func (r *mesaRepository) Actualizar(mesa *models.Mesa) error {
+	if err := r.db.Save(mesa).Error; err != nil {
+		return fmt.Errorf("error actualizando mesa: %w", err)
+	}
+	return nil
+}
+
+// Listar obtiene todas las mesas ordenadas por área y número
+func (r *mesaRepository) Listar() ([]*models.Mesa, error) {
+	var mesas []*models.Mesa
+	if err := r.db.Order("area ASC, LENGTH(numero) ASC, numero ASC").Find(&mesas).Error; err != nil {
+		return nil, fmt.Errorf("error listando mesas: %w", err)
+	}
+	return mesas, nil
+}
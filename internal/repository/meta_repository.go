@@ -0,0 +1,46 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+
+	"gorm.io/gorm"
+
+	"CheeseHouse/internal/models"
+)
+
+// MetaRepository define la interfaz para las metas mensuales
+type MetaRepository interface {
+	BuscarPorMes(mes string) (*models.MetaMensual, error)
+	Guardar(meta *models.MetaMensual) error
+}
+
+// metaRepository implementación de MetaRepository
+type metaRepository struct {
+	db *gorm.DB
+}
+
+// NewMetaRepository crea una nueva instancia del repositorio de metas
+func NewMetaRepository(db *gorm.DB) MetaRepository {
+	return &metaRepository{db: db}
+}
+
+// BuscarPorMes obtiene las metas de un mes (AAAA-MM), o nil si no se cargaron
+func (r *metaRepository) BuscarPorMes(mes string) (*models.MetaMensual, error) {
+	var meta models.MetaMensual
+	if err := r.db.Where("mes = ?", mes).First(&meta).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("error buscando metas del mes: %w", err)
+	}
+	return &meta, nil
+}
+
+// Guardar crea o actualiza las metas de un mes
+func (r *metaRepository) Guardar(meta *models.MetaMensual) error {
+	if err := r.db.Save(meta).Error; err != nil {
+		return fmt.Errorf("error guardando metas del mes: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,78 @@
+package repository
+
+import (
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+
+	"CheeseHouse/internal/models"
+)
+
+// NotificacionRepository define la interfaz para el centro de notificaciones
+type NotificacionRepository interface {
+	Crear(notificacion *models.Notificacion) error
+	Listar(soloNoLeidas bool, limite int) ([]*models.Notificacion, error)
+	ContarNoLeidas() (int, error)
+	MarcarLeida(id uint, usuarioID uint) error
+}
+
+// notificacionRepository implementación de NotificacionRepository
+type notificacionRepository struct {
+	db *gorm.DB
+}
+
+// NewNotificacionRepository crea una nueva instancia del repositorio de notificaciones
+func NewNotificacionRepository(db *gorm.DB) NotificacionRepository {
+	return &notificacionRepository{db: db}
+}
+
+// Crear registra una nueva notificación
+func (r *notificacionRepository) Crear(notificacion *models.Notificacion) error {
+	if err := r.db.Create(notificacion).Error; err != nil {
+		return fmt.Errorf("error creando notificación: %w", err)
+	}
+	return nil
+}
+
+// Listar obtiene las notificaciones más recientes
+func (r *notificacionRepository) Listar(soloNoLeidas bool, limite int) ([]*models.Notificacion, error) {
+	var notificaciones []*models.Notificacion
+	query := r.db.Order("created_at DESC").Limit(limite)
+	if soloNoLeidas {
+		query = query.Where("leida = FALSE")
+	}
+	if err := query.Find(&notificaciones).Error; err != nil {
+		return nil, fmt.Errorf("error listando notificaciones: %w", err)
+	}
+	return notificaciones, nil
+}
+
+// ContarNoLeidas cuenta las notificaciones pendientes de revisar
+func (r *notificacionRepository) ContarNoLeidas() (int, error) {
+	var count int64
+	if err := r.db.Model(&models.Notificacion{}).
+		Where("leida = FALSE").
+		Count(&count).Error; err != nil {
+		return 0, fmt.Errorf("error contando notificaciones: %w", err)
+	}
+	return int(count), nil
+}
+
+// MarcarLeida marca una notificación como revisada
+func (r *notificacionRepository) MarcarLeida(id uint, usuarioID uint) error {
+	resultado := r.db.Model(&models.Notificacion{}).
+		Where("id = ?", id).
+		Updates(map[string]interface{}{
+			"leida":     true,
+			"leida_por": usuarioID,
+			"leida_at":  time.Now(),
+		})
+	if resultado.Error != nil {
+		return fmt.Errorf("error marcando notificación: %w", resultado.Error)
+	}
+	if resultado.RowsAffected == 0 {
+		return fmt.Errorf("notificación no encontrada")
+	}
+	return nil
+}
@@ -0,0 +1,137 @@
+package repository
+
+import (
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+
+	"CheeseHouse/internal/models"
+)
+
+// OutboxRepository define la interfaz para la cola de mensajes salientes
+type OutboxRepository interface {
+	Crear(mensaje *models.MensajeOutbox) error
+	ListarPendientes(hasta time.Time, limit int) ([]*models.MensajeOutbox, error)
+	MarcarEnviado(id uint, messageID string) error
+	MarcarFallido(id uint, errorMsg string) error
+	Reprogramar(id uint, programadoPara time.Time, errorMsg string) error
+	BuscarPorID(id uint) (*models.MensajeOutbox, error)
+	ListarPorEstado(estado string, limit int) ([]*models.MensajeOutbox, error)
+	GetProgramadosEntre(categoria string, desde, hasta time.Time) ([]time.Time, error)
+}
+
+// outboxRepository implementación de OutboxRepository
+type outboxRepository struct {
+	db *gorm.DB
+}
+
+// NewOutboxRepository crea una nueva instancia del repositorio de outbox
+func NewOutboxRepository(db *gorm.DB) OutboxRepository {
+	return &outboxRepository{db: db}
+}
+
+// Crear encola un nuevo mensaje
+func (r *outboxRepository) Crear(mensaje *models.MensajeOutbox) error {
+	if err := r.db.Create(mensaje).Error; err != nil {
+		return fmt.Errorf("error encolando mensaje: %w", err)
+	}
+	return nil
+}
+
+// ListarPendientes obtiene mensajes pendientes cuya hora programada ya llegó
+func (r *outboxRepository) ListarPendientes(hasta time.Time, limit int) ([]*models.MensajeOutbox, error) {
+	var mensajes []*models.MensajeOutbox
+	query := r.db.Preload("Voucher.Cliente").
+		Where("estado = 'pendiente' AND programado_para <= ?", hasta).
+		Order("programado_para ASC")
+	if limit > 0 {
+		query = query.Limit(limit)
+	}
+	if err := query.Find(&mensajes).Error; err != nil {
+		return nil, fmt.Errorf("error listando mensajes pendientes: %w", err)
+	}
+	return mensajes, nil
+}
+
+// MarcarEnviado marca un mensaje como enviado y guarda el wamid para seguir su entrega
+func (r *outboxRepository) MarcarEnviado(id uint, messageID string) error {
+	now := time.Now()
+	if err := r.db.Model(&models.MensajeOutbox{}).
+		Where("id = ?", id).
+		Updates(map[string]interface{}{
+			"estado":     "enviado",
+			"enviado_at": now,
+			"intentos":   gorm.Expr("intentos + 1"),
+			"message_id": messageID,
+		}).Error; err != nil {
+		return fmt.Errorf("error marcando mensaje como enviado: %w", err)
+	}
+	return nil
+}
+
+// MarcarFallido registra un intento fallido de envío
+func (r *outboxRepository) MarcarFallido(id uint, errorMsg string) error {
+	if err := r.db.Model(&models.MensajeOutbox{}).
+		Where("id = ?", id).
+		Updates(map[string]interface{}{
+			"estado":       "fallido",
+			"ultimo_error": errorMsg,
+			"intentos":     gorm.Expr("intentos + 1"),
+		}).Error; err != nil {
+		return fmt.Errorf("error marcando mensaje como fallido: %w", err)
+	}
+	return nil
+}
+
+// Reprogramar registra un intento fallido y deja el mensaje pendiente para más tarde
+func (r *outboxRepository) Reprogramar(id uint, programadoPara time.Time, errorMsg string) error {
+	if err := r.db.Model(&models.MensajeOutbox{}).
+		Where("id = ?", id).
+		Updates(map[string]interface{}{
+			"estado":          "pendiente",
+			"programado_para": programadoPara,
+			"ultimo_error":    errorMsg,
+			"intentos":        gorm.Expr("intentos + 1"),
+		}).Error; err != nil {
+		return fmt.Errorf("error reprogramando mensaje: %w", err)
+	}
+	return nil
+}
+
+// BuscarPorID obtiene un mensaje de la cola, con el voucher y su cliente si es un aviso de voucher
+func (r *outboxRepository) BuscarPorID(id uint) (*models.MensajeOutbox, error) {
+	var mensaje models.MensajeOutbox
+	if err := r.db.Preload("Voucher.Cliente").First(&mensaje, id).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, fmt.Errorf("mensaje no encontrado")
+		}
+		return nil, fmt.Errorf("error buscando mensaje: %w", err)
+	}
+	return &mensaje, nil
+}
+
+// ListarPorEstado obtiene los mensajes de un estado; vacío = pendientes y fallidos
+func (r *outboxRepository) ListarPorEstado(estado string, limit int) ([]*models.MensajeOutbox, error) {
+	var mensajes []*models.MensajeOutbox
+	query := r.db.Where("estado IN ?", []string{"pendiente", "fallido"})
+	if estado != "" {
+		query = r.db.Where("estado = ?", estado)
+	}
+	if err := query.Order("programado_para ASC").Limit(limit).Find(&mensajes).Error; err != nil {
+		return nil, fmt.Errorf("error listando mensajes: %w", err)
+	}
+	return mensajes, nil
+}
+
+// GetProgramadosEntre obtiene el horario de envío de los mensajes pendientes de una
+// categoría programados en el rango [desde, hasta)
+func (r *outboxRepository) GetProgramadosEntre(categoria string, desde, hasta time.Time) ([]time.Time, error) {
+	var fechas []time.Time
+	if err := r.db.Model(&models.MensajeOutbox{}).
+		Where("estado = 'pendiente' AND categoria = ? AND programado_para >= ? AND programado_para < ?", categoria, desde, hasta).
+		Pluck("programado_para", &fechas).Error; err != nil {
+		return nil, fmt.Errorf("error obteniendo mensajes programados: %w", err)
+	}
+	return fechas, nil
+}
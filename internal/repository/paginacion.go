@@ -0,0 +1,103 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"gorm.io/gorm"
+
+	"CheeseHouse/internal/models"
+)
+
+// ErrOrdenInvalido el campo de orden pedido no está en la lista blanca del listado
+var ErrOrdenInvalido = errors.New("campo de orden no permitido")
+
+// Campos por los que se puede ordenar cada listado, con la columna que les corresponde
+// (todos incluyen "id"). Solo se arma ORDER BY con estas columnas, nunca con lo que
+// llega en la consulta
+var (
+	ordenClientes = map[string]string{
+		"id":                 "id",
+		"nombre":             "nombre",
+		"created_at":         "created_at",
+		"fecha_ultimo_juego": "fecha_ultimo_juego",
+		"total_juegos":       "total_juegos",
+		"juegos_ganados":     "juegos_ganados",
+		"puntaje_actividad":  "puntaje_actividad",
+	}
+	ordenContactos = map[string]string{
+		"id":         "c.id",
+		"nombre":     "c.nombre",
+		"created_at": "c.created_at",
+	}
+	ordenVouchers = map[string]string{
+		"id":                "id",
+		"codigo":            "codigo",
+		"created_at":        "created_at",
+		"fecha_emision":     "fecha_emision",
+		"fecha_vencimiento": "fecha_vencimiento",
+		"fecha_uso":         "fecha_uso",
+		"descuento":         "descuento",
+	}
+	ordenCampanas = map[string]string{
+		"id":                "c.id",
+		"nombre":            "c.nombre",
+		"created_at":        "c.created_at",
+		"fecha_vencimiento": "c.fecha_vencimiento",
+		"descuento":         "c.descuento",
+		"total_envios":      "total_envios",
+	}
+)
+
+// ordenarPor traduce el orden pedido (campo o -campo) a una cláusula ORDER BY con la
+// columna de la lista blanca. Vacío = porDefecto
+func ordenarPor(orden string, columnas map[string]string, porDefecto string) (string, error) {
+	if orden == "" {
+		return porDefecto, nil
+	}
+
+	direccion := "ASC"
+	campo := orden
+	if strings.HasPrefix(orden, "-") {
+		direccion = "DESC"
+		campo = orden[1:]
+	}
+
+	columna, ok := columnas[campo]
+	if !ok {
+		permitidos := make([]string, 0, len(columnas))
+		for nombre := range columnas {
+			permitidos = append(permitidos, nombre)
+		}
+		sort.Strings(permitidos)
+		return "", fmt.Errorf("%w: %q (permitidos: %s)", ErrOrdenInvalido, campo, strings.Join(permitidos, ", "))
+	}
+	// El id desempata filas con el mismo valor para que no salten entre páginas
+	if campo != "id" {
+		return fmt.Sprintf("%s %s, %s %s", columna, direccion, columnas["id"], direccion), nil
+	}
+	return columna + " " + direccion, nil
+}
+
+// paginar cuenta las filas que cumplen los filtros de query y le aplica orden, offset y
+// límite de la página. query debe tener solo los filtros (sin Preload ni Order)
+func paginar(query *gorm.DB, modelo interface{}, p models.Paginacion, columnas map[string]string, porDefecto string) (*gorm.DB, int64, error) {
+	orden, err := ordenarPor(p.Orden, columnas, porDefecto)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	query = query.Session(&gorm.Session{})
+	var total int64
+	if err := query.Model(modelo).Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("error contando resultados: %w", err)
+	}
+
+	query = query.Order(orden)
+	if p.TamanoPagina > 0 {
+		query = query.Offset(p.Offset()).Limit(p.TamanoPagina)
+	}
+	return query, total, nil
+}
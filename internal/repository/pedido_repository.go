@@ -0,0 +1,196 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+
+	"CheeseHouse/internal/models"
+)
+
+// PedidoRepository define la interfaz para los pedidos de WhatsApp
+type PedidoRepository interface {
+	Crear(pedido *models.Pedido) error
+	BuscarPorID(id uint) (*models.Pedido, error)
+	ActualizarEstado(pedido *models.Pedido) error
+	Asignar(pedidoID uint, usuarioID uint) error
+	ListarConFiltros(filtros map[string]interface{}) ([]*models.Pedido, error)
+	ListarPorCliente(clienteID uint, telefono string, limite int) ([]*models.Pedido, error)
+	UltimoDelCliente(clienteID uint, telefono string) (*models.Pedido, error)
+	ListarCola() ([]*models.Pedido, error)
+	MarcarItemPreparado(itemID uint, preparado bool) error
+	ContarCola() (int, error)
+	GetPromediosPreparacion(desde time.Time) ([]*models.PromedioPreparacion, error)
+}
+
+// pedidoRepository implementación de PedidoRepository
+type pedidoRepository struct {
+	db *gorm.DB
+}
+
+// NewPedidoRepository crea una nueva instancia del repositorio de pedidos
+func NewPedidoRepository(db *gorm.DB) PedidoRepository {
+	return &pedidoRepository{db: db}
+}
+
+// Crear guarda el pedido junto con sus items
+func (r *pedidoRepository) Crear(pedido *models.Pedido) error {
+	if err := r.db.Create(pedido).Error; err != nil {
+		return fmt.Errorf("error creando pedido: %w", err)
+	}
+	return nil
+}
+
+// BuscarPorID busca un pedido con sus items
+func (r *pedidoRepository) BuscarPorID(id uint) (*models.Pedido, error) {
+	var pedido models.Pedido
+	if err := r.db.Preload("Items").Preload("EmpleadoAtiende").First(&pedido, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("pedido con ID %d no encontrado", id)
+		}
+		return nil, fmt.Errorf("error buscando pedido: %w", err)
+	}
+	return &pedido, nil
+}
+
+// ActualizarEstado guarda el estado y el empleado que atiende el pedido
+func (r *pedidoRepository) ActualizarEstado(pedido *models.Pedido) error {
+	if err := r.db.Model(pedido).
+		Select("estado", "atendido_por", "completado_at").
+		Updates(pedido).Error; err != nil {
+		return fmt.Errorf("error actualizando estado del pedido: %w", err)
+	}
+	return nil
+}
+
+// Asignar fija el empleado que atiende el pedido
+func (r *pedidoRepository) Asignar(pedidoID uint, usuarioID uint) error {
+	if err := r.db.Model(&models.Pedido{}).
+		Where("id = ?", pedidoID).
+		Update("atendido_por", usuarioID).Error; err != nil {
+		return fmt.Errorf("error asignando pedido: %w", err)
+	}
+	return nil
+}
+
+// ListarConFiltros lista pedidos aplicando filtros (estado, atendido_por, sin_asignar,
+// telefono, fecha_desde, fecha_hasta, limit), del más reciente al más antiguo
+func (r *pedidoRepository) ListarConFiltros(filtros map[string]interface{}) ([]*models.Pedido, error) {
+	query := r.db.Preload("Items").Preload("EmpleadoAtiende")
+
+	if estado, ok := filtros["estado"]; ok {
+		query = query.Where("estado = ?", estado)
+	}
+
+	if atendidoPor, ok := filtros["atendido_por"]; ok {
+		query = query.Where("atendido_por = ?", atendidoPor)
+	}
+
+	if _, ok := filtros["sin_asignar"]; ok {
+		query = query.Where("atendido_por IS NULL")
+	}
+
+	if telefono, ok := filtros["telefono"]; ok {
+		query = query.Where("telefono = ?", telefono)
+	}
+
+	if fechaDesde, ok := filtros["fecha_desde"]; ok {
+		query = query.Where("created_at >= ?", fechaDesde)
+	}
+
+	if fechaHasta, ok := filtros["fecha_hasta"]; ok {
+		query = query.Where("created_at <= ?", fechaHasta)
+	}
+
+	if limit, ok := filtros["limit"]; ok {
+		query = query.Limit(limit.(int))
+	}
+
+	var pedidos []*models.Pedido
+	if err := query.Order("created_at DESC").Find(&pedidos).Error; err != nil {
+		return nil, fmt.Errorf("error listando pedidos: %w", err)
+	}
+	return pedidos, nil
+}
+
+// ListarPorCliente obtiene los pedidos del cliente, incluidos los hechos desde su
+// teléfono antes de registrarse, del más reciente al más antiguo
+func (r *pedidoRepository) ListarPorCliente(clienteID uint, telefono string, limite int) ([]*models.Pedido, error) {
+	var pedidos []*models.Pedido
+	if err := r.db.Preload("Items").
+		Where("cliente_id = ? OR telefono = ?", clienteID, telefono).
+		Order("created_at DESC").
+		Limit(limite).
+		Find(&pedidos).Error; err != nil {
+		return nil, fmt.Errorf("error listando pedidos del cliente: %w", err)
+	}
+	return pedidos, nil
+}
+
+// UltimoDelCliente obtiene el último pedido no cancelado del cliente, o nil si no tiene
+func (r *pedidoRepository) UltimoDelCliente(clienteID uint, telefono string) (*models.Pedido, error) {
+	var pedido models.Pedido
+	if err := r.db.Preload("Items").
+		Where("(cliente_id = ? OR telefono = ?) AND estado <> 'cancelado'", clienteID, telefono).
+		Order("created_at DESC").
+		First(&pedido).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("error buscando último pedido del cliente: %w", err)
+	}
+	return &pedido, nil
+}
+
+// ListarCola obtiene los pedidos que la cocina tiene que preparar, por hora prometida
+func (r *pedidoRepository) ListarCola() ([]*models.Pedido, error) {
+	var pedidos []*models.Pedido
+	if err := r.db.Preload("Items").
+		Where("estado IN ?", []string{"pendiente", "procesando"}).
+		Order("prometido_para IS NULL, prometido_para ASC, id ASC").
+		Find(&pedidos).Error; err != nil {
+		return nil, fmt.Errorf("error listando cola de cocina: %w", err)
+	}
+	return pedidos, nil
+}
+
+// MarcarItemPreparado guarda el estado de preparación de un item
+func (r *pedidoRepository) MarcarItemPreparado(itemID uint, preparado bool) error {
+	if err := r.db.Model(&models.PedidoItem{}).
+		Where("id = ?", itemID).
+		Update("preparado", preparado).Error; err != nil {
+		return fmt.Errorf("error marcando item como preparado: %w", err)
+	}
+	return nil
+}
+
+// ContarCola cuenta los pedidos pendientes y en preparación
+func (r *pedidoRepository) ContarCola() (int, error) {
+	var count int64
+	if err := r.db.Model(&models.Pedido{}).
+		Where("estado IN ?", []string{"pendiente", "procesando"}).
+		Count(&count).Error; err != nil {
+		return 0, fmt.Errorf("error contando cola de cocina: %w", err)
+	}
+	return int(count), nil
+}
+
+// GetPromediosPreparacion calcula el tiempo promedio de preparación por tamaño de
+// pedido de los completados desde la fecha indicada
+func (r *pedidoRepository) GetPromediosPreparacion(desde time.Time) ([]*models.PromedioPreparacion, error) {
+	var promedios []*models.PromedioPreparacion
+	if err := r.db.Raw(fmt.Sprintf(`
+		SELECT CASE WHEN t.unidades <= 2 THEN 'chico' WHEN t.unidades <= 5 THEN 'mediano' ELSE 'grande' END AS tamano,
+			COUNT(*) AS pedidos,
+			AVG(%s) / 60 AS minutos
+		FROM pedidos p
+		JOIN (SELECT pedido_id, SUM(cantidad) AS unidades FROM pedidos_items GROUP BY pedido_id) t ON t.pedido_id = p.id
+		WHERE p.estado = 'completado' AND p.completado_at >= ?
+		GROUP BY tamano`, segundosEntre(r.db, "p.created_at", "p.completado_at")), desde).
+		Scan(&promedios).Error; err != nil {
+		return nil, fmt.Errorf("error calculando tiempos de preparación: %w", err)
+	}
+	return promedios, nil
+}
@@ -0,0 +1,64 @@
+package repository
+
+import (
+	"fmt"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"CheeseHouse/internal/models"
+)
+
+// PreferenciasRepository define la interfaz para las preferencias de comunicación
+type PreferenciasRepository interface {
+	BuscarPorCliente(clienteID uint) (*models.PreferenciasComunicacion, error)
+	Guardar(preferencias *models.PreferenciasComunicacion) error
+	RegistrarBaja(baja *models.BajaMarketing) error
+}
+
+// preferenciasRepository implementación de PreferenciasRepository
+type preferenciasRepository struct {
+	db *gorm.DB
+}
+
+// NewPreferenciasRepository crea una nueva instancia del repositorio de preferencias
+func NewPreferenciasRepository(db *gorm.DB) PreferenciasRepository {
+	return &preferenciasRepository{db: db}
+}
+
+// BuscarPorCliente obtiene las preferencias de un cliente; si no las configuró
+// retorna los valores por defecto (WhatsApp, todas las categorías)
+func (r *preferenciasRepository) BuscarPorCliente(clienteID uint) (*models.PreferenciasComunicacion, error) {
+	var preferencias models.PreferenciasComunicacion
+	if err := r.db.Where("cliente_id = ?", clienteID).First(&preferencias).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return &models.PreferenciasComunicacion{
+				ClienteID:     clienteID,
+				Canal:         "whatsapp",
+				Transaccional: true,
+				Marketing:     true,
+			}, nil
+		}
+		return nil, fmt.Errorf("error buscando preferencias: %w", err)
+	}
+	return &preferencias, nil
+}
+
+// Guardar crea o actualiza las preferencias de un cliente
+func (r *preferenciasRepository) Guardar(preferencias *models.PreferenciasComunicacion) error {
+	if err := r.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "cliente_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"canal", "transaccional", "marketing", "updated_at"}),
+	}).Create(preferencias).Error; err != nil {
+		return fmt.Errorf("error guardando preferencias: %w", err)
+	}
+	return nil
+}
+
+// RegistrarBaja guarda una baja de marketing para las estadísticas de campañas
+func (r *preferenciasRepository) RegistrarBaja(baja *models.BajaMarketing) error {
+	if err := r.db.Create(baja).Error; err != nil {
+		return fmt.Errorf("error registrando baja de marketing: %w", err)
+	}
+	return nil
+}
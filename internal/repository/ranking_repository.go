@@ -0,0 +1,153 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+
+	"CheeseHouse/internal/models"
+)
+
+// ErrCierreRankingDuplicado el período ya se cerró (otra instancia o un cierre simultáneo)
+var ErrCierreRankingDuplicado = errors.New("el período del ranking ya se cerró")
+
+// RankingRepository define la interfaz para el ranking semanal y sus cierres
+type RankingRepository interface {
+	MejoresEntre(desde, hasta time.Time, soloMuro bool, limite int) ([]models.PosicionRanking, error)
+	ContarParticipantesEntre(desde, hasta time.Time) (int, error)
+	UltimoCierre() (*models.CierreRanking, error)
+	CrearCierre(cierre *models.CierreRanking) error
+	AsignarVoucherCierre(cierreID, voucherID uint) error
+	ListarCierres(limite int) ([]*models.CierreRanking, error)
+}
+
+// partidaJugada filtro de juegos que cuentan para el ranking: los intentos rechazados (espera,
+// tope, aprobación, emisión pausada) también se guardan y no deben competir. Las filas
+// anteriores al flag rechazado se reconocen por no tener voucher ni premio retenido
+const partidaJugada = "juegos.rechazado = ? AND (juegos.voucher_id IS NOT NULL OR juegos.premio_retenido = ?)"
+
+// rankingRepository implementación de RankingRepository
+type rankingRepository struct {
+	db *gorm.DB
+}
+
+// NewRankingRepository crea una nueva instancia del repositorio del ranking
+func NewRankingRepository(db *gorm.DB) RankingRepository {
+	return &rankingRepository{db: db}
+}
+
+// MejoresEntre obtiene la mejor partida jugada de cada cliente activo en el rango
+// [desde, hasta), de la más cercana al objetivo a la más lejana (a igual diferencia, la
+// primera en lograrla). Con soloMuro quedan solo los que aceptaron aparecer en público
+func (r *rankingRepository) MejoresEntre(desde, hasta time.Time, soloMuro bool, limite int) ([]models.PosicionRanking, error) {
+	consulta := r.db.Table("juegos").
+		Select("juegos.cliente_id, MIN(ABS(juegos.tiempo_obtenido - juegos.tiempo_objetivo)) AS diferencia_segundos, COUNT(*) AS partidas, MIN(juegos.created_at) AS primera").
+		Joins("JOIN clientes ON clientes.id = juegos.cliente_id").
+		Where("juegos.created_at >= ? AND juegos.created_at < ?", desde, hasta).
+		Where(partidaJugada, false, true).
+		Where("clientes.estado = 'activo'")
+	if soloMuro {
+		consulta = consulta.Where("clientes.consentimiento_muro = ?", true)
+	}
+
+	var filas []struct {
+		ClienteID          uint
+		DiferenciaSegundos float64
+		Partidas           int
+	}
+	if err := consulta.Group("juegos.cliente_id").
+		Order("diferencia_segundos ASC, primera ASC").
+		Limit(limite).
+		Scan(&filas).Error; err != nil {
+		return nil, fmt.Errorf("error obteniendo ranking: %w", err)
+	}
+	if len(filas) == 0 {
+		return nil, nil
+	}
+
+	ids := make([]uint, 0, len(filas))
+	for _, fila := range filas {
+		ids = append(ids, fila.ClienteID)
+	}
+	var clientes []*models.Cliente
+	if err := r.db.Where("id IN ?", ids).Find(&clientes).Error; err != nil {
+		return nil, fmt.Errorf("error obteniendo clientes del ranking: %w", err)
+	}
+	porID := make(map[uint]*models.Cliente, len(clientes))
+	for _, cliente := range clientes {
+		porID[cliente.ID] = cliente
+	}
+
+	posiciones := make([]models.PosicionRanking, 0, len(filas))
+	for i, fila := range filas {
+		posiciones = append(posiciones, models.PosicionRanking{
+			Posicion:           i + 1,
+			DiferenciaSegundos: fila.DiferenciaSegundos,
+			ClienteID:          fila.ClienteID,
+			Partidas:           fila.Partidas,
+			Cliente:            porID[fila.ClienteID],
+		})
+	}
+	return posiciones, nil
+}
+
+// ContarParticipantesEntre cuenta los clientes que jugaron en el rango [desde, hasta)
+func (r *rankingRepository) ContarParticipantesEntre(desde, hasta time.Time) (int, error) {
+	var count int64
+	if err := r.db.Model(&models.Juego{}).
+		Where("juegos.created_at >= ? AND juegos.created_at < ?", desde, hasta).
+		Where(partidaJugada, false, true).
+		Distinct("cliente_id").
+		Count(&count).Error; err != nil {
+		return 0, fmt.Errorf("error contando participantes del ranking: %w", err)
+	}
+	return int(count), nil
+}
+
+// UltimoCierre obtiene el cierre más reciente (nil si nunca se cerró)
+func (r *rankingRepository) UltimoCierre() (*models.CierreRanking, error) {
+	var cierre models.CierreRanking
+	if err := r.db.Order("hasta DESC").First(&cierre).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("error obteniendo último cierre del ranking: %w", err)
+	}
+	return &cierre, nil
+}
+
+// CrearCierre registra el cierre de un período; si ya estaba cerrado retorna
+// ErrCierreRankingDuplicado
+func (r *rankingRepository) CrearCierre(cierre *models.CierreRanking) error {
+	if err := r.db.Create(cierre).Error; err != nil {
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			return ErrCierreRankingDuplicado
+		}
+		return fmt.Errorf("error registrando cierre del ranking: %w", err)
+	}
+	return nil
+}
+
+// AsignarVoucherCierre vincula el voucher del premio con el cierre
+func (r *rankingRepository) AsignarVoucherCierre(cierreID, voucherID uint) error {
+	if err := r.db.Model(&models.CierreRanking{}).
+		Where("id = ?", cierreID).
+		Update("voucher_id", voucherID).Error; err != nil {
+		return fmt.Errorf("error asignando voucher al cierre del ranking: %w", err)
+	}
+	return nil
+}
+
+// ListarCierres obtiene los últimos cierres con el ganador y su voucher
+func (r *rankingRepository) ListarCierres(limite int) ([]*models.CierreRanking, error) {
+	var cierres []*models.CierreRanking
+	if err := r.db.Preload("Cliente").Preload("Voucher").
+		Order("hasta DESC").
+		Limit(limite).
+		Find(&cierres).Error; err != nil {
+		return nil, fmt.Errorf("error listando cierres del ranking: %w", err)
+	}
+	return cierres, nil
+}
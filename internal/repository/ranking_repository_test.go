@@ -0,0 +1,92 @@
+package repository
+
+import (
+	"database/sql/driver"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// baseDePrueba abre gorm sobre un sqlmock con el dialecto de MySQL
+func baseDePrueba(t *testing.T) (*gorm.DB, sqlmock.Sqlmock) {
+	t.Helper()
+	sqlDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("error creando sqlmock: %v", err)
+	}
+	t.Cleanup(func() { sqlDB.Close() })
+
+	db, err := gorm.Open(mysql.New(mysql.Config{Conn: sqlDB, SkipInitializeWithVersion: true}),
+		&gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatalf("error abriendo gorm: %v", err)
+	}
+	return db, mock
+}
+
+// filtroPartidaJugada el WHERE que deja afuera los intentos rechazados
+var filtroPartidaJugada = regexp.QuoteMeta("(juegos.rechazado = ? AND (juegos.voucher_id IS NOT NULL OR juegos.premio_retenido = ?))")
+
+func TestMejoresEntreSoloPartidasJugadas(t *testing.T) {
+	casos := []struct {
+		nombre   string
+		soloMuro bool
+		args     []driver.Value
+	}{
+		{"ranking completo", false, []driver.Value{sqlmock.AnyArg(), sqlmock.AnyArg(), false, true}},
+		{"solo muro", true, []driver.Value{sqlmock.AnyArg(), sqlmock.AnyArg(), false, true, true}},
+	}
+
+	for _, caso := range casos {
+		t.Run(caso.nombre, func(t *testing.T) {
+			db, mock := baseDePrueba(t)
+			desde := time.Date(2026, 10, 12, 0, 0, 0, 0, time.Local)
+
+			mock.ExpectQuery("FROM `juegos` JOIN clientes .* WHERE .*" + filtroPartidaJugada).
+				WithArgs(caso.args...).
+				WillReturnRows(sqlmock.NewRows([]string{"cliente_id", "diferencia_segundos", "partidas"}).
+					AddRow(7, 0.01, 3).
+					AddRow(3, 0.2, 1))
+			mock.ExpectQuery("SELECT \\* FROM `clientes` WHERE id IN").
+				WillReturnRows(sqlmock.NewRows([]string{"id", "nombre"}).AddRow(3, "Ana").AddRow(7, "Luis"))
+
+			posiciones, err := NewRankingRepository(db).MejoresEntre(desde, desde.AddDate(0, 0, 7), caso.soloMuro, 10)
+			if err != nil {
+				t.Fatalf("error inesperado: %v", err)
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Fatal(err)
+			}
+			if len(posiciones) != 2 || posiciones[0].ClienteID != 7 || posiciones[0].Posicion != 1 || posiciones[1].Posicion != 2 {
+				t.Fatalf("posiciones inesperadas: %+v", posiciones)
+			}
+			if posiciones[0].Cliente == nil || posiciones[0].Cliente.Nombre != "Luis" {
+				t.Errorf("el primer puesto debe traer su cliente, se obtuvo %+v", posiciones[0].Cliente)
+			}
+		})
+	}
+}
+
+func TestContarParticipantesSoloPartidasJugadas(t *testing.T) {
+	db, mock := baseDePrueba(t)
+	mock.ExpectQuery("SELECT COUNT\\(DISTINCT\\(`cliente_id`\\)\\) FROM `juegos` WHERE .*"+filtroPartidaJugada).
+		WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), false, true).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(4))
+
+	ahora := time.Now()
+	participantes, err := NewRankingRepository(db).ContarParticipantesEntre(ahora.AddDate(0, 0, -7), ahora)
+	if err != nil {
+		t.Fatalf("error inesperado: %v", err)
+	}
+	if participantes != 4 {
+		t.Errorf("participantes = %d, se esperaban 4", participantes)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
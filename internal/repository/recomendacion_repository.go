@@ -0,0 +1,78 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+
+	"gorm.io/gorm"
+
+	"CheeseHouse/internal/models"
+)
+
+// RecomendacionRepository define la interfaz para las reglas de recomendación de vouchers
+type RecomendacionRepository interface {
+	Crear(regla *models.ReglaRecomendacion) error
+	BuscarPorID(id uint) (*models.ReglaRecomendacion, error)
+	Actualizar(regla *models.ReglaRecomendacion) error
+	Eliminar(id uint) error
+	Listar() ([]*models.ReglaRecomendacion, error)
+}
+
+// recomendacionRepository implementación de RecomendacionRepository
+type recomendacionRepository struct {
+	db *gorm.DB
+}
+
+// NewRecomendacionRepository crea una nueva instancia del repositorio de recomendaciones
+func NewRecomendacionRepository(db *gorm.DB) RecomendacionRepository {
+	return &recomendacionRepository{db: db}
+}
+
+// Crear registra una nueva regla
+func (r *recomendacionRepository) Crear(regla *models.ReglaRecomendacion) error {
+	if err := r.db.Create(regla).Error; err != nil {
+		return fmt.Errorf("error creando regla de recomendación: %w", err)
+	}
+	return nil
+}
+
+// BuscarPorID busca una regla por ID
+func (r *recomendacionRepository) BuscarPorID(id uint) (*models.ReglaRecomendacion, error) {
+	var regla models.ReglaRecomendacion
+	if err := r.db.First(&regla, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("regla de recomendación con ID %d no encontrada", id)
+		}
+		return nil, fmt.Errorf("error buscando regla de recomendación: %w", err)
+	}
+	return &regla, nil
+}
+
+// Actualizar guarda los cambios de una regla
+func (r *recomendacionRepository) Actualizar(regla *models.ReglaRecomendacion) error {
+	if err := r.db.Save(regla).Error; err != nil {
+		return fmt.Errorf("error actualizando regla de recomendación: %w", err)
+	}
+	return nil
+}
+
+// Eliminar borra una regla; los vouchers emitidos conservan su alcance y mensaje
+func (r *recomendacionRepository) Eliminar(id uint) error {
+	resultado := r.db.Delete(&models.ReglaRecomendacion{}, id)
+	if resultado.Error != nil {
+		return fmt.Errorf("error eliminando regla de recomendación: %w", resultado.Error)
+	}
+	if resultado.RowsAffected == 0 {
+		return fmt.Errorf("regla de recomendación con ID %d no encontrada", id)
+	}
+	return nil
+}
+
+// Listar obtiene todas las reglas en el orden en que se evalúan
+func (r *recomendacionRepository) Listar() ([]*models.ReglaRecomendacion, error) {
+	var reglas []*models.ReglaRecomendacion
+	if err := r.db.Order("prioridad ASC, id ASC").Find(&reglas).Error; err != nil {
+		return nil, fmt.Errorf("error listando reglas de recomendación: %w", err)
+	}
+	return reglas, nil
+}
@@ -0,0 +1,101 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+
+	"CheeseHouse/internal/models"
+)
+
+// ReporteRepository define la interfaz para los reportes guardados
+type ReporteRepository interface {
+	Crear(reporte *models.ReporteGuardado) error
+	BuscarPorID(id uint) (*models.ReporteGuardado, error)
+	Actualizar(reporte *models.ReporteGuardado) error
+	Eliminar(id uint) error
+	ListarPorUsuario(usuarioID uint) ([]*models.ReporteGuardado, error)
+	ListarProgramadosPendientes(hasta time.Time) ([]*models.ReporteGuardado, error)
+	ListarProgramados() ([]*models.ReporteGuardado, error)
+}
+
+// reporteRepository implementación de ReporteRepository
+type reporteRepository struct {
+	db *gorm.DB
+}
+
+// NewReporteRepository crea una nueva instancia del repositorio de reportes
+func NewReporteRepository(db *gorm.DB) ReporteRepository {
+	return &reporteRepository{db: db}
+}
+
+// Crear guarda una nueva definición de reporte
+func (r *reporteRepository) Crear(reporte *models.ReporteGuardado) error {
+	if err := r.db.Create(reporte).Error; err != nil {
+		return fmt.Errorf("error creando reporte: %w", err)
+	}
+	return nil
+}
+
+// BuscarPorID busca un reporte guardado por su ID
+func (r *reporteRepository) BuscarPorID(id uint) (*models.ReporteGuardado, error) {
+	var reporte models.ReporteGuardado
+	if err := r.db.First(&reporte, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("reporte no encontrado")
+		}
+		return nil, fmt.Errorf("error buscando reporte: %w", err)
+	}
+	return &reporte, nil
+}
+
+// Actualizar actualiza una definición de reporte
+func (r *reporteRepository) Actualizar(reporte *models.ReporteGuardado) error {
+	if err := r.db.Save(reporte).Error; err != nil {
+		return fmt.Errorf("error actualizando reporte: %w", err)
+	}
+	return nil
+}
+
+// Eliminar elimina un reporte guardado
+func (r *reporteRepository) Eliminar(id uint) error {
+	if err := r.db.Delete(&models.ReporteGuardado{}, id).Error; err != nil {
+		return fmt.Errorf("error eliminando reporte: %w", err)
+	}
+	return nil
+}
+
+// ListarPorUsuario lista los reportes de un usuario con los favoritos primero
+func (r *reporteRepository) ListarPorUsuario(usuarioID uint) ([]*models.ReporteGuardado, error) {
+	var reportes []*models.ReporteGuardado
+	if err := r.db.Where("usuario_id = ?", usuarioID).
+		Order("favorito DESC, nombre ASC").
+		Find(&reportes).Error; err != nil {
+		return nil, fmt.Errorf("error listando reportes: %w", err)
+	}
+	return reportes, nil
+}
+
+// ListarProgramadosPendientes obtiene los reportes programados cuya próxima ejecución ya llegó
+func (r *reporteRepository) ListarProgramadosPendientes(hasta time.Time) ([]*models.ReporteGuardado, error) {
+	var reportes []*models.ReporteGuardado
+	if err := r.db.Where("programacion <> 'ninguna' AND proxima_ejecucion <= ?", hasta).
+		Order("proxima_ejecucion ASC").
+		Find(&reportes).Error; err != nil {
+		return nil, fmt.Errorf("error listando reportes programados: %w", err)
+	}
+	return reportes, nil
+}
+
+// ListarProgramados obtiene todos los reportes con programación periódica
+func (r *reporteRepository) ListarProgramados() ([]*models.ReporteGuardado, error) {
+	var reportes []*models.ReporteGuardado
+	if err := r.db.Where("programacion <> 'ninguna' AND proxima_ejecucion IS NOT NULL").
+		Order("proxima_ejecucion ASC").
+		Find(&reportes).Error; err != nil {
+		return nil, fmt.Errorf("error listando reportes programados: %w", err)
+	}
+	return reportes, nil
+}
@@ -0,0 +1,113 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+
+	"CheeseHouse/internal/models"
+)
+
+// RespuestaRapidaRepository define la interfaz para las respuestas rápidas de la bandeja
+type RespuestaRapidaRepository interface {
+	Crear(respuesta *models.RespuestaRapida) error
+	BuscarPorID(id uint) (*models.RespuestaRapida, error)
+	Actualizar(respuesta *models.RespuestaRapida) error
+	Eliminar(id uint) error
+	Listar(soloActivas bool) ([]*models.RespuestaRapida, error)
+	Contar() (int, error)
+	RegistrarUso(id uint) error
+}
+
+// respuestaRapidaRepository implementación de RespuestaRapidaRepository
+type respuestaRapidaRepository struct {
+	db *gorm.DB
+}
+
+// NewRespuestaRapidaRepository crea una nueva instancia del repositorio de respuestas rápidas
+func NewRespuestaRapidaRepository(db *gorm.DB) RespuestaRapidaRepository {
+	return &respuestaRapidaRepository{db: db}
+}
+
+// Crear registra una nueva respuesta rápida
+func (r *respuestaRapidaRepository) Crear(respuesta *models.RespuestaRapida) error {
+	if err := r.db.Create(respuesta).Error; err != nil {
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			return fmt.Errorf("ya existe una respuesta rápida con el atajo %q", respuesta.Atajo)
+		}
+		return fmt.Errorf("error creando respuesta rápida: %w", err)
+	}
+	return nil
+}
+
+// BuscarPorID busca una respuesta rápida por ID
+func (r *respuestaRapidaRepository) BuscarPorID(id uint) (*models.RespuestaRapida, error) {
+	var respuesta models.RespuestaRapida
+	if err := r.db.First(&respuesta, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("respuesta rápida con ID %d no encontrada", id)
+		}
+		return nil, fmt.Errorf("error buscando respuesta rápida: %w", err)
+	}
+	return &respuesta, nil
+}
+
+// Actualizar guarda los cambios de una respuesta rápida
+func (r *respuestaRapidaRepository) Actualizar(respuesta *models.RespuestaRapida) error {
+	if err := r.db.Save(respuesta).Error; err != nil {
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			return fmt.Errorf("ya existe una respuesta rápida con el atajo %q", respuesta.Atajo)
+		}
+		return fmt.Errorf("error actualizando respuesta rápida: %w", err)
+	}
+	return nil
+}
+
+// Eliminar borra una respuesta rápida
+func (r *respuestaRapidaRepository) Eliminar(id uint) error {
+	resultado := r.db.Delete(&models.RespuestaRapida{}, id)
+	if resultado.Error != nil {
+		return fmt.Errorf("error eliminando respuesta rápida: %w", resultado.Error)
+	}
+	if resultado.RowsAffected == 0 {
+		return fmt.Errorf("respuesta rápida con ID %d no encontrada", id)
+	}
+	return nil
+}
+
+// Listar obtiene las respuestas rápidas, las más usadas primero
+func (r *respuestaRapidaRepository) Listar(soloActivas bool) ([]*models.RespuestaRapida, error) {
+	var respuestas []*models.RespuestaRapida
+	query := r.db.Order("usos DESC, titulo ASC")
+	if soloActivas {
+		query = query.Where("activa = TRUE")
+	}
+	if err := query.Find(&respuestas).Error; err != nil {
+		return nil, fmt.Errorf("error listando respuestas rápidas: %w", err)
+	}
+	return respuestas, nil
+}
+
+// Contar cuenta las respuestas rápidas cargadas
+func (r *respuestaRapidaRepository) Contar() (int, error) {
+	var count int64
+	if err := r.db.Model(&models.RespuestaRapida{}).Count(&count).Error; err != nil {
+		return 0, fmt.Errorf("error contando respuestas rápidas: %w", err)
+	}
+	return int(count), nil
+}
+
+// RegistrarUso incrementa el contador de usos de una respuesta rápida
+func (r *respuestaRapidaRepository) RegistrarUso(id uint) error {
+	if err := r.db.Model(&models.RespuestaRapida{}).
+		Where("id = ?", id).
+		Updates(map[string]interface{}{
+			"usos":       gorm.Expr("usos + 1"),
+			"ultimo_uso": time.Now(),
+		}).Error; err != nil {
+		return fmt.Errorf("error registrando uso de respuesta rápida: %w", err)
+	}
+	return nil
+}
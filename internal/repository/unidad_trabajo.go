@@ -0,0 +1,52 @@
+package repository
+
+import "gorm.io/gorm"
+
+// Transaccion repositorios que comparten una misma transacción. Las acciones registradas
+// con AlConfirmar corren solo si la transacción se confirma
+type Transaccion struct {
+	Clientes *ClienteRepository
+	Vouchers VoucherRepository
+	Juegos   JuegoRepository
+	Outbox   OutboxRepository
+	Campanas CampanaRepository
+
+	alConfirmar []func()
+}
+
+// AlConfirmar agrega una acción a ejecutar después del commit (eventos, avisos): si la
+// transacción se revierte no se ejecuta
+func (t *Transaccion) AlConfirmar(accion func()) {
+	t.alConfirmar = append(t.alConfirmar, accion)
+}
+
+// UnidadDeTrabajo agrupa en una transacción operaciones de varios repositorios
+type UnidadDeTrabajo struct {
+	db *gorm.DB
+}
+
+// NewUnidadDeTrabajo crea una nueva unidad de trabajo
+func NewUnidadDeTrabajo(db *gorm.DB) *UnidadDeTrabajo {
+	return &UnidadDeTrabajo{db: db}
+}
+
+// Ejecutar corre fn en una transacción: si retorna error (o entra en pánico) se revierte
+// todo lo hecho con los repositorios de tx
+func (u *UnidadDeTrabajo) Ejecutar(fn func(tx *Transaccion) error) error {
+	transaccion := &Transaccion{}
+	err := u.db.Transaction(func(db *gorm.DB) error {
+		transaccion.Clientes = NewClienteRepository(db)
+		transaccion.Vouchers = NewVoucherRepository(db)
+		transaccion.Juegos = NewJuegoRepository(db)
+		transaccion.Outbox = NewOutboxRepository(db)
+		transaccion.Campanas = NewCampanaRepository(db)
+		return fn(transaccion)
+	})
+	if err != nil {
+		return err
+	}
+	for _, accion := range transaccion.alConfirmar {
+		accion()
+	}
+	return nil
+}
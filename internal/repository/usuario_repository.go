@@ -28,6 +28,8 @@ type UsuarioRepository interface {
 	BuscarRolPorNombre(nombre string) (*models.Rol, error)
 	ListarRoles() ([]*models.Rol, error)
 	CrearRol(rol *models.Rol) error
+	ActualizarRol(rol *models.Rol) error
+	EliminarRol(id uint) error
 
 	// Contadores y estadísticas
 	ContarUsuarios() (int, error)
@@ -170,6 +172,22 @@ func (r *usuarioRepository) CrearRol(rol *models.Rol) error {
 	return nil
 }
 
+// ActualizarRol guarda el nombre y los permisos de un rol
+func (r *usuarioRepository) ActualizarRol(rol *models.Rol) error {
+	if err := r.db.Save(rol).Error; err != nil {
+		return fmt.Errorf("error actualizando rol: %w", err)
+	}
+	return nil
+}
+
+// EliminarRol elimina un rol
+func (r *usuarioRepository) EliminarRol(id uint) error {
+	if err := r.db.Delete(&models.Rol{}, id).Error; err != nil {
+		return fmt.Errorf("error eliminando rol: %w", err)
+	}
+	return nil
+}
+
 // ContarUsuarios cuenta el total de usuarios
 func (r *usuarioRepository) ContarUsuarios() (int, error) {
 	var count int64
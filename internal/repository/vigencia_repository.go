@@ -0,0 +1,78 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+
+	"gorm.io/gorm"
+
+	"CheeseHouse/internal/models"
+)
+
+// VigenciaRepository define la interfaz para las reglas de vigencia de vouchers
+type VigenciaRepository interface {
+	Crear(regla *models.ReglaVigencia) error
+	BuscarPorID(id uint) (*models.ReglaVigencia, error)
+	Actualizar(regla *models.ReglaVigencia) error
+	Eliminar(id uint) error
+	Listar() ([]*models.ReglaVigencia, error)
+}
+
+// vigenciaRepository implementación de VigenciaRepository
+type vigenciaRepository struct {
+	db *gorm.DB
+}
+
+// NewVigenciaRepository crea una nueva instancia del repositorio de vigencias
+func NewVigenciaRepository(db *gorm.DB) VigenciaRepository {
+	return &vigenciaRepository{db: db}
+}
+
+// Crear registra una nueva regla
+func (r *vigenciaRepository) Crear(regla *models.ReglaVigencia) error {
+	if err := r.db.Create(regla).Error; err != nil {
+		return fmt.Errorf("error creando regla de vigencia: %w", err)
+	}
+	return nil
+}
+
+// BuscarPorID busca una regla por ID
+func (r *vigenciaRepository) BuscarPorID(id uint) (*models.ReglaVigencia, error) {
+	var regla models.ReglaVigencia
+	if err := r.db.First(&regla, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("regla de vigencia con ID %d no encontrada", id)
+		}
+		return nil, fmt.Errorf("error buscando regla de vigencia: %w", err)
+	}
+	return &regla, nil
+}
+
+// Actualizar guarda los cambios de una regla
+func (r *vigenciaRepository) Actualizar(regla *models.ReglaVigencia) error {
+	if err := r.db.Save(regla).Error; err != nil {
+		return fmt.Errorf("error actualizando regla de vigencia: %w", err)
+	}
+	return nil
+}
+
+// Eliminar borra una regla; los vouchers emitidos conservan su vencimiento
+func (r *vigenciaRepository) Eliminar(id uint) error {
+	resultado := r.db.Delete(&models.ReglaVigencia{}, id)
+	if resultado.Error != nil {
+		return fmt.Errorf("error eliminando regla de vigencia: %w", resultado.Error)
+	}
+	if resultado.RowsAffected == 0 {
+		return fmt.Errorf("regla de vigencia con ID %d no encontrada", id)
+	}
+	return nil
+}
+
+// Listar obtiene todas las reglas por tipo y día de la semana
+func (r *vigenciaRepository) Listar() ([]*models.ReglaVigencia, error) {
+	var reglas []*models.ReglaVigencia
+	if err := r.db.Order("tipo ASC, dia_semana ASC, id ASC").Find(&reglas).Error; err != nil {
+		return nil, fmt.Errorf("error listando reglas de vigencia: %w", err)
+	}
+	return reglas, nil
+}
@@ -5,6 +5,7 @@ import (
 	"time"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 
 	"CheeseHouse/internal/models"
 )
@@ -13,25 +14,41 @@ import (
 type VoucherRepository interface {
 	// CRUD básico
 	Crear(voucher *models.Voucher) error
+	CrearEnLote(vouchers []*models.Voucher, tamanoLote int) error
 	BuscarPorID(id uint) (*models.Voucher, error)
 	BuscarPorCodigo(codigo string) (*models.Voucher, error)
+	BuscarParaCanje(codigo string) (*models.Voucher, error)
 	Actualizar(voucher *models.Voucher) error
+	Canjear(voucher *models.Voucher) (bool, error)
 	Eliminar(id uint) error
-	ListarTodos() ([]*models.Voucher, error)
-	ListarConFiltros(filtros map[string]interface{}) ([]*models.Voucher, error)
+	ListarTodos(paginacion models.Paginacion) ([]*models.Voucher, int64, error)
+	ListarConFiltros(filtros map[string]interface{}, paginacion models.Paginacion) ([]*models.Voucher, int64, error)
+	RecorrerConFiltros(filtros map[string]interface{}, lote int, fn func([]*models.Voucher) error) error
 
 	// Consultas específicas de vouchers
 	GetVouchersPorCliente(clienteID uint) ([]*models.Voucher, error)
+	GetGanadoresMuro(desde time.Time, limite int) ([]*models.Voucher, error)
 	GetVouchersActivos() ([]*models.Voucher, error)
 	GetVouchersVencidos(dias int) ([]*models.Voucher, error)
 	GetVouchersPorVencer(dias int) ([]*models.Voucher, error)
 	GetVouchersCanjeadosPorPeriodo(inicio, fin time.Time) ([]*models.Voucher, error)
+	GetVencimientosEntre(desde, hasta time.Time) ([]time.Time, error)
 
 	// Contadores y estadísticas
 	ContarVouchersActivos() (int, error)
 	ContarVouchersVencidos() (int, error)
 	ContarVouchersCanjeados() (int, error)
+	GetTasaCanjePorTipo(tipo string) (float64, int, error)
+	ContarEmitidosEntre(inicio, fin time.Time) (int, error)
+	ContarCanjeadosEntre(inicio, fin time.Time) (int, error)
+	GetCanjeDeEmitidosEntre(inicio, fin time.Time) (int, int, error)
+	GetClustersPorOrigen(criterio string, desde time.Time, minClientes int) ([]*models.ClusterHuella, error)
 	GetEstadisticasPorPeriodo(dias int) ([]*models.EstadisticasPorPeriodo, error)
+	GetEstadisticasPorWidget(desde time.Time) (map[uint]models.EstadisticasWidget, error)
+	GetEstadisticasPorMesa(desde time.Time) (map[uint]models.EstadisticasMesa, error)
+	GetEstadisticasPorArea(desde time.Time) ([]models.EstadisticasArea, error)
+	GetEstadisticasPorRegla(desde time.Time) (map[uint]models.EstadisticasRegla, error)
+	GetRendimientoPorVigencia(desde time.Time) ([]models.RendimientoVouchers, error)
 
 	// Operaciones de mantenimiento
 	MarcarVouchersVencidos() (int, error)
@@ -56,6 +73,18 @@ func (r *voucherRepository) Crear(voucher *models.Voucher) error {
 	return nil
 }
 
+// CrearEnLote genera muchos vouchers (ej. una campaña) con INSERTs de tamanoLote filas.
+// Corre en una transacción: un código duplicado descarta todo el alta
+func (r *voucherRepository) CrearEnLote(vouchers []*models.Voucher, tamano int) error {
+	if len(vouchers) == 0 {
+		return nil
+	}
+	if err := r.db.CreateInBatches(vouchers, tamanoLote(tamano)).Error; err != nil {
+		return fmt.Errorf("error creando %d vouchers: %w", len(vouchers), err)
+	}
+	return nil
+}
+
 // BuscarPorID busca un voucher por su ID
 func (r *voucherRepository) BuscarPorID(id uint) (*models.Voucher, error) {
 	var voucher models.Voucher
@@ -71,7 +100,7 @@ func (r *voucherRepository) BuscarPorID(id uint) (*models.Voucher, error) {
 // BuscarPorCodigo busca un voucher por su código único
 func (r *voucherRepository) BuscarPorCodigo(codigo string) (*models.Voucher, error) {
 	var voucher models.Voucher
-	if err := r.db.Preload("Cliente").Preload("UsuarioQueCanje").
+	if err := r.db.Preload("Cliente").Preload("UsuarioQueCanje").Preload("Aprobador").Preload("Mesa").
 		Where("codigo = ?", codigo).First(&voucher).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
 			return nil, fmt.Errorf("voucher con código %s no encontrado", codigo)
@@ -89,6 +118,40 @@ func (r *voucherRepository) Actualizar(voucher *models.Voucher) error {
 	return nil
 }
 
+// BuscarParaCanje busca el voucher bloqueando la fila (SELECT ... FOR UPDATE) hasta el fin
+// de la transacción: otro canje del mismo código espera y después lo ve usado. Solo tiene
+// sentido con el repositorio de una UnidadDeTrabajo
+func (r *voucherRepository) BuscarParaCanje(codigo string) (*models.Voucher, error) {
+	var voucher models.Voucher
+	if err := r.db.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("codigo = ?", codigo).First(&voucher).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, fmt.Errorf("voucher con código %s no encontrado", codigo)
+		}
+		return nil, fmt.Errorf("error buscando voucher por código: %w", err)
+	}
+	return &voucher, nil
+}
+
+// Canjear marca el voucher como usado con los datos del canje solo si todavía no lo
+// estaba, en una sola sentencia: de dos canjes simultáneos del mismo código gana uno.
+// Retorna false si otro canje se adelantó
+func (r *voucherRepository) Canjear(voucher *models.Voucher) (bool, error) {
+	resultado := r.db.Model(&models.Voucher{}).
+		Where("id = ? AND usado = ?", voucher.ID, false).
+		Updates(map[string]interface{}{
+			"usado":          true,
+			"fecha_uso":      voucher.FechaUso,
+			"usuario_canje":  voucher.UsuarioCanje,
+			"sucursal_canje": voucher.SucursalCanje,
+			"aprobado_por":   voucher.AprobadoPor,
+		})
+	if resultado.Error != nil {
+		return false, fmt.Errorf("error canjeando voucher: %w", resultado.Error)
+	}
+	return resultado.RowsAffected == 1, nil
+}
+
 // Eliminar elimina un voucher (soft delete)
 func (r *voucherRepository) Eliminar(id uint) error {
 	if err := r.db.Delete(&models.Voucher{}, id).Error; err != nil {
@@ -97,20 +160,53 @@ func (r *voucherRepository) Eliminar(id uint) error {
 	return nil
 }
 
-// ListarTodos obtiene todos los vouchers
-func (r *voucherRepository) ListarTodos() ([]*models.Voucher, error) {
+// ListarTodos obtiene una página de todos los vouchers junto con el total
+func (r *voucherRepository) ListarTodos(paginacion models.Paginacion) ([]*models.Voucher, int64, error) {
+	query, total, err := paginar(r.db, &models.Voucher{}, paginacion, ordenVouchers, "id ASC")
+	if err != nil {
+		return nil, 0, err
+	}
+
 	var vouchers []*models.Voucher
-	if err := r.db.Preload("Cliente").Preload("UsuarioQueCanje").Find(&vouchers).Error; err != nil {
-		return nil, fmt.Errorf("error listando vouchers: %w", err)
+	if err := query.Preload("Cliente").Preload("UsuarioQueCanje").Find(&vouchers).Error; err != nil {
+		return nil, 0, fmt.Errorf("error listando vouchers: %w", err)
 	}
-	return vouchers, nil
+	return vouchers, total, nil
 }
 
-// ListarConFiltros obtiene vouchers aplicando filtros
-func (r *voucherRepository) ListarConFiltros(filtros map[string]interface{}) ([]*models.Voucher, error) {
-	query := r.db.Preload("Cliente").Preload("UsuarioQueCanje")
+// ListarConFiltros obtiene una página de vouchers aplicando filtros, junto con el total
+// de vouchers que cumplen los filtros
+func (r *voucherRepository) ListarConFiltros(filtros map[string]interface{}, paginacion models.Paginacion) ([]*models.Voucher, int64, error) {
+	query, total, err := paginar(r.filtrar(filtros), &models.Voucher{}, paginacion, ordenVouchers, "created_at DESC, id DESC")
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var vouchers []*models.Voucher
+	if err := query.Preload("Cliente").Preload("UsuarioQueCanje").Preload("Aprobador").Find(&vouchers).Error; err != nil {
+		return nil, 0, fmt.Errorf("error listando vouchers con filtros: %w", err)
+	}
 
-	// Aplicar filtros
+	return vouchers, total, nil
+}
+
+// RecorrerConFiltros recorre en lotes (por id) todos los vouchers que cumplen los filtros
+// del listado, con su cliente y el empleado que lo canjeó. Si fn retorna error se corta
+func (r *voucherRepository) RecorrerConFiltros(filtros map[string]interface{}, lote int, fn func([]*models.Voucher) error) error {
+	var vouchers []*models.Voucher
+	resultado := r.filtrar(filtros).Preload("Cliente").Preload("UsuarioQueCanje").
+		FindInBatches(&vouchers, lote, func(*gorm.DB, int) error {
+			return fn(vouchers)
+		})
+	if resultado.Error != nil {
+		return fmt.Errorf("error recorriendo vouchers: %w", resultado.Error)
+	}
+	return nil
+}
+
+// filtrar aplica los filtros del listado de vouchers
+func (r *voucherRepository) filtrar(filtros map[string]interface{}) *gorm.DB {
+	query := r.db
 	if tipo, ok := filtros["tipo"]; ok {
 		query = query.Where("tipo = ?", tipo)
 	}
@@ -136,32 +232,14 @@ func (r *voucherRepository) ListarConFiltros(filtros map[string]interface{}) ([]
 	}
 
 	if vencido, ok := filtros["vencido"]; ok && vencido.(bool) {
-		query = query.Where("fecha_vencimiento < CURDATE()")
+		query = query.Where("fecha_vencimiento < ?", hoy())
 	}
 
 	if porVencer, ok := filtros["por_vencer_dias"]; ok {
 		dias := porVencer.(int)
-		query = query.Where("fecha_vencimiento BETWEEN CURDATE() AND DATE_ADD(CURDATE(), INTERVAL ? DAY)", dias)
+		query = query.Where("fecha_vencimiento BETWEEN ? AND ?", hoy(), hoy().AddDate(0, 0, dias))
 	}
-
-	// Ordenamiento
-	orderBy := "created_at DESC"
-	if order, ok := filtros["order_by"]; ok {
-		orderBy = order.(string)
-	}
-	query = query.Order(orderBy)
-
-	// Límite
-	if limit, ok := filtros["limit"]; ok {
-		query = query.Limit(limit.(int))
-	}
-
-	var vouchers []*models.Voucher
-	if err := query.Find(&vouchers).Error; err != nil {
-		return nil, fmt.Errorf("error listando vouchers con filtros: %w", err)
-	}
-
-	return vouchers, nil
+	return query
 }
 
 // GetVouchersPorCliente obtiene todos los vouchers de un cliente específico
@@ -175,11 +253,30 @@ func (r *voucherRepository) GetVouchersPorCliente(clienteID uint) ([]*models.Vou
 	return vouchers, nil
 }
 
+// GetGanadoresMuro obtiene las partidas ganadas más recientes de clientes activos que
+// aceptaron aparecer en el muro de ganadores
+func (r *voucherRepository) GetGanadoresMuro(desde time.Time, limite int) ([]*models.Voucher, error) {
+	consintieron := r.db.Model(&models.Cliente{}).
+		Select("id").
+		Where("consentimiento_muro = ? AND estado = 'activo'", true)
+
+	var vouchers []*models.Voucher
+	if err := r.db.Preload("Cliente").
+		Where("tipo = 'juego_ganado' AND diferencia_segundos IS NOT NULL AND created_at >= ?", desde).
+		Where("cliente_id IN (?)", consintieron).
+		Order("created_at DESC").
+		Limit(limite).
+		Find(&vouchers).Error; err != nil {
+		return nil, fmt.Errorf("error obteniendo ganadores del muro: %w", err)
+	}
+	return vouchers, nil
+}
+
 // GetVouchersActivos obtiene vouchers válidos y no usados
 func (r *voucherRepository) GetVouchersActivos() ([]*models.Voucher, error) {
 	var vouchers []*models.Voucher
 	if err := r.db.Preload("Cliente").
-		Where("usado = FALSE AND fecha_vencimiento >= CURDATE()").
+		Where("usado = FALSE AND fecha_vencimiento >= ?", hoy()).
 		Order("fecha_vencimiento ASC").
 		Find(&vouchers).Error; err != nil {
 		return nil, fmt.Errorf("error obteniendo vouchers activos: %w", err)
@@ -191,7 +288,7 @@ func (r *voucherRepository) GetVouchersActivos() ([]*models.Voucher, error) {
 func (r *voucherRepository) GetVouchersVencidos(dias int) ([]*models.Voucher, error) {
 	var vouchers []*models.Voucher
 	if err := r.db.Preload("Cliente").
-		Where("fecha_vencimiento < CURDATE() AND fecha_vencimiento >= DATE_SUB(CURDATE(), INTERVAL ? DAY)", dias).
+		Where("fecha_vencimiento < ? AND fecha_vencimiento >= ?", hoy(), hoy().AddDate(0, 0, -dias)).
 		Order("fecha_vencimiento DESC").
 		Find(&vouchers).Error; err != nil {
 		return nil, fmt.Errorf("error obteniendo vouchers vencidos: %w", err)
@@ -203,7 +300,7 @@ func (r *voucherRepository) GetVouchersVencidos(dias int) ([]*models.Voucher, er
 func (r *voucherRepository) GetVouchersPorVencer(dias int) ([]*models.Voucher, error) {
 	var vouchers []*models.Voucher
 	if err := r.db.Preload("Cliente").
-		Where("usado = FALSE AND fecha_vencimiento BETWEEN CURDATE() AND DATE_ADD(CURDATE(), INTERVAL ? DAY)", dias).
+		Where("usado = FALSE AND fecha_vencimiento BETWEEN ? AND ?", hoy(), hoy().AddDate(0, 0, dias)).
 		Order("fecha_vencimiento ASC").
 		Find(&vouchers).Error; err != nil {
 		return nil, fmt.Errorf("error obteniendo vouchers por vencer: %w", err)
@@ -227,7 +324,7 @@ func (r *voucherRepository) GetVouchersCanjeadosPorPeriodo(inicio, fin time.Time
 func (r *voucherRepository) ContarVouchersActivos() (int, error) {
 	var count int64
 	if err := r.db.Model(&models.Voucher{}).
-		Where("usado = FALSE AND fecha_vencimiento >= CURDATE()").
+		Where("usado = FALSE AND fecha_vencimiento >= ?", hoy()).
 		Count(&count).Error; err != nil {
 		return 0, fmt.Errorf("error contando vouchers activos: %w", err)
 	}
@@ -238,7 +335,7 @@ func (r *voucherRepository) ContarVouchersActivos() (int, error) {
 func (r *voucherRepository) ContarVouchersVencidos() (int, error) {
 	var count int64
 	if err := r.db.Model(&models.Voucher{}).
-		Where("fecha_vencimiento < CURDATE()").
+		Where("fecha_vencimiento < ?", hoy()).
 		Count(&count).Error; err != nil {
 		return 0, fmt.Errorf("error contando vouchers vencidos: %w", err)
 	}
@@ -256,46 +353,261 @@ func (r *voucherRepository) ContarVouchersCanjeados() (int, error) {
 	return int(count), nil
 }
 
+// GetTasaCanjePorTipo calcula la tasa histórica de canje de un tipo de voucher.
+// Solo considera vouchers ya resueltos (canjeados o vencidos); retorna también el tamaño de la muestra
+func (r *voucherRepository) GetTasaCanjePorTipo(tipo string) (float64, int, error) {
+	var stats struct {
+		Total     int
+		Canjeados int
+	}
+	if err := r.db.Model(&models.Voucher{}).
+		Select("COUNT(*) AS total, COUNT(CASE WHEN usado = TRUE THEN 1 END) AS canjeados").
+		Where("tipo = ? AND (usado = TRUE OR fecha_vencimiento < ?)", tipo, time.Now()).
+		Scan(&stats).Error; err != nil {
+		return 0, 0, fmt.Errorf("error calculando tasa de canje: %w", err)
+	}
+
+	if stats.Total == 0 {
+		return 0, 0, nil
+	}
+	return float64(stats.Canjeados) / float64(stats.Total), stats.Total, nil
+}
+
+// GetVencimientosEntre obtiene las fechas de vencimiento de los vouchers sin usar que
+// vencen en el rango [desde, hasta)
+func (r *voucherRepository) GetVencimientosEntre(desde, hasta time.Time) ([]time.Time, error) {
+	var fechas []time.Time
+	if err := r.db.Model(&models.Voucher{}).
+		Where("usado = FALSE AND fecha_vencimiento >= ? AND fecha_vencimiento < ?", desde, hasta).
+		Pluck("fecha_vencimiento", &fechas).Error; err != nil {
+		return nil, fmt.Errorf("error obteniendo vencimientos de vouchers: %w", err)
+	}
+	return fechas, nil
+}
+
+// ContarEmitidosEntre cuenta los vouchers de juego emitidos en el intervalo [inicio, fin)
+func (r *voucherRepository) ContarEmitidosEntre(inicio, fin time.Time) (int, error) {
+	var count int64
+	if err := r.db.Model(&models.Voucher{}).
+		Where("tipo IN ('juego_ganado', 'juego_perdido') AND created_at >= ? AND created_at < ?", inicio, fin).
+		Count(&count).Error; err != nil {
+		return 0, fmt.Errorf("error contando vouchers emitidos: %w", err)
+	}
+	return int(count), nil
+}
+
+// ContarCanjeadosEntre cuenta los vouchers de cualquier tipo canjeados en el rango [inicio, fin)
+func (r *voucherRepository) ContarCanjeadosEntre(inicio, fin time.Time) (int, error) {
+	var count int64
+	if err := r.db.Model(&models.Voucher{}).
+		Where("usado = TRUE AND fecha_uso >= ? AND fecha_uso < ?", inicio, fin).
+		Count(&count).Error; err != nil {
+		return 0, fmt.Errorf("error contando vouchers canjeados: %w", err)
+	}
+	return int(count), nil
+}
+
+// GetCanjeDeEmitidosEntre cuenta los vouchers emitidos en el rango [inicio, fin) y
+// cuántos de ellos ya se canjearon
+func (r *voucherRepository) GetCanjeDeEmitidosEntre(inicio, fin time.Time) (int, int, error) {
+	var fila struct {
+		Emitidos  int
+		Canjeados int
+	}
+	if err := r.db.Model(&models.Voucher{}).
+		Select("COUNT(*) AS emitidos, COUNT(CASE WHEN usado = TRUE THEN 1 END) AS canjeados").
+		Where("created_at >= ? AND created_at < ?", inicio, fin).
+		Scan(&fila).Error; err != nil {
+		return 0, 0, fmt.Errorf("error obteniendo canje de vouchers emitidos: %w", err)
+	}
+	return fila.Emitidos, fila.Canjeados, nil
+}
+
+// GetClustersPorOrigen agrupa las partidas por huella de dispositivo o IP y
+// retorna los orígenes usados por al menos minClientes teléfonos distintos
+func (r *voucherRepository) GetClustersPorOrigen(criterio string, desde time.Time, minClientes int) ([]*models.ClusterHuella, error) {
+	columnas := map[string]string{
+		"huella": "v.huella_dispositivo",
+		"ip":     "v.ip_origen",
+	}
+	columna, ok := columnas[criterio]
+	if !ok {
+		return nil, fmt.Errorf("criterio de agrupación inválido: %s", criterio)
+	}
+
+	query := fmt.Sprintf(`
+		SELECT
+			? as criterio,
+			%[1]s as valor,
+			COUNT(DISTINCT v.cliente_id) as clientes,
+			%[2]s as telefonos,
+			COUNT(*) as partidas,
+			COUNT(CASE WHEN v.ganado = TRUE THEN 1 END) as ganadas,
+			COUNT(CASE WHEN v.usado = TRUE THEN 1 END) as canjeados,
+			MIN(v.created_at) as primera_partida,
+			MAX(v.created_at) as ultima_partida
+		FROM vouchers v
+		JOIN clientes c ON c.id = v.cliente_id
+		WHERE v.tipo IN ('juego_ganado', 'juego_perdido')
+			AND %[1]s <> ''
+			AND v.created_at >= ?
+		GROUP BY %[1]s
+		HAVING COUNT(DISTINCT v.cliente_id) >= ?
+		ORDER BY clientes DESC, partidas DESC
+	`, columna, unirDistintos(r.db, "c.telefono"))
+
+	var clusters []*models.ClusterHuella
+	if err := r.db.Raw(query, criterio, desde, minClientes).Scan(&clusters).Error; err != nil {
+		return nil, fmt.Errorf("error agrupando partidas por %s: %w", criterio, err)
+	}
+	return clusters, nil
+}
+
 // GetEstadisticasPorPeriodo obtiene estadísticas de juegos agrupadas por día
 func (r *voucherRepository) GetEstadisticasPorPeriodo(dias int) ([]*models.EstadisticasPorPeriodo, error) {
 	query := `
 		SELECT 
-			DATE(fecha_emision) as fecha,
+			CAST(fecha_emision AS DATE) as fecha,
 			COUNT(CASE WHEN ganado = TRUE THEN 1 END) as victorias_dia,
 			COUNT(CASE WHEN ganado = FALSE THEN 1 END) as derrotas_dia,
 			COUNT(*) as total_juegos_dia,
 			CASE 
 				WHEN COUNT(*) > 0 THEN
-					ROUND((COUNT(CASE WHEN ganado = TRUE THEN 1 END) / COUNT(*)) * 100, 2)
+					ROUND(COUNT(CASE WHEN ganado = TRUE THEN 1 END) * 100.0 / COUNT(*), 2)
 				ELSE 0
 			END as porcentaje_victorias_dia
 		FROM vouchers
 		WHERE tipo IN ('juego_ganado', 'juego_perdido')
-			AND fecha_emision >= DATE_SUB(CURDATE(), INTERVAL ? DAY)
-		GROUP BY DATE(fecha_emision)
+			AND fecha_emision >= ?
+		GROUP BY CAST(fecha_emision AS DATE)
 		ORDER BY fecha DESC
 	`
 
 	var estadisticas []*models.EstadisticasPorPeriodo
-	if err := r.db.Raw(query, dias).Scan(&estadisticas).Error; err != nil {
+	if err := r.db.Raw(query, hoy().AddDate(0, 0, -dias)).Scan(&estadisticas).Error; err != nil {
 		return nil, fmt.Errorf("error obteniendo estadísticas por período: %w", err)
 	}
 
 	return estadisticas, nil
 }
 
+// GetEstadisticasPorWidget agrupa las partidas jugadas desde widgets embebidos por widget
+func (r *voucherRepository) GetEstadisticasPorWidget(desde time.Time) (map[uint]models.EstadisticasWidget, error) {
+	var filas []models.EstadisticasWidget
+	if err := r.db.Model(&models.Voucher{}).
+		Select(`widget_id,
+			COUNT(*) AS partidas,
+			COUNT(CASE WHEN tipo = 'juego_ganado' THEN 1 END) AS ganadas,
+			COUNT(DISTINCT cliente_id) AS jugadores,
+			COUNT(CASE WHEN usado = TRUE THEN 1 END) AS canjeados`).
+		Where("widget_id IS NOT NULL AND tipo IN ('juego_ganado', 'juego_perdido') AND created_at >= ?", desde).
+		Group("widget_id").
+		Scan(&filas).Error; err != nil {
+		return nil, fmt.Errorf("error obteniendo estadísticas por widget: %w", err)
+	}
+
+	porWidget := make(map[uint]models.EstadisticasWidget, len(filas))
+	for _, fila := range filas {
+		porWidget[fila.WidgetID] = fila
+	}
+	return porWidget, nil
+}
+
+// GetEstadisticasPorMesa agrupa las partidas jugadas desde el QR de cada mesa
+func (r *voucherRepository) GetEstadisticasPorMesa(desde time.Time) (map[uint]models.EstadisticasMesa, error) {
+	var filas []models.EstadisticasMesa
+	if err := r.db.Model(&models.Voucher{}).
+		Select(`mesa_id,
+			COUNT(*) AS partidas,
+			COUNT(CASE WHEN tipo = 'juego_ganado' THEN 1 END) AS ganadas,
+			COUNT(DISTINCT cliente_id) AS jugadores,
+			COUNT(CASE WHEN usado = TRUE THEN 1 END) AS canjeados`).
+		Where("mesa_id IS NOT NULL AND tipo IN ('juego_ganado', 'juego_perdido') AND created_at >= ?", desde).
+		Group("mesa_id").
+		Scan(&filas).Error; err != nil {
+		return nil, fmt.Errorf("error obteniendo estadísticas por mesa: %w", err)
+	}
+
+	porMesa := make(map[uint]models.EstadisticasMesa, len(filas))
+	for _, fila := range filas {
+		porMesa[fila.MesaID] = fila
+	}
+	return porMesa, nil
+}
+
+// GetEstadisticasPorArea agrupa las partidas de las mesas por área, de la más jugada a
+// la menos. Los jugadores se cuentan una vez por área aunque hayan jugado en varias mesas
+func (r *voucherRepository) GetEstadisticasPorArea(desde time.Time) ([]models.EstadisticasArea, error) {
+	var areas []models.EstadisticasArea
+	if err := r.db.Table("vouchers").
+		Select(`mesas.area AS area,
+			COUNT(DISTINCT vouchers.mesa_id) AS mesas,
+			COUNT(*) AS partidas,
+			COUNT(CASE WHEN vouchers.tipo = 'juego_ganado' THEN 1 END) AS ganadas,
+			COUNT(DISTINCT vouchers.cliente_id) AS jugadores,
+			COUNT(CASE WHEN vouchers.usado = TRUE THEN 1 END) AS canjeados`).
+		Joins("JOIN mesas ON mesas.id = vouchers.mesa_id").
+		Where("vouchers.tipo IN ('juego_ganado', 'juego_perdido') AND vouchers.created_at >= ?", desde).
+		Group("mesas.area").
+		Order("partidas DESC").
+		Scan(&areas).Error; err != nil {
+		return nil, fmt.Errorf("error obteniendo estadísticas por área: %w", err)
+	}
+	return areas, nil
+}
+
+// GetEstadisticasPorRegla cuenta los vouchers emitidos y canjeados con cada regla de
+// recomendación
+func (r *voucherRepository) GetEstadisticasPorRegla(desde time.Time) (map[uint]models.EstadisticasRegla, error) {
+	var filas []models.EstadisticasRegla
+	if err := r.db.Model(&models.Voucher{}).
+		Select(`regla_id,
+			COUNT(*) AS emitidos,
+			COUNT(CASE WHEN usado = TRUE THEN 1 END) AS canjeados`).
+		Where("regla_id IS NOT NULL AND created_at >= ?", desde).
+		Group("regla_id").
+		Scan(&filas).Error; err != nil {
+		return nil, fmt.Errorf("error obteniendo estadísticas por regla: %w", err)
+	}
+
+	porRegla := make(map[uint]models.EstadisticasRegla, len(filas))
+	for _, fila := range filas {
+		porRegla[fila.ReglaID] = fila
+	}
+	return porRegla, nil
+}
+
+// GetRendimientoPorVigencia compara los vouchers de partida flash con los estándar:
+// emitidos, canjeados, vencidos sin usar y minutos promedio hasta el canje
+func (r *voucherRepository) GetRendimientoPorVigencia(desde time.Time) ([]models.RendimientoVouchers, error) {
+	var filas []models.RendimientoVouchers
+	if err := r.db.Model(&models.Voucher{}).
+		Select(fmt.Sprintf(`flash,
+			COUNT(*) AS emitidos,
+			COUNT(CASE WHEN usado = TRUE THEN 1 END) AS canjeados,
+			COUNT(CASE WHEN usado = FALSE AND fecha_vencimiento < NOW() THEN 1 END) AS vencidos,
+			COALESCE(AVG(CASE WHEN usado = TRUE THEN %s END), 0) AS minutos_hasta_canje`, minutosEntre(r.db, "fecha_emision", "fecha_uso"))).
+		Where("tipo IN ? AND created_at >= ?", []string{"juego_ganado", "juego_perdido"}, desde).
+		Group("flash").
+		Order("flash DESC").
+		Scan(&filas).Error; err != nil {
+		return nil, fmt.Errorf("error obteniendo rendimiento de vouchers flash: %w", err)
+	}
+	return filas, nil
+}
+
 // MarcarVouchersVencidos marca vouchers vencidos (operación de mantenimiento)
 func (r *voucherRepository) MarcarVouchersVencidos() (int, error) {
 	// Esta operación es más para logging/auditoría ya que MySQL maneja las fechas automáticamente
 	var count int64
 	if err := r.db.Model(&models.Voucher{}).
-		Where("fecha_vencimiento < CURDATE() AND usado = FALSE").
+		Where("fecha_vencimiento < ? AND usado = FALSE", hoy()).
 		Count(&count).Error; err != nil {
 		return 0, fmt.Errorf("error contando vouchers a marcar como vencidos: %w", err)
 	}
 
 	// Opcional: agregar campo "vencido" si queremos marcarlo explícitamente
-	// UPDATE vouchers SET vencido = TRUE WHERE fecha_vencimiento < CURDATE() AND usado = FALSE
+	// UPDATE vouchers SET vencido = TRUE WHERE fecha_vencimiento < hoy AND usado = FALSE
 
 	return int(count), nil
 }
@@ -303,7 +615,7 @@ func (r *voucherRepository) MarcarVouchersVencidos() (int, error) {
 // LimpiarVouchersAntiguos elimina vouchers muy antiguos (mantenimiento)
 func (r *voucherRepository) LimpiarVouchersAntiguos(dias int) (int, error) {
 	// Eliminar vouchers vencidos hace más de X días (para limpiar BD)
-	result := r.db.Where("fecha_vencimiento < DATE_SUB(CURDATE(), INTERVAL ? DAY)", dias).
+	result := r.db.Where("fecha_vencimiento < ?", hoy().AddDate(0, 0, -dias)).
 		Delete(&models.Voucher{})
 
 	if result.Error != nil {
@@ -338,8 +650,8 @@ func (r *voucherRepository) GetEstadisticasVouchersPorCliente() ([]map[string]in
 			c.telefono,
 			COUNT(v.id) as total_vouchers,
 			COUNT(CASE WHEN v.usado = TRUE THEN 1 END) as vouchers_usados,
-			COUNT(CASE WHEN v.usado = FALSE AND v.fecha_vencimiento >= CURDATE() THEN 1 END) as vouchers_activos,
-			COUNT(CASE WHEN v.fecha_vencimiento < CURDATE() AND v.usado = FALSE THEN 1 END) as vouchers_vencidos,
+			COUNT(CASE WHEN v.usado = FALSE AND v.fecha_vencimiento >= ? THEN 1 END) as vouchers_activos,
+			COUNT(CASE WHEN v.fecha_vencimiento < ? AND v.usado = FALSE THEN 1 END) as vouchers_vencidos,
 			ROUND(AVG(v.descuento), 2) as promedio_descuento,
 			MAX(v.created_at) as ultimo_voucher
 		FROM clientes c
@@ -351,7 +663,7 @@ func (r *voucherRepository) GetEstadisticasVouchersPorCliente() ([]map[string]in
 	`
 
 	var resultados []map[string]interface{}
-	if err := r.db.Raw(query).Scan(&resultados).Error; err != nil {
+	if err := r.db.Raw(query, hoy(), hoy()).Scan(&resultados).Error; err != nil {
 		return nil, fmt.Errorf("error obteniendo estadísticas de vouchers por cliente: %w", err)
 	}
 
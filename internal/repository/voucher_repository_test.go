@@ -0,0 +1,66 @@
+package repository
+
+import (
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+
+	"CheeseHouse/internal/models"
+)
+
+func TestBuscarParaCanjeBloqueaLaFila(t *testing.T) {
+	db, mock := baseDePrueba(t)
+
+	mock.ExpectQuery("SELECT \\* FROM `vouchers` WHERE codigo = \\? .* FOR UPDATE").
+		WithArgs("CHPRUEBA1").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "codigo", "usado"}).AddRow(1, "CHPRUEBA1", false))
+
+	voucher, err := NewVoucherRepository(db).BuscarParaCanje("CHPRUEBA1")
+	if err != nil {
+		t.Fatalf("error buscando el voucher: %v", err)
+	}
+	if voucher.ID != 1 || voucher.Usado {
+		t.Errorf("voucher = %+v", voucher)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestCanjearSoloSiNoEstabaUsado(t *testing.T) {
+	casos := []struct {
+		nombre    string
+		afectadas int64
+		canjeado  bool
+	}{
+		{"primer canje", 1, true},
+		{"otro canje se adelantó", 0, false},
+	}
+
+	for _, caso := range casos {
+		t.Run(caso.nombre, func(t *testing.T) {
+			db, mock := baseDePrueba(t)
+			ahora := time.Now()
+			empleado := uint(3)
+
+			mock.ExpectBegin()
+			mock.ExpectExec(regexp.QuoteMeta("UPDATE `vouchers` SET")+".*"+regexp.QuoteMeta("WHERE id = ? AND usado = ?")).
+				WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), true, sqlmock.AnyArg(), 1, false).
+				WillReturnResult(sqlmock.NewResult(0, caso.afectadas))
+			mock.ExpectCommit()
+
+			canjeado, err := NewVoucherRepository(db).Canjear(&models.Voucher{ID: 1, FechaUso: &ahora, UsuarioCanje: &empleado})
+			if err != nil {
+				t.Fatalf("error canjeando: %v", err)
+			}
+			if canjeado != caso.canjeado {
+				t.Errorf("canjeado = %t, se esperaba %t", canjeado, caso.canjeado)
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
@@ -0,0 +1,78 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+
+	"gorm.io/gorm"
+
+	"CheeseHouse/internal/models"
+)
+
+// WidgetRepository define la interfaz para los sitios autorizados a embeber el juego
+type WidgetRepository interface {
+	Crear(widget *models.Widget) error
+	BuscarPorID(id uint) (*models.Widget, error)
+	BuscarPorClave(clave string) (*models.Widget, error)
+	Actualizar(widget *models.Widget) error
+	Listar() ([]*models.Widget, error)
+}
+
+// widgetRepository implementación de WidgetRepository
+type widgetRepository struct {
+	db *gorm.DB
+}
+
+// NewWidgetRepository crea una nueva instancia del repositorio de widgets
+func NewWidgetRepository(db *gorm.DB) WidgetRepository {
+	return &widgetRepository{db: db}
+}
+
+// Crear registra un nuevo widget
+func (r *widgetRepository) Crear(widget *models.Widget) error {
+	if err := r.db.Create(widget).Error; err != nil {
+		return fmt.Errorf("error creando widget: %w", err)
+	}
+	return nil
+}
+
+// BuscarPorID busca un widget por ID
+func (r *widgetRepository) BuscarPorID(id uint) (*models.Widget, error) {
+	var widget models.Widget
+	if err := r.db.First(&widget, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("widget con ID %d no encontrado", id)
+		}
+		return nil, fmt.Errorf("error buscando widget: %w", err)
+	}
+	return &widget, nil
+}
+
+// BuscarPorClave busca un widget por su clave pública; nil si no existe
+func (r *widgetRepository) BuscarPorClave(clave string) (*models.Widget, error) {
+	var widget models.Widget
+	if err := r.db.Where("clave = ?", clave).First(&widget).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("error buscando widget: %w", err)
+	}
+	return &widget, nil
+}
+
+// Actualizar guarda los cambios de un widget
+func (r *widgetRepository) Actualizar(widget *models.Widget) error {
+	if err := r.db.Save(widget).Error; err != nil {
+		return fmt.Errorf("error actualizando widget: %w", err)
+	}
+	return nil
+}
+
+// Listar obtiene todos los widgets, los activos primero
+func (r *widgetRepository) Listar() ([]*models.Widget, error) {
+	var widgets []*models.Widget
+	if err := r.db.Order("activo DESC, nombre ASC").Find(&widgets).Error; err != nil {
+		return nil, fmt.Errorf("error listando widgets: %w", err)
+	}
+	return widgets, nil
+}
@@ -1,38 +1,92 @@
 package services
 
 import (
+	"errors"
 	"fmt"
 	"log"
+	"math"
+	"math/rand"
+	"strconv"
+	"strings"
 	"time"
 
+	"CheeseHouse/internal/config"
+	"CheeseHouse/internal/fechas"
 	"CheeseHouse/internal/models"
+	"CheeseHouse/internal/planilla"
 	"CheeseHouse/internal/repository"
+	"CheeseHouse/internal/version"
 )
 
 // AdminService maneja las operaciones administrativas de CheeseHouse
 type AdminService struct {
+	config          *config.Config
 	clienteRepo     repository.ClienteRepository
 	voucherRepo     repository.VoucherRepository
 	whatsappService *WhatsAppService
+	eventService    *EventService
+	circuito        *CircuitoPremiosService
+	picoEmision     *PicoEmisionService
+	conversaciones  *ConversacionService
+	diasSinCanje    *DiaSinCanjeService
+	canjesEmpleado  *CanjeEmpleadoService
+	campanaRepo     repository.CampanaRepository
+	pedidoRepo      repository.PedidoRepository
+	preferencias    *PreferenciasService
+	costoCampana    *CostoCampanaService
+	auth            *AuthService
+	voucherQR       *VoucherQRService
+	unidad          *repository.UnidadDeTrabajo
+	trabajos        *TrabajoService
 }
 
 // NewAdminService crea una nueva instancia del servicio administrativo
 func NewAdminService(
+	cfg *config.Config,
 	clienteRepo repository.ClienteRepository,
 	voucherRepo repository.VoucherRepository,
 	whatsappService *WhatsAppService,
+	eventService *EventService,
+	circuito *CircuitoPremiosService,
+	picoEmision *PicoEmisionService,
+	conversaciones *ConversacionService,
+	diasSinCanje *DiaSinCanjeService,
+	canjesEmpleado *CanjeEmpleadoService,
+	campanaRepo repository.CampanaRepository,
+	pedidoRepo repository.PedidoRepository,
+	preferencias *PreferenciasService,
+	costoCampana *CostoCampanaService,
+	auth *AuthService,
+	voucherQR *VoucherQRService,
+	unidad *repository.UnidadDeTrabajo,
+	trabajos *TrabajoService,
 ) *AdminService {
 	return &AdminService{
+		config:          cfg,
 		clienteRepo:     clienteRepo,
 		voucherRepo:     voucherRepo,
 		whatsappService: whatsappService,
+		eventService:    eventService,
+		circuito:        circuito,
+		picoEmision:     picoEmision,
+		conversaciones:  conversaciones,
+		diasSinCanje:    diasSinCanje,
+		canjesEmpleado:  canjesEmpleado,
+		campanaRepo:     campanaRepo,
+		pedidoRepo:      pedidoRepo,
+		preferencias:    preferencias,
+		costoCampana:    costoCampana,
+		auth:            auth,
+		voucherQR:       voucherQR,
+		unidad:          unidad,
+		trabajos:        trabajos,
 	}
 }
 
 // GetDashboardData obtiene todos los datos para el dashboard
 func (a *AdminService) GetDashboardData() (map[string]interface{}, error) {
 	// Estadísticas generales
-	stats, err := a.clienteRepo.GetEstadisticasGenerales()
+	stats, err := a.clienteRepo.GetEstadisticasGenerales(a.config.InicioDelDia(time.Now()))
 	if err != nil {
 		return nil, fmt.Errorf("error obteniendo estadísticas: %w", err)
 	}
@@ -66,44 +120,120 @@ func (a *AdminService) GetDashboardData() (map[string]interface{}, error) {
 		estadisticasPeriodo = []*models.EstadisticasPorPeriodo{}
 	}
 
+	// Cuota de envíos de WhatsApp
+	cuotaWhatsApp, err := a.whatsappService.GetCuota()
+	if err != nil {
+		log.Printf("⚠️  Error obteniendo cuota de WhatsApp: %v", err)
+	}
+
+	// SLA de atención de pedidos/conversaciones de WhatsApp
+	slaAtencion, err := a.conversaciones.GetResumenSLA()
+	if err != nil {
+		log.Printf("⚠️  Error obteniendo SLA de atención: %v", err)
+	}
+
 	return map[string]interface{}{
 		"estadisticas_generales": stats,
 		"vouchers_por_vencer":    vouchersPorVencer,
 		"top_clientes":           topClientes,
 		"estadisticas_periodo":   estadisticasPeriodo,
 		"whatsapp_status":        a.whatsappService.GetStatus(),
+		"whatsapp_cuota":         cuotaWhatsApp,
+		"circuito_premios":       a.circuito.Estado(),
+		"pico_emision":           a.picoEmision.Estado(),
+		"sla_atencion":           slaAtencion,
 	}, nil
 }
 
-// CanjearVoucher canjea un voucher en caja
-func (a *AdminService) CanjearVoucher(codigo string, empleadoID uint) (*models.CanjearVoucherResponse, error) {
-	log.Printf("🎟️  Canjeando voucher: %s por empleado ID: %d", codigo, empleadoID)
+// CanjearVoucher canjea un voucher en caja. sucursal es la del empleado que lo procesa y
+// aprobacion la de un encargado, necesaria solo para descuentos altos. Los rechazos
+// quedan registrados a nombre del empleado para el reporte de anomalías
+func (a *AdminService) CanjearVoucher(codigo string, empleadoID uint, sucursal string, aprobacion models.AprobacionCanje) (*models.CanjearVoucherResponse, error) {
+	sucursal = a.config.Sucursal(sucursal)
+	log.Printf("🎟️  Canjeando voucher: %s por empleado ID: %d (%s)", codigo, empleadoID, sucursal)
+
+	var respuesta *models.CanjearVoucherResponse
+	err := a.unidad.Ejecutar(func(tx *repository.Transaccion) error {
+		var err error
+		respuesta, err = a.canjearEnTransaccion(tx, codigo, empleadoID, sucursal, aprobacion)
+		return err
+	})
+	return respuesta, err
+}
 
+// canjearEnTransaccion valida y canjea el voucher con su fila bloqueada: si dos cajas
+// escanean el mismo código a la vez, la segunda espera a que termine la primera y lo
+// encuentra usado
+func (a *AdminService) canjearEnTransaccion(tx *repository.Transaccion, codigo string, empleadoID uint, sucursal string, aprobacion models.AprobacionCanje) (*models.CanjearVoucherResponse, error) {
 	// Buscar voucher
-	voucher, err := a.voucherRepo.BuscarPorCodigo(codigo)
+	voucher, err := tx.Vouchers.BuscarParaCanje(codigo)
 	if err != nil {
-		return &models.CanjearVoucherResponse{
+		return a.rechazarCanje(empleadoID, codigo, "invalido", &models.CanjearVoucherResponse{
 			Success: false,
 			Message: "Código de voucher no válido",
-		}, nil
+		})
 	}
 
 	// Verificar si ya fue usado
 	if voucher.Usado {
-		return &models.CanjearVoucherResponse{
+		return a.rechazarCanje(empleadoID, codigo, "usado", &models.CanjearVoucherResponse{
 			Success:   false,
 			Message:   "Este voucher ya fue utilizado",
 			Descuento: voucher.Descuento,
-		}, nil
+		})
 	}
 
 	// Verificar vencimiento
 	if voucher.FechaVencimiento.Before(time.Now()) {
-		return &models.CanjearVoucherResponse{
+		return a.rechazarCanje(empleadoID, codigo, "vencido", &models.CanjearVoucherResponse{
 			Success:   false,
 			Message:   "Este voucher está vencido",
 			Descuento: voucher.Descuento,
-		}, nil
+		})
+	}
+
+	// Verificar que se pueda canjear en la sucursal del empleado
+	if emisora := a.config.Sucursal(voucher.Sucursal); voucher.SoloSucursal && emisora != sucursal {
+		return a.rechazarCanje(empleadoID, codigo, "sucursal", &models.CanjearVoucherResponse{
+			Success:   false,
+			Message:   fmt.Sprintf("Este voucher solo se puede canjear en la sucursal %s", emisora),
+			Descuento: voucher.Descuento,
+		})
+	}
+
+	// Verificar que hoy se acepten vouchers. Si no se puede consultar, se deja canjear
+	bloqueo, proximo, err := a.diasSinCanje.Verificar(time.Now())
+	if err != nil {
+		log.Printf("⚠️  No se pudieron verificar los días sin canje: %v", err)
+	}
+	if bloqueo != nil {
+		formato := a.config.Fechas("")
+		return a.rechazarCanje(empleadoID, codigo, "dia_sin_canje", &models.CanjearVoucherResponse{
+			Success:           false,
+			Message:           mensajeDiaSinCanje(formato, bloqueo, proximo, voucher),
+			Descuento:         voucher.Descuento,
+			ProximoCanje:      formato.Dia(proximo),
+			ProximoCanjeTexto: formato.Fecha(proximo),
+		})
+	}
+
+	// Verificar el tope diario de canjes del empleado
+	if limite := a.canjesEmpleado.VerificarLimite(empleadoID); limite > 0 {
+		return a.rechazarCanje(empleadoID, codigo, "limite", &models.CanjearVoucherResponse{
+			Success:   false,
+			Message:   fmt.Sprintf("Alcanzaste el tope de %d canjes por hoy; pedí a un encargado que lo procese", limite),
+			Descuento: voucher.Descuento,
+		})
+	}
+
+	// Descuentos altos: segunda confirmación con el PIN de un encargado
+	var aprobadorID *uint
+	if a.config.RequiereAprobacionCanje(voucher.Descuento) {
+		aprobador, rechazo := a.verificarAprobacion(empleadoID, voucher, aprobacion)
+		if rechazo != nil {
+			return rechazo, nil
+		}
+		aprobadorID = &aprobador.ID
 	}
 
 	// Marcar como usado
@@ -111,13 +241,25 @@ func (a *AdminService) CanjearVoucher(codigo string, empleadoID uint) (*models.C
 	now := time.Now()
 	voucher.FechaUso = &now
 	voucher.UsuarioCanje = &empleadoID
+	voucher.SucursalCanje = sucursal
+	voucher.AprobadoPor = aprobadorID
 
-	if err := a.voucherRepo.Actualizar(voucher); err != nil {
+	canjeado, err := tx.Vouchers.Canjear(voucher)
+	if err != nil {
+		log.Printf("❌ Error guardando canje de %s: %v", codigo, err)
 		return &models.CanjearVoucherResponse{
 			Success: false,
 			Message: "Error interno procesando canje",
 		}, nil
 	}
+	if !canjeado {
+		// Otro canje del mismo voucher se guardó entre la búsqueda y este
+		return a.rechazarCanje(empleadoID, codigo, "usado", &models.CanjearVoucherResponse{
+			Success:   false,
+			Message:   "Este voucher ya fue utilizado",
+			Descuento: voucher.Descuento,
+		})
+	}
 
 	// Obtener datos del cliente
 	cliente, err := a.clienteRepo.BuscarPorID(voucher.ClienteID)
@@ -134,16 +276,138 @@ func (a *AdminService) CanjearVoucher(codigo string, empleadoID uint) (*models.C
 		codigo, voucher.Descuento, clienteNombre)
 
 	return &models.CanjearVoucherResponse{
-		Success:   true,
-		Message:   "Voucher canjeado correctamente",
-		Descuento: voucher.Descuento,
-		Cliente:   clienteNombre,
+		Success:       true,
+		Message:       "Voucher canjeado correctamente",
+		Descuento:     voucher.Descuento,
+		Cliente:       clienteNombre,
+		Alcance:       voucher.Alcance,
+		Recomendacion: voucher.Recomendacion,
 	}, nil
 }
 
-// GetClientes obtiene lista de clientes con filtros
-func (a *AdminService) GetClientes(filtros map[string]interface{}) ([]*models.ClienteConEstadisticas, error) {
-	return a.clienteRepo.ListarConEstadisticas(filtros)
+// CanjearVoucherQR canjea el voucher a partir del contenido escaneado de su QR: valida la
+// firma y el vencimiento antes de seguir el canje normal
+func (a *AdminService) CanjearVoucherQR(contenido string, empleadoID uint, sucursal string, aprobacion models.AprobacionCanje) (*models.CanjearVoucherResponse, error) {
+	codigo, err := a.voucherQR.Leer(contenido, time.Now())
+	if err != nil {
+		log.Printf("⚠️  QR de voucher rechazado (empleado %d): %v", empleadoID, err)
+		mensaje, motivo := "QR de voucher no válido", "invalido"
+		if errors.Is(err, ErrQRVoucherVencido) {
+			mensaje, motivo = "Este voucher está vencido", "vencido"
+		}
+		return a.rechazarCanje(empleadoID, codigo, motivo, &models.CanjearVoucherResponse{
+			Success: false,
+			Message: mensaje,
+		})
+	}
+	return a.CanjearVoucher(codigo, empleadoID, sucursal, aprobacion)
+}
+
+// verificarAprobacion valida el email y el PIN del encargado de un canje de descuento
+// alto. Retorna el encargado que aprueba o la respuesta de rechazo
+func (a *AdminService) verificarAprobacion(empleadoID uint, voucher *models.Voucher, aprobacion models.AprobacionCanje) (*models.Usuario, *models.CanjearVoucherResponse) {
+	rechazo := &models.CanjearVoucherResponse{
+		Success:            false,
+		Descuento:          voucher.Descuento,
+		RequiereAprobacion: true,
+	}
+
+	// Pedir el PIN no es un rechazo: es el paso normal del canje
+	if aprobacion.AprobadorEmail == "" || aprobacion.PINAprobacion == "" {
+		rechazo.Message = fmt.Sprintf("Descuento del %d%%: ingresá el email y el PIN de un encargado para confirmar", voucher.Descuento)
+		return nil, rechazo
+	}
+	if a.canjesEmpleado.PINBloqueado(empleadoID) {
+		rechazo.Message = "Demasiados PIN incorrectos, esperá unos minutos o pedí a un encargado que lo canjee"
+		a.canjesEmpleado.RegistrarRechazo(empleadoID, voucher.Codigo, "aprobacion", voucher.Descuento)
+		return nil, rechazo
+	}
+
+	aprobador, err := a.auth.BuscarAprobador(aprobacion.AprobadorEmail, aprobacion.PINAprobacion)
+	if err != nil {
+		a.canjesEmpleado.RegistrarFalloPIN(empleadoID)
+		a.canjesEmpleado.RegistrarRechazo(empleadoID, voucher.Codigo, "aprobacion", voucher.Descuento)
+		rechazo.Message = "Email o PIN de encargado incorrecto"
+		return nil, rechazo
+	}
+	a.canjesEmpleado.LimpiarFallosPIN(empleadoID)
+
+	log.Printf("🔐 Canje de %s (%d%%) aprobado por %s", voucher.Codigo, voucher.Descuento, aprobador.Email)
+	return aprobador, nil
+}
+
+// rechazarCanje registra el rechazo a nombre del empleado y retorna la respuesta
+func (a *AdminService) rechazarCanje(empleadoID uint, codigo, motivo string, respuesta *models.CanjearVoucherResponse) (*models.CanjearVoucherResponse, error) {
+	a.canjesEmpleado.RegistrarRechazo(empleadoID, codigo, motivo, respuesta.Descuento)
+	return respuesta, nil
+}
+
+// mensajeDiaSinCanje explica por qué hoy no se acepta el voucher y desde cuándo se puede usar
+func mensajeDiaSinCanje(formato fechas.Formato, bloqueo *models.DiaSinCanje, proximo time.Time, voucher *models.Voucher) string {
+	mensaje := "Hoy no se pueden canjear vouchers"
+	if bloqueo.Motivo != "" {
+		mensaje += " (" + bloqueo.Motivo + ")"
+	}
+	if voucher.FechaVencimiento.Before(proximo) {
+		return fmt.Sprintf("%s y este voucher vence el %s, antes del próximo día habilitado (%s)",
+			mensaje, formato.Fecha(voucher.FechaVencimiento), formato.Fecha(proximo))
+	}
+	return fmt.Sprintf("%s. Se puede usar desde el %s", mensaje, formato.Fecha(proximo))
+}
+
+// GetVoucherDetalle busca un voucher por código junto con las fechas sin canje que
+// caen dentro de su vigencia
+func (a *AdminService) GetVoucherDetalle(codigo string) (*models.Voucher, []*models.DiaSinCanje, error) {
+	voucher, err := a.voucherRepo.BuscarPorCodigo(strings.ToUpper(strings.TrimSpace(codigo)))
+	if err != nil {
+		return nil, nil, err
+	}
+	diasSinCanje, err := a.diasSinCanje.EnVigencia(voucher)
+	if err != nil {
+		return nil, nil, err
+	}
+	return voucher, diasSinCanje, nil
+}
+
+// TransferirVoucher mueve un voucher sin usar a otra sucursal; si estaba restringido a
+// la emisora pasa a canjearse solo en la nueva
+func (a *AdminService) TransferirVoucher(codigo string, req models.TransferirVoucherRequest, usuarioID uint) (*models.Voucher, error) {
+	voucher, err := a.voucherRepo.BuscarPorCodigo(strings.ToUpper(strings.TrimSpace(codigo)))
+	if err != nil {
+		return nil, fmt.Errorf("voucher no encontrado")
+	}
+	if voucher.Usado {
+		return nil, fmt.Errorf("el voucher ya fue utilizado")
+	}
+	if voucher.FechaVencimiento.Before(time.Now()) {
+		return nil, fmt.Errorf("el voucher está vencido")
+	}
+
+	origen, destino := a.config.Sucursal(voucher.Sucursal), a.config.Sucursal(req.Sucursal)
+	if origen == destino {
+		return nil, fmt.Errorf("el voucher ya es de la sucursal %s", destino)
+	}
+
+	nota := fmt.Sprintf("Transferido de %s a %s por usuario %d", origen, destino, usuarioID)
+	if motivo := strings.TrimSpace(req.Motivo); motivo != "" {
+		nota += ": " + motivo
+	}
+	if voucher.Notas != "" {
+		nota = voucher.Notas + "\n" + nota
+	}
+	voucher.Sucursal = destino
+	voucher.Notas = nota
+	if err := a.voucherRepo.Actualizar(voucher); err != nil {
+		return nil, fmt.Errorf("error transfiriendo voucher: %w", err)
+	}
+
+	log.Printf("🔀 Voucher %s transferido de %s a %s por usuario %d", voucher.Codigo, origen, destino, usuarioID)
+	return voucher, nil
+}
+
+// GetClientes obtiene una página de clientes con filtros y el total que los cumple
+func (a *AdminService) GetClientes(filtros map[string]interface{}, paginacion models.Paginacion) ([]*models.ClienteConEstadisticas, int64, error) {
+	return a.clienteRepo.ListarConEstadisticas(filtros, paginacion)
 }
 
 // GetClienteDetalle obtiene detalle completo de un cliente
@@ -151,14 +415,16 @@ func (a *AdminService) GetClienteDetalle(clienteID uint) (*models.ClienteConEsta
 	return a.clienteRepo.GetClienteConEstadisticas(clienteID)
 }
 
-// GetVouchers obtiene lista de vouchers con filtros
-func (a *AdminService) GetVouchers(filtros map[string]interface{}) ([]*models.Voucher, error) {
-	return a.voucherRepo.ListarConFiltros(filtros)
+// GetVouchers obtiene una página de vouchers con filtros y el total que los cumple
+func (a *AdminService) GetVouchers(filtros map[string]interface{}, paginacion models.Paginacion) ([]*models.Voucher, int64, error) {
+	return a.voucherRepo.ListarConFiltros(filtros, paginacion)
 }
 
-// CrearCampana crea una nueva campaña promocional
+// CrearCampana crea una nueva campaña promocional. La fecha de vencimiento es la de los
+// vouchers que se generan al enviarla
 func (a *AdminService) CrearCampana(campana *models.CampanaClientesVouchers) error {
 	// Validaciones
+	campana.Nombre = strings.TrimSpace(campana.Nombre)
 	if campana.Nombre == "" {
 		return fmt.Errorf("nombre de campaña es requerido")
 	}
@@ -171,55 +437,436 @@ func (a *AdminService) CrearCampana(campana *models.CampanaClientesVouchers) err
 		return fmt.Errorf("fecha de vencimiento debe ser futura")
 	}
 
-	// Crear campaña (implementar repository para campañas)
-	log.Printf("📢 Creando campaña: %s (%d%% descuento)", campana.Nombre, campana.Descuento)
+	campana.Activa = true
+	if err := a.campanaRepo.Crear(campana); err != nil {
+		return err
+	}
 
-	// TODO: Implementar repository para campañas
-	return fmt.Errorf("funcionalidad de campañas no implementada aún")
+	log.Printf("📢 Campaña #%d creada: %s (%d%% descuento)", campana.ID, campana.Nombre, campana.Descuento)
+	return nil
 }
 
-// EnviarCampana envía una campaña a clientes seleccionados
-func (a *AdminService) EnviarCampana(campanaID uint, clientesIDs []uint) error {
+// EnviarCampana genera un voucher por cliente seleccionado, registra los envíos y manda
+// los mensajes de WhatsApp en segundo plano. Se omiten los clientes bloqueados, los que
+// no aceptan marketing y los que ya recibieron la campaña
+func (a *AdminService) EnviarCampana(campanaID uint, clientesIDs []uint) (*models.ResultadoEnvioCampana, error) {
 	log.Printf("📢 Enviando campaña ID %d a %d clientes", campanaID, len(clientesIDs))
 
-	// TODO: Implementar envío de campañas
-	// 1. Obtener datos de la campaña
-	// 2. Generar vouchers para cada cliente
-	// 3. Enviar WhatsApp a cada cliente
-	// 4. Registrar envíos en clientes_vouchers_envios
+	resultado := &models.ResultadoEnvioCampana{
+		CampanaID:     campanaID,
+		Seleccionados: len(clientesIDs),
+	}
+	var (
+		campana       *models.CampanaClientesVouchers
+		destinatarios []*models.Cliente
+		vouchers      []*models.Voucher
+		envios        []*models.ClientesVouchersEnvios
+	)
+	// Destinatarios, vouchers y envíos en una transacción con la campaña bloqueada: un doble
+	// click o dos instancias enviando la misma campaña no generan vouchers repetidos, y el
+	// índice único de envíos revierte todo si igual se cruzan
+	err := a.unidad.Ejecutar(func(tx *repository.Transaccion) error {
+		var err error
+		if campana, err = tx.Campanas.BloquearParaEnvio(campanaID); err != nil {
+			return err
+		}
+		if !campana.Activa {
+			return fmt.Errorf("la campaña está desactivada")
+		}
+		if campana.FechaVencimiento.Before(time.Now()) {
+			return fmt.Errorf("la campaña ya venció")
+		}
+
+		destinatarios, resultado.Omitidos, err = a.destinatariosCampana(tx.Campanas, campanaID, clientesIDs)
+		if err != nil {
+			return err
+		}
+		resultado.Destinatarios = len(destinatarios)
+		if len(destinatarios) == 0 {
+			return nil
+		}
+
+		// No lanzar campañas que excedan la cuota restante de WhatsApp
+		if err := a.whatsappService.VerificarCuota(len(destinatarios)); err != nil {
+			return err
+		}
+
+		// Un voucher por cliente
+		ahora := time.Now()
+		codigos := generarCodigosCampana(a.config.GenerateVoucherCode(), len(destinatarios))
+		vouchers = make([]*models.Voucher, 0, len(destinatarios))
+		for i, cliente := range destinatarios {
+			vouchers = append(vouchers, &models.Voucher{
+				Codigo:           codigos[i],
+				ClienteID:        cliente.ID,
+				Tipo:             "cliente_promocion",
+				Descuento:        campana.Descuento,
+				FechaEmision:     ahora,
+				FechaVencimiento: campana.FechaVencimiento,
+				Notas:            fmt.Sprintf("Campaña #%d: %s", campana.ID, campana.Nombre),
+			})
+		}
+		if err := tx.Vouchers.CrearEnLote(vouchers, a.config.DBBatchSize); err != nil {
+			return err
+		}
 
-	return fmt.Errorf("funcionalidad de campañas no implementada aún")
+		envios = make([]*models.ClientesVouchersEnvios, 0, len(vouchers))
+		for _, voucher := range vouchers {
+			envios = append(envios, &models.ClientesVouchersEnvios{
+				CampanaID:     campanaID,
+				ClienteID:     voucher.ClienteID,
+				VoucherID:     &voucher.ID,
+				CodigoVoucher: voucher.Codigo,
+				Estado:        "pendiente",
+			})
+		}
+		return tx.Campanas.CrearEnviosEnLote(envios, a.config.DBBatchSize)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(destinatarios) == 0 {
+		return resultado, nil
+	}
+
+	ids := make([]uint, 0, len(destinatarios))
+	for _, cliente := range destinatarios {
+		ids = append(ids, cliente.ID)
+	}
+	if resultado.Estimacion, err = a.costoCampana.RegistrarEstimacion(campanaID, campana.Descuento, ids); err != nil {
+		log.Printf("⚠️  No se pudo registrar el costo estimado de la campaña %d: %v", campanaID, err)
+	}
+
+	descripcion := fmt.Sprintf("Envío de la campaña #%d: %s", campana.ID, campana.Nombre)
+	trabajo := a.trabajos.Iniciar("campana", descripcion, len(envios), true, func(avance *AvanceTrabajo) error {
+		a.despacharCampana(campana, destinatarios, vouchers, envios, avance)
+		return nil
+	})
+	resultado.TrabajoID = trabajo.ID
+
+	log.Printf("📢 Campaña #%d: %d vouchers generados, %d clientes omitidos", campanaID, len(vouchers), len(clientesIDs)-len(vouchers))
+	return resultado, nil
+}
+
+// GetCampanas obtiene una página de campañas con el resumen de sus envíos y el total
+func (a *AdminService) GetCampanas(paginacion models.Paginacion) ([]map[string]interface{}, int64, error) {
+	return a.campanaRepo.GetCampanasConEstadisticas(paginacion)
+}
+
+// GetCampanaDetalle obtiene una campaña con sus envíos y estadísticas
+func (a *AdminService) GetCampanaDetalle(campanaID uint) (*models.CampanaClientesVouchers, map[string]interface{}, error) {
+	campana, err := a.campanaRepo.BuscarPorID(campanaID)
+	if err != nil {
+		return nil, nil, err
+	}
+	estadisticas, err := a.campanaRepo.GetEstadisticasCampana(campanaID)
+	if err != nil {
+		return nil, nil, err
+	}
+	return campana, estadisticas, nil
 }
 
-// AprobarJuegoFrecuente aprueba que un cliente frecuente pueda seguir jugando
+// destinatariosCampana filtra los clientes seleccionados que pueden recibir la campaña y
+// cuenta los omitidos por motivo
+func (a *AdminService) destinatariosCampana(campanas repository.CampanaRepository, campanaID uint, clientesIDs []uint) ([]*models.Cliente, map[string]int, error) {
+	clientes, err := a.clienteRepo.BuscarPorIDs(clientesIDs)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error obteniendo clientes de la campaña: %w", err)
+	}
+	conEnvio, err := campanas.GetClientesConEnvio(campanaID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	porID := make(map[uint]*models.Cliente, len(clientes))
+	for _, cliente := range clientes {
+		porID[cliente.ID] = cliente
+	}
+
+	omitidos := make(map[string]int)
+	destinatarios := make([]*models.Cliente, 0, len(clientes))
+	for _, clienteID := range clientesIDs {
+		cliente, ok := porID[clienteID]
+		switch {
+		case !ok:
+			omitidos["no_encontrado"]++
+			continue
+		case conEnvio[clienteID]:
+			omitidos["ya_enviado"]++
+			continue
+		case cliente.Estado == "bloqueado":
+			omitidos["bloqueado"]++
+			continue
+		case a.preferencias != nil && !a.preferencias.PermiteEnvio(clienteID, CategoriaMarketing, "whatsapp"):
+			omitidos["sin_marketing"]++
+			continue
+		}
+		conEnvio[clienteID] = true // IDs repetidos en la selección
+		destinatarios = append(destinatarios, cliente)
+	}
+	return destinatarios, omitidos, nil
+}
+
+// despacharCampana envía los mensajes de una campaña, actualiza el estado de cada envío e
+// informa el avance al trabajo. Si se cancela, los envíos que faltan quedan pendientes
+func (a *AdminService) despacharCampana(campana *models.CampanaClientesVouchers, clientes []*models.Cliente, vouchers []*models.Voucher, envios []*models.ClientesVouchersEnvios, avance *AvanceTrabajo) {
+	enviados, fallidos, retenidos := 0, 0, 0
+	var limitado error
+	for i, envio := range envios {
+		item := fmt.Sprintf("cliente #%d", clientes[i].ID)
+		estado, detalle := "enviado", ""
+		if limitado == nil && avance.Cancelado() {
+			limitado = errors.New("envío de la campaña cancelado")
+		}
+		if limitado != nil {
+			// La calidad del número bajó o se canceló durante la campaña: el resto queda pendiente
+			estado, detalle = "pendiente", limitado.Error()
+			retenidos++
+		} else if mensaje, err := a.whatsappService.EnviarMensajeMarketing(clientes[i], campana.ID, campana.Mensaje, vouchers[i]); err != nil {
+			if errors.Is(err, ErrMarketingLimitado) {
+				limitado = err
+				estado, detalle = "pendiente", err.Error()
+				retenidos++
+			} else {
+				estado, detalle = "fallido", err.Error()
+				fallidos++
+			}
+			avance.Fallo(item, err)
+		} else {
+			estado = a.asociarMensajeEnvio(envio, mensaje)
+			enviados++
+			avance.Avanzar(1)
+		}
+
+		if err := a.campanaRepo.ActualizarEstadoEnvio(envio.ID, estado, detalle); err != nil {
+			log.Printf("⚠️  No se pudo actualizar el envío #%d de la campaña %d: %v", envio.ID, campana.ID, err)
+		}
+	}
+
+	avance.Resultado(map[string]int{"enviados": enviados, "fallidos": fallidos, "pendientes": retenidos})
+	log.Printf("📢 Campaña #%d despachada: %d enviados, %d fallidos", campana.ID, enviados, fallidos)
+	if retenidos > 0 {
+		log.Printf("🚦 Campaña #%d: %d envíos retenidos: %v", campana.ID, retenidos, limitado)
+	}
+}
+
+// asociarMensajeEnvio vincula el envío con el mensaje que salió para seguir su entrega y
+// retorna el estado en que queda el envío
+func (a *AdminService) asociarMensajeEnvio(envio *models.ClientesVouchersEnvios, mensaje EnvioWhatsApp) string {
+	if mensaje.MessageID != "" || mensaje.OutboxID != nil {
+		if err := a.campanaRepo.AsociarMensajeEnvio(envio.ID, mensaje.MessageID, mensaje.OutboxID); err != nil {
+			log.Printf("⚠️  No se pudo asociar el mensaje al envío #%d de la campaña %d: %v", envio.ID, envio.CampanaID, err)
+		}
+	}
+	if mensaje.OutboxID != nil {
+		// Retenido por horario silencioso: pasa a enviado cuando el webhook confirme la salida
+		return "pendiente"
+	}
+	return "enviado"
+}
+
+// ReintentarEnviosCampanas vuelve a mandar los envíos de campañas que fallaron y todavía
+// no llegaron a maxIntentos. Se saltean los de campañas desactivadas o vencidas y los de
+// vouchers ya canjeados o archivados. Si se alcanza el tope de marketing, el resto queda
+// para la próxima pasada
+func (a *AdminService) ReintentarEnviosCampanas(maxIntentos int, avance *AvanceTrabajo) error {
+	// intentos_envio arranca en 1 y suma uno por cada fallo
+	envios, err := a.campanaRepo.GetEnviosPendientesReintento(maxIntentos + 1)
+	if err != nil {
+		return err
+	}
+	avance.FijarTotal(len(envios))
+
+	hoy := a.config.InicioDelDia(time.Now())
+	reenviados, fallidos, omitidos := 0, 0, 0
+	for _, envio := range envios {
+		if avance.Cancelado() {
+			break
+		}
+		if envio.Campana == nil || !envio.Campana.Activa || envio.Campana.FechaVencimiento.Before(hoy) ||
+			envio.Cliente == nil || envio.Voucher == nil || envio.Voucher.Usado {
+			omitidos++
+			avance.Avanzar(1)
+			continue
+		}
+
+		estado, detalle := "enviado", ""
+		mensaje, err := a.whatsappService.EnviarMensajeMarketing(envio.Cliente, envio.CampanaID, envio.Campana.Mensaje, envio.Voucher)
+		if errors.Is(err, ErrMarketingLimitado) {
+			log.Printf("🚦 Reintentos de campañas frenados: %v", err)
+			break
+		}
+		if err != nil {
+			estado, detalle = "fallido", err.Error()
+			fallidos++
+			avance.Fallo(fmt.Sprintf("envío #%d", envio.ID), err)
+		} else {
+			estado = a.asociarMensajeEnvio(envio, mensaje)
+			reenviados++
+			avance.Avanzar(1)
+		}
+		if err := a.campanaRepo.ActualizarEstadoEnvio(envio.ID, estado, detalle); err != nil {
+			log.Printf("⚠️  No se pudo actualizar el envío #%d de la campaña %d: %v", envio.ID, envio.CampanaID, err)
+		}
+	}
+
+	avance.Resultado(map[string]int{"reenviados": reenviados, "fallidos": fallidos, "omitidos": omitidos})
+	if reenviados+fallidos > 0 {
+		log.Printf("🔁 Reintentos de campañas: %d reenviados, %d fallidos", reenviados, fallidos)
+	}
+	return nil
+}
+
+// estadosEnvioWebhook estado del envío de campaña que corresponde a cada estado de WhatsApp
+var estadosEnvioWebhook = map[string]string{
+	"sent":      "enviado",
+	"delivered": "entregado",
+	"read":      "leido",
+	"failed":    "fallido",
+}
+
+// ActualizarEnviosCampanas aplica a los envíos de campañas los estados de entrega que
+// informa el webhook, para que las estadísticas reflejen lo que realmente llegó
+func (a *AdminService) ActualizarEnviosCampanas(webhook models.WhatsAppWebhookMessage) {
+	for _, entry := range webhook.Entry {
+		for _, change := range entry.Changes {
+			for _, estado := range change.Value.Statuses {
+				nuevo, ok := estadosEnvioWebhook[estado.Status]
+				if !ok || estado.ID == "" {
+					continue
+				}
+				detalle := ""
+				if nuevo == "fallido" {
+					detalle = "entrega fallida"
+					if len(estado.Errors) > 0 {
+						detalle = estado.Errors[0].Title
+					}
+				}
+				actualizados, err := a.campanaRepo.ActualizarEstadoPorMensaje(estado.ID, nuevo, momentoEstadoWhatsApp(estado.Timestamp), detalle)
+				if err != nil {
+					log.Printf("⚠️  Error registrando estado %s de %s en campañas: %v", estado.Status, estado.ID, err)
+				} else if actualizados > 0 {
+					log.Printf("📬 Envío de campaña %s: %s", estado.ID, nuevo)
+				}
+			}
+		}
+	}
+}
+
+// generarCodigosCampana genera n códigos de voucher distintos entre sí. El formato es el
+// de los vouchers de partida, pero todos los dígitos son aleatorios para que un lote
+// generado en el mismo segundo no repita códigos
+func generarCodigosCampana(prefijo string, n int) []string {
+	codigos := make([]string, 0, n)
+	usados := make(map[string]bool, n)
+	for len(codigos) < n {
+		codigo := fmt.Sprintf("%s%08d", prefijo, rand.Intn(100000000))
+		if usados[codigo] {
+			continue
+		}
+		usados[codigo] = true
+		codigos = append(codigos, codigo)
+	}
+	return codigos
+}
+
+// AprobarJuegoFrecuente habilita a un cliente que llegó a GAMES_REQUIRE_APPROVAL a seguir
+// jugando por el resto del día. Cuenta las partidas igual que el juego (ver
+// GameService.necesitaAprobacion) y deja la aprobación en el cliente para que el juego la lea
 func (a *AdminService) AprobarJuegoFrecuente(clienteID uint, empleadoID uint) error {
+	var (
+		cliente   *models.Cliente
+		juegosHoy int
+	)
+	err := a.unidad.Ejecutar(func(tx *repository.Transaccion) error {
+		var err error
+		cliente, err = tx.Clientes.BloquearPorID(clienteID)
+		if err != nil {
+			return fmt.Errorf("cliente no encontrado: %w", err)
+		}
+
+		juegosHoy, err = tx.Juegos.ContarPorClienteDesde(clienteID, a.config.InicioDelDia(time.Now()))
+		if err != nil {
+			return err
+		}
+		if juegosHoy < a.config.Game.GamesRequireApproval {
+			return fmt.Errorf("cliente no necesita aprobación (solo %d juegos hoy)", juegosHoy)
+		}
+
+		return tx.Clientes.RegistrarAprobacion(clienteID, empleadoID, time.Now())
+	})
+	if err != nil {
+		return err
+	}
+
+	log.Printf("✅ Empleado ID %d aprobó juegos para cliente %s %s (%s) - Juegos hoy: %d",
+		empleadoID, cliente.Nombre, cliente.Apellido, cliente.Telefono, juegosHoy)
+	return nil
+}
+
+// CambiarEstadoCliente bloquea o reactiva a un cliente
+func (a *AdminService) CambiarEstadoCliente(clienteID uint, estado string, motivo string, empleadoID uint) (*models.Cliente, error) {
+	if estado != "activo" && estado != "bloqueado" {
+		return nil, fmt.Errorf("estado de cliente no válido: %s", estado)
+	}
+
 	cliente, err := a.clienteRepo.BuscarPorID(clienteID)
 	if err != nil {
-		return fmt.Errorf("cliente no encontrado: %w", err)
+		return nil, fmt.Errorf("cliente no encontrado: %w", err)
 	}
 
-	if cliente.TotalJuegos < 3 {
-		return fmt.Errorf("cliente no necesita aprobación (solo %d juegos)", cliente.TotalJuegos)
+	cliente.Estado = estado
+	if err := a.clienteRepo.Actualizar(cliente); err != nil {
+		return nil, fmt.Errorf("error actualizando estado del cliente: %w", err)
 	}
 
-	// Registrar aprobación
-	log.Printf("✅ Empleado ID %d aprobó juegos para cliente %s %s (%s) - Total juegos: %d",
-		empleadoID, cliente.Nombre, cliente.Apellido, cliente.Telefono, cliente.TotalJuegos)
+	log.Printf("👤 Cliente %s marcado como %s por empleado ID %d: %s",
+		cliente.Telefono, estado, empleadoID, motivo)
 
-	// TODO: Implementar sistema de aprobaciones en BD si es necesario
-	// Por ahora solo logueamos la aprobación
+	evento := EventoClienteActualizado
+	if estado == "bloqueado" {
+		evento = EventoClienteBloqueado
+	}
+	a.eventService.EmitirCliente(evento, cliente, motivo)
 
-	return nil
+	return cliente, nil
+}
+
+// AnonimizarCliente elimina los datos personales de un cliente conservando sus estadísticas
+func (a *AdminService) AnonimizarCliente(clienteID uint, empleadoID uint) (*models.Cliente, error) {
+	cliente, err := a.clienteRepo.BuscarPorID(clienteID)
+	if err != nil {
+		return nil, fmt.Errorf("cliente no encontrado: %w", err)
+	}
+
+	cliente.Nombre = "Anónimo"
+	cliente.Apellido = "Anónimo"
+	cliente.Telefono = fmt.Sprintf("anon-%d", cliente.ID)
+	cliente.Estado = "bloqueado"
+	cliente.ConsentimientoMuro = false
+
+	if err := a.clienteRepo.Actualizar(cliente); err != nil {
+		return nil, fmt.Errorf("error anonimizando cliente: %w", err)
+	}
+
+	log.Printf("🕶️  Cliente ID %d anonimizado por empleado ID %d", clienteID, empleadoID)
+	a.eventService.EmitirCliente(EventoClienteAnonimizado, cliente, "")
+
+	return cliente, nil
 }
 
-// GetClientesPendientesAprobacion obtiene clientes que necesitan aprobación
+// GetClientesPendientesAprobacion obtiene los clientes que hoy ya jugaron las partidas
+// a partir de las cuales hace falta aprobación y todavía no fueron aprobados
 func (a *AdminService) GetClientesPendientesAprobacion() ([]*models.ClienteConEstadisticas, error) {
+	inicioDia := a.config.InicioDelDia(time.Now())
 	filtros := map[string]interface{}{
-		"min_juegos":  3,
-		"jugaron_hoy": true,
+		"min_juegos":        a.config.Game.GamesRequireApproval,
+		"jugaron_desde":     inicioDia,
+		"sin_aprobar_desde": inicioDia,
 	}
 
-	return a.clienteRepo.ListarConEstadisticas(filtros)
+	clientes, _, err := a.clienteRepo.ListarConEstadisticas(filtros, models.Paginacion{})
+	return clientes, err
 }
 
 // GetVouchersVencidos obtiene vouchers vencidos para análisis
@@ -227,13 +874,98 @@ func (a *AdminService) GetVouchersVencidos(dias int) ([]*models.Voucher, error)
 	return a.voucherRepo.GetVouchersVencidos(dias)
 }
 
+// GetRendimientoFlash compara cómo se canjean los vouchers flash frente a los estándar
+func (a *AdminService) GetRendimientoFlash(dias int) ([]models.RendimientoVouchers, error) {
+	rendimiento, err := a.voucherRepo.GetRendimientoPorVigencia(time.Now().AddDate(0, 0, -dias))
+	if err != nil {
+		return nil, err
+	}
+	for i := range rendimiento {
+		if rendimiento[i].Emitidos > 0 {
+			rendimiento[i].TasaCanje = math.Round(float64(rendimiento[i].Canjeados)/float64(rendimiento[i].Emitidos)*1000) / 10
+		}
+		rendimiento[i].MinutosHastaCanje = math.Round(rendimiento[i].MinutosHastaCanje*10) / 10
+	}
+	return rendimiento, nil
+}
+
+// Teléfonos distintos a partir de los cuales un cluster se marca como riesgo alto.
+// Las IPs usan un umbral mayor porque el Wi-Fi del local es compartido por muchos clientes
+const (
+	riesgoAltoClientesHuella = 3
+	riesgoAltoClientesIP     = 8
+)
+
+// GetReporteHuellas correlaciona partidas por huella de dispositivo e IP entre
+// teléfonos distintos para detectar a una misma persona abusando de la promoción
+func (a *AdminService) GetReporteHuellas(dias int, minClientes int) (map[string]interface{}, error) {
+	desde := time.Now().AddDate(0, 0, -dias)
+
+	porHuella, err := a.voucherRepo.GetClustersPorOrigen("huella", desde, minClientes)
+	if err != nil {
+		return nil, err
+	}
+	porIP, err := a.voucherRepo.GetClustersPorOrigen("ip", desde, minClientes)
+	if err != nil {
+		return nil, err
+	}
+
+	riesgoAlto := 0
+	for _, cluster := range porHuella {
+		cluster.Riesgo = nivelRiesgo(cluster.Clientes, riesgoAltoClientesHuella)
+		if cluster.Riesgo == "alto" {
+			riesgoAlto++
+		}
+	}
+	for _, cluster := range porIP {
+		cluster.Riesgo = nivelRiesgo(cluster.Clientes, riesgoAltoClientesIP)
+		if cluster.Riesgo == "alto" {
+			riesgoAlto++
+		}
+	}
+
+	return map[string]interface{}{
+		"desde":        desde.Format("2006-01-02"),
+		"min_clientes": minClientes,
+		"por_huella":   porHuella,
+		"por_ip":       porIP,
+		"riesgo_alto":  riesgoAlto,
+	}, nil
+}
+
+// nivelRiesgo clasifica un cluster según la cantidad de teléfonos distintos
+func nivelRiesgo(clientes, umbralAlto int) string {
+	if clientes >= umbralAlto {
+		return "alto"
+	}
+	return "medio"
+}
+
 // GetReporteVentas genera reporte de "ventas" (vouchers canjeados)
 func (a *AdminService) GetReporteVentas(fechaInicio, fechaFin time.Time) (map[string]interface{}, error) {
-	vouchersCanjeados, err := a.voucherRepo.GetVouchersCanjeadosPorPeriodo(fechaInicio, fechaFin)
+	return a.GetReporteVentasConFiltros(fechaInicio, fechaFin, nil)
+}
+
+// GetReporteVentasConFiltros genera el reporte de ventas aplicando filtros opcionales
+// (tipo de voucher y descuento mínimo)
+func (a *AdminService) GetReporteVentasConFiltros(fechaInicio, fechaFin time.Time, filtros map[string]string) (map[string]interface{}, error) {
+	todos, err := a.voucherRepo.GetVouchersCanjeadosPorPeriodo(fechaInicio, fechaFin)
 	if err != nil {
 		return nil, fmt.Errorf("error obteniendo vouchers canjeados: %w", err)
 	}
 
+	descuentoMinimo, _ := strconv.Atoi(filtros["descuento_minimo"])
+	vouchersCanjeados := make([]*models.Voucher, 0, len(todos))
+	for _, voucher := range todos {
+		if tipo := filtros["tipo"]; tipo != "" && voucher.Tipo != tipo {
+			continue
+		}
+		if voucher.Descuento < descuentoMinimo {
+			continue
+		}
+		vouchersCanjeados = append(vouchersCanjeados, voucher)
+	}
+
 	// Calcular métricas
 	totalVouchers := len(vouchersCanjeados)
 	totalDescuentos := 0
@@ -267,7 +999,7 @@ func (a *AdminService) GetReporteVentas(fechaInicio, fechaFin time.Time) (map[st
 // GetEstadisticasDetalladas obtiene estadísticas detalladas para reportes
 func (a *AdminService) GetEstadisticasDetalladas() (map[string]interface{}, error) {
 	// Estadísticas generales
-	statsGenerales, err := a.clienteRepo.GetEstadisticasGenerales()
+	statsGenerales, err := a.clienteRepo.GetEstadisticasGenerales(a.config.InicioDelDia(time.Now()))
 	if err != nil {
 		return nil, fmt.Errorf("error obteniendo estadísticas generales: %w", err)
 	}
@@ -318,13 +1050,16 @@ func (a *AdminService) GetEstadisticasDetalladas() (map[string]interface{}, erro
 		tendencia = []*models.EstadisticasPorPeriodo{}
 	}
 
+	formato := a.config.Fechas("")
+	ahora := time.Now()
 	return map[string]interface{}{
 		"resumen":           statsGenerales,
 		"vouchers":          vouchersStats,
 		"clientes":          clientesStats,
 		"tendencia_30_dias": tendencia,
 		"whatsapp":          a.whatsappService.GetStatus(),
-		"generado_en":       time.Now().Format("2006-01-02 15:04:05"),
+		"generado_en":       formato.ISO(ahora),
+		"generado_en_texto": formato.FechaHora(ahora),
 	}, nil
 }
 
@@ -335,15 +1070,19 @@ func (a *AdminService) ProcesarPedidoWhatsApp(pedido *models.Pedido) error {
 	// Buscar cliente por teléfono
 	cliente, err := a.clienteRepo.BuscarPorTelefono(pedido.Telefono)
 	if err != nil {
+		// Número sin registrar: el pedido queda sin cliente y se identifica por teléfono
 		log.Printf("⚠️  Cliente no encontrado para pedido: %s", pedido.Telefono)
-		// Cliente nuevo, crear uno básico o manejar como pedido anónimo
+		cliente = nil
 	} else {
-		pedido.ClienteID = cliente.ID
+		pedido.ClienteID = &cliente.ID
 		log.Printf("👤 Pedido asociado al cliente: %s %s", cliente.Nombre, cliente.Apellido)
 	}
 
-	// TODO: Guardar pedido en base de datos cuando se implemente la tabla
-	// Por ahora solo enviamos respuesta automática
+	// Sin el pedido guardado no se confirma la recepción al cliente
+	if err := a.pedidoRepo.Crear(pedido); err != nil {
+		return err
+	}
+	log.Printf("🧾 Pedido #%d de WhatsApp guardado como %s", pedido.ID, pedido.Estado)
 
 	nombreCliente := "Cliente"
 	if cliente != nil {
@@ -362,14 +1101,15 @@ func (a *AdminService) ProcesarPedidoWhatsApp(pedido *models.Pedido) error {
 func (a *AdminService) GetConfiguracionSistema() map[string]interface{} {
 	return map[string]interface{}{
 		"restaurante": "CheeseHouse",
-		"version":     "1.0.0",
+		"version":     version.Obtener().Version,
 		"ambiente":    "desarrollo", // TODO: obtener de config
 		"whatsapp": map[string]interface{}{
 			"configurado": a.whatsappService.GetStatus()["configured"],
 			"estado":      "activo", // TODO: verificar estado real
 		},
 		"base_datos": map[string]interface{}{
-			"tipo":   "MySQL",
+			"tipo":   nombreMotor(a.config.DBDriver),
+			"driver": a.config.DBDriver,
 			"estado": "conectada",
 		},
 		"juego": map[string]interface{}{
@@ -381,37 +1121,60 @@ func (a *AdminService) GetConfiguracionSistema() map[string]interface{} {
 	}
 }
 
-// ExportarDatos exporta datos para backup (formato básico)
-func (a *AdminService) ExportarDatos(tipoExport string) (map[string]interface{}, error) {
+// nombreMotor nombre legible del motor configurado en DB_DRIVER
+func nombreMotor(driver string) string {
+	switch driver {
+	case "postgres":
+		return "PostgreSQL"
+	case "mysql":
+		return "MySQL"
+	}
+	return driver
+}
+
+// ExportarDatos exporta datos para backup (formato básico), de a una página para no
+// armar respuestas gigantes. En el completo la página se aplica a clientes y vouchers
+func (a *AdminService) ExportarDatos(tipoExport string, paginacion models.Paginacion) (map[string]interface{}, error) {
 	resultado := make(map[string]interface{})
 
 	switch tipoExport {
 	case "clientes":
-		clientes, err := a.clienteRepo.ListarTodos()
+		clientes, total, err := a.clienteRepo.ListarTodos(paginacion)
 		if err != nil {
 			return nil, fmt.Errorf("error exportando clientes: %w", err)
 		}
 		resultado["clientes"] = clientes
-		resultado["total"] = len(clientes)
+		resultado["paginacion"] = paginacion.Meta(total)
+
+	case "marketing":
+		// Contactos para herramientas de marketing externas: solo quienes lo aceptan
+		contactos, total, err := a.clienteRepo.ListarContactosMarketing(paginacion)
+		if err != nil {
+			return nil, fmt.Errorf("error exportando contactos: %w", err)
+		}
+		resultado["contactos"] = contactos
+		resultado["paginacion"] = paginacion.Meta(total)
 
 	case "vouchers":
-		vouchers, err := a.voucherRepo.ListarTodos()
+		vouchers, total, err := a.voucherRepo.ListarTodos(paginacion)
 		if err != nil {
 			return nil, fmt.Errorf("error exportando vouchers: %w", err)
 		}
 		resultado["vouchers"] = vouchers
-		resultado["total"] = len(vouchers)
+		resultado["paginacion"] = paginacion.Meta(total)
 
 	case "completo":
 		// Exportar todo
-		clientes, _ := a.clienteRepo.ListarTodos()
-		vouchers, _ := a.voucherRepo.ListarTodos()
+		clientes, totalClientes, _ := a.clienteRepo.ListarTodos(models.Paginacion{Pagina: paginacion.Pagina, TamanoPagina: paginacion.TamanoPagina})
+		vouchers, totalVouchers, _ := a.voucherRepo.ListarTodos(models.Paginacion{Pagina: paginacion.Pagina, TamanoPagina: paginacion.TamanoPagina})
 		estadisticas, _ := a.GetEstadisticasDetalladas()
 
 		resultado["clientes"] = clientes
 		resultado["vouchers"] = vouchers
+		resultado["paginacion_clientes"] = paginacion.Meta(totalClientes)
+		resultado["paginacion_vouchers"] = paginacion.Meta(totalVouchers)
 		resultado["estadisticas"] = estadisticas
-		resultado["exportado_en"] = time.Now().Format("2006-01-02 15:04:05")
+		resultado["exportado_en"] = a.config.Fechas("").ISO(time.Now())
 
 	default:
 		return nil, fmt.Errorf("tipo de export no válido: %s", tipoExport)
@@ -420,11 +1183,120 @@ func (a *AdminService) ExportarDatos(tipoExport string) (map[string]interface{},
 	return resultado, nil
 }
 
+// loteExportacion filas que se leen de la base por vez al exportar una planilla
+const loteExportacion = 500
+
+// ExportarPlanilla escribe en la planilla todos los clientes o vouchers (tipo) que cumplen
+// los filtros del listado, leyéndolos de a lotes para no cargarlos todos en memoria. Las
+// fechas van en la zona horaria del restaurante
+func (a *AdminService) ExportarPlanilla(tipo string, filtros map[string]interface{}, escritor planilla.Escritor) (int, error) {
+	loc := a.config.GetLocation()
+	local := func(t *time.Time) *time.Time {
+		if t == nil {
+			return nil
+		}
+		enZona := t.In(loc)
+		return &enZona
+	}
+	filas := 0
+
+	switch tipo {
+	case "clientes":
+		if err := escritor.Fila("id", "nombre", "apellido", "telefono", "email", "idioma", "estado", "tipo_cliente",
+			"total_juegos", "juegos_ganados", "juegos_perdidos", "fecha_registro", "fecha_ultimo_juego", "muro_ganadores"); err != nil {
+			return 0, err
+		}
+		err := a.clienteRepo.RecorrerConFiltros(filtros, loteExportacion, func(clientes []*models.Cliente) error {
+			for _, cliente := range clientes {
+				email := ""
+				if cliente.Email != nil {
+					email = *cliente.Email
+				}
+				if err := escritor.Fila(cliente.ID, cliente.Nombre, cliente.Apellido, cliente.Telefono, email, cliente.Idioma,
+					cliente.Estado, cliente.TipoCliente, cliente.TotalJuegos, cliente.JuegosGanados, cliente.JuegosPerdidos,
+					local(&cliente.FechaRegistro), local(cliente.FechaUltimoJuego), siNo(cliente.ConsentimientoMuro)); err != nil {
+					return err
+				}
+				filas++
+			}
+			return nil
+		})
+		return filas, err
+
+	case "vouchers":
+		if err := escritor.Fila("id", "codigo", "cliente_id", "cliente", "telefono", "tipo", "descuento", "fecha_emision",
+			"fecha_vencimiento", "usado", "fecha_uso", "canjeado_por", "sucursal", "sucursal_canje", "diferencia_segundos"); err != nil {
+			return 0, err
+		}
+		err := a.voucherRepo.RecorrerConFiltros(filtros, loteExportacion, func(vouchers []*models.Voucher) error {
+			for _, voucher := range vouchers {
+				var nombre, telefono, canjeadoPor string
+				if voucher.Cliente != nil {
+					nombre = strings.TrimSpace(voucher.Cliente.Nombre + " " + voucher.Cliente.Apellido)
+					telefono = voucher.Cliente.Telefono
+				}
+				if voucher.UsuarioQueCanje != nil {
+					canjeadoPor = voucher.UsuarioQueCanje.Nombre
+				}
+				var diferencia interface{}
+				if voucher.DiferenciaSegundos != nil {
+					diferencia = *voucher.DiferenciaSegundos
+				}
+				if err := escritor.Fila(voucher.ID, voucher.Codigo, voucher.ClienteID, nombre, telefono, voucher.Tipo,
+					voucher.Descuento, local(&voucher.FechaEmision), local(&voucher.FechaVencimiento), siNo(voucher.Usado),
+					local(voucher.FechaUso), canjeadoPor, voucher.Sucursal, voucher.SucursalCanje, diferencia); err != nil {
+					return err
+				}
+				filas++
+			}
+			return nil
+		})
+		return filas, err
+
+	default:
+		return 0, fmt.Errorf("tipo de export no válido: %s", tipo)
+	}
+}
+
+// siNo texto de un campo booleano en las planillas
+func siNo(valor bool) string {
+	if valor {
+		return "sí"
+	}
+	return "no"
+}
+
 // LimpiarVouchersVencidos marca vouchers vencidos como tal (mantenimiento)
 func (a *AdminService) LimpiarVouchersVencidos() (int, error) {
 	return a.voucherRepo.MarcarVouchersVencidos()
 }
 
+// GetCuotaWhatsApp obtiene los límites de envío de WhatsApp y su consumo
+func (a *AdminService) GetCuotaWhatsApp() (*models.CuotaWhatsApp, error) {
+	return a.whatsappService.GetCuota()
+}
+
+// GetEstadoPlantillasWhatsApp último estado de aprobación conocido de los templates
+func (a *AdminService) GetEstadoPlantillasWhatsApp() []models.EstadoPlantilla {
+	return a.whatsappService.GetEstadoPlantillas()
+}
+
+// GetEstadisticasMensajes resume los envíos de WhatsApp de los últimos días
+func (a *AdminService) GetEstadisticasMensajes(dias int) (*models.EstadisticasEnvios, error) {
+	desde := a.config.InicioDelDia(time.Now()).AddDate(0, 0, -(dias - 1))
+	return a.whatsappService.GetEstadisticasEnvios(desde)
+}
+
+// GetCircuitoPremios obtiene el estado del corte por tasa de victorias
+func (a *AdminService) GetCircuitoPremios() models.EstadoCircuitoPremios {
+	return a.circuito.Estado()
+}
+
+// RestablecerCircuitoPremios reanuda la emisión normal luego de revisar el incidente
+func (a *AdminService) RestablecerCircuitoPremios(usuarioID uint) models.EstadoCircuitoPremios {
+	return a.circuito.Restablecer(usuarioID)
+}
+
 // GetAlertasOperativas obtiene alertas para el dashboard
 func (a *AdminService) GetAlertasOperativas() []map[string]interface{} {
 	var alertas []map[string]interface{}
@@ -451,6 +1323,83 @@ func (a *AdminService) GetAlertasOperativas() []map[string]interface{} {
 		})
 	}
 
+	// Cuota de WhatsApp por agotarse (menos del 10% disponible)
+	if cuota, err := a.whatsappService.GetCuota(); err == nil {
+		if cuota.ParteMarketing < 1 {
+			descripcion := fmt.Sprintf("Calidad del número: %s. Las campañas pueden usar hasta el %.0f%% del límite diario", cuota.Calidad, cuota.ParteMarketing*100)
+			if cuota.ParteMarketing == 0 {
+				descripcion = fmt.Sprintf("Calidad del número: %s. Las campañas de marketing están pausadas", cuota.Calidad)
+			}
+			alertas = append(alertas, map[string]interface{}{
+				"tipo":        "warning",
+				"titulo":      "Marketing de WhatsApp limitado por calidad del número",
+				"descripcion": descripcion,
+				"accion":      "revisar_calidad_mensajes",
+			})
+		}
+		if cuota.DisponiblesHoy >= 0 && cuota.DisponiblesHoy < cuota.LimiteDiario/10 {
+			alertas = append(alertas, map[string]interface{}{
+				"tipo":        "warning",
+				"titulo":      "Límite diario de WhatsApp casi agotado",
+				"descripcion": fmt.Sprintf("Quedan %d de %d destinatarios en 24h", cuota.DisponiblesHoy, cuota.LimiteDiario),
+				"accion":      "reducir_volumen_envios",
+			})
+		}
+		if cuota.DisponiblesMes >= 0 && cuota.DisponiblesMes < cuota.LimiteMensual/10 {
+			alertas = append(alertas, map[string]interface{}{
+				"tipo":        "warning",
+				"titulo":      "Presupuesto mensual de WhatsApp casi agotado",
+				"descripcion": fmt.Sprintf("Quedan %d de %d conversaciones este mes", cuota.DisponiblesMes, cuota.LimiteMensual),
+				"accion":      "revisar_presupuesto_whatsapp",
+			})
+		}
+	}
+
+	// Circuito de premios activado por tasa de victorias anómala
+	if circuito := a.circuito.Estado(); circuito.Estado != CircuitoNormal {
+		descripcion := fmt.Sprintf("%.0f%% de victorias en las últimas %d partidas (máximo %.0f%%), tolerancia reducida a %.2fs",
+			circuito.TasaActivacion*100, circuito.Ventana, circuito.TasaMaxima*100, circuito.Tolerancia)
+		if circuito.Estado == CircuitoPausado {
+			descripcion = fmt.Sprintf("%.0f%% de victorias en las últimas %d partidas (máximo %.0f%%), emisión de vouchers pausada",
+				circuito.TasaActivacion*100, circuito.Ventana, circuito.TasaMaxima*100)
+		}
+		alertas = append(alertas, map[string]interface{}{
+			"tipo":        "error",
+			"titulo":      "Tasa de victorias anómala",
+			"descripcion": descripcion,
+			"accion":      "revisar_circuito_premios",
+		})
+	}
+
+	// Pico de emisión de vouchers sobre la línea base
+	if pico := a.picoEmision.Estado(); pico.Activo {
+		alertas = append(alertas, map[string]interface{}{
+			"tipo":   "error",
+			"titulo": "Pico de emisión de vouchers",
+			"descripcion": fmt.Sprintf("%d vouchers en la última hora contra un promedio de %.1f por hora",
+				pico.EmitidosUltimaHora, pico.PromedioPorHora),
+			"accion": "revisar_emision_vouchers",
+		})
+	}
+
+	// Conversaciones de WhatsApp fuera de SLA
+	if sla, err := a.conversaciones.GetResumenSLA(); err == nil && len(sla.Incumplimientos) > 0 {
+		alertas = append(alertas, map[string]interface{}{
+			"tipo":   "warning",
+			"titulo": "Pedidos de WhatsApp fuera de SLA",
+			"descripcion": fmt.Sprintf("%d sin responder en %d min y %d sin resolver en %d min (%d sin asignar)",
+				sla.IncumplenRespuesta, sla.MinutosObjetivoRespuesta,
+				sla.IncumplenResolucion, sla.MinutosObjetivoResolucion, sla.SinAsignar),
+			"accion": "revisar_conversaciones",
+		})
+	}
+
+	// Errores recientes de la API de WhatsApp (últimas 24 horas)
+	alertas = append(alertas, a.whatsappService.GetAlertasErrores(time.Now().Add(-24*time.Hour))...)
+
+	// Templates de WhatsApp rechazados, pausados o deshabilitados en Meta
+	alertas = append(alertas, a.whatsappService.GetAlertasPlantillas()...)
+
 	// Verificar clientes que necesitan aprobación
 	clientesPendientes, err := a.GetClientesPendientesAprobacion()
 	if err == nil && len(clientesPendientes) > 0 {
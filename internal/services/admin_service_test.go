@@ -0,0 +1,470 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+
+	"CheeseHouse/internal/config"
+	"CheeseHouse/internal/models"
+	"CheeseHouse/internal/repository"
+)
+
+const codigoDePrueba = "CHPRUEBA1"
+
+// vouchersDePrueba guarda un único voucher en memoria. Con lecturaVieja BuscarParaCanje lo
+// devuelve sin usar aunque ya lo esté, como una lectura hecha antes de otro canje
+type vouchersDePrueba struct {
+	repository.VoucherRepository
+	mu           sync.Mutex
+	voucher      models.Voucher
+	canjes       int
+	lecturaVieja bool
+}
+
+func (r *vouchersDePrueba) BuscarParaCanje(codigo string) (*models.Voucher, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if codigo != r.voucher.Codigo {
+		return nil, fmt.Errorf("voucher con código %s no encontrado", codigo)
+	}
+	copia := r.voucher
+	if r.lecturaVieja {
+		copia.Usado = false
+	}
+	return &copia, nil
+}
+
+func (r *vouchersDePrueba) Canjear(voucher *models.Voucher) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.voucher.Usado {
+		return false, nil
+	}
+	r.voucher.Usado = true
+	r.voucher.UsuarioCanje = voucher.UsuarioCanje
+	r.voucher.AprobadoPor = voucher.AprobadoPor
+	r.canjes++
+	return true, nil
+}
+
+// diasSinCanjeDePrueba fechas bloqueadas fijas
+type diasSinCanjeDePrueba struct {
+	repository.DiaSinCanjeRepository
+	dias []*models.DiaSinCanje
+}
+
+func (r *diasSinCanjeDePrueba) ListarEntre(desde, hasta time.Time) ([]*models.DiaSinCanje, error) {
+	return r.dias, nil
+}
+
+// canjesEmpleadoDePrueba canjes del día fijos y rechazos registrados
+type canjesEmpleadoDePrueba struct {
+	repository.CanjeEmpleadoRepository
+	mu       sync.Mutex
+	canjes   int
+	intentos []models.IntentoCanje
+}
+
+func (r *canjesEmpleadoDePrueba) ContarCanjes(usuarioID uint, desde time.Time) (int, error) {
+	return r.canjes, nil
+}
+
+func (r *canjesEmpleadoDePrueba) RegistrarIntento(intento *models.IntentoCanje) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.intentos = append(r.intentos, *intento)
+	return nil
+}
+
+// motivos motivos de los rechazos registrados, en orden
+func (r *canjesEmpleadoDePrueba) motivos() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	motivos := make([]string, 0, len(r.intentos))
+	for _, intento := range r.intentos {
+		motivos = append(motivos, intento.Motivo)
+	}
+	return motivos
+}
+
+// usuariosDePrueba usuarios en memoria con su rol cargado
+type usuariosDePrueba struct {
+	repository.UsuarioRepository
+	usuarios []*models.Usuario
+}
+
+func (r *usuariosDePrueba) BuscarPorID(id uint) (*models.Usuario, error) {
+	for _, usuario := range r.usuarios {
+		if usuario.ID == id {
+			return usuario, nil
+		}
+	}
+	return nil, fmt.Errorf("usuario con ID %d no encontrado", id)
+}
+
+func (r *usuariosDePrueba) BuscarPorEmail(email string) (*models.Usuario, error) {
+	for _, usuario := range r.usuarios {
+		if usuario.Email == email {
+			return usuario, nil
+		}
+	}
+	return nil, fmt.Errorf("usuario con email %s no encontrado", email)
+}
+
+func (r *usuariosDePrueba) Actualizar(usuario *models.Usuario) error {
+	return nil
+}
+
+// notificacionesDePrueba descarta las alertas del panel
+type notificacionesDePrueba struct {
+	repository.NotificacionRepository
+}
+
+func (r *notificacionesDePrueba) Crear(notificacion *models.Notificacion) error {
+	return nil
+}
+
+// entornoCanje AdminService con lo justo para canjear en memoria
+type entornoCanje struct {
+	t        *testing.T
+	config   *config.Config
+	admin    *AdminService
+	vouchers *vouchersDePrueba
+	dias     *diasSinCanjeDePrueba
+	canjes   *canjesEmpleadoDePrueba
+	usuarios *usuariosDePrueba
+}
+
+// nuevoEntornoCanje arma el entorno con un voucher vigente del 10% y un empleado (ID 1)
+// sin tope de canjes
+func nuevoEntornoCanje(t *testing.T) *entornoCanje {
+	t.Helper()
+
+	// El nombre del cliente se busca en la base después del canje; sin filas esperadas
+	// la búsqueda falla y la respuesta usa "Cliente"
+	sqlDB, _, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("error creando sqlmock: %v", err)
+	}
+	t.Cleanup(func() { sqlDB.Close() })
+	db, err := gorm.Open(mysql.New(mysql.Config{Conn: sqlDB, SkipInitializeWithVersion: true}),
+		&gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatalf("error abriendo gorm: %v", err)
+	}
+
+	sinLimite := 0
+	e := &entornoCanje{
+		t:      t,
+		config: &config.Config{},
+		vouchers: &vouchersDePrueba{voucher: models.Voucher{
+			ID:               1,
+			Codigo:           codigoDePrueba,
+			Descuento:        10,
+			FechaVencimiento: time.Now().AddDate(0, 0, 7),
+		}},
+		dias:   &diasSinCanjeDePrueba{},
+		canjes: &canjesEmpleadoDePrueba{},
+		usuarios: &usuariosDePrueba{usuarios: []*models.Usuario{
+			{ID: 1, Nombre: "Caja", Email: "caja@cheesehouse.test", Activo: true, LimiteCanjes: &sinLimite, Rol: &models.Rol{Nombre: "empleado"}},
+		}},
+	}
+
+	estado := repository.NewEstadoCompartidoEnMemoria()
+	e.admin = &AdminService{
+		config:         e.config,
+		clienteRepo:    *repository.NewClienteRepository(db),
+		diasSinCanje:   NewDiaSinCanjeService(e.config, e.dias),
+		canjesEmpleado: NewCanjeEmpleadoService(e.config, e.canjes, e.usuarios, NewNotificacionService(e.config, &notificacionesDePrueba{}), estado),
+		auth:           NewAuthService(e.usuarios, "secreto-de-prueba", estado),
+	}
+	return e
+}
+
+// agregarEncargado suma un administrador con ese PIN de aprobación
+func (e *entornoCanje) agregarEncargado(id uint, email, pin string) {
+	e.t.Helper()
+	clave, err := bcrypt.GenerateFromPassword([]byte("clave-segura"), bcrypt.MinCost)
+	if err != nil {
+		e.t.Fatalf("error hasheando la contraseña: %v", err)
+	}
+	e.usuarios.usuarios = append(e.usuarios.usuarios, &models.Usuario{
+		ID: id, Nombre: "Encargado", Email: email, PasswordHash: string(clave), Activo: true, Rol: &models.Rol{Nombre: "admin"},
+	})
+	if err := e.admin.auth.EstablecerPIN(id, "clave-segura", pin); err != nil {
+		e.t.Fatalf("error fijando el PIN: %v", err)
+	}
+}
+
+// canjear corre el canje como lo hace CanjearVoucher dentro de la transacción
+func (e *entornoCanje) canjear(empleadoID uint, aprobacion models.AprobacionCanje) *models.CanjearVoucherResponse {
+	e.t.Helper()
+	tx := &repository.Transaccion{Vouchers: e.vouchers}
+	respuesta, err := e.admin.canjearEnTransaccion(tx, codigoDePrueba, empleadoID, "", aprobacion)
+	if err != nil {
+		e.t.Fatalf("error inesperado canjeando: %v", err)
+	}
+	return respuesta
+}
+
+func TestCanjearVoucherUnaSolaVez(t *testing.T) {
+	e := nuevoEntornoCanje(t)
+
+	if respuesta := e.canjear(1, models.AprobacionCanje{}); !respuesta.Success {
+		t.Fatalf("el primer canje falló: %s", respuesta.Message)
+	}
+	respuesta := e.canjear(1, models.AprobacionCanje{})
+	if respuesta.Success || respuesta.Message != "Este voucher ya fue utilizado" {
+		t.Fatalf("el segundo canje = %+v, se esperaba el rechazo por usado", respuesta)
+	}
+	if e.vouchers.canjes != 1 {
+		t.Errorf("canjes guardados = %d, se esperaba 1", e.vouchers.canjes)
+	}
+	if motivos := e.canjes.motivos(); len(motivos) != 1 || motivos[0] != "usado" {
+		t.Errorf("rechazos registrados = %v, se esperaba [usado]", motivos)
+	}
+}
+
+func TestCanjearVoucherSimultaneo(t *testing.T) {
+	e := nuevoEntornoCanje(t)
+
+	// fila hace de SELECT ... FOR UPDATE: cada caja la toma al buscar el voucher y la
+	// suelta al confirmar la transacción
+	var fila sync.Mutex
+	const cajas = 8
+	respuestas := make(chan *models.CanjearVoucherResponse, cajas)
+	var wg sync.WaitGroup
+	for i := 0; i < cajas; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			fila.Lock()
+			defer fila.Unlock()
+			tx := &repository.Transaccion{Vouchers: e.vouchers}
+			respuesta, err := e.admin.canjearEnTransaccion(tx, codigoDePrueba, 1, "", models.AprobacionCanje{})
+			if err != nil {
+				t.Errorf("error inesperado canjeando: %v", err)
+				return
+			}
+			respuestas <- respuesta
+		}()
+	}
+	wg.Wait()
+	close(respuestas)
+
+	exitos := 0
+	for respuesta := range respuestas {
+		if respuesta.Success {
+			exitos++
+		} else if respuesta.Message != "Este voucher ya fue utilizado" {
+			t.Errorf("rechazo inesperado: %s", respuesta.Message)
+		}
+	}
+	if exitos != 1 || e.vouchers.canjes != 1 {
+		t.Errorf("canjes exitosos = %d, guardados = %d; se esperaba uno solo", exitos, e.vouchers.canjes)
+	}
+}
+
+func TestCanjearVoucherOtroCanjeSeAdelanto(t *testing.T) {
+	e := nuevoEntornoCanje(t)
+	// Otra caja lo canjeó entre la búsqueda y el guardado: el UPDATE condicional no
+	// afecta filas y el canje se rechaza en lugar de pisar al anterior
+	e.vouchers.voucher.Usado = true
+	e.vouchers.lecturaVieja = true
+
+	respuesta := e.canjear(1, models.AprobacionCanje{})
+	if respuesta.Success || !strings.Contains(respuesta.Message, "ya fue utilizado") {
+		t.Fatalf("canje = %+v, se esperaba el rechazo por usado", respuesta)
+	}
+	if e.vouchers.canjes != 0 {
+		t.Errorf("canjes guardados = %d, se esperaba 0", e.vouchers.canjes)
+	}
+}
+
+func TestCanjearVoucherDiaSinCanje(t *testing.T) {
+	casos := []struct {
+		nombre      string
+		bloqueados  int // Días bloqueados seguidos desde hoy
+		vencimiento int // Días hasta que vence el voucher
+		mensaje     string
+		proximo     int // Días hasta el próximo canje habilitado
+	}{
+		{"se puede usar más adelante", 1, 7, "Se puede usar desde el", 1},
+		{"bloqueo de varios días", 3, 7, "Se puede usar desde el", 3},
+		{"vence antes del próximo día habilitado", 3, 2, "antes del próximo día habilitado", 3},
+	}
+
+	for _, caso := range casos {
+		t.Run(caso.nombre, func(t *testing.T) {
+			e := nuevoEntornoCanje(t)
+			hoy := e.config.InicioDelDia(time.Now())
+			for i := 0; i < caso.bloqueados; i++ {
+				e.dias.dias = append(e.dias.dias, &models.DiaSinCanje{Fecha: hoy.AddDate(0, 0, i), Motivo: "Inventario"})
+			}
+			e.vouchers.voucher.FechaVencimiento = hoy.AddDate(0, 0, caso.vencimiento)
+
+			respuesta := e.canjear(1, models.AprobacionCanje{})
+			if respuesta.Success {
+				t.Fatal("se canjeó un voucher en un día sin canje")
+			}
+			if !strings.Contains(respuesta.Message, "Inventario") || !strings.Contains(respuesta.Message, caso.mensaje) {
+				t.Errorf("mensaje = %q, se esperaba el motivo y %q", respuesta.Message, caso.mensaje)
+			}
+			if esperado := hoy.AddDate(0, 0, caso.proximo).Format("2006-01-02"); respuesta.ProximoCanje != esperado {
+				t.Errorf("próximo canje = %s, se esperaba %s", respuesta.ProximoCanje, esperado)
+			}
+			if e.vouchers.canjes != 0 {
+				t.Errorf("canjes guardados = %d, se esperaba 0", e.vouchers.canjes)
+			}
+			if motivos := e.canjes.motivos(); len(motivos) != 1 || motivos[0] != "dia_sin_canje" {
+				t.Errorf("rechazos registrados = %v, se esperaba [dia_sin_canje]", motivos)
+			}
+		})
+	}
+}
+
+func TestCanjearVoucherDiaBloqueadoDeOtraFecha(t *testing.T) {
+	e := nuevoEntornoCanje(t)
+	manana := e.config.InicioDelDia(time.Now()).AddDate(0, 0, 1)
+	e.dias.dias = []*models.DiaSinCanje{{Fecha: manana, Motivo: "Feriado"}}
+
+	if respuesta := e.canjear(1, models.AprobacionCanje{}); !respuesta.Success {
+		t.Fatalf("un bloqueo de mañana frenó el canje de hoy: %s", respuesta.Message)
+	}
+}
+
+func TestCanjearVoucherTopeDelEmpleado(t *testing.T) {
+	tres, cero := 3, 0
+	casos := []struct {
+		nombre      string
+		topeGeneral int  // REDEMPTION_DAILY_CAP
+		propio      *int // Tope del empleado; nil = el general
+		canjesHoy   int
+		canjea      bool
+	}{
+		{"debajo del tope general", 5, nil, 4, true},
+		{"llegó al tope general", 5, nil, 5, false},
+		{"el tope propio pisa al general", 5, &tres, 3, false},
+		{"tope propio cero es sin límite", 5, &cero, 50, true},
+		{"sin tope general", 0, nil, 50, true},
+	}
+
+	for _, caso := range casos {
+		t.Run(caso.nombre, func(t *testing.T) {
+			e := nuevoEntornoCanje(t)
+			e.config.Redemption.DailyCapPerEmployee = caso.topeGeneral
+			e.usuarios.usuarios[0].LimiteCanjes = caso.propio
+			e.canjes.canjes = caso.canjesHoy
+
+			respuesta := e.canjear(1, models.AprobacionCanje{})
+			if respuesta.Success != caso.canjea {
+				t.Fatalf("canjeado = %t (%s), se esperaba %t", respuesta.Success, respuesta.Message, caso.canjea)
+			}
+			if caso.canjea {
+				return
+			}
+			if !strings.Contains(respuesta.Message, "Alcanzaste el tope") {
+				t.Errorf("mensaje = %q", respuesta.Message)
+			}
+			if e.vouchers.canjes != 0 {
+				t.Errorf("canjes guardados = %d, se esperaba 0", e.vouchers.canjes)
+			}
+			if motivos := e.canjes.motivos(); len(motivos) != 1 || motivos[0] != "limite" {
+				t.Errorf("rechazos registrados = %v, se esperaba [limite]", motivos)
+			}
+		})
+	}
+}
+
+func TestCanjearVoucherConAprobacion(t *testing.T) {
+	e := nuevoEntornoCanje(t)
+	e.config.Redemption.ApprovalAbove = 20
+	e.vouchers.voucher.Descuento = 30
+	// Dos encargados con el mismo PIN: aprueba el del email indicado
+	e.agregarEncargado(2, "ana@cheesehouse.test", "1234")
+	e.agregarEncargado(3, "luis@cheesehouse.test", "1234")
+
+	respuesta := e.canjear(1, models.AprobacionCanje{})
+	if respuesta.Success || !respuesta.RequiereAprobacion {
+		t.Fatalf("sin aprobación = %+v, se esperaba que la pida", respuesta)
+	}
+
+	respuesta = e.canjear(1, models.AprobacionCanje{AprobadorEmail: "luis@cheesehouse.test", PINAprobacion: "1234"})
+	if !respuesta.Success {
+		t.Fatalf("el canje aprobado falló: %s", respuesta.Message)
+	}
+	if aprobador := e.vouchers.voucher.AprobadoPor; aprobador == nil || *aprobador != 3 {
+		t.Errorf("aprobado por = %v, se esperaba el encargado 3", aprobador)
+	}
+}
+
+func TestCanjearVoucherBloqueoPorPINIncorrecto(t *testing.T) {
+	e := nuevoEntornoCanje(t)
+	e.config.Redemption.ApprovalAbove = 20
+	e.vouchers.voucher.Descuento = 30
+	e.agregarEncargado(2, "ana@cheesehouse.test", "1234")
+	sinLimite := 0
+	e.usuarios.usuarios = append(e.usuarios.usuarios, &models.Usuario{ID: 4, Nombre: "Otra caja", Activo: true, LimiteCanjes: &sinLimite, Rol: &models.Rol{Nombre: "empleado"}})
+
+	correcta := models.AprobacionCanje{AprobadorEmail: "ana@cheesehouse.test", PINAprobacion: "1234"}
+	incorrectas := []models.AprobacionCanje{
+		{AprobadorEmail: "ana@cheesehouse.test", PINAprobacion: "0000"},
+		{AprobadorEmail: "nadie@cheesehouse.test", PINAprobacion: "1234"}, // Email que no es de un encargado
+		{AprobadorEmail: "caja@cheesehouse.test", PINAprobacion: "1234"},  // Empleado sin PIN
+	}
+	for i := 0; i < maxFallosPIN; i++ {
+		aprobacion := incorrectas[i%len(incorrectas)]
+		respuesta := e.canjear(1, aprobacion)
+		if respuesta.Success || respuesta.Message != "Email o PIN de encargado incorrecto" {
+			t.Fatalf("intento %d con %+v = %+v, se esperaba el rechazo", i+1, aprobacion, respuesta)
+		}
+	}
+
+	// Bloqueado: ni el PIN correcto pasa
+	respuesta := e.canjear(1, correcta)
+	if respuesta.Success || !strings.Contains(respuesta.Message, "Demasiados PIN incorrectos") {
+		t.Fatalf("canje del empleado bloqueado = %+v", respuesta)
+	}
+	if e.vouchers.canjes != 0 {
+		t.Errorf("canjes guardados = %d, se esperaba 0", e.vouchers.canjes)
+	}
+
+	// El bloqueo es del empleado, no del voucher ni del encargado
+	if respuesta := e.canjear(4, correcta); !respuesta.Success {
+		t.Errorf("otra caja no pudo canjear: %s", respuesta.Message)
+	}
+}
+
+func TestCanjearVoucherAprobacionCorrectaLimpiaFallos(t *testing.T) {
+	e := nuevoEntornoCanje(t)
+	e.config.Redemption.ApprovalAbove = 20
+	e.vouchers.voucher.Descuento = 30
+	e.agregarEncargado(2, "ana@cheesehouse.test", "1234")
+	incorrecta := models.AprobacionCanje{AprobadorEmail: "ana@cheesehouse.test", PINAprobacion: "9999"}
+
+	for i := 0; i < maxFallosPIN-1; i++ {
+		e.canjear(1, incorrecta)
+	}
+	if respuesta := e.canjear(1, models.AprobacionCanje{AprobadorEmail: "ana@cheesehouse.test", PINAprobacion: "1234"}); !respuesta.Success {
+		t.Fatalf("el canje aprobado falló: %s", respuesta.Message)
+	}
+	e.vouchers.voucher.Usado = false
+	for i := 0; i < maxFallosPIN-1; i++ {
+		e.canjear(1, incorrecta)
+	}
+	if motivos := e.canjes.motivos(); len(motivos) != 2*(maxFallosPIN-1) {
+		t.Fatalf("rechazos registrados = %v, se esperaban %d por aprobación", motivos, 2*(maxFallosPIN-1))
+	}
+	if e.admin.canjesEmpleado.PINBloqueado(1) {
+		t.Error("el empleado quedó bloqueado aunque una aprobación correcta limpió los fallos")
+	}
+}
@@ -0,0 +1,69 @@
+package services
+
+import (
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"CheeseHouse/internal/config"
+	"CheeseHouse/internal/models"
+	"CheeseHouse/internal/repository"
+)
+
+// ArchivoService mantiene chicas las tablas de vouchers y de partidas moviendo los
+// registros viejos ya cerrados a vouchers_archivo y juegos_archivo
+type ArchivoService struct {
+	config      *config.Config
+	archivoRepo repository.ArchivoRepository
+
+	// Evita dos corridas simultáneas (programada y manual)
+	mu sync.Mutex
+}
+
+// NewArchivoService crea una nueva instancia del servicio de archivado
+func NewArchivoService(cfg *config.Config, archivoRepo repository.ArchivoRepository) *ArchivoService {
+	return &ArchivoService{
+		config:      cfg,
+		archivoRepo: archivoRepo,
+	}
+}
+
+// Archivar mueve al archivo los vouchers canjeados o vencidos emitidos hace más de
+// ARCHIVE_AFTER_DAYS días y las partidas jugadas antes de esa fecha
+func (s *ArchivoService) Archivar() (*models.ResultadoArchivo, error) {
+	if s.config.ArchiveAfterDays <= 0 {
+		return nil, fmt.Errorf("el archivado está desactivado (ARCHIVE_AFTER_DAYS=0)")
+	}
+	if !s.mu.TryLock() {
+		return nil, fmt.Errorf("ya hay un archivado en curso")
+	}
+	defer s.mu.Unlock()
+
+	inicio := time.Now()
+	resultado := &models.ResultadoArchivo{
+		AntesDe: inicio.AddDate(0, 0, -s.config.ArchiveAfterDays),
+	}
+
+	vouchers, envios, err := s.archivoRepo.ArchivarVouchers(resultado.AntesDe, s.config.DBBatchSize)
+	resultado.Vouchers = vouchers
+	resultado.EnviosDesvinculados = envios
+	if err == nil {
+		resultado.Juegos, err = s.archivoRepo.ArchivarJuegos(resultado.AntesDe, s.config.DBBatchSize)
+	}
+	resultado.Duracion = time.Since(inicio).Round(time.Millisecond).String()
+	if err != nil {
+		return resultado, err
+	}
+
+	if vouchers > 0 || resultado.Juegos > 0 {
+		log.Printf("🗄️  %d vouchers y %d partidas anteriores al %s movidos al archivo (%s)",
+			vouchers, resultado.Juegos, resultado.AntesDe.Format("2006-01-02"), resultado.Duracion)
+	}
+	return resultado, nil
+}
+
+// BuscarVouchers consulta el archivo con los mismos filtros que el listado de vouchers
+func (s *ArchivoService) BuscarVouchers(filtros map[string]interface{}) ([]*models.Voucher, error) {
+	return s.archivoRepo.BuscarVouchers(filtros)
+}
@@ -0,0 +1,66 @@
+package services
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"CheeseHouse/internal/almacenamiento"
+	"CheeseHouse/internal/models"
+)
+
+// ArtefactoService publica los archivos que generan los distintos módulos (reportes
+// programados, exportaciones, QR de mesas) en el almacenamiento configurado y entrega
+// links de descarga que vencen
+type ArtefactoService struct {
+	almacenamiento almacenamiento.Almacenamiento
+	vigencia       time.Duration
+}
+
+// NewArtefactoService crea una nueva instancia del servicio de archivos generados
+func NewArtefactoService(alm almacenamiento.Almacenamiento, vigencia time.Duration) *ArtefactoService {
+	return &ArtefactoService{
+		almacenamiento: alm,
+		vigencia:       vigencia,
+	}
+}
+
+// Publicar guarda el archivo como carpeta/nombre y retorna su link de descarga
+func (a *ArtefactoService) Publicar(carpeta, nombre string, contenido []byte, tipoContenido string) (*models.ArchivoGenerado, error) {
+	clave := carpeta + "/" + nombre
+	if err := a.almacenamiento.Guardar(clave, contenido, tipoContenido); err != nil {
+		return nil, fmt.Errorf("error guardando archivo generado: %w", err)
+	}
+
+	log.Printf("🗄️ Archivo generado guardado: %s (%d bytes)", clave, len(contenido))
+	return a.Link(clave)
+}
+
+// Link arma un nuevo link de descarga para un archivo ya guardado
+func (a *ArtefactoService) Link(clave string) (*models.ArchivoGenerado, error) {
+	vence := time.Now().Add(a.vigencia)
+	url, err := a.almacenamiento.URLFirmada(clave, a.vigencia)
+	if err != nil {
+		return nil, fmt.Errorf("error generando link de descarga: %w", err)
+	}
+	return &models.ArchivoGenerado{
+		Clave: clave,
+		URL:   url,
+		Vence: vence,
+	}, nil
+}
+
+// Leer valida un link del almacenamiento local y retorna el archivo. Con S3 los links
+// apuntan directo al bucket, así que la API no sirve descargas
+func (a *ArtefactoService) Leer(clave, expira, firma string) ([]byte, error) {
+	local, ok := a.almacenamiento.(*almacenamiento.Local)
+	if !ok {
+		return nil, almacenamiento.ErrNoEncontrado
+	}
+	return local.Leer(clave, expira, firma)
+}
+
+// Descripcion resume dónde se guardan los archivos, para el log de arranque
+func (a *ArtefactoService) Descripcion() string {
+	return a.almacenamiento.Descripcion()
+}
@@ -1,9 +1,14 @@
 package services
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"log"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/golang-jwt/jwt/v4"
@@ -18,6 +23,28 @@ type AuthService struct {
 	usuarioRepo repository.UsuarioRepository
 	jwtSecret   string
 	expiration  time.Duration
+
+	estado repository.EstadoCompartidoRepository // Tokens revocados, hasta que vencen solos
+
+	muPermisos sync.RWMutex
+	permisos   map[uint]permisosCacheados // Rol -> permisos interpretados
+}
+
+// permisosCacheados permisos de un rol ya interpretados y hasta cuándo se reutilizan
+type permisosCacheados struct {
+	permisos models.Permisos
+	hasta    time.Time
+}
+
+// vigenciaPermisos tiempo que se reutilizan los permisos de un rol sin volver a leerlos;
+// los cambios hechos en esta instancia se ven al instante
+const vigenciaPermisos = time.Minute
+
+// rolesBase roles que necesita el sistema (RequireAdmin compara por nombre); los mismos
+// que siembran las migraciones 0001 y 0004
+var rolesBase = []models.Rol{
+	{Nombre: "admin", Permisos: `{"todo": true}`},
+	{Nombre: "empleado", Permisos: `{"canjear": true, "pedidos": true, "clientes": true, "conversaciones": true}`},
 }
 
 // Claims estructura para JWT tokens
@@ -31,11 +58,13 @@ type Claims struct {
 }
 
 // NewAuthService crea una nueva instancia del servicio de autenticación
-func NewAuthService(usuarioRepo repository.UsuarioRepository, jwtSecret string) *AuthService {
+func NewAuthService(usuarioRepo repository.UsuarioRepository, jwtSecret string, estado repository.EstadoCompartidoRepository) *AuthService {
 	return &AuthService{
 		usuarioRepo: usuarioRepo,
 		jwtSecret:   jwtSecret,
 		expiration:  24 * time.Hour, // 24 horas por defecto
+		estado:      estado,
+		permisos:    make(map[uint]permisosCacheados),
 	}
 }
 
@@ -150,6 +179,9 @@ func (a *AuthService) ValidateToken(tokenString string) (*Claims, error) {
 	}
 
 	if claims, ok := token.Claims.(*Claims); ok && token.Valid {
+		if a.revocado(tokenString) {
+			return nil, errors.New("token revocado")
+		}
 		return claims, nil
 	}
 
@@ -197,7 +229,7 @@ func (a *AuthService) CrearUsuario(nombre, email, password string, rolID uint, c
 		return nil, fmt.Errorf("creador no encontrado: %w", err)
 	}
 
-	if !a.TienePermiso(creador, "can_manage_users") {
+	if !a.TienePermiso(creador, models.PermisoUsuarios) {
 		return nil, errors.New("sin permisos para crear usuarios")
 	}
 
@@ -230,6 +262,61 @@ func (a *AuthService) CrearUsuario(nombre, email, password string, rolID uint, c
 	return usuario, nil
 }
 
+// SembrarAccesoInicial crea los roles que falten y, si todavía no hay ningún usuario, el
+// admin inicial con las credenciales de ADMIN_EMAIL / ADMIN_PASSWORD. Con usuarios
+// cargados no toca nada: las credenciales solo sirven para el primer arranque
+func (a *AuthService) SembrarAccesoInicial(nombre, email, password string) error {
+	roles, err := a.usuarioRepo.ListarRoles()
+	if err != nil {
+		return err
+	}
+	existentes := make(map[string]*models.Rol, len(roles))
+	for _, rol := range roles {
+		existentes[rol.Nombre] = rol
+	}
+	for _, base := range rolesBase {
+		if existentes[base.Nombre] != nil {
+			continue
+		}
+		rol := base
+		if err := a.usuarioRepo.CrearRol(&rol); err != nil {
+			return err
+		}
+		existentes[rol.Nombre] = &rol
+		log.Printf("🔑 Rol creado: %s", rol.Nombre)
+	}
+
+	usuarios, err := a.usuarioRepo.ContarUsuarios()
+	if err != nil {
+		return err
+	}
+	if usuarios > 0 {
+		return nil
+	}
+	if email == "" || password == "" {
+		log.Println("⚠️  No hay usuarios cargados: definí ADMIN_EMAIL y ADMIN_PASSWORD para crear el admin inicial")
+		return nil
+	}
+
+	hashedPassword, err := a.HashPassword(password)
+	if err != nil {
+		return fmt.Errorf("ADMIN_PASSWORD inválida: %w", err)
+	}
+	admin := &models.Usuario{
+		Nombre:       nombre,
+		Email:        email,
+		PasswordHash: hashedPassword,
+		RolID:        existentes["admin"].ID,
+		Activo:       true,
+	}
+	if err := a.usuarioRepo.Crear(admin); err != nil {
+		return fmt.Errorf("error creando admin inicial: %w", err)
+	}
+
+	log.Printf("👤 Admin inicial creado: %s. Cambiá la contraseña después del primer ingreso", admin.Email)
+	return nil
+}
+
 // CambiarPassword cambia la contraseña de un usuario
 func (a *AuthService) CambiarPassword(userID uint, currentPassword, newPassword string) error {
 	usuario, err := a.usuarioRepo.BuscarPorID(userID)
@@ -259,27 +346,230 @@ func (a *AuthService) CambiarPassword(userID uint, currentPassword, newPassword
 	return nil
 }
 
+// EstablecerPIN fija el PIN con el que un administrador aprueba canjes de descuento alto.
+// Pide la contraseña para que no lo cambie cualquiera con la sesión abierta en caja
+func (a *AuthService) EstablecerPIN(userID uint, password, pin string) error {
+	usuario, err := a.usuarioRepo.BuscarPorID(userID)
+	if err != nil {
+		return fmt.Errorf("usuario no encontrado: %w", err)
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(usuario.PasswordHash), []byte(password)); err != nil {
+		return errors.New("contraseña incorrecta")
+	}
+	if !a.EsAdmin(usuario) {
+		return errors.New("solo los administradores pueden aprobar canjes")
+	}
+
+	// HashPassword pide 6 caracteres y el PIN puede tener 4
+	pinHash, err := bcrypt.GenerateFromPassword([]byte(pin), bcrypt.DefaultCost)
+	if err != nil {
+		return fmt.Errorf("error hasheando PIN: %w", err)
+	}
+	usuario.PINHash = string(pinHash)
+	if err := a.usuarioRepo.Actualizar(usuario); err != nil {
+		return fmt.Errorf("error guardando PIN: %w", err)
+	}
+
+	log.Printf("🔐 PIN de aprobación actualizado para: %s", usuario.Email)
+	return nil
+}
+
+// BuscarAprobador retorna el administrador activo de ese email si el PIN de aprobación es
+// el suyo. Cualquier falla da el mismo error para no revelar qué emails son de encargados
+func (a *AuthService) BuscarAprobador(email, pin string) (*models.Usuario, error) {
+	errAprobacion := errors.New("PIN de aprobación incorrecto")
+
+	usuario, err := a.usuarioRepo.BuscarPorEmail(email)
+	if err != nil || !usuario.Activo || usuario.PINHash == "" || !a.EsAdmin(usuario) {
+		return nil, errAprobacion
+	}
+	if bcrypt.CompareHashAndPassword([]byte(usuario.PINHash), []byte(pin)) != nil {
+		return nil, errAprobacion
+	}
+	return usuario, nil
+}
+
 // TienePermiso verifica si un usuario tiene un permiso específico
 func (a *AuthService) TienePermiso(usuario *models.Usuario, permiso string) bool {
-	if usuario.Rol == nil {
-		// Cargar rol si no está cargado
-		rol, err := a.usuarioRepo.BuscarRolPorID(usuario.RolID)
+	return a.RolTienePermiso(usuario.RolID, permiso)
+}
+
+// RolTienePermiso verifica si un rol tiene un permiso específico
+func (a *AuthService) RolTienePermiso(rolID uint, permiso string) bool {
+	permisos, err := a.PermisosDeRol(rolID)
+	if err != nil {
+		log.Printf("⚠️  Error cargando permisos del rol %d: %v", rolID, err)
+		return false
+	}
+	return permisos.Tiene(permiso)
+}
+
+// PermisosDeRol retorna los permisos interpretados del rol, de la cache si están vigentes
+func (a *AuthService) PermisosDeRol(rolID uint) (models.Permisos, error) {
+	a.muPermisos.RLock()
+	cacheados, ok := a.permisos[rolID]
+	a.muPermisos.RUnlock()
+	if ok && time.Now().Before(cacheados.hasta) {
+		return cacheados.permisos, nil
+	}
+
+	rol, err := a.usuarioRepo.BuscarRolPorID(rolID)
+	if err != nil {
+		return models.Permisos{}, err
+	}
+	permisos := interpretarPermisos(rol)
+
+	a.muPermisos.Lock()
+	a.permisos[rolID] = permisosCacheados{permisos: permisos, hasta: time.Now().Add(vigenciaPermisos)}
+	a.muPermisos.Unlock()
+	return permisos, nil
+}
+
+// interpretarPermisos lee el JSON de permisos del rol. El admin tiene todos aunque su JSON
+// diga otra cosa (RequireAdmin también lo reconoce por nombre); un JSON inválido no da
+// ningún permiso
+func interpretarPermisos(rol *models.Rol) models.Permisos {
+	var permisos models.Permisos
+	if rol.Nombre == "admin" {
+		return models.Permisos{Todo: true}
+	}
+	if strings.TrimSpace(rol.Permisos) == "" {
+		return permisos
+	}
+	if err := json.Unmarshal([]byte(rol.Permisos), &permisos); err != nil {
+		log.Printf("⚠️  Permisos inválidos en el rol %s: %v", rol.Nombre, err)
+		return models.Permisos{}
+	}
+	return permisos
+}
+
+// olvidarPermisos descarta los permisos cacheados del rol tras editarlo o eliminarlo
+func (a *AuthService) olvidarPermisos(rolID uint) {
+	a.muPermisos.Lock()
+	delete(a.permisos, rolID)
+	a.muPermisos.Unlock()
+}
+
+// ListarRoles lista los roles con sus permisos y cuántos usuarios tiene cada uno
+func (a *AuthService) ListarRoles() ([]models.RolConPermisos, error) {
+	roles, err := a.usuarioRepo.ListarRoles()
+	if err != nil {
+		return nil, err
+	}
+
+	resultado := make([]models.RolConPermisos, 0, len(roles))
+	for _, rol := range roles {
+		usuarios, err := a.usuarioRepo.ContarUsuariosPorRol(rol.ID)
 		if err != nil {
-			log.Printf("⚠️  Error cargando rol: %v", err)
-			return false
+			return nil, err
 		}
-		usuario.Rol = rol
+		resultado = append(resultado, models.RolConPermisos{
+			ID:        rol.ID,
+			Nombre:    rol.Nombre,
+			Permisos:  interpretarPermisos(rol),
+			Usuarios:  usuarios,
+			CreatedAt: rol.CreatedAt,
+		})
+	}
+	return resultado, nil
+}
+
+// CrearRol crea un rol con los permisos indicados
+func (a *AuthService) CrearRol(req models.RolRequest) (*models.RolConPermisos, error) {
+	nombre := strings.ToLower(strings.TrimSpace(req.Nombre))
+	if nombre == "" {
+		return nil, errors.New("el nombre del rol es obligatorio")
+	}
+	if _, err := a.usuarioRepo.BuscarRolPorNombre(nombre); err == nil {
+		return nil, fmt.Errorf("ya existe un rol llamado %s", nombre)
 	}
 
-	// Admin tiene todos los permisos
-	if usuario.Rol.Nombre == "admin" {
-		return true
+	permisos, err := json.Marshal(req.Permisos)
+	if err != nil {
+		return nil, fmt.Errorf("error guardando permisos: %w", err)
+	}
+	rol := &models.Rol{Nombre: nombre, Permisos: string(permisos)}
+	if err := a.usuarioRepo.CrearRol(rol); err != nil {
+		return nil, err
+	}
+
+	log.Printf("🛡️  Rol creado: %s %s", rol.Nombre, rol.Permisos)
+	return &models.RolConPermisos{ID: rol.ID, Nombre: rol.Nombre, Permisos: interpretarPermisos(rol), CreatedAt: rol.CreatedAt}, nil
+}
+
+// ActualizarRol cambia el nombre y los permisos de un rol. Los roles base conservan su
+// nombre y el admin siempre tiene todos los permisos
+func (a *AuthService) ActualizarRol(id uint, req models.RolRequest) (*models.RolConPermisos, error) {
+	rol, err := a.usuarioRepo.BuscarRolPorID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	nombre := strings.ToLower(strings.TrimSpace(req.Nombre))
+	if nombre != rol.Nombre {
+		if esRolBase(rol.Nombre) {
+			return nil, fmt.Errorf("el rol %s no se puede renombrar", rol.Nombre)
+		}
+		if _, err := a.usuarioRepo.BuscarRolPorNombre(nombre); err == nil {
+			return nil, fmt.Errorf("ya existe un rol llamado %s", nombre)
+		}
+	}
+	if rol.Nombre == "admin" && !req.Permisos.Todo {
+		return nil, errors.New("el rol admin siempre tiene todos los permisos")
+	}
+
+	permisos, err := json.Marshal(req.Permisos)
+	if err != nil {
+		return nil, fmt.Errorf("error guardando permisos: %w", err)
+	}
+	rol.Nombre = nombre
+	rol.Permisos = string(permisos)
+	if err := a.usuarioRepo.ActualizarRol(rol); err != nil {
+		return nil, err
+	}
+	a.olvidarPermisos(rol.ID)
+
+	usuarios, err := a.usuarioRepo.ContarUsuariosPorRol(rol.ID)
+	if err != nil {
+		return nil, err
 	}
+	log.Printf("🛡️  Rol actualizado: %s %s", rol.Nombre, rol.Permisos)
+	return &models.RolConPermisos{ID: rol.ID, Nombre: rol.Nombre, Permisos: interpretarPermisos(rol), Usuarios: usuarios, CreatedAt: rol.CreatedAt}, nil
+}
 
-	// Verificar permiso específico en el JSON de permisos
-	// Por simplicidad, asumir que los permisos son un objeto JSON
-	// En implementación real, parsear el JSON y verificar
-	return false // Implementar parsing de JSON de permisos
+// EliminarRol elimina un rol que no es base y no tiene usuarios
+func (a *AuthService) EliminarRol(id uint) error {
+	rol, err := a.usuarioRepo.BuscarRolPorID(id)
+	if err != nil {
+		return err
+	}
+	if esRolBase(rol.Nombre) {
+		return fmt.Errorf("el rol %s no se puede eliminar", rol.Nombre)
+	}
+	usuarios, err := a.usuarioRepo.ContarUsuariosPorRol(id)
+	if err != nil {
+		return err
+	}
+	if usuarios > 0 {
+		return fmt.Errorf("el rol %s tiene %d usuarios, reasignalos antes de eliminarlo", rol.Nombre, usuarios)
+	}
+
+	if err := a.usuarioRepo.EliminarRol(id); err != nil {
+		return err
+	}
+	a.olvidarPermisos(id)
+	log.Printf("🛡️  Rol eliminado: %s", rol.Nombre)
+	return nil
+}
+
+// esRolBase indica si el rol es uno de los que necesita el sistema
+func esRolBase(nombre string) bool {
+	for _, rol := range rolesBase {
+		if rol.Nombre == nombre {
+			return true
+		}
+	}
+	return false
 }
 
 // EsAdmin verifica si un usuario es administrador
@@ -347,7 +637,7 @@ func (a *AuthService) ActivarDesactivarUsuario(userID uint, activar bool, reques
 	return nil
 }
 
-// RefreshToken genera un nuevo token para un usuario autenticado
+// RefreshToken genera un nuevo token para un usuario autenticado y revoca el anterior
 func (a *AuthService) RefreshToken(oldTokenString string) (string, error) {
 	claims, err := a.ValidateToken(oldTokenString)
 	if err != nil {
@@ -363,7 +653,47 @@ func (a *AuthService) RefreshToken(oldTokenString string) (string, error) {
 		return "", errors.New("usuario desactivado")
 	}
 
-	return a.GenerateToken(usuario)
+	token, err := a.GenerateToken(usuario)
+	if err != nil {
+		return "", err
+	}
+	a.RevocarToken(oldTokenString)
+	return token, nil
+}
+
+// Expiracion duración de los tokens, para la cookie de sesión
+func (a *AuthService) Expiracion() time.Duration {
+	return a.expiration
+}
+
+// RevocarToken invalida un token antes de su vencimiento (logout o refresh). Queda en el
+// estado compartido hasta que vence, así lo rechazan todas las instancias
+func (a *AuthService) RevocarToken(tokenString string) {
+	claims, err := a.ValidateToken(tokenString)
+	if err != nil {
+		return
+	}
+
+	if _, err := a.estado.Crear(claveRevocado(tokenString), "", claims.ExpiresAt.Time); err != nil {
+		log.Printf("⚠️  No se pudo revocar el token de %s: %v", claims.Email, err)
+	}
+}
+
+// revocado indica si el token fue revocado. Si no se puede consultar se lo trata como
+// revocado: es preferible pedir login de nuevo a aceptar un token dado de baja
+func (a *AuthService) revocado(tokenString string) bool {
+	revocado, err := a.estado.Obtener(claveRevocado(tokenString))
+	if err != nil {
+		log.Printf("⚠️  Error consultando tokens revocados: %v", err)
+		return true
+	}
+	return revocado != nil
+}
+
+// claveRevocado clave del estado compartido de un token revocado (el hash, no el token)
+func claveRevocado(tokenString string) string {
+	suma := sha256.Sum256([]byte(tokenString))
+	return "revocado:" + hex.EncodeToString(suma[:])
 }
 
 // GetEstadisticasAuth obtiene estadísticas de autenticación
@@ -0,0 +1,118 @@
+package services
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"CheeseHouse/internal/models"
+	"CheeseHouse/internal/repository"
+)
+
+// BloqueoService administra la lista de bloqueo de teléfonos, IPs y huellas
+// de dispositivo que no pueden participar del juego
+type BloqueoService struct {
+	bloqueoRepo repository.BloqueoRepository
+	whatsapp    *WhatsAppService
+}
+
+// NewBloqueoService crea una nueva instancia del servicio de bloqueos
+func NewBloqueoService(bloqueoRepo repository.BloqueoRepository, whatsapp *WhatsAppService) *BloqueoService {
+	return &BloqueoService{
+		bloqueoRepo: bloqueoRepo,
+		whatsapp:    whatsapp,
+	}
+}
+
+// Bloquear agrega una entrada a la lista registrando quién la creó y por qué
+func (s *BloqueoService) Bloquear(req models.CrearBloqueoRequest, usuarioID uint) (*models.Bloqueo, error) {
+	valor := strings.TrimSpace(req.Valor)
+	switch req.Tipo {
+	case "telefono":
+		valor = s.whatsapp.NormalizarTelefono(valor)
+	case "huella":
+		valor = strings.ToLower(valor)
+	}
+	if valor == "" {
+		return nil, fmt.Errorf("el valor a bloquear no puede estar vacío")
+	}
+
+	if existente, err := s.bloqueoRepo.BuscarVigente(req.Tipo, valor); err != nil {
+		return nil, err
+	} else if existente != nil {
+		return nil, fmt.Errorf("%s ya está bloqueado (bloqueo #%d)", req.Tipo, existente.ID)
+	}
+
+	bloqueo := &models.Bloqueo{
+		Tipo:      req.Tipo,
+		Valor:     valor,
+		Motivo:    strings.TrimSpace(req.Motivo),
+		CreadoPor: usuarioID,
+		Activo:    true,
+	}
+	if req.DuracionHoras > 0 {
+		expira := time.Now().Add(time.Duration(req.DuracionHoras) * time.Hour)
+		bloqueo.ExpiraEn = &expira
+	}
+
+	if err := s.bloqueoRepo.Crear(bloqueo); err != nil {
+		return nil, err
+	}
+
+	log.Printf("🚫 Usuario %d bloqueó %s %s: %s", usuarioID, bloqueo.Tipo, bloqueo.Valor, bloqueo.Motivo)
+	return bloqueo, nil
+}
+
+// Levantar desactiva un bloqueo conservándolo en el historial
+func (s *BloqueoService) Levantar(id uint, usuarioID uint) (*models.Bloqueo, error) {
+	bloqueo, err := s.bloqueoRepo.BuscarPorID(id)
+	if err != nil {
+		return nil, err
+	}
+	if !bloqueo.Activo {
+		return nil, fmt.Errorf("el bloqueo #%d ya fue levantado", id)
+	}
+
+	ahora := time.Now()
+	bloqueo.Activo = false
+	bloqueo.LevantadoPor = &usuarioID
+	bloqueo.LevantadoAt = &ahora
+
+	if err := s.bloqueoRepo.Actualizar(bloqueo); err != nil {
+		return nil, err
+	}
+
+	log.Printf("✅ Usuario %d levantó el bloqueo #%d (%s %s)", usuarioID, bloqueo.ID, bloqueo.Tipo, bloqueo.Valor)
+	return bloqueo, nil
+}
+
+// Listar obtiene los bloqueos vigentes o todo el historial
+func (s *BloqueoService) Listar(incluirInactivos bool) ([]*models.Bloqueo, error) {
+	return s.bloqueoRepo.Listar(incluirInactivos)
+}
+
+// BuscarBloqueo retorna el primer bloqueo vigente que aplique a la partida, o nil.
+// Ante un error de base de datos no bloquea para no frenar el juego
+func (s *BloqueoService) BuscarBloqueo(telefono, ip, huella string) *models.Bloqueo {
+	candidatos := []struct{ tipo, valor string }{
+		{"telefono", telefono},
+		{"ip", ip},
+		{"huella", huella},
+	}
+
+	for _, candidato := range candidatos {
+		if candidato.valor == "" {
+			continue
+		}
+		bloqueo, err := s.bloqueoRepo.BuscarVigente(candidato.tipo, candidato.valor)
+		if err != nil {
+			log.Printf("⚠️  Error consultando lista de bloqueo: %v", err)
+			continue
+		}
+		if bloqueo != nil {
+			return bloqueo
+		}
+	}
+	return nil
+}
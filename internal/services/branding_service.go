@@ -0,0 +1,114 @@
+package services
+
+import (
+	"log"
+	"sync"
+	"time"
+
+	"CheeseHouse/internal/config"
+	"CheeseHouse/internal/models"
+	"CheeseHouse/internal/repository"
+)
+
+// BrandingService provee el logo, colores y texto legal usados en vouchers y en
+// la página del juego, combinando lo editado por el admin con la configuración.
+// Cada instancia cachea el branding: la que recibe la edición la ve enseguida y,
+// con estado compartido, las demás la releen de la base a los
+// SHARED_CACHE_TTL_SECONDS como mucho
+type BrandingService struct {
+	config       *config.Config
+	brandingRepo repository.BrandingRepository
+
+	mu        sync.RWMutex
+	cache     *models.Branding
+	cargadoEn time.Time
+}
+
+// NewBrandingService crea una nueva instancia del servicio de branding
+func NewBrandingService(cfg *config.Config, brandingRepo repository.BrandingRepository) *BrandingService {
+	return &BrandingService{
+		config:       cfg,
+		brandingRepo: brandingRepo,
+	}
+}
+
+// Obtener retorna el branding vigente; si la base no responde usa la configuración
+func (b *BrandingService) Obtener() models.Branding {
+	b.mu.RLock()
+	if b.cache != nil && b.config.Scaling.CacheVigente(b.cargadoEn) {
+		branding := *b.cache
+		b.mu.RUnlock()
+		return branding
+	}
+	b.mu.RUnlock()
+
+	guardado, err := b.brandingRepo.Obtener()
+	if err != nil {
+		log.Printf("⚠️  Error obteniendo branding, usando configuración: %v", err)
+		return b.completar(models.Branding{})
+	}
+
+	branding := models.Branding{}
+	if guardado != nil {
+		branding = *guardado
+	}
+	branding = b.completar(branding)
+
+	b.mu.Lock()
+	b.cache = &branding
+	b.cargadoEn = time.Now()
+	b.mu.Unlock()
+
+	return branding
+}
+
+// Actualizar guarda el branding editado por un admin
+func (b *BrandingService) Actualizar(req models.ActualizarBrandingRequest, usuarioID uint) (models.Branding, error) {
+	branding, err := b.brandingRepo.Obtener()
+	if err != nil {
+		return models.Branding{}, err
+	}
+	if branding == nil {
+		branding = &models.Branding{}
+	}
+
+	branding.LogoURL = req.LogoURL
+	branding.ColorPrimario = req.ColorPrimario
+	branding.ColorSecundario = req.ColorSecundario
+	branding.TextoLegal = req.TextoLegal
+	branding.UpdatedBy = usuarioID
+
+	if err := b.brandingRepo.Guardar(branding); err != nil {
+		return models.Branding{}, err
+	}
+
+	b.mu.Lock()
+	b.cache = nil
+	b.mu.Unlock()
+
+	if b.config.Scaling.Compartido() {
+		log.Printf("🎨 Branding actualizado por usuario %d; las demás instancias lo toman en %v como mucho",
+			usuarioID, b.config.Scaling.CacheTTL)
+	} else {
+		log.Printf("🎨 Branding actualizado por usuario %d", usuarioID)
+	}
+	return b.Obtener(), nil
+}
+
+// completar rellena los campos vacíos con los valores de la configuración
+func (b *BrandingService) completar(branding models.Branding) models.Branding {
+	defaults := b.config.Branding
+	if branding.LogoURL == "" {
+		branding.LogoURL = defaults.LogoURL
+	}
+	if branding.ColorPrimario == "" {
+		branding.ColorPrimario = defaults.PrimaryColor
+	}
+	if branding.ColorSecundario == "" {
+		branding.ColorSecundario = defaults.SecondaryColor
+	}
+	if branding.TextoLegal == "" {
+		branding.TextoLegal = defaults.LegalText
+	}
+	return branding
+}
@@ -0,0 +1,186 @@
+package services
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"CheeseHouse/internal/config"
+	"CheeseHouse/internal/models"
+	"CheeseHouse/internal/repository"
+)
+
+// Un día es pico de vencimientos si vencen al menos minVencimientosPico vouchers y el
+// doble (o más) del promedio diario del mes
+const (
+	factorPicoVencimientos = 2.0
+	minVencimientosPico    = 10
+)
+
+// CalendarioService arma el calendario mensual de actividad programada: campañas,
+// reportes automáticos, mensajes de marketing en cola, días sin canje y picos de
+// vencimiento de vouchers, para que el dueño no apile promociones
+type CalendarioService struct {
+	config          *config.Config
+	campanaRepo     repository.CampanaRepository
+	voucherRepo     repository.VoucherRepository
+	outboxRepo      repository.OutboxRepository
+	diaSinCanjeRepo repository.DiaSinCanjeRepository
+	reportes        *ReporteService
+}
+
+// NewCalendarioService crea una nueva instancia del servicio de calendario
+func NewCalendarioService(cfg *config.Config, campanaRepo repository.CampanaRepository, voucherRepo repository.VoucherRepository, outboxRepo repository.OutboxRepository, diaSinCanjeRepo repository.DiaSinCanjeRepository, reportes *ReporteService) *CalendarioService {
+	return &CalendarioService{
+		config:          cfg,
+		campanaRepo:     campanaRepo,
+		voucherRepo:     voucherRepo,
+		outboxRepo:      outboxRepo,
+		diaSinCanjeRepo: diaSinCanjeRepo,
+		reportes:        reportes,
+	}
+}
+
+// GetMes arma el calendario del mes indicado (AAAA-MM); vacío = el mes actual
+func (s *CalendarioService) GetMes(mes string) (*models.CalendarioMes, error) {
+	loc := s.config.GetLocation()
+	ahora := time.Now().In(loc)
+	desde := time.Date(ahora.Year(), ahora.Month(), 1, 0, 0, 0, 0, loc)
+	if mes != "" {
+		var err error
+		if desde, err = time.ParseInLocation("2006-01", mes, loc); err != nil {
+			return nil, fmt.Errorf("mes inválido, usar el formato AAAA-MM")
+		}
+	}
+	hasta := desde.AddDate(0, 1, 0)
+
+	calendario := &models.CalendarioMes{Mes: desde.Format("2006-01")}
+	indice := make(map[string]*models.DiaCalendario)
+	for dia := desde; dia.Before(hasta); dia = dia.AddDate(0, 0, 1) {
+		calendario.Dias = append(calendario.Dias, models.DiaCalendario{
+			Fecha:   dia.Format("2006-01-02"),
+			Eventos: []models.EventoCalendario{},
+		})
+	}
+	for i := range calendario.Dias {
+		indice[calendario.Dias[i].Fecha] = &calendario.Dias[i]
+	}
+	diaDe := func(t time.Time) *models.DiaCalendario {
+		return indice[t.In(loc).Format("2006-01-02")]
+	}
+
+	// Días sin canje
+	bloqueados, err := s.diaSinCanjeRepo.ListarEntre(desde, hasta.AddDate(0, 0, -1))
+	if err != nil {
+		return nil, err
+	}
+	for _, bloqueado := range bloqueados {
+		if dia := indice[bloqueado.Fecha.Format("2006-01-02")]; dia != nil {
+			dia.SinCanje = true
+			dia.Eventos = append(dia.Eventos, models.EventoCalendario{
+				Tipo: "dia_sin_canje", ID: bloqueado.ID, Titulo: "Sin canje de vouchers", Detalle: bloqueado.Motivo,
+			})
+		}
+	}
+
+	// Vencimientos de vouchers sin usar y picos respecto del promedio del mes
+	vencimientos, err := s.voucherRepo.GetVencimientosEntre(desde, hasta)
+	if err != nil {
+		return nil, err
+	}
+	for _, vence := range vencimientos {
+		if dia := diaDe(vence); dia != nil {
+			dia.VouchersQueVencen++
+		}
+	}
+	promedio := float64(len(vencimientos)) / float64(len(calendario.Dias))
+	calendario.PromedioVencimientos = math.Round(promedio*10) / 10
+	for i := range calendario.Dias {
+		dia := &calendario.Dias[i]
+		dia.PicoVencimientos = dia.VouchersQueVencen >= minVencimientosPico &&
+			float64(dia.VouchersQueVencen) >= promedio*factorPicoVencimientos
+	}
+
+	// Campañas: lanzamiento y vencimiento de sus vouchers
+	campanas, err := s.campanaRepo.ListarEntre(desde, hasta)
+	if err != nil {
+		return nil, err
+	}
+	for _, campana := range campanas {
+		detalle := fmt.Sprintf("%d%% de descuento", campana.Descuento)
+		if dia := diaDe(campana.CreatedAt); dia != nil {
+			dia.Eventos = append(dia.Eventos, models.EventoCalendario{
+				Tipo: "campana_inicio", ID: campana.ID, Titulo: campana.Nombre, Detalle: detalle,
+			})
+		}
+		if dia := diaDe(campana.FechaVencimiento); dia != nil {
+			dia.Eventos = append(dia.Eventos, models.EventoCalendario{
+				Tipo: "campana_vencimiento", ID: campana.ID, Titulo: campana.Nombre, Detalle: detalle,
+			})
+		}
+	}
+
+	// Reportes automáticos
+	ejecuciones, err := s.reportes.EjecucionesEntre(desde, hasta)
+	if err != nil {
+		return nil, err
+	}
+	for _, ejecucion := range ejecuciones {
+		if dia := diaDe(ejecucion.Fecha); dia != nil {
+			dia.Eventos = append(dia.Eventos, models.EventoCalendario{
+				Tipo: "reporte_programado", ID: ejecucion.ReporteID, Titulo: ejecucion.Nombre,
+				Detalle: fmt.Sprintf("%s, %s", ejecucion.Programacion, s.config.Fechas("es").Hora(ejecucion.Fecha)),
+			})
+		}
+	}
+
+	// Mensajes de marketing en cola (horario silencioso o programados)
+	programados, err := s.outboxRepo.GetProgramadosEntre("marketing", desde, hasta)
+	if err != nil {
+		return nil, err
+	}
+	mensajesPorDia := make(map[*models.DiaCalendario]int)
+	for _, programado := range programados {
+		if dia := diaDe(programado); dia != nil {
+			mensajesPorDia[dia]++
+		}
+	}
+	for i := range calendario.Dias {
+		dia := &calendario.Dias[i]
+		if cantidad := mensajesPorDia[dia]; cantidad > 0 {
+			dia.Eventos = append(dia.Eventos, models.EventoCalendario{
+				Tipo: "mensajes_programados", Titulo: "Mensajes de marketing en cola",
+				Detalle: fmt.Sprintf("%d mensajes", cantidad),
+			})
+		}
+		dia.Avisos = avisosCalendario(dia)
+	}
+
+	return calendario, nil
+}
+
+// avisosCalendario detecta promociones que se pisan en un mismo día
+func avisosCalendario(dia *models.DiaCalendario) []string {
+	var avisos []string
+	lanzamientos := 0
+	for _, evento := range dia.Eventos {
+		switch evento.Tipo {
+		case "campana_inicio":
+			lanzamientos++
+			if dia.PicoVencimientos {
+				avisos = append(avisos, fmt.Sprintf("La campaña %q arranca en un pico de vencimientos (%d vouchers)", evento.Titulo, dia.VouchersQueVencen))
+			}
+		case "campana_vencimiento":
+			if dia.SinCanje {
+				avisos = append(avisos, fmt.Sprintf("La campaña %q vence en un día sin canje", evento.Titulo))
+			}
+		}
+	}
+	if lanzamientos > 1 {
+		avisos = append(avisos, fmt.Sprintf("%d campañas arrancan el mismo día", lanzamientos))
+	}
+	if dia.SinCanje && dia.VouchersQueVencen > 0 {
+		avisos = append(avisos, fmt.Sprintf("Vencen %d vouchers en un día sin canje", dia.VouchersQueVencen))
+	}
+	return avisos
+}
@@ -0,0 +1,254 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"CheeseHouse/internal/config"
+	"CheeseHouse/internal/models"
+	"CheeseHouse/internal/repository"
+)
+
+// Intentos de PIN de aprobación fallidos antes de bloquear al empleado un rato, para que
+// no se pueda adivinar el PIN de un encargado desde la caja
+const (
+	maxFallosPIN = 5
+	bloqueoPIN   = 15 * time.Minute
+)
+
+// CanjeEmpleadoService controla los canjes del lado de la caja: tope diario por empleado,
+// registro de rechazos y reporte de empleados con un patrón de canje fuera de lo normal
+type CanjeEmpleadoService struct {
+	config         *config.Config
+	repo           repository.CanjeEmpleadoRepository
+	usuarioRepo    repository.UsuarioRepository
+	notificaciones *NotificacionService
+	// Los PIN incorrectos recientes van al estado compartido: si cada instancia los contara
+	// por separado, repartir los intentos entre instancias evitaría el bloqueo
+	estado repository.EstadoCompartidoRepository
+
+	mu        sync.Mutex
+	avisadoEl map[uint]string // Día (AAAA-MM-DD) en que se avisó que el empleado llegó al tope
+}
+
+// NewCanjeEmpleadoService crea una nueva instancia del servicio de canjes por empleado
+func NewCanjeEmpleadoService(cfg *config.Config, repo repository.CanjeEmpleadoRepository, usuarioRepo repository.UsuarioRepository, notificaciones *NotificacionService, estado repository.EstadoCompartidoRepository) *CanjeEmpleadoService {
+	return &CanjeEmpleadoService{
+		config:         cfg,
+		repo:           repo,
+		usuarioRepo:    usuarioRepo,
+		notificaciones: notificaciones,
+		estado:         estado,
+		avisadoEl:      make(map[uint]string),
+	}
+}
+
+// Limite retorna el tope diario de canjes del empleado (0 = sin límite)
+func (s *CanjeEmpleadoService) Limite(usuario *models.Usuario) int {
+	if usuario.LimiteCanjes != nil {
+		return *usuario.LimiteCanjes
+	}
+	return s.config.Redemption.DailyCapPerEmployee
+}
+
+// VerificarLimite retorna el tope del empleado si ya lo alcanzó hoy, o 0 si puede seguir
+// canjeando. Si no se puede consultar, se deja canjear
+func (s *CanjeEmpleadoService) VerificarLimite(empleadoID uint) int {
+	usuario, err := s.usuarioRepo.BuscarPorID(empleadoID)
+	if err != nil {
+		log.Printf("⚠️  No se pudo obtener el tope de canjes del empleado %d: %v", empleadoID, err)
+		return 0
+	}
+	limite := s.Limite(usuario)
+	if limite == 0 {
+		return 0
+	}
+
+	ahora := time.Now()
+	canjes, err := s.repo.ContarCanjes(empleadoID, s.config.InicioDelDia(ahora))
+	if err != nil {
+		log.Printf("⚠️  No se pudieron contar los canjes del empleado %d: %v", empleadoID, err)
+		return 0
+	}
+	if canjes < limite {
+		return 0
+	}
+
+	s.avisarLimite(usuario, limite, ahora)
+	return limite
+}
+
+// RegistrarRechazo guarda un canje rechazado para el reporte de anomalías
+func (s *CanjeEmpleadoService) RegistrarRechazo(empleadoID uint, codigo, motivo string, descuento int) {
+	intento := &models.IntentoCanje{
+		UsuarioID: empleadoID,
+		Codigo:    codigo,
+		Motivo:    motivo,
+		Descuento: descuento,
+	}
+	if err := s.repo.RegistrarIntento(intento); err != nil {
+		log.Printf("⚠️  No se pudo registrar el intento de canje de %s: %v", codigo, err)
+	}
+}
+
+// PINBloqueado indica si el empleado erró el PIN de aprobación demasiadas veces seguidas
+func (s *CanjeEmpleadoService) PINBloqueado(empleadoID uint) bool {
+	guardado, err := s.estado.Obtener(clavePIN(empleadoID))
+	if err != nil {
+		log.Printf("⚠️  Error leyendo los fallos de PIN del empleado %d: %v", empleadoID, err)
+		return false
+	}
+	if guardado == nil {
+		return false
+	}
+	return len(fallosRecientes(guardado.Valor, time.Now())) >= maxFallosPIN
+}
+
+// RegistrarFalloPIN suma un PIN incorrecto y avisa al panel cuando el empleado queda bloqueado
+func (s *CanjeEmpleadoService) RegistrarFalloPIN(empleadoID uint) {
+	var fallos []time.Time
+	err := repository.ActualizarCompartido(s.estado, clavePIN(empleadoID), func(valor string, existe bool) (string, time.Time, error) {
+		ahora := time.Now()
+		fallos = append(fallosRecientes(valor, ahora), ahora)
+		codificado, err := json.Marshal(fallos)
+		// Vence cuando sale de la ventana el último fallo, que es cuando dejaría de contar
+		return string(codificado), ahora.Add(bloqueoPIN), err
+	})
+	if err != nil {
+		log.Printf("⚠️  Error registrando el fallo de PIN del empleado %d: %v", empleadoID, err)
+		return
+	}
+
+	if len(fallos) == maxFallosPIN {
+		log.Printf("🚨 Empleado %d bloqueado por %d PIN de aprobación incorrectos", empleadoID, maxFallosPIN)
+		s.notificaciones.Notificar("error", "PIN de aprobación bloqueado",
+			fmt.Sprintf("El empleado #%d ingresó %d PIN incorrectos seguidos; no puede pedir aprobaciones por %d minutos",
+				empleadoID, maxFallosPIN, int(bloqueoPIN.Minutes())),
+			"revisar_canjes_empleado")
+	}
+}
+
+// LimpiarFallosPIN descarta los fallos del empleado tras una aprobación correcta
+func (s *CanjeEmpleadoService) LimpiarFallosPIN(empleadoID uint) {
+	if err := s.estado.Eliminar(clavePIN(empleadoID)); err != nil {
+		log.Printf("⚠️  Error limpiando los fallos de PIN del empleado %d: %v", empleadoID, err)
+	}
+}
+
+// clavePIN clave del estado compartido con los fallos de PIN del empleado
+func clavePIN(empleadoID uint) string {
+	return fmt.Sprintf("pin:fallos:%d", empleadoID)
+}
+
+// fallosRecientes decodifica los fallos guardados y deja los de la ventana de bloqueo
+func fallosRecientes(valor string, ahora time.Time) []time.Time {
+	var fallos []time.Time
+	if valor == "" || json.Unmarshal([]byte(valor), &fallos) != nil {
+		return nil
+	}
+	limite := ahora.Add(-bloqueoPIN)
+	recientes := fallos[:0]
+	for _, fallo := range fallos {
+		if fallo.After(limite) {
+			recientes = append(recientes, fallo)
+		}
+	}
+	return recientes
+}
+
+// ActualizarLimite fija el tope diario de un empleado; nil vuelve al de la cadena
+func (s *CanjeEmpleadoService) ActualizarLimite(usuarioID uint, limite *int, adminID uint) (*models.Usuario, error) {
+	usuario, err := s.usuarioRepo.BuscarPorID(usuarioID)
+	if err != nil {
+		return nil, err
+	}
+	usuario.LimiteCanjes = limite
+
+	if err := s.usuarioRepo.Actualizar(usuario); err != nil {
+		return nil, err
+	}
+
+	log.Printf("🎟️  Usuario %d fijó el tope diario de canjes de %s en %d", adminID, usuario.Nombre, s.Limite(usuario))
+	return usuario, nil
+}
+
+// Reporte compara la actividad de canje de cada empleado con el promedio de la cadena y
+// marca a los que canjean vouchers de alto valor o intentan canjear vencidos mucho más
+// que el resto. Solo se evalúa a quienes tienen actividad suficiente en el período
+func (s *CanjeEmpleadoService) Reporte(dias int) (*models.ReporteCanjesEmpleados, error) {
+	desde := time.Now().AddDate(0, 0, -dias)
+	actividad, err := s.repo.GetActividadPorEmpleado(desde, s.config.Redemption.HighValueDiscount)
+	if err != nil {
+		return nil, err
+	}
+
+	var canjes, altoValor, vencidos int
+	for _, empleado := range actividad {
+		canjes += empleado.Canjes
+		altoValor += empleado.CanjesAltoValor
+		vencidos += empleado.IntentosVencidos
+	}
+	reporte := &models.ReporteCanjesEmpleados{
+		Desde:                 desde,
+		DescuentoAltoValor:    s.config.Redemption.HighValueDiscount,
+		TasaAltoValorPromedio: porcentaje(altoValor, canjes),
+		TasaVencidosPromedio:  porcentaje(vencidos, canjes+vencidos),
+		Empleados:             actividad,
+	}
+
+	factor := s.config.Redemption.AnomalyFactor
+	for i := range reporte.Empleados {
+		empleado := &reporte.Empleados[i]
+		empleado.TasaAltoValor = porcentaje(empleado.CanjesAltoValor, empleado.Canjes)
+		empleado.TasaVencidos = porcentaje(empleado.IntentosVencidos, empleado.Canjes+empleado.IntentosVencidos)
+
+		if empleado.Canjes+empleado.IntentosVencidos+empleado.IntentosOtros < s.config.Redemption.AnomalyMinActivity {
+			continue
+		}
+		if empleado.CanjesAltoValor > 0 && empleado.TasaAltoValor >= reporte.TasaAltoValorPromedio*factor {
+			empleado.Anomalias = append(empleado.Anomalias, fmt.Sprintf("%.1f%% de canjes de alto valor (promedio %.1f%%)",
+				empleado.TasaAltoValor, reporte.TasaAltoValorPromedio))
+		}
+		if empleado.IntentosVencidos > 0 && empleado.TasaVencidos >= reporte.TasaVencidosPromedio*factor {
+			empleado.Anomalias = append(empleado.Anomalias, fmt.Sprintf("%d intentos con vouchers vencidos, %.1f%% (promedio %.1f%%)",
+				empleado.IntentosVencidos, empleado.TasaVencidos, reporte.TasaVencidosPromedio))
+		}
+	}
+
+	// Primero los marcados; el resto queda ordenado por canjes
+	sort.SliceStable(reporte.Empleados, func(i, j int) bool {
+		return len(reporte.Empleados[i].Anomalias) > 0 && len(reporte.Empleados[j].Anomalias) == 0
+	})
+	return reporte, nil
+}
+
+// avisarLimite notifica al panel la primera vez en el día que un empleado llega al tope
+func (s *CanjeEmpleadoService) avisarLimite(usuario *models.Usuario, limite int, ahora time.Time) {
+	dia := ahora.In(s.config.GetLocation()).Format("2006-01-02")
+
+	s.mu.Lock()
+	if s.avisadoEl[usuario.ID] == dia {
+		s.mu.Unlock()
+		return
+	}
+	s.avisadoEl[usuario.ID] = dia
+	s.mu.Unlock()
+
+	log.Printf("⚠️  %s alcanzó el tope de %d canjes por hoy", usuario.Nombre, limite)
+	s.notificaciones.Notificar("warning", "Tope de canjes alcanzado",
+		fmt.Sprintf("%s llegó a %d canjes hoy; los siguientes se rechazan hasta mañana", usuario.Nombre, limite),
+		"revisar_canjes_empleado")
+}
+
+// porcentaje parte sobre total en porcentaje con un decimal (0 si no hay total)
+func porcentaje(parte, total int) float64 {
+	if total == 0 {
+		return 0
+	}
+	return math.Round(float64(parte)/float64(total)*1000) / 10
+}
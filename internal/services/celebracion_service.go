@@ -0,0 +1,108 @@
+package services
+
+import (
+	"log"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"CheeseHouse/internal/config"
+	"CheeseHouse/internal/models"
+	"CheeseHouse/internal/repository"
+)
+
+// confetiPorDefecto se usa mientras marketing no configure la celebración
+const confetiPorDefecto = "clasico"
+
+// CelebracionService provee la celebración que muestra el frontend al ganar, editable
+// desde el panel para cambiar la experiencia sin redeploy
+type CelebracionService struct {
+	config          *config.Config
+	celebracionRepo repository.CelebracionRepository
+
+	mu        sync.RWMutex
+	cache     *models.Celebracion
+	cargadoEn time.Time
+}
+
+// NewCelebracionService crea una nueva instancia del servicio de celebración
+func NewCelebracionService(cfg *config.Config, celebracionRepo repository.CelebracionRepository) *CelebracionService {
+	return &CelebracionService{
+		config:          cfg,
+		celebracionRepo: celebracionRepo,
+	}
+}
+
+// Obtener retorna la celebración vigente; si la base no responde usa la de por defecto
+func (s *CelebracionService) Obtener() models.Celebracion {
+	s.mu.RLock()
+	if s.cache != nil && s.config.Scaling.CacheVigente(s.cargadoEn) {
+		celebracion := *s.cache
+		s.mu.RUnlock()
+		return celebracion
+	}
+	s.mu.RUnlock()
+
+	guardada, err := s.celebracionRepo.Obtener()
+	if err != nil {
+		log.Printf("⚠️  Error obteniendo celebración, usando la de por defecto: %v", err)
+		return models.Celebracion{Confeti: confetiPorDefecto}
+	}
+
+	celebracion := models.Celebracion{Confeti: confetiPorDefecto}
+	if guardada != nil {
+		celebracion = *guardada
+	}
+
+	s.mu.Lock()
+	s.cache = &celebracion
+	s.cargadoEn = time.Now()
+	s.mu.Unlock()
+
+	return celebracion
+}
+
+// Actualizar guarda la celebración editada por un admin
+func (s *CelebracionService) Actualizar(req models.ActualizarCelebracionRequest, usuarioID uint) (models.Celebracion, error) {
+	celebracion, err := s.celebracionRepo.Obtener()
+	if err != nil {
+		return models.Celebracion{}, err
+	}
+	if celebracion == nil {
+		celebracion = &models.Celebracion{}
+	}
+
+	celebracion.Confeti = req.Confeti
+	celebracion.SonidoURL = req.SonidoURL
+	celebracion.Titular = strings.TrimSpace(req.Titular)
+	celebracion.Upsell = strings.TrimSpace(req.Upsell)
+	celebracion.UpdatedBy = usuarioID
+
+	if err := s.celebracionRepo.Guardar(celebracion); err != nil {
+		return models.Celebracion{}, err
+	}
+
+	s.mu.Lock()
+	s.cache = nil
+	s.mu.Unlock()
+
+	log.Printf("🎉 Celebración actualizada por usuario %d (confeti %s)", usuarioID, celebracion.Confeti)
+	return s.Obtener(), nil
+}
+
+// ParaGanador arma la celebración de una victoria con el nombre y el descuento del cliente
+func (s *CelebracionService) ParaGanador(cliente *models.Cliente, descuento int) *models.CelebracionGanador {
+	celebracion := s.Obtener()
+	reemplazos := strings.NewReplacer(
+		"{nombre}", cliente.Nombre,
+		"{descuento}", strconv.Itoa(descuento),
+	)
+
+	return &models.CelebracionGanador{
+		Confeti:   celebracion.Confeti,
+		SonidoURL: celebracion.SonidoURL,
+		Titular:   reemplazos.Replace(celebracion.Titular),
+		Upsell:    reemplazos.Replace(celebracion.Upsell),
+	}
+}
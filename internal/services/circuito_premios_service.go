@@ -0,0 +1,254 @@
+package services
+
+import (
+	"encoding/json"
+	"log"
+	"math"
+	"sync"
+	"time"
+
+	"CheeseHouse/internal/config"
+	"CheeseHouse/internal/models"
+	"CheeseHouse/internal/repository"
+)
+
+// Estados del circuito de premios
+const (
+	CircuitoNormal   = "normal"
+	CircuitoAjustado = "ajustado"
+	CircuitoPausado  = "pausado"
+)
+
+const (
+	// claveCircuitoPremios clave del estado compartido con el estado del circuito
+	claveCircuitoPremios = "circuito_premios"
+	// refrescoCircuitoPremios cada cuánto se relee el estado que pudo cambiar otra instancia
+	refrescoCircuitoPremios = 5 * time.Second
+	// vigenciaCircuitoPremios cuánto se guarda el estado compartido del circuito
+	vigenciaCircuitoPremios = 30 * 24 * time.Hour
+)
+
+// circuitoCompartido parte del estado del circuito que comparten las instancias: si una
+// lo activa, todas ajustan o pausan, y el restablecimiento de un admin vale para todas
+type circuitoCompartido struct {
+	Estado          string     `json:"estado"`
+	ActivadoEn      *time.Time `json:"activado_en,omitempty"`
+	TasaActivacion  float64    `json:"tasa_activacion,omitempty"`
+	RestablecidoPor uint       `json:"restablecido_por,omitempty"`
+	RestablecidoEn  *time.Time `json:"restablecido_en,omitempty"`
+}
+
+// CircuitoPremiosService vigila la tasa de victorias de las últimas partidas.
+// Si supera el máximo configurado (señal de un exploit en el frontend) reduce la
+// tolerancia o pausa la emisión de vouchers hasta que un admin lo restablezca. Cada
+// instancia observa sus propias partidas; el estado del circuito es compartido
+type CircuitoPremiosService struct {
+	config        *config.Config
+	eventService  *EventService
+	configuracion *ConfiguracionService // Tolerancia vigente, editable desde el panel
+	compartido    repository.EstadoCompartidoRepository
+
+	mu              sync.Mutex
+	sincronizadoEn  time.Time
+	resultados      []bool // buffer circular de las últimas partidas
+	siguiente       int
+	cantidad        int
+	estado          string
+	activadoEn      *time.Time
+	tasaActivacion  float64
+	restablecidoPor uint
+	restablecidoEn  *time.Time
+}
+
+// NewCircuitoPremiosService crea una nueva instancia del circuito de premios
+func NewCircuitoPremiosService(cfg *config.Config, eventService *EventService, configuracion *ConfiguracionService, compartido repository.EstadoCompartidoRepository) *CircuitoPremiosService {
+	return &CircuitoPremiosService{
+		config:        cfg,
+		eventService:  eventService,
+		configuracion: configuracion,
+		compartido:    compartido,
+		resultados:    make([]bool, cfg.Game.WinRateWindow),
+		estado:        CircuitoNormal,
+	}
+}
+
+// Registrar agrega el resultado de una partida y activa el corte si corresponde
+func (s *CircuitoPremiosService) Registrar(gano bool) {
+	if len(s.resultados) == 0 {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.sincronizar(false)
+	s.resultados[s.siguiente] = gano
+	s.siguiente = (s.siguiente + 1) % len(s.resultados)
+	if s.cantidad < len(s.resultados) {
+		s.cantidad++
+	}
+
+	// Solo se evalúa con la ventana completa para no reaccionar a rachas cortas
+	if s.estado != CircuitoNormal || s.cantidad < len(s.resultados) {
+		return
+	}
+
+	tasa := s.tasaVictorias()
+	if tasa <= s.config.Game.WinRateCeiling {
+		return
+	}
+
+	ahora := time.Now()
+	s.estado = CircuitoAjustado
+	if s.config.Game.WinRateAction == "pausar" {
+		s.estado = CircuitoPausado
+	}
+	s.activadoEn = &ahora
+	s.tasaActivacion = tasa
+	s.publicar(true)
+
+	log.Printf("🚨 Tasa de victorias anómala: %.0f%% en las últimas %d partidas (máximo %.0f%%). Circuito de premios: %s",
+		tasa*100, len(s.resultados), s.config.Game.WinRateCeiling*100, s.estado)
+	s.eventService.Emitir(EventoCircuitoPremiosActivado, s.estadoActual())
+}
+
+// Tolerancia retorna la tolerancia vigente según el estado del circuito
+func (s *CircuitoPremiosService) Tolerancia() float64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sincronizar(false)
+	return s.tolerancia()
+}
+
+// EmisionPausada indica si la emisión de vouchers está suspendida
+func (s *CircuitoPremiosService) EmisionPausada() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sincronizar(false)
+	return s.estado == CircuitoPausado
+}
+
+// Estado retorna el estado del circuito para el dashboard
+func (s *CircuitoPremiosService) Estado() models.EstadoCircuitoPremios {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sincronizar(true)
+	return s.estadoActual()
+}
+
+// Restablecer vuelve el circuito a la normalidad y descarta la ventana observada
+func (s *CircuitoPremiosService) Restablecer(usuarioID uint) models.EstadoCircuitoPremios {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ahora := time.Now()
+	s.estado = CircuitoNormal
+	s.activadoEn = nil
+	s.tasaActivacion = 0
+	s.siguiente = 0
+	s.cantidad = 0
+	s.restablecidoPor = usuarioID
+	s.restablecidoEn = &ahora
+	s.publicar(false)
+
+	log.Printf("🔄 Circuito de premios restablecido por usuario %d", usuarioID)
+	return s.estadoActual()
+}
+
+// sincronizar adopta el estado que haya guardado otra instancia. Se relee cada
+// refrescoCircuitoPremios salvo que forzar pida leerlo ya. Debe llamarse con el mutex tomado
+func (s *CircuitoPremiosService) sincronizar(forzar bool) {
+	ahora := time.Now()
+	if !forzar && ahora.Sub(s.sincronizadoEn) < refrescoCircuitoPremios {
+		return
+	}
+	s.sincronizadoEn = ahora
+
+	guardado, err := s.compartido.Obtener(claveCircuitoPremios)
+	if err != nil {
+		log.Printf("⚠️  Error leyendo el estado compartido del circuito de premios: %v", err)
+		return
+	}
+	if guardado == nil {
+		return
+	}
+	var compartido circuitoCompartido
+	if err := json.Unmarshal([]byte(guardado.Valor), &compartido); err != nil {
+		return
+	}
+
+	// Un restablecimiento hecho en otra instancia también descarta la ventana local
+	if compartido.RestablecidoEn != nil && (s.restablecidoEn == nil || compartido.RestablecidoEn.After(*s.restablecidoEn)) {
+		s.siguiente = 0
+		s.cantidad = 0
+	}
+	s.estado = compartido.Estado
+	s.activadoEn = compartido.ActivadoEn
+	s.tasaActivacion = compartido.TasaActivacion
+	s.restablecidoPor = compartido.RestablecidoPor
+	s.restablecidoEn = compartido.RestablecidoEn
+}
+
+// publicar guarda el estado local para las demás instancias. Al activar no pisa una
+// activación de otra instancia. Debe llamarse con el mutex tomado
+func (s *CircuitoPremiosService) publicar(activacion bool) {
+	local := circuitoCompartido{
+		Estado:          s.estado,
+		ActivadoEn:      s.activadoEn,
+		TasaActivacion:  s.tasaActivacion,
+		RestablecidoPor: s.restablecidoPor,
+		RestablecidoEn:  s.restablecidoEn,
+	}
+	err := repository.ActualizarCompartido(s.compartido, claveCircuitoPremios, func(valor string, existe bool) (string, time.Time, error) {
+		var actual circuitoCompartido
+		if activacion && existe && json.Unmarshal([]byte(valor), &actual) == nil && actual.Estado != CircuitoNormal {
+			return valor, time.Now().Add(vigenciaCircuitoPremios), nil
+		}
+		codificado, err := json.Marshal(local)
+		return string(codificado), time.Now().Add(vigenciaCircuitoPremios), err
+	})
+	if err != nil {
+		log.Printf("⚠️  Error guardando el estado compartido del circuito de premios: %v", err)
+	}
+	// La próxima consulta relee lo guardado por si quedó la activación de otra instancia
+	s.sincronizadoEn = time.Time{}
+}
+
+// tasaVictorias calcula la tasa de la ventana. Debe llamarse con el mutex tomado
+func (s *CircuitoPremiosService) tasaVictorias() float64 {
+	if s.cantidad == 0 {
+		return 0
+	}
+	ganadas := 0
+	for i := 0; i < s.cantidad; i++ {
+		if s.resultados[i] {
+			ganadas++
+		}
+	}
+	return float64(ganadas) / float64(s.cantidad)
+}
+
+// tolerancia calcula la tolerancia vigente. Debe llamarse con el mutex tomado
+func (s *CircuitoPremiosService) tolerancia() float64 {
+	tolerancia := s.configuracion.ParametrosJuego().Tolerancia
+	if s.estado == CircuitoAjustado {
+		return tolerancia / 2
+	}
+	return tolerancia
+}
+
+// estadoActual arma el estado del circuito. Debe llamarse con el mutex tomado
+func (s *CircuitoPremiosService) estadoActual() models.EstadoCircuitoPremios {
+	return models.EstadoCircuitoPremios{
+		Estado:          s.estado,
+		Ventana:         len(s.resultados),
+		PartidasVentana: s.cantidad,
+		TasaVictorias:   math.Round(s.tasaVictorias()*10000) / 10000,
+		TasaMaxima:      s.config.Game.WinRateCeiling,
+		Tolerancia:      s.tolerancia(),
+		ActivadoEn:      s.activadoEn,
+		TasaActivacion:  math.Round(s.tasaActivacion*10000) / 10000,
+		RestablecidoPor: s.restablecidoPor,
+		RestablecidoEn:  s.restablecidoEn,
+	}
+}
@@ -0,0 +1,352 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"sync"
+	"time"
+
+	"CheeseHouse/internal/config"
+	"CheeseHouse/internal/models"
+	"CheeseHouse/internal/repository"
+)
+
+// factorCambioBrusco un parámetro que pasa a valer más del triple o menos de un tercio
+// de lo que valía necesita confirmación (ej. tolerancia de 0.3 a 3.0 por un typo)
+const factorCambioBrusco = 3.0
+
+var (
+	// ErrCambioSuperado el valor cambió después del cambio que se quiere revertir
+	ErrCambioSuperado = errors.New("el valor cambió después; revertí primero los cambios posteriores")
+	// ErrCambioBrusco el cambio supera factorCambioBrusco y no se confirmó
+	ErrCambioBrusco = errors.New("cambio brusco sin confirmar")
+)
+
+// clavesParametrosJuego parámetros del juego versionados, con el nombre de su campo JSON
+var clavesParametrosJuego = []string{"tiempo_min", "tiempo_max", "descuento_ganador", "descuento_perdedor", "tolerancia", "limite_ip", "limite_telefono"}
+
+// ConfiguracionService administra los parámetros del juego editables en caliente y el
+// historial versionado de cambios de configuración (juego y textos de WhatsApp), con
+// la posibilidad de revertir un cambio
+type ConfiguracionService struct {
+	config   *config.Config
+	repo     repository.ConfiguracionRepository
+	mensajes *MensajeConfigService
+
+	mu        sync.RWMutex
+	cache     *models.ParametrosJuego
+	cargadoEn time.Time
+}
+
+// NewConfiguracionService crea una nueva instancia del servicio de configuración
+func NewConfiguracionService(cfg *config.Config, repo repository.ConfiguracionRepository, mensajes *MensajeConfigService) *ConfiguracionService {
+	return &ConfiguracionService{
+		config:   cfg,
+		repo:     repo,
+		mensajes: mensajes,
+	}
+}
+
+// ParametrosJuego retorna los parámetros vigentes del juego; si la base no responde o
+// nunca se editaron, los de la configuración
+func (s *ConfiguracionService) ParametrosJuego() models.ParametrosJuego {
+	s.mu.RLock()
+	if s.cache != nil && s.config.Scaling.CacheVigente(s.cargadoEn) {
+		parametros := *s.cache
+		s.mu.RUnlock()
+		return parametros
+	}
+	s.mu.RUnlock()
+
+	guardados, err := s.repo.ObtenerParametrosJuego()
+	if err != nil {
+		log.Printf("⚠️  Error obteniendo parámetros del juego, usando configuración: %v", err)
+		return s.ParametrosPorDefecto()
+	}
+
+	parametros := s.ParametrosPorDefecto()
+	if guardados != nil {
+		porDefecto := parametros
+		parametros = *guardados
+		// Filas guardadas antes de que existieran los límites
+		if parametros.LimiteIP == 0 {
+			parametros.LimiteIP = porDefecto.LimiteIP
+		}
+		if parametros.LimiteTelefono == 0 {
+			parametros.LimiteTelefono = porDefecto.LimiteTelefono
+		}
+	}
+
+	s.mu.Lock()
+	s.cache = &parametros
+	s.cargadoEn = time.Now()
+	s.mu.Unlock()
+
+	return parametros
+}
+
+// ParametrosPorDefecto retorna los parámetros del juego de la configuración
+func (s *ConfiguracionService) ParametrosPorDefecto() models.ParametrosJuego {
+	return models.ParametrosJuego{
+		TiempoMin:         s.config.Game.MinTargetTime,
+		TiempoMax:         s.config.Game.MaxTargetTime,
+		DescuentoGanador:  s.config.Game.WinDiscount,
+		DescuentoPerdedor: s.config.Game.LoseDiscount,
+		Tolerancia:        s.config.Game.Tolerance,
+		LimiteIP:          s.config.Game.SubmitLimitPerIP,
+		LimiteTelefono:    s.config.Game.SubmitLimitPerPhone,
+	}
+}
+
+// ActualizarParametrosJuego aplica los parámetros enviados y registra un cambio por
+// cada valor que efectivamente cambió
+func (s *ConfiguracionService) ActualizarParametrosJuego(req models.ActualizarParametrosJuegoRequest, usuarioID uint) (models.ParametrosJuego, []*models.CambioConfiguracion, error) {
+	anteriores := s.ParametrosJuego()
+	nuevos := anteriores
+	if req.TiempoMin != nil {
+		nuevos.TiempoMin = *req.TiempoMin
+	}
+	if req.TiempoMax != nil {
+		nuevos.TiempoMax = *req.TiempoMax
+	}
+	if req.DescuentoGanador != nil {
+		nuevos.DescuentoGanador = *req.DescuentoGanador
+	}
+	if req.DescuentoPerdedor != nil {
+		nuevos.DescuentoPerdedor = *req.DescuentoPerdedor
+	}
+	if req.Tolerancia != nil {
+		nuevos.Tolerancia = *req.Tolerancia
+	}
+	if req.LimiteIP != nil {
+		nuevos.LimiteIP = *req.LimiteIP
+	}
+	if req.LimiteTelefono != nil {
+		nuevos.LimiteTelefono = *req.LimiteTelefono
+	}
+
+	if err := validarParametrosJuego(nuevos); err != nil {
+		return anteriores, nil, err
+	}
+	if !req.Confirmar {
+		if err := verificarCambiosBruscos(anteriores, nuevos); err != nil {
+			return anteriores, nil, err
+		}
+	}
+
+	cambios := diferenciasParametros(anteriores, nuevos)
+	if len(cambios) == 0 {
+		return anteriores, cambios, nil
+	}
+	for _, cambio := range cambios {
+		cambio.Motivo = req.Motivo
+		cambio.UsuarioID = usuarioID
+	}
+
+	if err := s.guardarParametros(nuevos, usuarioID, cambios); err != nil {
+		return anteriores, nil, err
+	}
+	for _, cambio := range cambios {
+		log.Printf("⚙️  Usuario %d cambió %s del juego: %s → %s", usuarioID, cambio.Clave, cambio.ValorAnterior, cambio.ValorNuevo)
+	}
+	return s.ParametrosJuego(), cambios, nil
+}
+
+// Historial obtiene los cambios de configuración, del más reciente al más viejo
+func (s *ConfiguracionService) Historial(ambito, clave string, limite int) ([]*models.CambioConfiguracion, error) {
+	if ambito != "" && ambito != models.AmbitoJuego && ambito != models.AmbitoMensaje {
+		return nil, fmt.Errorf("ámbito inválido, usar '%s' o '%s'", models.AmbitoJuego, models.AmbitoMensaje)
+	}
+	if limite <= 0 || limite > 500 {
+		limite = 100
+	}
+	return s.repo.ListarCambios(ambito, clave, limite)
+}
+
+// Revertir vuelve el valor de un cambio a su valor anterior, registrando la reversión
+// como un cambio nuevo. Solo se revierte si el valor sigue siendo el que dejó ese cambio
+func (s *ConfiguracionService) Revertir(cambioID uint, usuarioID uint) (*models.CambioConfiguracion, error) {
+	cambio, err := s.repo.BuscarCambio(cambioID)
+	if err != nil {
+		return nil, err
+	}
+
+	if cambio.Ambito == models.AmbitoMensaje {
+		return s.mensajes.Revertir(cambio, usuarioID)
+	}
+
+	parametros := s.ParametrosJuego()
+	actual, err := valorParametro(parametros, cambio.Clave)
+	if err != nil {
+		return nil, err
+	}
+	if actual != cambio.ValorNuevo {
+		return nil, fmt.Errorf("%w (%s vale %s)", ErrCambioSuperado, cambio.Clave, actual)
+	}
+	if err := asignarParametro(&parametros, cambio.Clave, cambio.ValorAnterior); err != nil {
+		return nil, err
+	}
+	if err := validarParametrosJuego(parametros); err != nil {
+		return nil, err
+	}
+
+	reversion := &models.CambioConfiguracion{
+		Ambito:        models.AmbitoJuego,
+		Clave:         cambio.Clave,
+		ValorAnterior: cambio.ValorNuevo,
+		ValorNuevo:    cambio.ValorAnterior,
+		Motivo:        fmt.Sprintf("Reversión del cambio #%d", cambio.ID),
+		UsuarioID:     usuarioID,
+		RevierteA:     &cambio.ID,
+	}
+	if err := s.guardarParametros(parametros, usuarioID, []*models.CambioConfiguracion{reversion}); err != nil {
+		return nil, err
+	}
+
+	log.Printf("↩️  Usuario %d revirtió el cambio #%d: %s del juego vuelve a %s", usuarioID, cambio.ID, cambio.Clave, cambio.ValorAnterior)
+	return reversion, nil
+}
+
+// guardarParametros persiste los parámetros con sus cambios e invalida el cache
+func (s *ConfiguracionService) guardarParametros(parametros models.ParametrosJuego, usuarioID uint, cambios []*models.CambioConfiguracion) error {
+	guardados, err := s.repo.ObtenerParametrosJuego()
+	if err != nil {
+		return err
+	}
+	if guardados != nil {
+		parametros.ID = guardados.ID
+	}
+	parametros.UpdatedBy = usuarioID
+
+	if err := s.repo.GuardarConCambios(&parametros, cambios); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	s.cache = nil
+	s.mu.Unlock()
+	return nil
+}
+
+// validarParametrosJuego verifica que los parámetros sean coherentes entre sí
+func validarParametrosJuego(p models.ParametrosJuego) error {
+	if p.TiempoMin >= p.TiempoMax {
+		return fmt.Errorf("el tiempo mínimo (%.2fs) debe ser menor que el máximo (%.2fs)", p.TiempoMin, p.TiempoMax)
+	}
+	if p.DescuentoPerdedor > p.DescuentoGanador {
+		return fmt.Errorf("el descuento del perdedor (%d%%) no puede superar al del ganador (%d%%)", p.DescuentoPerdedor, p.DescuentoGanador)
+	}
+	if p.Tolerancia >= (p.TiempoMax-p.TiempoMin)/2 {
+		return fmt.Errorf("la tolerancia (%.3fs) debe ser menor que la mitad del rango de tiempos (%.2fs)", p.Tolerancia, (p.TiempoMax-p.TiempoMin)/2)
+	}
+	if p.LimiteIP < p.LimiteTelefono {
+		return fmt.Errorf("el límite por IP (%d/h) no puede ser menor que el límite por teléfono (%d/h)", p.LimiteIP, p.LimiteTelefono)
+	}
+	return nil
+}
+
+// verificarCambiosBruscos rechaza parámetros que se multiplican o dividen por más de
+// factorCambioBrusco, para que un error de tipeo no pase sin que alguien lo confirme
+func verificarCambiosBruscos(anteriores, nuevos models.ParametrosJuego) error {
+	comparar := []struct {
+		clave           string
+		anterior, nuevo float64
+	}{
+		{"tolerancia", anteriores.Tolerancia, nuevos.Tolerancia},
+		{"descuento_ganador", float64(anteriores.DescuentoGanador), float64(nuevos.DescuentoGanador)},
+		{"descuento_perdedor", float64(anteriores.DescuentoPerdedor), float64(nuevos.DescuentoPerdedor)},
+	}
+	for _, c := range comparar {
+		if c.anterior <= 0 || c.nuevo == c.anterior {
+			continue
+		}
+		if c.nuevo > c.anterior*factorCambioBrusco || c.nuevo < c.anterior/factorCambioBrusco {
+			return fmt.Errorf("%w: %s pasa de %s a %s; reenviar con \"confirmar\": true si es correcto",
+				ErrCambioBrusco, c.clave, formatearValor(c.anterior), formatearValor(c.nuevo))
+		}
+	}
+	return nil
+}
+
+// diferenciasParametros arma un cambio por cada parámetro distinto
+func diferenciasParametros(anteriores, nuevos models.ParametrosJuego) []*models.CambioConfiguracion {
+	cambios := []*models.CambioConfiguracion{}
+	for _, clave := range clavesParametrosJuego {
+		anterior, _ := valorParametro(anteriores, clave)
+		nuevo, _ := valorParametro(nuevos, clave)
+		if anterior != nuevo {
+			cambios = append(cambios, &models.CambioConfiguracion{
+				Ambito:        models.AmbitoJuego,
+				Clave:         clave,
+				ValorAnterior: anterior,
+				ValorNuevo:    nuevo,
+			})
+		}
+	}
+	return cambios
+}
+
+// valorParametro retorna el valor de un parámetro como texto, tal como queda en el historial
+func valorParametro(p models.ParametrosJuego, clave string) (string, error) {
+	switch clave {
+	case "tiempo_min":
+		return formatearValor(p.TiempoMin), nil
+	case "tiempo_max":
+		return formatearValor(p.TiempoMax), nil
+	case "descuento_ganador":
+		return strconv.Itoa(p.DescuentoGanador), nil
+	case "descuento_perdedor":
+		return strconv.Itoa(p.DescuentoPerdedor), nil
+	case "tolerancia":
+		return formatearValor(p.Tolerancia), nil
+	case "limite_ip":
+		return strconv.Itoa(p.LimiteIP), nil
+	case "limite_telefono":
+		return strconv.Itoa(p.LimiteTelefono), nil
+	}
+	return "", fmt.Errorf("parámetro del juego desconocido: %q", clave)
+}
+
+// asignarParametro fija un parámetro a partir de su valor en el historial
+func asignarParametro(p *models.ParametrosJuego, clave, valor string) error {
+	switch clave {
+	case "descuento_ganador", "descuento_perdedor", "limite_ip", "limite_telefono":
+		entero, err := strconv.Atoi(valor)
+		if err != nil {
+			return fmt.Errorf("valor inválido para %s: %q", clave, valor)
+		}
+		switch clave {
+		case "descuento_ganador":
+			p.DescuentoGanador = entero
+		case "descuento_perdedor":
+			p.DescuentoPerdedor = entero
+		case "limite_ip":
+			p.LimiteIP = entero
+		default:
+			p.LimiteTelefono = entero
+		}
+		return nil
+	case "tiempo_min", "tiempo_max", "tolerancia":
+		decimal, err := strconv.ParseFloat(valor, 64)
+		if err != nil {
+			return fmt.Errorf("valor inválido para %s: %q", clave, valor)
+		}
+		switch clave {
+		case "tiempo_min":
+			p.TiempoMin = decimal
+		case "tiempo_max":
+			p.TiempoMax = decimal
+		default:
+			p.Tolerancia = decimal
+		}
+		return nil
+	}
+	return fmt.Errorf("parámetro del juego desconocido: %q", clave)
+}
+
+// formatearValor escribe un número sin ceros de más (0.3, no 0.300000)
+func formatearValor(valor float64) string {
+	return strconv.FormatFloat(valor, 'f', -1, 64)
+}
@@ -0,0 +1,386 @@
+package services
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"CheeseHouse/internal/config"
+	"CheeseHouse/internal/models"
+	"CheeseHouse/internal/repository"
+)
+
+// ventanaAtencionWhatsApp tiempo desde el último mensaje del cliente en el que
+// WhatsApp permite responder con texto libre (fuera de ella se requiere template)
+const ventanaAtencionWhatsApp = 24 * time.Hour
+
+// mensajesPorConversacion cantidad de mensajes que se muestran de cada hilo
+const mensajesPorConversacion = 100
+
+// ConversacionService guarda los mensajes recibidos por WhatsApp y permite al
+// staff leerlos y responderlos desde el panel como una bandeja compartida
+type ConversacionService struct {
+	config           *config.Config
+	conversacionRepo repository.ConversacionRepository
+	atencionRepo     repository.AtencionRepository
+	respuestas       *RespuestaRapidaService
+	clienteRepo      *repository.ClienteRepository
+	whatsapp         *WhatsAppService
+}
+
+// NewConversacionService crea una nueva instancia del servicio de conversaciones
+func NewConversacionService(
+	cfg *config.Config,
+	conversacionRepo repository.ConversacionRepository,
+	atencionRepo repository.AtencionRepository,
+	clienteRepo *repository.ClienteRepository,
+	whatsapp *WhatsAppService,
+	respuestas *RespuestaRapidaService,
+) *ConversacionService {
+	return &ConversacionService{
+		config:           cfg,
+		conversacionRepo: conversacionRepo,
+		atencionRepo:     atencionRepo,
+		clienteRepo:      clienteRepo,
+		whatsapp:         whatsapp,
+		respuestas:       respuestas,
+	}
+}
+
+// RegistrarEntrantes guarda todos los mensajes del webhook (no solo pedidos).
+// Los reintentos de Meta se descartan por el ID del mensaje
+func (s *ConversacionService) RegistrarEntrantes(webhook models.WhatsAppWebhookMessage) int {
+	registrados := 0
+
+	for _, entry := range webhook.Entry {
+		for _, change := range entry.Changes {
+			if change.Field != "messages" {
+				continue
+			}
+
+			nombres := make(map[string]string)
+			for _, contact := range change.Value.Contacts {
+				nombres[contact.WaID] = contact.Profile.Name
+			}
+
+			for _, message := range change.Value.Messages {
+				if message.ID != "" {
+					if existe, err := s.conversacionRepo.ExisteMessageID(message.ID); err == nil && existe {
+						continue
+					}
+				}
+
+				texto := message.Text.Body
+				if message.Type != "text" {
+					texto = fmt.Sprintf("[%s]", message.Type)
+				}
+
+				mensaje := &models.Conversacion{
+					Telefono:       s.whatsapp.normalizePhoneNumber(message.From),
+					Direccion:      "entrante",
+					Tipo:           message.Type,
+					Mensaje:        texto,
+					MessageID:      message.ID,
+					NombreContacto: nombres[message.From],
+				}
+				if cliente, err := s.clienteRepo.BuscarPorTelefono(mensaje.Telefono); err == nil {
+					mensaje.ClienteID = &cliente.ID
+				}
+
+				if err := s.conversacionRepo.Crear(mensaje); err != nil {
+					log.Printf("❌ Error guardando mensaje de %s: %v", mensaje.Telefono, err)
+					continue
+				}
+				registrados++
+				s.abrirAtencion(mensaje.Telefono, mensaje.CreatedAt)
+			}
+		}
+	}
+
+	return registrados
+}
+
+// ListarHilos obtiene las conversaciones y el total de mensajes sin leer
+func (s *ConversacionService) ListarHilos(soloNoLeidos bool) ([]*models.HiloConversacion, int, error) {
+	hilos, err := s.conversacionRepo.ListarHilos(soloNoLeidos)
+	if err != nil {
+		return nil, 0, err
+	}
+	noLeidos, err := s.conversacionRepo.ContarNoLeidos()
+	if err != nil {
+		return nil, 0, err
+	}
+	return hilos, noLeidos, nil
+}
+
+// GetConversacion obtiene los mensajes con un teléfono y los marca como leídos
+func (s *ConversacionService) GetConversacion(telefono string) ([]*models.Conversacion, error) {
+	telefono = s.whatsapp.normalizePhoneNumber(telefono)
+
+	mensajes, err := s.conversacionRepo.GetMensajes(telefono, mensajesPorConversacion)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.conversacionRepo.MarcarLeidos(telefono); err != nil {
+		log.Printf("⚠️  %v", err)
+	}
+	return mensajes, nil
+}
+
+// Responder envía una respuesta manual, escrita o a partir de una respuesta rápida, y la agrega al hilo
+func (s *ConversacionService) Responder(telefono string, req models.ResponderConversacionRequest, usuarioID uint) (*models.Conversacion, error) {
+	telefono = s.whatsapp.normalizePhoneNumber(telefono)
+	cliente, _ := s.clienteRepo.BuscarPorTelefono(telefono)
+
+	texto := strings.TrimSpace(req.Mensaje)
+	if texto == "" && req.RespuestaID != 0 {
+		nombre := ""
+		if cliente != nil {
+			nombre = cliente.Nombre
+		}
+		rapida, err := s.respuestas.Preparar(req.RespuestaID, nombre)
+		if err != nil {
+			return nil, err
+		}
+		texto = rapida
+	}
+	if texto == "" {
+		return nil, fmt.Errorf("el mensaje no puede estar vacío")
+	}
+
+	ultimo, err := s.conversacionRepo.UltimoEntrante(telefono)
+	if err != nil {
+		return nil, err
+	}
+	if ultimo == nil {
+		return nil, fmt.Errorf("no hay mensajes de %s para responder", telefono)
+	}
+	if time.Since(*ultimo) > ventanaAtencionWhatsApp {
+		return nil, fmt.Errorf("pasaron más de 24 horas desde el último mensaje del cliente; WhatsApp solo permite responder con un template")
+	}
+
+	if err := s.whatsapp.EnviarRespuestaManual(telefono, texto); err != nil {
+		return nil, fmt.Errorf("error enviando respuesta: %w", err)
+	}
+
+	mensaje := &models.Conversacion{
+		Telefono:   telefono,
+		Direccion:  "saliente",
+		Tipo:       "text",
+		Mensaje:    texto,
+		Leido:      true,
+		EnviadoPor: &usuarioID,
+	}
+	if cliente != nil {
+		mensaje.ClienteID = &cliente.ID
+	}
+	if req.RespuestaID != 0 {
+		mensaje.RespuestaID = &req.RespuestaID
+		s.respuestas.RegistrarUso(req.RespuestaID)
+	}
+	if err := s.conversacionRepo.Crear(mensaje); err != nil {
+		// El mensaje ya salió; solo falta en el historial de la bandeja
+		log.Printf("⚠️  Respuesta enviada a %s pero no guardada: %v", telefono, err)
+	}
+
+	if err := s.conversacionRepo.MarcarLeidos(telefono); err != nil {
+		log.Printf("⚠️  %v", err)
+	}
+	s.registrarRespuesta(telefono, usuarioID)
+
+	log.Printf("💬 Usuario %d respondió a %s", usuarioID, telefono)
+	return mensaje, nil
+}
+
+// TextoPendiente junta los mensajes de texto del cliente posteriores a la última
+// respuesta del staff, que es lo que normalmente forma el pedido en curso
+func (s *ConversacionService) TextoPendiente(telefono string) (string, error) {
+	telefono = s.whatsapp.normalizePhoneNumber(telefono)
+
+	mensajes, err := s.conversacionRepo.GetMensajes(telefono, mensajesPorConversacion)
+	if err != nil {
+		return "", err
+	}
+
+	var partes []string
+	for i := len(mensajes) - 1; i >= 0; i-- {
+		if mensajes[i].Direccion == "saliente" {
+			break
+		}
+		if mensajes[i].Tipo == "text" {
+			partes = append([]string{mensajes[i].Mensaje}, partes...)
+		}
+	}
+	if len(partes) == 0 {
+		return "", fmt.Errorf("no hay mensajes del cliente sin responder")
+	}
+	return strings.Join(partes, "\n"), nil
+}
+
+// Asignar toma una atención abierta para el usuario. Falla si ya la tomó otro empleado
+func (s *ConversacionService) Asignar(telefono string, usuarioID uint) (*models.Atencion, error) {
+	atencion, err := s.buscarAbierta(telefono)
+	if err != nil {
+		return nil, err
+	}
+	if atencion.AsignadoA != nil && *atencion.AsignadoA != usuarioID {
+		return nil, fmt.Errorf("la conversación ya fue tomada por otro empleado")
+	}
+
+	ahora := time.Now()
+	atencion.AsignadoA = &usuarioID
+	atencion.AsignadoAt = &ahora
+	if err := s.atencionRepo.Actualizar(atencion); err != nil {
+		return nil, err
+	}
+
+	log.Printf("🙋 Usuario %d tomó la conversación con %s", usuarioID, atencion.Telefono)
+	s.marcarSLA(atencion, ahora)
+	return atencion, nil
+}
+
+// Resolver cierra la atención abierta del teléfono
+func (s *ConversacionService) Resolver(telefono string, usuarioID uint) (*models.Atencion, error) {
+	atencion, err := s.buscarAbierta(telefono)
+	if err != nil {
+		return nil, err
+	}
+
+	ahora := time.Now()
+	atencion.Estado = "resuelta"
+	atencion.ResueltaAt = &ahora
+	atencion.ResueltaPor = &usuarioID
+	if atencion.AsignadoA == nil {
+		atencion.AsignadoA = &usuarioID
+		atencion.AsignadoAt = &ahora
+	}
+	if err := s.atencionRepo.Actualizar(atencion); err != nil {
+		return nil, err
+	}
+
+	log.Printf("✅ Usuario %d resolvió la conversación con %s en %s",
+		usuarioID, atencion.Telefono, ahora.Sub(atencion.PrimerMensajeAt).Round(time.Minute))
+	s.marcarSLA(atencion, ahora)
+	return atencion, nil
+}
+
+// GetResumenSLA obtiene las atenciones abiertas que incumplen el SLA y los tiempos promedio del último día
+func (s *ConversacionService) GetResumenSLA() (*models.ResumenSLA, error) {
+	ahora := time.Now()
+	resumen := &models.ResumenSLA{
+		MinutosObjetivoRespuesta:  s.config.Alerts.SLAFirstResponseMinutes,
+		MinutosObjetivoResolucion: s.config.Alerts.SLAResolutionMinutes,
+		Incumplimientos:           []*models.Atencion{},
+	}
+
+	abiertas, err := s.atencionRepo.ListarAbiertas()
+	if err != nil {
+		return nil, err
+	}
+	for _, atencion := range abiertas {
+		s.marcarSLA(atencion, ahora)
+		resumen.Abiertas++
+		if atencion.AsignadoA == nil {
+			resumen.SinAsignar++
+		}
+		if atencion.IncumpleRespuesta {
+			resumen.IncumplenRespuesta++
+		}
+		if atencion.IncumpleResolucion {
+			resumen.IncumplenResolucion++
+		}
+		if atencion.IncumpleRespuesta || atencion.IncumpleResolucion {
+			resumen.Incumplimientos = append(resumen.Incumplimientos, atencion)
+		}
+	}
+
+	promedios, err := s.atencionRepo.GetPromedios(ahora.Add(-24 * time.Hour))
+	if err != nil {
+		return nil, err
+	}
+	resumen.ResueltasUltimas24 = promedios.Resueltas
+	resumen.PromedioRespuestaMinutos = redondearMonto(promedios.RespuestaSegundos / 60)
+	resumen.PromedioResolucionMinutos = redondearMonto(promedios.ResolucionSegundos / 60)
+
+	return resumen, nil
+}
+
+// abrirAtencion crea una atención para el teléfono si no tiene una abierta
+func (s *ConversacionService) abrirAtencion(telefono string, recibido time.Time) {
+	abierta, err := s.atencionRepo.BuscarAbierta(telefono)
+	if err != nil {
+		log.Printf("⚠️  %v", err)
+		return
+	}
+	if abierta != nil {
+		return
+	}
+
+	if err := s.atencionRepo.Crear(&models.Atencion{
+		Telefono:        telefono,
+		Estado:          "abierta",
+		PrimerMensajeAt: recibido,
+	}); err != nil {
+		log.Printf("⚠️  %v", err)
+	}
+}
+
+// registrarRespuesta marca la primera respuesta de la atención abierta y,
+// si nadie la había tomado, la asigna a quien respondió
+func (s *ConversacionService) registrarRespuesta(telefono string, usuarioID uint) {
+	atencion, err := s.atencionRepo.BuscarAbierta(telefono)
+	if err != nil || atencion == nil {
+		return
+	}
+
+	ahora := time.Now()
+	cambios := false
+	if atencion.PrimeraRespuestaAt == nil {
+		atencion.PrimeraRespuestaAt = &ahora
+		cambios = true
+	}
+	if atencion.AsignadoA == nil {
+		atencion.AsignadoA = &usuarioID
+		atencion.AsignadoAt = &ahora
+		cambios = true
+	}
+	if !cambios {
+		return
+	}
+
+	if err := s.atencionRepo.Actualizar(atencion); err != nil {
+		log.Printf("⚠️  %v", err)
+	}
+}
+
+// buscarAbierta obtiene la atención abierta de un teléfono o un error si no hay
+func (s *ConversacionService) buscarAbierta(telefono string) (*models.Atencion, error) {
+	telefono = s.whatsapp.normalizePhoneNumber(telefono)
+	atencion, err := s.atencionRepo.BuscarAbierta(telefono)
+	if err != nil {
+		return nil, err
+	}
+	if atencion == nil {
+		return nil, fmt.Errorf("no hay una conversación abierta con %s", telefono)
+	}
+	return atencion, nil
+}
+
+// marcarSLA calcula si la atención incumple los tiempos objetivo configurados
+func (s *ConversacionService) marcarSLA(atencion *models.Atencion, ahora time.Time) {
+	objetivoRespuesta := time.Duration(s.config.Alerts.SLAFirstResponseMinutes) * time.Minute
+	objetivoResolucion := time.Duration(s.config.Alerts.SLAResolutionMinutes) * time.Minute
+
+	respondida := ahora
+	if atencion.PrimeraRespuestaAt != nil {
+		respondida = *atencion.PrimeraRespuestaAt
+	}
+	resuelta := ahora
+	if atencion.ResueltaAt != nil {
+		resuelta = *atencion.ResueltaAt
+	}
+
+	atencion.IncumpleRespuesta = objetivoRespuesta > 0 && respondida.Sub(atencion.PrimerMensajeAt) > objetivoRespuesta
+	atencion.IncumpleResolucion = objetivoResolucion > 0 && resuelta.Sub(atencion.PrimerMensajeAt) > objetivoResolucion
+}
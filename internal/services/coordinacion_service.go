@@ -0,0 +1,195 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	"CheeseHouse/internal/config"
+	"CheeseHouse/internal/repository"
+)
+
+const (
+	// claveLider clave del estado compartido con la instancia líder
+	claveLider = "lider"
+	// duracionLiderazgo cuánto vale el liderazgo sin renovarse; si la líder se cae, otra
+	// instancia lo toma después de este tiempo
+	duracionLiderazgo = 30 * time.Second
+	// renovacionLiderazgo cada cuánto la líder renueva y las demás intentan tomarlo
+	renovacionLiderazgo = 10 * time.Second
+	// duracionTurno cuánto se guarda que una ejecución programada ya se tomó; alcanza con
+	// que cubra la diferencia de relojes y un cambio de líder en medio de la ejecución
+	duracionTurno = 24 * time.Hour
+)
+
+// errOtraInstanciaLider el liderazgo vigente es de otra instancia
+var errOtraInstanciaLider = errors.New("otra instancia es líder")
+
+// CoordinacionService decide qué instancia corre las tareas en segundo plano que no se
+// pueden duplicar (outbox, reportes programados, resúmenes, mantenimiento). La líder
+// renueva su liderazgo en el estado compartido; si deja de hacerlo, otra lo toma cuando
+// vence. Con estado en memoria hay una sola instancia y siempre es la líder
+type CoordinacionService struct {
+	config *config.Config
+	estado repository.EstadoCompartidoRepository
+
+	mu         sync.Mutex
+	liderHasta time.Time
+	detener    chan struct{}
+	detenido   sync.Once
+}
+
+// NewCoordinacionService crea una nueva instancia del servicio de coordinación
+func NewCoordinacionService(cfg *config.Config, estado repository.EstadoCompartidoRepository) *CoordinacionService {
+	return &CoordinacionService{
+		config:  cfg,
+		estado:  estado,
+		detener: make(chan struct{}),
+	}
+}
+
+// IniciarLiderazgo intenta tomar el liderazgo antes de volver (así las tareas que corren
+// al arrancar ya saben si les toca) y después lo renueva periódicamente hasta Detener
+func (s *CoordinacionService) IniciarLiderazgo() {
+	if !s.config.Scaling.Compartido() {
+		return
+	}
+	s.renovar()
+	go func() {
+		ticker := time.NewTicker(renovacionLiderazgo)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-s.detener:
+				return
+			case <-ticker.C:
+			}
+			s.renovar()
+		}
+	}()
+}
+
+// EsLider indica si esta instancia debe correr las tareas únicas. Se consulta antes de
+// cada pasada, así una tarea no arranca si el liderazgo pasó a otra instancia
+func (s *CoordinacionService) EsLider() bool {
+	if !s.config.Scaling.Compartido() {
+		return true
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return time.Now().Before(s.liderHasta)
+}
+
+// TomarTurno reserva la ejecución programada de la tarea para el momento indicado y
+// retorna si le toca a esta instancia. Solo la líder la intenta, y el turno queda anotado
+// en el estado compartido: si el liderazgo cambia justo en ese momento o los relojes no
+// coinciden, la misma ejecución no corre dos veces. Si el estado compartido no responde se
+// saltea la ejecución (la próxima la retoma) antes que arriesgar duplicarla
+func (s *CoordinacionService) TomarTurno(tarea string, programada time.Time) bool {
+	if !s.config.Scaling.Compartido() {
+		return true
+	}
+	if !s.EsLider() {
+		return false
+	}
+
+	clave := "turno:" + tarea + ":" + programada.UTC().Format(time.RFC3339)
+	tomado, err := s.estado.Crear(clave, s.config.Scaling.InstanceID, programada.Add(duracionTurno))
+	if err != nil {
+		log.Printf("⚠️  No se pudo reservar la ejecución de %s, se saltea: %v", tarea, err)
+		return false
+	}
+	if !tomado {
+		log.Printf("👑 La ejecución de %s de las %s ya estaba tomada, se saltea", tarea, programada.Format("15:04"))
+	}
+	return tomado
+}
+
+// PublicarEstado guarda el resultado de un monitor que corre solo en la líder, para que
+// las demás instancias lo tomen sin repetir la consulta ni las alertas. Vence tras
+// vigencia, así una líder caída no deja un estado viejo para siempre
+func (s *CoordinacionService) PublicarEstado(clave string, valor interface{}, vigencia time.Duration) {
+	if !s.config.Scaling.Compartido() {
+		return
+	}
+	codificado, err := json.Marshal(valor)
+	if err != nil {
+		log.Printf("⚠️  No se pudo codificar el estado de %s: %v", clave, err)
+		return
+	}
+	err = repository.ActualizarCompartido(s.estado, "monitor:"+clave, func(string, bool) (string, time.Time, error) {
+		return string(codificado), time.Now().Add(vigencia), nil
+	})
+	if err != nil {
+		log.Printf("⚠️  No se pudo publicar el estado de %s: %v", clave, err)
+	}
+}
+
+// LeerEstado carga en destino lo último que publicó la líder y retorna false si no hay
+// nada vigente
+func (s *CoordinacionService) LeerEstado(clave string, destino interface{}) bool {
+	guardado, err := s.estado.Obtener("monitor:" + clave)
+	if err != nil {
+		log.Printf("⚠️  No se pudo leer el estado de %s: %v", clave, err)
+		return false
+	}
+	return guardado != nil && json.Unmarshal([]byte(guardado.Valor), destino) == nil
+}
+
+// Detener deja de renovar y libera el liderazgo para que otra instancia lo tome enseguida
+func (s *CoordinacionService) Detener() {
+	s.detenido.Do(func() { close(s.detener) })
+	if !s.EsLider() || !s.config.Scaling.Compartido() {
+		return
+	}
+
+	actual, err := s.estado.Obtener(claveLider)
+	if err == nil && actual != nil && actual.Valor == s.config.Scaling.InstanceID {
+		if err := s.estado.Eliminar(claveLider); err != nil {
+			log.Printf("⚠️  No se pudo liberar el liderazgo: %v", err)
+			return
+		}
+		log.Printf("👑 Instancia %s liberó el liderazgo", s.config.Scaling.InstanceID)
+	}
+}
+
+// Estado resume la instancia y su rol para /info
+func (s *CoordinacionService) Estado() map[string]interface{} {
+	return map[string]interface{}{
+		"id":                s.config.Scaling.InstanceID,
+		"lider":             s.EsLider(),
+		"estado_compartido": s.config.Scaling.SharedState,
+	}
+}
+
+// renovar extiende el liderazgo si es de esta instancia o lo toma si está libre
+func (s *CoordinacionService) renovar() {
+	id := s.config.Scaling.InstanceID
+	vence := time.Now().Add(duracionLiderazgo)
+	err := repository.ActualizarCompartido(s.estado, claveLider, func(valor string, existe bool) (string, time.Time, error) {
+		if existe && valor != id {
+			return "", time.Time{}, errOtraInstanciaLider
+		}
+		return id, vence, nil
+	})
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	eraLider := time.Now().Before(s.liderHasta)
+	if err != nil {
+		if !errors.Is(err, errOtraInstanciaLider) {
+			log.Printf("⚠️  Error renovando el liderazgo: %v", err)
+		}
+		if eraLider {
+			log.Printf("👑 Instancia %s dejó de ser líder", id)
+		}
+		s.liderHasta = time.Time{}
+		return
+	}
+	if !eraLider {
+		log.Printf("👑 Instancia %s es la líder: corre las tareas en segundo plano", id)
+	}
+	s.liderHasta = vence
+}
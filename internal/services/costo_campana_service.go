@@ -0,0 +1,147 @@
+package services
+
+import (
+	"log"
+	"math"
+	"time"
+
+	"CheeseHouse/internal/config"
+	"CheeseHouse/internal/models"
+	"CheeseHouse/internal/repository"
+)
+
+// muestraMinimaTasaCanje vouchers resueltos necesarios para confiar en la tasa histórica
+const muestraMinimaTasaCanje = 30
+
+// CostoCampanaService estima el costo de una campaña antes de enviarla
+// (conversaciones de marketing + descuentos que se proyecta canjear)
+// y lo concilia con los resultados reales
+type CostoCampanaService struct {
+	config       *config.Config
+	campanaRepo  repository.CampanaRepository
+	voucherRepo  repository.VoucherRepository
+	preferencias *PreferenciasService
+	whatsapp     *WhatsAppService
+}
+
+// NewCostoCampanaService crea una nueva instancia del servicio de costos de campañas
+func NewCostoCampanaService(
+	cfg *config.Config,
+	campanaRepo repository.CampanaRepository,
+	voucherRepo repository.VoucherRepository,
+	preferencias *PreferenciasService,
+	whatsapp *WhatsAppService,
+) *CostoCampanaService {
+	return &CostoCampanaService{
+		config:       cfg,
+		campanaRepo:  campanaRepo,
+		voucherRepo:  voucherRepo,
+		preferencias: preferencias,
+		whatsapp:     whatsapp,
+	}
+}
+
+// Estimar calcula el costo proyectado de enviar una campaña con el descuento dado
+// a los clientes seleccionados, sin registrarlo
+func (s *CostoCampanaService) Estimar(descuento int, clientesIDs []uint) *models.EstimacionCostoCampana {
+	costos := s.config.Costs
+
+	conversaciones := 0
+	for _, clienteID := range clientesIDs {
+		if s.preferencias == nil || s.preferencias.PermiteEnvio(clienteID, CategoriaMarketing, "whatsapp") {
+			conversaciones++
+		}
+	}
+
+	tasa := s.tasaCanjeProyectada()
+	canjes := float64(conversaciones) * tasa
+	costoMensajes := float64(conversaciones) * costos.MarketingConversationCost
+	costoDescuentos := canjes * costos.AverageTicket * float64(descuento) / 100
+
+	return &models.EstimacionCostoCampana{
+		Moneda:                  costos.Currency,
+		Destinatarios:           len(clientesIDs),
+		ConversacionesMarketing: conversaciones,
+		PrecioConversacion:      costos.MarketingConversationCost,
+		CostoMensajesEstimado:   redondearMonto(costoMensajes),
+		TasaCanjeProyectada:     math.Round(tasa*10000) / 10000,
+		CanjesProyectados:       math.Round(canjes*100) / 100,
+		CostoDescuentosEstimado: redondearMonto(costoDescuentos),
+		CostoTotalEstimado:      redondearMonto(costoMensajes + costoDescuentos),
+	}
+}
+
+// RegistrarEstimacion guarda la estimación de una campaña al momento de enviarla
+// para compararla luego con el resultado real
+func (s *CostoCampanaService) RegistrarEstimacion(campanaID uint, descuento int, clientesIDs []uint) (*models.EstimacionCostoCampana, error) {
+	estimacion := s.Estimar(descuento, clientesIDs)
+	estimacion.CampanaID = campanaID
+
+	if existente, err := s.campanaRepo.BuscarEstimacion(campanaID); err == nil {
+		estimacion.ID = existente.ID
+		estimacion.CreatedAt = existente.CreatedAt
+	}
+
+	if err := s.campanaRepo.GuardarEstimacion(estimacion); err != nil {
+		return nil, err
+	}
+
+	log.Printf("💰 Costo estimado de campaña %d: %.2f %s (%d conversaciones, %.1f canjes proyectados)",
+		campanaID, estimacion.CostoTotalEstimado, estimacion.Moneda,
+		estimacion.ConversacionesMarketing, estimacion.CanjesProyectados)
+	return estimacion, nil
+}
+
+// Conciliar actualiza la estimación de una campaña con las conversaciones y canjes reales
+func (s *CostoCampanaService) Conciliar(campanaID uint) (*models.EstimacionCostoCampana, error) {
+	estimacion, err := s.campanaRepo.BuscarEstimacion(campanaID)
+	if err != nil {
+		return nil, err
+	}
+
+	resultados, err := s.campanaRepo.GetResultadosCosto(campanaID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Se usan los precios registrados al estimar para que la comparación sea consistente
+	costoMensajes := float64(resultados.Conversaciones) * estimacion.PrecioConversacion
+	costoDescuentos := s.config.Costs.AverageTicket * float64(resultados.DescuentoCanjes) / 100
+
+	ahora := time.Now()
+	estimacion.ConversacionesReales = resultados.Conversaciones
+	estimacion.CanjesReales = resultados.Canjes
+	estimacion.CostoMensajesReal = redondearMonto(costoMensajes)
+	estimacion.CostoDescuentosReal = redondearMonto(costoDescuentos)
+	estimacion.CostoTotalReal = redondearMonto(costoMensajes + costoDescuentos)
+	estimacion.ConciliadoAt = &ahora
+
+	if err := s.campanaRepo.GuardarEstimacion(estimacion); err != nil {
+		return nil, err
+	}
+	return estimacion, nil
+}
+
+// VerificarCuota indica si la campaña entra en la cuota restante de WhatsApp
+func (s *CostoCampanaService) VerificarCuota(conversaciones int) error {
+	return s.whatsapp.VerificarCuota(conversaciones)
+}
+
+// tasaCanjeProyectada usa la tasa histórica de vouchers promocionales si hay
+// muestra suficiente, o la configurada en caso contrario
+func (s *CostoCampanaService) tasaCanjeProyectada() float64 {
+	tasa, muestra, err := s.voucherRepo.GetTasaCanjePorTipo("cliente_promocion")
+	if err != nil {
+		log.Printf("⚠️  Error obteniendo tasa histórica de canje: %v", err)
+		return s.config.Costs.DefaultRedemptionRate
+	}
+	if muestra < muestraMinimaTasaCanje {
+		return s.config.Costs.DefaultRedemptionRate
+	}
+	return tasa
+}
+
+// redondearMonto redondea un importe a 2 decimales
+func redondearMonto(monto float64) float64 {
+	return math.Round(monto*100) / 100
+}
@@ -0,0 +1,106 @@
+package services
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"CheeseHouse/internal/config"
+	"CheeseHouse/internal/models"
+	"CheeseHouse/internal/repository"
+)
+
+// DiaSinCanjeService administra las fechas en que la caja no acepta vouchers
+// (feriados, eventos especiales) y calcula el próximo día en que sí
+type DiaSinCanjeService struct {
+	config *config.Config
+	repo   repository.DiaSinCanjeRepository
+}
+
+// NewDiaSinCanjeService crea una nueva instancia del servicio de días sin canje
+func NewDiaSinCanjeService(cfg *config.Config, repo repository.DiaSinCanjeRepository) *DiaSinCanjeService {
+	return &DiaSinCanjeService{
+		config: cfg,
+		repo:   repo,
+	}
+}
+
+// Listar obtiene las fechas sin canje desde hoy hasta dentro de un año
+func (s *DiaSinCanjeService) Listar() ([]*models.DiaSinCanje, error) {
+	hoy := s.config.InicioDelDia(time.Now())
+	return s.repo.ListarEntre(hoy, hoy.AddDate(1, 0, 0))
+}
+
+// Crear bloquea el canje en una fecha
+func (s *DiaSinCanjeService) Crear(req models.CrearDiaSinCanjeRequest, usuarioID uint) (*models.DiaSinCanje, error) {
+	fecha, err := time.ParseInLocation("2006-01-02", req.Fecha, s.config.GetLocation())
+	if err != nil {
+		return nil, fmt.Errorf("fecha inválida, usar el formato AAAA-MM-DD")
+	}
+	if fecha.Before(s.config.InicioDelDia(time.Now())) {
+		return nil, fmt.Errorf("la fecha ya pasó")
+	}
+	existentes, err := s.repo.ListarEntre(fecha, fecha)
+	if err != nil {
+		return nil, err
+	}
+	if len(existentes) > 0 {
+		return nil, fmt.Errorf("el %s ya está bloqueado", s.config.Fechas("es").Fecha(fecha))
+	}
+
+	dia := &models.DiaSinCanje{
+		Fecha:     fecha,
+		Motivo:    strings.TrimSpace(req.Motivo),
+		CreatedBy: usuarioID,
+	}
+	if err := s.repo.Crear(dia); err != nil {
+		return nil, err
+	}
+
+	log.Printf("🚫 Usuario %d bloqueó el canje de vouchers el %s (%s)", usuarioID, req.Fecha, dia.Motivo)
+	return dia, nil
+}
+
+// Eliminar vuelve a habilitar el canje en una fecha
+func (s *DiaSinCanjeService) Eliminar(id uint, usuarioID uint) error {
+	if err := s.repo.Eliminar(id); err != nil {
+		return err
+	}
+	log.Printf("🚫 Usuario %d habilitó el día sin canje #%d", usuarioID, id)
+	return nil
+}
+
+// Verificar indica si en t no se aceptan vouchers y, en ese caso, el primer día
+// siguiente habilitado. Retorna nil si se puede canjear
+func (s *DiaSinCanjeService) Verificar(t time.Time) (*models.DiaSinCanje, time.Time, error) {
+	hoy := s.config.InicioDelDia(t)
+	dias, err := s.repo.ListarEntre(hoy, hoy.AddDate(1, 0, 0))
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+
+	bloqueados := make(map[string]*models.DiaSinCanje, len(dias))
+	for _, dia := range dias {
+		bloqueados[dia.Fecha.Format("2006-01-02")] = dia
+	}
+
+	actual := bloqueados[hoy.Format("2006-01-02")]
+	if actual == nil {
+		return nil, time.Time{}, nil
+	}
+	proximo := hoy.AddDate(0, 0, 1)
+	for bloqueados[proximo.Format("2006-01-02")] != nil {
+		proximo = proximo.AddDate(0, 0, 1)
+	}
+	return actual, proximo, nil
+}
+
+// EnVigencia obtiene las fechas sin canje entre hoy y el vencimiento del voucher
+func (s *DiaSinCanjeService) EnVigencia(voucher *models.Voucher) ([]*models.DiaSinCanje, error) {
+	hoy := s.config.InicioDelDia(time.Now())
+	if voucher.Usado || voucher.FechaVencimiento.Before(hoy) {
+		return []*models.DiaSinCanje{}, nil
+	}
+	return s.repo.ListarEntre(hoy, voucher.FechaVencimiento.In(s.config.GetLocation()))
+}
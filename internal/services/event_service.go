@@ -0,0 +1,154 @@
+package services
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"CheeseHouse/internal/config"
+	"CheeseHouse/internal/models"
+)
+
+// Tipos de eventos del ciclo de vida de clientes
+const (
+	EventoClienteCreado      = "cliente.creado"
+	EventoClienteActualizado = "cliente.actualizado"
+	EventoClienteBloqueado   = "cliente.bloqueado"
+	EventoClienteFusionado   = "cliente.fusionado"
+	EventoClienteAnonimizado = "cliente.anonimizado"
+)
+
+// Tipos de eventos operativos
+const (
+	EventoCircuitoPremiosActivado = "juego.circuito_premios_activado"
+)
+
+// EventService publica eventos de dominio hacia webhooks externos (ej. un CRM)
+type EventService struct {
+	config *config.Config
+	client *http.Client
+
+	// Entregas en curso, para que Esperar no corte los reintentos
+	enCurso sync.WaitGroup
+}
+
+// NewEventService crea una nueva instancia del servicio de eventos
+func NewEventService(cfg *config.Config) *EventService {
+	return &EventService{
+		config: cfg,
+		client: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// Emitir publica un evento de forma asíncrona en todos los webhooks configurados
+func (e *EventService) Emitir(tipo string, datos interface{}) {
+	if e == nil || len(e.config.EventWebhookURLs) == 0 {
+		return
+	}
+
+	evento := models.Evento{
+		ID:         generarIDEvento(),
+		Tipo:       tipo,
+		OcurridoEn: time.Now(),
+		Datos:      datos,
+	}
+
+	body, err := json.Marshal(evento)
+	if err != nil {
+		log.Printf("❌ Error serializando evento %s: %v", tipo, err)
+		return
+	}
+
+	for _, url := range e.config.EventWebhookURLs {
+		e.enCurso.Add(1)
+		go func(url string) {
+			defer e.enCurso.Done()
+			e.entregar(url, evento, body)
+		}(url)
+	}
+}
+
+// Esperar bloquea hasta que terminen las entregas en curso, entregadas o descartadas tras
+// los reintentos. Lo usan los comandos que emiten eventos y salen enseguida
+func (e *EventService) Esperar() {
+	if e == nil {
+		return
+	}
+	e.enCurso.Wait()
+}
+
+// EmitirCliente publica un evento del ciclo de vida de un cliente con su payload completo
+func (e *EventService) EmitirCliente(tipo string, cliente *models.Cliente, motivo string) {
+	e.Emitir(tipo, models.EventoCliente{
+		Cliente: cliente,
+		Motivo:  motivo,
+	})
+}
+
+// entregar envía el evento a un webhook reintentando con backoff exponencial
+func (e *EventService) entregar(url string, evento models.Evento, body []byte) {
+	const maxIntentos = 4
+	espera := 2 * time.Second
+
+	for intento := 1; intento <= maxIntentos; intento++ {
+		err := e.post(url, evento, body)
+		if err == nil {
+			return
+		}
+
+		log.Printf("⚠️  Error entregando evento %s (%s) a %s, intento %d/%d: %v",
+			evento.Tipo, evento.ID, url, intento, maxIntentos, err)
+
+		if intento < maxIntentos {
+			time.Sleep(espera)
+			espera *= 2
+		}
+	}
+
+	log.Printf("❌ Evento %s (%s) descartado para %s tras %d intentos", evento.Tipo, evento.ID, url, maxIntentos)
+}
+
+// post realiza la entrega HTTP firmando el cuerpo con HMAC-SHA256
+func (e *EventService) post(url string, evento models.Evento, body []byte) error {
+	req, err := http.NewRequest("POST", url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("error al crear request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-CheeseHouse-Event", evento.Tipo)
+	req.Header.Set("X-CheeseHouse-Event-ID", evento.ID)
+	if e.config.EventWebhookSecret != "" {
+		mac := hmac.New(sha256.New, []byte(e.config.EventWebhookSecret))
+		mac.Write(body)
+		req.Header.Set("X-CheeseHouse-Signature", "sha256="+hex.EncodeToString(mac.Sum(nil)))
+	}
+
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook respondió con código %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// generarIDEvento genera un identificador aleatorio para deduplicar entregas
+func generarIDEvento() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return fmt.Sprintf("%d", time.Now().UnixNano())
+	}
+	return hex.EncodeToString(b)
+}
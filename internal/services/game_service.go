@@ -1,13 +1,22 @@
 package services
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"fmt"
 	"log"
 	"math"
 	"math/rand"
+	"strconv"
+	"strings"
 	"time"
+	"unicode"
+	"unicode/utf8"
 
 	"CheeseHouse/internal/config"
+	"CheeseHouse/internal/fechas"
+	"CheeseHouse/internal/i18n"
 	"CheeseHouse/internal/models"
 	"CheeseHouse/internal/repository"
 )
@@ -17,7 +26,22 @@ type GameService struct {
 	config          *config.Config
 	clienteRepo     *repository.ClienteRepository
 	voucherRepo     repository.VoucherRepository
+	unidad          *repository.UnidadDeTrabajo
 	whatsappService *WhatsAppService
+	eventService    *EventService
+	verificacion    *VerificacionService
+	objetivos       *ObjetivoService
+	circuito        *CircuitoPremiosService
+	picoEmision     *PicoEmisionService
+	bloqueos        *BloqueoService
+	tiposCliente    *TipoClienteService
+	tarjetas        *TarjetaService
+	mesas           *MesaService
+	celebraciones   *CelebracionService
+	recomendaciones *RecomendacionService
+	vigencias       *VigenciaService
+	configuracion   *ConfiguracionService
+	pantalla        *VoucherPantallaService
 }
 
 // NewGameService crea una nueva instancia del servicio de juego
@@ -25,13 +49,43 @@ func NewGameService(
 	config *config.Config,
 	clienteRepo *repository.ClienteRepository,
 	voucherRepo repository.VoucherRepository,
+	unidad *repository.UnidadDeTrabajo,
 	whatsappService *WhatsAppService,
+	eventService *EventService,
+	verificacion *VerificacionService,
+	objetivos *ObjetivoService,
+	circuito *CircuitoPremiosService,
+	picoEmision *PicoEmisionService,
+	bloqueos *BloqueoService,
+	tiposCliente *TipoClienteService,
+	tarjetas *TarjetaService,
+	mesas *MesaService,
+	celebraciones *CelebracionService,
+	recomendaciones *RecomendacionService,
+	vigencias *VigenciaService,
+	configuracion *ConfiguracionService,
+	pantalla *VoucherPantallaService,
 ) *GameService {
 	return &GameService{
 		config:          config,
 		clienteRepo:     clienteRepo,
 		voucherRepo:     voucherRepo,
+		unidad:          unidad,
 		whatsappService: whatsappService,
+		eventService:    eventService,
+		verificacion:    verificacion,
+		objetivos:       objetivos,
+		circuito:        circuito,
+		picoEmision:     picoEmision,
+		bloqueos:        bloqueos,
+		tiposCliente:    tiposCliente,
+		tarjetas:        tarjetas,
+		mesas:           mesas,
+		celebraciones:   celebraciones,
+		recomendaciones: recomendaciones,
+		vigencias:       vigencias,
+		configuracion:   configuracion,
+		pantalla:        pantalla,
 	}
 }
 
@@ -52,7 +106,42 @@ func (g *GameService) ProcesarResultadoJuego(gameResult models.GameResult) (*mod
 		}, nil
 	}
 
-	// 2. Validar datos del juego
+	// Lista de bloqueo: se responde con un mensaje neutro para no revelar el motivo
+	huella := hashHuella(gameResult.Huella)
+	if bloqueo := g.bloqueos.BuscarBloqueo(telefonoNormalizado, gameResult.IP, huella); bloqueo != nil {
+		log.Printf("🚫 Partida rechazada por bloqueo #%d (%s) - Tel: %s", bloqueo.ID, bloqueo.Tipo, telefonoNormalizado)
+		return &models.VoucherResponse{
+			Success: false,
+			Message: "No pudimos procesar tu juego en este momento",
+		}, nil
+	}
+
+	// 2. El objetivo válido es el firmado por el servidor, no el que informa el cliente, y el
+	// tiempo obtenido tiene que coincidir con lo que midió el servidor entre /start y /stop
+	objetivo, medido, err := g.objetivos.ConsumirSesion(gameResult.Resultado.TokenSesion)
+	if err != nil {
+		log.Printf("⚠️  Sesión de juego rechazada para %s: %v", telefonoNormalizado, err)
+		return &models.VoucherResponse{
+			Success: false,
+			Message: err.Error(),
+		}, nil
+	}
+	if objetivo != gameResult.Resultado.TiempoObjetivo {
+		log.Printf("🚨 Objetivo adulterado para %s: informado %.1fs, emitido %.1fs",
+			telefonoNormalizado, gameResult.Resultado.TiempoObjetivo, objetivo)
+	}
+	gameResult.Resultado.TiempoObjetivo = objetivo
+
+	if desvio := math.Abs(gameResult.Resultado.TiempoObtenido - medido); desvio > g.config.Game.SessionMaxDrift {
+		log.Printf("🚨 Tiempo adulterado para %s: informado %.2fs, medido por el servidor %.2fs",
+			telefonoNormalizado, gameResult.Resultado.TiempoObtenido, medido)
+		return &models.VoucherResponse{
+			Success: false,
+			Message: "El tiempo informado no coincide con la partida, volvé a jugar",
+		}, nil
+	}
+
+	// Validar datos del juego
 	if err := g.validarDatosJuego(gameResult.Resultado); err != nil {
 		return &models.VoucherResponse{
 			Success: false,
@@ -67,82 +156,478 @@ func (g *GameService) ProcesarResultadoJuego(gameResult models.GameResult) (*mod
 		gameResult.Resultado.TiempoObtenido,
 		gano)
 
-	// 4. Crear o buscar cliente
-	cliente, esNuevo, err := g.crearOBuscarCliente(models.ClienteData{
-		Nombre:   gameResult.ClienteData.Nombre,
-		Apellido: gameResult.ClienteData.Apellido,
-		Telefono: telefonoNormalizado,
-	})
-	if err != nil {
-		return &models.VoucherResponse{
-			Success: false,
-			Message: "Error al procesar cliente: " + err.Error(),
-		}, nil
+	// 4 a 6. Cliente, partida, voucher y estadísticas se guardan en una sola transacción:
+	// una falla a mitad de camino no deja un voucher sin estadísticas ni al revés
+	diferencia := math.Abs(gameResult.Resultado.TiempoObtenido - gameResult.Resultado.TiempoObjetivo)
+	mesa := g.mesas.BuscarActiva(gameResult.Mesa)
+	var mesaID *uint
+	if mesa != nil {
+		mesaID = &mesa.ID
 	}
+	// WhatsApp salvo a quien marcó que no lo usa y ve el voucher en pantalla
+	enPantalla := g.config.VoucherEnPantalla(gameResult.ClienteData.SinWhatsApp)
+	avisar := !enPantalla || !gameResult.ClienteData.SinWhatsApp
+	var (
+		cliente *models.Cliente
+		esNuevo bool
+		voucher *models.Voucher
+		aviso   *models.MensajeOutbox
+		rechazo *models.VoucherResponse
+	)
+	procesar := func(tx *repository.Transaccion) error {
+		cliente, esNuevo, rechazo, voucher, aviso = nil, false, nil, nil, nil
 
-	// 5. Verificar si necesita aprobación (≥3 juegos)
-	necesitaAprobacion := cliente.TotalJuegos >= g.config.Game.GamesRequireApproval
+		// 4. Crear o buscar cliente
+		var err error
+		cliente, esNuevo, err = g.crearOBuscarCliente(tx, models.ClienteData{
+			Nombre:   gameResult.ClienteData.Nombre,
+			Apellido: gameResult.ClienteData.Apellido,
+			Telefono: telefonoNormalizado,
+			Idioma:   gameResult.ClienteData.Idioma,
+			Email:    gameResult.ClienteData.Email,
 
-	if necesitaAprobacion {
-		log.Printf("⚠️  Cliente %s necesita aprobación para juego #%d",
-			cliente.Telefono, cliente.TotalJuegos+1)
+			MostrarEnMuro: gameResult.ClienteData.MostrarEnMuro,
+		})
+		if err != nil {
+			return err
+		}
 
-		return &models.VoucherResponse{
-			Success:            false,
-			Message:            "Este cliente necesita aprobación de un empleado para seguir jugando",
-			NecesitaAprobacion: true,
-			ClienteID:          cliente.ID,
-		}, nil
+		// Espera tras derrota, tope de partidas y aprobación de un empleado
+		if rechazo = g.verificarTurno(tx, cliente, time.Now()); rechazo != nil {
+			return g.registrarRechazo(tx, cliente, gameResult, gano)
+		}
+
+		// Emisión suspendida por tasa de victorias anómala
+		if g.circuito.EmisionPausada() {
+			log.Printf("⛔ Emisión de vouchers pausada, partida de %s rechazada", cliente.Telefono)
+			rechazo = &models.VoucherResponse{
+				Success:   false,
+				Message:   "La entrega de premios está pausada momentáneamente, consultá en caja",
+				ClienteID: cliente.ID,
+			}
+			return g.registrarRechazo(tx, cliente, gameResult, gano)
+		}
+
+		// Durante un pico de emisión el premio queda retenido hasta que un empleado lo libere
+		// (ver LiberarPremioRetenido)
+		if gano && g.picoEmision.RequiereAprobacion() {
+			juego := g.nuevoJuego(cliente, gameResult, gano, nil)
+			juego.PremioRetenido = true
+			if err := tx.Juegos.Crear(juego); err != nil {
+				return err
+			}
+			log.Printf("⚠️  Pico de emisión activo, premio de %s retenido (partida #%d)", cliente.Telefono, juego.ID)
+			rechazo = &models.VoucherResponse{
+				Success:            false,
+				Message:            "¡Ganaste! Pedile a un empleado que apruebe tu premio",
+				NecesitaAprobacion: true,
+				ClienteID:          cliente.ID,
+				JuegoID:            juego.ID,
+			}
+			return nil
+		}
+
+		// 6. Crear voucher y actualizar estadísticas
+		voucher, err = g.crearVoucherYActualizarCliente(tx, cliente, gano, diferencia, gameResult.IP, huella, gameResult.WidgetID, mesaID, gameResult.Sucursal)
+		if err != nil {
+			return err
+		}
+
+		// 7. El aviso por WhatsApp se encola con el voucher: si el proceso se cae después
+		// del commit, el worker del outbox lo envía igual
+		if avisar {
+			aviso = g.whatsappService.AvisoVoucher(cliente, voucher)
+			if err := tx.Outbox.Crear(aviso); err != nil {
+				return err
+			}
+		}
+		return g.registrarJuego(tx, cliente, gameResult, gano, &voucher.ID)
 	}
 
-	// 6. Crear voucher y actualizar estadísticas
-	voucher, err := g.crearVoucherYActualizarCliente(cliente, gano)
+	err = g.unidad.Ejecutar(procesar)
+	if errors.Is(err, repository.ErrClienteDuplicado) {
+		// Otra solicitud concurrente creó el cliente entre la búsqueda y el insert; el insert
+		// fallido invalida la transacción, así que se repite completa con el cliente existente
+		log.Printf("🔁 Cliente %s creado concurrentemente, usando registro existente", telefonoNormalizado)
+		err = g.unidad.Ejecutar(procesar)
+	}
 	if err != nil {
+		log.Printf("❌ Partida de %s revertida: %v", telefonoNormalizado, err)
 		return &models.VoucherResponse{
 			Success: false,
-			Message: "Error al crear voucher: " + err.Error(),
+			Message: "Error al procesar el juego: " + err.Error(),
 		}, nil
 	}
+	if rechazo != nil {
+		return rechazo, nil
+	}
+	g.circuito.Registrar(gano)
 
-	// 7. Enviar WhatsApp
-	go g.enviarWhatsAppAsync(cliente, voucher, gano)
+	// Jugó desde el QR de una mesa: avisar al personal para que lleve el premio
+	if gano && mesa != nil {
+		go g.mesas.AvisarPremio(mesa, cliente, voucher)
+	}
+
+	// Enviar enseguida el aviso encolado; si no sale queda reprogramado en el outbox
+	if aviso != nil {
+		g.whatsappService.EnSegundoPlano(func() { g.whatsappService.EnviarAvisoVoucher(aviso, cliente, voucher) })
+	}
 
 	// 8. Retornar respuesta exitosa
-	return &models.VoucherResponse{
+	formato := g.config.Fechas(cliente.Idioma)
+	respuesta := &models.VoucherResponse{
 		Success:            true,
 		Message:            g.generarMensajeExito(gano, voucher.Descuento),
 		Codigo:             voucher.Codigo,
 		Descuento:          voucher.Descuento,
-		FechaVencimiento:   voucher.FechaVencimiento.Format("02/01/2006"),
+		FechaVencimiento:   formato.ISO(voucher.FechaVencimiento),
+		VencimientoTexto:   formato.Fecha(voucher.FechaVencimiento),
 		ClienteID:          cliente.ID,
 		EsClienteNuevo:     esNuevo,
 		NecesitaAprobacion: false,
-	}, nil
+		CompartirURL:       g.tarjetas.URL(gameResult.Resultado, gano, cliente.Idioma),
+	}
+	if g.config.Game.CodeOnScreenOnOutage &&
+		g.whatsappService.EntregaCaida(time.Duration(g.config.Game.WhatsAppOutageMinutes)*time.Minute) {
+		// El WhatsApp sale igual (o queda en cola), pero no se promete: el código va en pantalla
+		respuesta.Message = g.generarMensajeCodigoEnPantalla(gano, voucher.Descuento)
+		respuesta.MostrarCodigo = true
+		respuesta.NotaCodigo = i18n.Textos(cliente.Idioma)["codigo_nota"]
+	}
+	if enPantalla {
+		// El código no viaja en la respuesta: se revela confirmando los dígitos del teléfono
+		token, err := g.pantalla.Emitir(voucher, cliente)
+		if err != nil {
+			log.Printf("⚠️  No se pudo preparar el voucher en pantalla de %s: %v", cliente.Telefono, err)
+			respuesta.MostrarCodigo = gameResult.ClienteData.SinWhatsApp
+		} else {
+			respuesta.Message = g.generarMensajeCodigoEnPantalla(gano, voucher.Descuento)
+			respuesta.Codigo = ""
+			respuesta.MostrarCodigo = false
+			respuesta.NotaCodigo = ""
+			respuesta.TokenPantalla = token
+		}
+	}
+	if voucher.Flash {
+		respuesta.Flash = true
+		respuesta.VencimientoTexto = formato.FechaHora(voucher.FechaVencimiento)
+		respuesta.VenceEnSegundos = int(time.Until(voucher.FechaVencimiento).Seconds())
+	}
+	if gano {
+		respuesta.Celebracion = g.celebraciones.ParaGanador(cliente, voucher.Descuento)
+	}
+	if voucher.Recomendacion != "" {
+		respuesta.Recomendacion = &models.Recomendacion{
+			Alcance: voucher.Alcance,
+			Mensaje: voucher.Recomendacion,
+		}
+	}
+	return respuesta, nil
+}
+
+// registrarJuego guarda el intento en el historial de partidas dentro de la transacción
+// de la partida
+func (g *GameService) registrarJuego(tx *repository.Transaccion, cliente *models.Cliente, gameResult models.GameResult, gano bool, voucherID *uint) error {
+	return tx.Juegos.Crear(g.nuevoJuego(cliente, gameResult, gano, voucherID))
+}
+
+// registrarRechazo guarda un intento que no llegó a jugarse (espera, tope, aprobación o
+// emisión pausada): queda en el historial pero no cuenta para los topes ni el ranking
+func (g *GameService) registrarRechazo(tx *repository.Transaccion, cliente *models.Cliente, gameResult models.GameResult, gano bool) error {
+	juego := g.nuevoJuego(cliente, gameResult, gano, nil)
+	juego.Rechazado = true
+	return tx.Juegos.Crear(juego)
+}
+
+// nuevoJuego arma el registro de la partida
+func (g *GameService) nuevoJuego(cliente *models.Cliente, gameResult models.GameResult, gano bool, voucherID *uint) *models.Juego {
+	return &models.Juego{
+		ClienteID:      cliente.ID,
+		TiempoObjetivo: gameResult.Resultado.TiempoObjetivo,
+		TiempoObtenido: gameResult.Resultado.TiempoObtenido,
+		Gano:           gano,
+		VoucherID:      voucherID,
+		IP:             gameResult.IP,
+	}
+}
+
+// ListarPremiosRetenidos lista los premios ganados durante un pico de emisión que esperan
+// a un empleado, del más viejo al más nuevo
+func (g *GameService) ListarPremiosRetenidos() ([]*models.PremioRetenido, error) {
+	var juegos []*models.Juego
+	err := g.unidad.Ejecutar(func(tx *repository.Transaccion) error {
+		var err error
+		juegos, err = tx.Juegos.ListarRetenidos()
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]uint, 0, len(juegos))
+	for _, juego := range juegos {
+		ids = append(ids, juego.ClienteID)
+	}
+	clientes, err := g.clienteRepo.BuscarPorIDs(ids)
+	if err != nil {
+		return nil, fmt.Errorf("error obteniendo clientes de los premios retenidos: %w", err)
+	}
+	porID := make(map[uint]*models.Cliente, len(clientes))
+	for _, cliente := range clientes {
+		porID[cliente.ID] = cliente
+	}
+
+	retenidos := make([]*models.PremioRetenido, 0, len(juegos))
+	for _, juego := range juegos {
+		retenido := &models.PremioRetenido{
+			JuegoID:        juego.ID,
+			ClienteID:      juego.ClienteID,
+			TiempoObjetivo: juego.TiempoObjetivo,
+			TiempoObtenido: juego.TiempoObtenido,
+			JugadoEn:       juego.CreatedAt,
+		}
+		if cliente, ok := porID[juego.ClienteID]; ok {
+			retenido.Nombre = strings.TrimSpace(cliente.Nombre + " " + cliente.Apellido)
+			retenido.Telefono = cliente.Telefono
+		}
+		retenidos = append(retenidos, retenido)
+	}
+	return retenidos, nil
+}
+
+// LiberarPremioRetenido emite el voucher de una partida ganada durante un pico de emisión,
+// como si el pico no hubiera existido, y lo envía por WhatsApp
+func (g *GameService) LiberarPremioRetenido(juegoID, empleadoID uint) (*models.Voucher, error) {
+	var (
+		cliente *models.Cliente
+		voucher *models.Voucher
+		aviso   *models.MensajeOutbox
+	)
+	err := g.unidad.Ejecutar(func(tx *repository.Transaccion) error {
+		juego, err := tx.Juegos.BloquearRetenido(juegoID)
+		if err != nil {
+			return err
+		}
+		cliente, err = tx.Clientes.BloquearPorID(juego.ClienteID)
+		if err != nil {
+			return fmt.Errorf("cliente de la partida no encontrado: %w", err)
+		}
+
+		diferencia := math.Abs(juego.TiempoObtenido - juego.TiempoObjetivo)
+		voucher, err = g.crearVoucherYActualizarCliente(tx, cliente, true, diferencia, juego.IP, "", nil, nil, "")
+		if err != nil {
+			return err
+		}
+		if err := tx.Juegos.ResolverRetenido(juego.ID, &voucher.ID); err != nil {
+			return err
+		}
+
+		aviso = g.whatsappService.AvisoVoucher(cliente, voucher)
+		return tx.Outbox.Crear(aviso)
+	})
+	if err != nil {
+		return nil, err
+	}
+	g.circuito.Registrar(true)
+	g.whatsappService.EnSegundoPlano(func() { g.whatsappService.EnviarAvisoVoucher(aviso, cliente, voucher) })
+
+	log.Printf("✅ Empleado ID %d liberó el premio retenido de la partida #%d: voucher %s para %s",
+		empleadoID, juegoID, voucher.Codigo, cliente.Telefono)
+	return voucher, nil
+}
+
+// DescartarPremioRetenido saca la partida de los retenidos sin emitir el voucher, por
+// ejemplo si el pico era un abuso
+func (g *GameService) DescartarPremioRetenido(juegoID, empleadoID uint) error {
+	err := g.unidad.Ejecutar(func(tx *repository.Transaccion) error {
+		if _, err := tx.Juegos.BloquearRetenido(juegoID); err != nil {
+			return err
+		}
+		return tx.Juegos.ResolverRetenido(juegoID, nil)
+	})
+	if err != nil {
+		return err
+	}
+	log.Printf("🚫 Empleado ID %d descartó el premio retenido de la partida #%d", empleadoID, juegoID)
+	return nil
+}
+
+// verificarTurno revisa, con la fila del cliente bloqueada, si puede jugar ahora: la espera
+// tras una derrota, el tope de partidas del día o la semana (que ni la aprobación de un
+// empleado saltea) y la aprobación pasado GAMES_REQUIRE_APPROVAL. Retorna el rechazo o nil
+func (g *GameService) verificarTurno(tx *repository.Transaccion, cliente *models.Cliente, ahora time.Time) *models.VoucherResponse {
+	if espera := g.esperaTrasDerrota(tx, cliente, ahora); espera > 0 {
+		segundos := int(math.Ceil(espera.Seconds()))
+		log.Printf("⏳ Cliente %s en espera tras perder: %ds restantes", cliente.Telefono, segundos)
+		return &models.VoucherResponse{
+			Success:        false,
+			Message:        fmt.Sprintf("Podés volver a jugar en %d minutos", int(math.Ceil(espera.Minutes()))),
+			ClienteID:      cliente.ID,
+			EsperaSegundos: segundos,
+		}
+	}
+
+	if tope := g.topeDePartidas(tx, cliente, ahora); tope != nil {
+		log.Printf("🛑 Cliente %s llegó al tope de %d partidas por %s", cliente.Telefono, tope.limite, tope.periodo)
+		formato := g.config.Fechas(cliente.Idioma)
+		return &models.VoucherResponse{
+			Success:         false,
+			Message:         tope.mensaje(formato),
+			ClienteID:       cliente.ID,
+			EsperaSegundos:  int(math.Ceil(tope.desde.Sub(ahora).Seconds())),
+			PuedeJugarDesde: formato.ISO(tope.desde),
+		}
+	}
+
+	if juegosHoy, necesita := g.necesitaAprobacion(tx, cliente); necesita {
+		log.Printf("⚠️  Cliente %s necesita aprobación para el juego #%d de hoy",
+			cliente.Telefono, juegosHoy+1)
+		return &models.VoucherResponse{
+			Success:            false,
+			Message:            "Este cliente necesita aprobación de un empleado para seguir jugando",
+			NecesitaAprobacion: true,
+			ClienteID:          cliente.ID,
+		}
+	}
+	return nil
+}
+
+// necesitaAprobacion cuenta las partidas de hoy en el historial (no los contadores del
+// cliente, que pueden quedar desactualizados; tampoco los intentos rechazados). Si no se
+// puede contar pide aprobación. Un cliente aprobado hoy por un empleado (ver
+// AdminService.AprobarJuegoFrecuente) sigue jugando hasta el fin del día
+func (g *GameService) necesitaAprobacion(tx *repository.Transaccion, cliente *models.Cliente) (int, bool) {
+	inicioDia := g.config.InicioDelDia(time.Now())
+	juegosHoy, err := tx.Juegos.ContarPorClienteDesde(cliente.ID, inicioDia)
+	if err != nil {
+		log.Printf("⚠️  No se pudieron contar las partidas de hoy de %s: %v", cliente.Telefono, err)
+		return 0, true
+	}
+	if cliente.AprobadoEl != nil && !cliente.AprobadoEl.Before(inicioDia) {
+		return juegosHoy, false
+	}
+	return juegosHoy, juegosHoy >= g.config.Game.GamesRequireApproval
+}
+
+// topePartidas límite de partidas alcanzado y desde cuándo se puede volver a jugar
+type topePartidas struct {
+	periodo string // "día" o "semana"
+	limite  int
+	desde   time.Time
+}
+
+// mensaje explica al jugador hasta cuándo no puede jugar
+func (t *topePartidas) mensaje(formato fechas.Formato) string {
+	partidas := fmt.Sprintf("tus %d partidas", t.limite)
+	if t.limite == 1 {
+		partidas = "tu partida"
+	}
+	if t.periodo == "día" {
+		return fmt.Sprintf("Ya jugaste %s de hoy. ¡Te esperamos mañana para volver a jugar!", partidas)
+	}
+	return fmt.Sprintf("Ya jugaste %s de esta semana. Podés volver a jugar desde el %s", partidas, formato.Fecha(t.desde))
+}
+
+// topeDePartidas revisa MAX_PLAYS_PER_DAY y MAX_PLAYS_PER_WEEK contando las partidas del
+// historial, como la aprobación; el próximo horario permitido es el fin del
+// período que llegó al tope. Si no se puede contar deja jugar: la aprobación, que se
+// revisa después, ya pide un empleado en ese caso
+func (g *GameService) topeDePartidas(tx *repository.Transaccion, cliente *models.Cliente, ahora time.Time) *topePartidas {
+	topes := []struct {
+		periodo string
+		limite  int
+		inicio  time.Time
+		fin     time.Time
+	}{
+		{"semana", g.config.Game.MaxPlaysPerWeek, g.config.InicioDeSemana(ahora), g.config.InicioDeSemana(ahora).AddDate(0, 0, 7)},
+		{"día", g.config.Game.MaxPlaysPerDay, g.config.InicioDelDia(ahora), g.config.InicioDelDia(ahora).AddDate(0, 0, 1)},
+	}
+	for _, tope := range topes {
+		if tope.limite <= 0 {
+			continue
+		}
+		jugadas, err := tx.Juegos.ContarPorClienteDesde(cliente.ID, tope.inicio)
+		if err != nil {
+			log.Printf("⚠️  No se pudieron contar las partidas de %s para el tope por %s: %v", cliente.Telefono, tope.periodo, err)
+			continue
+		}
+		if jugadas >= tope.limite {
+			return &topePartidas{periodo: tope.periodo, limite: tope.limite, desde: tope.fin}
+		}
+	}
+	return nil
+}
+
+// esperaTrasDerrota calcula cuánto falta para que el cliente pueda volver a jugar
+// si su última partida resuelta fue una derrota dentro del período de espera configurado
+func (g *GameService) esperaTrasDerrota(tx *repository.Transaccion, cliente *models.Cliente, ahora time.Time) time.Duration {
+	if g.config.Game.LossCooldownMinutes <= 0 {
+		return 0
+	}
+
+	ultimo, err := tx.Juegos.UltimoResueltoPorCliente(cliente.ID)
+	if err != nil {
+		log.Printf("⚠️  No se pudo verificar la espera tras derrota: %v", err)
+		return 0
+	}
+	if ultimo == nil || ultimo.Gano {
+		return 0
+	}
+
+	cooldown := time.Duration(g.config.Game.LossCooldownMinutes) * time.Minute
+	return ultimo.CreatedAt.Add(cooldown).Sub(ahora)
 }
 
 // GenerarTiempoObjetivo genera un tiempo objetivo aleatorio
 func (g *GameService) GenerarTiempoObjetivo() float64 {
 	rand.Seed(time.Now().UnixNano())
-	min := g.config.Game.MinTargetTime
-	max := g.config.Game.MaxTargetTime
+	parametros := g.configuracion.ParametrosJuego()
+	min := parametros.TiempoMin
+	max := parametros.TiempoMax
 
 	// Generar número aleatorio entre min y max con 1 decimal
 	tiempo := min + rand.Float64()*(max-min)
 	return math.Round(tiempo*10) / 10 // Redondear a 1 decimal
 }
 
+// GenerarObjetivoFirmado genera un tiempo objetivo junto con el token de un solo uso
+// que el cliente debe devolver al enviar el resultado
+func (g *GameService) GenerarObjetivoFirmado() (float64, string, error) {
+	objetivo := g.GenerarTiempoObjetivo()
+	token, err := g.objetivos.Emitir(objetivo)
+	if err != nil {
+		return 0, "", err
+	}
+	return objetivo, token, nil
+}
+
+// IniciarPartida abre la sesión de juego con el objetivo firmado que recibió el jugador
+func (g *GameService) IniciarPartida(tokenObjetivo string) (string, float64, time.Time, error) {
+	return g.objetivos.IniciarSesion(tokenObjetivo)
+}
+
+// DetenerPartida registra la detención del cronómetro y retorna los segundos medidos
+func (g *GameService) DetenerPartida(tokenSesion string) (float64, error) {
+	medido, err := g.objetivos.DetenerSesion(tokenSesion)
+	if err != nil {
+		return 0, err
+	}
+	return math.Round(medido*100) / 100, nil
+}
+
 // determinarSiGano determina si el jugador ganó basado en la tolerancia
 func (g *GameService) determinarSiGano(resultado models.Resultado) bool {
 	diferencia := math.Abs(resultado.TiempoObtenido - resultado.TiempoObjetivo)
-	return diferencia <= g.config.Game.Tolerance
+	return diferencia <= g.circuito.Tolerancia()
 }
 
 // validarDatosJuego valida que los datos del juego sean coherentes
 func (g *GameService) validarDatosJuego(resultado models.Resultado) error {
-	if resultado.TiempoObjetivo < g.config.Game.MinTargetTime ||
-		resultado.TiempoObjetivo > g.config.Game.MaxTargetTime {
+	parametros := g.configuracion.ParametrosJuego()
+	if resultado.TiempoObjetivo < parametros.TiempoMin ||
+		resultado.TiempoObjetivo > parametros.TiempoMax {
 		return fmt.Errorf("tiempo objetivo fuera de rango (%.1f-%.1fs)",
-			g.config.Game.MinTargetTime, g.config.Game.MaxTargetTime)
+			parametros.TiempoMin, parametros.TiempoMax)
 	}
 
 	if resultado.TiempoObtenido < 0 || resultado.TiempoObtenido > 30 {
@@ -159,78 +644,154 @@ func (g *GameService) validarDatosJuego(resultado models.Resultado) error {
 	return nil
 }
 
-// crearOBuscarCliente crea un cliente nuevo o busca uno existente
-func (g *GameService) crearOBuscarCliente(clienteData models.ClienteData) (*models.Cliente, bool, error) {
-	// Buscar cliente existente por teléfono
-	cliente, err := g.clienteRepo.BuscarPorTelefono(clienteData.Telefono)
+// crearOBuscarCliente crea un cliente nuevo o busca uno existente, por teléfono o, si el
+// teléfono es nuevo, por email. Si otra solicitud creó el mismo teléfono o email en
+// paralelo retorna repository.ErrClienteDuplicado
+func (g *GameService) crearOBuscarCliente(tx *repository.Transaccion, clienteData models.ClienteData) (*models.Cliente, bool, error) {
+	email := normalizarEmail(clienteData.Email)
+	actualizado := false
+
+	// Buscar cliente existente por teléfono, bloqueando su fila: los topes y la espera se
+	// cuentan con la fila tomada, así dos envíos simultáneos no ven el mismo conteo
+	cliente, err := tx.Clientes.BloquearPorTelefono(clienteData.Telefono)
+	if err != nil && email != "" {
+		// Mismo email con otro teléfono: conocer un email no prueba ser su dueño, así que no
+		// se le cambia el teléfono a ese cliente; se crea uno aparte sin el email (es único)
+		if porEmail, errEmail := tx.Clientes.BuscarPorEmail(email); errEmail == nil {
+			log.Printf("⚠️  Email ya registrado por el cliente #%d con otro teléfono, %s se registra sin email",
+				porEmail.ID, clienteData.Telefono)
+			email = ""
+		}
+	}
 	if err != nil {
 		// Si no existe, crear nuevo cliente
+		idioma := clienteData.Idioma
+		if idioma == "" {
+			idioma = g.config.DefaultLanguage
+		}
+
 		nuevoCliente := &models.Cliente{
 			Nombre:         clienteData.Nombre,
 			Apellido:       clienteData.Apellido,
 			Telefono:       clienteData.Telefono,
+			Idioma:         idioma,
 			FechaRegistro:  time.Now(),
 			TotalJuegos:    0,
 			JuegosGanados:  0,
 			JuegosPerdidos: 0,
 			Estado:         "activo",
 		}
+		if email != "" {
+			nuevoCliente.Email = &email
+		}
+		if clienteData.MostrarEnMuro != nil {
+			ahora := time.Now()
+			nuevoCliente.ConsentimientoMuro = *clienteData.MostrarEnMuro
+			nuevoCliente.ConsentimientoMuroEn = &ahora
+		}
 
-		if err := g.clienteRepo.Crear(nuevoCliente); err != nil {
+		if err := tx.Clientes.Crear(nuevoCliente); err != nil {
+			if errors.Is(err, repository.ErrClienteDuplicado) {
+				return nil, false, err
+			}
 			return nil, false, fmt.Errorf("error al crear cliente: %w", err)
 		}
 
 		log.Printf("✨ Cliente nuevo creado: %s %s (%s)",
 			nuevoCliente.Nombre, nuevoCliente.Apellido, nuevoCliente.Telefono)
+		tx.AlConfirmar(func() { g.eventService.EmitirCliente(EventoClienteCreado, nuevoCliente, "") })
 
 		return nuevoCliente, true, nil
 	}
 
 	// Cliente existente, actualizar datos si han cambiado
-	actualizado := false
 	if cliente.Nombre != clienteData.Nombre || cliente.Apellido != clienteData.Apellido {
 		cliente.Nombre = clienteData.Nombre
 		cliente.Apellido = clienteData.Apellido
 		actualizado = true
 	}
+	if clienteData.Idioma != "" && cliente.Idioma != clienteData.Idioma {
+		cliente.Idioma = clienteData.Idioma
+		actualizado = true
+	}
+	if clienteData.MostrarEnMuro != nil && cliente.ConsentimientoMuro != *clienteData.MostrarEnMuro {
+		ahora := time.Now()
+		cliente.ConsentimientoMuro = *clienteData.MostrarEnMuro
+		cliente.ConsentimientoMuroEn = &ahora
+		actualizado = true
+	}
+	if email != "" && (cliente.Email == nil || *cliente.Email != email) {
+		// El email es único: si ya es de otro cliente se conserva el anterior
+		if otro, err := tx.Clientes.BuscarPorEmail(email); err == nil && otro.ID != cliente.ID {
+			log.Printf("⚠️  Email de %s ya registrado por el cliente #%d, no se actualiza", cliente.Telefono, otro.ID)
+		} else {
+			cliente.Email = &email
+			actualizado = true
+		}
+	}
 
 	if actualizado {
-		if err := g.clienteRepo.Actualizar(cliente); err != nil {
-			log.Printf("⚠️  Error al actualizar datos del cliente: %v", err)
-		} else {
-			log.Printf("📝 Datos del cliente actualizados: %s %s", cliente.Nombre, cliente.Apellido)
+		if err := tx.Clientes.Actualizar(cliente); err != nil {
+			return nil, false, fmt.Errorf("error al actualizar datos del cliente: %w", err)
 		}
+		log.Printf("📝 Datos del cliente actualizados: %s %s", cliente.Nombre, cliente.Apellido)
+		tx.AlConfirmar(func() { g.eventService.EmitirCliente(EventoClienteActualizado, cliente, "datos_personales") })
 	}
 
 	return cliente, false, nil
 }
 
-// crearVoucherYActualizarCliente crea el voucher y actualiza las estadísticas del cliente
-func (g *GameService) crearVoucherYActualizarCliente(cliente *models.Cliente, gano bool) (*models.Voucher, error) {
+// normalizarEmail pasa el email a minúsculas y sin espacios; vacío si no se informó
+func normalizarEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// crearVoucherYActualizarCliente crea el voucher y actualiza las estadísticas del cliente en
+// la transacción de la partida
+func (g *GameService) crearVoucherYActualizarCliente(tx *repository.Transaccion, cliente *models.Cliente, gano bool, diferencia float64, ip, huella string, widgetID, mesaID *uint, sucursal string) (*models.Voucher, error) {
 	// Determinar descuento
 	var descuento int
 	var tipo string
+	parametros := g.configuracion.ParametrosJuego()
 	if gano {
-		descuento = g.config.Game.WinDiscount
+		descuento = parametros.DescuentoGanador
 		tipo = "juego_ganado"
 	} else {
-		descuento = g.config.Game.LoseDiscount
+		descuento = parametros.DescuentoPerdedor
 		tipo = "juego_perdido"
 	}
 
+	// Los vouchers flash vencen a las pocas horas (o al corte del día)
+	emision := time.Now()
+	flash := g.config.EsVoucherFlash(gano)
+	vencimiento := emision.AddDate(0, 0, g.vigencias.Dias(tipo, emision))
+	if flash {
+		vencimiento = g.config.VencimientoFlash(emision)
+	}
+
 	// Crear voucher
 	voucher := &models.Voucher{
-		Codigo:           g.generarCodigoVoucher(),
-		ClienteID:        cliente.ID,
-		Tipo:             tipo,
-		Descuento:        descuento,
-		Ganado:           &gano,
-		FechaEmision:     time.Now(),
-		FechaVencimiento: time.Now().AddDate(0, 0, g.config.Game.VoucherValidityDays),
-		Usado:            false,
+		Codigo:            g.generarCodigoVoucher(),
+		ClienteID:         cliente.ID,
+		Tipo:              tipo,
+		Descuento:         descuento,
+		Ganado:            &gano,
+		FechaEmision:      emision,
+		FechaVencimiento:  vencimiento,
+		Flash:             flash,
+		Usado:             false,
+		IPOrigen:          ip,
+		HuellaDispositivo: huella,
+		WidgetID:          widgetID,
+		MesaID:            mesaID,
+		Sucursal:          g.config.Sucursal(sucursal),
+		SoloSucursal:      g.config.VoucherSoloSucursal(),
 	}
+	diferencia = math.Round(diferencia*1000) / 1000 // Precisión de la columna
+	voucher.DiferenciaSegundos = &diferencia
+	g.recomendaciones.Aplicar(voucher, cliente, gano)
 
-	if err := g.voucherRepo.Crear(voucher); err != nil {
+	if err := tx.Vouchers.Crear(voucher); err != nil {
 		return nil, fmt.Errorf("error al crear voucher: %w", err)
 	}
 
@@ -238,6 +799,7 @@ func (g *GameService) crearVoucherYActualizarCliente(cliente *models.Cliente, ga
 	cliente.TotalJuegos++
 	hoy := time.Now()
 	cliente.FechaUltimoJuego = &hoy
+	g.tiposCliente.RegistrarPartida(cliente, hoy)
 
 	if gano {
 		cliente.JuegosGanados++
@@ -245,10 +807,10 @@ func (g *GameService) crearVoucherYActualizarCliente(cliente *models.Cliente, ga
 		cliente.JuegosPerdidos++
 	}
 
-	if err := g.clienteRepo.Actualizar(cliente); err != nil {
-		log.Printf("⚠️  Error al actualizar estadísticas del cliente: %v", err)
-		// No es crítico, el voucher ya se creó
+	if err := tx.Clientes.Actualizar(cliente); err != nil {
+		return nil, fmt.Errorf("error al actualizar estadísticas del cliente: %w", err)
 	}
+	tx.AlConfirmar(func() { g.eventService.EmitirCliente(EventoClienteActualizado, cliente, "juego") })
 
 	log.Printf("🎟️  Voucher creado: %s (%d%% descuento) para %s",
 		voucher.Codigo, voucher.Descuento, cliente.Telefono)
@@ -256,6 +818,15 @@ func (g *GameService) crearVoucherYActualizarCliente(cliente *models.Cliente, ga
 	return voucher, nil
 }
 
+// hashHuella normaliza la huella del navegador a un SHA-256 de largo fijo
+func hashHuella(huella string) string {
+	if huella == "" {
+		return ""
+	}
+	suma := sha256.Sum256([]byte(huella))
+	return hex.EncodeToString(suma[:])
+}
+
 // generarCodigoVoucher genera un código único para el voucher
 func (g *GameService) generarCodigoVoucher() string {
 	prefix := g.config.GenerateVoucherCode() // "CH"
@@ -265,24 +836,6 @@ func (g *GameService) generarCodigoVoucher() string {
 	return fmt.Sprintf("%s%05d%03d", prefix, timestamp, random)
 }
 
-// enviarWhatsAppAsync envía WhatsApp de forma asíncrona
-func (g *GameService) enviarWhatsAppAsync(cliente *models.Cliente, voucher *models.Voucher, gano bool) {
-	var err error
-
-	if gano {
-		err = g.whatsappService.EnviarVoucherGanador(cliente, voucher)
-	} else {
-		err = g.whatsappService.EnviarVoucherPerdedor(cliente, voucher)
-	}
-
-	if err != nil {
-		log.Printf("❌ Error enviando WhatsApp a %s: %v", cliente.Telefono, err)
-		// TODO: Marcar voucher para reintento de envío
-	} else {
-		log.Printf("📱 WhatsApp enviado exitosamente a %s", cliente.Telefono)
-	}
-}
-
 // generarMensajeExito genera mensaje de éxito para la respuesta
 func (g *GameService) generarMensajeExito(gano bool, descuento int) string {
 	if gano {
@@ -291,9 +844,17 @@ func (g *GameService) generarMensajeExito(gano bool, descuento int) string {
 	return fmt.Sprintf("¡Casi! No te preocupes, tienes un %d%% de descuento de consolación. Revisa tu WhatsApp.", descuento)
 }
 
+// generarMensajeCodigoEnPantalla mensaje de éxito cuando WhatsApp no está entregando
+func (g *GameService) generarMensajeCodigoEnPantalla(gano bool, descuento int) string {
+	if gano {
+		return fmt.Sprintf("¡Felicitaciones! Ganaste un %d%% de descuento. Guardá el código que ves en pantalla.", descuento)
+	}
+	return fmt.Sprintf("¡Casi! Tenés un %d%% de descuento de consolación. Guardá el código que ves en pantalla.", descuento)
+}
+
 // GetEstadisticasGenerales obtiene estadísticas generales del juego
 func (g *GameService) GetEstadisticasGenerales() (*models.EstadisticasGenerales, error) {
-	stats, err := g.clienteRepo.GetEstadisticasGenerales()
+	stats, err := g.clienteRepo.GetEstadisticasGenerales(g.config.InicioDelDia(time.Now()))
 	if err != nil {
 		return nil, fmt.Errorf("error al obtener estadísticas: %w", err)
 	}
@@ -321,48 +882,194 @@ func (g *GameService) GetEstadisticasPorPeriodo(dias int) ([]*models.Estadistica
 	return g.voucherRepo.GetEstadisticasPorPeriodo(dias)
 }
 
-// ValidarAprobacionJuego valida si un cliente puede seguir jugando
-func (g *GameService) ValidarAprobacionJuego(clienteID uint, empleadoID uint) error {
-	cliente, err := g.clienteRepo.BuscarPorID(clienteID)
+// GetClientePorTelefono busca un cliente por teléfono (para consultas)
+func (g *GameService) GetClientePorTelefono(telefono string) (*models.ClienteConEstadisticas, error) {
+	telefonoNormalizado := g.whatsappService.NormalizarTelefono(telefono)
+
+	cliente, err := g.clienteRepo.BuscarPorTelefono(telefonoNormalizado)
 	if err != nil {
-		return fmt.Errorf("cliente no encontrado: %w", err)
+		return nil, fmt.Errorf("cliente no encontrado: %w", err)
 	}
 
-	if cliente.TotalJuegos < g.config.Game.GamesRequireApproval {
-		return fmt.Errorf("cliente no necesita aprobación")
+	// Obtener estadísticas completas
+	return g.clienteRepo.GetClienteConEstadisticas(cliente.ID)
+}
+
+// ErrVerificacionFallida se retorna cuando no se puede probar que quien consulta conoce al cliente.
+// No distingue entre cliente inexistente y datos incorrectos para no permitir enumerar teléfonos
+var ErrVerificacionFallida = errors.New("cliente no encontrado o datos de verificación incorrectos")
+
+// GetClienteVerificado busca un cliente por teléfono solo si se informa su apellido
+// o un código de verificación válido enviado previamente por WhatsApp
+func (g *GameService) GetClienteVerificado(telefono, apellido, codigo string) (*models.ClienteConEstadisticas, error) {
+	telefonoNormalizado := g.whatsappService.NormalizarTelefono(telefono)
+
+	cliente, err := g.clienteRepo.BuscarPorTelefono(telefonoNormalizado)
+	if err != nil {
+		return nil, ErrVerificacionFallida
 	}
 
-	// Actualizar fecha de última aprobación o similar lógica
-	// Por ahora, simplemente loguear la aprobación
-	log.Printf("✅ Empleado ID %d aprobó juego para cliente %s (%s)",
-		empleadoID, cliente.Telefono, cliente.Nombre)
+	switch {
+	case codigo != "":
+		if err := g.verificacion.ValidarCodigo(telefonoNormalizado, codigo); err != nil {
+			return nil, ErrVerificacionFallida
+		}
+	case apellido != "":
+		if !g.verificacion.ApellidoCoincide(cliente.Apellido, apellido) {
+			return nil, ErrVerificacionFallida
+		}
+	default:
+		return nil, ErrVerificacionFallida
+	}
 
-	return nil
+	return g.clienteRepo.GetClienteConEstadisticas(cliente.ID)
 }
 
-// GetClientePorTelefono busca un cliente por teléfono (para consultas)
-func (g *GameService) GetClientePorTelefono(telefono string) (*models.ClienteConEstadisticas, error) {
+// SolicitarCodigoCliente envía un código de verificación si el teléfono pertenece a un cliente.
+// Siempre responde igual para no revelar qué teléfonos están registrados
+func (g *GameService) SolicitarCodigoCliente(telefono string) {
 	telefonoNormalizado := g.whatsappService.NormalizarTelefono(telefono)
 
-	cliente, err := g.clienteRepo.BuscarPorTelefono(telefonoNormalizado)
+	if _, err := g.clienteRepo.BuscarPorTelefono(telefonoNormalizado); err != nil {
+		return
+	}
+
+	if err := g.verificacion.EnviarCodigo(telefonoNormalizado); err != nil {
+		log.Printf("❌ Error enviando código de verificación a %s: %v", telefonoNormalizado, err)
+	}
+}
+
+// umbralPrivacidadEstadisticas cantidad mínima para publicar un conteo sin redondear
+const umbralPrivacidadEstadisticas = 10
+
+// GetEstadisticasPublicas retorna solo agregados aptos para mostrar en widgets públicos.
+// Los conteos se redondean hacia abajo a múltiplos de 5 y los porcentajes se omiten
+// con pocos datos, para que no se pueda inferir la actividad de una persona concreta
+func (g *GameService) GetEstadisticasPublicas() (*models.EstadisticasPublicas, error) {
+	stats, err := g.GetEstadisticasGenerales()
 	if err != nil {
-		return nil, fmt.Errorf("cliente no encontrado: %w", err)
+		return nil, err
 	}
 
-	// Obtener estadísticas completas
-	return g.clienteRepo.GetClienteConEstadisticas(cliente.ID)
+	resultado := &models.EstadisticasPublicas{
+		Restaurante:     g.config.RestaurantName,
+		TotalPartidas:   redondearPrivado(stats.TotalPartidas),
+		TotalJugadores:  redondearPrivado(stats.TotalClientes),
+		JugaronHoy:      redondearPrivado(stats.JugaronHoy),
+		VouchersActivos: redondearPrivado(stats.VouchersActivos),
+	}
+
+	if stats.TotalPartidas >= umbralPrivacidadEstadisticas {
+		porcentaje := math.Round(stats.PorcentajeVictorias)
+		resultado.PorcentajeVictorias = &porcentaje
+	}
+
+	return resultado, nil
+}
+
+// redondearPrivado redondea conteos pequeños para evitar identificar individuos
+func redondearPrivado(valor int) int {
+	if valor < umbralPrivacidadEstadisticas {
+		return 0
+	}
+	return valor - valor%5
+}
+
+// Límites del muro de ganadores
+const (
+	diasMuroGanadores   = 30
+	limiteMuroGanadores = 50
+)
+
+// GetMuroGanadores retorna los ganadores recientes que aceptaron aparecer en el muro,
+// uno por cliente, con el nombre reducido a "Juan P." y el texto en el idioma pedido
+func (g *GameService) GetMuroGanadores(limite int, locale string) ([]models.GanadorMuro, error) {
+	if limite <= 0 || limite > limiteMuroGanadores {
+		limite = limiteMuroGanadores
+	}
+
+	// Se piden más partidas de las necesarias porque un mismo cliente puede tener varias
+	desde := time.Now().AddDate(0, 0, -diasMuroGanadores)
+	vouchers, err := g.voucherRepo.GetGanadoresMuro(desde, limite*3)
+	if err != nil {
+		return nil, err
+	}
+
+	plantilla := i18n.Textos(locale)["muro_ganador"]
+	formato := g.config.Fechas(locale)
+	vistos := make(map[uint]bool)
+	ganadores := make([]models.GanadorMuro, 0, limite)
+	for _, voucher := range vouchers {
+		if voucher.Cliente == nil || vistos[voucher.ClienteID] {
+			continue
+		}
+		vistos[voucher.ClienteID] = true
+
+		nombre := nombrePublico(voucher.Cliente.Nombre, voucher.Cliente.Apellido)
+		diferencia := math.Round(*voucher.DiferenciaSegundos*100) / 100
+		texto := strings.NewReplacer(
+			"{nombre}", nombre,
+			"{diferencia}", strconv.FormatFloat(diferencia, 'f', 2, 64),
+		).Replace(plantilla)
+
+		ganadores = append(ganadores, models.GanadorMuro{
+			Nombre:             nombre,
+			DiferenciaSegundos: diferencia,
+			Texto:              texto,
+			Fecha:              formato.Dia(voucher.CreatedAt),
+			FechaTexto:         formato.Fecha(voucher.CreatedAt),
+		})
+		if len(ganadores) == limite {
+			break
+		}
+	}
+	return ganadores, nil
+}
+
+// nombrePublico reduce el nombre a la primera palabra y el apellido a su inicial
+func nombrePublico(nombre, apellido string) string {
+	primero, _, _ := strings.Cut(strings.TrimSpace(nombre), " ")
+	publico := capitalizar(primero)
+	if inicial, _ := utf8.DecodeRuneInString(strings.TrimSpace(apellido)); inicial != utf8.RuneError {
+		publico += " " + string(unicode.ToUpper(inicial)) + "."
+	}
+	return publico
+}
+
+// capitalizar pone en mayúscula la primera letra y en minúscula el resto
+func capitalizar(palabra string) string {
+	runas := []rune(strings.ToLower(palabra))
+	if len(runas) > 0 {
+		runas[0] = unicode.ToUpper(runas[0])
+	}
+	return string(runas)
+}
+
+// GetVoucherPorCodigo busca un voucher por su código
+func (g *GameService) GetVoucherPorCodigo(codigo string) (*models.Voucher, error) {
+	return g.voucherRepo.BuscarPorCodigo(strings.ToUpper(strings.TrimSpace(codigo)))
 }
 
 // GetConfiguracionJuego retorna la configuración actual del juego
-func (g *GameService) GetConfiguracionJuego() map[string]interface{} {
-	return map[string]interface{}{
-		"tolerancia":         g.config.Game.Tolerance,
-		"descuento_ganador":  g.config.Game.WinDiscount,
-		"descuento_perdedor": g.config.Game.LoseDiscount,
-		"tiempo_min":         g.config.Game.MinTargetTime,
-		"tiempo_max":         g.config.Game.MaxTargetTime,
-		"validez_voucher":    g.config.Game.VoucherValidityDays,
-		"juegos_aprobacion":  g.config.Game.GamesRequireApproval,
-		"restaurante":        g.config.RestaurantName,
+func (g *GameService) GetConfiguracionJuego() models.ConfiguracionJuego {
+	parametros := g.configuracion.ParametrosJuego()
+	return models.ConfiguracionJuego{
+		Tolerancia:        g.circuito.Tolerancia(),
+		DescuentoGanador:  parametros.DescuentoGanador,
+		DescuentoPerdedor: parametros.DescuentoPerdedor,
+		TiempoMin:         parametros.TiempoMin,
+		TiempoMax:         parametros.TiempoMax,
+		ValidezVoucher:    g.vigencias.Dias("juego_ganado", time.Now()), // Lo que valdría un premio hoy
+		JuegosAprobacion:  g.config.Game.GamesRequireApproval,
+		PartidasPorDia:    max(g.config.Game.MaxPlaysPerDay, 0),
+		PartidasPorSemana: max(g.config.Game.MaxPlaysPerWeek, 0),
+		Restaurante:       g.config.RestaurantName,
+		IdiomaPorDefecto:  g.config.DefaultLanguage,
+		VoucherEnPantalla: g.config.Game.ScreenVoucherMode,
 	}
 }
+
+// RevelarVoucherPantalla retorna el voucher de la partida para mostrarlo en pantalla
+func (g *GameService) RevelarVoucherPantalla(token, digitos string) (*models.VoucherPantalla, error) {
+	return g.pantalla.Revelar(token, digitos)
+}
@@ -0,0 +1,173 @@
+package services
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"CheeseHouse/internal/config"
+	"CheeseHouse/internal/models"
+	"CheeseHouse/internal/repository"
+)
+
+// juegosDePrueba historial en memoria: partidas son las fechas de las partidas jugadas
+// (sin los intentos rechazados) y ultimo la última resuelta
+type juegosDePrueba struct {
+	repository.JuegoRepository
+	partidas []time.Time
+	ultimo   *models.Juego
+}
+
+func (r *juegosDePrueba) ContarPorClienteDesde(clienteID uint, desde time.Time) (int, error) {
+	jugadas := 0
+	for _, partida := range r.partidas {
+		if !partida.Before(desde) {
+			jugadas++
+		}
+	}
+	return jugadas, nil
+}
+
+func (r *juegosDePrueba) UltimoResueltoPorCliente(clienteID uint) (*models.Juego, error) {
+	return r.ultimo, nil
+}
+
+// turnoDePrueba juego sin topes ni espera, con la aprobación bien lejos para que no
+// interfiera
+func turnoDePrueba() (*GameService, *juegosDePrueba, *repository.Transaccion) {
+	cfg := &config.Config{}
+	cfg.Game.GamesRequireApproval = 100
+	juegos := &juegosDePrueba{}
+	return &GameService{config: cfg}, juegos, &repository.Transaccion{Juegos: juegos}
+}
+
+func TestVerificarTurnoEsperaTrasDerrota(t *testing.T) {
+	ahora := time.Date(2026, 10, 14, 12, 0, 0, 0, time.UTC)
+	casos := []struct {
+		nombre   string
+		espera   int // LOSS_COOLDOWN_MINUTES
+		ultimo   *models.Juego
+		segundos int // 0 = puede jugar
+		mensaje  string
+	}{
+		{"perdió dentro de la espera", 30, &models.Juego{Gano: false, CreatedAt: ahora.Add(-5 * time.Minute)}, 25 * 60, "Podés volver a jugar en 25 minutos"},
+		{"redondea los minutos para arriba", 30, &models.Juego{Gano: false, CreatedAt: ahora.Add(-90 * time.Second)}, 28*60 + 30, "Podés volver a jugar en 29 minutos"},
+		{"la espera ya pasó", 30, &models.Juego{Gano: false, CreatedAt: ahora.Add(-40 * time.Minute)}, 0, ""},
+		{"la última fue una victoria", 30, &models.Juego{Gano: true, CreatedAt: ahora.Add(-time.Minute)}, 0, ""},
+		{"nunca jugó", 30, nil, 0, ""},
+		{"sin espera configurada", 0, &models.Juego{Gano: false, CreatedAt: ahora.Add(-time.Minute)}, 0, ""},
+	}
+
+	for _, caso := range casos {
+		t.Run(caso.nombre, func(t *testing.T) {
+			g, juegos, tx := turnoDePrueba()
+			g.config.Game.LossCooldownMinutes = caso.espera
+			juegos.ultimo = caso.ultimo
+
+			rechazo := g.verificarTurno(tx, &models.Cliente{ID: 7}, ahora)
+			if caso.segundos == 0 {
+				if rechazo != nil {
+					t.Fatalf("se rechazó la partida: %s", rechazo.Message)
+				}
+				return
+			}
+			if rechazo == nil {
+				t.Fatal("se dejó jugar durante la espera")
+			}
+			if rechazo.EsperaSegundos != caso.segundos || rechazo.Message != caso.mensaje {
+				t.Errorf("rechazo = %d s %q, se esperaba %d s %q", rechazo.EsperaSegundos, rechazo.Message, caso.segundos, caso.mensaje)
+			}
+			if rechazo.ClienteID != 7 || rechazo.Success {
+				t.Errorf("rechazo = %+v", rechazo)
+			}
+		})
+	}
+}
+
+func TestVerificarTurnoEsperaAntesQueElTope(t *testing.T) {
+	ahora := time.Date(2026, 10, 14, 12, 0, 0, 0, time.UTC)
+	g, juegos, tx := turnoDePrueba()
+	g.config.Game.LossCooldownMinutes = 30
+	g.config.Game.MaxPlaysPerDay = 1
+	juegos.partidas = []time.Time{ahora.Add(-10 * time.Minute)}
+	juegos.ultimo = &models.Juego{Gano: false, CreatedAt: ahora.Add(-10 * time.Minute)}
+
+	rechazo := g.verificarTurno(tx, &models.Cliente{ID: 7}, ahora)
+	if rechazo == nil || rechazo.EsperaSegundos != 20*60 || rechazo.PuedeJugarDesde != "" {
+		t.Fatalf("rechazo = %+v, se esperaba la espera tras derrota", rechazo)
+	}
+}
+
+func TestVerificarTurnoTopeDePartidas(t *testing.T) {
+	// Miércoles al mediodía: la semana empezó el lunes 12 y termina el lunes 19
+	ahora := time.Date(2026, 10, 14, 12, 0, 0, 0, time.UTC)
+	hoy := time.Date(2026, 10, 14, 9, 0, 0, 0, time.UTC)
+	lunes := time.Date(2026, 10, 12, 20, 0, 0, 0, time.UTC)
+	semanaPasada := time.Date(2026, 10, 9, 20, 0, 0, 0, time.UTC)
+	repetir := func(fecha time.Time, n int) []time.Time {
+		partidas := make([]time.Time, n)
+		for i := range partidas {
+			partidas[i] = fecha
+		}
+		return partidas
+	}
+
+	casos := []struct {
+		nombre   string
+		porDia   int
+		porSem   int
+		partidas []time.Time
+		desde    string // "" = puede jugar
+		mensaje  string
+	}{
+		{"debajo del tope diario", 3, 0, repetir(hoy, 2), "", ""},
+		{"llegó al tope diario", 3, 0, repetir(hoy, 3), "2026-10-15T00:00:00Z", "Ya jugaste tus 3 partidas de hoy. ¡Te esperamos mañana para volver a jugar!"},
+		{"tope diario de una partida", 1, 0, repetir(hoy, 1), "2026-10-15T00:00:00Z", "Ya jugaste tu partida de hoy. ¡Te esperamos mañana para volver a jugar!"},
+		{"las de ayer no cuentan para el día", 3, 0, repetir(lunes, 5), "", ""},
+		{"llegó al tope semanal", 0, 5, repetir(lunes, 5), "2026-10-19T00:00:00Z", "Ya jugaste tus 5 partidas de esta semana. Podés volver a jugar desde el"},
+		{"las de la semana pasada no cuentan", 0, 5, repetir(semanaPasada, 9), "", ""},
+		{"con los dos topes manda el semanal", 2, 4, append(repetir(lunes, 2), repetir(hoy, 2)...), "2026-10-19T00:00:00Z", "de esta semana"},
+		{"sin topes configurados", 0, 0, repetir(hoy, 50), "", ""},
+	}
+
+	for _, caso := range casos {
+		t.Run(caso.nombre, func(t *testing.T) {
+			g, juegos, tx := turnoDePrueba()
+			g.config.Game.MaxPlaysPerDay = caso.porDia
+			g.config.Game.MaxPlaysPerWeek = caso.porSem
+			juegos.partidas = caso.partidas
+
+			rechazo := g.verificarTurno(tx, &models.Cliente{ID: 7}, ahora)
+			if caso.desde == "" {
+				if rechazo != nil {
+					t.Fatalf("se rechazó la partida: %s", rechazo.Message)
+				}
+				return
+			}
+			if rechazo == nil {
+				t.Fatal("se dejó jugar pasado el tope")
+			}
+			if rechazo.PuedeJugarDesde != caso.desde || !strings.Contains(rechazo.Message, caso.mensaje) {
+				t.Errorf("rechazo = %s %q, se esperaba %s %q", rechazo.PuedeJugarDesde, rechazo.Message, caso.desde, caso.mensaje)
+			}
+			desde, _ := time.Parse(time.RFC3339, caso.desde)
+			if esperado := int(desde.Sub(ahora).Seconds()); rechazo.EsperaSegundos != esperado {
+				t.Errorf("espera = %d s, se esperaba %d s", rechazo.EsperaSegundos, esperado)
+			}
+		})
+	}
+}
+
+func TestVerificarTurnoTopeSinSaltoPorAprobacion(t *testing.T) {
+	ahora := time.Date(2026, 10, 14, 12, 0, 0, 0, time.UTC)
+	g, juegos, tx := turnoDePrueba()
+	g.config.Game.MaxPlaysPerDay = 3
+	juegos.partidas = []time.Time{ahora.Add(-3 * time.Hour), ahora.Add(-2 * time.Hour), ahora.Add(-time.Hour)}
+	// Un empleado lo aprobó hoy: la aprobación saltea GAMES_REQUIRE_APPROVAL, no el tope
+	aprobado := ahora.Add(-30 * time.Minute)
+
+	rechazo := g.verificarTurno(tx, &models.Cliente{ID: 7, AprobadoEl: &aprobado}, ahora)
+	if rechazo == nil || rechazo.NecesitaAprobacion || rechazo.PuedeJugarDesde == "" {
+		t.Fatalf("rechazo = %+v, se esperaba el tope diario", rechazo)
+	}
+}
@@ -0,0 +1,229 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"CheeseHouse/internal/config"
+	"CheeseHouse/internal/models"
+	"CheeseHouse/internal/repository"
+)
+
+// diasSemana nombres de los días según time.Weekday, para los mensajes a clientes
+var diasSemana = [...]string{"domingo", "lunes", "martes", "miércoles", "jueves", "viernes", "sábado"}
+
+// HorarioService administra los horarios de atención y feriados de cada sucursal
+// y determina si el local está abierto para la respuesta automática de WhatsApp
+type HorarioService struct {
+	config      *config.Config
+	horarioRepo repository.HorarioRepository
+}
+
+// NewHorarioService crea una nueva instancia del servicio de horarios
+func NewHorarioService(cfg *config.Config, horarioRepo repository.HorarioRepository) *HorarioService {
+	return &HorarioService{
+		config:      cfg,
+		horarioRepo: horarioRepo,
+	}
+}
+
+// Sucursal normaliza el nombre de la sucursal; vacío es la configurada en LOCATION
+func (s *HorarioService) Sucursal(sucursal string) string {
+	return s.config.Sucursal(sucursal)
+}
+
+// GetHorarios obtiene los turnos de la sucursal; si nunca se cargaron retorna
+// el horario por defecto de la configuración para todos los días
+func (s *HorarioService) GetHorarios(sucursal string) ([]*models.HorarioSucursal, error) {
+	sucursal = s.Sucursal(sucursal)
+	horarios, err := s.horarioRepo.ListarHorarios(sucursal)
+	if err != nil {
+		return nil, err
+	}
+	if len(horarios) > 0 {
+		return horarios, nil
+	}
+
+	apertura := formatearHora(s.config.BusinessHours.Open)
+	cierre := formatearHora(s.config.BusinessHours.Close)
+	for dia := 0; dia < 7; dia++ {
+		horarios = append(horarios, &models.HorarioSucursal{
+			Sucursal:  sucursal,
+			DiaSemana: dia,
+			Apertura:  apertura,
+			Cierre:    cierre,
+		})
+	}
+	return horarios, nil
+}
+
+// GuardarHorarios reemplaza el horario semanal de la sucursal. Sin turnos vuelve al horario por defecto
+func (s *HorarioService) GuardarHorarios(sucursal string, req models.GuardarHorariosRequest, usuarioID uint) ([]*models.HorarioSucursal, error) {
+	sucursal = s.Sucursal(sucursal)
+
+	horarios := make([]*models.HorarioSucursal, 0, len(req.Turnos))
+	for _, turno := range req.Turnos {
+		apertura, err := minutosDelDia(turno.Apertura)
+		if err != nil {
+			return nil, err
+		}
+		cierre, err := minutosDelDia(turno.Cierre)
+		if err != nil {
+			return nil, err
+		}
+		if apertura == cierre {
+			return nil, fmt.Errorf("el turno del %s abre y cierra a la misma hora", diasSemana[turno.DiaSemana])
+		}
+		horarios = append(horarios, &models.HorarioSucursal{
+			Sucursal:  sucursal,
+			DiaSemana: turno.DiaSemana,
+			Apertura:  formatearHora(apertura),
+			Cierre:    formatearHora(cierre),
+			UpdatedBy: usuarioID,
+		})
+	}
+
+	if err := s.horarioRepo.ReemplazarHorarios(sucursal, horarios); err != nil {
+		return nil, err
+	}
+	return s.GetHorarios(sucursal)
+}
+
+// ListarFeriados obtiene los feriados de la sucursal desde hoy hasta dentro de un año
+func (s *HorarioService) ListarFeriados(sucursal string) ([]*models.Feriado, error) {
+	hoy := time.Now().In(s.config.GetLocation())
+	return s.horarioRepo.ListarFeriados(s.Sucursal(sucursal), hoy, hoy.AddDate(1, 0, 0))
+}
+
+// CrearFeriado carga un feriado; sin sucursal aplica a todas
+func (s *HorarioService) CrearFeriado(req models.CrearFeriadoRequest, usuarioID uint) (*models.Feriado, error) {
+	fecha, err := time.ParseInLocation("2006-01-02", req.Fecha, s.config.GetLocation())
+	if err != nil {
+		return nil, fmt.Errorf("fecha inválida, usar el formato AAAA-MM-DD")
+	}
+
+	feriado := &models.Feriado{
+		Sucursal:    strings.TrimSpace(req.Sucursal),
+		Fecha:       fecha,
+		Descripcion: strings.TrimSpace(req.Descripcion),
+		CreatedBy:   usuarioID,
+	}
+	if err := s.horarioRepo.CrearFeriado(feriado); err != nil {
+		return nil, err
+	}
+	return feriado, nil
+}
+
+// EliminarFeriado borra un feriado
+func (s *HorarioService) EliminarFeriado(id uint) error {
+	return s.horarioRepo.EliminarFeriado(id)
+}
+
+// Estado indica si la sucursal está abierta en t y, si no, cuándo vuelve a abrir
+// (buscando hasta dos semanas hacia adelante y salteando feriados)
+func (s *HorarioService) Estado(sucursal string, t time.Time) (models.EstadoAtencion, error) {
+	sucursal = s.Sucursal(sucursal)
+	estado := models.EstadoAtencion{Sucursal: sucursal}
+
+	horarios, err := s.GetHorarios(sucursal)
+	if err != nil {
+		return estado, err
+	}
+
+	loc := s.config.GetLocation()
+	local := t.In(loc)
+	hoy := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, loc)
+
+	// Desde ayer, por los turnos que cruzan la medianoche
+	lista, err := s.horarioRepo.ListarFeriados(sucursal, hoy.AddDate(0, 0, -1), hoy.AddDate(0, 0, 14))
+	if err != nil {
+		return estado, err
+	}
+	feriados := make(map[string]string, len(lista))
+	for _, feriado := range lista {
+		descripcion := feriado.Descripcion
+		if descripcion == "" {
+			descripcion = "feriado"
+		}
+		feriados[feriado.Fecha.Format("2006-01-02")] = descripcion
+	}
+	estado.Feriado = feriados[hoy.Format("2006-01-02")]
+
+	for d := -1; d <= 14; d++ {
+		dia := hoy.AddDate(0, 0, d)
+		if _, esFeriado := feriados[dia.Format("2006-01-02")]; esFeriado {
+			continue
+		}
+
+		for _, turno := range horarios {
+			if turno.DiaSemana != int(dia.Weekday()) {
+				continue
+			}
+			apertura, err := minutosDelDia(turno.Apertura)
+			if err != nil {
+				continue
+			}
+			cierre, err := minutosDelDia(turno.Cierre)
+			if err != nil {
+				continue
+			}
+
+			inicio := dia.Add(time.Duration(apertura) * time.Minute)
+			fin := dia.Add(time.Duration(cierre) * time.Minute)
+			if cierre <= apertura {
+				fin = fin.AddDate(0, 0, 1)
+			}
+
+			if !local.Before(inicio) && local.Before(fin) {
+				estado.Abierta = true
+				estado.CierraA = &fin
+				estado.ProximaApertura = nil
+				return estado, nil
+			}
+			if inicio.After(local) && (estado.ProximaApertura == nil || inicio.Before(*estado.ProximaApertura)) {
+				proxima := inicio
+				estado.ProximaApertura = &proxima
+			}
+		}
+	}
+
+	return estado, nil
+}
+
+// DescribirApertura arma "hoy a las 19:00", "mañana a las 12:00" o "el lunes a las 12:00"
+func (s *HorarioService) DescribirApertura(apertura time.Time, ahora time.Time) string {
+	loc := s.config.GetLocation()
+	apertura = apertura.In(loc)
+	ahora = ahora.In(loc)
+
+	hoy := time.Date(ahora.Year(), ahora.Month(), ahora.Day(), 0, 0, 0, 0, loc)
+	dia := time.Date(apertura.Year(), apertura.Month(), apertura.Day(), 0, 0, 0, 0, loc)
+	formato := s.config.Fechas("es")
+	hora := formato.Hora(apertura)
+
+	switch {
+	case dia.Equal(hoy):
+		return "hoy a las " + hora
+	case dia.Equal(hoy.AddDate(0, 0, 1)):
+		return "mañana a las " + hora
+	case dia.Before(hoy.AddDate(0, 0, 7)):
+		return fmt.Sprintf("el %s a las %s", diasSemana[apertura.Weekday()], hora)
+	default:
+		return fmt.Sprintf("el %s a las %s", formato.DiaMes(apertura), hora)
+	}
+}
+
+// minutosDelDia convierte "HH:MM" a minutos desde medianoche
+func minutosDelDia(valor string) (int, error) {
+	t, err := time.Parse("15:04", strings.TrimSpace(valor))
+	if err != nil {
+		return 0, fmt.Errorf("hora inválida %q, usar el formato HH:MM", valor)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// formatearHora convierte minutos desde medianoche a "HH:MM"
+func formatearHora(minutos int) string {
+	return fmt.Sprintf("%02d:%02d", minutos/60, minutos%60)
+}
@@ -0,0 +1,141 @@
+package services
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	"CheeseHouse/internal/config"
+	"CheeseHouse/internal/models"
+	"CheeseHouse/internal/repository"
+)
+
+const (
+	// esperaIdempotenciaEnCurso cuánto espera un reintento a que termine el request original
+	// antes de responder que sigue en proceso
+	esperaIdempotenciaEnCurso = 10 * time.Second
+	// sondeoIdempotencia cada cuánto se vuelve a mirar si el request original terminó
+	sondeoIdempotencia = 200 * time.Millisecond
+	// limpiezaIdempotencia frecuencia mínima con la que se borran las claves vencidas
+	limpiezaIdempotencia = time.Hour
+)
+
+var (
+	// ErrIdempotenciaOtroCuerpo la clave ya se usó con un cuerpo distinto
+	ErrIdempotenciaOtroCuerpo = errors.New("la Idempotency-Key ya se usó con otros datos")
+	// ErrIdempotenciaEnCurso el request original con la misma clave todavía no terminó
+	ErrIdempotenciaEnCurso = errors.New("hay un request con la misma Idempotency-Key en proceso")
+)
+
+// IdempotenciaService guarda la respuesta de los requests con Idempotency-Key para que
+// los reintentos (doble toque en el kiosco, reconexiones) reciban la original en lugar de
+// crear otro cliente, otro voucher y otro WhatsApp
+type IdempotenciaService struct {
+	repo repository.IdempotenciaRepository
+	ttl  time.Duration
+
+	mu             sync.Mutex
+	ultimaLimpieza time.Time
+}
+
+// NewIdempotenciaService crea una nueva instancia del servicio de idempotencia
+func NewIdempotenciaService(cfg *config.Config, repo repository.IdempotenciaRepository) *IdempotenciaService {
+	return &IdempotenciaService{
+		repo: repo,
+		ttl:  cfg.IdempotencyTTL,
+	}
+}
+
+// Reservar toma la clave para procesar el request. Si otro request ya la completó retorna
+// su respuesta en previa; si la está procesando espera a que termine
+func (s *IdempotenciaService) Reservar(clave, ruta string, cuerpo []byte) (reserva, previa *models.ClaveIdempotencia, err error) {
+	s.limpiarVencidas()
+
+	suma := sha256.Sum256(cuerpo)
+	huella := hex.EncodeToString(suma[:])
+	limite := time.Now().Add(esperaIdempotenciaEnCurso)
+
+	for {
+		ahora := time.Now()
+		nueva := &models.ClaveIdempotencia{
+			Clave:    clave,
+			Ruta:     ruta,
+			Huella:   huella,
+			ExpiraEn: ahora.Add(s.ttl),
+		}
+		reservada, err := s.repo.Reservar(nueva)
+		if err != nil {
+			return nil, nil, err
+		}
+		if reservada {
+			return nueva, nil, nil
+		}
+
+		existente, err := s.repo.Buscar(clave, ruta)
+		if err != nil {
+			return nil, nil, err
+		}
+		switch {
+		case existente == nil:
+			// Se liberó entre la reserva y la búsqueda; se vuelve a intentar
+			continue
+		case existente.ExpiraEn.Before(ahora):
+			if err := s.repo.Eliminar(existente.ID); err != nil {
+				return nil, nil, err
+			}
+			continue
+		case existente.Huella != huella:
+			return nil, nil, ErrIdempotenciaOtroCuerpo
+		case existente.Status != 0:
+			return nil, existente, nil
+		}
+
+		if ahora.After(limite) {
+			return nil, nil, ErrIdempotenciaEnCurso
+		}
+		time.Sleep(sondeoIdempotencia)
+	}
+}
+
+// Completar guarda la respuesta para los reintentos. Solo se guardan las respuestas
+// exitosas: ante un error (límite de envíos, falla del servidor) la clave se libera para
+// que el reintento se procese de nuevo
+func (s *IdempotenciaService) Completar(reserva *models.ClaveIdempotencia, status int, respuesta []byte) {
+	var err error
+	if status >= 200 && status < 300 {
+		err = s.repo.Completar(reserva.ID, status, string(respuesta))
+	} else {
+		err = s.repo.Eliminar(reserva.ID)
+	}
+	if err != nil {
+		log.Printf("⚠️  Error cerrando Idempotency-Key %s: %v", reserva.Clave, err)
+	}
+}
+
+// Liberar descarta la reserva sin guardar respuesta
+func (s *IdempotenciaService) Liberar(reserva *models.ClaveIdempotencia) {
+	if err := s.repo.Eliminar(reserva.ID); err != nil {
+		log.Printf("⚠️  Error liberando Idempotency-Key %s: %v", reserva.Clave, err)
+	}
+}
+
+// limpiarVencidas borra las claves vencidas, como mucho una vez por limpiezaIdempotencia
+func (s *IdempotenciaService) limpiarVencidas() {
+	ahora := time.Now()
+	s.mu.Lock()
+	if ahora.Sub(s.ultimaLimpieza) < limpiezaIdempotencia {
+		s.mu.Unlock()
+		return
+	}
+	s.ultimaLimpieza = ahora
+	s.mu.Unlock()
+
+	if eliminadas, err := s.repo.EliminarVencidas(ahora); err != nil {
+		log.Printf("⚠️  %v", err)
+	} else if eliminadas > 0 {
+		log.Printf("🧹 %d claves de idempotencia vencidas eliminadas", eliminadas)
+	}
+}
@@ -0,0 +1,300 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"CheeseHouse/internal/config"
+	"CheeseHouse/internal/models"
+	"CheeseHouse/internal/repository"
+)
+
+// diasResumenDiario cuántos días cerrados recalcula cada pasada del resumen diario, así
+// se completan los que faltan si el servidor estuvo apagado a la hora programada
+const diasResumenDiario = 7
+
+var (
+	// ErrTareaNoEncontrada no hay una tarea programada con ese nombre
+	ErrTareaNoEncontrada = errors.New("tarea programada no encontrada")
+	// ErrTareaEnCurso la tarea todavía está corriendo desde la ejecución anterior
+	ErrTareaEnCurso = errors.New("la tarea ya está en curso")
+)
+
+// programacion cuándo corre una tarea: cada un intervalo fijo o todos los días a una hora
+type programacion struct {
+	cada time.Duration // Si es mayor a 0, la tarea corre cada este intervalo
+	hora int           // Minutos desde medianoche, para las tareas diarias
+}
+
+// siguiente próxima ejecución después de ahora, en la zona horaria del restaurante. Las
+// de intervalo fijo se alinean al reloj para que todas las instancias coincidan en el turno
+func (p programacion) siguiente(ahora time.Time, loc *time.Location) time.Time {
+	if p.cada > 0 {
+		return ahora.Truncate(p.cada).Add(p.cada)
+	}
+	local := ahora.In(loc)
+	proxima := time.Date(local.Year(), local.Month(), local.Day(), p.hora/60, p.hora%60, 0, 0, loc)
+	if !proxima.After(local) {
+		proxima = proxima.AddDate(0, 0, 1)
+	}
+	return proxima
+}
+
+func (p programacion) String() string {
+	if p.cada > 0 {
+		return "cada " + p.cada.String()
+	}
+	return fmt.Sprintf("diaria %02d:%02d", p.hora/60, p.hora%60)
+}
+
+// tareaProgramada tarea registrada con su programación y su última ejecución
+type tareaProgramada struct {
+	nombre       string
+	descripcion  string
+	programacion programacion
+	cancelable   bool
+	ejecutar     func(avance *AvanceTrabajo) error
+
+	proxima       *time.Time
+	ultima        *time.Time
+	ultimoTrabajo string
+}
+
+// MantenimientoService corre las tareas de mantenimiento según su programación: marcar
+// vencimientos, archivar datos viejos, reintentar envíos de campañas, guardar el resumen
+// diario y cerrar la semana del ranking. Cada ejecución es un trabajo de tipo
+// "mantenimiento" en /api/admin/jobs
+type MantenimientoService struct {
+	config           *config.Config
+	trabajos         *TrabajoService
+	admin            *AdminService
+	archivo          *ArchivoService
+	estadisticasRepo repository.EstadisticaDiariaRepository
+	juegoRepo        repository.JuegoRepository
+	clienteRepo      *repository.ClienteRepository
+	voucherRepo      repository.VoucherRepository
+	estado           repository.EstadoCompartidoRepository
+	coordinacion     *CoordinacionService
+	ranking          *RankingService
+
+	mu     sync.Mutex
+	tareas []*tareaProgramada
+}
+
+// NewMantenimientoService crea una nueva instancia del servicio de mantenimiento y
+// registra las tareas que la configuración habilita
+func NewMantenimientoService(cfg *config.Config, trabajos *TrabajoService, admin *AdminService, archivo *ArchivoService, estadisticasRepo repository.EstadisticaDiariaRepository, juegoRepo repository.JuegoRepository, clienteRepo *repository.ClienteRepository, voucherRepo repository.VoucherRepository, estado repository.EstadoCompartidoRepository, coordinacion *CoordinacionService, ranking *RankingService) *MantenimientoService {
+	s := &MantenimientoService{
+		config:           cfg,
+		trabajos:         trabajos,
+		admin:            admin,
+		archivo:          archivo,
+		estadisticasRepo: estadisticasRepo,
+		juegoRepo:        juegoRepo,
+		clienteRepo:      clienteRepo,
+		voucherRepo:      voucherRepo,
+		estado:           estado,
+		coordinacion:     coordinacion,
+		ranking:          ranking,
+	}
+
+	diaria := programacion{hora: cfg.Maintenance.Time}
+	s.registrar("vencimientos", "Control de vouchers vencidos sin canjear", diaria, false, s.controlarVencimientos)
+	if cfg.ArchiveAfterDays > 0 {
+		s.registrar("archivado", "Archivado de vouchers viejos", diaria, false, s.archivar)
+	}
+	if cfg.Maintenance.CampaignRetryMinutes > 0 && cfg.Maintenance.CampaignMaxAttempts > 1 {
+		cada := programacion{cada: time.Duration(cfg.Maintenance.CampaignRetryMinutes) * time.Minute}
+		s.registrar("reintentos_campanas", "Reintento de envíos de campañas fallidos", cada, true, s.reintentarEnvios)
+	}
+	s.registrar("resumen_diario", "Resumen diario de partidas, vouchers y clientes", diaria, true, s.resumirDias)
+	s.registrar("ranking_semanal", "Cierre de la semana del ranking y premio al ganador", diaria, false, s.cerrarRanking)
+	s.registrar("estado_compartido", "Limpieza de sesiones, límites y códigos vencidos", programacion{cada: 15 * time.Minute}, false, s.limpiarEstadoCompartido)
+	return s
+}
+
+// registrar agrega una tarea al programador; cancelable indica si su trabajo se puede
+// cancelar desde el panel
+func (s *MantenimientoService) registrar(nombre, descripcion string, prog programacion, cancelable bool, ejecutar func(avance *AvanceTrabajo) error) {
+	s.tareas = append(s.tareas, &tareaProgramada{
+		nombre:       nombre,
+		descripcion:  descripcion,
+		programacion: prog,
+		cancelable:   cancelable,
+		ejecutar:     ejecutar,
+	})
+}
+
+// IniciarProgramador corre cada tarea en su horario. Con MAINTENANCE_ENABLED=false no
+// arranca y las tareas solo se ejecutan a mano desde el panel. Con varias instancias cada
+// ejecución programada corre una sola vez, en la líder; a mano se lanzan en cualquiera
+func (s *MantenimientoService) IniciarProgramador() {
+	if !s.config.Maintenance.Enabled {
+		return
+	}
+	for _, tarea := range s.tareas {
+		go func(tarea *tareaProgramada) {
+			for {
+				proxima := tarea.programacion.siguiente(time.Now(), s.config.GetLocation())
+				s.mu.Lock()
+				tarea.proxima = &proxima
+				s.mu.Unlock()
+
+				time.Sleep(time.Until(proxima))
+				if !s.coordinacion.TomarTurno(tarea.nombre, proxima) {
+					continue
+				}
+				if _, err := s.Ejecutar(tarea.nombre); err != nil && !errors.Is(err, ErrTareaEnCurso) {
+					log.Printf("⚠️  Error iniciando la tarea %s: %v", tarea.nombre, err)
+				}
+			}
+		}(tarea)
+	}
+	log.Printf("🧹 Programador de mantenimiento iniciado (%d tareas)", len(s.tareas))
+}
+
+// Ejecutar lanza la tarea como trabajo en segundo plano, salvo que siga corriendo la
+// ejecución anterior
+func (s *MantenimientoService) Ejecutar(nombre string) (*models.Trabajo, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var tarea *tareaProgramada
+	for _, t := range s.tareas {
+		if t.nombre == nombre {
+			tarea = t
+		}
+	}
+	if tarea == nil {
+		return nil, ErrTareaNoEncontrada
+	}
+	if tarea.ultimoTrabajo != "" {
+		if anterior, err := s.trabajos.Obtener(tarea.ultimoTrabajo); err == nil && anterior.Estado == "en_curso" {
+			return nil, ErrTareaEnCurso
+		}
+	}
+
+	trabajo := s.trabajos.Iniciar("mantenimiento", tarea.descripcion, 0, tarea.cancelable, tarea.ejecutar)
+	ahora := time.Now()
+	tarea.ultima = &ahora
+	tarea.ultimoTrabajo = trabajo.ID
+	return &trabajo, nil
+}
+
+// Tareas lista las tareas registradas con su próxima ejecución y el estado de la última
+func (s *MantenimientoService) Tareas() []models.TareaProgramada {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	lista := make([]models.TareaProgramada, 0, len(s.tareas))
+	for _, tarea := range s.tareas {
+		item := models.TareaProgramada{
+			Nombre:           tarea.nombre,
+			Descripcion:      tarea.descripcion,
+			Programacion:     tarea.programacion.String(),
+			ProximaEjecucion: tarea.proxima,
+			UltimaEjecucion:  tarea.ultima,
+		}
+		if tarea.ultimoTrabajo != "" {
+			if trabajo, err := s.trabajos.Obtener(tarea.ultimoTrabajo); err == nil {
+				item.UltimoTrabajo = trabajo
+			}
+		}
+		lista = append(lista, item)
+	}
+	return lista
+}
+
+// GetEstadisticasDiarias resúmenes guardados de los últimos días cerrados
+func (s *MantenimientoService) GetEstadisticasDiarias(dias int) ([]*models.EstadisticaDiaria, error) {
+	ayer := s.config.InicioDelDia(time.Now()).AddDate(0, 0, -1)
+	return s.estadisticasRepo.ListarEntre(ayer.AddDate(0, 0, -(dias-1)), ayer)
+}
+
+// controlarVencimientos cuenta los vouchers vencidos sin canjear. El vencimiento se
+// deduce de la fecha, así que no hay nada que actualizar: queda registrado en el trabajo
+func (s *MantenimientoService) controlarVencimientos(avance *AvanceTrabajo) error {
+	vencidos, err := s.admin.LimpiarVouchersVencidos()
+	if err != nil {
+		return err
+	}
+	avance.Resultado(map[string]int{"vencidos_sin_canjear": vencidos})
+	return nil
+}
+
+// archivar mueve al archivo los vouchers y partidas viejos (ARCHIVE_AFTER_DAYS)
+func (s *MantenimientoService) archivar(avance *AvanceTrabajo) error {
+	resultado, err := s.archivo.Archivar()
+	if resultado != nil {
+		avance.Resultado(resultado)
+	}
+	return err
+}
+
+// reintentarEnvios reenvía los envíos de campañas fallidos
+func (s *MantenimientoService) reintentarEnvios(avance *AvanceTrabajo) error {
+	return s.admin.ReintentarEnviosCampanas(s.config.Maintenance.CampaignMaxAttempts, avance)
+}
+
+// cerrarRanking cierra la semana anterior del ranking si todavía no se cerró; corre todos
+// los días para que un lunes con el servidor apagado no quede sin cerrar
+func (s *MantenimientoService) cerrarRanking(avance *AvanceTrabajo) error {
+	cierre, err := s.ranking.CerrarSemanaAnterior()
+	if err != nil || cierre == nil {
+		return err
+	}
+	avance.Resultado(cierre)
+	return nil
+}
+
+// limpiarEstadoCompartido borra del estado compartido las entradas vencidas (sesiones de
+// juego, límites de envíos, códigos de verificación, tokens revocados)
+func (s *MantenimientoService) limpiarEstadoCompartido(avance *AvanceTrabajo) error {
+	eliminadas, err := s.estado.EliminarVencidos(time.Now())
+	if err != nil {
+		return err
+	}
+	avance.Resultado(map[string]int64{"eliminadas": eliminadas})
+	return nil
+}
+
+// resumirDias guarda el resumen de los últimos días cerrados; recalcular uno ya guardado
+// lo reemplaza
+func (s *MantenimientoService) resumirDias(avance *AvanceTrabajo) error {
+	avance.FijarTotal(diasResumenDiario)
+	hoy := s.config.InicioDelDia(time.Now())
+	for i := diasResumenDiario; i >= 1; i-- {
+		if avance.Cancelado() {
+			return nil
+		}
+		desde := hoy.AddDate(0, 0, -i)
+		if err := s.resumirDia(desde, desde.AddDate(0, 0, 1)); err != nil {
+			avance.Fallo(desde.Format("2006-01-02"), err)
+			continue
+		}
+		avance.Avanzar(1)
+	}
+	return nil
+}
+
+// resumirDia calcula y guarda la actividad del intervalo [desde, hasta)
+func (s *MantenimientoService) resumirDia(desde, hasta time.Time) error {
+	estadistica := &models.EstadisticaDiaria{Fecha: desde}
+	var err error
+	if estadistica.Partidas, err = s.juegoRepo.ContarEntre(desde, hasta); err != nil {
+		return err
+	}
+	if estadistica.VouchersEmitidos, err = s.voucherRepo.ContarEmitidosEntre(desde, hasta); err != nil {
+		return err
+	}
+	if estadistica.VouchersCanjeados, err = s.voucherRepo.ContarCanjeadosEntre(desde, hasta); err != nil {
+		return err
+	}
+	if estadistica.ClientesNuevos, err = s.clienteRepo.ContarNuevosEntre(desde, hasta); err != nil {
+		return err
+	}
+	return s.estadisticasRepo.Guardar(estadistica)
+}
@@ -0,0 +1,365 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"CheeseHouse/internal/config"
+	"CheeseHouse/internal/fechas"
+	"CheeseHouse/internal/i18n"
+	"CheeseHouse/internal/models"
+	"CheeseHouse/internal/repository"
+)
+
+// ErrMensajeDesconocido la clave no corresponde a ningún mensaje editable
+var ErrMensajeDesconocido = errors.New("mensaje desconocido")
+
+// mensajeEditable texto original de un mensaje y las variables que acepta
+type mensajeEditable struct {
+	Clave       string
+	Descripcion string
+	Contenido   string
+	Variables   []string
+	Requeridas  []string // Sin estas variables el mensaje pierde sentido
+}
+
+// mensajesEditables textos que antes estaban fijos en WhatsAppService; se usan para
+// sembrar mensajes_config y como respaldo si la base no responde
+var mensajesEditables = []mensajeEditable{
+	{
+		Clave:       models.MensajeMarketing,
+		Descripcion: "Mensaje de campañas de marketing. Al final se agregan los links de baja y preferencias",
+		Contenido:   "🧀 *CheeseHouse* 🧀\n\n{mensaje}\n\n🎁 *Código: {codigo}*\n\n¡Te esperamos!",
+		Variables:   []string{"nombre", "mensaje", "codigo", "vencimiento"},
+		Requeridas:  []string{"mensaje", "codigo"},
+	},
+	{
+		Clave:       models.MensajeRespuestaAutomatica,
+		Descripcion: "Respuesta automática a pedidos por WhatsApp. {aviso} indica el tiempo de respuesta o cuándo abrimos",
+		Contenido:   "¡Hola {nombre}! 👋\n\n🧀 Gracias por contactar *CheeseHouse*\n\n{aviso}\n\n¡Gracias por elegirnos! 🧀",
+		Variables:   []string{"nombre", "aviso"},
+		Requeridas:  []string{"aviso"},
+	},
+	{
+		Clave:       models.MensajeCodigoVerificacion,
+		Descripcion: "Código de un solo uso para consultar los datos del cliente",
+		Contenido:   "🧀 *CheeseHouse*\n\nTu código de verificación es *{codigo}*.\nVence en 10 minutos. No lo compartas con nadie.",
+		Variables:   []string{"codigo"},
+		Requeridas:  []string{"codigo"},
+	},
+	{
+		Clave:       models.MensajeVoucherGanador,
+		Descripcion: "Voucher del ganador en texto, solo si el template voucher_ganador no está aprobado en Meta",
+		Contenido:   "🧀 *CheeseHouse* 🧀\n\n¡Felicitaciones {nombre}, ganaste!\n\n🎁 *Código: {codigo}*\n💰 {descuento} de descuento\n📅 Válido hasta {vencimiento}",
+		Variables:   []string{"nombre", "codigo", "descuento", "vencimiento"},
+		Requeridas:  []string{"codigo", "descuento"},
+	},
+	{
+		Clave:       models.MensajeVoucherPerdedor,
+		Descripcion: "Voucher de consuelo en texto, solo si el template voucher_perdedor no está aprobado en Meta",
+		Contenido:   "🧀 *CheeseHouse* 🧀\n\n¡Gracias por jugar, {nombre}!\n\n🎁 *Código: {codigo}*\n💰 {descuento} de descuento\n📅 Válido hasta {vencimiento}",
+		Variables:   []string{"nombre", "codigo", "descuento", "vencimiento"},
+		Requeridas:  []string{"codigo", "descuento"},
+	},
+}
+
+// variablesDeEjemplo valores para previsualizar un mensaje
+var variablesDeEjemplo = map[string]string{
+	"nombre":    "Juan",
+	"mensaje":   "¡Este finde 2x1 en tablas de quesos!",
+	"codigo":    "CH12345678",
+	"descuento": "30%",
+	"aviso":     "⏰ Te responderemos en breve\n📞 O puedes llamarnos directamente",
+}
+
+// patronVariable encuentra las variables {nombre} dentro de un texto
+var patronVariable = regexp.MustCompile(`\{([a-z_]+)\}`)
+
+// MensajeConfigService administra los textos de WhatsApp editables desde el panel
+type MensajeConfigService struct {
+	config      *config.Config
+	mensajeRepo repository.MensajeConfigRepository
+	configRepo  repository.ConfiguracionRepository // Historial de cambios de los textos
+
+	mu        sync.RWMutex
+	cache     map[string]string
+	cargadoEn time.Time // Cuándo se empezó a llenar el cache; vence entero
+}
+
+// NewMensajeConfigService crea una nueva instancia del servicio de textos de mensajes
+func NewMensajeConfigService(cfg *config.Config, mensajeRepo repository.MensajeConfigRepository, configRepo repository.ConfiguracionRepository) *MensajeConfigService {
+	return &MensajeConfigService{
+		config:      cfg,
+		mensajeRepo: mensajeRepo,
+		configRepo:  configRepo,
+		cache:       make(map[string]string),
+	}
+}
+
+// SembrarPorDefecto guarda el texto original de las claves que todavía no están en la base
+func (s *MensajeConfigService) SembrarPorDefecto() {
+	creados := 0
+	for _, editable := range mensajesEditables {
+		guardado, err := s.mensajeRepo.BuscarPorClave(editable.Clave)
+		if err != nil || guardado != nil {
+			continue
+		}
+		if err := s.mensajeRepo.Crear(&models.MensajeConfig{Clave: editable.Clave, Contenido: editable.Contenido}); err != nil {
+			log.Printf("⚠️  No se pudo crear el texto del mensaje %q: %v", editable.Clave, err)
+			continue
+		}
+		creados++
+	}
+	if creados > 0 {
+		log.Printf("💬 %d textos de mensajes por defecto creados", creados)
+	}
+}
+
+// Texto retorna el texto vigente de una clave; si la base no responde usa el original
+func (s *MensajeConfigService) Texto(clave string) string {
+	s.mu.RLock()
+	contenido, ok := s.cache[clave]
+	vigente := s.config.Scaling.CacheVigente(s.cargadoEn)
+	s.mu.RUnlock()
+	if ok && vigente {
+		return contenido
+	}
+
+	editable, err := buscarMensajeEditable(clave)
+	if err != nil {
+		return ""
+	}
+
+	guardado, err := s.mensajeRepo.BuscarPorClave(clave)
+	if err != nil {
+		log.Printf("⚠️  Error obteniendo texto del mensaje %q, usando el original: %v", clave, err)
+		return editable.Contenido
+	}
+
+	contenido = editable.Contenido
+	if guardado != nil {
+		contenido = guardado.Contenido
+	}
+
+	s.mu.Lock()
+	if !s.config.Scaling.CacheVigente(s.cargadoEn) {
+		s.cache = make(map[string]string)
+		s.cargadoEn = time.Now()
+	}
+	s.cache[clave] = contenido
+	s.mu.Unlock()
+
+	return contenido
+}
+
+// Renderizar arma el mensaje de una clave completando sus variables
+func (s *MensajeConfigService) Renderizar(clave string, variables map[string]string) string {
+	return renderizarMensaje(s.Texto(clave), variables)
+}
+
+// Listar obtiene todos los mensajes editables con su texto vigente
+func (s *MensajeConfigService) Listar() ([]models.MensajeConfigDetalle, error) {
+	guardados, err := s.mensajeRepo.Listar()
+	if err != nil {
+		return nil, err
+	}
+
+	porClave := make(map[string]*models.MensajeConfig, len(guardados))
+	for _, guardado := range guardados {
+		porClave[guardado.Clave] = guardado
+	}
+
+	detalles := make([]models.MensajeConfigDetalle, 0, len(mensajesEditables))
+	for _, editable := range mensajesEditables {
+		detalles = append(detalles, armarDetalleMensaje(editable, porClave[editable.Clave]))
+	}
+	return detalles, nil
+}
+
+// Guardar reemplaza el texto de una clave después de validar sus variables
+func (s *MensajeConfigService) Guardar(clave string, req models.GuardarMensajeConfigRequest, usuarioID uint) (*models.MensajeConfigDetalle, error) {
+	editable, err := buscarMensajeEditable(clave)
+	if err != nil {
+		return nil, err
+	}
+	if err := validarVariables(editable, req.Contenido); err != nil {
+		return nil, err
+	}
+
+	mensaje, err := s.mensajeRepo.BuscarPorClave(clave)
+	if err != nil {
+		return nil, err
+	}
+	mensaje, err = s.guardar(editable, mensaje, req.Contenido, &models.CambioConfiguracion{UsuarioID: usuarioID})
+	if err != nil {
+		return nil, err
+	}
+
+	detalle := armarDetalleMensaje(editable, mensaje)
+	return &detalle, nil
+}
+
+// Revertir vuelve el texto de una clave al que tenía antes de un cambio del historial,
+// siempre que nadie lo haya modificado después
+func (s *MensajeConfigService) Revertir(cambio *models.CambioConfiguracion, usuarioID uint) (*models.CambioConfiguracion, error) {
+	editable, err := buscarMensajeEditable(cambio.Clave)
+	if err != nil {
+		return nil, err
+	}
+	mensaje, err := s.mensajeRepo.BuscarPorClave(cambio.Clave)
+	if err != nil {
+		return nil, err
+	}
+
+	actual := editable.Contenido
+	if mensaje != nil {
+		actual = mensaje.Contenido
+	}
+	if actual != cambio.ValorNuevo {
+		return nil, fmt.Errorf("%w (el texto de %q se editó otra vez)", ErrCambioSuperado, cambio.Clave)
+	}
+	if err := validarVariables(editable, cambio.ValorAnterior); err != nil {
+		return nil, err
+	}
+
+	reversion := &models.CambioConfiguracion{
+		Motivo:    fmt.Sprintf("Reversión del cambio #%d", cambio.ID),
+		UsuarioID: usuarioID,
+		RevierteA: &cambio.ID,
+	}
+	if _, err := s.guardar(editable, mensaje, cambio.ValorAnterior, reversion); err != nil {
+		return nil, err
+	}
+
+	log.Printf("↩️  Usuario %d revirtió el cambio #%d del texto %q", usuarioID, cambio.ID, cambio.Clave)
+	return reversion, nil
+}
+
+// guardar persiste el texto nuevo de la clave junto con su versión en el historial. Si
+// el texto no cambia no se registra nada
+func (s *MensajeConfigService) guardar(editable mensajeEditable, mensaje *models.MensajeConfig, contenido string, cambio *models.CambioConfiguracion) (*models.MensajeConfig, error) {
+	if mensaje == nil {
+		mensaje = &models.MensajeConfig{Clave: editable.Clave, Contenido: editable.Contenido}
+	}
+	anterior := mensaje.Contenido
+	mensaje.Contenido = contenido
+	mensaje.UpdatedBy = cambio.UsuarioID
+
+	var cambios []*models.CambioConfiguracion
+	if anterior != contenido {
+		cambio.Ambito = models.AmbitoMensaje
+		cambio.Clave = editable.Clave
+		cambio.ValorAnterior = anterior
+		cambio.ValorNuevo = contenido
+		cambios = append(cambios, cambio)
+	}
+
+	if err := s.configRepo.GuardarConCambios(mensaje, cambios); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	delete(s.cache, editable.Clave)
+	s.mu.Unlock()
+	return mensaje, nil
+}
+
+// Restablecer vuelve el texto de una clave al original
+func (s *MensajeConfigService) Restablecer(clave string, usuarioID uint) (*models.MensajeConfigDetalle, error) {
+	editable, err := buscarMensajeEditable(clave)
+	if err != nil {
+		return nil, err
+	}
+	return s.Guardar(clave, models.GuardarMensajeConfigRequest{Contenido: editable.Contenido}, usuarioID)
+}
+
+// Previsualizar muestra cómo queda el mensaje con el texto indicado (o el vigente) y
+// las variables enviadas, completando las que faltan con valores de ejemplo
+func (s *MensajeConfigService) Previsualizar(clave string, req models.PrevisualizarMensajeRequest) (string, error) {
+	editable, err := buscarMensajeEditable(clave)
+	if err != nil {
+		return "", err
+	}
+
+	contenido := req.Contenido
+	if contenido == "" {
+		contenido = s.Texto(clave)
+	}
+	if err := validarVariables(editable, contenido); err != nil {
+		return "", err
+	}
+
+	variables := map[string]string{
+		"vencimiento": fechas.Nuevo(nil, i18n.LocalePorDefecto).Fecha(time.Now().AddDate(0, 0, 30)),
+	}
+	for nombre, valor := range variablesDeEjemplo {
+		variables[nombre] = valor
+	}
+	for nombre, valor := range req.Variables {
+		variables[nombre] = valor
+	}
+
+	return renderizarMensaje(contenido, variables), nil
+}
+
+// buscarMensajeEditable busca la definición de una clave
+func buscarMensajeEditable(clave string) (mensajeEditable, error) {
+	for _, editable := range mensajesEditables {
+		if editable.Clave == clave {
+			return editable, nil
+		}
+	}
+	return mensajeEditable{}, fmt.Errorf("%w: %q", ErrMensajeDesconocido, clave)
+}
+
+// validarVariables rechaza variables que la clave no completa y exige las requeridas
+func validarVariables(editable mensajeEditable, contenido string) error {
+	for _, coincidencia := range patronVariable.FindAllStringSubmatch(contenido, -1) {
+		if !slices.Contains(editable.Variables, coincidencia[1]) {
+			return fmt.Errorf("la variable {%s} no está disponible en este mensaje (disponibles: {%s})",
+				coincidencia[1], strings.Join(editable.Variables, "}, {"))
+		}
+	}
+	for _, requerida := range editable.Requeridas {
+		if !strings.Contains(contenido, "{"+requerida+"}") {
+			return fmt.Errorf("el mensaje debe incluir la variable {%s}", requerida)
+		}
+	}
+	return nil
+}
+
+// armarDetalleMensaje combina la definición de una clave con lo guardado en la base
+func armarDetalleMensaje(editable mensajeEditable, guardado *models.MensajeConfig) models.MensajeConfigDetalle {
+	detalle := models.MensajeConfigDetalle{
+		Clave:       editable.Clave,
+		Descripcion: editable.Descripcion,
+		Contenido:   editable.Contenido,
+		PorDefecto:  editable.Contenido,
+		Variables:   editable.Variables,
+	}
+	if guardado != nil {
+		detalle.Contenido = guardado.Contenido
+		detalle.Personalizado = guardado.Contenido != editable.Contenido
+		detalle.UpdatedBy = guardado.UpdatedBy
+		if !guardado.UpdatedAt.IsZero() {
+			ultimoCambio := guardado.UpdatedAt
+			detalle.UltimoCambio = &ultimoCambio
+		}
+	}
+	return detalle
+}
+
+// renderizarMensaje completa las variables del texto. {nombre} sin valor se quita sin
+// dejar espacios sueltos; el resto de las variables sin valor quedan vacías
+func renderizarMensaje(contenido string, variables map[string]string) string {
+	contenido = reemplazarNombre(contenido, variables["nombre"])
+	return patronVariable.ReplaceAllStringFunc(contenido, func(variable string) string {
+		return variables[strings.Trim(variable, "{}")]
+	})
+}
@@ -0,0 +1,26 @@
+package services
+
+import (
+	"errors"
+
+	"CheeseHouse/internal/models"
+)
+
+// ErrPlantillaNoSoportada el proveedor no maneja templates (ej. SMS); hay que mandar texto
+var ErrPlantillaNoSoportada = errors.New("el proveedor no admite templates")
+
+// ProveedorMensajeria canal por el que salen los mensajes a los clientes. WhatsAppService
+// arma los mensajes (templates, textos editables, preferencias, outbox) y el proveedor
+// solo los entrega. Los teléfonos llegan en E.164, con o sin "+"
+type ProveedorMensajeria interface {
+	// Nombre identifica al proveedor en logs y en /health
+	Nombre() string
+	// Configurado indica si tiene las credenciales para enviar
+	Configurado() bool
+	// EnviarPlantilla envía un template aprobado y retorna el ID del mensaje del proveedor
+	EnviarPlantilla(telefono string, plantilla *models.Template) (string, error)
+	// EnviarTexto envía un texto libre y retorna el ID del mensaje del proveedor
+	EnviarTexto(telefono string, texto string) (string, error)
+	// Estado resume la configuración para /health, sin secretos
+	Estado() map[string]interface{}
+}
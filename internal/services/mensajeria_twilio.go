@@ -0,0 +1,100 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"CheeseHouse/internal/config"
+	"CheeseHouse/internal/models"
+)
+
+// proveedorTwilioSMS entrega mensajes de texto por SMS con la API de Twilio. No tiene
+// templates: se usa para el texto de respaldo de los vouchers
+type proveedorTwilioSMS struct {
+	client     *http.Client
+	apiURL     string
+	accountSID string
+	authToken  string
+	remitente  string
+}
+
+// nuevoProveedorTwilioSMS crea el proveedor de SMS; nil si Twilio no está configurado
+func nuevoProveedorTwilioSMS(cfg *config.Config, client *http.Client) *proveedorTwilioSMS {
+	if !cfg.SMS.Configurado() {
+		return nil
+	}
+	return &proveedorTwilioSMS{
+		client:     client,
+		apiURL:     cfg.SMS.TwilioURL,
+		accountSID: cfg.SMS.TwilioAccountSID,
+		authToken:  cfg.SMS.TwilioAuthToken,
+		remitente:  cfg.SMS.From,
+	}
+}
+
+func (p *proveedorTwilioSMS) Nombre() string { return "twilio_sms" }
+
+func (p *proveedorTwilioSMS) Configurado() bool {
+	return p != nil && p.accountSID != "" && p.authToken != "" && p.remitente != ""
+}
+
+func (p *proveedorTwilioSMS) EnviarPlantilla(string, *models.Template) (string, error) {
+	return "", ErrPlantillaNoSoportada
+}
+
+// EnviarTexto crea el mensaje en /Messages.json y retorna su SID
+func (p *proveedorTwilioSMS) EnviarTexto(destino string, texto string) (string, error) {
+	datos := url.Values{
+		"To":   {"+" + strings.TrimPrefix(destino, "+")},
+		"Body": {texto},
+	}
+	// Un Messaging Service elige el número remitente; si no, se usa el número fijo
+	if strings.HasPrefix(p.remitente, "MG") {
+		datos.Set("MessagingServiceSid", p.remitente)
+	} else {
+		datos.Set("From", p.remitente)
+	}
+
+	endpoint := fmt.Sprintf("%s/2010-04-01/Accounts/%s/Messages.json", p.apiURL, p.accountSID)
+	req, err := http.NewRequest("POST", endpoint, strings.NewReader(datos.Encode()))
+	if err != nil {
+		return "", fmt.Errorf("error al crear request de SMS: %w", err)
+	}
+	req.SetBasicAuth(p.accountSID, p.authToken)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("error al enviar SMS: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var respuesta struct {
+		SID     string `json:"sid"`
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	}
+	json.NewDecoder(resp.Body).Decode(&respuesta)
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
+		if respuesta.Message == "" {
+			respuesta.Message = "respuesta de error no reconocida"
+		}
+		return "", fmt.Errorf("Twilio respondió %d: %s (código %d)", resp.StatusCode, respuesta.Message, respuesta.Code)
+	}
+
+	log.Printf("✅ SMS enviado exitosamente: %s", respuesta.SID)
+	return respuesta.SID, nil
+}
+
+func (p *proveedorTwilioSMS) Estado() map[string]interface{} {
+	return map[string]interface{}{
+		"configured":  p.Configurado(),
+		"account_sid": p.accountSID != "",
+		"from":        p.remitente,
+		"api_url":     p.apiURL,
+	}
+}
@@ -0,0 +1,122 @@
+package services
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+
+	"CheeseHouse/internal/config"
+	"CheeseHouse/internal/models"
+	"CheeseHouse/internal/telefono"
+)
+
+// proveedorWhatsApp entrega los mensajes por la API de WhatsApp Cloud de Meta
+type proveedorWhatsApp struct {
+	client        *http.Client
+	accessToken   string
+	phoneNumberID string
+	apiURL        string
+}
+
+// nuevoProveedorWhatsApp crea el proveedor de WhatsApp con el cliente HTTP del servicio
+// (así EnvolverTransporte también alcanza a los envíos)
+func nuevoProveedorWhatsApp(cfg *config.Config, client *http.Client) *proveedorWhatsApp {
+	return &proveedorWhatsApp{
+		client:        client,
+		accessToken:   cfg.WhatsAppToken,
+		phoneNumberID: cfg.WhatsAppPhoneNumberID,
+		apiURL:        cfg.WhatsAppURL,
+	}
+}
+
+func (p *proveedorWhatsApp) Nombre() string { return "whatsapp" }
+
+func (p *proveedorWhatsApp) Configurado() bool {
+	return p.accessToken != "" && p.phoneNumberID != ""
+}
+
+func (p *proveedorWhatsApp) EnviarPlantilla(destino string, plantilla *models.Template) (string, error) {
+	return p.enviar(models.WhatsAppMessage{
+		MessagingProduct: "whatsapp",
+		To:               telefono.SinMas(destino),
+		Type:             "template",
+		Template:         plantilla,
+	})
+}
+
+func (p *proveedorWhatsApp) EnviarTexto(destino string, texto string) (string, error) {
+	return p.enviar(models.WhatsAppMessage{
+		MessagingProduct: "whatsapp",
+		To:               telefono.SinMas(destino),
+		Type:             "text",
+		Text:             &models.TextBody{Body: texto},
+	})
+}
+
+func (p *proveedorWhatsApp) Estado() map[string]interface{} {
+	return map[string]interface{}{
+		"configured":      p.Configurado(),
+		"access_token":    p.accessToken != "",
+		"phone_number_id": p.phoneNumberID != "",
+		"api_url":         p.apiURL,
+	}
+}
+
+// enviar hace el POST a /messages y retorna el wamid con el que la API aceptó el mensaje
+func (p *proveedorWhatsApp) enviar(message models.WhatsAppMessage) (string, error) {
+	url := fmt.Sprintf("%s/%s/messages", p.apiURL, p.phoneNumberID)
+
+	jsonData, err := json.Marshal(message)
+	if err != nil {
+		return "", fmt.Errorf("error al serializar mensaje: %w", err)
+	}
+
+	req, err := http.NewRequest("POST", url, bytes.NewBuffer(jsonData))
+	if err != nil {
+		return "", fmt.Errorf("error al crear request: %w", err)
+	}
+
+	req.Header.Set("Authorization", "Bearer "+p.accessToken)
+	req.Header.Set("Content-Type", "application/json")
+
+	log.Printf("📱 Enviando WhatsApp a %s: %s", message.To, string(jsonData))
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("error al enviar mensaje: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", parsearErrorAPI(resp)
+	}
+
+	// Leer respuesta de éxito
+	var successResp struct {
+		Messages []struct {
+			ID string `json:"id"`
+		} `json:"messages"`
+	}
+	messageID := ""
+	if err := json.NewDecoder(resp.Body).Decode(&successResp); err == nil && len(successResp.Messages) > 0 {
+		messageID = successResp.Messages[0].ID
+		log.Printf("✅ WhatsApp enviado exitosamente: %s", messageID)
+	}
+	return messageID, nil
+}
+
+// parsearErrorAPI interpreta el cuerpo de error estructurado de la API de WhatsApp
+func parsearErrorAPI(resp *http.Response) *models.WhatsAppAPIError {
+	var errorResp models.WhatsAppErrorResponse
+	if err := json.NewDecoder(resp.Body).Decode(&errorResp); err != nil || errorResp.Error == nil {
+		return &models.WhatsAppAPIError{
+			HTTPStatus: resp.StatusCode,
+			Message:    "respuesta de error no reconocida",
+		}
+	}
+
+	errorResp.Error.HTTPStatus = resp.StatusCode
+	return errorResp.Error
+}
@@ -0,0 +1,207 @@
+package services
+
+import (
+	"fmt"
+	"log"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"CheeseHouse/internal/barcode"
+	"CheeseHouse/internal/config"
+	"CheeseHouse/internal/models"
+	"CheeseHouse/internal/repository"
+)
+
+// MesaService administra las mesas del local y sus códigos QR. Cada QR abre el juego con
+// el número de mesa, que queda registrado en la partida
+type MesaService struct {
+	config         *config.Config
+	mesaRepo       repository.MesaRepository
+	voucherRepo    repository.VoucherRepository
+	notificaciones *NotificacionService
+
+	mu    sync.RWMutex
+	cache map[string]*models.Mesa // Por número
+}
+
+// NewMesaService crea una nueva instancia del servicio de mesas
+func NewMesaService(cfg *config.Config, mesaRepo repository.MesaRepository, voucherRepo repository.VoucherRepository, notificaciones *NotificacionService) *MesaService {
+	return &MesaService{
+		config:         cfg,
+		mesaRepo:       mesaRepo,
+		voucherRepo:    voucherRepo,
+		notificaciones: notificaciones,
+		cache:          make(map[string]*models.Mesa),
+	}
+}
+
+// Crear da de alta una mesa
+func (s *MesaService) Crear(req models.CrearMesaRequest) (*models.MesaDetalle, error) {
+	numero := normalizarNumeroMesa(req.Numero)
+	if numero == "" {
+		return nil, fmt.Errorf("el número de mesa es obligatorio")
+	}
+	existente, err := s.mesaRepo.BuscarPorNumero(numero)
+	if err != nil {
+		return nil, err
+	}
+	if existente != nil {
+		return nil, fmt.Errorf("ya existe la mesa %s", numero)
+	}
+
+	mesa := &models.Mesa{
+		Numero: numero,
+		Area:   strings.TrimSpace(req.Area),
+		Activa: true,
+	}
+	if err := s.mesaRepo.Crear(mesa); err != nil {
+		return nil, err
+	}
+
+	log.Printf("🪑 Mesa %s creada (%s)", mesa.Numero, mesa.Area)
+	detalle := s.detalle(mesa, models.EstadisticasMesa{})
+	return &detalle, nil
+}
+
+// Actualizar cambia el número, el área o el estado de una mesa. Cambiar el número
+// invalida los QR impresos con el anterior
+func (s *MesaService) Actualizar(id uint, req models.ActualizarMesaRequest) (*models.MesaDetalle, error) {
+	mesa, err := s.mesaRepo.BuscarPorID(id)
+	if err != nil {
+		return nil, err
+	}
+	numeroAnterior := mesa.Numero
+
+	if numero := normalizarNumeroMesa(req.Numero); numero != "" && numero != mesa.Numero {
+		existente, err := s.mesaRepo.BuscarPorNumero(numero)
+		if err != nil {
+			return nil, err
+		}
+		if existente != nil {
+			return nil, fmt.Errorf("ya existe la mesa %s", numero)
+		}
+		mesa.Numero = numero
+	}
+	if req.Area != "" {
+		mesa.Area = strings.TrimSpace(req.Area)
+	}
+	if req.Activa != nil {
+		mesa.Activa = *req.Activa
+	}
+
+	if err := s.mesaRepo.Actualizar(mesa); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	delete(s.cache, numeroAnterior)
+	delete(s.cache, mesa.Numero)
+	s.mu.Unlock()
+
+	log.Printf("🪑 Mesa #%d actualizada: %s (%s, activa=%t)", mesa.ID, mesa.Numero, mesa.Area, mesa.Activa)
+	detalle := s.detalle(mesa, models.EstadisticasMesa{})
+	return &detalle, nil
+}
+
+// Listar obtiene las mesas con sus partidas y el resumen por área de los últimos días
+func (s *MesaService) Listar(dias int) ([]models.MesaDetalle, []models.EstadisticasArea, error) {
+	mesas, err := s.mesaRepo.Listar()
+	if err != nil {
+		return nil, nil, err
+	}
+	desde := time.Now().AddDate(0, 0, -dias)
+	estadisticas, err := s.voucherRepo.GetEstadisticasPorMesa(desde)
+	if err != nil {
+		return nil, nil, err
+	}
+	areas, err := s.voucherRepo.GetEstadisticasPorArea(desde)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	detalles := make([]models.MesaDetalle, 0, len(mesas))
+	for _, mesa := range mesas {
+		detalles = append(detalles, s.detalle(mesa, estadisticas[mesa.ID]))
+	}
+	return detalles, areas, nil
+}
+
+// BuscarActiva retorna la mesa activa con ese número o nil. Un número desconocido no
+// impide jugar: la partida queda sin mesa
+func (s *MesaService) BuscarActiva(numero string) *models.Mesa {
+	numero = normalizarNumeroMesa(numero)
+	if numero == "" {
+		return nil
+	}
+
+	s.mu.RLock()
+	mesa, ok := s.cache[numero]
+	s.mu.RUnlock()
+
+	if !ok {
+		var err error
+		mesa, err = s.mesaRepo.BuscarPorNumero(numero)
+		if err != nil {
+			log.Printf("⚠️  No se pudo buscar la mesa %s: %v", numero, err)
+			return nil
+		}
+		if mesa == nil {
+			log.Printf("⚠️  Partida con mesa desconocida: %q", numero)
+			return nil
+		}
+
+		s.mu.Lock()
+		s.cache[numero] = mesa
+		s.mu.Unlock()
+	}
+
+	if !mesa.Activa {
+		return nil
+	}
+	return mesa
+}
+
+// QR genera la imagen del código QR de la mesa para imprimir
+func (s *MesaService) QR(id uint, tamModulo int) (*models.Mesa, []byte, error) {
+	mesa, err := s.mesaRepo.BuscarPorID(id)
+	if err != nil {
+		return nil, nil, err
+	}
+	imagen, err := barcode.QRPNG(s.URL(mesa), tamModulo)
+	if err != nil {
+		return nil, nil, err
+	}
+	return mesa, imagen, nil
+}
+
+// URL dirección del juego con el número de mesa, la que codifica el QR
+func (s *MesaService) URL(mesa *models.Mesa) string {
+	return strings.TrimRight(s.config.PublicBaseURL, "/") + "/?mesa=" + url.QueryEscape(mesa.Numero)
+}
+
+// AvisarPremio avisa al personal qué mesa ganó para que lleven el premio
+func (s *MesaService) AvisarPremio(mesa *models.Mesa, cliente *models.Cliente, voucher *models.Voucher) {
+	titulo := "Premio para la mesa " + mesa.Numero
+	if mesa.Area != "" {
+		titulo += " (" + mesa.Area + ")"
+	}
+	s.notificaciones.Notificar("info", titulo,
+		fmt.Sprintf("%s %s ganó un %d%% de descuento, voucher %s", cliente.Nombre, cliente.Apellido, voucher.Descuento, voucher.Codigo),
+		"entregar_premio_mesa")
+}
+
+// detalle arma la respuesta del panel con la URL del QR
+func (s *MesaService) detalle(mesa *models.Mesa, estadisticas models.EstadisticasMesa) models.MesaDetalle {
+	return models.MesaDetalle{
+		Mesa:         mesa,
+		URL:          s.URL(mesa),
+		Estadisticas: estadisticas,
+	}
+}
+
+// normalizarNumeroMesa el número se compara sin espacios y en mayúsculas (t3 = T3)
+func normalizarNumeroMesa(numero string) string {
+	return strings.ToUpper(strings.TrimSpace(numero))
+}
@@ -0,0 +1,330 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"strings"
+	"time"
+
+	"CheeseHouse/internal/config"
+	"CheeseHouse/internal/models"
+	"CheeseHouse/internal/repository"
+)
+
+// horaResumenSemanal hora local de los lunes en que se manda el resumen de la semana anterior
+const horaResumenSemanal = 9
+
+// Períodos de la comparación de indicadores
+const (
+	PeriodoSemana = "semana"
+	PeriodoMes    = "mes"
+)
+
+// ErrPeriodoComparacion el período pedido no es semana ni mes
+var ErrPeriodoComparacion = errors.New("el período debe ser semana o mes")
+
+// MetaService administra las metas mensuales de la promoción (partidas, clientes nuevos,
+// canjes y tasa de canje), calcula el avance del mes y manda el resumen semanal
+type MetaService struct {
+	config         *config.Config
+	metaRepo       repository.MetaRepository
+	juegoRepo      repository.JuegoRepository
+	clienteRepo    *repository.ClienteRepository
+	voucherRepo    repository.VoucherRepository
+	notificaciones *NotificacionService
+}
+
+// NewMetaService crea una nueva instancia del servicio de metas
+func NewMetaService(cfg *config.Config, metaRepo repository.MetaRepository, juegoRepo repository.JuegoRepository, clienteRepo *repository.ClienteRepository, voucherRepo repository.VoucherRepository, notificaciones *NotificacionService) *MetaService {
+	return &MetaService{
+		config:         cfg,
+		metaRepo:       metaRepo,
+		juegoRepo:      juegoRepo,
+		clienteRepo:    clienteRepo,
+		voucherRepo:    voucherRepo,
+		notificaciones: notificaciones,
+	}
+}
+
+// Guardar fija las metas de un mes (AAAA-MM)
+func (s *MetaService) Guardar(mes string, req models.GuardarMetaMensualRequest, usuarioID uint) (*models.MetaMensual, error) {
+	if _, _, err := s.rangoMes(mes); err != nil {
+		return nil, err
+	}
+
+	meta, err := s.metaRepo.BuscarPorMes(mes)
+	if err != nil {
+		return nil, err
+	}
+	if meta == nil {
+		meta = &models.MetaMensual{Mes: mes}
+	}
+	meta.Partidas = req.Partidas
+	meta.ClientesNuevos = req.ClientesNuevos
+	meta.Canjes = req.Canjes
+	meta.TasaCanje = req.TasaCanje
+	meta.UpdatedBy = usuarioID
+
+	if err := s.metaRepo.Guardar(meta); err != nil {
+		return nil, err
+	}
+
+	log.Printf("🎯 Usuario %d fijó las metas de %s: %d partidas, %d clientes nuevos, %d canjes, %.1f%% de canje",
+		usuarioID, mes, meta.Partidas, meta.ClientesNuevos, meta.Canjes, meta.TasaCanje)
+	return meta, nil
+}
+
+// Progreso compara lo logrado en el mes (vacío = el actual) con sus metas. Los
+// indicadores acumulativos se proyectan a fin de mes al ritmo de los días corridos
+func (s *MetaService) Progreso(mes string) (*models.ProgresoMetas, error) {
+	if mes == "" {
+		mes = time.Now().In(s.config.GetLocation()).Format("2006-01")
+	}
+	desde, hasta, err := s.rangoMes(mes)
+	if err != nil {
+		return nil, err
+	}
+
+	meta, err := s.metaRepo.BuscarPorMes(mes)
+	if err != nil {
+		return nil, err
+	}
+	actual, err := s.Indicadores(desde, hasta)
+	if err != nil {
+		return nil, err
+	}
+
+	// Fracción del mes transcurrida, para proyectar
+	ahora := time.Now()
+	fraccion := 1.0
+	if ahora.Before(hasta) {
+		fraccion = math.Max(0, ahora.Sub(desde).Hours()/hasta.Sub(desde).Hours())
+	}
+
+	progreso := &models.ProgresoMetas{
+		Mes:          mes,
+		DiasTotales:  hasta.AddDate(0, 0, -1).Day(),
+		DiasCorridos: int(math.Ceil(fraccion * float64(hasta.AddDate(0, 0, -1).Day()))),
+		Meta:         meta,
+		Actual:       actual,
+		Indicadores:  []models.ProgresoIndicador{},
+	}
+	if meta == nil {
+		return progreso, nil
+	}
+
+	agregar := func(indicador string, objetivo, logrado float64, acumulativo bool) {
+		if objetivo <= 0 {
+			return
+		}
+		proyectado := logrado
+		if acumulativo && fraccion > 0 {
+			proyectado = logrado / fraccion
+		}
+		progreso.Indicadores = append(progreso.Indicadores, models.ProgresoIndicador{
+			Indicador:  indicador,
+			Meta:       objetivo,
+			Actual:     logrado,
+			Porcentaje: math.Round(logrado/objetivo*1000) / 10,
+			Proyectado: math.Round(proyectado*10) / 10,
+			EnCamino:   proyectado >= objetivo,
+		})
+	}
+	agregar("partidas", float64(meta.Partidas), float64(actual.Partidas), true)
+	agregar("clientes_nuevos", float64(meta.ClientesNuevos), float64(actual.ClientesNuevos), true)
+	agregar("canjes", float64(meta.Canjes), float64(actual.Canjes), true)
+	agregar("tasa_canje", meta.TasaCanje, actual.TasaCanje, false)
+
+	return progreso, nil
+}
+
+// Indicadores calcula los indicadores promocionales del rango [desde, hasta)
+func (s *MetaService) Indicadores(desde, hasta time.Time) (models.IndicadoresPromocion, error) {
+	var indicadores models.IndicadoresPromocion
+	var err error
+
+	if indicadores.Partidas, err = s.juegoRepo.ContarEntre(desde, hasta); err != nil {
+		return indicadores, err
+	}
+	if indicadores.ClientesNuevos, err = s.clienteRepo.ContarNuevosEntre(desde, hasta); err != nil {
+		return indicadores, err
+	}
+	if indicadores.Canjes, err = s.voucherRepo.ContarCanjeadosEntre(desde, hasta); err != nil {
+		return indicadores, err
+	}
+	emitidos, canjeados, err := s.voucherRepo.GetCanjeDeEmitidosEntre(desde, hasta)
+	if err != nil {
+		return indicadores, err
+	}
+	indicadores.TasaCanje = porcentaje(canjeados, emitidos)
+
+	return indicadores, nil
+}
+
+// Comparar compara los indicadores de la semana en curso con la semana pasada, o los del
+// mes en curso con el mismo mes del año anterior. El período en curso va hasta ahora y se
+// compara con el mismo tramo del otro (del lunes a esta hora contra lo mismo de la semana
+// pasada), así un período a medio andar no aparece siempre en baja
+func (s *MetaService) Comparar(periodo string) (*models.ComparacionPeriodos, error) {
+	ahora := time.Now()
+	comparacion := &models.ComparacionPeriodos{Periodo: periodo, Hasta: ahora}
+	switch periodo {
+	case PeriodoSemana:
+		comparacion.Desde = s.config.InicioDeSemana(ahora)
+		comparacion.AnteriorDesde = comparacion.Desde.AddDate(0, 0, -7)
+		comparacion.AnteriorHasta = ahora.AddDate(0, 0, -7)
+	case PeriodoMes:
+		local := ahora.In(s.config.GetLocation())
+		comparacion.Desde = time.Date(local.Year(), local.Month(), 1, 0, 0, 0, 0, local.Location())
+		comparacion.AnteriorDesde = comparacion.Desde.AddDate(-1, 0, 0)
+		comparacion.AnteriorHasta = ahora.AddDate(-1, 0, 0)
+	default:
+		return nil, ErrPeriodoComparacion
+	}
+
+	actual, err := s.Indicadores(comparacion.Desde, comparacion.Hasta)
+	if err != nil {
+		return nil, err
+	}
+	anterior, err := s.Indicadores(comparacion.AnteriorDesde, comparacion.AnteriorHasta)
+	if err != nil {
+		return nil, err
+	}
+
+	comparacion.Indicadores = map[string]models.VariacionIndicador{
+		"partidas":        variacionIndicador(float64(actual.Partidas), float64(anterior.Partidas)),
+		"clientes_nuevos": variacionIndicador(float64(actual.ClientesNuevos), float64(anterior.ClientesNuevos)),
+		"canjes":          variacionIndicador(float64(actual.Canjes), float64(anterior.Canjes)),
+		"tasa_canje":      variacionIndicador(actual.TasaCanje, anterior.TasaCanje),
+	}
+	return comparacion, nil
+}
+
+// CompararTodos arma la comparación semanal y la mensual
+func (s *MetaService) CompararTodos() ([]*models.ComparacionPeriodos, error) {
+	comparaciones := make([]*models.ComparacionPeriodos, 0, 2)
+	for _, periodo := range []string{PeriodoSemana, PeriodoMes} {
+		comparacion, err := s.Comparar(periodo)
+		if err != nil {
+			return nil, err
+		}
+		comparaciones = append(comparaciones, comparacion)
+	}
+	return comparaciones, nil
+}
+
+// EnviarResumenSemanal manda al centro de notificaciones (y a Slack/Telegram) los
+// indicadores de la semana pasada, su variación contra la anterior y el avance del mes
+func (s *MetaService) EnviarResumenSemanal() {
+	hoy := s.config.InicioDelDia(time.Now())
+	lunes := hoy.AddDate(0, 0, -((int(hoy.Weekday()) + 6) % 7))
+	desde := lunes.AddDate(0, 0, -7)
+
+	semana, err := s.Indicadores(desde, lunes)
+	if err != nil {
+		log.Printf("❌ Error calculando el resumen semanal: %v", err)
+		return
+	}
+	anterior, err := s.Indicadores(desde.AddDate(0, 0, -7), desde)
+	if err != nil {
+		log.Printf("❌ Error calculando el resumen semanal: %v", err)
+		return
+	}
+
+	formato := s.config.Fechas("es")
+	lineas := []string{
+		fmt.Sprintf("Semana del %s al %s:", formato.DiaMes(desde), formato.DiaMes(lunes.AddDate(0, 0, -1))),
+		fmt.Sprintf("partidas %d (%s)", semana.Partidas, variacion(semana.Partidas, anterior.Partidas)),
+		fmt.Sprintf("clientes nuevos %d (%s)", semana.ClientesNuevos, variacion(semana.ClientesNuevos, anterior.ClientesNuevos)),
+		fmt.Sprintf("canjes %d (%s)", semana.Canjes, variacion(semana.Canjes, anterior.Canjes)),
+		fmt.Sprintf("tasa de canje %.1f%% (%+.1f pts)", semana.TasaCanje, semana.TasaCanje-anterior.TasaCanje),
+	}
+
+	if progreso, err := s.Progreso(""); err != nil {
+		log.Printf("⚠️  Error calculando el avance de las metas: %v", err)
+	} else if len(progreso.Indicadores) > 0 {
+		var metas []string
+		for _, indicador := range progreso.Indicadores {
+			estado := "en camino"
+			if !indicador.EnCamino {
+				estado = "por debajo"
+			}
+			metas = append(metas, fmt.Sprintf("%s %.0f%% de la meta (%s)",
+				strings.ReplaceAll(indicador.Indicador, "_", " "), indicador.Porcentaje, estado))
+		}
+		lineas = append(lineas, fmt.Sprintf("Metas de %s: %s", progreso.Mes, strings.Join(metas, ", ")))
+	}
+
+	log.Printf("🎯 Enviando resumen semanal de la promoción")
+	s.notificaciones.Notificar("info", "Resumen semanal", strings.Join(lineas, "\n"), "ver_metas")
+}
+
+// IniciarResumenSemanal manda el resumen todos los lunes a horaResumenSemanal en la zona
+// horaria del restaurante; con varias instancias lo manda una sola, la líder
+func (s *MetaService) IniciarResumenSemanal(coordinacion *CoordinacionService) {
+	go func() {
+		for {
+			proximo := s.proximoResumen(time.Now())
+			time.Sleep(time.Until(proximo))
+			if !coordinacion.TomarTurno("resumen_semanal", proximo) {
+				continue
+			}
+			s.EnviarResumenSemanal()
+		}
+	}()
+}
+
+// proximoResumen próximo lunes a horaResumenSemanal después de ahora
+func (s *MetaService) proximoResumen(ahora time.Time) time.Time {
+	local := ahora.In(s.config.GetLocation())
+	dias := (8 - int(local.Weekday())) % 7
+	proximo := time.Date(local.Year(), local.Month(), local.Day()+dias, horaResumenSemanal, 0, 0, 0, local.Location())
+	if !proximo.After(local) {
+		proximo = proximo.AddDate(0, 0, 7)
+	}
+	return proximo
+}
+
+// rangoMes retorna el inicio del mes (AAAA-MM) y el del siguiente
+func (s *MetaService) rangoMes(mes string) (time.Time, time.Time, error) {
+	desde, err := time.ParseInLocation("2006-01", mes, s.config.GetLocation())
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("mes inválido, usar el formato AAAA-MM")
+	}
+	return desde, desde.AddDate(0, 1, 0), nil
+}
+
+// variacion describe el cambio porcentual de un indicador contra el período anterior
+func variacion(actual, anterior int) string {
+	if anterior == 0 {
+		if actual == 0 {
+			return "sin cambios"
+		}
+		return "sin datos la semana anterior"
+	}
+	return fmt.Sprintf("%+.0f%% vs. semana anterior", float64(actual-anterior)/float64(anterior)*100)
+}
+
+// variacionIndicador diferencia, cambio porcentual (con un decimal) y tendencia de un
+// indicador contra el período anterior
+func variacionIndicador(actual, anterior float64) models.VariacionIndicador {
+	diferencia := math.Round((actual-anterior)*10) / 10
+	resultado := models.VariacionIndicador{
+		Actual:     actual,
+		Anterior:   anterior,
+		Diferencia: diferencia,
+		Tendencia:  "igual",
+	}
+	if anterior > 0 {
+		porcentaje := math.Round((actual-anterior)/anterior*1000) / 10
+		resultado.Porcentaje = &porcentaje
+	}
+	if diferencia > 0 {
+		resultado.Tendencia = "sube"
+	} else if diferencia < 0 {
+		resultado.Tendencia = "baja"
+	}
+	return resultado
+}
@@ -0,0 +1,130 @@
+package services
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"time"
+
+	"CheeseHouse/internal/config"
+	"CheeseHouse/internal/models"
+	"CheeseHouse/internal/repository"
+	"CheeseHouse/internal/version"
+)
+
+// NotificacionService registra alertas operativas en el centro de notificaciones
+// del panel admin y las replica en Slack y Telegram si están configurados
+type NotificacionService struct {
+	config           *config.Config
+	notificacionRepo repository.NotificacionRepository
+	client           *http.Client
+}
+
+// NewNotificacionService crea una nueva instancia del servicio de notificaciones
+func NewNotificacionService(cfg *config.Config, notificacionRepo repository.NotificacionRepository) *NotificacionService {
+	return &NotificacionService{
+		config:           cfg,
+		notificacionRepo: notificacionRepo,
+		client:           &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// Notificar guarda la alerta y la envía a los canales externos en segundo plano
+func (n *NotificacionService) Notificar(tipo, titulo, descripcion, accion string) {
+	notificacion := &models.Notificacion{
+		Tipo:        tipo,
+		Titulo:      titulo,
+		Descripcion: descripcion,
+		Accion:      accion,
+	}
+	if err := n.notificacionRepo.Crear(notificacion); err != nil {
+		log.Printf("❌ Error guardando notificación %q: %v", titulo, err)
+	}
+
+	texto := fmt.Sprintf("%s %s: %s", iconoNotificacion(tipo), titulo, descripcion)
+	if tipo == "error" {
+		// Para saber qué binario corre en el local sin tener que pedirlo
+		texto += fmt.Sprintf(" [versión %s]", version.Obtener())
+	}
+	go n.enviarExternos(texto)
+}
+
+// Listar obtiene las notificaciones recientes y la cantidad sin leer
+func (n *NotificacionService) Listar(soloNoLeidas bool, limite int) ([]*models.Notificacion, int, error) {
+	if limite <= 0 || limite > 200 {
+		limite = 50
+	}
+
+	notificaciones, err := n.notificacionRepo.Listar(soloNoLeidas, limite)
+	if err != nil {
+		return nil, 0, err
+	}
+	noLeidas, err := n.notificacionRepo.ContarNoLeidas()
+	if err != nil {
+		return nil, 0, err
+	}
+	return notificaciones, noLeidas, nil
+}
+
+// MarcarLeida marca una notificación como revisada por un usuario
+func (n *NotificacionService) MarcarLeida(id uint, usuarioID uint) error {
+	return n.notificacionRepo.MarcarLeida(id, usuarioID)
+}
+
+// enviarExternos replica la alerta en Slack y Telegram
+func (n *NotificacionService) enviarExternos(texto string) {
+	alertas := n.config.Alerts
+
+	if alertas.SlackWebhookURL != "" {
+		if err := n.postJSON(alertas.SlackWebhookURL, map[string]string{"text": texto}); err != nil {
+			log.Printf("⚠️  Error enviando alerta a Slack: %v", err)
+		}
+	}
+
+	if alertas.TelegramBotToken != "" && alertas.TelegramChatID != "" {
+		destino := fmt.Sprintf("https://api.telegram.org/bot%s/sendMessage", alertas.TelegramBotToken)
+		if err := n.postJSON(destino, map[string]string{"chat_id": alertas.TelegramChatID, "text": texto}); err != nil {
+			log.Printf("⚠️  Error enviando alerta a Telegram: %v", err)
+		}
+	}
+}
+
+// postJSON envía un payload JSON y falla si la respuesta no es 2xx.
+// Los errores no incluyen la URL porque puede contener el token del bot
+func (n *NotificacionService) postJSON(destino string, payload interface{}) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	resp, err := n.client.Post(destino, "application/json", bytes.NewReader(body))
+	if err != nil {
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			return urlErr.Err
+		}
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("respuesta %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// iconoNotificacion emoji según la severidad
+func iconoNotificacion(tipo string) string {
+	switch tipo {
+	case "error":
+		return "🚨"
+	case "warning":
+		return "⚠️"
+	default:
+		return "ℹ️"
+	}
+}
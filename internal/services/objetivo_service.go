@@ -0,0 +1,245 @@
+package services
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"CheeseHouse/internal/repository"
+)
+
+// objetivoTokenTTL tiempo máximo entre pedir el objetivo y enviar el resultado
+const objetivoTokenTTL = 15 * time.Minute
+
+// ErrTokenObjetivoInvalido el token del tiempo objetivo falta, está adulterado, vencido o ya se usó
+var ErrTokenObjetivoInvalido = errors.New("tiempo objetivo inválido o vencido, volvé a jugar")
+
+// ErrSesionInvalida la sesión de juego falta, está adulterada, vencida, ya se usó o no se detuvo
+var ErrSesionInvalida = errors.New("partida inválida o vencida, volvé a jugar")
+
+// sesionEnviada valor de una sesión cuyo resultado ya se envió
+const sesionEnviada = "enviada"
+
+// ObjetivoService firma los tiempos objetivo generados por el servidor para que el
+// jugador no pueda elegir su propio objetivo, y las sesiones de juego con las que el
+// servidor mide cuánto duró la partida. Cada token se puede usar una sola vez; los usos
+// se anotan en el estado compartido para que valga aunque cada paso lo atienda otra instancia
+type ObjetivoService struct {
+	secret []byte
+	estado repository.EstadoCompartidoRepository
+}
+
+// NewObjetivoService crea una nueva instancia del servicio de objetivos firmados
+func NewObjetivoService(secret string, estado repository.EstadoCompartidoRepository) *ObjetivoService {
+	return &ObjetivoService{
+		secret: []byte(secret),
+		estado: estado,
+	}
+}
+
+// Emitir genera el token firmado para un tiempo objetivo
+func (o *ObjetivoService) Emitir(objetivo float64) (string, error) {
+	nonce, err := nuevoNonce()
+	if err != nil {
+		return "", fmt.Errorf("error generando token de objetivo: %w", err)
+	}
+
+	payload := fmt.Sprintf("%s|%d|%s",
+		strconv.FormatFloat(objetivo, 'f', 1, 64), time.Now().Unix(), nonce)
+
+	return o.sellar("objetivo", payload), nil
+}
+
+// Consumir valida el token, lo marca como usado y retorna el objetivo firmado
+func (o *ObjetivoService) Consumir(token string) (float64, error) {
+	partes, ok := o.abrir("objetivo", token)
+	if !ok {
+		return 0, ErrTokenObjetivoInvalido
+	}
+
+	objetivo, err := strconv.ParseFloat(partes[0], 64)
+	if err != nil {
+		return 0, ErrTokenObjetivoInvalido
+	}
+	emitido, err := strconv.ParseInt(partes[1], 10, 64)
+	if err != nil {
+		return 0, ErrTokenObjetivoInvalido
+	}
+
+	vence := time.Unix(emitido, 0).Add(objetivoTokenTTL)
+	if time.Now().After(vence) {
+		return 0, ErrTokenObjetivoInvalido
+	}
+
+	nuevo, err := o.estado.Crear("objetivo:"+partes[2], "", vence)
+	if err != nil {
+		return 0, fmt.Errorf("error registrando el uso del objetivo: %w", err)
+	}
+	if !nuevo {
+		return 0, ErrTokenObjetivoInvalido
+	}
+
+	return objetivo, nil
+}
+
+// IniciarSesion consume el token del objetivo y abre la sesión de juego: el token
+// retornado lleva el objetivo y el momento en que el servidor registró el inicio
+func (o *ObjetivoService) IniciarSesion(tokenObjetivo string) (string, float64, time.Time, error) {
+	objetivo, err := o.Consumir(tokenObjetivo)
+	if err != nil {
+		return "", 0, time.Time{}, err
+	}
+
+	nonce, err := nuevoNonce()
+	if err != nil {
+		return "", 0, time.Time{}, fmt.Errorf("error generando sesión de juego: %w", err)
+	}
+
+	inicio := time.Now()
+	payload := fmt.Sprintf("%s|%d|%s",
+		strconv.FormatFloat(objetivo, 'f', 1, 64), inicio.UnixMilli(), nonce)
+
+	return o.sellar("sesion", payload), objetivo, inicio, nil
+}
+
+// DetenerSesion registra el momento en que el jugador detuvo el cronómetro y retorna
+// los segundos medidos por el servidor. Solo cuenta la primera detención
+func (o *ObjetivoService) DetenerSesion(token string) (float64, error) {
+	partes, inicio, ok := o.abrirSesion(token)
+	if !ok {
+		return 0, ErrSesionInvalida
+	}
+
+	vence := inicio.Add(objetivoTokenTTL)
+	if time.Now().After(vence) {
+		return 0, ErrSesionInvalida
+	}
+
+	clave := "sesion:" + partes[2]
+	fin := time.Now()
+	nueva, err := o.estado.Crear(clave, strconv.FormatInt(fin.UnixMilli(), 10), vence)
+	if err != nil {
+		log.Printf("⚠️  Error registrando la detención de la partida: %v", err)
+		return 0, ErrSesionInvalida
+	}
+	if !nueva {
+		// Ya se había detenido: vale la primera detención, salvo que la partida ya se envió
+		registrada, err := o.estado.Obtener(clave)
+		if err != nil || registrada == nil || registrada.Valor == sesionEnviada {
+			return 0, ErrSesionInvalida
+		}
+		milis, err := strconv.ParseInt(registrada.Valor, 10, 64)
+		if err != nil {
+			return 0, ErrSesionInvalida
+		}
+		fin = time.UnixMilli(milis)
+	}
+	return fin.Sub(inicio).Seconds(), nil
+}
+
+// ConsumirSesion valida el token de una sesión detenida, la cierra y retorna el
+// objetivo firmado y los segundos que midió el servidor entre el inicio y la detención
+func (o *ObjetivoService) ConsumirSesion(token string) (float64, float64, error) {
+	partes, inicio, ok := o.abrirSesion(token)
+	if !ok {
+		return 0, 0, ErrSesionInvalida
+	}
+
+	objetivo, err := strconv.ParseFloat(partes[0], 64)
+	if err != nil {
+		return 0, 0, ErrSesionInvalida
+	}
+
+	clave := "sesion:" + partes[2]
+	registrada, err := o.estado.Obtener(clave)
+	if err != nil {
+		log.Printf("⚠️  Error leyendo la sesión de juego: %v", err)
+		return 0, 0, ErrSesionInvalida
+	}
+	if registrada == nil || registrada.Valor == sesionEnviada {
+		return 0, 0, ErrSesionInvalida
+	}
+	milis, err := strconv.ParseInt(registrada.Valor, 10, 64)
+	if err != nil {
+		return 0, 0, ErrSesionInvalida
+	}
+
+	// Se marca como enviada en lugar de borrarla, así no se puede volver a detener y enviar
+	cerrada, err := o.estado.Reemplazar(clave, registrada.Version, sesionEnviada, registrada.VenceEn)
+	if err != nil {
+		log.Printf("⚠️  Error cerrando la sesión de juego: %v", err)
+		return 0, 0, ErrSesionInvalida
+	}
+	if !cerrada {
+		return 0, 0, ErrSesionInvalida
+	}
+
+	return objetivo, time.UnixMilli(milis).Sub(inicio).Seconds(), nil
+}
+
+// abrirSesion verifica un token de sesión y retorna sus campos y el inicio registrado
+func (o *ObjetivoService) abrirSesion(token string) ([]string, time.Time, bool) {
+	partes, ok := o.abrir("sesion", token)
+	if !ok {
+		return nil, time.Time{}, false
+	}
+	milis, err := strconv.ParseInt(partes[1], 10, 64)
+	if err != nil {
+		return nil, time.Time{}, false
+	}
+	return partes, time.UnixMilli(milis), true
+}
+
+// sellar codifica el payload y le agrega la firma del tipo de token
+func (o *ObjetivoService) sellar(tipo, payload string) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(payload)) + "." + o.firmar(tipo, payload)
+}
+
+// abrir verifica la firma de un token del tipo indicado y retorna sus tres campos
+func (o *ObjetivoService) abrir(tipo, token string) ([]string, bool) {
+	codificado, firma, ok := strings.Cut(token, ".")
+	if !ok {
+		return nil, false
+	}
+
+	bytesPayload, err := base64.RawURLEncoding.DecodeString(codificado)
+	if err != nil {
+		return nil, false
+	}
+	payload := string(bytesPayload)
+
+	if !hmac.Equal([]byte(firma), []byte(o.firmar(tipo, payload))) {
+		return nil, false
+	}
+
+	partes := strings.Split(payload, "|")
+	if len(partes) != 3 {
+		return nil, false
+	}
+	return partes, true
+}
+
+// firmar calcula el HMAC-SHA256 del payload. El tipo evita que un token de objetivo
+// sirva como sesión y viceversa
+func (o *ObjetivoService) firmar(tipo, payload string) string {
+	mac := hmac.New(sha256.New, o.secret)
+	mac.Write([]byte(tipo + ":" + payload))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// nuevoNonce genera un identificador aleatorio de un solo uso
+func nuevoNonce() (string, error) {
+	nonce := make([]byte, 12)
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(nonce), nil
+}
@@ -0,0 +1,294 @@
+package services
+
+import (
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"CheeseHouse/internal/models"
+)
+
+// separadorPedido token que reemplaza comas, saltos de línea y similares
+const separadorPedido = "|"
+
+// cantidadesEnLetras números escritos que los clientes usan al pedir
+var cantidadesEnLetras = map[string]int{
+	"un": 1, "una": 1, "uno": 1, "dos": 2, "tres": 3, "cuatro": 4, "cinco": 5,
+	"seis": 6, "siete": 7, "ocho": 8, "nueve": 9, "diez": 10, "doce": 12,
+	"one": 1, "two": 2, "three": 3, "four": 4, "five": 5,
+}
+
+// conectoresPedido palabras que separan items cuando no forman parte de un nombre del menú
+var conectoresPedido = map[string]bool{"y": true, "e": true, "and": true, "mas": true}
+
+// palabrasDeRelleno se ignoran al decidir si una parte del mensaje quedó sin interpretar
+var palabrasDeRelleno = map[string]bool{
+	"hola": true, "buenas": true, "buen": true, "dia": true, "tardes": true, "noches": true,
+	"quiero": true, "queria": true, "quisiera": true, "pedir": true, "pedido": true, "me": true,
+	"das": true, "manda": true, "mandame": true, "traeme": true, "por": true, "favor": true,
+	"porfa": true, "gracias": true, "de": true, "el": true, "la": true, "los": true, "las": true,
+	"para": true, "a": true, "al": true, "con": true, "otro": true, "otra": true, "tambien": true,
+	"please": true, "i": true, "want": true, "would": true, "like": true,
+}
+
+// sinAcentos quita tildes para comparar sin importar cómo escribió el cliente
+var sinAcentos = strings.NewReplacer("á", "a", "é", "e", "í", "i", "ó", "o", "ú", "u", "ü", "u", "ñ", "n")
+
+// tokenPedido palabra del mensaje en su forma original y normalizada
+type tokenPedido struct {
+	original  string
+	normal    string
+	consumido bool
+}
+
+// frasePedido nombre o alias de un item del menú ya separado en palabras
+type frasePedido struct {
+	item     *models.ItemMenu
+	palabras []string
+}
+
+// interpretarPedido busca los nombres y alias del menú dentro del texto y arma una
+// propuesta de pedido con cantidades ("2 cheese burgers y una coca")
+func interpretarPedido(texto string, menu []*models.ItemMenu) *models.PropuestaPedido {
+	propuesta := &models.PropuestaPedido{
+		Mensaje:        texto,
+		Items:          []*models.ItemPropuesto{},
+		SinInterpretar: []string{},
+	}
+
+	tokens := tokenizarPedido(texto)
+	frases := frasesDelMenu(menu)
+	porItem := make(map[uint]*models.ItemPropuesto)
+
+	for i := 0; i < len(tokens); {
+		frase, ok := buscarFrase(tokens, i, frases)
+		if !ok {
+			i++
+			continue
+		}
+
+		fin := i + len(frase.palabras)
+		partes := make([]string, 0, len(frase.palabras)+2)
+		for j := i; j < fin; j++ {
+			tokens[j].consumido = true
+			partes = append(partes, tokens[j].original)
+		}
+
+		cantidad, antes, despues := cantidadAlrededor(tokens, i, fin)
+		if antes != "" {
+			partes = append([]string{antes}, partes...)
+		}
+		if despues != "" {
+			partes = append(partes, despues)
+		}
+
+		if propuesto, existe := porItem[frase.item.ID]; existe {
+			propuesto.Cantidad += cantidad
+			propuesto.TextoOriginal += ", " + strings.Join(partes, " ")
+		} else {
+			propuesto = &models.ItemPropuesto{
+				ItemMenuID:     frase.item.ID,
+				Nombre:         frase.item.Nombre,
+				Cantidad:       cantidad,
+				PrecioUnitario: frase.item.Precio,
+				TextoOriginal:  strings.Join(partes, " "),
+			}
+			porItem[frase.item.ID] = propuesto
+			propuesta.Items = append(propuesta.Items, propuesto)
+		}
+		i = fin
+	}
+
+	for _, propuesto := range propuesta.Items {
+		propuesto.Subtotal = redondearCentavos(float64(propuesto.Cantidad) * propuesto.PrecioUnitario)
+		propuesta.Total += propuesto.Subtotal
+	}
+	propuesta.Total = redondearCentavos(propuesta.Total)
+	propuesta.SinInterpretar = partesSinInterpretar(tokens)
+
+	return propuesta
+}
+
+// tokenizarPedido separa el texto en palabras; comas, saltos de línea, "+" y ";" quedan como separadores
+func tokenizarPedido(texto string) []*tokenPedido {
+	var tokens []*tokenPedido
+	var actual strings.Builder
+
+	cerrar := func() {
+		if actual.Len() == 0 {
+			return
+		}
+		original := actual.String()
+		tokens = append(tokens, &tokenPedido{original: original, normal: sinAcentos.Replace(original)})
+		actual.Reset()
+	}
+
+	for _, r := range strings.ToLower(texto) {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			actual.WriteRune(r)
+		case strings.ContainsRune(",;+\n/", r):
+			cerrar()
+			tokens = append(tokens, &tokenPedido{original: separadorPedido, normal: separadorPedido})
+		default:
+			cerrar()
+		}
+	}
+	cerrar()
+
+	return tokens
+}
+
+// frasesDelMenu arma las frases a buscar, las más largas primero para que
+// "cheese burger doble" gane sobre "cheese burger"
+func frasesDelMenu(menu []*models.ItemMenu) []frasePedido {
+	var frases []frasePedido
+	for _, item := range menu {
+		nombres := append([]string{item.Nombre}, strings.Split(item.Alias, ",")...)
+		for _, nombre := range nombres {
+			var palabras []string
+			for _, token := range tokenizarPedido(nombre) {
+				if token.normal != separadorPedido {
+					palabras = append(palabras, token.normal)
+				}
+			}
+			if len(palabras) > 0 {
+				frases = append(frases, frasePedido{item: item, palabras: palabras})
+			}
+		}
+	}
+
+	sort.SliceStable(frases, func(i, j int) bool {
+		return len(frases[i].palabras) > len(frases[j].palabras)
+	})
+	return frases
+}
+
+// buscarFrase busca una frase del menú que empiece en la posición i
+func buscarFrase(tokens []*tokenPedido, i int, frases []frasePedido) (frasePedido, bool) {
+	for _, frase := range frases {
+		if i+len(frase.palabras) > len(tokens) {
+			continue
+		}
+		coincide := true
+		for j, palabra := range frase.palabras {
+			token := tokens[i+j]
+			if token.consumido || !mismaPalabra(token.normal, palabra) {
+				coincide = false
+				break
+			}
+		}
+		if coincide {
+			return frase, true
+		}
+	}
+	return frasePedido{}, false
+}
+
+// mismaPalabra compara ignorando plurales ("burgers" = "burger", "panes" = "pan")
+func mismaPalabra(a, b string) bool {
+	return a == b || singular(a) == singular(b)
+}
+
+// singular quita la terminación de plural más común
+func singular(palabra string) string {
+	switch {
+	case len(palabra) > 4 && strings.HasSuffix(palabra, "es"):
+		return strings.TrimSuffix(palabra, "es")
+	case len(palabra) > 3 && strings.HasSuffix(palabra, "s"):
+		return strings.TrimSuffix(palabra, "s")
+	}
+	return palabra
+}
+
+// cantidadAlrededor busca la cantidad antes ("2", "2x", "dos", "2 de") o después ("x2", "x 2")
+// del item; por defecto es 1. Retorna también el texto usado para mostrarlo en la propuesta
+func cantidadAlrededor(tokens []*tokenPedido, inicio, fin int) (int, string, string) {
+	j := inicio - 1
+	if j >= 0 && tokens[j].normal == "de" && !tokens[j].consumido {
+		j--
+	}
+	if j >= 0 && !tokens[j].consumido {
+		if cantidad, ok := leerCantidad(tokens[j].normal); ok {
+			tokens[j].consumido = true
+			texto := tokens[j].original
+			if j < inicio-1 {
+				texto += " " + tokens[inicio-1].original
+				tokens[inicio-1].consumido = true
+			}
+			return cantidad, texto, ""
+		}
+	}
+
+	if fin < len(tokens) && !tokens[fin].consumido {
+		if strings.HasPrefix(tokens[fin].normal, "x") {
+			if cantidad, err := strconv.Atoi(strings.TrimPrefix(tokens[fin].normal, "x")); err == nil && cantidad > 0 {
+				tokens[fin].consumido = true
+				return cantidad, "", tokens[fin].original
+			}
+		}
+		if tokens[fin].normal == "x" && fin+1 < len(tokens) {
+			if cantidad, err := strconv.Atoi(tokens[fin+1].normal); err == nil && cantidad > 0 {
+				tokens[fin].consumido, tokens[fin+1].consumido = true, true
+				return cantidad, "", "x " + tokens[fin+1].original
+			}
+		}
+	}
+
+	return 1, "", ""
+}
+
+// leerCantidad interpreta "2", "2x" o un número en letras
+func leerCantidad(palabra string) (int, bool) {
+	if cantidad, ok := cantidadesEnLetras[palabra]; ok {
+		return cantidad, true
+	}
+	cantidad, err := strconv.Atoi(strings.TrimSuffix(palabra, "x"))
+	if err != nil || cantidad <= 0 || cantidad > 100 {
+		return 0, false
+	}
+	return cantidad, true
+}
+
+// partesSinInterpretar junta las partes del mensaje (separadas por comas o conectores)
+// que no coincidieron con ningún item y tienen algo más que saludos o relleno
+func partesSinInterpretar(tokens []*tokenPedido) []string {
+	partes := []string{}
+	var segmento []*tokenPedido
+
+	cerrar := func() {
+		defer func() { segmento = nil }()
+		relevante := false
+		palabras := make([]string, 0, len(segmento))
+		for _, token := range segmento {
+			if token.consumido {
+				return
+			}
+			palabras = append(palabras, token.original)
+			if !palabrasDeRelleno[token.normal] {
+				relevante = true
+			}
+		}
+		if relevante {
+			partes = append(partes, strings.Join(palabras, " "))
+		}
+	}
+
+	for _, token := range tokens {
+		if token.normal == separadorPedido || (!token.consumido && conectoresPedido[token.normal]) {
+			cerrar()
+			continue
+		}
+		segmento = append(segmento, token)
+	}
+	cerrar()
+
+	return partes
+}
+
+// redondearCentavos redondea un importe a dos decimales
+func redondearCentavos(valor float64) float64 {
+	return math.Round(valor*100) / 100
+}
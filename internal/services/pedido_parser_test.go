@@ -0,0 +1,175 @@
+package services
+
+import (
+	"reflect"
+	"testing"
+
+	"CheeseHouse/internal/models"
+)
+
+// menuDePrueba items con nombres que se pisan ("cheese burger" y "cheese burger doble") y alias
+func menuDePrueba() []*models.ItemMenu {
+	return []*models.ItemMenu{
+		{ID: 1, Nombre: "Cheese Burger", Alias: "burger,hamburguesa", Precio: 1500},
+		{ID: 2, Nombre: "Cheese Burger Doble", Alias: "doble", Precio: 2200},
+		{ID: 3, Nombre: "Coca Cola", Alias: "coca", Precio: 800.5},
+		{ID: 4, Nombre: "Papas Fritas", Alias: "papas", Precio: 1000},
+	}
+}
+
+func TestInterpretarPedido(t *testing.T) {
+	casos := []struct {
+		nombre         string
+		texto          string
+		items          []models.ItemPropuesto
+		total          float64
+		sinInterpretar []string
+	}{
+		{
+			nombre: "cantidades en números y letras con plural",
+			texto:  "2 cheese burgers y una coca",
+			items: []models.ItemPropuesto{
+				{ItemMenuID: 1, Nombre: "Cheese Burger", Cantidad: 2, PrecioUnitario: 1500, Subtotal: 3000, TextoOriginal: "2 cheese burgers"},
+				{ItemMenuID: 3, Nombre: "Coca Cola", Cantidad: 1, PrecioUnitario: 800.5, Subtotal: 800.5, TextoOriginal: "una coca"},
+			},
+			total:          3800.5,
+			sinInterpretar: []string{},
+		},
+		{
+			nombre: "gana el nombre más largo y cantidad después con x",
+			texto:  "cheese burger doble x2, papas",
+			items: []models.ItemPropuesto{
+				{ItemMenuID: 2, Nombre: "Cheese Burger Doble", Cantidad: 2, PrecioUnitario: 2200, Subtotal: 4400, TextoOriginal: "cheese burger doble x2"},
+				{ItemMenuID: 4, Nombre: "Papas Fritas", Cantidad: 1, PrecioUnitario: 1000, Subtotal: 1000, TextoOriginal: "papas"},
+			},
+			total:          5400,
+			sinInterpretar: []string{},
+		},
+		{
+			nombre: "cantidad con de",
+			texto:  "dos de papas",
+			items: []models.ItemPropuesto{
+				{ItemMenuID: 4, Nombre: "Papas Fritas", Cantidad: 2, PrecioUnitario: 1000, Subtotal: 2000, TextoOriginal: "dos de papas"},
+			},
+			total:          2000,
+			sinInterpretar: []string{},
+		},
+		{
+			nombre: "cantidad con x separada",
+			texto:  "papas x 3",
+			items: []models.ItemPropuesto{
+				{ItemMenuID: 4, Nombre: "Papas Fritas", Cantidad: 3, PrecioUnitario: 1000, Subtotal: 3000, TextoOriginal: "papas x 3"},
+			},
+			total:          3000,
+			sinInterpretar: []string{},
+		},
+		{
+			nombre: "alias con tilde",
+			texto:  "Una HAMBURGUÉSA",
+			items: []models.ItemPropuesto{
+				{ItemMenuID: 1, Nombre: "Cheese Burger", Cantidad: 1, PrecioUnitario: 1500, Subtotal: 1500, TextoOriginal: "una hamburguésa"},
+			},
+			total:          1500,
+			sinInterpretar: []string{},
+		},
+		{
+			nombre: "mismo item repetido se suma y lo desconocido se informa",
+			texto:  "hola, quiero 1 burger y 1 burger mas por favor, y un flan",
+			items: []models.ItemPropuesto{
+				{ItemMenuID: 1, Nombre: "Cheese Burger", Cantidad: 2, PrecioUnitario: 1500, Subtotal: 3000, TextoOriginal: "1 burger, 1 burger"},
+			},
+			total:          3000,
+			sinInterpretar: []string{"un flan"},
+		},
+		{
+			nombre: "cantidad fuera de rango queda en 1",
+			texto:  "200 papas",
+			items: []models.ItemPropuesto{
+				{ItemMenuID: 4, Nombre: "Papas Fritas", Cantidad: 1, PrecioUnitario: 1000, Subtotal: 1000, TextoOriginal: "papas"},
+			},
+			total:          1000,
+			sinInterpretar: []string{},
+		},
+		{
+			nombre:         "solo saludos",
+			texto:          "buenas tardes, gracias!",
+			items:          []models.ItemPropuesto{},
+			total:          0,
+			sinInterpretar: []string{},
+		},
+		{
+			nombre:         "nada del menú",
+			texto:          "una pizza grande",
+			items:          []models.ItemPropuesto{},
+			total:          0,
+			sinInterpretar: []string{"una pizza grande"},
+		},
+	}
+
+	for _, caso := range casos {
+		t.Run(caso.nombre, func(t *testing.T) {
+			propuesta := interpretarPedido(caso.texto, menuDePrueba())
+
+			items := []models.ItemPropuesto{}
+			for _, item := range propuesta.Items {
+				items = append(items, *item)
+			}
+			if !reflect.DeepEqual(items, caso.items) {
+				t.Errorf("items = %+v, se esperaba %+v", items, caso.items)
+			}
+			if propuesta.Total != caso.total {
+				t.Errorf("total = %v, se esperaba %v", propuesta.Total, caso.total)
+			}
+			if !reflect.DeepEqual(propuesta.SinInterpretar, caso.sinInterpretar) {
+				t.Errorf("sin interpretar = %q, se esperaba %q", propuesta.SinInterpretar, caso.sinInterpretar)
+			}
+			if propuesta.Mensaje != caso.texto {
+				t.Errorf("mensaje = %q, se esperaba %q", propuesta.Mensaje, caso.texto)
+			}
+		})
+	}
+}
+
+func TestLeerCantidad(t *testing.T) {
+	casos := []struct {
+		palabra  string
+		cantidad int
+		ok       bool
+	}{
+		{"3", 3, true},
+		{"2x", 2, true},
+		{"doce", 12, true},
+		{"two", 2, true},
+		{"100", 100, true},
+		{"101", 0, false},
+		{"0", 0, false},
+		{"x", 0, false},
+		{"papas", 0, false},
+	}
+
+	for _, caso := range casos {
+		cantidad, ok := leerCantidad(caso.palabra)
+		if cantidad != caso.cantidad || ok != caso.ok {
+			t.Errorf("leerCantidad(%q) = (%d, %v), se esperaba (%d, %v)", caso.palabra, cantidad, ok, caso.cantidad, caso.ok)
+		}
+	}
+}
+
+func TestSingular(t *testing.T) {
+	casos := []struct {
+		palabra  string
+		esperado string
+	}{
+		{"burgers", "burger"},
+		{"panes", "pan"},
+		{"papas", "papa"},
+		{"res", "res"},
+		{"coca", "coca"},
+	}
+
+	for _, caso := range casos {
+		if obtenido := singular(caso.palabra); obtenido != caso.esperado {
+			t.Errorf("singular(%q) = %q, se esperaba %q", caso.palabra, obtenido, caso.esperado)
+		}
+	}
+}
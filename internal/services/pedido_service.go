@@ -0,0 +1,554 @@
+package services
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"CheeseHouse/internal/config"
+	"CheeseHouse/internal/models"
+	"CheeseHouse/internal/repository"
+)
+
+// ventanaPromediosPreparacion historial usado para estimar la espera
+const ventanaPromediosPreparacion = 14 * 24 * time.Hour
+
+// minimoPedidosPromedio pedidos completados necesarios para confiar en un promedio
+const minimoPedidosPromedio = 5
+
+// transicionesPedido estados a los que puede pasar un pedido desde cada estado abierto:
+// pendiente → procesando → completado, y se puede cancelar mientras no esté completado
+var transicionesPedido = map[string][]string{
+	"pendiente":  {"procesando", "cancelado"},
+	"procesando": {"completado", "cancelado"},
+}
+
+// PedidoService administra el menú y los pedidos recibidos por WhatsApp: interpreta
+// el texto libre del cliente y guarda el pedido una vez que el empleado lo confirma
+type PedidoService struct {
+	config         *config.Config
+	pedidoRepo     repository.PedidoRepository
+	menuRepo       repository.MenuRepository
+	clienteRepo    *repository.ClienteRepository
+	usuarioRepo    repository.UsuarioRepository
+	conversaciones *ConversacionService
+	whatsapp       *WhatsAppService
+
+	// Pantallas de cocina conectadas por WebSocket
+	cocinaMu     sync.Mutex
+	suscriptores map[chan models.EventoCocina]struct{}
+
+	// Espera publicada en la web, recalculada como mucho una vez por minuto
+	esperaMu      sync.Mutex
+	esperaPublica *models.EstimacionEspera
+}
+
+// NewPedidoService crea una nueva instancia del servicio de pedidos
+func NewPedidoService(
+	cfg *config.Config,
+	pedidoRepo repository.PedidoRepository,
+	menuRepo repository.MenuRepository,
+	clienteRepo *repository.ClienteRepository,
+	usuarioRepo repository.UsuarioRepository,
+	conversaciones *ConversacionService,
+	whatsapp *WhatsAppService,
+) *PedidoService {
+	return &PedidoService{
+		config:         cfg,
+		pedidoRepo:     pedidoRepo,
+		menuRepo:       menuRepo,
+		clienteRepo:    clienteRepo,
+		usuarioRepo:    usuarioRepo,
+		conversaciones: conversaciones,
+		whatsapp:       whatsapp,
+		suscriptores:   make(map[chan models.EventoCocina]struct{}),
+	}
+}
+
+// ListarMenu obtiene los items del menú
+func (s *PedidoService) ListarMenu(soloActivos bool) ([]*models.ItemMenu, error) {
+	return s.menuRepo.Listar(soloActivos)
+}
+
+// CrearItemMenu agrega un item al menú
+func (s *PedidoService) CrearItemMenu(req models.GuardarItemMenuRequest) (*models.ItemMenu, error) {
+	item := &models.ItemMenu{Activo: true}
+	aplicarItemMenu(item, req)
+
+	if err := s.menuRepo.Crear(item); err != nil {
+		return nil, err
+	}
+	return item, nil
+}
+
+// ActualizarItemMenu modifica un item del menú
+func (s *PedidoService) ActualizarItemMenu(id uint, req models.GuardarItemMenuRequest) (*models.ItemMenu, error) {
+	item, err := s.menuRepo.BuscarPorID(id)
+	if err != nil {
+		return nil, err
+	}
+	aplicarItemMenu(item, req)
+
+	if err := s.menuRepo.Actualizar(item); err != nil {
+		return nil, err
+	}
+	return item, nil
+}
+
+// EliminarItemMenu borra un item del menú
+func (s *PedidoService) EliminarItemMenu(id uint) error {
+	return s.menuRepo.Eliminar(id)
+}
+
+// Interpretar propone un pedido a partir del texto recibido o, con teléfono,
+// de los mensajes del cliente que todavía no se respondieron
+func (s *PedidoService) Interpretar(req models.InterpretarPedidoRequest) (*models.PropuestaPedido, error) {
+	texto := strings.TrimSpace(req.Mensaje)
+	if texto == "" && req.Telefono != "" {
+		pendiente, err := s.conversaciones.TextoPendiente(req.Telefono)
+		if err != nil {
+			return nil, err
+		}
+		texto = pendiente
+	}
+	if texto == "" {
+		return nil, fmt.Errorf("no hay texto para interpretar")
+	}
+
+	menu, err := s.menuRepo.Listar(true)
+	if err != nil {
+		return nil, err
+	}
+	if len(menu) == 0 {
+		return nil, fmt.Errorf("el menú está vacío, cargá los items antes de interpretar pedidos")
+	}
+
+	return interpretarPedido(texto, menu), nil
+}
+
+// Confirmar guarda el pedido revisado por el empleado con los precios actuales del menú
+func (s *PedidoService) Confirmar(req models.ConfirmarPedidoRequest, usuarioID uint) (*models.Pedido, error) {
+	pedido := &models.Pedido{
+		Telefono:    s.whatsapp.NormalizarTelefono(req.Telefono),
+		Mensaje:     strings.TrimSpace(req.Mensaje),
+		Estado:      "pendiente",
+		Notas:       strings.TrimSpace(req.Notas),
+		AtendidoPor: &usuarioID,
+	}
+	cliente, err := s.clienteRepo.BuscarPorTelefono(pedido.Telefono)
+	if err == nil {
+		pedido.ClienteID = &cliente.ID
+	} else {
+		cliente = nil
+	}
+
+	total := 0.0
+	unidades := 0
+	for _, linea := range req.Items {
+		item, err := s.menuRepo.BuscarPorID(linea.ItemMenuID)
+		if err != nil {
+			return nil, err
+		}
+		if !item.Activo {
+			return nil, fmt.Errorf("%q no está disponible en el menú", item.Nombre)
+		}
+
+		itemID := item.ID
+		pedido.Items = append(pedido.Items, models.PedidoItem{
+			ItemMenuID:     &itemID,
+			Nombre:         item.Nombre,
+			Cantidad:       linea.Cantidad,
+			PrecioUnitario: item.Precio,
+			Notas:          strings.TrimSpace(linea.Notas),
+		})
+		total += float64(linea.Cantidad) * item.Precio
+		unidades += linea.Cantidad
+	}
+	total = redondearCentavos(total)
+	pedido.Total = &total
+
+	// Sin hora indicada por el empleado se promete la espera estimada
+	minutosEspera := s.config.OrderPrepMinutes
+	if req.PrometidoPara != nil {
+		minutosEspera = int(math.Ceil(time.Until(*req.PrometidoPara).Minutes()))
+		pedido.PrometidoPara = req.PrometidoPara
+	} else {
+		if estimacion, err := s.EstimarEspera(unidades); err == nil {
+			minutosEspera = estimacion.MinutosEstimados
+		} else {
+			log.Printf("⚠️  No se pudo estimar la espera, se usan %d minutos: %v", minutosEspera, err)
+		}
+		prometido := time.Now().Add(time.Duration(minutosEspera) * time.Minute)
+		pedido.PrometidoPara = &prometido
+	}
+
+	if err := s.pedidoRepo.Crear(pedido); err != nil {
+		return nil, err
+	}
+	s.publicarCocina("confirmado", pedido)
+
+	if s.config.NotificaEstadoPedido("confirmado") {
+		if err := s.whatsapp.EnviarConfirmacionPedido(pedido, cliente, minutosEspera); err != nil {
+			log.Printf("⚠️  No se pudo enviar la confirmación del pedido #%d: %v", pedido.ID, err)
+		}
+	}
+
+	log.Printf("🧾 Pedido #%d confirmado para %s (%d items, total %.2f)", pedido.ID, pedido.Telefono, len(pedido.Items), total)
+	return pedido, nil
+}
+
+// CambiarEstado mueve el pedido de estado y, si el estado está configurado en
+// ORDER_STATUS_NOTIFICATIONS, avisa al cliente por WhatsApp. Retorna si se notificó
+func (s *PedidoService) CambiarEstado(id uint, req models.CambiarEstadoPedidoRequest, usuarioID uint) (*models.Pedido, bool, error) {
+	pedido, err := s.pedidoRepo.BuscarPorID(id)
+	if err != nil {
+		return nil, false, err
+	}
+	if pedido.Estado == req.Estado {
+		return nil, false, fmt.Errorf("el pedido ya está %s", req.Estado)
+	}
+	if pedido.Estado == "completado" || pedido.Estado == "cancelado" {
+		return nil, false, fmt.Errorf("el pedido está %s y no se puede modificar", pedido.Estado)
+	}
+	if !slices.Contains(transicionesPedido[pedido.Estado], req.Estado) {
+		return nil, false, fmt.Errorf("un pedido %s solo puede pasar a %s", pedido.Estado, strings.Join(transicionesPedido[pedido.Estado], " o "))
+	}
+
+	pedido.Estado = req.Estado
+	if pedido.AtendidoPor == nil {
+		// Sin asignar, queda a cargo de quien lo mueve
+		pedido.AtendidoPor = &usuarioID
+	}
+	if pedido.Estado == "completado" {
+		ahora := time.Now()
+		pedido.CompletadoAt = &ahora
+	}
+	if err := s.pedidoRepo.ActualizarEstado(pedido); err != nil {
+		return nil, false, err
+	}
+	log.Printf("🧾 Pedido #%d pasó a %s (usuario %d)", pedido.ID, pedido.Estado, usuarioID)
+	s.publicarCocina("actualizado", pedido)
+
+	if (req.Notificar != nil && !*req.Notificar) || !s.config.NotificaEstadoPedido(pedido.Estado) {
+		return pedido, false, nil
+	}
+
+	cliente, _ := s.clienteRepo.BuscarPorTelefono(pedido.Telefono)
+	if err := s.whatsapp.EnviarEstadoPedido(pedido, cliente); err != nil {
+		// El cambio de estado ya quedó guardado; el aviso es secundario
+		log.Printf("⚠️  No se pudo avisar al cliente del pedido #%d: %v", pedido.ID, err)
+		return pedido, false, nil
+	}
+	return pedido, true, nil
+}
+
+// Listar obtiene los pedidos que coinciden con los filtros
+func (s *PedidoService) Listar(filtros map[string]interface{}) ([]*models.Pedido, error) {
+	pedidos, err := s.pedidoRepo.ListarConFiltros(filtros)
+	if err != nil {
+		return nil, err
+	}
+
+	ahora := time.Now()
+	for _, pedido := range pedidos {
+		pedido.Atrasado = esPedidoAbierto(pedido) && pedido.PrometidoPara != nil && ahora.After(*pedido.PrometidoPara)
+	}
+	return pedidos, nil
+}
+
+// Obtener busca un pedido con sus items y el empleado que lo atiende
+func (s *PedidoService) Obtener(id uint) (*models.Pedido, error) {
+	pedido, err := s.pedidoRepo.BuscarPorID(id)
+	if err != nil {
+		return nil, err
+	}
+	pedido.Atrasado = esPedidoAbierto(pedido) && pedido.PrometidoPara != nil && time.Now().After(*pedido.PrometidoPara)
+	return pedido, nil
+}
+
+// Asignar deja el pedido a cargo de un empleado activo. Solo se reasignan pedidos abiertos
+func (s *PedidoService) Asignar(id uint, empleadoID uint, usuarioID uint) (*models.Pedido, error) {
+	pedido, err := s.pedidoRepo.BuscarPorID(id)
+	if err != nil {
+		return nil, err
+	}
+	if !esPedidoAbierto(pedido) {
+		return nil, fmt.Errorf("el pedido está %s y no se puede reasignar", pedido.Estado)
+	}
+
+	empleado, err := s.usuarioRepo.BuscarPorID(empleadoID)
+	if err != nil {
+		return nil, err
+	}
+	if !empleado.Activo {
+		return nil, fmt.Errorf("el usuario %s está inactivo", empleado.Nombre)
+	}
+
+	if err := s.pedidoRepo.Asignar(pedido.ID, empleado.ID); err != nil {
+		return nil, err
+	}
+	pedido.AtendidoPor = &empleado.ID
+	pedido.EmpleadoAtiende = empleado
+
+	log.Printf("🧾 Pedido #%d asignado a %s (usuario %d)", pedido.ID, empleado.Nombre, usuarioID)
+	s.publicarCocina("actualizado", pedido)
+	return pedido, nil
+}
+
+// ListarPorCliente obtiene el historial de pedidos de un cliente
+func (s *PedidoService) ListarPorCliente(clienteID uint, limite int) ([]*models.Pedido, error) {
+	if limite <= 0 || limite > 200 {
+		limite = 50
+	}
+
+	cliente, err := s.clienteRepo.BuscarPorID(clienteID)
+	if err != nil {
+		return nil, fmt.Errorf("cliente con ID %d no encontrado", clienteID)
+	}
+	return s.pedidoRepo.ListarPorCliente(cliente.ID, cliente.Telefono, limite)
+}
+
+// Repetir crea un pedido pendiente con los mismos items que un pedido anterior del
+// cliente, a los precios actuales. Los items que ya no están en el menú se omiten y se informan
+func (s *PedidoService) Repetir(clienteID uint, req models.RepetirPedidoRequest, usuarioID uint) (*models.Pedido, []string, error) {
+	cliente, err := s.clienteRepo.BuscarPorID(clienteID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("cliente con ID %d no encontrado", clienteID)
+	}
+
+	var anterior *models.Pedido
+	if req.PedidoID != 0 {
+		anterior, err = s.pedidoRepo.BuscarPorID(req.PedidoID)
+		if err != nil {
+			return nil, nil, err
+		}
+		if (anterior.ClienteID == nil || *anterior.ClienteID != cliente.ID) && anterior.Telefono != cliente.Telefono {
+			return nil, nil, fmt.Errorf("el pedido #%d no es de este cliente", anterior.ID)
+		}
+	} else {
+		anterior, err = s.pedidoRepo.UltimoDelCliente(cliente.ID, cliente.Telefono)
+		if err != nil {
+			return nil, nil, err
+		}
+		if anterior == nil {
+			return nil, nil, fmt.Errorf("el cliente no tiene pedidos para repetir")
+		}
+	}
+
+	nuevo := models.ConfirmarPedidoRequest{
+		Telefono: cliente.Telefono,
+		Mensaje:  fmt.Sprintf("Repetición del pedido #%d", anterior.ID),
+		Notas:    req.Notas,
+	}
+	var omitidos []string
+	for _, item := range anterior.Items {
+		if item.ItemMenuID == nil {
+			omitidos = append(omitidos, item.Nombre)
+			continue
+		}
+		enMenu, err := s.menuRepo.BuscarPorID(*item.ItemMenuID)
+		if err != nil || !enMenu.Activo {
+			omitidos = append(omitidos, item.Nombre)
+			continue
+		}
+		nuevo.Items = append(nuevo.Items, models.ItemPedidoRequest{
+			ItemMenuID: enMenu.ID,
+			Cantidad:   item.Cantidad,
+			Notas:      item.Notas,
+		})
+	}
+	if len(nuevo.Items) == 0 {
+		return nil, omitidos, fmt.Errorf("ningún item del pedido #%d sigue disponible en el menú", anterior.ID)
+	}
+
+	pedido, err := s.Confirmar(nuevo, usuarioID)
+	if err != nil {
+		return nil, omitidos, err
+	}
+	return pedido, omitidos, nil
+}
+
+// ColaCocina obtiene los pedidos pendientes y en preparación ordenados por hora prometida
+func (s *PedidoService) ColaCocina() ([]*models.Pedido, error) {
+	pedidos, err := s.pedidoRepo.ListarCola()
+	if err != nil {
+		return nil, err
+	}
+
+	ahora := time.Now()
+	for _, pedido := range pedidos {
+		pedido.Atrasado = pedido.PrometidoPara != nil && ahora.After(*pedido.PrometidoPara)
+	}
+	return pedidos, nil
+}
+
+// MarcarItemPreparado marca o desmarca un item del pedido como listo desde la cocina
+func (s *PedidoService) MarcarItemPreparado(pedidoID, itemID uint, preparado bool) (*models.Pedido, error) {
+	pedido, err := s.pedidoRepo.BuscarPorID(pedidoID)
+	if err != nil {
+		return nil, err
+	}
+	if pedido.Estado == "completado" || pedido.Estado == "cancelado" {
+		return nil, fmt.Errorf("el pedido está %s", pedido.Estado)
+	}
+
+	var item *models.PedidoItem
+	for i := range pedido.Items {
+		if pedido.Items[i].ID == itemID {
+			item = &pedido.Items[i]
+			break
+		}
+	}
+	if item == nil {
+		return nil, fmt.Errorf("el pedido #%d no tiene el item %d", pedidoID, itemID)
+	}
+
+	if err := s.pedidoRepo.MarcarItemPreparado(itemID, preparado); err != nil {
+		return nil, err
+	}
+	item.Preparado = preparado
+	pedido.Atrasado = pedido.PrometidoPara != nil && time.Now().After(*pedido.PrometidoPara)
+
+	s.publicarCocina("actualizado", pedido)
+	return pedido, nil
+}
+
+// EstimarEspera calcula cuánto tardaría un pedido nuevo de esa cantidad de unidades:
+// el tiempo promedio de preparación de su tamaño más lo que demora despachar la cola
+// actual entre los pedidos que la cocina prepara en paralelo. Con 0 unidades usa el promedio general
+func (s *PedidoService) EstimarEspera(unidades int) (*models.EstimacionEspera, error) {
+	promedios, err := s.pedidoRepo.GetPromediosPreparacion(time.Now().Add(-ventanaPromediosPreparacion))
+	if err != nil {
+		return nil, err
+	}
+	cola, err := s.pedidoRepo.ContarCola()
+	if err != nil {
+		return nil, err
+	}
+
+	general := float64(s.config.OrderPrepMinutes)
+	pedidos, suma := 0, 0.0
+	for _, promedio := range promedios {
+		pedidos += promedio.Pedidos
+		suma += promedio.Minutos * float64(promedio.Pedidos)
+	}
+	if pedidos >= minimoPedidosPromedio {
+		general = suma / float64(pedidos)
+	}
+
+	base := general
+	if unidades > 0 {
+		tamano := tamanoPedido(unidades)
+		for _, promedio := range promedios {
+			if promedio.Tamano == tamano && promedio.Pedidos >= minimoPedidosPromedio {
+				base = promedio.Minutos
+			}
+		}
+	}
+
+	capacidad := s.config.KitchenParallelOrders
+	if capacidad < 1 {
+		capacidad = 1
+	}
+
+	return &models.EstimacionEspera{
+		MinutosEstimados: int(math.Ceil(base + general*float64(cola)/float64(capacidad))),
+		PedidosEnCola:    cola,
+		Promedios:        promedios,
+		CalculadoEn:      time.Now(),
+	}, nil
+}
+
+// EsperaPublica retorna la espera estimada para mostrar en la web, redondeada hacia
+// arriba a múltiplos de 5 minutos y sin el detalle de los promedios
+func (s *PedidoService) EsperaPublica() (*models.EstimacionEspera, error) {
+	s.esperaMu.Lock()
+	defer s.esperaMu.Unlock()
+
+	if s.esperaPublica != nil && time.Since(s.esperaPublica.CalculadoEn) < time.Minute {
+		return s.esperaPublica, nil
+	}
+
+	estimacion, err := s.EstimarEspera(0)
+	if err != nil {
+		return nil, err
+	}
+	estimacion.MinutosEstimados = (estimacion.MinutosEstimados + 4) / 5 * 5
+	estimacion.Promedios = nil
+
+	s.esperaPublica = estimacion
+	return estimacion, nil
+}
+
+// SuscribirCocina registra una pantalla de cocina para recibir los pedidos nuevos y
+// sus cambios. La función retornada la da de baja
+func (s *PedidoService) SuscribirCocina() (<-chan models.EventoCocina, func()) {
+	ch := make(chan models.EventoCocina, 16)
+
+	s.cocinaMu.Lock()
+	s.suscriptores[ch] = struct{}{}
+	s.cocinaMu.Unlock()
+
+	return ch, func() {
+		s.cocinaMu.Lock()
+		if _, ok := s.suscriptores[ch]; ok {
+			delete(s.suscriptores, ch)
+			close(ch)
+		}
+		s.cocinaMu.Unlock()
+	}
+}
+
+// publicarCocina avisa a las pantallas conectadas; las que no leen a tiempo pierden el
+// evento y se ponen al día con la próxima consulta de la cola
+func (s *PedidoService) publicarCocina(tipo string, pedido *models.Pedido) {
+	s.cocinaMu.Lock()
+	defer s.cocinaMu.Unlock()
+
+	for ch := range s.suscriptores {
+		select {
+		case ch <- models.EventoCocina{Tipo: tipo, Pedido: pedido}:
+		default:
+			log.Printf("⚠️  Pantalla de cocina lenta, se descarta aviso del pedido #%d", pedido.ID)
+		}
+	}
+}
+
+// tamanoPedido clasifica el pedido por unidades, igual que GetPromediosPreparacion
+func tamanoPedido(unidades int) string {
+	switch {
+	case unidades <= 2:
+		return "chico"
+	case unidades <= 5:
+		return "mediano"
+	default:
+		return "grande"
+	}
+}
+
+// aplicarItemMenu copia los datos del request al item, limpiando los alias
+func aplicarItemMenu(item *models.ItemMenu, req models.GuardarItemMenuRequest) {
+	var alias []string
+	for _, a := range req.Alias {
+		if a = strings.TrimSpace(strings.ReplaceAll(a, ",", " ")); a != "" {
+			alias = append(alias, a)
+		}
+	}
+
+	item.Nombre = strings.TrimSpace(req.Nombre)
+	item.Alias = strings.Join(alias, ",")
+	item.Precio = req.Precio
+	if req.Activo != nil {
+		item.Activo = *req.Activo
+	}
+}
+
+// esPedidoAbierto indica si el pedido todavía está pendiente o en preparación
+func esPedidoAbierto(pedido *models.Pedido) bool {
+	_, abierto := transicionesPedido[pedido.Estado]
+	return abierto
+}
@@ -0,0 +1,129 @@
+package services
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"sync"
+	"time"
+
+	"CheeseHouse/internal/config"
+	"CheeseHouse/internal/models"
+	"CheeseHouse/internal/repository"
+)
+
+// PicoEmisionService compara los vouchers emitidos en la última hora con el
+// promedio por hora de la línea base. Si lo supera por el factor configurado
+// notifica y, opcionalmente, exige aprobación de un empleado para nuevos premios
+type PicoEmisionService struct {
+	config         *config.Config
+	voucherRepo    repository.VoucherRepository
+	notificaciones *NotificacionService
+
+	mu     sync.Mutex
+	estado models.PicoEmision
+}
+
+// NewPicoEmisionService crea una nueva instancia del detector de picos de emisión
+func NewPicoEmisionService(cfg *config.Config, voucherRepo repository.VoucherRepository, notificaciones *NotificacionService) *PicoEmisionService {
+	return &PicoEmisionService{
+		config:         cfg,
+		voucherRepo:    voucherRepo,
+		notificaciones: notificaciones,
+		estado:         models.PicoEmision{Factor: cfg.Alerts.IssuanceSpikeFactor},
+	}
+}
+
+// Verificar recalcula la tasa de emisión y actualiza el estado del pico
+func (p *PicoEmisionService) Verificar() error {
+	alertas := p.config.Alerts
+	ahora := time.Now()
+	inicioHora := ahora.Add(-time.Hour)
+	inicioBase := inicioHora.Add(-time.Duration(alertas.IssuanceBaselineHours) * time.Hour)
+
+	ultimaHora, err := p.voucherRepo.ContarEmitidosEntre(inicioHora, ahora)
+	if err != nil {
+		return err
+	}
+	base, err := p.voucherRepo.ContarEmitidosEntre(inicioBase, inicioHora)
+	if err != nil {
+		return err
+	}
+
+	promedio := 0.0
+	if alertas.IssuanceBaselineHours > 0 {
+		promedio = float64(base) / float64(alertas.IssuanceBaselineHours)
+	}
+	pico := ultimaHora >= alertas.IssuanceSpikeMinPerHour &&
+		float64(ultimaHora) > promedio*alertas.IssuanceSpikeFactor
+
+	p.mu.Lock()
+	eraPico := p.estado.Activo
+	p.estado.EmitidosUltimaHora = ultimaHora
+	p.estado.PromedioPorHora = math.Round(promedio*100) / 100
+	p.estado.UltimaVerificacion = &ahora
+	p.estado.Activo = pico
+	p.estado.RequiereAprobacion = pico && alertas.IssuanceSpikeRequireApproval
+	if pico && !eraPico {
+		p.estado.DetectadoEn = &ahora
+	}
+	if !pico {
+		p.estado.DetectadoEn = nil
+	}
+	p.mu.Unlock()
+
+	switch {
+	case pico && !eraPico:
+		descripcion := fmt.Sprintf("%d vouchers emitidos en la última hora contra un promedio de %.1f por hora",
+			ultimaHora, promedio)
+		if alertas.IssuanceSpikeRequireApproval {
+			descripcion += ". Los nuevos premios quedan retenidos hasta que un empleado los libere en Premios retenidos"
+		}
+		log.Printf("🚨 Pico de emisión de vouchers: %s", descripcion)
+		p.notificaciones.Notificar("error", "Pico de emisión de vouchers", descripcion, "revisar_emision_vouchers")
+	case !pico && eraPico:
+		log.Printf("✅ Emisión de vouchers normalizada: %d en la última hora", ultimaHora)
+		p.notificaciones.Notificar("info", "Emisión de vouchers normalizada",
+			fmt.Sprintf("%d vouchers emitidos en la última hora", ultimaHora), "")
+	}
+	return nil
+}
+
+// IniciarMonitor verifica la tasa de emisión periódicamente en segundo plano. Con varias
+// instancias verifica solo la líder, que es la que avisa, y las demás toman el estado que
+// publicó para saber si los premios requieren aprobación
+func (p *PicoEmisionService) IniciarMonitor(intervalo time.Duration, coordinacion *CoordinacionService) {
+	go func() {
+		for {
+			if coordinacion.EsLider() {
+				if err := p.Verificar(); err != nil {
+					log.Printf("⚠️  Error verificando picos de emisión: %v", err)
+				} else {
+					coordinacion.PublicarEstado("pico_emision", p.Estado(), 3*intervalo)
+				}
+			} else {
+				var estado models.PicoEmision
+				if coordinacion.LeerEstado("pico_emision", &estado) {
+					p.mu.Lock()
+					p.estado = estado
+					p.mu.Unlock()
+				}
+			}
+			time.Sleep(intervalo)
+		}
+	}()
+}
+
+// RequiereAprobacion indica si los premios deben ser aprobados por un empleado
+func (p *PicoEmisionService) RequiereAprobacion() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.estado.RequiereAprobacion
+}
+
+// Estado retorna el estado del detector para el dashboard
+func (p *PicoEmisionService) Estado() models.PicoEmision {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.estado
+}
@@ -0,0 +1,185 @@
+package services
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+
+	"CheeseHouse/internal/config"
+	"CheeseHouse/internal/models"
+	"CheeseHouse/internal/repository"
+)
+
+// PreferenciasService maneja el centro de preferencias de comunicación de los clientes
+type PreferenciasService struct {
+	config           *config.Config
+	preferenciasRepo repository.PreferenciasRepository
+	clienteRepo      *repository.ClienteRepository
+}
+
+// NewPreferenciasService crea una nueva instancia del servicio de preferencias
+func NewPreferenciasService(
+	cfg *config.Config,
+	preferenciasRepo repository.PreferenciasRepository,
+	clienteRepo *repository.ClienteRepository,
+) *PreferenciasService {
+	return &PreferenciasService{
+		config:           cfg,
+		preferenciasRepo: preferenciasRepo,
+		clienteRepo:      clienteRepo,
+	}
+}
+
+// PermiteEnvio indica si el cliente acepta mensajes de la categoría por el canal dado. Los
+// transaccionales (el voucher ganado, avisos de pedidos) salen siempre: las preferencias
+// solo filtran el marketing
+func (p *PreferenciasService) PermiteEnvio(clienteID uint, categoria string, canal string) bool {
+	if categoria != CategoriaMarketing {
+		return true
+	}
+
+	preferencias, err := p.preferenciasRepo.BuscarPorCliente(clienteID)
+	if err != nil {
+		// Ante errores de lectura solo se bloquea el marketing
+		log.Printf("⚠️  Error leyendo preferencias del cliente %d: %v", clienteID, err)
+		return false
+	}
+	return preferencias.Canal == canal && preferencias.Marketing
+}
+
+// PermiteEnvioTelefono igual que PermiteEnvio pero a partir del teléfono;
+// los teléfonos sin cliente registrado no tienen restricciones
+func (p *PreferenciasService) PermiteEnvioTelefono(telefono string, categoria string, canal string) bool {
+	cliente, err := p.clienteRepo.BuscarPorTelefono(telefono)
+	if err != nil {
+		return true
+	}
+	return p.PermiteEnvio(cliente.ID, categoria, canal)
+}
+
+// DestinoEmail retorna el email al que el canal email puede enviar mensajes de la
+// categoría: el cliente tiene que haberlo elegido como canal y tener un email registrado
+func (p *PreferenciasService) DestinoEmail(clienteID uint, categoria string) (string, bool) {
+	preferencias, err := p.preferenciasRepo.BuscarPorCliente(clienteID)
+	if err != nil || preferencias.Canal != "email" || !p.PermiteEnvio(clienteID, categoria, "email") {
+		return "", false
+	}
+	return p.emailCliente(clienteID)
+}
+
+// emailCliente retorna el email registrado del cliente
+func (p *PreferenciasService) emailCliente(clienteID uint) (string, bool) {
+	cliente, err := p.clienteRepo.BuscarPorID(clienteID)
+	if err != nil || cliente.Email == nil {
+		return "", false
+	}
+	return *cliente.Email, true
+}
+
+// GenerarLinkPreferencias genera el link firmado al centro de preferencias del cliente
+func (p *PreferenciasService) GenerarLinkPreferencias(clienteID uint) string {
+	return fmt.Sprintf("%s/preferencias.html?c=%d&t=%s",
+		p.config.PublicBaseURL, clienteID, p.firmar(clienteID))
+}
+
+// GenerarLinkBaja genera el link firmado de baja de marketing en un click.
+// campanaID identifica la campaña que originó el mensaje (0 si no corresponde)
+func (p *PreferenciasService) GenerarLinkBaja(clienteID uint, campanaID uint) string {
+	return fmt.Sprintf("%s/baja.html?c=%d&k=%d&t=%s",
+		p.config.PublicBaseURL, clienteID, campanaID, p.firmarBaja(clienteID, campanaID))
+}
+
+// DarDeBaja desactiva el marketing del cliente desde el link de una campaña
+// y registra la baja para las estadísticas. Es idempotente
+func (p *PreferenciasService) DarDeBaja(clienteID uint, req models.BajaMarketingRequest) error {
+	if !hmac.Equal([]byte(p.firmarBaja(clienteID, req.CampanaID)), []byte(req.Token)) {
+		return errors.New("link de baja inválido")
+	}
+
+	preferencias, err := p.preferenciasRepo.BuscarPorCliente(clienteID)
+	if err != nil {
+		return err
+	}
+	if !preferencias.Marketing {
+		return nil
+	}
+
+	preferencias.Marketing = false
+	if err := p.preferenciasRepo.Guardar(preferencias); err != nil {
+		return err
+	}
+
+	baja := &models.BajaMarketing{ClienteID: clienteID}
+	if req.CampanaID != 0 {
+		baja.CampanaID = &req.CampanaID
+	}
+	if err := p.preferenciasRepo.RegistrarBaja(baja); err != nil {
+		// La baja ya quedó aplicada; solo se pierde el dato estadístico
+		log.Printf("⚠️  Error registrando baja del cliente %d: %v", clienteID, err)
+	}
+
+	log.Printf("📭 Cliente %d se dio de baja de marketing (campaña %d)", clienteID, req.CampanaID)
+	return nil
+}
+
+// ObtenerPreferencias retorna las preferencias validando el token del link
+func (p *PreferenciasService) ObtenerPreferencias(clienteID uint, token string) (*models.PreferenciasComunicacion, error) {
+	if !p.validarFirma(clienteID, token) {
+		return nil, errors.New("link de preferencias inválido")
+	}
+	return p.preferenciasRepo.BuscarPorCliente(clienteID)
+}
+
+// ActualizarPreferencias guarda las preferencias elegidas por el cliente
+func (p *PreferenciasService) ActualizarPreferencias(clienteID uint, req models.ActualizarPreferenciasRequest) (*models.PreferenciasComunicacion, error) {
+	if !p.validarFirma(clienteID, req.Token) {
+		return nil, errors.New("link de preferencias inválido")
+	}
+
+	if req.Canal == "email" {
+		if _, ok := p.emailCliente(clienteID); !ok {
+			return nil, errors.New("no tenemos un email tuyo registrado: cargalo en tu próxima partida para elegir este canal")
+		}
+	}
+
+	preferencias, err := p.preferenciasRepo.BuscarPorCliente(clienteID)
+	if err != nil {
+		return nil, err
+	}
+
+	preferencias.Canal = req.Canal
+	// Los transaccionales no se pueden desactivar (ver PermiteEnvio)
+	preferencias.Transaccional = true
+	preferencias.Marketing = *req.Marketing
+
+	if err := p.preferenciasRepo.Guardar(preferencias); err != nil {
+		return nil, err
+	}
+
+	log.Printf("📝 Preferencias actualizadas para cliente %d: canal=%s, marketing=%t",
+		clienteID, preferencias.Canal, preferencias.Marketing)
+
+	return preferencias, nil
+}
+
+// firmar genera la firma HMAC del link de preferencias
+func (p *PreferenciasService) firmar(clienteID uint) string {
+	mac := hmac.New(sha256.New, []byte(p.config.LinkSigningSecret))
+	fmt.Fprintf(mac, "preferencias:%d", clienteID)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// firmarBaja genera la firma HMAC del link de baja
+func (p *PreferenciasService) firmarBaja(clienteID uint, campanaID uint) string {
+	mac := hmac.New(sha256.New, []byte(p.config.LinkSigningSecret))
+	fmt.Fprintf(mac, "baja:%d:%d", clienteID, campanaID)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// validarFirma verifica la firma del link de preferencias
+func (p *PreferenciasService) validarFirma(clienteID uint, token string) bool {
+	return hmac.Equal([]byte(p.firmar(clienteID)), []byte(token))
+}
@@ -0,0 +1,159 @@
+package services
+
+import (
+	"net/url"
+	"testing"
+
+	"CheeseHouse/internal/config"
+	"CheeseHouse/internal/models"
+	"CheeseHouse/internal/repository"
+)
+
+// preferenciasDePrueba preferencias en memoria por cliente, con marketing activo
+type preferenciasDePrueba struct {
+	repository.PreferenciasRepository
+	porCliente map[uint]*models.PreferenciasComunicacion
+	bajas      []models.BajaMarketing
+}
+
+func (r *preferenciasDePrueba) BuscarPorCliente(clienteID uint) (*models.PreferenciasComunicacion, error) {
+	preferencias, ok := r.porCliente[clienteID]
+	if !ok {
+		preferencias = &models.PreferenciasComunicacion{ClienteID: clienteID, Canal: "whatsapp", Transaccional: true, Marketing: true}
+		r.porCliente[clienteID] = preferencias
+	}
+	return preferencias, nil
+}
+
+func (r *preferenciasDePrueba) Guardar(preferencias *models.PreferenciasComunicacion) error {
+	r.porCliente[preferencias.ClienteID] = preferencias
+	return nil
+}
+
+func (r *preferenciasDePrueba) RegistrarBaja(baja *models.BajaMarketing) error {
+	r.bajas = append(r.bajas, *baja)
+	return nil
+}
+
+func preferenciasDePruebaConSecreto(secreto string) (*PreferenciasService, *preferenciasDePrueba) {
+	cfg := &config.Config{LinkSigningSecret: secreto, PublicBaseURL: "https://cheesehouse.test"}
+	repo := &preferenciasDePrueba{porCliente: make(map[uint]*models.PreferenciasComunicacion)}
+	return NewPreferenciasService(cfg, repo, nil), repo
+}
+
+// tokenDelLink extrae el parámetro t de un link firmado
+func tokenDelLink(t *testing.T, link string) string {
+	t.Helper()
+	u, err := url.Parse(link)
+	if err != nil {
+		t.Fatalf("link inválido %q: %v", link, err)
+	}
+	return u.Query().Get("t")
+}
+
+func TestLinkPreferenciasFirmado(t *testing.T) {
+	p, _ := preferenciasDePruebaConSecreto("secreto-de-prueba")
+	otroSecreto, _ := preferenciasDePruebaConSecreto("otro-secreto")
+	token := tokenDelLink(t, p.GenerarLinkPreferencias(7))
+
+	casos := []struct {
+		nombre   string
+		servicio *PreferenciasService
+		cliente  uint
+		token    string
+		valido   bool
+	}{
+		{"link propio", p, 7, token, true},
+		{"token de otro cliente", p, 8, token, false},
+		{"token adulterado", p, 7, token[:len(token)-1] + "0", false},
+		{"token vacío", p, 7, "", false},
+		{"firmado con otro secreto", otroSecreto, 7, token, false},
+		{"token de baja", p, 7, tokenDelLink(t, p.GenerarLinkBaja(7, 0)), false},
+	}
+
+	for _, caso := range casos {
+		t.Run(caso.nombre, func(t *testing.T) {
+			_, err := caso.servicio.ObtenerPreferencias(caso.cliente, caso.token)
+			if (err == nil) != caso.valido {
+				t.Errorf("ObtenerPreferencias error = %v, se esperaba válido = %t", err, caso.valido)
+			}
+		})
+	}
+}
+
+func TestActualizarPreferenciasPideLinkValido(t *testing.T) {
+	p, repo := preferenciasDePruebaConSecreto("secreto-de-prueba")
+	sinMarketing := false
+
+	_, err := p.ActualizarPreferencias(7, models.ActualizarPreferenciasRequest{
+		Token: tokenDelLink(t, p.GenerarLinkPreferencias(8)), Canal: "whatsapp", Marketing: &sinMarketing,
+	})
+	if err == nil {
+		t.Fatal("se aceptó el link de otro cliente")
+	}
+	if _, tocado := repo.porCliente[7]; tocado {
+		t.Error("se leyeron o guardaron preferencias con un link inválido")
+	}
+
+	preferencias, err := p.ActualizarPreferencias(7, models.ActualizarPreferenciasRequest{
+		Token: tokenDelLink(t, p.GenerarLinkPreferencias(7)), Canal: "whatsapp", Marketing: &sinMarketing,
+	})
+	if err != nil {
+		t.Fatalf("error con el link propio: %v", err)
+	}
+	if preferencias.Marketing || !preferencias.Transaccional {
+		t.Errorf("preferencias = %+v", preferencias)
+	}
+}
+
+func TestLinkBajaFirmado(t *testing.T) {
+	p, _ := preferenciasDePruebaConSecreto("secreto-de-prueba")
+	token := tokenDelLink(t, p.GenerarLinkBaja(7, 3))
+
+	casos := []struct {
+		nombre  string
+		cliente uint
+		req     models.BajaMarketingRequest
+	}{
+		{"otra campaña", 7, models.BajaMarketingRequest{Token: token, CampanaID: 4}},
+		{"sin campaña", 7, models.BajaMarketingRequest{Token: token}},
+		{"otro cliente", 8, models.BajaMarketingRequest{Token: token, CampanaID: 3}},
+		{"token adulterado", 7, models.BajaMarketingRequest{Token: token[:len(token)-1] + "0", CampanaID: 3}},
+		{"token de preferencias", 7, models.BajaMarketingRequest{Token: tokenDelLink(t, p.GenerarLinkPreferencias(7)), CampanaID: 3}},
+	}
+
+	for _, caso := range casos {
+		t.Run(caso.nombre, func(t *testing.T) {
+			p, repo := preferenciasDePruebaConSecreto("secreto-de-prueba")
+			if err := p.DarDeBaja(caso.cliente, caso.req); err == nil {
+				t.Fatal("se aceptó un link de baja inválido")
+			}
+			if len(repo.porCliente) != 0 || len(repo.bajas) != 0 {
+				t.Errorf("se tocaron las preferencias: %v %v", repo.porCliente, repo.bajas)
+			}
+		})
+	}
+}
+
+func TestDarDeBajaDesdeCampana(t *testing.T) {
+	p, repo := preferenciasDePruebaConSecreto("secreto-de-prueba")
+	req := models.BajaMarketingRequest{Token: tokenDelLink(t, p.GenerarLinkBaja(7, 3)), CampanaID: 3}
+
+	if err := p.DarDeBaja(7, req); err != nil {
+		t.Fatalf("error con el link válido: %v", err)
+	}
+	if preferencias := repo.porCliente[7]; preferencias.Marketing || !preferencias.Transaccional {
+		t.Errorf("preferencias = %+v", preferencias)
+	}
+	if len(repo.bajas) != 1 || repo.bajas[0].CampanaID == nil || *repo.bajas[0].CampanaID != 3 {
+		t.Fatalf("bajas = %+v, se esperaba una de la campaña 3", repo.bajas)
+	}
+
+	// Repetir el click no registra otra baja
+	if err := p.DarDeBaja(7, req); err != nil {
+		t.Fatalf("error repitiendo la baja: %v", err)
+	}
+	if len(repo.bajas) != 1 {
+		t.Errorf("bajas = %d, se esperaba 1", len(repo.bajas))
+	}
+}
@@ -0,0 +1,255 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"strings"
+	"time"
+
+	"CheeseHouse/internal/config"
+	"CheeseHouse/internal/models"
+	"CheeseHouse/internal/repository"
+)
+
+// limiteCierresRanking cuántos cierres anteriores se listan en el panel
+const limiteCierresRanking = 52
+
+var (
+	// ErrRankingYaCerrado el período se cerró en el medio (otro admin u otra instancia)
+	ErrRankingYaCerrado = errors.New("el período del ranking ya se cerró, volvé a cargarlo")
+	// ErrDescuentoPremioRanking se pidió el voucher del ganador sin descuento configurado
+	ErrDescuentoPremioRanking = errors.New("indicá el descuento del voucher: LEADERBOARD_BONUS_DISCOUNT no está configurado")
+)
+
+// RankingService arma el ranking semanal con la mejor partida de cada cliente (la más
+// cercana al objetivo) y cierra las semanas con su ganador. La semana va de lunes a
+// lunes en la zona horaria del restaurante y se cierra sola; un admin puede cerrarla
+// antes y el ranking vuelve a empezar desde ese momento
+type RankingService struct {
+	config      *config.Config
+	rankingRepo repository.RankingRepository
+	voucherRepo repository.VoucherRepository
+	whatsapp    *WhatsAppService
+}
+
+// NewRankingService crea una nueva instancia del servicio de ranking
+func NewRankingService(cfg *config.Config, rankingRepo repository.RankingRepository, voucherRepo repository.VoucherRepository, whatsapp *WhatsAppService) *RankingService {
+	return &RankingService{
+		config:      cfg,
+		rankingRepo: rankingRepo,
+		voucherRepo: voucherRepo,
+		whatsapp:    whatsapp,
+	}
+}
+
+// GetRanking retorna las posiciones del período en curso. El público solo ve a los
+// clientes que aceptaron aparecer en el muro, con el nombre reducido a "Juan P."; el
+// panel ve a todos con el nombre completo y la cantidad de partidas
+func (s *RankingService) GetRanking(publico bool) (*models.Ranking, error) {
+	ahora := time.Now()
+	desde, err := s.inicioPeriodo(ahora)
+	if err != nil {
+		return nil, err
+	}
+
+	tamano := s.config.Leaderboard.Size
+	if tamano <= 0 {
+		tamano = 10
+	}
+	posiciones, err := s.rankingRepo.MejoresEntre(desde, ahora, publico, tamano)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range posiciones {
+		posicion := &posiciones[i]
+		posicion.DiferenciaSegundos = math.Round(posicion.DiferenciaSegundos*1000) / 1000
+		if posicion.Cliente == nil {
+			continue
+		}
+		if publico {
+			posicion.Nombre = nombrePublico(posicion.Cliente.Nombre, posicion.Cliente.Apellido)
+			posicion.ClienteID, posicion.Partidas, posicion.Cliente = 0, 0, nil
+		} else {
+			posicion.Nombre = strings.TrimSpace(posicion.Cliente.Nombre + " " + posicion.Cliente.Apellido)
+		}
+	}
+
+	return &models.Ranking{
+		Desde:      desde,
+		Hasta:      s.config.InicioDeSemana(ahora).AddDate(0, 0, 7),
+		Posiciones: posiciones,
+	}, nil
+}
+
+// Cerrar cierra el período en curso a pedido de un admin y, si se pide, le emite el
+// voucher al ganador con el descuento indicado o el de LEADERBOARD_BONUS_DISCOUNT
+func (s *RankingService) Cerrar(req models.CerrarRankingRequest, usuarioID uint) (*models.CierreRanking, error) {
+	descuento := 0
+	if req.EmitirVoucher {
+		descuento = req.Descuento
+		if descuento == 0 {
+			descuento = s.descuentoAutomatico()
+		}
+		if descuento <= 0 {
+			return nil, ErrDescuentoPremioRanking
+		}
+	}
+
+	// Si la semana anterior todavía no se cerró sola, primero se cierra esa
+	ahora := time.Now().Truncate(time.Second)
+	if _, err := s.cerrarSemanaAnterior(ahora); err != nil {
+		return nil, err
+	}
+	desde, err := s.inicioPeriodo(ahora)
+	if err != nil {
+		return nil, err
+	}
+	return s.cerrar(desde, ahora, &usuarioID, descuento)
+}
+
+// CerrarSemanaAnterior cierra la semana pasada si todavía no se cerró, con el premio
+// automático si LEADERBOARD_BONUS_DISCOUNT está configurado. Retorna nil si no había nada
+// que cerrar; la corre el mantenimiento todos los días
+func (s *RankingService) CerrarSemanaAnterior() (*models.CierreRanking, error) {
+	return s.cerrarSemanaAnterior(time.Now())
+}
+
+// GetCierres lista los últimos períodos cerrados con su ganador y el voucher del premio
+func (s *RankingService) GetCierres() ([]*models.CierreRanking, error) {
+	return s.rankingRepo.ListarCierres(limiteCierresRanking)
+}
+
+// cerrarSemanaAnterior cierra desde el último cierre (o el lunes anterior) hasta el
+// inicio de la semana de ahora
+func (s *RankingService) cerrarSemanaAnterior(ahora time.Time) (*models.CierreRanking, error) {
+	inicio := s.config.InicioDeSemana(ahora)
+	ultimo, err := s.rankingRepo.UltimoCierre()
+	if err != nil {
+		return nil, err
+	}
+	if ultimo != nil && !ultimo.Hasta.Before(inicio) {
+		return nil, nil
+	}
+
+	desde := inicio.AddDate(0, 0, -7)
+	if ultimo != nil && ultimo.Hasta.After(desde) {
+		desde = ultimo.Hasta
+	}
+	cierre, err := s.cerrar(desde, inicio, nil, s.descuentoAutomatico())
+	if errors.Is(err, ErrRankingYaCerrado) {
+		return nil, nil
+	}
+	return cierre, err
+}
+
+// cerrar registra el cierre del rango [desde, hasta) con su ganador y, con descuento
+// mayor a 0, le emite el voucher del premio. El cierre se registra antes que el voucher
+// para que un cierre simultáneo no premie dos veces
+func (s *RankingService) cerrar(desde, hasta time.Time, cerradoPor *uint, descuento int) (*models.CierreRanking, error) {
+	ganadores, err := s.rankingRepo.MejoresEntre(desde, hasta, false, 1)
+	if err != nil {
+		return nil, err
+	}
+	participantes, err := s.rankingRepo.ContarParticipantesEntre(desde, hasta)
+	if err != nil {
+		return nil, err
+	}
+
+	cierre := &models.CierreRanking{
+		Desde:         desde,
+		Hasta:         hasta,
+		Participantes: participantes,
+		CerradoPor:    cerradoPor,
+	}
+	var ganador *models.PosicionRanking
+	if len(ganadores) > 0 && ganadores[0].Cliente != nil {
+		ganador = &ganadores[0]
+		diferencia := math.Round(ganador.DiferenciaSegundos*1000) / 1000
+		cierre.ClienteID = &ganador.ClienteID
+		cierre.DiferenciaSegundos = &diferencia
+	}
+	if err := s.rankingRepo.CrearCierre(cierre); err != nil {
+		if errors.Is(err, repository.ErrCierreRankingDuplicado) {
+			return nil, ErrRankingYaCerrado
+		}
+		return nil, err
+	}
+
+	if ganador == nil {
+		log.Printf("🏆 Ranking cerrado (%s a %s) sin partidas", desde.Format("2006-01-02 15:04"), hasta.Format("2006-01-02 15:04"))
+		return cierre, nil
+	}
+	log.Printf("🏆 Ranking cerrado (%s a %s): gana el cliente #%d por %.3fs entre %d participantes",
+		desde.Format("2006-01-02 15:04"), hasta.Format("2006-01-02 15:04"), ganador.ClienteID, *cierre.DiferenciaSegundos, participantes)
+	cierre.Cliente = ganador.Cliente
+
+	if descuento > 0 {
+		// El cierre ya quedó registrado: si el voucher falla se avisa en el log y el
+		// admin puede premiar a mano al ganador que figura en el cierre
+		if voucher, err := s.premiar(cierre, ganador.Cliente, descuento); err != nil {
+			log.Printf("⚠️  No se pudo emitir el voucher del ganador del ranking #%d: %v", cierre.ID, err)
+		} else {
+			cierre.VoucherID = &voucher.ID
+			cierre.Voucher = voucher
+		}
+	}
+	return cierre, nil
+}
+
+// premiar emite el voucher del ganador, lo vincula con el cierre y se lo manda por WhatsApp
+func (s *RankingService) premiar(cierre *models.CierreRanking, cliente *models.Cliente, descuento int) (*models.Voucher, error) {
+	dias := s.config.Leaderboard.BonusValidDays
+	if dias < 1 {
+		dias = 30
+	}
+	ahora := time.Now()
+	voucher := &models.Voucher{
+		Codigo:           generarCodigosCampana(s.config.GenerateVoucherCode(), 1)[0],
+		ClienteID:        cliente.ID,
+		Tipo:             "cliente_promocion",
+		Descuento:        descuento,
+		FechaEmision:     ahora,
+		FechaVencimiento: ahora.AddDate(0, 0, dias),
+		Notas:            fmt.Sprintf("Premio del ranking semanal (cierre #%d)", cierre.ID),
+		Sucursal:         s.config.Sucursal(""),
+	}
+	if err := s.voucherRepo.Crear(voucher); err != nil {
+		return nil, err
+	}
+	if err := s.rankingRepo.AsignarVoucherCierre(cierre.ID, voucher.ID); err != nil {
+		log.Printf("⚠️  %v", err)
+	}
+
+	s.whatsapp.EnSegundoPlano(func() {
+		if err := s.whatsapp.EnviarVoucherGanador(cliente, voucher); err != nil {
+			log.Printf("⚠️  No se pudo enviar el premio del ranking al cliente #%d: %v", cliente.ID, err)
+		}
+	})
+	return voucher, nil
+}
+
+// descuentoAutomatico descuento del premio cuando la semana se cierra sola; 0 = sin premio
+func (s *RankingService) descuentoAutomatico() int {
+	descuento := s.config.Leaderboard.BonusDiscount
+	if descuento < 0 || descuento > 100 {
+		return 0
+	}
+	return descuento
+}
+
+// inicioPeriodo desde cuándo cuentan las partidas del ranking en curso: el lunes, o el
+// último cierre a mano si fue después
+func (s *RankingService) inicioPeriodo(ahora time.Time) (time.Time, error) {
+	inicio := s.config.InicioDeSemana(ahora)
+	ultimo, err := s.rankingRepo.UltimoCierre()
+	if err != nil {
+		return time.Time{}, err
+	}
+	if ultimo != nil && ultimo.Hasta.After(inicio) {
+		return ultimo.Hasta, nil
+	}
+	return inicio, nil
+}
@@ -0,0 +1,190 @@
+package services
+
+import (
+	"log"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"CheeseHouse/internal/config"
+	"CheeseHouse/internal/models"
+	"CheeseHouse/internal/repository"
+)
+
+// RecomendacionService motor de reglas que elige a qué productos aplica cada voucher de
+// partida y qué sugerirle al cliente, para subir el ticket promedio en el canje
+type RecomendacionService struct {
+	config      *config.Config
+	reglaRepo   repository.RecomendacionRepository
+	voucherRepo repository.VoucherRepository
+
+	mu        sync.RWMutex
+	activas   []*models.ReglaRecomendacion // nil = sin cargar
+	cargadoEn time.Time
+}
+
+// NewRecomendacionService crea una nueva instancia del servicio de recomendaciones
+func NewRecomendacionService(cfg *config.Config, reglaRepo repository.RecomendacionRepository, voucherRepo repository.VoucherRepository) *RecomendacionService {
+	return &RecomendacionService{
+		config:      cfg,
+		reglaRepo:   reglaRepo,
+		voucherRepo: voucherRepo,
+	}
+}
+
+// Aplicar completa el alcance y la recomendación del voucher con la primera regla activa
+// que coincide con el resultado y el tipo de cliente. Sin regla, el voucher queda igual
+func (s *RecomendacionService) Aplicar(voucher *models.Voucher, cliente *models.Cliente, gano bool) {
+	regla := s.elegir(gano, cliente.TipoCliente)
+	if regla == nil {
+		return
+	}
+
+	reemplazos := strings.NewReplacer(
+		"{nombre}", cliente.Nombre,
+		"{descuento}", strconv.Itoa(voucher.Descuento),
+	)
+	voucher.ReglaID = &regla.ID
+	voucher.Alcance = regla.Alcance
+	voucher.Recomendacion = reemplazos.Replace(regla.Mensaje)
+}
+
+// Listar obtiene las reglas en orden de evaluación con sus canjes de los últimos días
+func (s *RecomendacionService) Listar(dias int) ([]models.ReglaRecomendacionDetalle, error) {
+	reglas, err := s.reglaRepo.Listar()
+	if err != nil {
+		return nil, err
+	}
+	estadisticas, err := s.voucherRepo.GetEstadisticasPorRegla(time.Now().AddDate(0, 0, -dias))
+	if err != nil {
+		return nil, err
+	}
+
+	detalles := make([]models.ReglaRecomendacionDetalle, 0, len(reglas))
+	for _, regla := range reglas {
+		estadistica := estadisticas[regla.ID]
+		if estadistica.Emitidos > 0 {
+			estadistica.TasaCanje = math.Round(float64(estadistica.Canjeados)/float64(estadistica.Emitidos)*1000) / 10
+		}
+		detalles = append(detalles, models.ReglaRecomendacionDetalle{
+			ReglaRecomendacion: regla,
+			Estadisticas:       estadistica,
+		})
+	}
+	return detalles, nil
+}
+
+// Crear agrega una regla
+func (s *RecomendacionService) Crear(req models.GuardarReglaRecomendacionRequest, usuarioID uint) (*models.ReglaRecomendacion, error) {
+	regla := &models.ReglaRecomendacion{Activa: true, CreadoPor: usuarioID}
+	aplicarReglaRequest(regla, req)
+
+	if err := s.reglaRepo.Crear(regla); err != nil {
+		return nil, err
+	}
+	s.invalidar()
+
+	log.Printf("💡 Usuario %d creó la regla de recomendación %q (%s)", usuarioID, regla.Nombre, regla.Resultado)
+	return regla, nil
+}
+
+// Actualizar reemplaza los datos de una regla
+func (s *RecomendacionService) Actualizar(id uint, req models.GuardarReglaRecomendacionRequest, usuarioID uint) (*models.ReglaRecomendacion, error) {
+	regla, err := s.reglaRepo.BuscarPorID(id)
+	if err != nil {
+		return nil, err
+	}
+	aplicarReglaRequest(regla, req)
+
+	if err := s.reglaRepo.Actualizar(regla); err != nil {
+		return nil, err
+	}
+	s.invalidar()
+
+	log.Printf("💡 Usuario %d actualizó la regla de recomendación #%d (activa=%t)", usuarioID, regla.ID, regla.Activa)
+	return regla, nil
+}
+
+// Eliminar borra una regla
+func (s *RecomendacionService) Eliminar(id uint, usuarioID uint) error {
+	if err := s.reglaRepo.Eliminar(id); err != nil {
+		return err
+	}
+	s.invalidar()
+
+	log.Printf("💡 Usuario %d eliminó la regla de recomendación #%d", usuarioID, id)
+	return nil
+}
+
+// elegir retorna la primera regla activa que coincide, o nil
+func (s *RecomendacionService) elegir(gano bool, tipoCliente string) *models.ReglaRecomendacion {
+	resultado := "perdido"
+	if gano {
+		resultado = "ganado"
+	}
+
+	for _, regla := range s.reglasActivas() {
+		if regla.Resultado != "cualquiera" && regla.Resultado != resultado {
+			continue
+		}
+		if regla.TipoCliente != "" && regla.TipoCliente != tipoCliente {
+			continue
+		}
+		return regla
+	}
+	return nil
+}
+
+// reglasActivas retorna las reglas activas en orden de prioridad, cacheadas hasta el
+// próximo cambio (o SHARED_CACHE_TTL_SECONDS con varias instancias). Si la base no
+// responde los vouchers salen sin recomendación
+func (s *RecomendacionService) reglasActivas() []*models.ReglaRecomendacion {
+	s.mu.RLock()
+	activas := s.activas
+	vigente := s.config.Scaling.CacheVigente(s.cargadoEn)
+	s.mu.RUnlock()
+	if activas != nil && vigente {
+		return activas
+	}
+
+	reglas, err := s.reglaRepo.Listar()
+	if err != nil {
+		log.Printf("⚠️  Error obteniendo reglas de recomendación: %v", err)
+		return nil
+	}
+
+	activas = make([]*models.ReglaRecomendacion, 0, len(reglas))
+	for _, regla := range reglas {
+		if regla.Activa {
+			activas = append(activas, regla)
+		}
+	}
+
+	s.mu.Lock()
+	s.activas = activas
+	s.cargadoEn = time.Now()
+	s.mu.Unlock()
+	return activas
+}
+
+// invalidar descarta las reglas cacheadas
+func (s *RecomendacionService) invalidar() {
+	s.mu.Lock()
+	s.activas = nil
+	s.mu.Unlock()
+}
+
+// aplicarReglaRequest copia los campos editables del request a la regla
+func aplicarReglaRequest(regla *models.ReglaRecomendacion, req models.GuardarReglaRecomendacionRequest) {
+	regla.Nombre = strings.TrimSpace(req.Nombre)
+	regla.Resultado = req.Resultado
+	regla.TipoCliente = req.TipoCliente
+	regla.Alcance = strings.TrimSpace(req.Alcance)
+	regla.Mensaje = strings.TrimSpace(req.Mensaje)
+	regla.Prioridad = req.Prioridad
+	if req.Activa != nil {
+		regla.Activa = *req.Activa
+	}
+}
@@ -0,0 +1,147 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	"CheeseHouse/internal/models"
+)
+
+// Ejecución programada de los reportes guardados: el programador corre los que vencieron,
+// publica el resultado como archivo y deja el resumen en el reporte
+
+// ProcesarProgramados ejecuta los reportes programados cuya hora ya llegó
+// y guarda el resumen del resultado en el propio reporte
+func (r *ReporteService) ProcesarProgramados() {
+	ahora := time.Now()
+	reportes, err := r.reporteRepo.ListarProgramadosPendientes(ahora)
+	if err != nil {
+		log.Printf("❌ Error obteniendo reportes programados: %v", err)
+		return
+	}
+
+	for _, reporte := range reportes {
+		resultado, err := r.ejecutar(reporte)
+		if err != nil {
+			log.Printf("❌ Error ejecutando reporte programado %d (%s): %v", reporte.ID, reporte.Nombre, err)
+		} else {
+			// El resultado completo queda como archivo; en la base se guarda solo el resumen
+			if clave, err := r.guardarArchivo(reporte, resultado, ahora); err != nil {
+				log.Printf("⚠️ No se pudo guardar el archivo del reporte %d: %v", reporte.ID, err)
+			} else {
+				reporte.UltimoArchivo = clave
+			}
+			delete(resultado, "vouchers")
+			if resumen, err := json.Marshal(resultado); err == nil {
+				reporte.UltimoResultado = string(resumen)
+			}
+			log.Printf("📑 Reporte programado ejecutado: %s (%d)", reporte.Nombre, reporte.ID)
+		}
+
+		reporte.UltimaEjecucion = &ahora
+		reporte.ProximaEjecucion = r.proximaEjecucion(reporte.Programacion, ahora)
+		if err := r.reporteRepo.Actualizar(reporte); err != nil {
+			log.Printf("❌ Error actualizando reporte programado %d: %v", reporte.ID, err)
+		}
+	}
+}
+
+// DescargaUltimo arma un link de descarga para el último resultado guardado del reporte
+func (r *ReporteService) DescargaUltimo(usuarioID, reporteID uint) (*models.ArchivoGenerado, error) {
+	reporte, err := r.Obtener(usuarioID, reporteID)
+	if err != nil {
+		return nil, err
+	}
+	if reporte.UltimoArchivo == "" {
+		return nil, fmt.Errorf("el reporte todavía no tiene resultados guardados")
+	}
+	return r.artefactos.Link(reporte.UltimoArchivo)
+}
+
+// guardarArchivo publica el resultado de una ejecución en el formato del reporte
+// (reportes/<id>/<fecha>.csv|json) y retorna su clave
+func (r *ReporteService) guardarArchivo(reporte *models.ReporteGuardado, resultado map[string]interface{}, fecha time.Time) (string, error) {
+	var (
+		contenido []byte
+		err       error
+	)
+	extension, tipo := "json", "application/json"
+	if reporte.Formato == "csv" {
+		extension, tipo = "csv", "text/csv; charset=utf-8"
+		contenido, err = r.ExportarCSV(resultado)
+	} else {
+		contenido, err = json.Marshal(resultado)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	nombre := fmt.Sprintf("%s.%s", fecha.Format("20060102-150405"), extension)
+	archivo, err := r.artefactos.Publicar(fmt.Sprintf("reportes/%d", reporte.ID), nombre, contenido, tipo)
+	if err != nil {
+		return "", err
+	}
+	return archivo.Clave, nil
+}
+
+// IniciarProgramador revisa periódicamente los reportes programados en segundo plano; con
+// varias instancias cada revisión la hace una sola, la líder
+func (r *ReporteService) IniciarProgramador(intervalo time.Duration, coordinacion *CoordinacionService) {
+	go func() {
+		ticker := time.NewTicker(intervalo)
+		defer ticker.Stop()
+		for ahora := range ticker.C {
+			if !coordinacion.TomarTurno("reportes_programados", ahora.Truncate(intervalo)) {
+				continue
+			}
+			r.ProcesarProgramados()
+		}
+	}()
+}
+
+// EjecucionesEntre calcula las corridas de los reportes programados dentro del rango [desde, hasta)
+func (r *ReporteService) EjecucionesEntre(desde, hasta time.Time) ([]models.EjecucionProgramada, error) {
+	reportes, err := r.reporteRepo.ListarProgramados()
+	if err != nil {
+		return nil, err
+	}
+
+	var ejecuciones []models.EjecucionProgramada
+	for _, reporte := range reportes {
+		for fecha := reporte.ProximaEjecucion; fecha != nil && fecha.Before(hasta); fecha = r.proximaEjecucion(reporte.Programacion, *fecha) {
+			if fecha.Before(desde) {
+				continue
+			}
+			ejecuciones = append(ejecuciones, models.EjecucionProgramada{
+				ReporteID:    reporte.ID,
+				Nombre:       reporte.Nombre,
+				Programacion: reporte.Programacion,
+				Fecha:        *fecha,
+			})
+		}
+	}
+	return ejecuciones, nil
+}
+
+// proximaEjecucion calcula cuándo corre un reporte programado (a las 8:00 hora local)
+func (r *ReporteService) proximaEjecucion(programacion string, desde time.Time) *time.Time {
+	loc := r.config.GetLocation()
+	local := desde.In(loc)
+	base := time.Date(local.Year(), local.Month(), local.Day(), 8, 0, 0, 0, loc)
+
+	var proxima time.Time
+	switch programacion {
+	case "diaria":
+		proxima = base.AddDate(0, 0, 1)
+	case "semanal":
+		// Próximo lunes
+		proxima = base.AddDate(0, 0, 7-(int(base.Weekday())+6)%7)
+	case "mensual":
+		proxima = time.Date(local.Year(), local.Month()+1, 1, 8, 0, 0, 0, loc)
+	default:
+		return nil
+	}
+	return &proxima
+}
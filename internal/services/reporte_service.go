@@ -0,0 +1,254 @@
+package services
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+
+	"CheeseHouse/internal/config"
+	"CheeseHouse/internal/models"
+	"CheeseHouse/internal/repository"
+)
+
+// ReporteService gestiona reportes guardados: definiciones con nombre que se
+// pueden re-ejecutar y marcar como favoritas. La ejecución programada y la
+// entrega de sus resultados están en reporte_programados.go
+type ReporteService struct {
+	config       *config.Config
+	reporteRepo  repository.ReporteRepository
+	adminService *AdminService
+	artefactos   *ArtefactoService
+}
+
+// NewReporteService crea una nueva instancia del servicio de reportes
+func NewReporteService(cfg *config.Config, reporteRepo repository.ReporteRepository, adminService *AdminService, artefactos *ArtefactoService) *ReporteService {
+	return &ReporteService{
+		config:       cfg,
+		reporteRepo:  reporteRepo,
+		adminService: adminService,
+		artefactos:   artefactos,
+	}
+}
+
+// Guardar crea una definición de reporte para el usuario
+func (r *ReporteService) Guardar(usuarioID uint, req *models.GuardarReporteRequest) (*models.ReporteGuardado, error) {
+	reporte := &models.ReporteGuardado{UsuarioID: usuarioID}
+	if err := r.aplicarRequest(reporte, req); err != nil {
+		return nil, err
+	}
+
+	if err := r.reporteRepo.Crear(reporte); err != nil {
+		return nil, err
+	}
+
+	log.Printf("📑 Reporte guardado: %s (%s) - Usuario: %d", reporte.Nombre, reporte.Tipo, usuarioID)
+	return reporte, nil
+}
+
+// Actualizar modifica una definición de reporte del usuario
+func (r *ReporteService) Actualizar(usuarioID, reporteID uint, req *models.GuardarReporteRequest) (*models.ReporteGuardado, error) {
+	reporte, err := r.Obtener(usuarioID, reporteID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := r.aplicarRequest(reporte, req); err != nil {
+		return nil, err
+	}
+
+	if err := r.reporteRepo.Actualizar(reporte); err != nil {
+		return nil, err
+	}
+	return reporte, nil
+}
+
+// Eliminar borra una definición de reporte del usuario
+func (r *ReporteService) Eliminar(usuarioID, reporteID uint) error {
+	if _, err := r.Obtener(usuarioID, reporteID); err != nil {
+		return err
+	}
+	return r.reporteRepo.Eliminar(reporteID)
+}
+
+// Listar obtiene los reportes guardados del usuario (favoritos primero)
+func (r *ReporteService) Listar(usuarioID uint) ([]*models.ReporteGuardado, error) {
+	return r.reporteRepo.ListarPorUsuario(usuarioID)
+}
+
+// Obtener busca un reporte verificando que pertenezca al usuario
+func (r *ReporteService) Obtener(usuarioID, reporteID uint) (*models.ReporteGuardado, error) {
+	reporte, err := r.reporteRepo.BuscarPorID(reporteID)
+	if err != nil {
+		return nil, err
+	}
+	if reporte.UsuarioID != usuarioID {
+		return nil, fmt.Errorf("reporte no encontrado")
+	}
+	return reporte, nil
+}
+
+// MarcarFavorito agrega o quita un reporte de favoritos
+func (r *ReporteService) MarcarFavorito(usuarioID, reporteID uint, favorito bool) (*models.ReporteGuardado, error) {
+	reporte, err := r.Obtener(usuarioID, reporteID)
+	if err != nil {
+		return nil, err
+	}
+
+	reporte.Favorito = favorito
+	if err := r.reporteRepo.Actualizar(reporte); err != nil {
+		return nil, err
+	}
+	return reporte, nil
+}
+
+// Ejecutar corre un reporte guardado con sus parámetros actuales
+func (r *ReporteService) Ejecutar(usuarioID, reporteID uint) (*models.ReporteGuardado, map[string]interface{}, error) {
+	reporte, err := r.Obtener(usuarioID, reporteID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	resultado, err := r.ejecutar(reporte)
+	if err != nil {
+		return nil, nil, err
+	}
+	return reporte, resultado, nil
+}
+
+// ExportarCSV convierte el resultado de un reporte de ventas a CSV
+func (r *ReporteService) ExportarCSV(resultado map[string]interface{}) ([]byte, error) {
+	vouchers, ok := resultado["vouchers"].([]*models.Voucher)
+	if !ok {
+		return nil, fmt.Errorf("el reporte no admite exportación CSV")
+	}
+
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	w.Write([]string{"codigo", "cliente_id", "tipo", "descuento", "fecha_emision", "fecha_uso"})
+	for _, v := range vouchers {
+		fechaUso := ""
+		if v.FechaUso != nil {
+			fechaUso = v.FechaUso.Format("2006-01-02 15:04:05")
+		}
+		w.Write([]string{
+			v.Codigo,
+			strconv.FormatUint(uint64(v.ClienteID), 10),
+			v.Tipo,
+			strconv.Itoa(v.Descuento),
+			v.FechaEmision.Format("2006-01-02 15:04:05"),
+			fechaUso,
+		})
+	}
+	w.Flush()
+
+	if err := w.Error(); err != nil {
+		return nil, fmt.Errorf("error generando CSV: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// ejecutar corre el reporte según su tipo
+func (r *ReporteService) ejecutar(reporte *models.ReporteGuardado) (map[string]interface{}, error) {
+	var parametros models.ParametrosReporte
+	if err := json.Unmarshal([]byte(reporte.Parametros), &parametros); err != nil {
+		return nil, fmt.Errorf("parámetros de reporte inválidos: %w", err)
+	}
+
+	switch reporte.Tipo {
+	case "ventas":
+		inicio, fin, err := r.resolverRango(parametros, time.Now())
+		if err != nil {
+			return nil, err
+		}
+		return r.adminService.GetReporteVentasConFiltros(inicio, fin, parametros.Filtros)
+	case "estadisticas":
+		return r.adminService.GetEstadisticasDetalladas()
+	default:
+		return nil, fmt.Errorf("tipo de reporte desconocido: %s", reporte.Tipo)
+	}
+}
+
+// aplicarRequest valida el request y copia sus valores al reporte
+func (r *ReporteService) aplicarRequest(reporte *models.ReporteGuardado, req *models.GuardarReporteRequest) error {
+	if req.Tipo == "ventas" {
+		if _, _, err := r.resolverRango(req.Parametros, time.Now()); err != nil {
+			return err
+		}
+	}
+
+	formato := req.Formato
+	if formato == "" {
+		formato = "json"
+	}
+	if formato == "csv" && req.Tipo != "ventas" {
+		return fmt.Errorf("solo los reportes de ventas se pueden exportar en CSV")
+	}
+
+	programacion := req.Programacion
+	if programacion == "" {
+		programacion = "ninguna"
+	}
+
+	parametros, err := json.Marshal(req.Parametros)
+	if err != nil {
+		return fmt.Errorf("error serializando parámetros: %w", err)
+	}
+
+	if programacion != reporte.Programacion {
+		reporte.ProximaEjecucion = r.proximaEjecucion(programacion, time.Now())
+	}
+
+	reporte.Nombre = req.Nombre
+	reporte.Tipo = req.Tipo
+	reporte.Parametros = string(parametros)
+	reporte.Formato = formato
+	reporte.Favorito = req.Favorito
+	reporte.Programacion = programacion
+	return nil
+}
+
+// resolverRango calcula las fechas del reporte, recalculando los rangos relativos
+// respecto de la fecha actual en la zona horaria del restaurante
+func (r *ReporteService) resolverRango(parametros models.ParametrosReporte, ahora time.Time) (time.Time, time.Time, error) {
+	loc := r.config.GetLocation()
+	ahora = ahora.In(loc)
+	hoy := time.Date(ahora.Year(), ahora.Month(), ahora.Day(), 0, 0, 0, 0, loc)
+
+	switch parametros.RangoRelativo {
+	case "ultimos_7_dias":
+		return hoy.AddDate(0, 0, -7), ahora, nil
+	case "ultimos_30_dias":
+		return hoy.AddDate(0, 0, -30), ahora, nil
+	case "semana_anterior":
+		// Semanas de lunes a domingo
+		lunes := hoy.AddDate(0, 0, -((int(hoy.Weekday()) + 6) % 7))
+		return lunes.AddDate(0, 0, -7), lunes.Add(-time.Nanosecond), nil
+	case "mes_actual":
+		return time.Date(hoy.Year(), hoy.Month(), 1, 0, 0, 0, 0, loc), ahora, nil
+	case "mes_anterior":
+		inicioMes := time.Date(hoy.Year(), hoy.Month(), 1, 0, 0, 0, 0, loc)
+		return inicioMes.AddDate(0, -1, 0), inicioMes.Add(-time.Nanosecond), nil
+	case "":
+	default:
+		return time.Time{}, time.Time{}, fmt.Errorf("rango relativo desconocido: %s", parametros.RangoRelativo)
+	}
+
+	inicio, err := time.ParseInLocation("2006-01-02", parametros.FechaInicio, loc)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("fecha_inicio inválida (formato 2006-01-02)")
+	}
+	fin, err := time.ParseInLocation("2006-01-02", parametros.FechaFin, loc)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("fecha_fin inválida (formato 2006-01-02)")
+	}
+	if fin.Before(inicio) {
+		return time.Time{}, time.Time{}, fmt.Errorf("fecha_fin debe ser posterior a fecha_inicio")
+	}
+
+	// Incluir el día de fin completo
+	return inicio, fin.AddDate(0, 0, 1).Add(-time.Nanosecond), nil
+}
@@ -0,0 +1,123 @@
+package services
+
+import (
+	"fmt"
+	"log"
+	"strings"
+
+	"CheeseHouse/internal/config"
+	"CheeseHouse/internal/models"
+	"CheeseHouse/internal/repository"
+)
+
+// RespuestaRapidaService administra los textos predefinidos de la bandeja de WhatsApp
+type RespuestaRapidaService struct {
+	config        *config.Config
+	respuestaRepo repository.RespuestaRapidaRepository
+}
+
+// NewRespuestaRapidaService crea una nueva instancia del servicio de respuestas rápidas
+func NewRespuestaRapidaService(cfg *config.Config, respuestaRepo repository.RespuestaRapidaRepository) *RespuestaRapidaService {
+	return &RespuestaRapidaService{
+		config:        cfg,
+		respuestaRepo: respuestaRepo,
+	}
+}
+
+// SembrarPorDefecto carga las respuestas básicas si todavía no hay ninguna
+func (s *RespuestaRapidaService) SembrarPorDefecto() {
+	cantidad, err := s.respuestaRepo.Contar()
+	if err != nil || cantidad > 0 {
+		return
+	}
+
+	restaurante := s.config.RestaurantName
+	porDefecto := []*models.RespuestaRapida{
+		{Atajo: "saludo", Titulo: "Saludo", Contenido: fmt.Sprintf("¡Hola {nombre}! 👋 Gracias por escribir a %s. ¿En qué te podemos ayudar?", restaurante)},
+		{Atajo: "menu", Titulo: "Link al menú", Contenido: fmt.Sprintf("Acá tenés nuestro menú: %s/menu 🧀", s.config.PublicBaseURL)},
+		{Atajo: "horarios", Titulo: "Dirección y horarios", Contenido: fmt.Sprintf("Estamos en %s. Abrimos todos los días de 12 a 24 hs.", s.config.Location)},
+		{Atajo: "listo", Titulo: "Pedido listo", Contenido: "¡{nombre}, tu pedido está listo para retirar! 🧀"},
+	}
+
+	for _, respuesta := range porDefecto {
+		respuesta.Activa = true
+		if err := s.respuestaRepo.Crear(respuesta); err != nil {
+			log.Printf("⚠️  No se pudo crear la respuesta rápida %q: %v", respuesta.Atajo, err)
+		}
+	}
+	log.Printf("💬 Respuestas rápidas por defecto creadas")
+}
+
+// Listar obtiene las respuestas rápidas con sus estadísticas de uso
+func (s *RespuestaRapidaService) Listar(soloActivas bool) ([]*models.RespuestaRapida, error) {
+	return s.respuestaRepo.Listar(soloActivas)
+}
+
+// Crear agrega una nueva respuesta rápida
+func (s *RespuestaRapidaService) Crear(req models.GuardarRespuestaRapidaRequest, usuarioID uint) (*models.RespuestaRapida, error) {
+	respuesta := &models.RespuestaRapida{
+		Atajo:     strings.ToLower(strings.TrimSpace(req.Atajo)),
+		Titulo:    strings.TrimSpace(req.Titulo),
+		Contenido: req.Contenido,
+		Activa:    req.Activa == nil || *req.Activa,
+		CreatedBy: usuarioID,
+	}
+	if err := s.respuestaRepo.Crear(respuesta); err != nil {
+		return nil, err
+	}
+	return respuesta, nil
+}
+
+// Actualizar modifica una respuesta rápida existente
+func (s *RespuestaRapidaService) Actualizar(id uint, req models.GuardarRespuestaRapidaRequest) (*models.RespuestaRapida, error) {
+	respuesta, err := s.respuestaRepo.BuscarPorID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	respuesta.Atajo = strings.ToLower(strings.TrimSpace(req.Atajo))
+	respuesta.Titulo = strings.TrimSpace(req.Titulo)
+	respuesta.Contenido = req.Contenido
+	if req.Activa != nil {
+		respuesta.Activa = *req.Activa
+	}
+
+	if err := s.respuestaRepo.Actualizar(respuesta); err != nil {
+		return nil, err
+	}
+	return respuesta, nil
+}
+
+// Eliminar borra una respuesta rápida
+func (s *RespuestaRapidaService) Eliminar(id uint) error {
+	return s.respuestaRepo.Eliminar(id)
+}
+
+// Preparar arma el texto de la respuesta para el cliente
+func (s *RespuestaRapidaService) Preparar(id uint, nombreCliente string) (string, error) {
+	respuesta, err := s.respuestaRepo.BuscarPorID(id)
+	if err != nil {
+		return "", err
+	}
+	if !respuesta.Activa {
+		return "", fmt.Errorf("la respuesta rápida %q está desactivada", respuesta.Atajo)
+	}
+
+	return reemplazarNombre(respuesta.Contenido, nombreCliente), nil
+}
+
+// RegistrarUso suma un uso a la respuesta una vez enviada
+func (s *RespuestaRapidaService) RegistrarUso(id uint) {
+	if err := s.respuestaRepo.RegistrarUso(id); err != nil {
+		log.Printf("⚠️  %v", err)
+	}
+}
+
+// reemplazarNombre completa {nombre}; si no se conoce el nombre lo quita sin dejar
+// espacios o comas sueltas ("¡Hola {nombre}!" -> "¡Hola!")
+func reemplazarNombre(contenido, nombre string) string {
+	if nombre != "" {
+		return strings.ReplaceAll(contenido, "{nombre}", nombre)
+	}
+	return strings.NewReplacer(" {nombre}", "", "{nombre}, ", "", "{nombre}", "").Replace(contenido)
+}
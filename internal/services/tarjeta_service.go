@@ -0,0 +1,151 @@
+package services
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"image/color"
+	"math"
+	"strconv"
+	"strings"
+
+	"CheeseHouse/internal/config"
+	"CheeseHouse/internal/i18n"
+	"CheeseHouse/internal/models"
+	"CheeseHouse/internal/tarjeta"
+)
+
+// ErrTarjetaInvalida el link de la tarjeta está adulterado o mal formado
+var ErrTarjetaInvalida = errors.New("tarjeta para compartir inválida")
+
+// Colores de respaldo si el branding tiene un color mal cargado
+var (
+	fondoTarjetaPorDefecto = color.RGBA{R: 0xF4, G: 0xB4, B: 0x00, A: 0xff}
+	tintaTarjetaPorDefecto = color.RGBA{R: 0x3E, G: 0x27, B: 0x23, A: 0xff}
+)
+
+// datosTarjeta lo que viaja firmado en el link: solo tiempos y resultado, nunca el
+// cliente ni el código del voucher, que cualquiera podría canjear si se comparte
+type datosTarjeta struct {
+	obtenido float64
+	objetivo float64
+	gano     bool
+	locale   string
+}
+
+// TarjetaService genera las imágenes para compartir el resultado de una partida. La
+// imagen se arma a pedido desde los datos firmados en el link, sin guardar nada
+type TarjetaService struct {
+	config   *config.Config
+	branding *BrandingService
+	secret   []byte
+}
+
+// NewTarjetaService crea una nueva instancia del servicio de tarjetas para compartir
+func NewTarjetaService(cfg *config.Config, branding *BrandingService) *TarjetaService {
+	return &TarjetaService{
+		config:   cfg,
+		branding: branding,
+		secret:   []byte(cfg.LinkSigningSecret),
+	}
+}
+
+// URL arma el link firmado de la imagen para compartir el resultado
+func (t *TarjetaService) URL(resultado models.Resultado, gano bool, locale string) string {
+	ganoTexto := "0"
+	if gano {
+		ganoTexto = "1"
+	}
+	payload := strings.Join([]string{
+		formatearSegundos(resultado.TiempoObtenido),
+		formatearSegundos(resultado.TiempoObjetivo),
+		ganoTexto,
+		i18n.Detectar(locale, "", t.config.DefaultLanguage),
+	}, "|")
+
+	token := base64.RawURLEncoding.EncodeToString([]byte(payload)) + "." + t.firmar(payload)
+	return strings.TrimRight(t.config.PublicBaseURL, "/") + "/api/game/share/" + token + ".png"
+}
+
+// Generar valida el token del link y dibuja la tarjeta en el formato pedido
+func (t *TarjetaService) Generar(token string, formato tarjeta.Formato) ([]byte, error) {
+	datos, err := t.leer(strings.TrimSuffix(token, ".png"))
+	if err != nil {
+		return nil, err
+	}
+
+	textos := i18n.Textos(datos.locale)
+	clave := "tarjeta_casi"
+	if datos.gano {
+		clave = "tarjeta_gane"
+	}
+	reemplazos := strings.NewReplacer(
+		"{tiempo}", formatearSegundos(datos.obtenido),
+		"{objetivo}", formatearSegundos(datos.objetivo),
+		"{restaurante}", t.config.RestaurantName,
+	)
+
+	sitio := t.config.PublicBaseURL
+	if _, sinEsquema, ok := strings.Cut(sitio, "://"); ok {
+		sitio = sinEsquema
+	}
+
+	branding := t.branding.Obtener()
+	lado := formato.Lado()
+	return tarjeta.PNG(formato,
+		tarjeta.ParsearColor(branding.ColorPrimario, fondoTarjetaPorDefecto),
+		tarjeta.ParsearColor(branding.ColorSecundario, tintaTarjetaPorDefecto),
+		[]tarjeta.Bloque{
+			{Texto: reemplazos.Replace(textos[clave]), Escala: lado / 70},
+			{Texto: reemplazos.Replace(textos["tarjeta_objetivo"]), Escala: lado / 130},
+			{Texto: textos["tarjeta_invitacion"], Escala: lado / 100},
+			{Texto: strings.TrimRight(sitio, "/"), Escala: lado / 180},
+		})
+}
+
+// leer verifica la firma del token y decodifica sus datos
+func (t *TarjetaService) leer(token string) (datosTarjeta, error) {
+	codificado, firma, ok := strings.Cut(token, ".")
+	if !ok {
+		return datosTarjeta{}, ErrTarjetaInvalida
+	}
+	bytesPayload, err := base64.RawURLEncoding.DecodeString(codificado)
+	if err != nil {
+		return datosTarjeta{}, ErrTarjetaInvalida
+	}
+	payload := string(bytesPayload)
+	if !hmac.Equal([]byte(firma), []byte(t.firmar(payload))) {
+		return datosTarjeta{}, ErrTarjetaInvalida
+	}
+
+	partes := strings.Split(payload, "|")
+	if len(partes) != 4 {
+		return datosTarjeta{}, ErrTarjetaInvalida
+	}
+	obtenido, errObtenido := strconv.ParseFloat(partes[0], 64)
+	objetivo, errObjetivo := strconv.ParseFloat(partes[1], 64)
+	if errObtenido != nil || errObjetivo != nil {
+		return datosTarjeta{}, ErrTarjetaInvalida
+	}
+
+	return datosTarjeta{
+		obtenido: obtenido,
+		objetivo: objetivo,
+		gano:     partes[2] == "1",
+		locale:   partes[3],
+	}, nil
+}
+
+// firmar calcula el HMAC-SHA256 del payload
+func (t *TarjetaService) firmar(payload string) string {
+	mac := hmac.New(sha256.New, t.secret)
+	fmt.Fprintf(mac, "tarjeta:%s", payload)
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// formatearSegundos muestra los tiempos con dos decimales, como el cronómetro del juego
+func formatearSegundos(segundos float64) string {
+	return strconv.FormatFloat(math.Round(segundos*100)/100, 'f', 2, 64)
+}
@@ -0,0 +1,156 @@
+package services
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"sync"
+	"time"
+
+	"CheeseHouse/internal/config"
+	"CheeseHouse/internal/models"
+	"CheeseHouse/internal/repository"
+)
+
+// TipoClienteService es el único lugar donde se decide si un cliente es nuevo, ocasional
+// o frecuente. Cada partida suma 1 al puntaje de actividad y ese valor se reduce a la
+// mitad cada TIER_HALF_LIFE_DAYS días, así que un cliente que dejó de venir baja de tipo.
+// El tipo se guarda en el cliente: se actualiza en cada partida y se recalcula completo
+// todas las noches desde el historial de partidas
+type TipoClienteService struct {
+	config      *config.Config
+	clienteRepo *repository.ClienteRepository
+
+	// Evita dos recálculos simultáneos
+	mu sync.Mutex
+}
+
+// NewTipoClienteService crea una nueva instancia del servicio de tipos de cliente
+func NewTipoClienteService(cfg *config.Config, clienteRepo *repository.ClienteRepository) *TipoClienteService {
+	return &TipoClienteService{
+		config:      cfg,
+		clienteRepo: clienteRepo,
+	}
+}
+
+// Clasificar traduce un puntaje de actividad a tipo de cliente
+func (s *TipoClienteService) Clasificar(puntaje float64) string {
+	switch {
+	case puntaje > s.config.ClientTiers.FrequentScore:
+		return models.TipoClienteFrecuente
+	case puntaje > s.config.ClientTiers.OccasionalScore:
+		return models.TipoClienteOcasional
+	default:
+		return models.TipoClienteNuevo
+	}
+}
+
+// RegistrarPartida actualiza en memoria el puntaje y el tipo del cliente por una partida
+// nueva; lo persiste quien guarda el cliente
+func (s *TipoClienteService) RegistrarPartida(cliente *models.Cliente, ahora time.Time) {
+	puntaje := cliente.PuntajeActividad
+	if cliente.PuntajeCalculadoEn != nil {
+		puntaje = s.decaer(puntaje, ahora.Sub(*cliente.PuntajeCalculadoEn))
+	}
+
+	cliente.PuntajeActividad = redondearPuntaje(puntaje + 1)
+	cliente.PuntajeCalculadoEn = &ahora
+	cliente.TipoCliente = s.Clasificar(cliente.PuntajeActividad)
+}
+
+// Recalcular vuelve a calcular el puntaje de todos los clientes desde el historial de
+// partidas y guarda los que cambiaron de tipo o de puntaje
+func (s *TipoClienteService) Recalcular() (*models.ResultadoRecalculoTipos, error) {
+	if !s.mu.TryLock() {
+		return nil, fmt.Errorf("ya hay un recálculo de tipos de cliente en curso")
+	}
+	defer s.mu.Unlock()
+
+	inicio := time.Now()
+	puntajes, err := s.clienteRepo.PuntajesActividad(s.config.ClientTiers.RecencyHalfLifeDays, inicio)
+	if err != nil {
+		return nil, err
+	}
+	clientes, err := s.clienteRepo.ListarTipos()
+	if err != nil {
+		return nil, fmt.Errorf("error listando clientes: %w", err)
+	}
+
+	resultado := &models.ResultadoRecalculoTipos{
+		Clientes: len(clientes),
+		PorTipo:  make(map[string]int),
+	}
+	for _, cliente := range clientes {
+		puntaje := redondearPuntaje(puntajes[cliente.ID])
+		tipo := s.Clasificar(puntaje)
+		resultado.PorTipo[tipo]++
+
+		if tipo == cliente.TipoCliente && puntaje == cliente.PuntajeActividad {
+			continue
+		}
+		if err := s.clienteRepo.ActualizarTipo(cliente.ID, tipo, puntaje, inicio); err != nil {
+			return resultado, fmt.Errorf("error guardando tipo del cliente %d: %w", cliente.ID, err)
+		}
+		if tipo != cliente.TipoCliente {
+			resultado.Cambios++
+		}
+	}
+	resultado.Duracion = time.Since(inicio).Round(time.Millisecond).String()
+
+	log.Printf("🏷️  Tipos de cliente recalculados: %d clientes, %d cambios (%d nuevos, %d ocasionales, %d frecuentes) en %s",
+		resultado.Clientes, resultado.Cambios,
+		resultado.PorTipo[models.TipoClienteNuevo], resultado.PorTipo[models.TipoClienteOcasional],
+		resultado.PorTipo[models.TipoClienteFrecuente], resultado.Duracion)
+	return resultado, nil
+}
+
+// IniciarProgramador recalcula al arrancar (por si cambiaron los umbrales) y después
+// todos los días a TIER_RECALC_TIME en la zona horaria del restaurante. Con varias
+// instancias el recálculo al arrancar lo hace la líder y el diario una sola vez
+func (s *TipoClienteService) IniciarProgramador(coordinacion *CoordinacionService) {
+	go func() {
+		if coordinacion.EsLider() {
+			s.recalcularProgramado()
+		}
+		for {
+			proximo := s.proximoRecalculo(time.Now())
+			time.Sleep(time.Until(proximo))
+			if coordinacion.TomarTurno("tipos_cliente", proximo) {
+				s.recalcularProgramado()
+			}
+		}
+	}()
+}
+
+// recalcularProgramado recalcula desde el programador, donde el error solo se registra
+func (s *TipoClienteService) recalcularProgramado() {
+	if _, err := s.Recalcular(); err != nil {
+		log.Printf("⚠️  Error recalculando tipos de cliente: %v", err)
+	}
+}
+
+// proximoRecalculo próxima ocurrencia de la hora de recálculo después de ahora
+func (s *TipoClienteService) proximoRecalculo(ahora time.Time) time.Time {
+	local := ahora.In(s.config.GetLocation())
+	minutos := s.config.ClientTiers.RecalcTime
+	proximo := time.Date(local.Year(), local.Month(), local.Day(), minutos/60, minutos%60, 0, 0, local.Location())
+	if !proximo.After(local) {
+		proximo = proximo.AddDate(0, 0, 1)
+	}
+	return proximo
+}
+
+// decaer reduce el puntaje según el tiempo transcurrido y la media vida configurada
+func (s *TipoClienteService) decaer(puntaje float64, transcurrido time.Duration) float64 {
+	mediaVida := s.config.ClientTiers.RecencyHalfLifeDays
+	if mediaVida <= 0 || transcurrido <= 0 {
+		return puntaje
+	}
+	return puntaje * math.Pow(0.5, transcurrido.Hours()/(24*float64(mediaVida)))
+}
+
+// redondearPuntaje redondea a la precisión de la columna (3 decimales) para que comparar
+// con el valor guardado no detecte cambios inexistentes
+func redondearPuntaje(puntaje float64) float64 {
+	return math.Round(puntaje*1000) / 1000
+}
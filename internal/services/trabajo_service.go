@@ -0,0 +1,281 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"CheeseHouse/internal/models"
+)
+
+const (
+	// retencionTrabajos cuánto se conserva un trabajo terminado para consultarlo
+	retencionTrabajos = 24 * time.Hour
+	// maxTrabajos tope de trabajos en memoria; se descartan primero los terminados más viejos
+	maxTrabajos = 200
+	// maxMuestraErrores cuántos errores por ítem se guardan de ejemplo en cada trabajo
+	maxMuestraErrores = 10
+)
+
+var (
+	// ErrTrabajoNoEncontrado el trabajo no existe o ya se descartó
+	ErrTrabajoNoEncontrado = errors.New("trabajo no encontrado")
+	// ErrTrabajoTerminado el trabajo ya terminó y no se puede cancelar
+	ErrTrabajoTerminado = errors.New("el trabajo ya terminó")
+	// ErrTrabajoNoCancelable el trabajo no admite cancelación
+	ErrTrabajoNoCancelable = errors.New("el trabajo no se puede cancelar")
+)
+
+// trabajoEnCurso estado interno de un trabajo; el models.Trabajo se copia al consultarlo
+type trabajoEnCurso struct {
+	datos    models.Trabajo
+	cancelar context.CancelFunc
+}
+
+// TrabajoService registra las operaciones largas que corren en segundo plano para
+// consultar su avance, los errores por ítem y cancelarlas desde el panel
+type TrabajoService struct {
+	mu       sync.Mutex
+	trabajos map[string]*trabajoEnCurso
+	enCurso  sync.WaitGroup
+}
+
+// NewTrabajoService crea una nueva instancia del servicio de trabajos
+func NewTrabajoService() *TrabajoService {
+	return &TrabajoService{
+		trabajos: make(map[string]*trabajoEnCurso),
+	}
+}
+
+// AvanceTrabajo es lo que recibe la función del trabajo para informar su avance
+type AvanceTrabajo struct {
+	servicio *TrabajoService
+	id       string
+	ctx      context.Context
+}
+
+// Iniciar registra el trabajo y corre ejecutar en segundo plano. total puede ser 0 si
+// todavía no se conoce; se fija después con FijarTotal. Si cancelable es false, Cancelar
+// lo rechaza. Retorna una copia del trabajo recién creado
+func (s *TrabajoService) Iniciar(tipo, descripcion string, total int, cancelable bool, ejecutar func(avance *AvanceTrabajo) error) models.Trabajo {
+	ctx, cancelar := context.WithCancel(context.Background())
+	trabajo := &trabajoEnCurso{
+		datos: models.Trabajo{
+			ID:             generarIDTrabajo(),
+			Tipo:           tipo,
+			Descripcion:    descripcion,
+			Estado:         "en_curso",
+			Total:          total,
+			MuestraErrores: []models.ErrorTrabajo{},
+			Cancelable:     cancelable,
+			IniciadoEn:     time.Now(),
+		},
+		cancelar: cancelar,
+	}
+
+	s.mu.Lock()
+	s.descartarViejos()
+	s.trabajos[trabajo.datos.ID] = trabajo
+	copia := trabajo.datos
+	s.mu.Unlock()
+
+	avance := &AvanceTrabajo{servicio: s, id: copia.ID, ctx: ctx}
+	s.enCurso.Add(1)
+	go func() {
+		defer s.enCurso.Done()
+		defer cancelar()
+		err := ejecutar(avance)
+		s.finalizar(copia.ID, ctx.Err() != nil, err)
+	}()
+
+	log.Printf("⏳ Trabajo %s iniciado: %s", copia.ID, descripcion)
+	return copia
+}
+
+// Obtener retorna una copia del trabajo
+func (s *TrabajoService) Obtener(id string) (*models.Trabajo, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	trabajo, ok := s.trabajos[id]
+	if !ok {
+		return nil, ErrTrabajoNoEncontrado
+	}
+	copia := copiarTrabajo(trabajo.datos)
+	return &copia, nil
+}
+
+// Listar retorna los trabajos en memoria, los más recientes primero
+func (s *TrabajoService) Listar() []models.Trabajo {
+	s.mu.Lock()
+	lista := make([]models.Trabajo, 0, len(s.trabajos))
+	for _, trabajo := range s.trabajos {
+		lista = append(lista, copiarTrabajo(trabajo.datos))
+	}
+	s.mu.Unlock()
+
+	sort.Slice(lista, func(i, j int) bool { return lista[i].IniciadoEn.After(lista[j].IniciadoEn) })
+	return lista
+}
+
+// Cancelar pide al trabajo que se detenga. El trabajo termina el ítem en curso y queda
+// como cancelado cuando su función retorna
+func (s *TrabajoService) Cancelar(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	trabajo, ok := s.trabajos[id]
+	switch {
+	case !ok:
+		return ErrTrabajoNoEncontrado
+	case trabajo.datos.Estado != "en_curso":
+		return ErrTrabajoTerminado
+	case !trabajo.datos.Cancelable:
+		return ErrTrabajoNoCancelable
+	}
+	trabajo.cancelar()
+	log.Printf("🛑 Cancelación pedida para el trabajo %s", id)
+	return nil
+}
+
+// Detener espera los trabajos en curso hasta que venza ctx; al vencer cancela los que
+// quedan para que dejen su estado consistente
+func (s *TrabajoService) Detener(ctx context.Context) error {
+	listo := make(chan struct{})
+	go func() {
+		s.enCurso.Wait()
+		close(listo)
+	}()
+
+	select {
+	case <-listo:
+		return nil
+	case <-ctx.Done():
+		s.mu.Lock()
+		for _, trabajo := range s.trabajos {
+			trabajo.cancelar()
+		}
+		s.mu.Unlock()
+		return fmt.Errorf("quedaron trabajos en segundo plano sin terminar: %w", ctx.Err())
+	}
+}
+
+// finalizar deja el trabajo en su estado final
+func (s *TrabajoService) finalizar(id string, cancelado bool, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	trabajo, ok := s.trabajos[id]
+	if !ok {
+		return
+	}
+	ahora := time.Now()
+	datos := &trabajo.datos
+	datos.FinalizadoEn = &ahora
+	switch {
+	case err != nil:
+		datos.Estado = "fallido"
+		datos.Error = err.Error()
+		log.Printf("❌ Trabajo %s fallido: %v", id, err)
+	case cancelado:
+		datos.Estado = "cancelado"
+		log.Printf("🛑 Trabajo %s cancelado: %d de %d procesados", id, datos.Procesados, datos.Total)
+	default:
+		datos.Estado = "completado"
+		datos.Porcentaje = 100
+		log.Printf("✅ Trabajo %s completado: %d procesados, %d con error", id, datos.Procesados, datos.Errores)
+	}
+}
+
+// descartarViejos quita los trabajos terminados fuera de la retención y, si aun así se
+// pasa del tope, los terminados más viejos. Se llama con mu tomado
+func (s *TrabajoService) descartarViejos() {
+	limite := time.Now().Add(-retencionTrabajos)
+	terminados := make([]*trabajoEnCurso, 0)
+	for id, trabajo := range s.trabajos {
+		if trabajo.datos.FinalizadoEn == nil {
+			continue
+		}
+		if trabajo.datos.FinalizadoEn.Before(limite) {
+			delete(s.trabajos, id)
+			continue
+		}
+		terminados = append(terminados, trabajo)
+	}
+
+	sobrantes := len(s.trabajos) - maxTrabajos + 1
+	if sobrantes <= 0 {
+		return
+	}
+	sort.Slice(terminados, func(i, j int) bool { return terminados[i].datos.FinalizadoEn.Before(*terminados[j].datos.FinalizadoEn) })
+	for _, trabajo := range terminados[:min(sobrantes, len(terminados))] {
+		delete(s.trabajos, trabajo.datos.ID)
+	}
+}
+
+// FijarTotal fija la cantidad de ítems cuando no se conocía al iniciar
+func (a *AvanceTrabajo) FijarTotal(total int) {
+	a.actualizar(func(datos *models.Trabajo) { datos.Total = total })
+}
+
+// Avanzar suma n ítems procesados sin error
+func (a *AvanceTrabajo) Avanzar(n int) {
+	a.actualizar(func(datos *models.Trabajo) { datos.Procesados += n })
+}
+
+// Fallo suma un ítem procesado con error y lo guarda de ejemplo si todavía hay lugar
+func (a *AvanceTrabajo) Fallo(item string, err error) {
+	a.actualizar(func(datos *models.Trabajo) {
+		datos.Procesados++
+		datos.Errores++
+		if len(datos.MuestraErrores) < maxMuestraErrores {
+			datos.MuestraErrores = append(datos.MuestraErrores, models.ErrorTrabajo{Item: item, Error: err.Error()})
+		}
+	})
+}
+
+// Resultado guarda el resumen que se devuelve al consultar el trabajo
+func (a *AvanceTrabajo) Resultado(resultado interface{}) {
+	a.actualizar(func(datos *models.Trabajo) { datos.Resultado = resultado })
+}
+
+// Cancelado indica si se pidió cancelar el trabajo; la función lo consulta entre ítems
+func (a *AvanceTrabajo) Cancelado() bool {
+	return a.ctx.Err() != nil
+}
+
+// actualizar aplica el cambio y recalcula el porcentaje
+func (a *AvanceTrabajo) actualizar(cambio func(datos *models.Trabajo)) {
+	a.servicio.mu.Lock()
+	defer a.servicio.mu.Unlock()
+
+	trabajo, ok := a.servicio.trabajos[a.id]
+	if !ok {
+		return
+	}
+	cambio(&trabajo.datos)
+	if trabajo.datos.Total > 0 {
+		trabajo.datos.Porcentaje = min(trabajo.datos.Procesados*100/trabajo.datos.Total, 100)
+	}
+}
+
+// copiarTrabajo copia el trabajo para devolverlo fuera del lock
+func copiarTrabajo(datos models.Trabajo) models.Trabajo {
+	datos.MuestraErrores = append([]models.ErrorTrabajo(nil), datos.MuestraErrores...)
+	return datos
+}
+
+// generarIDTrabajo genera un identificador aleatorio para el trabajo
+func generarIDTrabajo() string {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return fmt.Sprintf("%x", time.Now().UnixNano())
+	}
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,119 @@
+package services
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"strings"
+	"time"
+
+	"CheeseHouse/internal/repository"
+)
+
+const (
+	codigoVerificacionTTL         = 10 * time.Minute
+	codigoVerificacionMaxIntentos = 5
+)
+
+// codigoVerificacion código OTP pendiente para un teléfono, guardado en el estado
+// compartido para que lo valide cualquier instancia
+type codigoVerificacion struct {
+	Codigo   string    `json:"codigo"`
+	Expira   time.Time `json:"expira"`
+	Intentos int       `json:"intentos"`
+}
+
+// errCodigoInvalido el código no existe, venció, no coincide o se bloqueó por intentos
+var errCodigoInvalido = errors.New("código de verificación inválido o vencido")
+
+// VerificacionService verifica la identidad de quien consulta datos de un cliente,
+// ya sea por coincidencia de apellido o por un código de un solo uso enviado por WhatsApp
+type VerificacionService struct {
+	whatsappService *WhatsAppService
+	estado          repository.EstadoCompartidoRepository
+}
+
+// NewVerificacionService crea una nueva instancia del servicio de verificación
+func NewVerificacionService(whatsappService *WhatsAppService, estado repository.EstadoCompartidoRepository) *VerificacionService {
+	return &VerificacionService{
+		whatsappService: whatsappService,
+		estado:          estado,
+	}
+}
+
+// EnviarCodigo genera un código de 6 dígitos y lo envía por WhatsApp al teléfono
+func (v *VerificacionService) EnviarCodigo(telefono string) error {
+	n, err := rand.Int(rand.Reader, big.NewInt(1000000))
+	if err != nil {
+		return fmt.Errorf("error generando código: %w", err)
+	}
+	codigo := fmt.Sprintf("%06d", n.Int64())
+
+	// Un código nuevo reemplaza al anterior y reinicia los intentos
+	pendiente := codigoVerificacion{Codigo: codigo, Expira: time.Now().Add(codigoVerificacionTTL)}
+	valor, _ := json.Marshal(pendiente)
+	err = repository.ActualizarCompartido(v.estado, "verificacion:"+telefono, func(string, bool) (string, time.Time, error) {
+		return string(valor), pendiente.Expira, nil
+	})
+	if err != nil {
+		return fmt.Errorf("error guardando código de verificación: %w", err)
+	}
+
+	return v.whatsappService.EnviarCodigoVerificacion(telefono, codigo)
+}
+
+// ValidarCodigo verifica (y consume) el código enviado a un teléfono
+func (v *VerificacionService) ValidarCodigo(telefono, codigo string) error {
+	clave := "verificacion:" + telefono
+	var correcto bool
+	err := repository.ActualizarCompartido(v.estado, clave, func(valor string, existe bool) (string, time.Time, error) {
+		var pendiente codigoVerificacion
+		if !existe || json.Unmarshal([]byte(valor), &pendiente) != nil {
+			return "", time.Time{}, errCodigoInvalido
+		}
+
+		pendiente.Intentos++
+		correcto = pendiente.Codigo == codigo
+		if !correcto && pendiente.Intentos >= codigoVerificacionMaxIntentos {
+			log.Printf("🔒 Código de verificación bloqueado para %s tras %d intentos", telefono, pendiente.Intentos)
+		}
+		if correcto || pendiente.Intentos >= codigoVerificacionMaxIntentos {
+			// Consumido o bloqueado: vence en el acto y se borra abajo
+			return "", time.Now(), nil
+		}
+		nuevo, _ := json.Marshal(pendiente)
+		return string(nuevo), pendiente.Expira, nil
+	})
+	if err != nil {
+		if !errors.Is(err, errCodigoInvalido) {
+			log.Printf("⚠️  Error validando código de verificación: %v", err)
+		}
+		return errCodigoInvalido
+	}
+
+	if !correcto {
+		return errCodigoInvalido
+	}
+	if err := v.estado.Eliminar(clave); err != nil {
+		log.Printf("⚠️  No se pudo borrar el código de verificación usado: %v", err)
+	}
+	return nil
+}
+
+// ApellidoCoincide compara apellidos ignorando mayúsculas, acentos y espacios
+func (v *VerificacionService) ApellidoCoincide(registrado, informado string) bool {
+	informado = normalizarApellido(informado)
+	return informado != "" && normalizarApellido(registrado) == informado
+}
+
+// normalizarApellido pasa a minúsculas y sin acentos para comparar apellidos
+func normalizarApellido(apellido string) string {
+	reemplazos := strings.NewReplacer(
+		"á", "a", "é", "e", "í", "i", "ó", "o", "ú", "u", "ü", "u", "ñ", "n",
+		" ", "", "-", "", "'", "",
+	)
+	return reemplazos.Replace(strings.ToLower(strings.TrimSpace(apellido)))
+}
@@ -0,0 +1,184 @@
+package services
+
+import (
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"CheeseHouse/internal/config"
+	"CheeseHouse/internal/models"
+	"CheeseHouse/internal/repository"
+)
+
+// VigenciaService decide cuántos días vale cada voucher de partida según su tipo y el día
+// de la semana de emisión (ej. victorias del fin de semana 7 días, derrotas entre semana 30)
+type VigenciaService struct {
+	config       *config.Config
+	vigenciaRepo repository.VigenciaRepository
+
+	mu     sync.RWMutex
+	reglas []*models.ReglaVigencia // nil = sin cargar
+}
+
+// NewVigenciaService crea una nueva instancia del servicio de vigencias
+func NewVigenciaService(cfg *config.Config, vigenciaRepo repository.VigenciaRepository) *VigenciaService {
+	return &VigenciaService{
+		config:       cfg,
+		vigenciaRepo: vigenciaRepo,
+	}
+}
+
+// Dias retorna los días de validez de un voucher de ese tipo emitido en ese momento.
+// Una regla con tipo y día le gana a una con solo uno de los dos; sin reglas que
+// coincidan se usa VOUCHER_VALIDITY_DAYS
+func (s *VigenciaService) Dias(tipo string, emision time.Time) int {
+	dia := int(emision.In(s.config.GetLocation()).Weekday())
+
+	dias, mejor := s.PorDefecto(), 0
+	for _, regla := range s.reglasCargadas() {
+		if regla.Tipo != "cualquiera" && regla.Tipo != tipo {
+			continue
+		}
+		if regla.DiaSemana != nil && *regla.DiaSemana != dia {
+			continue
+		}
+		if puntaje := especificidadVigencia(regla); puntaje > mejor {
+			dias, mejor = regla.Dias, puntaje
+		}
+	}
+	return dias
+}
+
+// PorDefecto días de validez cuando ninguna regla coincide
+func (s *VigenciaService) PorDefecto() int {
+	return s.config.Game.VoucherValidityDays
+}
+
+// Listar obtiene las reglas configuradas
+func (s *VigenciaService) Listar() ([]*models.ReglaVigencia, error) {
+	return s.vigenciaRepo.Listar()
+}
+
+// Crear agrega una regla; no puede haber dos para el mismo tipo y día
+func (s *VigenciaService) Crear(req models.GuardarReglaVigenciaRequest, usuarioID uint) (*models.ReglaVigencia, error) {
+	if err := s.validarDuplicada(0, req); err != nil {
+		return nil, err
+	}
+
+	regla := &models.ReglaVigencia{
+		Tipo:      req.Tipo,
+		DiaSemana: req.DiaSemana,
+		Dias:      req.Dias,
+		CreadoPor: usuarioID,
+	}
+	if err := s.vigenciaRepo.Crear(regla); err != nil {
+		return nil, err
+	}
+	s.invalidar()
+
+	log.Printf("📅 Usuario %d creó la regla de vigencia %s: %d días", usuarioID, descripcionVigencia(regla), regla.Dias)
+	return regla, nil
+}
+
+// Actualizar reemplaza el tipo, el día o los días de validez de una regla
+func (s *VigenciaService) Actualizar(id uint, req models.GuardarReglaVigenciaRequest, usuarioID uint) (*models.ReglaVigencia, error) {
+	regla, err := s.vigenciaRepo.BuscarPorID(id)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.validarDuplicada(id, req); err != nil {
+		return nil, err
+	}
+
+	regla.Tipo = req.Tipo
+	regla.DiaSemana = req.DiaSemana
+	regla.Dias = req.Dias
+	if err := s.vigenciaRepo.Actualizar(regla); err != nil {
+		return nil, err
+	}
+	s.invalidar()
+
+	log.Printf("📅 Usuario %d actualizó la regla de vigencia #%d (%s: %d días)", usuarioID, regla.ID, descripcionVigencia(regla), regla.Dias)
+	return regla, nil
+}
+
+// Eliminar borra una regla
+func (s *VigenciaService) Eliminar(id uint, usuarioID uint) error {
+	if err := s.vigenciaRepo.Eliminar(id); err != nil {
+		return err
+	}
+	s.invalidar()
+
+	log.Printf("📅 Usuario %d eliminó la regla de vigencia #%d", usuarioID, id)
+	return nil
+}
+
+// validarDuplicada rechaza una segunda regla con el mismo tipo y día
+func (s *VigenciaService) validarDuplicada(id uint, req models.GuardarReglaVigenciaRequest) error {
+	reglas, err := s.vigenciaRepo.Listar()
+	if err != nil {
+		return err
+	}
+	nueva := &models.ReglaVigencia{Tipo: req.Tipo, DiaSemana: req.DiaSemana}
+	for _, regla := range reglas {
+		if regla.ID != id && descripcionVigencia(regla) == descripcionVigencia(nueva) {
+			return fmt.Errorf("ya existe una regla para %s (#%d)", descripcionVigencia(regla), regla.ID)
+		}
+	}
+	return nil
+}
+
+// reglasCargadas retorna las reglas cacheadas hasta el próximo cambio. Si la base no
+// responde se usa la vigencia por defecto
+func (s *VigenciaService) reglasCargadas() []*models.ReglaVigencia {
+	s.mu.RLock()
+	reglas := s.reglas
+	s.mu.RUnlock()
+	if reglas != nil {
+		return reglas
+	}
+
+	reglas, err := s.vigenciaRepo.Listar()
+	if err != nil {
+		log.Printf("⚠️  Error obteniendo reglas de vigencia: %v", err)
+		return nil
+	}
+	if reglas == nil {
+		reglas = []*models.ReglaVigencia{}
+	}
+
+	s.mu.Lock()
+	s.reglas = reglas
+	s.mu.Unlock()
+	return reglas
+}
+
+// invalidar descarta las reglas cacheadas
+func (s *VigenciaService) invalidar() {
+	s.mu.Lock()
+	s.reglas = nil
+	s.mu.Unlock()
+}
+
+// especificidadVigencia cuán precisa es una regla: con tipo y día 4, solo tipo 3, solo día 2
+// y sin ninguno 1. Cualquier regla le gana a la vigencia de la configuración (0)
+func especificidadVigencia(regla *models.ReglaVigencia) int {
+	puntaje := 1
+	if regla.Tipo != "cualquiera" {
+		puntaje += 2
+	}
+	if regla.DiaSemana != nil {
+		puntaje++
+	}
+	return puntaje
+}
+
+// descripcionVigencia tipo y día de la regla para los logs y los errores
+func descripcionVigencia(regla *models.ReglaVigencia) string {
+	dia := "todos los días"
+	if regla.DiaSemana != nil {
+		dia = diasSemana[*regla.DiaSemana]
+	}
+	return regla.Tipo + " / " + dia
+}
@@ -0,0 +1,145 @@
+package services
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"CheeseHouse/internal/config"
+	"CheeseHouse/internal/models"
+	"CheeseHouse/internal/repository"
+)
+
+const (
+	voucherPantallaTTL         = 10 * time.Minute
+	voucherPantallaMaxIntentos = 5
+)
+
+// ErrVoucherPantallaInvalido token vencido, ya usado o bloqueado por intentos
+var ErrVoucherPantallaInvalido = errors.New("el voucher ya no se puede mostrar, consultá en caja")
+
+// voucherPantalla voucher pendiente de revelar en la pantalla del juego, guardado en el
+// estado compartido: la revelación puede llegar a otra instancia que la partida
+type voucherPantalla struct {
+	Codigo   string    `json:"codigo"`
+	Digitos  string    `json:"digitos"` // Últimos 4 dígitos del teléfono
+	Idioma   string    `json:"idioma"`
+	Expira   time.Time `json:"expira"`
+	Intentos int       `json:"intentos"`
+}
+
+// VoucherPantallaService muestra el voucher en la pantalla del juego a quien no usa
+// WhatsApp. El código no viaja en la respuesta de la partida: se revela una sola vez
+// confirmando los últimos dígitos del teléfono cargado
+type VoucherPantallaService struct {
+	config      *config.Config
+	voucherRepo repository.VoucherRepository
+	estado      repository.EstadoCompartidoRepository
+	qr          *VoucherQRService
+}
+
+// NewVoucherPantallaService crea una nueva instancia del servicio de vouchers en pantalla
+func NewVoucherPantallaService(cfg *config.Config, voucherRepo repository.VoucherRepository, estado repository.EstadoCompartidoRepository, qr *VoucherQRService) *VoucherPantallaService {
+	return &VoucherPantallaService{
+		config:      cfg,
+		voucherRepo: voucherRepo,
+		estado:      estado,
+		qr:          qr,
+	}
+}
+
+// Emitir guarda el voucher pendiente y retorna el token con el que se revela
+func (s *VoucherPantallaService) Emitir(voucher *models.Voucher, cliente *models.Cliente) (string, error) {
+	aleatorio := make([]byte, 16)
+	if _, err := rand.Read(aleatorio); err != nil {
+		return "", fmt.Errorf("error generando token: %w", err)
+	}
+	token := hex.EncodeToString(aleatorio)
+	expira := time.Now().Add(voucherPantallaTTL)
+
+	valor, err := json.Marshal(voucherPantalla{
+		Codigo:  voucher.Codigo,
+		Digitos: ultimosDigitos(cliente.Telefono),
+		Idioma:  cliente.Idioma,
+		Expira:  expira,
+	})
+	if err != nil {
+		return "", err
+	}
+	if _, err := s.estado.Crear("pantalla:"+token, string(valor), expira); err != nil {
+		return "", fmt.Errorf("error guardando voucher en pantalla: %w", err)
+	}
+	return token, nil
+}
+
+// Revelar verifica los dígitos y retorna el voucher para mostrar. El token se consume al
+// acertar o al agotar los intentos; como el intento se escribe con control de versión, dos
+// instancias no pueden revelar el mismo token ni sumar intentos de más
+func (s *VoucherPantallaService) Revelar(token, digitos string) (*models.VoucherPantalla, error) {
+	var (
+		pendiente voucherPantalla
+		acierto   bool
+	)
+	err := repository.ActualizarCompartido(s.estado, "pantalla:"+token, func(valor string, existe bool) (string, time.Time, error) {
+		pendiente = voucherPantalla{}
+		if !existe || json.Unmarshal([]byte(valor), &pendiente) != nil {
+			return "", time.Time{}, ErrVoucherPantallaInvalido
+		}
+		pendiente.Intentos++
+		acierto = subtle.ConstantTimeCompare([]byte(pendiente.Digitos), []byte(digitos)) == 1
+		vence := pendiente.Expira
+		if acierto || pendiente.Intentos >= voucherPantallaMaxIntentos {
+			// Consumido: queda vencido y el mantenimiento lo borra
+			vence = time.Now().Add(-time.Second)
+		}
+		codificado, err := json.Marshal(pendiente)
+		return string(codificado), vence, err
+	})
+	if err != nil {
+		if errors.Is(err, ErrVoucherPantallaInvalido) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("error verificando voucher en pantalla: %w", err)
+	}
+	if !acierto {
+		if pendiente.Intentos >= voucherPantallaMaxIntentos {
+			log.Printf("🔒 Voucher en pantalla %s bloqueado tras %d intentos", pendiente.Codigo, pendiente.Intentos)
+			return nil, ErrVoucherPantallaInvalido
+		}
+		return nil, errors.New("los dígitos no coinciden con el teléfono ingresado")
+	}
+
+	voucher, err := s.voucherRepo.BuscarPorCodigo(pendiente.Codigo)
+	if err != nil {
+		return nil, fmt.Errorf("error buscando voucher: %w", err)
+	}
+	log.Printf("📺 Voucher %s mostrado en pantalla", voucher.Codigo)
+
+	formato := s.config.Fechas(pendiente.Idioma)
+	vencimiento := formato.Fecha(voucher.FechaVencimiento)
+	if voucher.Flash {
+		vencimiento = formato.FechaHora(voucher.FechaVencimiento)
+	}
+	return &models.VoucherPantalla{
+		Codigo:           voucher.Codigo,
+		Descuento:        voucher.Descuento,
+		FechaVencimiento: formato.ISO(voucher.FechaVencimiento),
+		VencimientoTexto: vencimiento,
+		QRURL:            s.qr.URLMedia(voucher, "qr"),
+		CodigoBarrasURL:  s.qr.URLMedia(voucher, "code128"),
+		PDFURL:           s.qr.URLMedia(voucher, "pdf"),
+	}, nil
+}
+
+// ultimosDigitos retorna los últimos 4 dígitos de un teléfono normalizado
+func ultimosDigitos(telefono string) string {
+	if len(telefono) <= 4 {
+		return telefono
+	}
+	return telefono[len(telefono)-4:]
+}
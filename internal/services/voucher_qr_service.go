@@ -0,0 +1,148 @@
+package services
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"CheeseHouse/internal/barcode"
+	"CheeseHouse/internal/config"
+	"CheeseHouse/internal/models"
+	"CheeseHouse/internal/pdf"
+)
+
+// prefijoQRVoucher identifica el formato del contenido del QR, por si cambia más adelante
+const prefijoQRVoucher = "CHV1"
+
+var (
+	// ErrQRVoucherInvalido el QR no es de un voucher o está adulterado
+	ErrQRVoucherInvalido = errors.New("el QR no es un voucher válido")
+	// ErrQRVoucherVencido el QR es auténtico pero el voucher ya venció
+	ErrQRVoucherVencido = errors.New("el voucher del QR está vencido")
+)
+
+// VoucherQRService arma y valida el contenido firmado de los QR de vouchers que escanea
+// la app de caja: CHV1.<código>.<vencimiento unix>.<firma>. La firma evita que un QR
+// armado a mano con un código adivinado pase por uno emitido
+type VoucherQRService struct {
+	config *config.Config
+	secret []byte
+}
+
+// NewVoucherQRService crea una nueva instancia del servicio de QR de vouchers
+func NewVoucherQRService(cfg *config.Config) *VoucherQRService {
+	return &VoucherQRService{config: cfg, secret: []byte(cfg.LinkSigningSecret)}
+}
+
+// Contenido retorna el texto firmado a codificar en el QR del voucher
+func (s *VoucherQRService) Contenido(voucher *models.Voucher) string {
+	payload := voucher.Codigo + "." + strconv.FormatInt(voucher.FechaVencimiento.Unix(), 10)
+	return prefijoQRVoucher + "." + payload + "." + s.firmar(payload)
+}
+
+// Leer valida la firma y el vencimiento del contenido escaneado y retorna el código (también
+// con ErrQRVoucherVencido, para registrar el rechazo)
+func (s *VoucherQRService) Leer(contenido string, ahora time.Time) (string, error) {
+	partes := strings.Split(strings.TrimSpace(contenido), ".")
+	if len(partes) != 4 || partes[0] != prefijoQRVoucher {
+		return "", ErrQRVoucherInvalido
+	}
+	payload := partes[1] + "." + partes[2]
+	if !hmac.Equal([]byte(partes[3]), []byte(s.firmar(payload))) {
+		return "", ErrQRVoucherInvalido
+	}
+
+	vencimiento, err := strconv.ParseInt(partes[2], 10, 64)
+	if err != nil {
+		return "", ErrQRVoucherInvalido
+	}
+	if ahora.After(time.Unix(vencimiento, 0)) {
+		return partes[1], ErrQRVoucherVencido
+	}
+	return partes[1], nil
+}
+
+// URLMedia arma el link firmado a la imagen o el PDF del voucher (formato code128, qr o
+// pdf). La ruta es pública, así que sin la firma no se puede recorrer códigos adivinados;
+// el link vale hasta que vence el voucher
+func (s *VoucherQRService) URLMedia(voucher *models.Voucher, formato string) string {
+	vence := strconv.FormatInt(voucher.FechaVencimiento.Unix(), 10)
+	return "/api/vouchers/" + url.PathEscape(voucher.Codigo) + "/media?formato=" + formato +
+		"&vence=" + vence + "&firma=" + s.firmarMedia(voucher.Codigo+"."+vence)
+}
+
+// ValidarMedia verifica la firma y el vencimiento de un link de URLMedia
+func (s *VoucherQRService) ValidarMedia(codigo, vence, firma string, ahora time.Time) bool {
+	payload := strings.ToUpper(strings.TrimSpace(codigo)) + "." + vence
+	if !hmac.Equal([]byte(firma), []byte(s.firmarMedia(payload))) {
+		return false
+	}
+	vencimiento, err := strconv.ParseInt(vence, 10, 64)
+	return err == nil && !ahora.After(time.Unix(vencimiento, 0))
+}
+
+// PDF arma el voucher imprimible en A6: código, descuento, vencimiento, Code 128 para los
+// lectores del POS, QR firmado para la app de caja y el pie legal del branding
+func (s *VoucherQRService) PDF(voucher *models.Voucher, textoLegal string) ([]byte, error) {
+	barras, err := barcode.Code128(voucher.Codigo)
+	if err != nil {
+		return nil, err
+	}
+	qr, err := barcode.QR(s.Contenido(voucher))
+	if err != nil {
+		return nil, err
+	}
+
+	idioma := ""
+	if voucher.Cliente != nil {
+		idioma = voucher.Cliente.Idioma
+	}
+	formato := s.config.Fechas(idioma)
+	vencimiento := formato.Fecha(voucher.FechaVencimiento)
+	if voucher.Flash {
+		vencimiento = formato.FechaHora(voucher.FechaVencimiento)
+	}
+
+	pagina := pdf.NuevaPagina(pdf.A6Ancho, pdf.A6Alto)
+	pagina.TextoCentrado(380, 20, true, "CheeseHouse")
+	pagina.TextoCentrado(345, 22, true, fmt.Sprintf("%d%% de descuento", voucher.Descuento))
+	pagina.TextoCentrado(320, 11, false, "Vence: "+vencimiento)
+
+	// Code 128 con su zona silenciosa, a un ancho que los lectores 1D leen bien impreso
+	anchoBarras := float64(len(barras)) * 1.2
+	pagina.Modulos((pdf.A6Ancho-anchoBarras)/2, 250, anchoBarras, 50, [][]bool{barras})
+	pagina.TextoCentrado(235, 12, false, voucher.Codigo)
+
+	ladoQR := 120.0
+	pagina.Modulos((pdf.A6Ancho-ladoQR)/2, 95, ladoQR, ladoQR, qr)
+
+	y := 70.0
+	for _, linea := range strings.Split(textoLegal, "\n") {
+		if linea = strings.TrimSpace(linea); linea != "" && y > 15 {
+			pagina.TextoCentrado(y, 7, false, linea)
+			y -= 10
+		}
+	}
+	return pagina.Bytes()
+}
+
+// firmarMedia firma los links de URLMedia; el prefijo distinto evita que la firma de un
+// link sirva como contenido de un QR
+func (s *VoucherQRService) firmarMedia(payload string) string {
+	mac := hmac.New(sha256.New, s.secret)
+	fmt.Fprintf(mac, "voucher-media:%s", payload)
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil)[:16])
+}
+
+// firmar calcula el HMAC-SHA256 del payload, truncado para que el QR siga siendo chico
+func (s *VoucherQRService) firmar(payload string) string {
+	mac := hmac.New(sha256.New, s.secret)
+	fmt.Fprintf(mac, "voucher-qr:%s", payload)
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil)[:12])
+}
@@ -0,0 +1,94 @@
+package services
+
+import (
+	"errors"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"CheeseHouse/internal/config"
+	"CheeseHouse/internal/models"
+)
+
+func qrDePrueba(secreto string) *VoucherQRService {
+	return NewVoucherQRService(&config.Config{LinkSigningSecret: secreto})
+}
+
+func TestLeerQRVoucher(t *testing.T) {
+	s := qrDePrueba("secreto-de-prueba")
+	vence := time.Date(2026, 10, 20, 23, 59, 59, 0, time.UTC)
+	antes := vence.Add(-24 * time.Hour)
+	contenido := s.Contenido(&models.Voucher{Codigo: "CH12345678", FechaVencimiento: vence})
+	partes := strings.Split(contenido, ".")
+
+	casos := []struct {
+		nombre    string
+		servicio  *VoucherQRService
+		contenido string
+		ahora     time.Time
+		codigo    string
+		err       error
+	}{
+		{"emitido y vigente", s, contenido, antes, "CH12345678", nil},
+		{"con espacios del lector", s, " " + contenido + "\n", antes, "CH12345678", nil},
+		{"el último segundo todavía vale", s, contenido, vence, "CH12345678", nil},
+		{"vencido devuelve el código", s, contenido, vence.Add(time.Second), "CH12345678", ErrQRVoucherVencido},
+		{"código cambiado", s, strings.Replace(contenido, "CH12345678", "CH12345679", 1), antes, "", ErrQRVoucherInvalido},
+		{"vencimiento estirado", s, strings.Join([]string{partes[0], partes[1], "1900000000", partes[3]}, "."), antes, "", ErrQRVoucherInvalido},
+		{"firmado con otro secreto", qrDePrueba("otro-secreto"), contenido, antes, "", ErrQRVoucherInvalido},
+		{"otro prefijo", s, "CHV2" + strings.TrimPrefix(contenido, prefijoQRVoucher), antes, "", ErrQRVoucherInvalido},
+		{"código suelto", s, "CH12345678", antes, "", ErrQRVoucherInvalido},
+		{"vacío", s, "", antes, "", ErrQRVoucherInvalido},
+	}
+
+	for _, caso := range casos {
+		t.Run(caso.nombre, func(t *testing.T) {
+			codigo, err := caso.servicio.Leer(caso.contenido, caso.ahora)
+			if !errors.Is(err, caso.err) || codigo != caso.codigo {
+				t.Errorf("Leer = %q, %v; se esperaba %q, %v", codigo, err, caso.codigo, caso.err)
+			}
+		})
+	}
+}
+
+func TestValidarMediaVoucher(t *testing.T) {
+	s := qrDePrueba("secreto-de-prueba")
+	voucher := &models.Voucher{Codigo: "CH12345678", FechaVencimiento: time.Date(2026, 10, 20, 23, 59, 59, 0, time.UTC)}
+	antes := voucher.FechaVencimiento.Add(-24 * time.Hour)
+
+	link, err := url.Parse(s.URLMedia(voucher, "pdf"))
+	if err != nil {
+		t.Fatalf("link inválido: %v", err)
+	}
+	vence, firma := link.Query().Get("vence"), link.Query().Get("firma")
+	firmaQR := strings.Split(s.Contenido(voucher), ".")[3]
+
+	casos := []struct {
+		nombre   string
+		servicio *VoucherQRService
+		codigo   string
+		vence    string
+		firma    string
+		ahora    time.Time
+		valido   bool
+	}{
+		{"link emitido", s, "CH12345678", vence, firma, antes, true},
+		{"código en minúsculas", s, "ch12345678", vence, firma, antes, true},
+		{"voucher vencido", s, "CH12345678", vence, firma, voucher.FechaVencimiento.Add(time.Second), false},
+		{"otro código", s, "CH12345679", vence, firma, antes, false},
+		{"vencimiento estirado", s, "CH12345678", "1900000000", firma, antes, false},
+		{"firma adulterada", s, "CH12345678", vence, firma[:len(firma)-1] + "A", antes, false},
+		{"sin firma", s, "CH12345678", vence, "", antes, false},
+		{"firma del QR", s, "CH12345678", vence, firmaQR, antes, false},
+		{"firmado con otro secreto", qrDePrueba("otro-secreto"), "CH12345678", vence, firma, antes, false},
+	}
+
+	for _, caso := range casos {
+		t.Run(caso.nombre, func(t *testing.T) {
+			if valido := caso.servicio.ValidarMedia(caso.codigo, caso.vence, caso.firma, caso.ahora); valido != caso.valido {
+				t.Errorf("ValidarMedia = %t, se esperaba %t", valido, caso.valido)
+			}
+		})
+	}
+}
@@ -0,0 +1,287 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"CheeseHouse/internal/models"
+)
+
+// limitesPorTier destinatarios distintos por 24h según el tier de mensajería de Meta
+var limitesPorTier = map[string]int{
+	"TIER_50":        50,
+	"TIER_250":       250,
+	"TIER_1K":        1000,
+	"TIER_10K":       10000,
+	"TIER_100K":      100000,
+	"TIER_UNLIMITED": 0,
+}
+
+// Calidad del número según Meta. Con YELLOW o RED Meta puede bajar el tier o bloquear el
+// número, así que se recorta el marketing para que no arrastre a los vouchers
+const (
+	calidadVerde    = "GREEN"
+	calidadAmarilla = "YELLOW"
+	calidadRoja     = "RED"
+)
+
+// ErrMarketingLimitado la calidad del número no deja lugar para más marketing hoy
+var ErrMarketingLimitado = errors.New("envíos de marketing limitados por la calidad del número de WhatsApp")
+
+// ActualizarLimiteProveedor consulta a la API el tier de mensajería y la calidad del
+// número; el tier se usa como límite diario en lugar del configurado
+func (w *WhatsAppService) ActualizarLimiteProveedor() error {
+	if !w.isConfigured() {
+		return fmt.Errorf("WhatsApp no está configurado")
+	}
+
+	url := fmt.Sprintf("%s/%s?fields=messaging_limit_tier,quality_rating", w.apiURL, w.phoneNumberID)
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return fmt.Errorf("error al crear request de cuota: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+w.accessToken)
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("error al consultar cuota de WhatsApp: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return parsearErrorAPI(resp)
+	}
+
+	var respuesta struct {
+		MessagingLimitTier string `json:"messaging_limit_tier"`
+		QualityRating      string `json:"quality_rating"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&respuesta); err != nil {
+		return fmt.Errorf("error al leer cuota de WhatsApp: %w", err)
+	}
+
+	limite, ok := limitesPorTier[respuesta.MessagingLimitTier]
+	if !ok {
+		return fmt.Errorf("tier de mensajería desconocido: %q", respuesta.MessagingLimitTier)
+	}
+
+	ahora := time.Now()
+	w.cuotaMu.Lock()
+	calidadAnterior := w.calidadNumero
+	w.tierProveedor = respuesta.MessagingLimitTier
+	w.limiteProveedor = limite
+	w.calidadNumero = respuesta.QualityRating
+	w.consultadoEn = &ahora
+	w.cuotaMu.Unlock()
+
+	log.Printf("📊 Tier de mensajería de WhatsApp: %s, calidad del número: %s", respuesta.MessagingLimitTier, respuesta.QualityRating)
+	if calidadAnterior != respuesta.QualityRating {
+		w.avisarCambioCalidad(calidadAnterior, respuesta.QualityRating)
+	}
+	return nil
+}
+
+// avisarCambioCalidad alerta cuando la calidad del número baja (y el marketing se recorta)
+// o vuelve a GREEN. Al arrancar solo se avisa si ya no está en GREEN
+func (w *WhatsAppService) avisarCambioCalidad(anterior, actual string) {
+	if anterior == "" && (actual == calidadVerde || actual == "") {
+		return
+	}
+	if w.notificaciones == nil {
+		return
+	}
+
+	switch actual {
+	case calidadAmarilla, calidadRoja:
+		tipo := "warning"
+		if actual == calidadRoja {
+			tipo = "error"
+		}
+		descripcion := fmt.Sprintf("La calidad del número pasó a %s", actual)
+		if parte := w.parteMarketing(actual); parte == 0 {
+			descripcion += "; las campañas de marketing quedan pausadas"
+		} else {
+			descripcion += fmt.Sprintf("; las campañas pueden usar hasta el %.0f%% del límite diario", parte*100)
+		}
+		w.notificaciones.Notificar(tipo, "Calidad del número de WhatsApp en baja", descripcion+". Los vouchers siguen saliendo", "revisar_calidad_mensajes")
+	case calidadVerde:
+		w.notificaciones.Notificar("info", "Calidad del número de WhatsApp recuperada",
+			fmt.Sprintf("La calidad del número volvió a GREEN (antes %s); el marketing usa de nuevo todo el límite", anterior),
+			"revisar_calidad_mensajes")
+	}
+}
+
+// parteMarketing parte del límite diario disponible para campañas con la calidad dada
+func (w *WhatsAppService) parteMarketing(calidad string) float64 {
+	switch calidad {
+	case calidadAmarilla:
+		return w.config.WhatsAppMarketingShareYellow
+	case calidadRoja:
+		return w.config.WhatsAppMarketingShareRed
+	}
+	return 1
+}
+
+// verificarCupoMarketing retorna ErrMarketingLimitado si con la calidad actual del número
+// no quedan envíos de marketing hoy
+func (w *WhatsAppService) verificarCupoMarketing() error {
+	w.cuotaMu.Lock()
+	calidad := w.calidadNumero
+	w.cuotaMu.Unlock()
+	if w.parteMarketing(calidad) >= 1 {
+		return nil
+	}
+
+	cuota, err := w.GetCuota()
+	if err != nil {
+		return fmt.Errorf("no se pudo verificar la cuota de marketing: %w", err)
+	}
+	if cuota.DisponiblesMarketingHoy == 0 {
+		return fmt.Errorf("%w (calidad %s)", ErrMarketingLimitado, calidad)
+	}
+	return nil
+}
+
+// cuotaProveedor lo que la líder publica de la última consulta de cuota
+type cuotaProveedor struct {
+	Tier         string     `json:"tier"`
+	Limite       int        `json:"limite"`
+	Calidad      string     `json:"calidad"`
+	ConsultadoEn *time.Time `json:"consultado_en"`
+}
+
+// IniciarMonitorCuota consulta periódicamente el límite y la calidad del número en segundo
+// plano. Con varias instancias consulta solo la líder (una sola llamada a Meta y una sola
+// alerta por cambio de calidad) y las demás toman lo que publicó
+func (w *WhatsAppService) IniciarMonitorCuota(intervalo time.Duration, coordinacion *CoordinacionService) {
+	if !w.isConfigured() {
+		return
+	}
+
+	go func() {
+		for {
+			if coordinacion.EsLider() {
+				if err := w.ActualizarLimiteProveedor(); err != nil {
+					log.Printf("⚠️  No se pudo consultar la cuota de WhatsApp, se usa la configurada: %v", err)
+				} else {
+					w.cuotaMu.Lock()
+					cuota := cuotaProveedor{w.tierProveedor, w.limiteProveedor, w.calidadNumero, w.consultadoEn}
+					w.cuotaMu.Unlock()
+					coordinacion.PublicarEstado("cuota_whatsapp", cuota, 3*intervalo)
+				}
+			} else {
+				var cuota cuotaProveedor
+				if coordinacion.LeerEstado("cuota_whatsapp", &cuota) {
+					w.cuotaMu.Lock()
+					w.tierProveedor, w.limiteProveedor, w.calidadNumero, w.consultadoEn = cuota.Tier, cuota.Limite, cuota.Calidad, cuota.ConsultadoEn
+					w.cuotaMu.Unlock()
+				}
+			}
+			select {
+			case <-w.detener:
+				return
+			case <-time.After(intervalo):
+			}
+		}
+	}()
+}
+
+// GetCuota retorna los límites vigentes y el consumo del día y del mes
+func (w *WhatsAppService) GetCuota() (*models.CuotaWhatsApp, error) {
+	cuota := &models.CuotaWhatsApp{
+		Fuente:        "configuracion",
+		LimiteDiario:  w.config.WhatsAppDailyRecipientLimit,
+		LimiteMensual: w.config.WhatsAppMonthlyConversationCap,
+	}
+
+	w.cuotaMu.Lock()
+	if w.consultadoEn != nil {
+		cuota.Fuente = "proveedor"
+		cuota.TierProveedor = w.tierProveedor
+		cuota.LimiteDiario = w.limiteProveedor
+		cuota.ConsultadoProveedorEn = w.consultadoEn
+		cuota.Calidad = w.calidadNumero
+	}
+	w.cuotaMu.Unlock()
+	cuota.ParteMarketing = w.parteMarketing(cuota.Calidad)
+
+	if w.mensajeLogRepo == nil {
+		cuota.DisponiblesHoy = disponibles(cuota.LimiteDiario, 0)
+		cuota.DisponiblesMes = disponibles(cuota.LimiteMensual, 0)
+		cuota.DisponiblesMarketingHoy = disponiblesMarketing(cuota, 0)
+		return cuota, nil
+	}
+
+	ahora := time.Now()
+	destinatarios, err := w.mensajeLogRepo.ContarDestinatariosDesde(ahora.Add(-24 * time.Hour))
+	if err != nil {
+		return nil, err
+	}
+
+	local := ahora.In(w.config.GetLocation())
+	inicioMes := time.Date(local.Year(), local.Month(), 1, 0, 0, 0, 0, local.Location())
+	conversaciones, err := w.mensajeLogRepo.ContarConversacionesDesde(inicioMes)
+	if err != nil {
+		return nil, err
+	}
+
+	cuota.DestinatariosUltimas24 = destinatarios
+	cuota.ConversacionesMes = conversaciones
+	cuota.DisponiblesHoy = disponibles(cuota.LimiteDiario, destinatarios)
+	cuota.DisponiblesMes = disponibles(cuota.LimiteMensual, conversaciones)
+	cuota.DisponiblesMarketingHoy = disponiblesMarketing(cuota, destinatarios)
+	return cuota, nil
+}
+
+// VerificarCuota retorna error si enviar a la cantidad de destinatarios indicada
+// excedería el límite diario del número o el presupuesto mensual de conversaciones
+func (w *WhatsAppService) VerificarCuota(destinatarios int) error {
+	cuota, err := w.GetCuota()
+	if err != nil {
+		return fmt.Errorf("no se pudo verificar la cuota de WhatsApp: %w", err)
+	}
+
+	if cuota.DisponiblesHoy >= 0 && destinatarios > cuota.DisponiblesHoy {
+		return fmt.Errorf("la campaña requiere %d destinatarios y hoy quedan %d disponibles (límite %d en 24h)",
+			destinatarios, cuota.DisponiblesHoy, cuota.LimiteDiario)
+	}
+	if cuota.DisponiblesMarketingHoy >= 0 && destinatarios > cuota.DisponiblesMarketingHoy {
+		if cuota.ParteMarketing == 0 {
+			return fmt.Errorf("campañas pausadas: la calidad del número de WhatsApp es %s", cuota.Calidad)
+		}
+		return fmt.Errorf("la campaña requiere %d destinatarios y con la calidad %s del número quedan %d para marketing hoy (%.0f%% del límite)",
+			destinatarios, cuota.Calidad, cuota.DisponiblesMarketingHoy, cuota.ParteMarketing*100)
+	}
+	if cuota.DisponiblesMes >= 0 && destinatarios > cuota.DisponiblesMes {
+		return fmt.Errorf("la campaña requiere %d conversaciones y quedan %d en el presupuesto mensual (%d)",
+			destinatarios, cuota.DisponiblesMes, cuota.LimiteMensual)
+	}
+	return nil
+}
+
+// disponiblesMarketing lo que resta de la parte del límite diario que puede usar el
+// marketing; -1 si no hay límite. Sin límite diario solo se distingue pausado o no
+func disponiblesMarketing(cuota *models.CuotaWhatsApp, destinatarios int) int {
+	switch {
+	case cuota.ParteMarketing <= 0:
+		return 0
+	case cuota.LimiteDiario <= 0 || cuota.ParteMarketing >= 1:
+		return cuota.DisponiblesHoy
+	}
+	return disponibles(max(int(float64(cuota.LimiteDiario)*cuota.ParteMarketing), 1), destinatarios)
+}
+
+// disponibles calcula lo que resta de un límite; -1 si no hay límite
+func disponibles(limite, usado int) int {
+	if limite <= 0 {
+		return -1
+	}
+	if usado >= limite {
+		return 0
+	}
+	return limite - usado
+}
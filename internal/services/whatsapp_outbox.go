@@ -0,0 +1,318 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"CheeseHouse/internal/models"
+)
+
+const (
+	CategoriaTransaccional = "transaccional"
+	CategoriaMarketing     = "marketing"
+)
+
+// Reintentos de mensajes del outbox: la espera se duplica en cada intento fallido
+// (1, 2, 4... minutos, como mucho esperaMaxReintento) hasta agotar maxIntentosOutbox
+const (
+	maxIntentosOutbox   = 8
+	esperaBaseReintento = time.Minute
+	esperaMaxReintento  = 2 * time.Hour
+)
+
+// esperaEnvioDirecto cuánto espera el worker antes de tomar un aviso de voucher: la
+// partida lo envía apenas confirma la transacción y el worker solo lo levanta si ese envío
+// no llegó a registrarse (ej. el proceso se cayó justo después del commit)
+const esperaEnvioDirecto = 5 * time.Minute
+
+// ErrEnvioEncolado el envío falló por un problema transitorio y quedó en el outbox para reintentar
+var ErrEnvioEncolado = errors.New("envío de WhatsApp fallido, quedó en cola para reintentar")
+
+// EnvioWhatsApp identifica con qué mensaje salió un envío: el wamid si la API lo aceptó
+// o el mensaje del outbox si quedó retenido por horario silencioso
+type EnvioWhatsApp struct {
+	MessageID string
+	OutboxID  *uint
+}
+
+// enviarOProgramar envía el mensaje de inmediato o lo retiene en el outbox
+// si es no transaccional y estamos dentro del horario silencioso
+func (w *WhatsAppService) enviarOProgramar(message models.WhatsAppMessage, categoria string) error {
+	_, err := w.programarEnvio(message, categoria)
+	return err
+}
+
+// programarEnvio es enviarOProgramar, pero retorna con qué mensaje salió el envío
+func (w *WhatsAppService) programarEnvio(message models.WhatsAppMessage, categoria string) (EnvioWhatsApp, error) {
+	if categoria == CategoriaTransaccional || w.outboxRepo == nil {
+		messageID, err := w.enviarMensaje(message)
+		return EnvioWhatsApp{MessageID: messageID}, err
+	}
+
+	silencioso, liberarEn := w.EnHorarioSilencioso(time.Now())
+	if !silencioso {
+		messageID, err := w.enviarMensaje(message)
+		return EnvioWhatsApp{MessageID: messageID}, err
+	}
+
+	payload, err := json.Marshal(message)
+	if err != nil {
+		return EnvioWhatsApp{}, fmt.Errorf("error al serializar mensaje: %w", err)
+	}
+
+	retenido := &models.MensajeOutbox{
+		Telefono:       w.normalizePhoneNumber(message.To),
+		Categoria:      categoria,
+		Payload:        string(payload),
+		Estado:         "pendiente",
+		ProgramadoPara: liberarEn,
+	}
+	if err := w.outboxRepo.Crear(retenido); err != nil {
+		return EnvioWhatsApp{}, err
+	}
+
+	log.Printf("🌙 Horario silencioso: mensaje para %s retenido hasta %s",
+		message.To, w.config.Fechas("").FechaHora(liberarEn))
+	return EnvioWhatsApp{OutboxID: &retenido.ID}, nil
+}
+
+// enviarConReintento envía el mensaje y, si falla por un problema transitorio (red,
+// límite de tasa o error del proveedor), lo deja en el outbox para que el worker lo
+// reintente. En ese caso retorna un error que envuelve ErrEnvioEncolado
+func (w *WhatsAppService) enviarConReintento(message models.WhatsAppMessage, categoria string) error {
+	errEnvio := w.sendMessage(message)
+	if errEnvio == nil || w.outboxRepo == nil || !esReintentable(errEnvio) {
+		return errEnvio
+	}
+
+	payload, err := json.Marshal(message)
+	if err != nil {
+		return errEnvio
+	}
+	if err := w.outboxRepo.Crear(&models.MensajeOutbox{
+		Telefono:       w.normalizePhoneNumber(message.To),
+		Categoria:      categoria,
+		Payload:        string(payload),
+		Estado:         "pendiente",
+		ProgramadoPara: time.Now().Add(esperaReintento(1)),
+		Intentos:       1,
+		UltimoError:    errEnvio.Error(),
+	}); err != nil {
+		log.Printf("❌ No se pudo encolar el reintento para %s: %v", message.To, err)
+		return errEnvio
+	}
+
+	return fmt.Errorf("%w: %v", ErrEnvioEncolado, errEnvio)
+}
+
+// EnHorarioSilencioso indica si t cae dentro del horario silencioso configurado
+// (en la zona horaria del restaurante) y cuándo termina
+func (w *WhatsAppService) EnHorarioSilencioso(t time.Time) (bool, time.Time) {
+	inicio := w.config.Notifications.QuietHoursStart
+	fin := w.config.Notifications.QuietHoursEnd
+	if inicio == fin {
+		return false, t
+	}
+
+	loc := w.config.GetLocation()
+	local := t.In(loc)
+	minutos := local.Hour()*60 + local.Minute()
+
+	var dentro bool
+	if inicio < fin {
+		dentro = minutos >= inicio && minutos < fin
+	} else {
+		// El horario cruza la medianoche (ej. 22:00–09:00)
+		dentro = minutos >= inicio || minutos < fin
+	}
+	if !dentro {
+		return false, t
+	}
+
+	liberarEn := time.Date(local.Year(), local.Month(), local.Day(), fin/60, fin%60, 0, 0, loc)
+	if !liberarEn.After(local) {
+		liberarEn = liberarEn.AddDate(0, 0, 1)
+	}
+	return true, liberarEn
+}
+
+// AvisoVoucher arma el aviso del voucher de una partida para encolarlo en la misma
+// transacción que crea el voucher: así todo voucher confirmado tiene su envío pendiente
+func (w *WhatsAppService) AvisoVoucher(cliente *models.Cliente, voucher *models.Voucher) *models.MensajeOutbox {
+	return &models.MensajeOutbox{
+		Telefono:       w.normalizePhoneNumber(cliente.Telefono),
+		Categoria:      CategoriaTransaccional,
+		Payload:        "{}",
+		Estado:         "pendiente",
+		ProgramadoPara: time.Now().Add(esperaEnvioDirecto),
+		VoucherID:      &voucher.ID,
+	}
+}
+
+// EnviarAvisoVoucher envía el aviso encolado por la partida una vez confirmada la
+// transacción. Si falla por un problema transitorio queda reprogramado en el outbox
+func (w *WhatsAppService) EnviarAvisoVoucher(aviso *models.MensajeOutbox, cliente *models.Cliente, voucher *models.Voucher) {
+	if w.outboxRepo == nil {
+		w.enviarVoucher(cliente, voucher, voucher.Tipo == "juego_ganado", false)
+		return
+	}
+
+	pendiente := *aviso
+	conCliente := *voucher
+	conCliente.Cliente = cliente
+	pendiente.Voucher = &conCliente
+
+	if err := w.enviarPendiente(&pendiente); err == nil {
+		log.Printf("📱 Voucher %s enviado a %s", voucher.Codigo, cliente.Telefono)
+	}
+}
+
+// ProcesarOutbox envía los mensajes pendientes cuya hora programada ya llegó. Los que
+// fallan por un problema transitorio se reprograman con espera exponencial
+func (w *WhatsAppService) ProcesarOutbox() (int, error) {
+	pendientes, err := w.outboxRepo.ListarPendientes(time.Now(), 100)
+	if err != nil {
+		return 0, err
+	}
+
+	enviados := 0
+	for _, pendiente := range pendientes {
+		if w.enviarPendiente(pendiente) == nil {
+			enviados++
+		}
+	}
+
+	return enviados, nil
+}
+
+// ListarOutbox obtiene los mensajes retenidos o en reintento (estado pendiente o fallido)
+func (w *WhatsAppService) ListarOutbox(estado string, limite int) ([]*models.MensajeOutbox, error) {
+	if limite <= 0 || limite > 500 {
+		limite = 100
+	}
+	return w.outboxRepo.ListarPorEstado(estado, limite)
+}
+
+// ReintentarOutbox fuerza el envío inmediato de un mensaje pendiente o fallido
+func (w *WhatsAppService) ReintentarOutbox(id uint, usuarioID uint) (*models.MensajeOutbox, error) {
+	mensaje, err := w.outboxRepo.BuscarPorID(id)
+	if err != nil {
+		return nil, err
+	}
+	if mensaje.Estado == "enviado" {
+		return nil, fmt.Errorf("el mensaje #%d ya fue enviado", id)
+	}
+
+	log.Printf("🔁 Usuario %d reintenta el mensaje del outbox #%d para %s", usuarioID, id, mensaje.Telefono)
+	w.enviarPendiente(mensaje)
+	return w.outboxRepo.BuscarPorID(id)
+}
+
+// enviarPendiente envía un mensaje del outbox (o arma y envía el aviso del voucher) y
+// registra el resultado
+func (w *WhatsAppService) enviarPendiente(pendiente *models.MensajeOutbox) error {
+	var (
+		messageID string
+		err       error
+	)
+	if pendiente.VoucherID != nil {
+		voucher := pendiente.Voucher
+		if voucher == nil || voucher.Cliente == nil {
+			err = fmt.Errorf("el voucher #%d del aviso no existe", *pendiente.VoucherID)
+			w.outboxRepo.MarcarFallido(pendiente.ID, err.Error())
+			return err
+		}
+		// Sin reintento propio: de los reintentos se encarga este mismo mensaje
+		err = w.enviarVoucher(voucher.Cliente, voucher, voucher.Tipo == "juego_ganado", false)
+	} else {
+		var message models.WhatsAppMessage
+		if err := json.Unmarshal([]byte(pendiente.Payload), &message); err != nil {
+			w.outboxRepo.MarcarFallido(pendiente.ID, fmt.Sprintf("payload inválido: %v", err))
+			return err
+		}
+		messageID, err = w.enviarMensaje(message)
+	}
+	if err != nil {
+		intentos := pendiente.Intentos + 1
+		if esReintentable(err) && intentos < maxIntentosOutbox {
+			proximo := time.Now().Add(esperaReintento(intentos))
+			log.Printf("🔁 Mensaje del outbox #%d falló (intento %d), se reintenta a las %s: %v",
+				pendiente.ID, intentos, proximo.Format("15:04"), err)
+			if errRepo := w.outboxRepo.Reprogramar(pendiente.ID, proximo, err.Error()); errRepo != nil {
+				log.Printf("⚠️  Error reprogramando mensaje del outbox #%d: %v", pendiente.ID, errRepo)
+			}
+			return err
+		}
+
+		log.Printf("❌ Mensaje del outbox #%d descartado tras %d intentos: %v", pendiente.ID, intentos, err)
+		w.outboxRepo.MarcarFallido(pendiente.ID, err.Error())
+		return err
+	}
+
+	if err := w.outboxRepo.MarcarEnviado(pendiente.ID, messageID); err != nil {
+		log.Printf("⚠️  Error actualizando mensaje del outbox #%d: %v", pendiente.ID, err)
+	}
+	return nil
+}
+
+// esReintentable indica si vale la pena reintentar el envío: errores de red, límite de
+// tasa o errores del proveedor. Los rechazos del contenido o del número no se reintentan
+func esReintentable(err error) bool {
+	var apiErr *models.WhatsAppAPIError
+	if !errors.As(err, &apiErr) {
+		return true
+	}
+	return apiErr.HTTPStatus == http.StatusTooManyRequests || apiErr.HTTPStatus >= 500
+}
+
+// esperaReintento espera antes del próximo intento tras intentos fallidos
+func esperaReintento(intentos int) time.Duration {
+	espera := esperaBaseReintento
+	for i := 1; i < intentos && espera < esperaMaxReintento; i++ {
+		espera *= 2
+	}
+	if espera > esperaMaxReintento {
+		espera = esperaMaxReintento
+	}
+	return espera
+}
+
+// IniciarOutboxWorker procesa el outbox periódicamente en segundo plano hasta que se
+// llame a Detener (una pasada en curso se completa). Con varias instancias cada pasada la
+// hace una sola, la líder, así un mensaje no sale dos veces
+func (w *WhatsAppService) IniciarOutboxWorker(intervalo time.Duration, coordinacion *CoordinacionService) {
+	if w.outboxRepo == nil {
+		return
+	}
+
+	w.enCurso.Add(1)
+	go func() {
+		defer w.enCurso.Done()
+		ticker := time.NewTicker(intervalo)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-w.detener:
+				return
+			case ahora := <-ticker.C:
+				if !coordinacion.TomarTurno("outbox", ahora.Truncate(intervalo)) {
+					continue
+				}
+			}
+
+			enviados, err := w.ProcesarOutbox()
+			if err != nil {
+				log.Printf("⚠️  Error procesando outbox: %v", err)
+				continue
+			}
+			if enviados > 0 {
+				log.Printf("📤 Outbox: %d mensajes liberados", enviados)
+			}
+		}
+	}()
+}
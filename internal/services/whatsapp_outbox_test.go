@@ -0,0 +1,259 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"CheeseHouse/internal/config"
+	"CheeseHouse/internal/models"
+	"CheeseHouse/internal/repository"
+)
+
+// outboxDePrueba cola en memoria que anota cómo terminó cada mensaje
+type outboxDePrueba struct {
+	repository.OutboxRepository
+	mu          sync.Mutex
+	pendientes  []*models.MensajeOutbox
+	enviados    map[uint]string    // ID -> message ID del proveedor
+	reintentos  map[uint]time.Time // ID -> próximo intento
+	descartados map[uint]string    // ID -> error
+}
+
+func nuevoOutboxDePrueba(pendientes ...*models.MensajeOutbox) *outboxDePrueba {
+	return &outboxDePrueba{
+		pendientes:  pendientes,
+		enviados:    make(map[uint]string),
+		reintentos:  make(map[uint]time.Time),
+		descartados: make(map[uint]string),
+	}
+}
+
+func (r *outboxDePrueba) ListarPendientes(hasta time.Time, limit int) ([]*models.MensajeOutbox, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var vencidos []*models.MensajeOutbox
+	for _, mensaje := range r.pendientes {
+		if mensaje.Estado == "pendiente" && !mensaje.ProgramadoPara.After(hasta) {
+			vencidos = append(vencidos, mensaje)
+		}
+	}
+	return vencidos, nil
+}
+
+func (r *outboxDePrueba) MarcarEnviado(id uint, messageID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.enviados[id] = messageID
+	r.cambiarEstado(id, "enviado")
+	return nil
+}
+
+func (r *outboxDePrueba) MarcarFallido(id uint, errorMsg string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.descartados[id] = errorMsg
+	r.cambiarEstado(id, "fallido")
+	return nil
+}
+
+func (r *outboxDePrueba) Reprogramar(id uint, programadoPara time.Time, errorMsg string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.reintentos[id] = programadoPara
+	for _, mensaje := range r.pendientes {
+		if mensaje.ID == id {
+			mensaje.Intentos++
+			mensaje.ProgramadoPara = programadoPara
+		}
+	}
+	return nil
+}
+
+func (r *outboxDePrueba) cambiarEstado(id uint, estado string) {
+	for _, mensaje := range r.pendientes {
+		if mensaje.ID == id {
+			mensaje.Estado = estado
+		}
+	}
+}
+
+// proveedorDePrueba entrega los textos salvo a los teléfonos de fallas, que responden
+// con ese error
+type proveedorDePrueba struct {
+	fallas map[string]error
+}
+
+func (p *proveedorDePrueba) Nombre() string                 { return "prueba" }
+func (p *proveedorDePrueba) Configurado() bool              { return true }
+func (p *proveedorDePrueba) Estado() map[string]interface{} { return nil }
+
+func (p *proveedorDePrueba) EnviarTexto(telefono, texto string) (string, error) {
+	if err := p.fallas[telefono]; err != nil {
+		return "", err
+	}
+	return "wamid." + telefono, nil
+}
+
+func (p *proveedorDePrueba) EnviarPlantilla(telefono string, plantilla *models.Template) (string, error) {
+	return p.EnviarTexto(telefono, plantilla.Name)
+}
+
+// mensajeDePrueba mensaje de texto pendiente para el teléfono, vencido hace un minuto
+func mensajeDePrueba(t *testing.T, id uint, telefono string, intentos int) *models.MensajeOutbox {
+	t.Helper()
+	payload, err := json.Marshal(models.WhatsAppMessage{
+		MessagingProduct: "whatsapp",
+		To:               telefono,
+		Type:             "text",
+		Text:             &models.TextBody{Body: "Hola"},
+	})
+	if err != nil {
+		t.Fatalf("error armando el mensaje: %v", err)
+	}
+	return &models.MensajeOutbox{
+		ID:             id,
+		Telefono:       "+" + telefono,
+		Categoria:      CategoriaMarketing,
+		Payload:        string(payload),
+		Estado:         "pendiente",
+		ProgramadoPara: time.Now().Add(-time.Minute),
+		Intentos:       intentos,
+	}
+}
+
+func TestProcesarOutbox(t *testing.T) {
+	rechazado := &models.WhatsAppAPIError{HTTPStatus: http.StatusBadRequest, Code: 131026, Message: "número inválido"}
+	limitado := &models.WhatsAppAPIError{HTTPStatus: http.StatusTooManyRequests, Code: 130429, Message: "rate limit"}
+	caido := &models.WhatsAppAPIError{HTTPStatus: http.StatusBadGateway, Message: "bad gateway"}
+
+	futuro := mensajeDePrueba(t, 7, "5491100000007", 0)
+	futuro.ProgramadoPara = time.Now().Add(time.Hour)
+	sinPayload := mensajeDePrueba(t, 8, "5491100000008", 0)
+	sinPayload.Payload = "{no es json"
+	voucherID := uint(99)
+	avisoHuerfano := &models.MensajeOutbox{ID: 9, Telefono: "+5491100000009", Categoria: CategoriaTransaccional,
+		Payload: "{}", Estado: "pendiente", ProgramadoPara: time.Now().Add(-time.Minute), VoucherID: &voucherID}
+
+	outbox := nuevoOutboxDePrueba(
+		mensajeDePrueba(t, 1, "5491100000001", 0),
+		mensajeDePrueba(t, 2, "5491100000002", 0),
+		mensajeDePrueba(t, 3, "5491100000003", 3),
+		mensajeDePrueba(t, 4, "5491100000004", 0),
+		mensajeDePrueba(t, 5, "5491100000005", maxIntentosOutbox-1),
+		mensajeDePrueba(t, 6, "5491100000006", 0),
+		futuro, sinPayload, avisoHuerfano,
+	)
+	w := &WhatsAppService{
+		config:     &config.Config{},
+		outboxRepo: outbox,
+		proveedor: &proveedorDePrueba{fallas: map[string]error{
+			"5491100000002": limitado,
+			"5491100000003": caido,
+			"5491100000004": rechazado,
+			"5491100000005": caido,
+			"5491100000006": errors.New("connection reset by peer"),
+		}},
+	}
+
+	antes := time.Now()
+	enviados, err := w.ProcesarOutbox()
+	if err != nil {
+		t.Fatalf("error procesando el outbox: %v", err)
+	}
+	if enviados != 1 || outbox.enviados[1] != "wamid.5491100000001" {
+		t.Errorf("enviados = %d %v, se esperaba solo el #1", enviados, outbox.enviados)
+	}
+
+	// Reintentables: espera exponencial según los intentos ya hechos
+	reprogramados := map[uint]time.Duration{2: time.Minute, 3: 8 * time.Minute, 6: time.Minute}
+	for id, espera := range reprogramados {
+		proximo, ok := outbox.reintentos[id]
+		if !ok {
+			t.Errorf("el #%d no se reprogramó", id)
+			continue
+		}
+		if desvio := proximo.Sub(antes.Add(espera)); desvio < 0 || desvio > 5*time.Second {
+			t.Errorf("el #%d se reprogramó para %v, se esperaba dentro de %v", id, proximo.Sub(antes).Round(time.Second), espera)
+		}
+	}
+
+	// Rechazo del contenido, último intento, payload roto y aviso sin voucher: se descartan
+	for _, id := range []uint{4, 5, 8, 9} {
+		if _, ok := outbox.descartados[id]; !ok {
+			t.Errorf("el #%d no se descartó", id)
+		}
+	}
+	if len(outbox.reintentos) != len(reprogramados) || len(outbox.descartados) != 4 {
+		t.Errorf("reintentos = %v, descartados = %v", outbox.reintentos, outbox.descartados)
+	}
+	if futuro.Estado != "pendiente" || futuro.Intentos != 0 {
+		t.Errorf("el mensaje programado para más tarde se procesó: %+v", futuro)
+	}
+}
+
+func TestEsperaReintento(t *testing.T) {
+	casos := []struct {
+		intentos int
+		espera   time.Duration
+	}{
+		{1, time.Minute},
+		{2, 2 * time.Minute},
+		{4, 8 * time.Minute},
+		{7, 64 * time.Minute},
+		{8, esperaMaxReintento},
+		{20, esperaMaxReintento},
+	}
+	for _, caso := range casos {
+		if espera := esperaReintento(caso.intentos); espera != caso.espera {
+			t.Errorf("esperaReintento(%d) = %v, se esperaba %v", caso.intentos, espera, caso.espera)
+		}
+	}
+}
+
+func TestOutboxWorkerLiberaHastaDetener(t *testing.T) {
+	outbox := nuevoOutboxDePrueba(mensajeDePrueba(t, 1, "5491100000001", 0))
+	cfg := &config.Config{}
+	w := &WhatsAppService{
+		config:     cfg,
+		outboxRepo: outbox,
+		proveedor:  &proveedorDePrueba{},
+		detener:    make(chan struct{}),
+	}
+
+	w.IniciarOutboxWorker(10*time.Millisecond, NewCoordinacionService(cfg, repository.NewEstadoCompartidoEnMemoria()))
+	limite := time.Now().Add(2 * time.Second)
+	for {
+		outbox.mu.Lock()
+		_, enviado := outbox.enviados[1]
+		outbox.mu.Unlock()
+		if enviado {
+			break
+		}
+		if time.Now().After(limite) {
+			t.Fatal("el worker no envió el mensaje pendiente")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	ctx, cancelar := context.WithTimeout(context.Background(), time.Second)
+	defer cancelar()
+	if err := w.Detener(ctx); err != nil {
+		t.Fatalf("el worker no se detuvo: %v", err)
+	}
+
+	// Detenido no vuelve a levantar mensajes
+	outbox.mu.Lock()
+	outbox.pendientes = append(outbox.pendientes, mensajeDePrueba(t, 2, "5491100000002", 0))
+	outbox.mu.Unlock()
+	time.Sleep(50 * time.Millisecond)
+	outbox.mu.Lock()
+	defer outbox.mu.Unlock()
+	if _, enviado := outbox.enviados[2]; enviado {
+		t.Error("el worker siguió procesando después de Detener")
+	}
+}
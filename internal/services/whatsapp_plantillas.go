@@ -0,0 +1,383 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"CheeseHouse/internal/models"
+)
+
+// estadoPlantillaAprobada único estado de Meta con el que un template se puede enviar
+const estadoPlantillaAprobada = "APPROVED"
+
+// estadoPlantillaNoEncontrada el template configurado no existe en la cuenta
+const estadoPlantillaNoEncontrada = "NO_ENCONTRADO"
+
+// plantillasJuego templates con los que el juego manda el voucher
+var plantillasJuego = map[string]bool{"voucher_ganador": true, "voucher_perdedor": true}
+
+// estadoPorErrorPlantilla estado que se asume cuando la API rechaza un envío por el template,
+// hasta que el monitor vuelva a consultar
+var estadoPorErrorPlantilla = map[int]string{
+	132001: estadoPlantillaNoEncontrada,
+	132015: "PAUSED",
+	132016: "DISABLED",
+}
+
+// plantillaMeta template tal como lo lista la API de Meta
+type plantillaMeta struct {
+	Name           string `json:"name"`
+	Status         string `json:"status"`
+	Language       string `json:"language"`
+	RejectedReason string `json:"rejected_reason"`
+}
+
+// ActualizarEstadoPlantillas consulta a la API el estado de aprobación de los templates de
+// la cuenta y lo compara con los que usamos, avisando los que dejaron de estar aprobados
+func (w *WhatsAppService) ActualizarEstadoPlantillas() error {
+	if !w.isConfigured() {
+		return fmt.Errorf("WhatsApp no está configurado")
+	}
+	if w.config.WhatsAppBusinessAccountID == "" {
+		return fmt.Errorf("falta WHATSAPP_BUSINESS_ACCOUNT_ID")
+	}
+
+	enMeta, err := w.listarPlantillasMeta()
+	if err != nil {
+		return err
+	}
+
+	ahora := time.Now()
+	var bajas, altas []*models.EstadoPlantilla
+	for clave, variantes := range w.plantillasUsadas() {
+		for idioma, nombre := range variantes {
+			estado := &models.EstadoPlantilla{
+				Clave:        clave,
+				Nombre:       nombre,
+				Idioma:       idioma,
+				Estado:       estadoPlantillaNoEncontrada,
+				Juego:        plantillasJuego[clave],
+				ConsultadoEn: ahora,
+			}
+			if plantilla, ok := enMeta[clavePlantilla(nombre, idioma)]; ok {
+				estado.Estado = plantilla.Status
+				estado.Motivo = plantilla.RejectedReason
+				if estado.Motivo == "NONE" {
+					estado.Motivo = ""
+				}
+			}
+			estado.Utilizable = estado.Estado == estadoPlantillaAprobada
+
+			switch w.registrarEstadoPlantilla(estado) {
+			case -1:
+				bajas = append(bajas, estado)
+			case 1:
+				altas = append(altas, estado)
+			}
+		}
+	}
+	w.avisarCambiosPlantillas(bajas, altas)
+
+	log.Printf("📋 Estado de templates de WhatsApp actualizado (%d en la cuenta)", len(enMeta))
+	return nil
+}
+
+// listarPlantillasMeta recorre las páginas de templates de la cuenta de WhatsApp Business
+func (w *WhatsAppService) listarPlantillasMeta() (map[string]plantillaMeta, error) {
+	plantillas := make(map[string]plantillaMeta)
+	siguiente := fmt.Sprintf("%s/%s/message_templates?fields=name,status,language,rejected_reason&limit=100",
+		w.apiURL, url.PathEscape(w.config.WhatsAppBusinessAccountID))
+
+	for siguiente != "" {
+		req, err := http.NewRequest("GET", siguiente, nil)
+		if err != nil {
+			return nil, fmt.Errorf("error al crear request de templates: %w", err)
+		}
+		req.Header.Set("Authorization", "Bearer "+w.accessToken)
+
+		resp, err := w.client.Do(req)
+		if err != nil {
+			return nil, fmt.Errorf("error al consultar templates de WhatsApp: %w", err)
+		}
+
+		var pagina struct {
+			Data   []plantillaMeta `json:"data"`
+			Paging struct {
+				Next string `json:"next"`
+			} `json:"paging"`
+		}
+		if resp.StatusCode != http.StatusOK {
+			apiErr := parsearErrorAPI(resp)
+			resp.Body.Close()
+			return nil, apiErr
+		}
+		err = json.NewDecoder(resp.Body).Decode(&pagina)
+		resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("error al leer templates de WhatsApp: %w", err)
+		}
+
+		for _, plantilla := range pagina.Data {
+			plantillas[clavePlantilla(plantilla.Name, plantilla.Language)] = plantilla
+		}
+		siguiente = pagina.Paging.Next
+	}
+
+	return plantillas, nil
+}
+
+// plantillasUsadas templates configurados: clave de uso → código de idioma → nombre en Meta
+func (w *WhatsAppService) plantillasUsadas() map[string]map[string]string {
+	usadas := make(map[string]map[string]string)
+	for _, variante := range w.config.GetWhatsAppTemplatesPorIdioma() {
+		for clave, nombre := range variante.Templates {
+			if usadas[clave] == nil {
+				usadas[clave] = make(map[string]string)
+			}
+			usadas[clave][variante.CodigoIdioma] = nombre
+		}
+	}
+	return usadas
+}
+
+// clavePorNombre clave de uso de un template a partir de su nombre en Meta
+func (w *WhatsAppService) clavePorNombre(nombre string) string {
+	for clave, variantes := range w.plantillasUsadas() {
+		for _, usado := range variantes {
+			if usado == nombre {
+				return clave
+			}
+		}
+	}
+	return nombre
+}
+
+// registrarEstadoPlantilla guarda el estado y retorna -1 si el template dejó de ser
+// utilizable, 1 si volvió a serlo y 0 si no cambió. Un template que nunca se consultó se
+// considera utilizable
+func (w *WhatsAppService) registrarEstadoPlantilla(estado *models.EstadoPlantilla) int {
+	clave := clavePlantilla(estado.Nombre, estado.Idioma)
+
+	w.plantillasMu.Lock()
+	anterior, conocido := w.plantillas[clave]
+	w.plantillas[clave] = estado
+	w.plantillasMu.Unlock()
+
+	eraUtilizable := !conocido || anterior.Utilizable
+	switch {
+	case eraUtilizable && !estado.Utilizable:
+		return -1
+	case !eraUtilizable && estado.Utilizable:
+		return 1
+	}
+	return 0
+}
+
+// avisarCambiosPlantillas manda una sola alerta con los templates que quedaron rechazados,
+// pausados o deshabilitados y otra con los que se aprobaron de nuevo. Es un error si alguno
+// lo usa el juego: los vouchers salen en texto y fuera de la ventana de 24 horas Meta no
+// los entrega
+func (w *WhatsAppService) avisarCambiosPlantillas(bajas, altas []*models.EstadoPlantilla) {
+	if len(bajas) > 0 {
+		tipo := "warning"
+		lineas := make([]string, 0, len(bajas)+1)
+		for _, estado := range bajas {
+			linea := fmt.Sprintf("%s (%s) está %s", estado.Nombre, estado.Idioma, estado.Estado)
+			if estado.Motivo != "" {
+				linea += " - motivo: " + estado.Motivo
+			}
+			if estado.Juego {
+				tipo = "error"
+			}
+			lineas = append(lineas, linea)
+			log.Printf("🚨 Template de WhatsApp no utilizable: %s", linea)
+		}
+		if tipo == "error" {
+			lineas = append(lineas, "Los vouchers del juego se envían como texto hasta que se apruebe")
+		}
+		if w.notificaciones != nil {
+			w.notificaciones.Notificar(tipo, "Template de WhatsApp no utilizable", strings.Join(lineas, "\n"), "revisar_templates_whatsapp")
+		}
+	}
+
+	if len(altas) > 0 {
+		nombres := make([]string, 0, len(altas))
+		for _, estado := range altas {
+			nombres = append(nombres, fmt.Sprintf("%s (%s)", estado.Nombre, estado.Idioma))
+		}
+		log.Printf("✅ Templates de WhatsApp aprobados nuevamente: %s", strings.Join(nombres, ", "))
+		if w.notificaciones != nil {
+			w.notificaciones.Notificar("info", "Template de WhatsApp aprobado",
+				strings.Join(nombres, ", ")+" vuelven a estar aprobados; los envíos vuelven a usar el template",
+				"revisar_templates_whatsapp")
+		}
+	}
+}
+
+// plantillaUtilizable indica si el template se puede enviar según la última consulta
+func (w *WhatsAppService) plantillaUtilizable(nombre, idioma string) bool {
+	w.plantillasMu.Lock()
+	defer w.plantillasMu.Unlock()
+	estado, ok := w.plantillas[clavePlantilla(nombre, idioma)]
+	return !ok || estado.Utilizable
+}
+
+// enviarPlantillaConRespaldo envía un mensaje transaccional con template; si el template no
+// está aprobado (según el monitor o porque la API lo rechaza) envía el texto de respaldo.
+// Si el WhatsApp no sale y hay SMS de respaldo, el texto va por SMS. Con reintentar, un
+// fallo transitorio se encola en el outbox
+func (w *WhatsAppService) enviarPlantillaConRespaldo(message models.WhatsAppMessage, respaldo func() string, reintentar bool) error {
+	enviar := w.sendMessage
+	// Con SMS no se encola: el reintento llegaría después del SMS y el cliente recibiría
+	// dos veces el código
+	if reintentar && w.sms == nil {
+		enviar = func(m models.WhatsAppMessage) error { return w.enviarConReintento(m, CategoriaTransaccional) }
+	}
+
+	err := w.enviarPlantillaOTexto(message, respaldo, enviar)
+	if err == nil || w.sms == nil {
+		return err
+	}
+
+	telefono := w.normalizePhoneNumber(message.To)
+	if _, errSMS := w.sms.EnviarTexto(telefono, respaldo()); errSMS != nil {
+		log.Printf("❌ Tampoco salió el SMS de respaldo a %s: %v", telefono, errSMS)
+		return err
+	}
+	log.Printf("📨 WhatsApp a %s no salió (%v), se envió por SMS", telefono, err)
+	return nil
+}
+
+// enviarPlantillaOTexto envía el template por WhatsApp y, si no está disponible, el texto
+func (w *WhatsAppService) enviarPlantillaOTexto(message models.WhatsAppMessage, respaldo func() string, enviar func(models.WhatsAppMessage) error) error {
+	plantilla := message.Template
+	if plantilla != nil && w.plantillaUtilizable(plantilla.Name, plantilla.Language.Code) {
+		err := enviar(message)
+
+		var apiErr *models.WhatsAppAPIError
+		if !errors.As(err, &apiErr) {
+			return err
+		}
+		estado, esDePlantilla := estadoPorErrorPlantilla[apiErr.Code]
+		if !esDePlantilla {
+			return err
+		}
+		clave := w.clavePorNombre(plantilla.Name)
+		rechazada := &models.EstadoPlantilla{
+			Clave:        clave,
+			Nombre:       plantilla.Name,
+			Idioma:       plantilla.Language.Code,
+			Estado:       estado,
+			Juego:        plantillasJuego[clave],
+			ConsultadoEn: time.Now(),
+		}
+		if w.registrarEstadoPlantilla(rechazada) < 0 {
+			w.avisarCambiosPlantillas([]*models.EstadoPlantilla{rechazada}, nil)
+		}
+	}
+
+	log.Printf("📝 Template %s no disponible, se envía el texto de respaldo a %s", nombrePlantilla(plantilla), message.To)
+	return enviar(models.WhatsAppMessage{
+		MessagingProduct: "whatsapp",
+		To:               message.To,
+		Type:             "text",
+		Text:             &models.TextBody{Body: respaldo()},
+	})
+}
+
+// IniciarMonitorPlantillas consulta periódicamente el estado de los templates en segundo
+// plano. Con varias instancias consulta solo la líder y las demás toman lo que publicó
+func (w *WhatsAppService) IniciarMonitorPlantillas(intervalo time.Duration, coordinacion *CoordinacionService) {
+	if !w.isConfigured() || w.config.WhatsAppBusinessAccountID == "" {
+		return
+	}
+
+	go func() {
+		for {
+			if coordinacion.EsLider() {
+				if err := w.ActualizarEstadoPlantillas(); err != nil {
+					log.Printf("⚠️  No se pudo consultar el estado de los templates de WhatsApp: %v", err)
+				} else {
+					coordinacion.PublicarEstado("plantillas_whatsapp", w.GetEstadoPlantillas(), 3*intervalo)
+				}
+			} else {
+				var estados []models.EstadoPlantilla
+				if coordinacion.LeerEstado("plantillas_whatsapp", &estados) {
+					w.plantillasMu.Lock()
+					for i := range estados {
+						w.plantillas[clavePlantilla(estados[i].Nombre, estados[i].Idioma)] = &estados[i]
+					}
+					w.plantillasMu.Unlock()
+				}
+			}
+			select {
+			case <-w.detener:
+				return
+			case <-time.After(intervalo):
+			}
+		}
+	}()
+}
+
+// GetEstadoPlantillas retorna el último estado conocido de los templates, primero los no
+// utilizables
+func (w *WhatsAppService) GetEstadoPlantillas() []models.EstadoPlantilla {
+	w.plantillasMu.Lock()
+	estados := make([]models.EstadoPlantilla, 0, len(w.plantillas))
+	for _, estado := range w.plantillas {
+		estados = append(estados, *estado)
+	}
+	w.plantillasMu.Unlock()
+
+	sort.Slice(estados, func(i, j int) bool {
+		if estados[i].Utilizable != estados[j].Utilizable {
+			return !estados[i].Utilizable
+		}
+		if estados[i].Nombre != estados[j].Nombre {
+			return estados[i].Nombre < estados[j].Nombre
+		}
+		return estados[i].Idioma < estados[j].Idioma
+	})
+	return estados
+}
+
+// GetAlertasPlantillas genera una alerta por cada template que no se puede usar
+func (w *WhatsAppService) GetAlertasPlantillas() []map[string]interface{} {
+	var alertas []map[string]interface{}
+	for _, estado := range w.GetEstadoPlantillas() {
+		if estado.Utilizable {
+			continue
+		}
+		tipo := "warning"
+		if estado.Juego {
+			tipo = "error"
+		}
+		alertas = append(alertas, map[string]interface{}{
+			"tipo":        tipo,
+			"titulo":      "Template de WhatsApp no utilizable",
+			"descripcion": fmt.Sprintf("%s (%s) está %s", estado.Nombre, estado.Idioma, estado.Estado),
+			"accion":      "revisar_templates_whatsapp",
+		})
+	}
+	return alertas
+}
+
+// clavePlantilla identifica un template en Meta: el mismo nombre puede existir en varios idiomas
+func clavePlantilla(nombre, idioma string) string {
+	return nombre + "|" + idioma
+}
+
+// nombrePlantilla nombre del template para los logs
+func nombrePlantilla(plantilla *models.Template) string {
+	if plantilla == nil {
+		return "(sin template)"
+	}
+	return plantilla.Name
+}
@@ -0,0 +1,69 @@
+package services
+
+import (
+	"fmt"
+	"log"
+	"time"
+)
+
+// registrarSalud lleva desde cuándo fallan los envíos por problemas del proveedor (red,
+// límite de tasa o error del servidor). Los errores propios del mensaje (número inválido,
+// template) no cuentan como caída; un envío aceptado la da por terminada
+func (w *WhatsAppService) registrarSalud(errEnvio error) {
+	w.saludMu.Lock()
+	defer w.saludMu.Unlock()
+
+	if errEnvio == nil {
+		if w.fallaDesde != nil && w.caidaAvisada {
+			duracion := time.Since(*w.fallaDesde).Round(time.Minute)
+			log.Printf("✅ WhatsApp vuelve a entregar mensajes tras %v", duracion)
+			if w.notificaciones != nil {
+				w.notificaciones.Notificar("info", "WhatsApp recuperado",
+					fmt.Sprintf("Los envíos vuelven a funcionar después de %v; el juego deja de mostrar los códigos en pantalla", duracion),
+					"revisar_integracion_whatsapp")
+			}
+		}
+		w.fallaDesde = nil
+		w.caidaAvisada = false
+		return
+	}
+
+	if esReintentable(errEnvio) && w.fallaDesde == nil {
+		ahora := time.Now()
+		w.fallaDesde = &ahora
+	}
+}
+
+// EntregaCaida indica si los envíos fallan sin interrupción hace más de umbral. La primera
+// vez que se detecta la caída avisa al centro de notificaciones
+func (w *WhatsAppService) EntregaCaida(umbral time.Duration) bool {
+	w.saludMu.Lock()
+	defer w.saludMu.Unlock()
+
+	if w.fallaDesde == nil || time.Since(*w.fallaDesde) < umbral {
+		return false
+	}
+	if !w.caidaAvisada {
+		w.caidaAvisada = true
+		descripcion := fmt.Sprintf("Los envíos fallan desde hace %v", time.Since(*w.fallaDesde).Round(time.Minute))
+		if w.config.Game.CodeOnScreenOnOutage {
+			descripcion += "; el juego muestra los códigos en pantalla hasta que se recupere"
+		}
+		log.Printf("🚨 WhatsApp caído: %s", descripcion)
+		if w.notificaciones != nil {
+			w.notificaciones.Notificar("error", "WhatsApp sin entregar vouchers", descripcion, "revisar_integracion_whatsapp")
+		}
+	}
+	return true
+}
+
+// FallaDesde momento desde el que fallan los envíos; nil si funcionan
+func (w *WhatsAppService) FallaDesde() *time.Time {
+	w.saludMu.Lock()
+	defer w.saludMu.Unlock()
+	if w.fallaDesde == nil {
+		return nil
+	}
+	desde := *w.fallaDesde
+	return &desde
+}
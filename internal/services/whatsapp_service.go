@@ -1,47 +1,180 @@
 package services
 
 import (
-	"bytes"
-	"encoding/json"
+	"context"
+	"errors"
 	"fmt"
 	"log"
+	"math"
 	"net/http"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"CheeseHouse/internal/config"
+	"CheeseHouse/internal/i18n"
 	"CheeseHouse/internal/models"
+	"CheeseHouse/internal/repository"
+	"CheeseHouse/internal/telefono"
 )
 
 // WhatsAppService maneja toda la comunicación con WhatsApp Business API
 type WhatsAppService struct {
-	config        *config.Config
-	client        *http.Client
-	accessToken   string
-	phoneNumberID string
-	apiURL        string
+	config         *config.Config
+	client         *http.Client
+	accessToken    string
+	phoneNumberID  string
+	apiURL         string
+	mensajeLogRepo repository.MensajeLogRepository
+	outboxRepo     repository.OutboxRepository
+	preferencias   *PreferenciasService
+	horarios       *HorarioService
+	mensajes       *MensajeConfigService
+	notificaciones *NotificacionService
+
+	// Proveedor que entrega los mensajes y SMS de respaldo para los vouchers (nil sin Twilio)
+	proveedor ProveedorMensajeria
+	sms       ProveedorMensajeria
+
+	// Estado de aprobación de los templates (ver whatsapp_plantillas.go), por nombre e idioma
+	plantillasMu sync.Mutex
+	plantillas   map[string]*models.EstadoPlantilla
+
+	// Límite diario informado por el proveedor (ver whatsapp_cuota.go)
+	cuotaMu         sync.Mutex
+	tierProveedor   string
+	limiteProveedor int
+	calidadNumero   string
+	consultadoEn    *time.Time
+
+	// Salud de la entrega (ver whatsapp_salud.go)
+	saludMu      sync.Mutex
+	fallaDesde   *time.Time
+	caidaAvisada bool
+
+	// Envíos y workers en segundo plano que el apagado espera antes de cerrar la base
+	enCurso  sync.WaitGroup
+	detener  chan struct{}
+	detenido sync.Once
+}
+
+// erroresWhatsAppConocidos mapea códigos de error de la API a alertas accionables
+var erroresWhatsAppConocidos = map[int]struct {
+	Titulo string
+	Accion string
+}{
+	100:    {"Parámetro inválido en la API de WhatsApp", "revisar_integracion_whatsapp"},
+	190:    {"Token de acceso de WhatsApp vencido o inválido", "renovar_token_whatsapp"},
+	368:    {"Número de WhatsApp bloqueado temporalmente por políticas", "revisar_cuenta_whatsapp"},
+	130429: {"Límite de envío de WhatsApp alcanzado", "reducir_volumen_envios"},
+	131026: {"Destinatario sin WhatsApp o mensaje no entregable", "verificar_telefonos_clientes"},
+	131031: {"Cuenta de WhatsApp Business bloqueada", "revisar_cuenta_whatsapp"},
+	131047: {"Ventana de 24 horas vencida, se requiere template", "usar_template_aprobado"},
+	131048: {"Envíos limitados por reportes de spam", "revisar_calidad_mensajes"},
+	131056: {"Demasiados mensajes al mismo destinatario", "reducir_volumen_envios"},
+	132000: {"Cantidad de parámetros del template incorrecta", "revisar_templates_whatsapp"},
+	132001: {"Template de WhatsApp inexistente o no aprobado", "revisar_templates_whatsapp"},
+	132015: {"Template de WhatsApp pausado por baja calidad", "revisar_templates_whatsapp"},
+	132016: {"Template de WhatsApp deshabilitado", "revisar_templates_whatsapp"},
 }
 
 // NewWhatsAppService crea una nueva instancia del servicio de WhatsApp
-func NewWhatsAppService(cfg *config.Config) *WhatsAppService {
-	return &WhatsAppService{
-		config:        cfg,
-		client:        &http.Client{Timeout: 30 * time.Second},
-		accessToken:   cfg.WhatsAppToken,
-		phoneNumberID: cfg.WhatsAppPhoneNumberID,
-		apiURL:        cfg.WhatsAppURL,
+func NewWhatsAppService(
+	cfg *config.Config,
+	mensajeLogRepo repository.MensajeLogRepository,
+	outboxRepo repository.OutboxRepository,
+	preferencias *PreferenciasService,
+	horarios *HorarioService,
+	mensajes *MensajeConfigService,
+	notificaciones *NotificacionService,
+) *WhatsAppService {
+	client := &http.Client{Timeout: 30 * time.Second}
+	w := &WhatsAppService{
+		config:         cfg,
+		client:         client,
+		accessToken:    cfg.WhatsAppToken,
+		phoneNumberID:  cfg.WhatsAppPhoneNumberID,
+		apiURL:         cfg.WhatsAppURL,
+		mensajeLogRepo: mensajeLogRepo,
+		outboxRepo:     outboxRepo,
+		preferencias:   preferencias,
+		horarios:       horarios,
+		mensajes:       mensajes,
+		notificaciones: notificaciones,
+		plantillas:     make(map[string]*models.EstadoPlantilla),
+		detener:        make(chan struct{}),
+		proveedor:      nuevoProveedorWhatsApp(cfg, client),
+	}
+	if sms := nuevoProveedorTwilioSMS(cfg, client); sms != nil && cfg.SMS.Fallback {
+		w.sms = sms
+	}
+	return w
+}
+
+// EnvolverTransporte reemplaza el transporte HTTP del cliente de la API (ej. para inyectar
+// fallas en desarrollo). Se llama antes de iniciar los workers
+func (w *WhatsAppService) EnvolverTransporte(envolver func(http.RoundTripper) http.RoundTripper) {
+	w.client.Transport = envolver(w.client.Transport)
+}
+
+// EnSegundoPlano ejecuta un envío sin bloquear al que lo pide; Detener espera a que termine
+func (w *WhatsAppService) EnSegundoPlano(envio func()) {
+	w.enCurso.Add(1)
+	go func() {
+		defer w.enCurso.Done()
+		envio()
+	}()
+}
+
+// Detener frena los workers periódicos y espera los envíos en curso hasta que venza ctx.
+// Lo que quedó en el outbox se retoma al volver a arrancar
+func (w *WhatsAppService) Detener(ctx context.Context) error {
+	w.detenido.Do(func() { close(w.detener) })
+
+	listo := make(chan struct{})
+	go func() {
+		w.enCurso.Wait()
+		close(listo)
+	}()
+
+	select {
+	case <-listo:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("quedaron envíos de WhatsApp sin terminar: %w", ctx.Err())
 	}
 }
 
 // EnviarVoucherGanador envía voucher cuando el cliente gana
 func (w *WhatsAppService) EnviarVoucherGanador(cliente *models.Cliente, voucher *models.Voucher) error {
+	return w.enviarVoucher(cliente, voucher, true, true)
+}
+
+// EnviarVoucherPerdedor envía voucher cuando el cliente pierde
+func (w *WhatsAppService) EnviarVoucherPerdedor(cliente *models.Cliente, voucher *models.Voucher) error {
+	return w.enviarVoucher(cliente, voucher, false, true)
+}
+
+// enviarVoucher envía el template del voucher ganador o perdedor, con el texto (o el SMS)
+// de respaldo. Con reintentar, un fallo transitorio deja el mensaje en el outbox; sin él
+// el error vuelve al que llama, para cuando el envío ya sale del outbox
+func (w *WhatsAppService) enviarVoucher(cliente *models.Cliente, voucher *models.Voucher, gano bool, reintentar bool) error {
+	clave, respaldo := "voucher_perdedor", models.MensajeVoucherPerdedor
+	if gano {
+		clave, respaldo = "voucher_ganador", models.MensajeVoucherGanador
+	}
+
 	if !w.isConfigured() {
-		log.Printf(" WhatsApp no configurado, simulando envío de voucher ganador para %s", cliente.Telefono)
+		log.Printf("⚠️  WhatsApp no configurado, simulando envío de %s para %s", clave, cliente.Telefono)
+		return nil
+	}
+
+	if !w.permiteEnvio(cliente, CategoriaTransaccional) {
 		return nil
 	}
 
-	templates := w.config.GetWhatsAppTemplates()
-	templateName := templates["voucher_ganador"]
+	templateName, codigoIdioma := w.config.GetWhatsAppTemplate(clave, cliente.Idioma)
 
 	message := models.WhatsAppMessage{
 		MessagingProduct: "whatsapp",
@@ -49,7 +182,7 @@ func (w *WhatsAppService) EnviarVoucherGanador(cliente *models.Cliente, voucher
 		Type:             "template",
 		Template: &models.Template{
 			Name:     templateName,
-			Language: models.Language{Code: "es"},
+			Language: models.Language{Code: codigoIdioma},
 			Components: []models.Component{
 				{
 					Type: "body",
@@ -57,43 +190,174 @@ func (w *WhatsAppService) EnviarVoucherGanador(cliente *models.Cliente, voucher
 						{Type: "text", Text: cliente.Nombre},
 						{Type: "text", Text: voucher.Codigo},
 						{Type: "text", Text: fmt.Sprintf("%d%%", voucher.Descuento)},
-						{Type: "text", Text: voucher.FechaVencimiento.Format("02/01/2006")},
+						{Type: "text", Text: w.vencimientoVoucher(voucher, cliente.Idioma)},
 					},
 				},
 			},
 		},
 	}
 
+	return w.enviarPlantillaConRespaldo(message, func() string {
+		return w.texto(respaldo, map[string]string{
+			"nombre":      cliente.Nombre,
+			"codigo":      voucher.Codigo,
+			"descuento":   fmt.Sprintf("%d%%", voucher.Descuento),
+			"vencimiento": w.vencimientoVoucher(voucher, cliente.Idioma),
+		})
+	}, reintentar)
+}
+
+// EnviarMensajeMarketing envía mensajes promocionales de una campaña con el voucher del
+// cliente. Retorna con qué mensaje salió para seguir su entrega (vacío si se simuló o se omitió)
+func (w *WhatsAppService) EnviarMensajeMarketing(cliente *models.Cliente, campanaID uint, mensaje string, voucher *models.Voucher) (EnvioWhatsApp, error) {
+	if !w.isConfigured() {
+		log.Printf("⚠️  WhatsApp no configurado, simulando envío de marketing para %s", cliente.Telefono)
+		return EnvioWhatsApp{}, nil
+	}
+
+	if !w.permiteEnvio(cliente, CategoriaMarketing) {
+		return EnvioWhatsApp{}, nil
+	}
+	if err := w.verificarCupoMarketing(); err != nil {
+		return EnvioWhatsApp{}, err
+	}
+
+	// Para marketing, usar mensaje de texto simple (más flexible)
+	mensajeCompleto := w.texto(models.MensajeMarketing, map[string]string{
+		"nombre":      cliente.Nombre,
+		"mensaje":     mensaje,
+		"codigo":      voucher.Codigo,
+		"vencimiento": w.config.Fechas(cliente.Idioma).Fecha(voucher.FechaVencimiento),
+	})
+	if w.preferencias != nil {
+		mensajeCompleto += "\n\nNo querés recibir más promociones? " + w.preferencias.GenerarLinkBaja(cliente.ID, campanaID)
+		mensajeCompleto += "\nGestioná qué mensajes recibís: " + w.preferencias.GenerarLinkPreferencias(cliente.ID)
+	}
+
+	message := models.WhatsAppMessage{
+		MessagingProduct: "whatsapp",
+		To:               w.formatPhoneNumber(cliente.Telefono),
+		Type:             "text",
+		Text: &models.TextBody{
+			Body: mensajeCompleto,
+		},
+	}
+
+	return w.programarEnvio(message, CategoriaMarketing)
+}
+
+// EnviarRespuestaAutomatica envía respuesta automática a pedidos: con el local abierto
+// promete el tiempo de respuesta y fuera de horario (o en feriados) avisa cuándo abrimos
+func (w *WhatsAppService) EnviarRespuestaAutomatica(telefono string, nombreCliente string) error {
+	if !w.isConfigured() {
+		log.Printf("⚠️  WhatsApp no configurado, simulando respuesta automática para %s", telefono)
+		return nil
+	}
+
+	if w.preferencias != nil && !w.preferencias.PermiteEnvioTelefono(telefono, CategoriaTransaccional, "whatsapp") {
+		log.Printf("🔕 %s no acepta mensajes por WhatsApp, se omite respuesta automática", telefono)
+		return nil
+	}
+
+	message := models.WhatsAppMessage{
+		MessagingProduct: "whatsapp",
+		To:               w.formatPhoneNumber(telefono),
+		Type:             "text",
+		Text: &models.TextBody{
+			Body: w.armarRespuestaAutomatica(nombreCliente, time.Now()),
+		},
+	}
+
 	return w.sendMessage(message)
 }
 
-// EnviarVoucherPerdedor envía voucher cuando el cliente pierde
-func (w *WhatsAppService) EnviarVoucherPerdedor(cliente *models.Cliente, voucher *models.Voucher) error {
+// armarRespuestaAutomatica elige el texto según el horario de la sucursal configurada
+func (w *WhatsAppService) armarRespuestaAutomatica(nombreCliente string, ahora time.Time) string {
+	aviso := "⏰ Te responderemos en breve\n📞 O puedes llamarnos directamente"
+	if w.horarios != nil {
+		estado, err := w.horarios.Estado("", ahora)
+		switch {
+		case err != nil:
+			log.Printf("⚠️  Error consultando horario de atención: %v", err)
+		case estado.Abierta:
+			if minutos := w.config.BusinessHours.ResponseWindowMinutes; minutos > 0 {
+				aviso = fmt.Sprintf("⏰ Te respondemos en los próximos %d minutos\n📞 O puedes llamarnos directamente", minutos)
+			}
+		default:
+			aviso = "🌙 En este momento estamos cerrados"
+			if estado.Feriado != "" {
+				aviso = fmt.Sprintf("🌙 Hoy estamos cerrados por %s", estado.Feriado)
+			}
+			if estado.ProximaApertura != nil {
+				aviso += fmt.Sprintf(", abrimos %s hs.\nTe respondemos apenas abramos", w.horarios.DescribirApertura(*estado.ProximaApertura, ahora))
+			} else {
+				aviso += ".\nTe respondemos apenas volvamos a abrir"
+			}
+		}
+	}
+
+	return w.texto(models.MensajeRespuestaAutomatica, map[string]string{
+		"nombre": nombreCliente,
+		"aviso":  aviso,
+	})
+}
+
+// EnviarEstadoPedido avisa al cliente que su pedido cambió de estado usando el
+// template "pedido_<estado>", que también funciona fuera de la ventana de 24 horas
+func (w *WhatsAppService) EnviarEstadoPedido(pedido *models.Pedido, cliente *models.Cliente) error {
+	return w.enviarTemplatePedido(pedido, cliente, "pedido_"+pedido.Estado)
+}
+
+// EnviarConfirmacionPedido avisa al cliente que se tomó su pedido, con la espera
+// estimada en minutos y la hora aproximada en que va a estar listo
+func (w *WhatsAppService) EnviarConfirmacionPedido(pedido *models.Pedido, cliente *models.Cliente, minutosEspera int) error {
+	listo := time.Now().Add(time.Duration(minutosEspera) * time.Minute)
+	if pedido.PrometidoPara != nil {
+		listo = *pedido.PrometidoPara
+	}
+	return w.enviarTemplatePedido(pedido, cliente, "pedido_confirmado",
+		fmt.Sprintf("%d", minutosEspera),
+		w.config.Fechas(cliente.Idioma).Hora(listo))
+}
+
+// enviarTemplatePedido envía un template de pedido con el nombre del cliente, el número
+// de pedido y los parámetros extra que use ese template
+func (w *WhatsAppService) enviarTemplatePedido(pedido *models.Pedido, cliente *models.Cliente, plantilla string, extra ...string) error {
 	if !w.isConfigured() {
-		log.Printf("⚠️  WhatsApp no configurado, simulando envío de voucher perdedor para %s", cliente.Telefono)
+		log.Printf("⚠️  WhatsApp no configurado, simulando %s para %s", plantilla, pedido.Telefono)
 		return nil
 	}
 
-	templates := w.config.GetWhatsAppTemplates()
-	templateName := templates["voucher_perdedor"]
+	nombre, idioma := "Cliente", w.config.DefaultLanguage
+	if cliente != nil {
+		if !w.permiteEnvio(cliente, CategoriaTransaccional) {
+			return nil
+		}
+		nombre, idioma = cliente.Nombre, cliente.Idioma
+	}
+
+	templateName, codigoIdioma := w.config.GetWhatsAppTemplate(plantilla, idioma)
+	if templateName == "" {
+		return fmt.Errorf("no hay template de WhatsApp %s", plantilla)
+	}
+
+	parametros := []models.Parameter{
+		{Type: "text", Text: nombre},
+		{Type: "text", Text: fmt.Sprintf("%d", pedido.ID)},
+	}
+	for _, valor := range extra {
+		parametros = append(parametros, models.Parameter{Type: "text", Text: valor})
+	}
 
 	message := models.WhatsAppMessage{
 		MessagingProduct: "whatsapp",
-		To:               w.formatPhoneNumber(cliente.Telefono),
+		To:               w.formatPhoneNumber(pedido.Telefono),
 		Type:             "template",
 		Template: &models.Template{
 			Name:     templateName,
-			Language: models.Language{Code: "es"},
+			Language: models.Language{Code: codigoIdioma},
 			Components: []models.Component{
-				{
-					Type: "body",
-					Parameters: []models.Parameter{
-						{Type: "text", Text: cliente.Nombre},
-						{Type: "text", Text: voucher.Codigo},
-						{Type: "text", Text: fmt.Sprintf("%d%%", voucher.Descuento)},
-						{Type: "text", Text: voucher.FechaVencimiento.Format("02/01/2006")},
-					},
-				},
+				{Type: "body", Parameters: parametros},
 			},
 		},
 	}
@@ -101,88 +365,268 @@ func (w *WhatsAppService) EnviarVoucherPerdedor(cliente *models.Cliente, voucher
 	return w.sendMessage(message)
 }
 
-// EnviarMensajeMarketing envía mensajes promocionales
-func (w *WhatsAppService) EnviarMensajeMarketing(cliente *models.Cliente, mensaje string, codigoVoucher string) error {
+// EnviarRespuestaManual envía el texto escrito por un empleado desde la bandeja de conversaciones
+func (w *WhatsAppService) EnviarRespuestaManual(telefono string, texto string) error {
 	if !w.isConfigured() {
-		log.Printf("⚠️  WhatsApp no configurado, simulando envío de marketing para %s", cliente.Telefono)
+		log.Printf("⚠️  WhatsApp no configurado, simulando respuesta manual para %s", telefono)
 		return nil
 	}
 
-	// Para marketing, usar mensaje de texto simple (más flexible)
-	mensajeCompleto := fmt.Sprintf("🧀 *CheeseHouse* 🧀\n\n%s\n\n🎁 *Código: %s*\n\n¡Te esperamos!",
-		mensaje, codigoVoucher)
-
 	message := models.WhatsAppMessage{
 		MessagingProduct: "whatsapp",
-		To:               w.formatPhoneNumber(cliente.Telefono),
+		To:               w.formatPhoneNumber(telefono),
 		Type:             "text",
 		Text: &models.TextBody{
-			Body: mensajeCompleto,
+			Body: texto,
 		},
 	}
 
 	return w.sendMessage(message)
 }
 
-// EnviarRespuestaAutomatica envía respuesta automática a pedidos
-func (w *WhatsAppService) EnviarRespuestaAutomatica(telefono string, nombreCliente string) error {
+// EnviarCodigoVerificacion envía un código de un solo uso para consultar datos del cliente
+func (w *WhatsAppService) EnviarCodigoVerificacion(telefono string, codigo string) error {
 	if !w.isConfigured() {
-		log.Printf("⚠️  WhatsApp no configurado, simulando respuesta automática para %s", telefono)
+		log.Printf("⚠️  WhatsApp no configurado, simulando envío de código de verificación para %s", telefono)
 		return nil
 	}
 
-	mensaje := fmt.Sprintf("¡Hola %s! 👋\n\n🧀 Gracias por contactar *CheeseHouse*\n\n⏰ Te responderemos en breve\n📞 O puedes llamarnos directamente\n\n¡Gracias por elegirnos! 🧀", nombreCliente)
-
 	message := models.WhatsAppMessage{
 		MessagingProduct: "whatsapp",
 		To:               w.formatPhoneNumber(telefono),
 		Type:             "text",
 		Text: &models.TextBody{
-			Body: mensaje,
+			Body: w.texto(models.MensajeCodigoVerificacion, map[string]string{"codigo": codigo}),
 		},
 	}
 
 	return w.sendMessage(message)
 }
 
+// texto arma un mensaje con el texto editado desde el panel (o el original si no hay servicio)
+func (w *WhatsAppService) texto(clave string, variables map[string]string) string {
+	if w.mensajes == nil {
+		editable, _ := buscarMensajeEditable(clave)
+		return renderizarMensaje(editable.Contenido, variables)
+	}
+	return w.mensajes.Renderizar(clave, variables)
+}
+
+// permiteEnvio consulta el centro de preferencias antes de enviar al cliente
+func (w *WhatsAppService) permiteEnvio(cliente *models.Cliente, categoria string) bool {
+	if w.preferencias == nil {
+		return true
+	}
+	if !w.preferencias.PermiteEnvio(cliente.ID, categoria, "whatsapp") {
+		log.Printf("🔕 %s no acepta mensajes %s por WhatsApp, se omite envío", cliente.Telefono, categoria)
+		return false
+	}
+	return true
+}
+
 // sendMessage envía un mensaje a WhatsApp API
 func (w *WhatsAppService) sendMessage(message models.WhatsAppMessage) error {
-	url := fmt.Sprintf("%s/%s/messages", w.apiURL, w.phoneNumberID)
+	_, err := w.enviarMensaje(message)
+	return err
+}
 
-	jsonData, err := json.Marshal(message)
-	if err != nil {
-		return fmt.Errorf("error al serializar mensaje: %w", err)
+// enviarMensaje entrega el mensaje con el proveedor y retorna el ID con el que lo aceptó
+// (el wamid en WhatsApp), para seguir su entrega con el webhook de estados
+func (w *WhatsAppService) enviarMensaje(message models.WhatsAppMessage) (string, error) {
+	var (
+		messageID string
+		err       error
+	)
+	inicio := time.Now()
+	if message.Template != nil {
+		messageID, err = w.proveedor.EnviarPlantilla(message.To, message.Template)
+	} else {
+		texto := ""
+		if message.Text != nil {
+			texto = message.Text.Body
+		}
+		messageID, err = w.proveedor.EnviarTexto(message.To, texto)
 	}
+	latencia := time.Since(inicio)
 
-	req, err := http.NewRequest("POST", url, bytes.NewBuffer(jsonData))
+	w.registrarMensaje(message, messageID, latencia, err)
+	w.registrarSalud(err)
 	if err != nil {
-		return fmt.Errorf("error al crear request: %w", err)
+		return "", err
 	}
+	return messageID, nil
+}
 
-	req.Header.Set("Authorization", "Bearer "+w.accessToken)
-	req.Header.Set("Content-Type", "application/json")
+// registrarMensaje guarda el resultado del envío en el log de mensajes
+func (w *WhatsAppService) registrarMensaje(message models.WhatsAppMessage, messageID string, latencia time.Duration, sendErr error) {
+	if w.mensajeLogRepo == nil {
+		return
+	}
 
-	log.Printf("📱 Enviando WhatsApp a %s: %s", message.To, string(jsonData))
+	registro := &models.MensajeLog{
+		Telefono:   w.normalizePhoneNumber(message.To),
+		Tipo:       message.Type,
+		Estado:     "enviado",
+		MessageID:  messageID,
+		LatenciaMs: int(latencia.Milliseconds()),
+	}
+	if message.Template != nil {
+		registro.Plantilla = message.Template.Name
+	}
 
-	resp, err := w.client.Do(req)
+	if sendErr != nil {
+		registro.Estado = "fallido"
+		registro.ErrorMensaje = sendErr.Error()
+
+		var apiErr *models.WhatsAppAPIError
+		if errors.As(sendErr, &apiErr) {
+			registro.HTTPStatus = apiErr.HTTPStatus
+			registro.FbTraceID = apiErr.FbTraceID
+			if apiErr.Code != 0 {
+				codigo := apiErr.Code
+				registro.ErrorCodigo = &codigo
+			}
+			if apiErr.Subcode != 0 {
+				subcodigo := apiErr.Subcode
+				registro.ErrorSubcodigo = &subcodigo
+			}
+		}
+	}
+
+	if err := w.mensajeLogRepo.Crear(registro); err != nil {
+		log.Printf("⚠️  Error registrando mensaje en el log: %v", err)
+	}
+}
+
+// ProcesarEstados registra en el log de mensajes las entregas y los fallos que informa el
+// webhook para los mensajes ya aceptados por la API
+func (w *WhatsAppService) ProcesarEstados(webhook models.WhatsAppWebhookMessage) {
+	if w.mensajeLogRepo == nil {
+		return
+	}
+
+	for _, entry := range webhook.Entry {
+		for _, change := range entry.Changes {
+			for _, estado := range change.Value.Statuses {
+				var err error
+				switch estado.Status {
+				case "delivered", "read":
+					err = w.mensajeLogRepo.MarcarEntregado(estado.ID, momentoEstadoWhatsApp(estado.Timestamp))
+				case "failed":
+					codigo, mensaje := 0, "entrega fallida"
+					if len(estado.Errors) > 0 {
+						codigo, mensaje = estado.Errors[0].Code, estado.Errors[0].Title
+					}
+					log.Printf("⚠️  WhatsApp informó fallo de entrega a %s: %s", estado.RecipientID, mensaje)
+					err = w.mensajeLogRepo.MarcarFallido(estado.ID, codigo, mensaje)
+				}
+				if err != nil {
+					log.Printf("⚠️  Error registrando estado %s de %s: %v", estado.Status, estado.ID, err)
+				}
+			}
+		}
+	}
+}
+
+// momentoEstadoWhatsApp convierte el timestamp (segundos Unix) de un estado del webhook;
+// si no se puede leer se usa la hora de llegada
+func momentoEstadoWhatsApp(timestamp string) time.Time {
+	if segundos, err := strconv.ParseInt(timestamp, 10, 64); err == nil {
+		return time.Unix(segundos, 0)
+	}
+	return time.Now()
+}
+
+// GetEstadisticasEnvios resume los envíos desde la fecha indicada por día y por plantilla
+func (w *WhatsAppService) GetEstadisticasEnvios(desde time.Time) (*models.EstadisticasEnvios, error) {
+	estadisticas := &models.EstadisticasEnvios{
+		Desde:        desde,
+		PorDia:       []models.EstadisticaEnvios{},
+		PorPlantilla: []models.EstadisticaEnvios{},
+	}
+	if w.mensajeLogRepo == nil {
+		return estadisticas, nil
+	}
+
+	porDia, err := w.mensajeLogRepo.EstadisticasPorDia(desde)
 	if err != nil {
-		return fmt.Errorf("error al enviar mensaje: %w", err)
+		return nil, err
+	}
+	porPlantilla, err := w.mensajeLogRepo.EstadisticasPorPlantilla(desde)
+	if err != nil {
+		return nil, err
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		var errorResp map[string]interface{}
-		json.NewDecoder(resp.Body).Decode(&errorResp)
-		return fmt.Errorf("WhatsApp API error %d: %v", resp.StatusCode, errorResp)
+	// El total pondera los promedios por la cantidad de mensajes de cada día
+	var latencia, entrega float64
+	conLatencia := 0
+	for i := range porDia {
+		dia := &porDia[i]
+		dia.TasaFallo = tasaFalloEnvios(dia)
+		estadisticas.Total.Intentos += dia.Intentos
+		estadisticas.Total.Enviados += dia.Enviados
+		estadisticas.Total.Entregados += dia.Entregados
+		estadisticas.Total.Fallidos += dia.Fallidos
+		if dia.LatenciaMs > 0 {
+			latencia += dia.LatenciaMs * float64(dia.Intentos)
+			conLatencia += dia.Intentos
+		}
+		entrega += dia.EntregaSegundos * float64(dia.Entregados)
+	}
+	if conLatencia > 0 {
+		estadisticas.Total.LatenciaMs = latencia / float64(conLatencia)
 	}
+	if estadisticas.Total.Entregados > 0 {
+		estadisticas.Total.EntregaSegundos = entrega / float64(estadisticas.Total.Entregados)
+	}
+	estadisticas.Total.TasaFallo = tasaFalloEnvios(&estadisticas.Total)
 
-	// Leer respuesta de éxito
-	var successResp map[string]interface{}
-	if err := json.NewDecoder(resp.Body).Decode(&successResp); err == nil {
-		log.Printf("✅ WhatsApp enviado exitosamente: %v", successResp)
+	for i := range porPlantilla {
+		porPlantilla[i].TasaFallo = tasaFalloEnvios(&porPlantilla[i])
 	}
 
-	return nil
+	estadisticas.PorDia = append(estadisticas.PorDia, porDia...)
+	estadisticas.PorPlantilla = append(estadisticas.PorPlantilla, porPlantilla...)
+	return estadisticas, nil
+}
+
+// tasaFalloEnvios porcentaje de intentos fallidos, con un decimal
+func tasaFalloEnvios(e *models.EstadisticaEnvios) float64 {
+	if e.Intentos == 0 {
+		return 0
+	}
+	return math.Round(float64(e.Fallidos)*1000/float64(e.Intentos)) / 10
+}
+
+// GetAlertasErrores genera alertas accionables a partir de los errores recientes de la API
+func (w *WhatsAppService) GetAlertasErrores(desde time.Time) []map[string]interface{} {
+	var alertas []map[string]interface{}
+	if w.mensajeLogRepo == nil {
+		return alertas
+	}
+
+	errores, err := w.mensajeLogRepo.ContarErroresPorCodigo(desde)
+	if err != nil {
+		log.Printf("⚠️  Error obteniendo errores de WhatsApp: %v", err)
+		return alertas
+	}
+
+	for codigo, total := range errores {
+		alerta := map[string]interface{}{
+			"tipo":        "error",
+			"titulo":      fmt.Sprintf("Error de WhatsApp (código %d)", codigo),
+			"descripcion": fmt.Sprintf("%d mensajes fallaron con el código %d", total, codigo),
+			"accion":      "revisar_log_mensajes",
+			"codigo":      codigo,
+		}
+		if conocido, ok := erroresWhatsAppConocidos[codigo]; ok {
+			alerta["titulo"] = conocido.Titulo
+			alerta["accion"] = conocido.Accion
+		}
+		alertas = append(alertas, alerta)
+	}
+
+	return alertas
 }
 
 // ProcesarMensajeEntrante procesa mensajes recibidos por webhook
@@ -223,98 +667,94 @@ func (w *WhatsAppService) ProcesarMensajeEntrante(webhook models.WhatsAppWebhook
 	return pedidos
 }
 
+// vencimientoVoucher texto del vencimiento para las plantillas: la fecha, o la hora límite
+// si es un voucher flash ("hoy hasta las 23:00")
+func (w *WhatsAppService) vencimientoVoucher(voucher *models.Voucher, idioma string) string {
+	formato := w.config.Fechas(idioma)
+	if !voucher.Flash {
+		return formato.Fecha(voucher.FechaVencimiento)
+	}
+
+	vence := voucher.FechaVencimiento
+	if !w.config.InicioDelDia(vence).Equal(w.config.InicioDelDia(time.Now())) {
+		return formato.FechaHora(vence)
+	}
+	locale := i18n.Detectar(idioma, "", w.config.DefaultLanguage)
+	return strings.Replace(i18n.Textos(locale)["flash_vence_hoy"], "{hora}", formato.Hora(vence), 1)
+}
+
 // formatPhoneNumber formatea número para WhatsApp API (sin +)
 func (w *WhatsAppService) formatPhoneNumber(phone string) string {
 	// WhatsApp API espera números sin el símbolo +
-	return strings.TrimPrefix(phone, "+")
+	return telefono.SinMas(phone)
 }
 
-// normalizePhoneNumber normaliza número recibido para guardar en BD
+// normalizePhoneNumber normaliza número recibido de WhatsApp (sin +) para guardar en BD.
+// Es la clave de MensajeLog, el outbox y las conversaciones: todo lo que entra o sale por
+// WhatsApp pasa por acá para que los registros de un mismo cliente coincidan
 func (w *WhatsAppService) normalizePhoneNumber(phone string) string {
-	// Asegurar que tenga el prefijo +
-	if !strings.HasPrefix(phone, "+") {
-		return "+" + phone
+	normalizado, err := telefono.Normalizar("+" + strings.TrimPrefix(phone, "+"))
+	if err != nil {
+		return "+" + strings.TrimPrefix(phone, "+")
 	}
-	return phone
+	return normalizado
 }
 
 // ValidarTelefonoArgentino valida formato de teléfono argentino
-func (w *WhatsAppService) ValidarTelefonoArgentino(telefono string) error {
+func (w *WhatsAppService) ValidarTelefonoArgentino(tel string) error {
 	validation := w.config.GetPhoneValidation()
 
-	// Remover espacios y caracteres especiales
-	cleanPhone := strings.ReplaceAll(telefono, " ", "")
-	cleanPhone = strings.ReplaceAll(cleanPhone, "-", "")
-	cleanPhone = strings.ReplaceAll(cleanPhone, "(", "")
-	cleanPhone = strings.ReplaceAll(cleanPhone, ")", "")
-
-	// Verificar longitud
-	if len(cleanPhone) < validation.MinLength || len(cleanPhone) > validation.MaxLength {
-		return fmt.Errorf("número de teléfono debe tener entre %d y %d dígitos",
-			validation.MinLength, validation.MaxLength)
+	normalizado, err := telefono.Normalizar(tel)
+	if err != nil {
+		return err
 	}
 
-	// Verificar que empiece con +54 (Argentina) o permitir internacionales
-	if !strings.HasPrefix(cleanPhone, validation.CountryCode) {
+	// Verificar que sea argentino o permitir internacionales
+	if !telefono.EsArgentino(normalizado) {
 		if !validation.AllowIntl {
 			return fmt.Errorf("número debe ser argentino (+54)")
 		}
-		// Si permite internacionales, verificar que empiece con +
-		if !strings.HasPrefix(cleanPhone, "+") {
-			return fmt.Errorf("número internacional debe empezar con +")
-		}
-	} else {
-		// Es argentino, verificar código de área
-		withoutCountryCode := strings.TrimPrefix(cleanPhone, validation.CountryCode)
-
-		isValidAreaCode := false
-		for _, areaCode := range validation.AreaCodes {
-			if strings.HasPrefix(withoutCountryCode, areaCode) {
-				isValidAreaCode = true
-				break
-			}
-		}
+		return nil
+	}
 
-		if !isValidAreaCode && len(withoutCountryCode) < 10 {
-			return fmt.Errorf("código de área no válido para Argentina")
+	// Es argentino, verificar código de área (la lista no es exhaustiva, solo se audita)
+	nacional := telefono.NumeroNacional(normalizado)
+	for _, areaCode := range validation.AreaCodes {
+		if strings.HasPrefix(nacional, areaCode) {
+			return nil
 		}
 	}
 
+	log.Printf("⚠️  Código de área no reconocido para %s", normalizado)
 	return nil
 }
 
-// NormalizarTelefono normaliza y formatea un teléfono
-func (w *WhatsAppService) NormalizarTelefono(telefono string) string {
-	// Remover caracteres especiales
-	cleanPhone := strings.ReplaceAll(telefono, " ", "")
-	cleanPhone = strings.ReplaceAll(cleanPhone, "-", "")
-	cleanPhone = strings.ReplaceAll(cleanPhone, "(", "")
-	cleanPhone = strings.ReplaceAll(cleanPhone, ")", "")
-
-	// Asegurar que empiece con +
-	if !strings.HasPrefix(cleanPhone, "+") {
-		// Asumir argentino si no tiene prefijo internacional
-		if len(cleanPhone) >= 10 {
-			cleanPhone = "+54" + cleanPhone
-		}
+// NormalizarTelefono normaliza un teléfono a E.164 (+549XXXXXXXXXX para Argentina).
+// Si el número no se puede normalizar se retorna sin separadores para que la
+// validación informe el error
+func (w *WhatsAppService) NormalizarTelefono(tel string) string {
+	normalizado, err := telefono.Normalizar(tel)
+	if err != nil {
+		return strings.NewReplacer(" ", "", "-", "", "(", "", ")", "").Replace(tel)
 	}
-
-	return cleanPhone
+	return normalizado
 }
 
 // isConfigured verifica si WhatsApp está configurado
 func (w *WhatsAppService) isConfigured() bool {
-	return w.accessToken != "" && w.phoneNumberID != ""
+	return w.proveedor.Configurado()
 }
 
-// GetStatus retorna el estado de configuración de WhatsApp
+// GetStatus retorna el estado de configuración de WhatsApp y del SMS de respaldo
 func (w *WhatsAppService) GetStatus() map[string]interface{} {
-	return map[string]interface{}{
-		"configured":      w.isConfigured(),
-		"access_token":    w.accessToken != "",
-		"phone_number_id": w.phoneNumberID != "",
-		"api_url":         w.apiURL,
+	estado := w.proveedor.Estado()
+	estado["proveedor"] = w.proveedor.Nombre()
+	estado["business_account"] = w.config.WhatsAppBusinessAccountID != ""
+	estado["falla_desde"] = w.FallaDesde()
+	if w.sms != nil {
+		estado["sms_respaldo"] = w.sms.Estado()
 	}
+	return estado
 }
 
 // TestConnection prueba la conexión con WhatsApp API
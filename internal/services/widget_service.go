@@ -0,0 +1,214 @@
+package services
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"CheeseHouse/internal/config"
+	"CheeseHouse/internal/models"
+	"CheeseHouse/internal/repository"
+)
+
+// ErrWidgetNoAutorizado la clave no existe, el widget está desactivado o el origen no
+// está en su lista
+var ErrWidgetNoAutorizado = errors.New("widget no autorizado para este sitio")
+
+// WidgetService administra los sitios externos que pueden embeber el juego. Cada widget
+// tiene una clave pública y una lista de orígenes; las partidas quedan atribuidas a él
+type WidgetService struct {
+	config      *config.Config
+	widgetRepo  repository.WidgetRepository
+	voucherRepo repository.VoucherRepository
+
+	mu        sync.RWMutex
+	cache     map[string]*models.Widget // Por clave
+	cargadoEn time.Time                 // Cuándo se empezó a llenar el cache; vence entero
+}
+
+// NewWidgetService crea una nueva instancia del servicio de widgets
+func NewWidgetService(cfg *config.Config, widgetRepo repository.WidgetRepository, voucherRepo repository.VoucherRepository) *WidgetService {
+	return &WidgetService{
+		config:      cfg,
+		widgetRepo:  widgetRepo,
+		voucherRepo: voucherRepo,
+		cache:       make(map[string]*models.Widget),
+	}
+}
+
+// Crear autoriza un sitio externo y le genera la clave
+func (s *WidgetService) Crear(req models.CrearWidgetRequest, usuarioID uint) (*models.WidgetDetalle, error) {
+	origenes, err := normalizarOrigenes(req.Origenes)
+	if err != nil {
+		return nil, err
+	}
+
+	aleatorio := make([]byte, 16)
+	if _, err := rand.Read(aleatorio); err != nil {
+		return nil, fmt.Errorf("error generando clave del widget: %w", err)
+	}
+
+	widget := &models.Widget{
+		Nombre:    strings.TrimSpace(req.Nombre),
+		Clave:     "wk_" + hex.EncodeToString(aleatorio),
+		Origenes:  strings.Join(origenes, ","),
+		Activo:    true,
+		CreadoPor: usuarioID,
+	}
+	if err := s.widgetRepo.Crear(widget); err != nil {
+		return nil, err
+	}
+
+	log.Printf("🧩 Usuario %d creó el widget %q para %s", usuarioID, widget.Nombre, widget.Origenes)
+	detalle := s.detalle(widget, models.EstadisticasWidget{})
+	return &detalle, nil
+}
+
+// Actualizar cambia el nombre, los orígenes o el estado de un widget
+func (s *WidgetService) Actualizar(id uint, req models.ActualizarWidgetRequest, usuarioID uint) (*models.WidgetDetalle, error) {
+	widget, err := s.widgetRepo.BuscarPorID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Nombre != "" {
+		widget.Nombre = strings.TrimSpace(req.Nombre)
+	}
+	if len(req.Origenes) > 0 {
+		origenes, err := normalizarOrigenes(req.Origenes)
+		if err != nil {
+			return nil, err
+		}
+		widget.Origenes = strings.Join(origenes, ",")
+	}
+	if req.Activo != nil {
+		widget.Activo = *req.Activo
+	}
+
+	if err := s.widgetRepo.Actualizar(widget); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	delete(s.cache, widget.Clave)
+	s.mu.Unlock()
+
+	log.Printf("🧩 Usuario %d actualizó el widget #%d (%s, activo=%t)", usuarioID, widget.ID, widget.Origenes, widget.Activo)
+	detalle := s.detalle(widget, models.EstadisticasWidget{})
+	return &detalle, nil
+}
+
+// Listar obtiene los widgets con las partidas atribuidas en los últimos días
+func (s *WidgetService) Listar(dias int) ([]models.WidgetDetalle, error) {
+	widgets, err := s.widgetRepo.Listar()
+	if err != nil {
+		return nil, err
+	}
+	estadisticas, err := s.voucherRepo.GetEstadisticasPorWidget(time.Now().AddDate(0, 0, -dias))
+	if err != nil {
+		return nil, err
+	}
+
+	detalles := make([]models.WidgetDetalle, 0, len(widgets))
+	for _, widget := range widgets {
+		detalles = append(detalles, s.detalle(widget, estadisticas[widget.ID]))
+	}
+	return detalles, nil
+}
+
+// BuscarActivo retorna el widget activo de la clave o ErrWidgetNoAutorizado
+func (s *WidgetService) BuscarActivo(clave string) (*models.Widget, error) {
+	s.mu.RLock()
+	widget, ok := s.cache[clave]
+	vigente := s.config.Scaling.CacheVigente(s.cargadoEn)
+	s.mu.RUnlock()
+
+	if !ok || !vigente {
+		var err error
+		widget, err = s.widgetRepo.BuscarPorClave(clave)
+		if err != nil {
+			return nil, err
+		}
+		if widget == nil {
+			return nil, ErrWidgetNoAutorizado
+		}
+
+		s.mu.Lock()
+		if !s.config.Scaling.CacheVigente(s.cargadoEn) {
+			s.cache = make(map[string]*models.Widget)
+			s.cargadoEn = time.Now()
+		}
+		s.cache[clave] = widget
+		s.mu.Unlock()
+	}
+
+	if !widget.Activo {
+		return nil, ErrWidgetNoAutorizado
+	}
+	return widget, nil
+}
+
+// Autorizar valida que la clave esté activa y que el origen del request esté permitido
+func (s *WidgetService) Autorizar(clave, origen string) (*models.Widget, error) {
+	widget, err := s.BuscarActivo(clave)
+	if err != nil {
+		return nil, err
+	}
+
+	normalizado, err := normalizarOrigen(origen)
+	if err != nil || !slices.Contains(strings.Split(widget.Origenes, ","), normalizado) {
+		return nil, ErrWidgetNoAutorizado
+	}
+	return widget, nil
+}
+
+// URLBase dirección de la API del widget, usada por el snippet
+func (s *WidgetService) URLBase(widget *models.Widget) string {
+	return strings.TrimRight(s.config.PublicBaseURL, "/") + "/api/widget/" + widget.Clave
+}
+
+// detalle arma la respuesta del panel con el snippet listo para pegar
+func (s *WidgetService) detalle(widget *models.Widget, estadisticas models.EstadisticasWidget) models.WidgetDetalle {
+	return models.WidgetDetalle{
+		Widget:       widget,
+		Origenes:     strings.Split(widget.Origenes, ","),
+		Snippet:      fmt.Sprintf(`<div id="cheesehouse-juego"></div><script src="%s/embed.js" async></script>`, s.URLBase(widget)),
+		Estadisticas: estadisticas,
+	}
+}
+
+// normalizarOrigenes normaliza y deduplica la lista de orígenes de un widget
+func normalizarOrigenes(origenes []string) ([]string, error) {
+	normalizados := make([]string, 0, len(origenes))
+	for _, origen := range origenes {
+		normalizado, err := normalizarOrigen(origen)
+		if err != nil {
+			return nil, err
+		}
+		if !slices.Contains(normalizados, normalizado) {
+			normalizados = append(normalizados, normalizado)
+		}
+	}
+	return normalizados, nil
+}
+
+// normalizarOrigen reduce una URL a esquema://host[:puerto] en minúsculas, el formato
+// del header Origin que envían los navegadores
+func normalizarOrigen(origen string) (string, error) {
+	u, err := url.Parse(strings.TrimSpace(origen))
+	if err != nil || u.Host == "" {
+		return "", fmt.Errorf("origen inválido: %q", origen)
+	}
+	esquema := strings.ToLower(u.Scheme)
+	if esquema != "https" && esquema != "http" {
+		return "", fmt.Errorf("origen inválido: %q (debe ser http o https)", origen)
+	}
+	return esquema + "://" + strings.ToLower(u.Host), nil
+}
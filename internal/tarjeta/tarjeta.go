@@ -0,0 +1,181 @@
+// Package tarjeta dibuja las imágenes para compartir en redes (OpenGraph e historias)
+// con una fuente de mapa de bits propia, sin depender de fuentes instaladas
+package tarjeta
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Formato tamaño de la imagen según dónde se comparte
+type Formato struct {
+	Ancho int
+	Alto  int
+}
+
+// Lado retorna el lado menor, para escalar el texto igual en imágenes apaisadas y verticales
+func (f Formato) Lado() int {
+	return min(f.Ancho, f.Alto)
+}
+
+var (
+	// FormatoOpenGraph vista previa de links (Facebook, WhatsApp, X)
+	FormatoOpenGraph = Formato{Ancho: 1200, Alto: 630}
+	// FormatoHistoria historias de Instagram y Facebook (9:16)
+	FormatoHistoria = Formato{Ancho: 1080, Alto: 1920}
+)
+
+// Bloque párrafo de la tarjeta; Escala es el tamaño en píxeles de cada punto de la fuente
+type Bloque struct {
+	Texto  string
+	Escala int
+}
+
+const (
+	separacionLetras = 1 // Puntos entre caracteres
+	separacionLineas = 3 // Puntos entre líneas de un mismo bloque
+)
+
+// PNG dibuja los bloques centrados sobre el fondo y retorna la imagen codificada.
+// Cada bloque se parte en líneas por palabras y reduce su escala si una palabra no entra;
+// un espacio no separable (U+00A0) mantiene juntas dos palabras, como "12.30 s"
+func PNG(formato Formato, fondo, tinta color.RGBA, bloques []Bloque) ([]byte, error) {
+	margen := formato.Ancho / 12
+	anchoUtil := formato.Ancho - 2*margen
+
+	type bloqueArmado struct {
+		lineas []string
+		escala int
+	}
+	armados := make([]bloqueArmado, 0, len(bloques))
+	altoTotal := 0
+	for i, bloque := range bloques {
+		escala := bloque.Escala
+		palabras := strings.FieldsFunc(strings.ToUpper(bloque.Texto), func(r rune) bool {
+			return r != '\u00a0' && unicode.IsSpace(r)
+		})
+		for _, palabra := range palabras {
+			for escala > 1 && anchoTexto(palabra, escala) > anchoUtil {
+				escala--
+			}
+		}
+
+		lineas := partirEnLineas(palabras, anchoUtil, escala)
+		armados = append(armados, bloqueArmado{lineas: lineas, escala: escala})
+
+		altoTotal += altoBloque(len(lineas), escala)
+		if i > 0 {
+			altoTotal += separacionBloques(formato)
+		}
+	}
+
+	img := image.NewRGBA(image.Rect(0, 0, formato.Ancho, formato.Alto))
+	for y := 0; y < formato.Alto; y++ {
+		for x := 0; x < formato.Ancho; x++ {
+			img.SetRGBA(x, y, fondo)
+		}
+	}
+
+	y := (formato.Alto - altoTotal) / 2
+	for i, armado := range armados {
+		if i > 0 {
+			y += separacionBloques(formato)
+		}
+		for _, linea := range armado.lineas {
+			x := (formato.Ancho - anchoTexto(linea, armado.escala)) / 2
+			dibujarLinea(img, linea, x, y, armado.escala, tinta)
+			y += (altoGlifo + separacionLineas) * armado.escala
+		}
+		y -= separacionLineas * armado.escala
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, fmt.Errorf("error codificando PNG: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// ParsearColor convierte "#RRGGBB" en color; si no es válido retorna el color por defecto
+func ParsearColor(hex string, defecto color.RGBA) color.RGBA {
+	hex = strings.TrimPrefix(strings.TrimSpace(hex), "#")
+	if len(hex) != 6 {
+		return defecto
+	}
+	valor, err := strconv.ParseUint(hex, 16, 32)
+	if err != nil {
+		return defecto
+	}
+	return color.RGBA{R: uint8(valor >> 16), G: uint8(valor >> 8), B: uint8(valor), A: 0xff}
+}
+
+// partirEnLineas junta palabras mientras entren en el ancho disponible
+func partirEnLineas(palabras []string, anchoUtil, escala int) []string {
+	var lineas []string
+	actual := ""
+	for _, palabra := range palabras {
+		candidata := palabra
+		if actual != "" {
+			candidata = actual + " " + palabra
+		}
+		if actual != "" && anchoTexto(candidata, escala) > anchoUtil {
+			lineas = append(lineas, actual)
+			candidata = palabra
+		}
+		actual = candidata
+	}
+	if actual != "" {
+		lineas = append(lineas, actual)
+	}
+	return lineas
+}
+
+// anchoTexto ancho en píxeles de una línea
+func anchoTexto(texto string, escala int) int {
+	caracteres := len([]rune(texto))
+	if caracteres == 0 {
+		return 0
+	}
+	return (caracteres*(anchoGlifo+separacionLetras) - separacionLetras) * escala
+}
+
+// altoBloque alto en píxeles de un bloque de n líneas
+func altoBloque(lineas, escala int) int {
+	if lineas == 0 {
+		return 0
+	}
+	return (lineas*(altoGlifo+separacionLineas) - separacionLineas) * escala
+}
+
+// separacionBloques espacio entre bloques, proporcional al alto de la imagen
+func separacionBloques(formato Formato) int {
+	return formato.Alto / 18
+}
+
+// dibujarLinea pinta cada carácter como cuadrados de escala x escala píxeles
+func dibujarLinea(img *image.RGBA, linea string, x, y, escala int, tinta color.RGBA) {
+	for _, r := range linea {
+		filas := glifo(unicode.ToUpper(r))
+		for fila, puntos := range filas {
+			for columna, punto := range puntos {
+				if punto != '#' {
+					continue
+				}
+				x0 := x + columna*escala
+				y0 := y + fila*escala
+				for py := y0; py < y0+escala; py++ {
+					for px := x0; px < x0+escala; px++ {
+						img.SetRGBA(px, py, tinta)
+					}
+				}
+			}
+		}
+		x += (anchoGlifo + separacionLetras) * escala
+	}
+}
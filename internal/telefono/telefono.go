@@ -0,0 +1,95 @@
+package telefono
+
+import (
+	"fmt"
+	"strings"
+)
+
+// CodigoPaisArgentina código de país por defecto para números sin prefijo internacional
+const CodigoPaisArgentina = "54"
+
+// Normalizar convierte un teléfono a formato E.164 estricto.
+//
+// Los números argentinos se llevan siempre a la forma móvil +549 + 10 dígitos
+// (código de área + abonado), que es la que usa WhatsApp. Se aceptan las
+// variantes habituales: con o sin +54, con o sin el 9, con el 0 troncal y con
+// el 15 de celulares (ej. "011 15 1234-5678", "+54 11 1234 5678").
+// Los números de otros países deben venir con + o 00 y se validan por longitud.
+func Normalizar(raw string) (string, error) {
+	limpio := strings.TrimSpace(raw)
+	internacional := strings.HasPrefix(limpio, "+") || strings.HasPrefix(limpio, "00")
+
+	digitos := soloDigitos(limpio)
+	if strings.HasPrefix(limpio, "00") {
+		digitos = strings.TrimPrefix(digitos, "00")
+	}
+	if digitos == "" {
+		return "", fmt.Errorf("teléfono vacío")
+	}
+
+	if internacional && !strings.HasPrefix(digitos, CodigoPaisArgentina) {
+		if len(digitos) < 8 || len(digitos) > 15 {
+			return "", fmt.Errorf("número internacional debe tener entre 8 y 15 dígitos")
+		}
+		return "+" + digitos, nil
+	}
+
+	nacional := digitos
+	if internacional || (strings.HasPrefix(nacional, CodigoPaisArgentina) && len(nacional) >= 12) {
+		nacional = strings.TrimPrefix(nacional, CodigoPaisArgentina)
+	}
+
+	// Prefijo troncal nacional
+	nacional = strings.TrimPrefix(nacional, "0")
+
+	// Prefijo 9 de celulares en formato internacional
+	if len(nacional) == 11 && strings.HasPrefix(nacional, "9") {
+		nacional = nacional[1:]
+	}
+
+	// Prefijo 15 de celulares en formato local (área + 15 + abonado)
+	if len(nacional) == 12 {
+		nacional = quitarPrefijo15(nacional)
+	}
+
+	if len(nacional) != 10 {
+		return "", fmt.Errorf("número argentino debe tener 10 dígitos con código de área (se obtuvieron %d)", len(nacional))
+	}
+
+	return "+" + CodigoPaisArgentina + "9" + nacional, nil
+}
+
+// SinMas retorna el número sin el símbolo +, como lo espera la API de WhatsApp
+func SinMas(e164 string) string {
+	return strings.TrimPrefix(e164, "+")
+}
+
+// EsArgentino indica si un número normalizado es argentino
+func EsArgentino(e164 string) bool {
+	return strings.HasPrefix(e164, "+"+CodigoPaisArgentina)
+}
+
+// NumeroNacional retorna los 10 dígitos nacionales (área + abonado) de un número argentino normalizado
+func NumeroNacional(e164 string) string {
+	return strings.TrimPrefix(e164, "+"+CodigoPaisArgentina+"9")
+}
+
+// quitarPrefijo15 elimina el 15 que sigue al código de área (2 a 4 dígitos)
+func quitarPrefijo15(nacional string) string {
+	for _, largoArea := range []int{2, 3, 4} {
+		if nacional[largoArea:largoArea+2] == "15" {
+			return nacional[:largoArea] + nacional[largoArea+2:]
+		}
+	}
+	return nacional
+}
+
+func soloDigitos(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		if r >= '0' && r <= '9' {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,77 @@
+package telefono
+
+import "testing"
+
+func TestNormalizar(t *testing.T) {
+	casos := []struct {
+		nombre   string
+		entrada  string
+		esperado string
+	}{
+		{"nacional con área", "11 1234 5678", "+5491112345678"},
+		{"troncal y 15 con área de 2 dígitos", "011 15 1234-5678", "+5491112345678"},
+		{"troncal y 15 con área de 3 dígitos", "0351 15 123-4567", "+5493511234567"},
+		{"troncal y 15 con área de 4 dígitos", "02966 15 12-3456", "+5492966123456"},
+		{"15 sin troncal", "11 15 1234 5678", "+5491112345678"},
+		{"9 de celular sin código de país", "9 11 1234 5678", "+5491112345678"},
+		{"internacional sin 9", "+54 11 1234 5678", "+5491112345678"},
+		{"internacional con 9", "+54 9 11 1234 5678", "+5491112345678"},
+		{"código de país sin +", "54 9 11 1234 5678", "+5491112345678"},
+		{"prefijo 00", "0054 11 1234 5678", "+5491112345678"},
+		{"espacios alrededor", "  (011) 1234-5678  ", "+5491112345678"},
+		{"otro país con +", "+1 (202) 555-0123", "+12025550123"},
+		{"otro país con 00", "0034 600 123 456", "+34600123456"},
+	}
+
+	for _, caso := range casos {
+		t.Run(caso.nombre, func(t *testing.T) {
+			obtenido, err := Normalizar(caso.entrada)
+			if err != nil {
+				t.Fatalf("Normalizar(%q) error inesperado: %v", caso.entrada, err)
+			}
+			if obtenido != caso.esperado {
+				t.Errorf("Normalizar(%q) = %q, se esperaba %q", caso.entrada, obtenido, caso.esperado)
+			}
+		})
+	}
+}
+
+func TestNormalizarInvalidos(t *testing.T) {
+	casos := []struct {
+		nombre  string
+		entrada string
+	}{
+		{"vacío", ""},
+		{"sin dígitos", "abc-def"},
+		{"argentino corto", "1234 5678"},
+		{"argentino largo", "011 15 1234 56789"},
+		{"internacional corto", "+1 234 56"},
+		{"internacional largo", "+1 2345 6789 0123 456"},
+	}
+
+	for _, caso := range casos {
+		t.Run(caso.nombre, func(t *testing.T) {
+			if obtenido, err := Normalizar(caso.entrada); err == nil {
+				t.Errorf("Normalizar(%q) = %q, se esperaba un error", caso.entrada, obtenido)
+			}
+		})
+	}
+}
+
+func TestQuitarPrefijo15(t *testing.T) {
+	casos := []struct {
+		entrada  string
+		esperado string
+	}{
+		{"111512345678", "1112345678"},
+		{"351151234567", "3511234567"},
+		{"296615123456", "2966123456"},
+		{"111212345678", "111212345678"},
+	}
+
+	for _, caso := range casos {
+		if obtenido := quitarPrefijo15(caso.entrada); obtenido != caso.esperado {
+			t.Errorf("quitarPrefijo15(%q) = %q, se esperaba %q", caso.entrada, obtenido, caso.esperado)
+		}
+	}
+}
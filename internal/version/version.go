@@ -0,0 +1,85 @@
+// Package version identifica el binario que está corriendo: versión, commit y fecha de
+// compilación. Se inyectan al compilar:
+//
+//	go build -ldflags "-X CheeseHouse/internal/version.Version=1.4.0 \
+//	  -X CheeseHouse/internal/version.Commit=$(git rev-parse --short HEAD) \
+//	  -X CheeseHouse/internal/version.FechaCompilacion=$(date -u +%Y-%m-%dT%H:%M:%SZ)"
+//
+// Sin ldflags se completan con lo que Go grabó en el binario (commit y fecha del último
+// commit si se compiló dentro del repo) y la versión queda en "dev".
+package version
+
+import (
+	"fmt"
+	"runtime"
+	"runtime/debug"
+	"sync"
+)
+
+// Valores inyectados con -ldflags "-X"
+var (
+	Version          = "dev"
+	Commit           = ""
+	FechaCompilacion = ""
+)
+
+// Info metadatos del binario para /info, los logs y los reportes de error
+type Info struct {
+	Version          string `json:"version"`
+	Commit           string `json:"commit"`
+	FechaCompilacion string `json:"fecha_compilacion"`
+	Modificado       bool   `json:"modificado,omitempty"` // Compilado con cambios sin commitear
+	Go               string `json:"go"`
+}
+
+var (
+	info      Info
+	calculada sync.Once
+)
+
+// Obtener retorna los metadatos del binario
+func Obtener() Info {
+	calculada.Do(func() {
+		info = Info{
+			Version:          Version,
+			Commit:           Commit,
+			FechaCompilacion: FechaCompilacion,
+			Go:               runtime.Version(),
+		}
+		if datos, ok := debug.ReadBuildInfo(); ok {
+			for _, ajuste := range datos.Settings {
+				switch ajuste.Key {
+				case "vcs.revision":
+					if info.Commit == "" {
+						info.Commit = ajuste.Value
+					}
+				case "vcs.time":
+					if info.FechaCompilacion == "" {
+						info.FechaCompilacion = ajuste.Value
+					}
+				case "vcs.modified":
+					info.Modificado = ajuste.Value == "true"
+				}
+			}
+		}
+		if len(info.Commit) > 12 {
+			info.Commit = info.Commit[:12]
+		}
+		if info.Commit == "" {
+			info.Commit = "desconocido"
+		}
+		if info.FechaCompilacion == "" {
+			info.FechaCompilacion = "desconocida"
+		}
+	})
+	return info
+}
+
+// String resume la versión para logs: "1.4.0 (abc1234, 2026-10-16T12:00:00Z)"
+func (i Info) String() string {
+	commit := i.Commit
+	if i.Modificado {
+		commit += "+cambios"
+	}
+	return fmt.Sprintf("%s (%s, %s)", i.Version, commit, i.FechaCompilacion)
+}
@@ -1,23 +1,41 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"flag"
 	"fmt"
 	"log"
 	"net/http"
 	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
 
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
 	"github.com/joho/godotenv"
 
+	middleware "CheeseHouse/internal/Middlerware"
+	"CheeseHouse/internal/almacenamiento"
 	"CheeseHouse/internal/config"
 	"CheeseHouse/internal/database"
+	"CheeseHouse/internal/estaticos"
+	"CheeseHouse/internal/fallas"
 	"CheeseHouse/internal/handlers"
+	"CheeseHouse/internal/logging"
+	"CheeseHouse/internal/models"
 	"CheeseHouse/internal/repository"
 	"CheeseHouse/internal/services"
+	"CheeseHouse/internal/version"
 )
 
 func main() {
+	migrarTelefonos := flag.Bool("migrar-telefonos", false, "normalizar teléfonos existentes a E.164 y salir")
+	migrar := flag.Bool("migrar", false, "crear/actualizar el esquema (AutoMigrate + migraciones SQL) y salir")
+	flag.Parse()
+
 	// Cargar variables de entorno
 	if err := godotenv.Load(); err != nil {
 		log.Println("⚠️  No se encontró archivo .env, usando variables del sistema")
@@ -25,12 +43,17 @@ func main() {
 
 	// Inicializar configuración
 	cfg := config.Load()
+	if salida := logging.Configurar(cfg.LogFormat); salida != nil {
+		gin.DefaultWriter = salida
+		gin.DefaultErrorWriter = salida
+	}
+	log.Printf("🧀 CheeseHouse %s", version.Obtener())
 	cfg.LogConfig()
 
 	// Validar configuración
-	if errors := cfg.Validate(); len(errors) > 0 {
+	if advertencias := cfg.Validate(); len(advertencias) > 0 {
 		log.Println("⚠️  Advertencias de configuración:")
-		for _, err := range errors {
+		for _, err := range advertencias {
 			log.Printf("   - %s", err)
 		}
 	}
@@ -41,19 +64,199 @@ func main() {
 		log.Fatal("❌ Error fatal conectando a la base de datos:", err)
 	}
 
+	// Esquema: -migrar fuerza AutoMigrate aunque DB_AUTO_MIGRATE esté apagado
+	if err := db.Migrar(cfg.DBAutoMigrate || *migrar); err != nil {
+		log.Fatal("❌ Error fatal migrando la base de datos:", err)
+	}
+	if *migrar {
+		return
+	}
+	if esquema, err := db.EstadoEsquema(); err != nil {
+		log.Printf("⚠️  No se pudo revisar el esquema: %v", err)
+	} else if esquema.Advertencia != "" {
+		log.Printf("⚠️  %s", esquema.Advertencia)
+	}
+
+	// Inyección de fallas para probar reintentos, outbox y circuito de premios (solo desarrollo)
+	var inyector *fallas.Inyector
+	if cfg.InyeccionFallasActiva() {
+		inyector, err = fallas.Nuevo(fallas.Estado{
+			WhatsAppErrorRate: cfg.FaultInjection.WhatsAppErrorRate,
+			WhatsApp429Rate:   cfg.FaultInjection.WhatsApp429Rate,
+			DBLatenciaMs:      cfg.FaultInjection.DBLatencyMs,
+		})
+		if err == nil {
+			err = inyector.RegistrarEnBase(db.DB)
+		}
+		if err != nil {
+			log.Fatal("❌ Error fatal configurando la inyección de fallas:", err)
+		}
+		log.Println("🧨 Inyección de fallas activa, se ajusta en /api/dev/fallas")
+	}
+
+	eventService := services.NewEventService(cfg)
+
+	if *migrarTelefonos {
+		resultado, err := db.MigrarTelefonosE164()
+		if err != nil {
+			log.Fatal("❌ Error migrando teléfonos:", err)
+		}
+		for _, fusion := range resultado.Fusiones {
+			duplicado := fusion.Duplicado
+			eventService.Emitir(services.EventoClienteFusionado, models.EventoCliente{
+				Cliente:       &duplicado,
+				FusionadoEnID: fusion.SobrevivienteID,
+			})
+		}
+		// Salir recién cuando los eventos se entregaron (o se agotaron sus reintentos)
+		eventService.Esperar()
+		return
+	}
+
 	// Inicializar repositorios
 	clienteRepo := repository.NewClienteRepository(db.DB)
 	voucherRepo := repository.NewVoucherRepository(db.DB)
+	mensajeLogRepo := repository.NewMensajeLogRepository(db.DB)
+	outboxRepo := repository.NewOutboxRepository(db.DB)
+	preferenciasRepo := repository.NewPreferenciasRepository(db.DB)
+	usuarioRepo := repository.NewUsuarioRepository(db.DB)
+	reporteRepo := repository.NewReporteRepository(db.DB)
+	campanaRepo := repository.NewCampanaRepository(db.DB)
+	brandingRepo := repository.NewBrandingRepository(db.DB)
+	notificacionRepo := repository.NewNotificacionRepository(db.DB)
+	idempotenciaRepo := repository.NewIdempotenciaRepository(db.DB)
+	bloqueoRepo := repository.NewBloqueoRepository(db.DB)
+	conversacionRepo := repository.NewConversacionRepository(db.DB)
+	atencionRepo := repository.NewAtencionRepository(db.DB)
+	respuestaRapidaRepo := repository.NewRespuestaRapidaRepository(db.DB)
+	mensajeConfigRepo := repository.NewMensajeConfigRepository(db.DB)
+	horarioRepo := repository.NewHorarioRepository(db.DB)
+	menuRepo := repository.NewMenuRepository(db.DB)
+	pedidoRepo := repository.NewPedidoRepository(db.DB)
+	archivoRepo := repository.NewArchivoRepository(db.DB)
+	widgetRepo := repository.NewWidgetRepository(db.DB)
+	mesaRepo := repository.NewMesaRepository(db.DB)
+	celebracionRepo := repository.NewCelebracionRepository(db.DB)
+	recomendacionRepo := repository.NewRecomendacionRepository(db.DB)
+	vigenciaRepo := repository.NewVigenciaRepository(db.DB)
+	diaSinCanjeRepo := repository.NewDiaSinCanjeRepository(db.DB)
+	metaRepo := repository.NewMetaRepository(db.DB)
+	canjeEmpleadoRepo := repository.NewCanjeEmpleadoRepository(db.DB)
+	juegoRepo := repository.NewJuegoRepository(db.DB)
+	estadisticaDiariaRepo := repository.NewEstadisticaDiariaRepository(db.DB)
+	rankingRepo := repository.NewRankingRepository(db.DB)
+	unidadDeTrabajo := repository.NewUnidadDeTrabajo(db.DB)
+	configuracionRepo := repository.NewConfiguracionRepository(db.DB)
+
+	// Estado compartido entre instancias (sesiones de juego, límites, revocaciones, liderazgo);
+	// con SHARED_STATE=memory queda en el proceso y solo sirve para una instancia
+	var estadoCompartido repository.EstadoCompartidoRepository
+	if cfg.Scaling.Compartido() {
+		estadoCompartido = repository.NewEstadoCompartidoRepository(db.DB)
+	} else {
+		estadoCompartido = repository.NewEstadoCompartidoEnMemoria()
+	}
+	coordinacionService := services.NewCoordinacionService(cfg, estadoCompartido)
+	coordinacionService.IniciarLiderazgo()
+
+	// Almacenamiento de archivos generados; si S3 no está bien configurado se usa el disco
+	alm, err := almacenamiento.Nuevo(cfg.Storage, cfg.PublicBaseURL, cfg.LinkSigningSecret)
+	if err != nil {
+		log.Printf("⚠️  Almacenamiento %s no disponible (%v), usando disco local", cfg.Storage.Driver, err)
+		if alm, err = almacenamiento.NuevoLocal(cfg.Storage.LocalPath, cfg.PublicBaseURL, cfg.LinkSigningSecret); err != nil {
+			log.Fatal("❌ Error fatal inicializando almacenamiento:", err)
+		}
+	}
+	log.Printf("🗄️ Archivos generados en %s", alm.Descripcion())
 
 	// Inicializar servicios
-	whatsappService := services.NewWhatsAppService(cfg)
-	gameService := services.NewGameService(cfg, clienteRepo, voucherRepo, whatsappService)
+	artefactoService := services.NewArtefactoService(alm, cfg.Storage.SignedURLTTL)
+	preferenciasService := services.NewPreferenciasService(cfg, preferenciasRepo, clienteRepo)
+	horarioService := services.NewHorarioService(cfg, horarioRepo)
+	mensajeConfigService := services.NewMensajeConfigService(cfg, mensajeConfigRepo, configuracionRepo)
+	mensajeConfigService.SembrarPorDefecto()
+	configuracionService := services.NewConfiguracionService(cfg, configuracionRepo, mensajeConfigService)
+	notificacionService := services.NewNotificacionService(cfg, notificacionRepo)
+	whatsappService := services.NewWhatsAppService(cfg, mensajeLogRepo, outboxRepo, preferenciasService, horarioService, mensajeConfigService, notificacionService)
+	if inyector != nil {
+		whatsappService.EnvolverTransporte(inyector.Transporte)
+	}
+	whatsappService.IniciarOutboxWorker(time.Minute, coordinacionService)
+	whatsappService.IniciarMonitorCuota(15*time.Minute, coordinacionService)
+	whatsappService.IniciarMonitorPlantillas(30*time.Minute, coordinacionService)
+	verificacionService := services.NewVerificacionService(whatsappService, estadoCompartido)
+	objetivoService := services.NewObjetivoService(cfg.LinkSigningSecret, estadoCompartido)
+	circuitoPremiosService := services.NewCircuitoPremiosService(cfg, eventService, configuracionService, estadoCompartido)
+	picoEmisionService := services.NewPicoEmisionService(cfg, voucherRepo, notificacionService)
+	picoEmisionService.IniciarMonitor(5*time.Minute, coordinacionService)
+	bloqueoService := services.NewBloqueoService(bloqueoRepo, whatsappService)
+	tipoClienteService := services.NewTipoClienteService(cfg, clienteRepo)
+	tipoClienteService.IniciarProgramador(coordinacionService)
+	brandingService := services.NewBrandingService(cfg, brandingRepo)
+	tarjetaService := services.NewTarjetaService(cfg, brandingService)
+	mesaService := services.NewMesaService(cfg, mesaRepo, voucherRepo, notificacionService)
+	celebracionService := services.NewCelebracionService(cfg, celebracionRepo)
+	recomendacionService := services.NewRecomendacionService(cfg, recomendacionRepo, voucherRepo)
+	vigenciaService := services.NewVigenciaService(cfg, vigenciaRepo)
+	idempotenciaService := services.NewIdempotenciaService(cfg, idempotenciaRepo)
+	voucherQRService := services.NewVoucherQRService(cfg)
+	voucherPantallaService := services.NewVoucherPantallaService(cfg, voucherRepo, estadoCompartido, voucherQRService)
+	gameService := services.NewGameService(cfg, clienteRepo, voucherRepo, unidadDeTrabajo, whatsappService, eventService, verificacionService, objetivoService, circuitoPremiosService, picoEmisionService, bloqueoService, tipoClienteService, tarjetaService, mesaService, celebracionService, recomendacionService, vigenciaService, configuracionService, voucherPantallaService)
+	authService := services.NewAuthService(usuarioRepo, cfg.JWTSecret, estadoCompartido)
+	if err := authService.SembrarAccesoInicial(cfg.InitialAdminName, cfg.InitialAdminEmail, cfg.InitialAdminPassword); err != nil {
+		log.Printf("❌ Error creando roles y admin inicial: %v", err)
+	}
+	respuestaRapidaService := services.NewRespuestaRapidaService(cfg, respuestaRapidaRepo)
+	respuestaRapidaService.SembrarPorDefecto()
+	conversacionService := services.NewConversacionService(cfg, conversacionRepo, atencionRepo, clienteRepo, whatsappService, respuestaRapidaService)
+	pedidoService := services.NewPedidoService(cfg, pedidoRepo, menuRepo, clienteRepo, usuarioRepo, conversacionService, whatsappService)
+	diaSinCanjeService := services.NewDiaSinCanjeService(cfg, diaSinCanjeRepo)
+	costoCampanaService := services.NewCostoCampanaService(cfg, campanaRepo, voucherRepo, preferenciasService, whatsappService)
+	canjeEmpleadoService := services.NewCanjeEmpleadoService(cfg, canjeEmpleadoRepo, usuarioRepo, notificacionService, estadoCompartido)
+	trabajoService := services.NewTrabajoService()
+	adminService := services.NewAdminService(cfg, *clienteRepo, voucherRepo, whatsappService, eventService, circuitoPremiosService, picoEmisionService, conversacionService, diaSinCanjeService, canjeEmpleadoService, campanaRepo, pedidoRepo, preferenciasService, costoCampanaService, authService, voucherQRService, unidadDeTrabajo, trabajoService)
+	reporteService := services.NewReporteService(cfg, reporteRepo, adminService, artefactoService)
+	reporteService.IniciarProgramador(5*time.Minute, coordinacionService)
+	metaService := services.NewMetaService(cfg, metaRepo, juegoRepo, clienteRepo, voucherRepo, notificacionService)
+	metaService.IniciarResumenSemanal(coordinacionService)
+	calendarioService := services.NewCalendarioService(cfg, campanaRepo, voucherRepo, outboxRepo, diaSinCanjeRepo, reporteService)
+	archivoService := services.NewArchivoService(cfg, archivoRepo)
+	rankingService := services.NewRankingService(cfg, rankingRepo, voucherRepo, whatsappService)
+	mantenimientoService := services.NewMantenimientoService(cfg, trabajoService, adminService, archivoService, estadisticaDiariaRepo, juegoRepo, clienteRepo, voucherRepo, estadoCompartido, coordinacionService, rankingService)
+	mantenimientoService.IniciarProgramador()
+	widgetService := services.NewWidgetService(cfg, widgetRepo, voucherRepo)
 
 	// Inicializar handlers
-	gameHandler := handlers.NewGameHandler(gameService)
+	gameHandler := handlers.NewGameHandler(gameService, brandingService, tarjetaService, voucherQRService, rankingService)
+	preferenciasHandler := handlers.NewPreferenciasHandler(preferenciasService)
+	adminHandler := handlers.NewAdminHandler(adminService, reporteService, costoCampanaService, brandingService, notificacionService, bloqueoService, archivoService, celebracionService, diaSinCanjeService, canjeEmpleadoService, calendarioService, metaService, artefactoService, trabajoService, mantenimientoService, rankingService)
+	whatsappHandler := handlers.NewWhatsAppHandler(cfg, whatsappService, adminService, conversacionService, respuestaRapidaService, horarioService, mensajeConfigService)
+	pedidoHandler := handlers.NewPedidoHandler(pedidoService)
+	widgetHandler := handlers.NewWidgetHandler(widgetService, gameService)
+	mesaHandler := handlers.NewMesaHandler(mesaService, artefactoService)
+	recomendacionHandler := handlers.NewRecomendacionHandler(recomendacionService)
+	vigenciaHandler := handlers.NewVigenciaHandler(vigenciaService)
+	configuracionHandler := handlers.NewConfiguracionHandler(configuracionService)
+	descargaHandler := handlers.NewDescargaHandler(artefactoService)
+	errorHandler := handlers.NewErrorHandler(cfg.RestaurantName, brandingService)
+	authHandler := handlers.NewAuthHandler(cfg, authService)
+	authMiddleware := middleware.NewAuthMiddleware(authService)
+	widgetMiddleware := middleware.NewWidgetMiddleware(widgetService)
+	idempotencia := middleware.NewIdempotencia(idempotenciaService)
+	limiteEnvios := middleware.NewLimiteEnvios(estadoCompartido, func() (int, int) {
+		parametros := configuracionService.ParametrosJuego()
+		return parametros.LimiteIP, parametros.LimiteTelefono
+	}, cfg.Game.SubmitBurst)
+
+	// Métricas de latencia por ruta, asociadas a las consultas lentas de la base; si hay
+	// StatsD o Pushgateway configurado se envían también ahí
+	metricas := middleware.NewMetricas(cfg.Performance.SlowRequestThreshold)
+	db.ObservarConsultasLentas(metricas.RegistrarConsultaLenta)
+	emisorMetricas := middleware.NewEmisorMetricas(cfg, metricas)
+	emisorMetricas.Iniciar()
 
 	// Configurar router
-	router := setupRouter(gameHandler, db, cfg, whatsappService)
+	router := setupRouter(gameHandler, preferenciasHandler, adminHandler, whatsappHandler, pedidoHandler, widgetHandler, mesaHandler, recomendacionHandler, vigenciaHandler, configuracionHandler, descargaHandler, errorHandler, authHandler, authMiddleware, widgetMiddleware, idempotencia, limiteEnvios, estadoCompartido, coordinacionService, db, cfg, whatsappService, inyector, metricas)
 
 	// Iniciar servidor
 	port := os.Getenv("PORT")
@@ -69,48 +272,123 @@ func main() {
 	log.Printf(" Health check: http://localhost:%s/health", port)
 	log.Println(" ================================")
 
-	if err := router.Run(":" + port); err != nil {
-		log.Fatal(" Error fatal iniciando servidor:", err)
+	servidor := &http.Server{
+		Addr:    ":" + port,
+		Handler: router,
+	}
+	go func() {
+		if err := servidor.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Fatal(" Error fatal iniciando servidor:", err)
+		}
+	}()
+
+	// Esperar SIGINT/SIGTERM y apagar en orden: dejar de aceptar requests y terminar los
+	// que están en curso, esperar los envíos de WhatsApp y recién ahí cerrar la base
+	senal, detenerSenales := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer detenerSenales()
+	<-senal.Done()
+	detenerSenales()
+
+	log.Printf("🛑 Apagando servidor (hasta %v para terminar lo pendiente)...", cfg.ShutdownTimeout)
+	ctx, cancelar := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
+	defer cancelar()
+
+	if err := servidor.Shutdown(ctx); err != nil {
+		log.Printf("⚠️  Requests sin terminar al apagar: %v", err)
+	}
+	if err := trabajoService.Detener(ctx); err != nil {
+		log.Printf("⚠️  %v", err)
 	}
+	if err := whatsappService.Detener(ctx); err != nil {
+		log.Printf("⚠️  %v", err)
+	}
+	coordinacionService.Detener()
+	emisorMetricas.Detener()
+	if err := db.Close(); err != nil {
+		log.Printf("⚠️  Error cerrando la base de datos: %v", err)
+	}
+	log.Println("👋 Servidor detenido")
 }
 
 func setupRouter(
 	gameHandler *handlers.GameHandler,
+	preferenciasHandler *handlers.PreferenciasHandler,
+	adminHandler *handlers.AdminHandler,
+	whatsappHandler *handlers.WhatsAppHandler,
+	pedidoHandler *handlers.PedidoHandler,
+	widgetHandler *handlers.WidgetHandler,
+	mesaHandler *handlers.MesaHandler,
+	recomendacionHandler *handlers.RecomendacionHandler,
+	vigenciaHandler *handlers.VigenciaHandler,
+	configuracionHandler *handlers.ConfiguracionHandler,
+	descargaHandler *handlers.DescargaHandler,
+	errorHandler *handlers.ErrorHandler,
+	authHandler *handlers.AuthHandler,
+	authMiddleware *middleware.AuthMiddleware,
+	widgetMiddleware *middleware.WidgetMiddleware,
+	idempotencia *middleware.Idempotencia,
+	limiteEnvios *middleware.LimiteEnvios,
+	estadoCompartido repository.EstadoCompartidoRepository,
+	coordinacionService *services.CoordinacionService,
 	db *database.Database,
 	cfg *config.Config,
 	whatsappService *services.WhatsAppService,
+	inyector *fallas.Inyector,
+	metricas *middleware.Metricas,
 ) *gin.Engine {
 	// Modo release en producción
 	if cfg.IsProduction() {
 		gin.SetMode(gin.ReleaseMode)
 	}
 
-	router := gin.Default()
+	// Sin gin.Default: el logger y el recovery se agregan abajo según LOG_FORMAT
+	router := gin.New()
+
+	// IP del cliente (límites, bloqueos, auditoría): X-Forwarded-For solo de proxies
+	// configurados; sin ninguno cualquiera podría elegir su IP con ese header
+	router.TrustedPlatform = cfg.HeaderIPCliente()
+	if err := router.SetTrustedProxies(cfg.TrustedProxies); err != nil {
+		log.Fatalf("❌ TRUSTED_PROXIES inválido: %v", err)
+	}
 
-	// Middleware de CORS
-	router.Use(cors.New(cors.Config{
+	// Middleware de CORS. La API del widget embebible arma su propio CORS por origen
+	corsGeneral := cors.New(cors.Config{
 		AllowOrigins:     []string{"*"},
 		AllowMethods:     []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
 		AllowHeaders:     []string{"*"},
-		ExposeHeaders:    []string{"Content-Length"},
+		ExposeHeaders:    []string{"Content-Length", "Retry-After", "Idempotent-Replayed"},
 		AllowCredentials: true,
-	}))
+	})
+	router.Use(func(c *gin.Context) {
+		if strings.HasPrefix(c.Request.URL.Path, "/api/widget/") {
+			c.Next()
+			return
+		}
+		corsGeneral(c)
+	})
 
-	// Middleware de logging personalizado
-	router.Use(gin.LoggerWithFormatter(func(param gin.LogFormatterParams) string {
-		return fmt.Sprintf("🧀 %s - [%s] \"%s %s %s %d %s\"\n",
-			param.ClientIP,
-			param.TimeStamp.Format("15:04:05"),
-			param.Method,
-			param.Path,
-			param.Request.Proto,
-			param.StatusCode,
-			param.Latency,
-		)
-	}))
+	// Middleware de logging personalizado (campos estructurados fuera de LOG_FORMAT=pretty)
+	if cfg.LogFormat == logging.FormatoPretty {
+		router.Use(gin.LoggerWithFormatter(func(param gin.LogFormatterParams) string {
+			return fmt.Sprintf("🧀 %s - [%s] \"%s %s %s %d %s\"\n",
+				param.ClientIP,
+				param.TimeStamp.Format("15:04:05"),
+				param.Method,
+				param.Path,
+				param.Request.Proto,
+				param.StatusCode,
+				param.Latency,
+			)
+		}))
+	} else {
+		router.Use(middleware.StructuredRequestLogger())
+	}
 
-	// Middleware de recovery
-	router.Use(gin.Recovery())
+	// Métricas de latencia por ruta
+	router.Use(middleware.PerformanceLogger(metricas))
+
+	// Middleware de recovery: página de error para el navegador, JSON para la API
+	router.Use(gin.CustomRecovery(errorHandler.Recuperar))
 
 	// ===============================
 	// RUtAS PARA EL JUEGOVICH
@@ -118,11 +396,77 @@ func setupRouter(
 
 	// API del juego
 	gameAPI := router.Group("/api/game")
+
+	// Herramientas de desarrollo; las rutas se registran solo si están activas y nunca en producción
+	devAPI := router.Group("/api/dev")
+
+	// Captura de requests y respuestas del juego para depurar el frontend (solo desarrollo)
+	if cfg.DebugCaptureRequests > 0 && !cfg.IsProduction() {
+		captura := middleware.NewCapturaRequests(cfg.DebugCaptureRequests)
+		gameAPI.Use(captura.Middleware())
+
+		devAPI.GET("/requests", func(c *gin.Context) {
+			c.JSON(http.StatusOK, gin.H{
+				"success":      true,
+				"intercambios": captura.Ultimos(),
+			})
+		})
+		devAPI.DELETE("/requests", func(c *gin.Context) {
+			captura.Limpiar()
+			c.JSON(http.StatusOK, gin.H{"success": true})
+		})
+		log.Printf("🐞 Capturando los últimos %d requests de /api/game en /api/dev/requests", cfg.DebugCaptureRequests)
+	}
+
+	// Fallas inyectadas: GET muestra las activas y cuántas hubo, PUT las reemplaza
+	if inyector != nil {
+		devAPI.GET("/fallas", func(c *gin.Context) {
+			estado, contadores := inyector.Estado()
+			c.JSON(http.StatusOK, gin.H{
+				"success":    true,
+				"fallas":     estado,
+				"inyectadas": contadores,
+			})
+		})
+		devAPI.PUT("/fallas", func(c *gin.Context) {
+			var estado fallas.Estado
+			if err := c.ShouldBindJSON(&estado); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{
+					"success": false,
+					"message": "Fallas inválidas",
+					"error":   err.Error(),
+				})
+				return
+			}
+			if err := inyector.Configurar(estado); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{
+					"success": false,
+					"message": err.Error(),
+				})
+				return
+			}
+			log.Printf("🧨 Fallas inyectadas: WhatsApp 500 %.0f%%, 429 %.0f%%, DB +%dms",
+				estado.WhatsAppErrorRate*100, estado.WhatsApp429Rate*100, estado.DBLatenciaMs)
+			c.JSON(http.StatusOK, gin.H{
+				"success": true,
+				"fallas":  estado,
+			})
+		})
+	}
+
 	{
-		gameAPI.POST("/submit", gameHandler.SubmitGameResult)
+		gameAPI.POST("/submit", idempotencia.Middleware(), limiteEnvios.Limit(), gameHandler.SubmitGameResult)
 		gameAPI.GET("/stats", gameHandler.GetGameStats)
 		gameAPI.GET("/config", gameHandler.GetGameConfig)
 		gameAPI.GET("/target", gameHandler.GenerateTargetTime)
+		gameAPI.POST("/start", gameHandler.StartGame)
+		gameAPI.POST("/stop", gameHandler.StopGame)
+		gameAPI.POST("/voucher/pantalla", gameHandler.RevelarVoucherPantalla)
+		gameAPI.GET("/branding", gameHandler.GetBranding)
+		gameAPI.GET("/i18n/:locale", gameHandler.GetTextos)
+		gameAPI.GET("/winners", gameHandler.GetWinners)
+		gameAPI.GET("/leaderboard", gameHandler.GetLeaderboard)
+		gameAPI.GET("/share/:token", gameHandler.GetShareCard)
 
 		// Solo en desarrollo
 		if !cfg.IsProduction() {
@@ -131,9 +475,304 @@ func setupRouter(
 	}
 
 	// API de clientes (consultas públicas limitadas)
-	clientsAPI := router.Group("/api/clients")
+	// Limitada por IP y auditada para frenar la enumeración de teléfonos
+	lookupLimiter := middleware.NewLookupLimiter(estadoCompartido, 10, time.Minute, 5)
+	clientsAPI := router.Group("/api/clients", lookupLimiter.Limit())
 	{
 		clientsAPI.GET("/:phone", gameHandler.GetClientByPhone)
+		clientsAPI.POST("/:phone/codigo", gameHandler.SolicitarCodigoCliente)
+	}
+
+	// Imágenes de vouchers para escanear en caja
+	router.GET("/api/vouchers/:codigo/media", gameHandler.GetVoucherMedia)
+
+	// Descarga de archivos generados (reportes, exportaciones, QR) con link firmado y vencimiento
+	router.GET(almacenamiento.RutaDescargas+"*clave", descargaHandler.Descargar)
+
+	// API pública para widgets embebibles (solo datos agregados)
+	publicAPI := router.Group("/api/public")
+	{
+		publicAPI.GET("/stats", gameHandler.GetPublicWidgetStats)
+		publicAPI.GET("/espera", pedidoHandler.GetEsperaPublica)
+	}
+
+	// Juego embebible en sitios externos: solo config, objetivo, partida y envío, y solo desde
+	// los orígenes registrados para la clave
+	router.GET("/api/widget/:clave/embed.js", widgetHandler.GetScript)
+	widgetAPI := router.Group("/api/widget/:clave", widgetMiddleware.RequireOrigen())
+	{
+		widgetAPI.GET("/config", gameHandler.GetGameConfig)
+		widgetAPI.GET("/target", gameHandler.GenerateTargetTime)
+		widgetAPI.POST("/start", gameHandler.StartGame)
+		widgetAPI.POST("/stop", gameHandler.StopGame)
+		widgetAPI.POST("/voucher/pantalla", gameHandler.RevelarVoucherPantalla)
+		widgetAPI.POST("/submit", idempotencia.Middleware(), limiteEnvios.Limit(), gameHandler.SubmitGameResult)
+
+		// El middleware responde los preflight antes de llegar al handler
+		for _, ruta := range []string{"/config", "/target", "/start", "/stop", "/submit"} {
+			widgetAPI.OPTIONS(ruta, func(*gin.Context) {})
+		}
+	}
+
+	// API pública versionada: sobre data/meta/error y claves snake_case consistentes
+	v1 := router.Group("/api/v1")
+	{
+		v1.POST("/game/submit", idempotencia.Middleware(), limiteEnvios.Limit(), gameHandler.SubmitGameResultV1)
+		v1.GET("/game/stats", gameHandler.GetGameStatsV1)
+		v1.GET("/game/config", gameHandler.GetGameConfigV1)
+		v1.GET("/game/target", gameHandler.GenerateTargetTimeV1)
+		v1.POST("/game/start", gameHandler.StartGameV1)
+		v1.POST("/game/stop", gameHandler.StopGameV1)
+		v1.POST("/game/voucher/pantalla", gameHandler.RevelarVoucherPantallaV1)
+		v1.GET("/game/branding", gameHandler.GetBrandingV1)
+		v1.GET("/game/i18n/:locale", gameHandler.GetTextosV1)
+		v1.GET("/game/winners", gameHandler.GetWinnersV1)
+		v1.GET("/game/leaderboard", gameHandler.GetLeaderboardV1)
+
+		v1Clients := v1.Group("/clients", lookupLimiter.Limit())
+		v1Clients.GET("/:phone", gameHandler.GetClientByPhoneV1)
+		v1Clients.POST("/:phone/codigo", gameHandler.SolicitarCodigoClienteV1)
+
+		v1.GET("/public/stats", gameHandler.GetPublicWidgetStatsV1)
+		v1.GET("/public/espera", pedidoHandler.GetEsperaPublicaV1)
+	}
+
+	// Webhook de WhatsApp (mensajes entrantes)
+	router.GET("/webhook/whatsapp", whatsappHandler.VerificarWebhook)
+	router.POST("/webhook/whatsapp", whatsappHandler.RecibirWebhook)
+
+	// Centro de preferencias de comunicación (links firmados)
+	preferenciasAPI := router.Group("/api/preferencias")
+	{
+		preferenciasAPI.GET("/:cliente_id", preferenciasHandler.GetPreferencias)
+		preferenciasAPI.PUT("/:cliente_id", preferenciasHandler.ActualizarPreferencias)
+		preferenciasAPI.POST("/:cliente_id/baja", preferenciasHandler.DarDeBaja)
+	}
+
+	// ===============================
+	// PANEL ADMINISTRATIVO
+	// ===============================
+
+	// Sesión de los empleados (token en el header Authorization o en la cookie auth_token)
+	authAPI := router.Group("/api/auth")
+	{
+		authAPI.POST("/login", authHandler.Login)
+		authAPI.POST("/refresh", authHandler.Refresh)
+		authAPI.POST("/logout", authHandler.Logout)
+		authAPI.GET("/me", authMiddleware.RequireAuth(), authHandler.Me)
+		authAPI.PUT("/pin", authMiddleware.RequireAdmin(), authHandler.EstablecerPIN)
+	}
+
+	// Cada grupo pide el permiso del rol que lo habilita (ver models.CatalogoPermisos); las
+	// escrituras sensibles piden además ser admin
+	adminAPI := router.Group("/api/admin", authMiddleware.RequireAuth())
+	{
+		// Dashboard y alertas operativas
+		adminAPI.GET("/dashboard", adminHandler.GetDashboard)
+		adminAPI.GET("/alertas", adminHandler.GetAlertas)
+
+		// Centro de notificaciones
+		adminAPI.GET("/notificaciones", adminHandler.ListarNotificaciones)
+		adminAPI.PATCH("/notificaciones/:id/leida", adminHandler.MarcarNotificacionLeida)
+
+		// Roles y sus permisos
+		adminAPI.GET("/roles", authMiddleware.RequirePermission(models.PermisoUsuarios), authHandler.ListarRoles)
+		adminAPI.POST("/roles", authMiddleware.RequirePermission(models.PermisoUsuarios), authHandler.CrearRol)
+		adminAPI.PUT("/roles/:id", authMiddleware.RequirePermission(models.PermisoUsuarios), authHandler.ActualizarRol)
+		adminAPI.DELETE("/roles/:id", authMiddleware.RequirePermission(models.PermisoUsuarios), authHandler.EliminarRol)
+
+		// Tope diario de canjes por empleado
+		adminAPI.PUT("/usuarios/:id/limite-canjes", authMiddleware.RequireAdmin(), adminHandler.ActualizarLimiteCanjes)
+	}
+
+	canjeAPI := adminAPI.Group("", authMiddleware.RequirePermission(models.PermisoCanjear))
+	{
+		// Vouchers y canje en caja
+		canjeAPI.POST("/vouchers/canjear", adminHandler.CanjearVoucher)
+		canjeAPI.POST("/vouchers/scan", adminHandler.EscanearVoucher)
+		canjeAPI.GET("/vouchers", adminHandler.ListarVouchers)
+		canjeAPI.GET("/vouchers/vencidos", adminHandler.ListarVouchersVencidos)
+
+		// Detalle de vouchers y fechas en que la caja no los acepta
+		canjeAPI.GET("/vouchers/:codigo", adminHandler.GetVoucher)
+		canjeAPI.POST("/vouchers/:codigo/transferir", authMiddleware.RequireAdmin(), adminHandler.TransferirVoucher)
+		canjeAPI.GET("/dias-sin-canje", adminHandler.ListarDiasSinCanje)
+		canjeAPI.POST("/dias-sin-canje", authMiddleware.RequireAdmin(), adminHandler.CrearDiaSinCanje)
+		canjeAPI.DELETE("/dias-sin-canje/:id", authMiddleware.RequireAdmin(), adminHandler.EliminarDiaSinCanje)
+
+		// Archivo de vouchers y partidas viejas
+		canjeAPI.GET("/archivo/vouchers", adminHandler.BuscarVouchersArchivados)
+	}
+
+	clientesAPI := adminAPI.Group("", authMiddleware.RequirePermission(models.PermisoClientes))
+	{
+		// Clientes
+		clientesAPI.GET("/clientes", adminHandler.ListarClientes)
+		clientesAPI.GET("/clientes/pendientes", adminHandler.ListarClientesPendientes)
+		clientesAPI.GET("/clientes/:id", adminHandler.GetCliente)
+		clientesAPI.POST("/clientes/:id/aprobar", adminHandler.AprobarCliente)
+		clientesAPI.PATCH("/clientes/:id/estado", adminHandler.CambiarEstadoCliente)
+		clientesAPI.POST("/clientes/:id/anonimizar", authMiddleware.RequireAdmin(), adminHandler.AnonimizarCliente)
+
+		// Premios retenidos por un pico de emisión
+		clientesAPI.GET("/premios-retenidos", gameHandler.ListarPremiosRetenidos)
+		clientesAPI.POST("/premios-retenidos/:id/liberar", gameHandler.LiberarPremioRetenido)
+		clientesAPI.POST("/premios-retenidos/:id/descartar", gameHandler.DescartarPremioRetenido)
+
+		// Lista de bloqueo de teléfonos, IPs y huellas
+		clientesAPI.GET("/bloqueos", adminHandler.ListarBloqueos)
+		clientesAPI.POST("/bloqueos", authMiddleware.RequireAdmin(), adminHandler.CrearBloqueo)
+		clientesAPI.DELETE("/bloqueos/:id", authMiddleware.RequireAdmin(), adminHandler.LevantarBloqueo)
+	}
+
+	reportesAPI := adminAPI.Group("", authMiddleware.RequirePermission(models.PermisoReportes))
+	{
+		// Reportes de ventas y estadísticas
+		reportesAPI.GET("/reportes/ventas", adminHandler.GetReporteVentas)
+		reportesAPI.GET("/reportes/estadisticas", adminHandler.GetEstadisticasDetalladas)
+		reportesAPI.GET("/reportes/diarios", adminHandler.GetEstadisticasDiarias)
+		reportesAPI.GET("/reportes/comparacion", adminHandler.GetComparacion)
+		reportesAPI.GET("/leaderboard", adminHandler.GetRanking)
+		reportesAPI.POST("/leaderboard/cerrar", authMiddleware.RequireAdmin(), adminHandler.CerrarRanking)
+		reportesAPI.GET("/exportar/:tipo", authMiddleware.RequireAdmin(), adminHandler.ExportarDatos)
+		reportesAPI.GET("/export/:archivo", authMiddleware.RequireAdmin(), adminHandler.ExportarPlanilla)
+		reportesAPI.POST("/exportar/:tipo/trabajo", authMiddleware.RequireAdmin(), adminHandler.ExportarDatosEnSegundoPlano)
+
+		// Reportes guardados
+		reportesAPI.GET("/reportes", adminHandler.ListarReportes)
+		reportesAPI.POST("/reportes", adminHandler.GuardarReporte)
+		reportesAPI.PUT("/reportes/:id", adminHandler.ActualizarReporte)
+		reportesAPI.DELETE("/reportes/:id", adminHandler.EliminarReporte)
+		reportesAPI.PATCH("/reportes/:id/favorito", adminHandler.MarcarReporteFavorito)
+		reportesAPI.POST("/reportes/:id/ejecutar", adminHandler.EjecutarReporte)
+		reportesAPI.GET("/reportes/:id/descarga", adminHandler.DescargarReporte)
+		reportesAPI.GET("/reportes/huellas", adminHandler.GetReporteHuellas)
+		reportesAPI.GET("/reportes/vouchers-flash", adminHandler.GetRendimientoFlash)
+		reportesAPI.GET("/reportes/canjes-empleados", adminHandler.GetReporteCanjesEmpleados)
+		reportesAPI.GET("/calendar", adminHandler.GetCalendario)
+		reportesAPI.GET("/metas", adminHandler.GetMetas)
+		reportesAPI.PUT("/metas/:mes", authMiddleware.RequireAdmin(), adminHandler.GuardarMetas)
+		reportesAPI.GET("/stats/mensajes", adminHandler.GetEstadisticasMensajes)
+	}
+
+	campanasAPI := adminAPI.Group("", authMiddleware.RequirePermission(models.PermisoCampanas))
+	{
+		// Campañas promocionales y sus costos
+		campanasAPI.GET("/campanas", adminHandler.ListarCampanas)
+		campanasAPI.POST("/campanas", authMiddleware.RequireAdmin(), adminHandler.CrearCampana)
+		campanasAPI.GET("/campanas/:id", adminHandler.GetCampana)
+		campanasAPI.POST("/campanas/:id/enviar", authMiddleware.RequireAdmin(), adminHandler.EnviarCampana)
+		campanasAPI.POST("/campanas/estimar", adminHandler.EstimarCostoCampana)
+		campanasAPI.GET("/campanas/:id/costos", adminHandler.GetCostosCampana)
+
+		// WhatsApp
+		campanasAPI.GET("/whatsapp/cuota", adminHandler.GetCuotaWhatsApp)
+		campanasAPI.GET("/whatsapp/plantillas", adminHandler.GetPlantillasWhatsApp)
+		campanasAPI.GET("/whatsapp/pending", whatsappHandler.ListarOutboxWhatsApp)
+		campanasAPI.POST("/whatsapp/pending/:id/reintentar", whatsappHandler.ReintentarOutboxWhatsApp)
+	}
+
+	conversacionesAPI := adminAPI.Group("", authMiddleware.RequirePermission(models.PermisoConversaciones))
+	{
+		// Bandeja de conversaciones de WhatsApp
+		conversacionesAPI.GET("/conversaciones", whatsappHandler.ListarConversaciones)
+		conversacionesAPI.GET("/conversaciones/:telefono", whatsappHandler.GetConversacion)
+		conversacionesAPI.POST("/conversaciones/:telefono/responder", whatsappHandler.ResponderConversacion)
+		conversacionesAPI.POST("/conversaciones/:telefono/asignar", whatsappHandler.AsignarConversacion)
+		conversacionesAPI.POST("/conversaciones/:telefono/resolver", whatsappHandler.ResolverConversacion)
+		conversacionesAPI.GET("/atencion/sla", whatsappHandler.GetResumenSLA)
+
+		// Respuestas rápidas de la bandeja
+		conversacionesAPI.GET("/respuestas-rapidas", whatsappHandler.ListarRespuestasRapidas)
+		conversacionesAPI.POST("/respuestas-rapidas", authMiddleware.RequireAdmin(), whatsappHandler.CrearRespuestaRapida)
+		conversacionesAPI.PUT("/respuestas-rapidas/:id", authMiddleware.RequireAdmin(), whatsappHandler.ActualizarRespuestaRapida)
+		conversacionesAPI.DELETE("/respuestas-rapidas/:id", authMiddleware.RequireAdmin(), whatsappHandler.EliminarRespuestaRapida)
+	}
+
+	pedidosAPI := adminAPI.Group("", authMiddleware.RequirePermission(models.PermisoPedidos))
+	{
+		// Menú y carga de pedidos de WhatsApp
+		pedidosAPI.GET("/menu", pedidoHandler.ListarMenu)
+		pedidosAPI.POST("/menu", authMiddleware.RequireAdmin(), pedidoHandler.CrearItemMenu)
+		pedidosAPI.PUT("/menu/:id", authMiddleware.RequireAdmin(), pedidoHandler.ActualizarItemMenu)
+		pedidosAPI.DELETE("/menu/:id", authMiddleware.RequireAdmin(), pedidoHandler.EliminarItemMenu)
+		pedidosAPI.POST("/pedidos/interpretar", pedidoHandler.InterpretarPedido)
+		pedidosAPI.GET("/pedidos", pedidoHandler.ListarPedidos)
+		pedidosAPI.POST("/pedidos", pedidoHandler.ConfirmarPedido)
+		pedidosAPI.GET("/pedidos/:id", pedidoHandler.GetPedido)
+		pedidosAPI.PATCH("/pedidos/:id/asignar", pedidoHandler.AsignarPedido)
+		pedidosAPI.PATCH("/pedidos/:id/estado", pedidoHandler.CambiarEstadoPedido)
+		pedidosAPI.GET("/pedidos/queue", pedidoHandler.GetColaCocina)
+		pedidosAPI.GET("/pedidos/queue/ws", pedidoHandler.ColaCocinaWS)
+		pedidosAPI.GET("/pedidos/espera", pedidoHandler.GetEsperaEstimada)
+		pedidosAPI.PATCH("/pedidos/:id/items/:item_id/preparado", pedidoHandler.MarcarItemPreparado)
+		pedidosAPI.GET("/clientes/:id/pedidos", pedidoHandler.ListarPedidosCliente)
+		pedidosAPI.POST("/clientes/:id/pedidos/repetir", pedidoHandler.RepetirPedidoCliente)
+	}
+
+	configuracionAPI := adminAPI.Group("", authMiddleware.RequirePermission(models.PermisoConfiguracion))
+	{
+		// Textos de los mensajes de WhatsApp (marketing, respuesta automática, verificación)
+		configuracionAPI.GET("/mensajes", whatsappHandler.ListarMensajes)
+		configuracionAPI.PUT("/mensajes/:clave", authMiddleware.RequireAdmin(), whatsappHandler.GuardarMensaje)
+		configuracionAPI.POST("/mensajes/:clave/restablecer", authMiddleware.RequireAdmin(), whatsappHandler.RestablecerMensaje)
+		configuracionAPI.POST("/mensajes/:clave/preview", whatsappHandler.PrevisualizarMensaje)
+
+		// Horarios de atención y feriados (respuesta automática de WhatsApp)
+		configuracionAPI.GET("/horarios", whatsappHandler.GetHorarios)
+		configuracionAPI.PUT("/horarios", authMiddleware.RequireAdmin(), whatsappHandler.GuardarHorarios)
+		configuracionAPI.GET("/feriados", whatsappHandler.ListarFeriados)
+		configuracionAPI.POST("/feriados", authMiddleware.RequireAdmin(), whatsappHandler.CrearFeriado)
+		configuracionAPI.DELETE("/feriados/:id", authMiddleware.RequireAdmin(), whatsappHandler.EliminarFeriado)
+
+		// Corte automático por tasa de victorias anómala
+		configuracionAPI.GET("/juego/circuito", adminHandler.GetCircuitoPremios)
+		configuracionAPI.POST("/juego/circuito/restablecer", authMiddleware.RequireAdmin(), adminHandler.RestablecerCircuitoPremios)
+
+		// Operaciones en segundo plano (envíos de campañas, exportaciones, mantenimiento)
+		configuracionAPI.GET("/jobs", adminHandler.ListarTrabajos)
+		configuracionAPI.GET("/jobs/programados", adminHandler.ListarTareasProgramadas)
+		configuracionAPI.POST("/jobs/programados/:nombre/ejecutar", authMiddleware.RequireAdmin(), adminHandler.EjecutarTareaProgramada)
+		configuracionAPI.GET("/jobs/:id", adminHandler.GetTrabajo)
+		configuracionAPI.POST("/jobs/:id/cancelar", authMiddleware.RequireAdmin(), adminHandler.CancelarTrabajo)
+		configuracionAPI.POST("/archivo/ejecutar", authMiddleware.RequireAdmin(), adminHandler.ArchivarVouchers)
+
+		// Sitios externos que embeben el juego
+		configuracionAPI.GET("/widgets", widgetHandler.ListarWidgets)
+		configuracionAPI.POST("/widgets", authMiddleware.RequireAdmin(), widgetHandler.CrearWidget)
+		configuracionAPI.PUT("/widgets/:id", authMiddleware.RequireAdmin(), widgetHandler.ActualizarWidget)
+
+		// Mesas del local y sus códigos QR
+		configuracionAPI.GET("/mesas", mesaHandler.ListarMesas)
+		configuracionAPI.POST("/mesas", authMiddleware.RequireAdmin(), mesaHandler.CrearMesa)
+		configuracionAPI.PUT("/mesas/:id", authMiddleware.RequireAdmin(), mesaHandler.ActualizarMesa)
+		configuracionAPI.GET("/mesas/:id/qr", mesaHandler.GetQRMesa)
+
+		// Reglas de recomendación: alcance y sugerencia de los vouchers de partida
+		configuracionAPI.GET("/recomendaciones", recomendacionHandler.ListarReglas)
+		configuracionAPI.POST("/recomendaciones", authMiddleware.RequireAdmin(), recomendacionHandler.CrearRegla)
+		configuracionAPI.PUT("/recomendaciones/:id", authMiddleware.RequireAdmin(), recomendacionHandler.ActualizarRegla)
+		configuracionAPI.DELETE("/recomendaciones/:id", authMiddleware.RequireAdmin(), recomendacionHandler.EliminarRegla)
+
+		// Vigencia de los vouchers de partida por tipo y día de la semana
+		configuracionAPI.GET("/vigencias", vigenciaHandler.ListarVigencias)
+		configuracionAPI.POST("/vigencias", authMiddleware.RequireAdmin(), vigenciaHandler.CrearVigencia)
+		configuracionAPI.PUT("/vigencias/:id", authMiddleware.RequireAdmin(), vigenciaHandler.ActualizarVigencia)
+		configuracionAPI.DELETE("/vigencias/:id", authMiddleware.RequireAdmin(), vigenciaHandler.EliminarVigencia)
+
+		// Branding de vouchers y página del juego
+		configuracionAPI.GET("/branding", adminHandler.GetBranding)
+		configuracionAPI.PUT("/branding", authMiddleware.RequireAdmin(), adminHandler.ActualizarBranding)
+
+		// Celebración de las victorias (confeti, sonido, titular y oferta)
+		configuracionAPI.GET("/celebracion", adminHandler.GetCelebracion)
+		configuracionAPI.PUT("/celebracion", authMiddleware.RequireAdmin(), adminHandler.ActualizarCelebracion)
+
+		// Parámetros del juego editables en caliente e historial de cambios de configuración
+		configuracionAPI.GET("/juego/parametros", configuracionHandler.GetParametrosJuego)
+		configuracionAPI.PUT("/juego/parametros", authMiddleware.RequireAdmin(), configuracionHandler.ActualizarParametrosJuego)
+		configuracionAPI.GET("/configuracion/cambios", configuracionHandler.ListarCambiosConfiguracion)
+		configuracionAPI.POST("/configuracion/cambios/:id/revertir", authMiddleware.RequireAdmin(), configuracionHandler.RevertirCambioConfiguracion)
 	}
 
 	// ===============================
@@ -161,7 +800,8 @@ func setupRouter(
 		c.JSON(status, gin.H{
 			"status":       "running",
 			"service":      "CheeseHouse Timing Game",
-			"version":      "1.0.0",
+			"version":      version.Obtener().Version,
+			"commit":       version.Obtener().Commit,
 			"environment":  cfg.Environment,
 			"database":     dbHealth,
 			"game_service": gameHealth,
@@ -170,23 +810,93 @@ func setupRouter(
 		})
 	})
 
+	// Readiness: la base responde y tiene el esquema que espera este binario. Con
+	// migraciones pendientes responde 503 para que el deploy no reciba tráfico
+	router.GET("/readyz", func(c *gin.Context) {
+		if err := db.Health(); err != nil {
+			c.JSON(503, gin.H{
+				"status":   "not_ready",
+				"database": "error: " + err.Error(),
+			})
+			return
+		}
+		esquema, err := db.EstadoEsquema()
+		if err != nil {
+			c.JSON(503, gin.H{
+				"status":   "not_ready",
+				"database": "ok",
+				"esquema":  gin.H{"error": err.Error()},
+			})
+			return
+		}
+
+		status, estado := 200, "ready"
+		if !esquema.AlDia() {
+			status, estado = 503, "not_ready"
+		}
+		c.JSON(status, gin.H{
+			"status":   estado,
+			"database": "ok",
+			"esquema":  esquema,
+		})
+	})
+
 	// Endpoint para información del sistema
 	router.GET("/info", func(c *gin.Context) {
+		esquema := gin.H{}
+		if estado, err := db.EstadoEsquema(); err != nil {
+			esquema["error"] = err.Error()
+		} else {
+			esquema["version"] = estado.Version
+			esquema["version_esperada"] = estado.Esperada
+			if estado.Advertencia != "" {
+				esquema["advertencia"] = estado.Advertencia
+			}
+		}
+
 		c.JSON(200, gin.H{
 			"restaurante": cfg.RestaurantName,
 			"ubicacion":   cfg.Location,
-			"version":     "1.0.0",
+			"version":     version.Obtener().Version,
+			"build":       version.Obtener(),
+			"esquema":     esquema,
+			"instancia":   coordinacionService.Estado(),
 			"endpoints": map[string]string{
 				"juego":      "/",
 				"api_submit": "/api/game/submit",
 				"api_stats":  "/api/game/stats",
 				"health":     "/health",
+				"ready":      "/readyz",
 			},
 		})
 	})
 
-	// 404 Handler - servir archivos estáticos
-	router.NoRoute(gin.WrapH(http.FileServer(http.Dir("./Front/timing-game/"))))
+	// Métricas internas: p50/p95 por ruta, requests lentos y consultas lentas asociadas
+	adminAPI.GET("/metricas", func(c *gin.Context) {
+		c.JSON(200, gin.H{
+			"success":  true,
+			"metricas": metricas.Resumen(),
+		})
+	})
+
+	// 404 Handler - servir archivos estáticos; lo que no existe recibe la página o el JSON de 404.
+	// Con fingerprint los JS/CSS se publican en /static con el hash en el nombre; sin él
+	// (o si falla) se sirve la carpeta tal cual
+	carpetaFront := os.DirFS(cfg.Static.Dir)
+	archivosFront := http.FileServer(http.FS(carpetaFront))
+	if cfg.Static.Fingerprint {
+		sitio, err := estaticos.Nuevo(carpetaFront, cfg.Static.AssetMaxAge)
+		if err != nil {
+			log.Printf("⚠️  No se pudieron fingerprintear los archivos estáticos: %v", err)
+		} else {
+			log.Printf("📦 %d archivos estáticos publicados con hash en %s", len(sitio.Manifiesto()), estaticos.Prefijo)
+			archivosFront = sitio
+			router.GET(estaticos.Prefijo+"*archivo", gin.WrapH(sitio))
+		}
+	}
+	router.NoRoute(errorHandler.NoEncontrado(archivosFront, func(ruta string) bool {
+		return estaticos.Existe(carpetaFront, ruta)
+	}))
 
 	return router
 }